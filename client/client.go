@@ -0,0 +1,277 @@
+// Package client is a typed Go client for the buyer JSON API. The methods in
+// client_gen.go are generated from the OpenAPI document served at
+// /api/openapi.json; regenerate them after changing an API route or type.
+package client
+
+//go:generate go test ../cmd/buyer -run TestOpenAPI_GeneratedClient -args -update-client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shakfu/buyer/internal/api"
+)
+
+// Client calls a buyer web server
+type Client struct {
+	BaseURL    string // e.g. http://localhost:8080
+	HTTPClient *http.Client
+	header     http.Header
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.HTTPClient = httpClient
+	}
+}
+
+// WithBasicAuth sends HTTP basic auth credentials with every request
+func WithBasicAuth(username, password string) Option {
+	return func(c *Client) {
+		req := &http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(username, password)
+		c.header.Set("Authorization", req.Header.Get("Authorization"))
+	}
+}
+
+// WithToken sends an API token as "Authorization: Bearer" with every request
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// WithHeader sends a header with every request
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.header.Set(key, value)
+	}
+}
+
+// New returns a client for the server at baseURL
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		header:     make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ListParams pages, sorts and filters list operations. Filters map a field to
+// a value for equality, or "field[op]" to a value for the operators eq, ne,
+// gt, gte, lt, lte, like and in.
+type ListParams struct {
+	Limit   int
+	Offset  int
+	Sort    string // Comma-separated fields; prefix with - for descending
+	Filters map[string]string
+}
+
+// Values encodes the parameters as a query string
+func (p ListParams) Values() url.Values {
+	query := url.Values{}
+	if p.Limit > 0 {
+		query.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Offset > 0 {
+		query.Set("offset", strconv.Itoa(p.Offset))
+	}
+	if p.Sort != "" {
+		query.Set("sort", p.Sort)
+	}
+	keys := make([]string, 0, len(p.Filters))
+	for key := range p.Filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		query.Set(key, p.Filters[key])
+	}
+	return query
+}
+
+// do sends a request with an optional JSON body and decodes a JSON response
+// into out. Error responses are returned as *api.Problem.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+	resp, err := c.send(ctx, method, path, query, contentType, reader)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, out)
+}
+
+// upload sends the fields of body as a multipart/form-data form with the
+// file in part, streaming it, and decodes a JSON response into out
+func (c *Client) upload(ctx context.Context, method, path string, body interface{}, part, fileName string, file io.Reader, out interface{}) error {
+	fields, err := formFields(body)
+	if err != nil {
+		return err
+	}
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(writeForm(form, fields, part, fileName, file))
+	}()
+	resp, err := c.send(ctx, method, path, nil, form.FormDataContentType(), reader)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, out)
+}
+
+// download sends a request and returns the body of the response, a file the
+// caller closes
+func (c *Client) download(ctx context.Context, method, path string, query url.Values) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, method, path, query, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// send sends a request and returns a successful response, returning error
+// responses as *api.Problem
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		return nil, responseError(resp, data)
+	}
+	return resp, nil
+}
+
+// decodeResponse decodes a JSON response into out and closes it
+func decodeResponse(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// formFields returns the non-null fields of body, encoded as JSON, as form
+// values
+func formFields(body interface{}) (map[string]string, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var values map[string]interface{}
+	if err := decoder.Decode(&values); err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	fields := make(map[string]string, len(values))
+	for name, value := range values {
+		if value != nil {
+			fields[name] = fmt.Sprint(value)
+		}
+	}
+	return fields, nil
+}
+
+// writeForm writes the fields, sorted, and the file of a form
+func writeForm(form *multipart.Writer, fields map[string]string, part, fileName string, file io.Reader) error {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := form.WriteField(name, fields[name]); err != nil {
+			return err
+		}
+	}
+	w, err := form.CreateFormFile(part, fileName)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, file); err != nil {
+		return err
+	}
+	return form.Close()
+}
+
+// responseError turns an error response into a Problem. Endpoints outside
+// /api/v1 send {"error": "..."} rather than problem details.
+func responseError(resp *http.Response, data []byte) error {
+	problem := &api.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(resp.StatusCode),
+		Status: resp.StatusCode,
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/problem+json":
+		if err := json.Unmarshal(data, problem); err == nil {
+			return problem
+		}
+	case "application/json":
+		var message api.ErrorMessage
+		if err := json.Unmarshal(data, &message); err == nil && message.Error != "" {
+			problem.Detail = message.Error
+			return problem
+		}
+	}
+	problem.Detail = strings.TrimSpace(string(data))
+	return problem
+}
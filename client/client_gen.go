@@ -0,0 +1,608 @@
+// Code generated from the buyer OpenAPI document. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/shakfu/buyer/internal/api"
+	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/services"
+)
+
+// CreateBrand calls POST /api/v1/brands: create a brand
+func (c *Client) CreateBrand(ctx context.Context, body api.BrandInput) (*models.Brand, error) {
+	var out models.Brand
+	if err := c.do(ctx, "POST", "/api/v1/brands", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateDocument calls POST /api/v1/documents: upload a document
+func (c *Client) CreateDocument(ctx context.Context, body api.DocumentInput, fileName string, file io.Reader) (*models.Document, error) {
+	var out models.Document
+	if err := c.upload(ctx, "POST", "/api/v1/documents", body, "file", fileName, file, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateForexRate calls POST /api/v1/forex: create a forex rate
+func (c *Client) CreateForexRate(ctx context.Context, body api.ForexInput) (*models.Forex, error) {
+	var out models.Forex
+	if err := c.do(ctx, "POST", "/api/v1/forex", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateProduct calls POST /api/v1/products: create a product
+func (c *Client) CreateProduct(ctx context.Context, body api.ProductInput) (*models.Product, error) {
+	var out models.Product
+	if err := c.do(ctx, "POST", "/api/v1/products", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateProject calls POST /api/v1/projects: create a project
+func (c *Client) CreateProject(ctx context.Context, body api.ProjectInput) (*models.Project, error) {
+	var out models.Project
+	if err := c.do(ctx, "POST", "/api/v1/projects", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateProjectBOMItem calls POST /api/v1/projects/{id}/bom-items: add an item to the bill of materials of a project
+func (c *Client) CreateProjectBOMItem(ctx context.Context, id uint, body api.BOMItemInput) (*models.BillOfMaterialsItem, error) {
+	var out models.BillOfMaterialsItem
+	if err := c.do(ctx, "POST", fmt.Sprintf("/api/v1/projects/%d/bom-items", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreatePurchaseOrder calls POST /api/v1/purchase-orders: create a purchase order
+func (c *Client) CreatePurchaseOrder(ctx context.Context, body api.PurchaseOrderInput) (*models.PurchaseOrder, error) {
+	var out models.PurchaseOrder
+	if err := c.do(ctx, "POST", "/api/v1/purchase-orders", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateQuote calls POST /api/v1/quotes: create a quote
+func (c *Client) CreateQuote(ctx context.Context, body api.QuoteInput) (*models.Quote, error) {
+	var out models.Quote
+	if err := c.do(ctx, "POST", "/api/v1/quotes", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateRequisition calls POST /api/v1/requisitions: create a requisition
+func (c *Client) CreateRequisition(ctx context.Context, body api.RequisitionInput) (*models.Requisition, error) {
+	var out models.Requisition
+	if err := c.do(ctx, "POST", "/api/v1/requisitions", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateSpecification calls POST /api/v1/specifications: create a specification
+func (c *Client) CreateSpecification(ctx context.Context, body api.SpecificationInput) (*models.Specification, error) {
+	var out models.Specification
+	if err := c.do(ctx, "POST", "/api/v1/specifications", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateVendor calls POST /api/v1/vendors: create a vendor
+func (c *Client) CreateVendor(ctx context.Context, body api.VendorInput) (*models.Vendor, error) {
+	var out models.Vendor
+	if err := c.do(ctx, "POST", "/api/v1/vendors", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateVendorRating calls POST /api/v1/vendor-ratings: create a vendor rating
+func (c *Client) CreateVendorRating(ctx context.Context, body api.VendorRatingInput) (*models.VendorRating, error) {
+	var out models.VendorRating
+	if err := c.do(ctx, "POST", "/api/v1/vendor-ratings", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteBOMItem calls DELETE /api/v1/bom-items/{id}: delete a bill of materials item
+func (c *Client) DeleteBOMItem(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/api/v1/bom-items/%d", id), nil, nil, nil)
+}
+
+// DeleteBrand calls DELETE /api/v1/brands/{id}: delete a brand
+func (c *Client) DeleteBrand(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/api/v1/brands/%d", id), nil, nil, nil)
+}
+
+// DeleteDocument calls DELETE /api/v1/documents/{id}: delete a document
+func (c *Client) DeleteDocument(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/api/v1/documents/%d", id), nil, nil, nil)
+}
+
+// DeleteForexRate calls DELETE /api/v1/forex/{id}: delete a forex rate
+func (c *Client) DeleteForexRate(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/api/v1/forex/%d", id), nil, nil, nil)
+}
+
+// DeleteProduct calls DELETE /api/v1/products/{id}: delete a product
+func (c *Client) DeleteProduct(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/api/v1/products/%d", id), nil, nil, nil)
+}
+
+// DeleteProject calls DELETE /api/v1/projects/{id}: delete a project
+func (c *Client) DeleteProject(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/api/v1/projects/%d", id), nil, nil, nil)
+}
+
+// DeletePurchaseOrder calls DELETE /api/v1/purchase-orders/{id}: delete a purchase order
+func (c *Client) DeletePurchaseOrder(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/api/v1/purchase-orders/%d", id), nil, nil, nil)
+}
+
+// DeleteQuote calls DELETE /api/v1/quotes/{id}: delete a quote
+func (c *Client) DeleteQuote(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/api/v1/quotes/%d", id), nil, nil, nil)
+}
+
+// DeleteRequisition calls DELETE /api/v1/requisitions/{id}: delete a requisition
+func (c *Client) DeleteRequisition(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/api/v1/requisitions/%d", id), nil, nil, nil)
+}
+
+// DeleteSpecification calls DELETE /api/v1/specifications/{id}: delete a specification
+func (c *Client) DeleteSpecification(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/api/v1/specifications/%d", id), nil, nil, nil)
+}
+
+// DeleteVendor calls DELETE /api/v1/vendors/{id}: delete a vendor
+func (c *Client) DeleteVendor(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/api/v1/vendors/%d", id), nil, nil, nil)
+}
+
+// DeleteVendorRating calls DELETE /api/v1/vendor-ratings/{id}: delete a vendor rating
+func (c *Client) DeleteVendorRating(ctx context.Context, id uint) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/api/v1/vendor-ratings/%d", id), nil, nil, nil)
+}
+
+// DownloadDocument calls GET /api/v1/documents/{id}/download: download a document's file
+func (c *Client) DownloadDocument(ctx context.Context, id uint) (io.ReadCloser, error) {
+	return c.download(ctx, "GET", fmt.Sprintf("/api/v1/documents/%d/download", id), nil)
+}
+
+// GetBOMItem calls GET /api/v1/bom-items/{id}: get a bill of materials item
+func (c *Client) GetBOMItem(ctx context.Context, id uint) (*models.BillOfMaterialsItem, error) {
+	var out models.BillOfMaterialsItem
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/v1/bom-items/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetBrand calls GET /api/v1/brands/{id}: get a brand
+func (c *Client) GetBrand(ctx context.Context, id uint) (*models.Brand, error) {
+	var out models.Brand
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/v1/brands/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDocument calls GET /api/v1/documents/{id}: get a document
+func (c *Client) GetDocument(ctx context.Context, id uint) (*models.Document, error) {
+	var out models.Document
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/v1/documents/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetForexRate calls GET /api/v1/forex/{id}: get a forex rate
+func (c *Client) GetForexRate(ctx context.Context, id uint) (*models.Forex, error) {
+	var out models.Forex
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/v1/forex/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetProcurementAnalysis calls GET /api/projects/{id}/procurement/analysis: compare quotes for a project's bill of materials
+func (c *Client) GetProcurementAnalysis(ctx context.Context, id uint) (*services.ProjectProcurementComparison, error) {
+	var out services.ProjectProcurementComparison
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/projects/%d/procurement/analysis", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetProcurementDashboard calls GET /api/projects/{id}/procurement/dashboard: summarize procurement progress for a project
+func (c *Client) GetProcurementDashboard(ctx context.Context, id uint) (*services.ProjectDashboard, error) {
+	var out services.ProjectDashboard
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/projects/%d/procurement/dashboard", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetProcurementRecommendations calls GET /api/projects/{id}/procurement/recommendations: recommend vendors for a project
+func (c *Client) GetProcurementRecommendations(ctx context.Context, id uint, strategy string) ([]services.VendorRecommendation, error) {
+	query := url.Values{}
+	if strategy != "" {
+		query.Set("strategy", strategy)
+	}
+	var out []services.VendorRecommendation
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/projects/%d/procurement/recommendations", id), query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetProcurementRisks calls GET /api/projects/{id}/procurement/risks: assess procurement risks for a project
+func (c *Client) GetProcurementRisks(ctx context.Context, id uint) (*services.EnhancedRiskAssessment, error) {
+	var out services.EnhancedRiskAssessment
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/projects/%d/procurement/risks", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetProcurementSavings calls GET /api/projects/{id}/procurement/savings: estimate savings for a project
+func (c *Client) GetProcurementSavings(ctx context.Context, id uint) (*services.ProjectSavingsSummary, error) {
+	var out services.ProjectSavingsSummary
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/projects/%d/procurement/savings", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetProcurementScenarios calls GET /api/projects/{id}/procurement/scenarios: compare procurement scenarios for a project
+func (c *Client) GetProcurementScenarios(ctx context.Context, id uint) ([]services.ProcurementScenario, error) {
+	var out []services.ProcurementScenario
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/projects/%d/procurement/scenarios", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetProcurementStrategy calls GET /api/projects/{id}/procurement/strategy: get a project's procurement strategy
+func (c *Client) GetProcurementStrategy(ctx context.Context, id uint) (*models.ProjectProcurementStrategy, error) {
+	var out models.ProjectProcurementStrategy
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/projects/%d/procurement/strategy", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetProduct calls GET /api/v1/products/{id}: get a product
+func (c *Client) GetProduct(ctx context.Context, id uint) (*models.Product, error) {
+	var out models.Product
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/v1/products/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetProject calls GET /api/v1/projects/{id}: get a project
+func (c *Client) GetProject(ctx context.Context, id uint) (*models.Project, error) {
+	var out models.Project
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/v1/projects/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetPurchaseOrder calls GET /api/v1/purchase-orders/{id}: get a purchase order
+func (c *Client) GetPurchaseOrder(ctx context.Context, id uint) (*models.PurchaseOrder, error) {
+	var out models.PurchaseOrder
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/v1/purchase-orders/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetQuote calls GET /api/v1/quotes/{id}: get a quote
+func (c *Client) GetQuote(ctx context.Context, id uint) (*models.Quote, error) {
+	var out models.Quote
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/v1/quotes/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetRequisition calls GET /api/v1/requisitions/{id}: get a requisition
+func (c *Client) GetRequisition(ctx context.Context, id uint) (*models.Requisition, error) {
+	var out models.Requisition
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/v1/requisitions/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSpecification calls GET /api/v1/specifications/{id}: get a specification
+func (c *Client) GetSpecification(ctx context.Context, id uint) (*models.Specification, error) {
+	var out models.Specification
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/v1/specifications/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetVendor calls GET /api/v1/vendors/{id}: get a vendor
+func (c *Client) GetVendor(ctx context.Context, id uint) (*models.Vendor, error) {
+	var out models.Vendor
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/v1/vendors/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetVendorConsolidation calls GET /api/projects/{id}/procurement/consolidation: analyze vendor consolidation for a project
+func (c *Client) GetVendorConsolidation(ctx context.Context, id uint) ([]services.VendorConsolidationAnalysis, error) {
+	var out []services.VendorConsolidationAnalysis
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/projects/%d/procurement/consolidation", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetVendorRating calls GET /api/v1/vendor-ratings/{id}: get a vendor rating
+func (c *Client) GetVendorRating(ctx context.Context, id uint) (*models.VendorRating, error) {
+	var out models.VendorRating
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/v1/vendor-ratings/%d", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListBrands calls GET /api/v1/brands: list brands
+func (c *Client) ListBrands(ctx context.Context, params ListParams) (*services.ListPage[models.Brand], error) {
+	query := params.Values()
+	var out services.ListPage[models.Brand]
+	if err := c.do(ctx, "GET", "/api/v1/brands", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListDocuments calls GET /api/v1/documents: list documents
+func (c *Client) ListDocuments(ctx context.Context, params ListParams) (*services.ListPage[models.Document], error) {
+	query := params.Values()
+	var out services.ListPage[models.Document]
+	if err := c.do(ctx, "GET", "/api/v1/documents", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListForexRates calls GET /api/v1/forex: list forex rates
+func (c *Client) ListForexRates(ctx context.Context, params ListParams) (*services.ListPage[models.Forex], error) {
+	query := params.Values()
+	var out services.ListPage[models.Forex]
+	if err := c.do(ctx, "GET", "/api/v1/forex", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListProducts calls GET /api/v1/products: list products
+func (c *Client) ListProducts(ctx context.Context, params ListParams) (*services.ListPage[models.Product], error) {
+	query := params.Values()
+	var out services.ListPage[models.Product]
+	if err := c.do(ctx, "GET", "/api/v1/products", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListProjectBOMItems calls GET /api/v1/projects/{id}/bom-items: list the bill of materials items of a project
+func (c *Client) ListProjectBOMItems(ctx context.Context, id uint, params ListParams) (*services.ListPage[models.BillOfMaterialsItem], error) {
+	query := params.Values()
+	var out services.ListPage[models.BillOfMaterialsItem]
+	if err := c.do(ctx, "GET", fmt.Sprintf("/api/v1/projects/%d/bom-items", id), query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListProjects calls GET /api/v1/projects: list projects
+func (c *Client) ListProjects(ctx context.Context, params ListParams) (*services.ListPage[models.Project], error) {
+	query := params.Values()
+	var out services.ListPage[models.Project]
+	if err := c.do(ctx, "GET", "/api/v1/projects", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPurchaseOrders calls GET /api/v1/purchase-orders: list purchase orders
+func (c *Client) ListPurchaseOrders(ctx context.Context, params ListParams) (*services.ListPage[models.PurchaseOrder], error) {
+	query := params.Values()
+	var out services.ListPage[models.PurchaseOrder]
+	if err := c.do(ctx, "GET", "/api/v1/purchase-orders", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListQuotes calls GET /api/v1/quotes: list quotes
+func (c *Client) ListQuotes(ctx context.Context, params ListParams) (*services.ListPage[models.Quote], error) {
+	query := params.Values()
+	var out services.ListPage[models.Quote]
+	if err := c.do(ctx, "GET", "/api/v1/quotes", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListRequisitions calls GET /api/v1/requisitions: list requisitions
+func (c *Client) ListRequisitions(ctx context.Context, params ListParams) (*services.ListPage[models.Requisition], error) {
+	query := params.Values()
+	var out services.ListPage[models.Requisition]
+	if err := c.do(ctx, "GET", "/api/v1/requisitions", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSpecifications calls GET /api/v1/specifications: list specifications
+func (c *Client) ListSpecifications(ctx context.Context, params ListParams) (*services.ListPage[models.Specification], error) {
+	query := params.Values()
+	var out services.ListPage[models.Specification]
+	if err := c.do(ctx, "GET", "/api/v1/specifications", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListVendorRatings calls GET /api/v1/vendor-ratings: list vendor ratings
+func (c *Client) ListVendorRatings(ctx context.Context, params ListParams) (*services.ListPage[models.VendorRating], error) {
+	query := params.Values()
+	var out services.ListPage[models.VendorRating]
+	if err := c.do(ctx, "GET", "/api/v1/vendor-ratings", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListVendors calls GET /api/v1/vendors: list vendors
+func (c *Client) ListVendors(ctx context.Context, params ListParams) (*services.ListPage[models.Vendor], error) {
+	query := params.Values()
+	var out services.ListPage[models.Vendor]
+	if err := c.do(ctx, "GET", "/api/v1/vendors", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetProcurementStrategy calls POST /api/projects/{id}/procurement/strategy: set a project's procurement strategy
+func (c *Client) SetProcurementStrategy(ctx context.Context, id uint, body api.StrategyInput) (*models.ProjectProcurementStrategy, error) {
+	var out models.ProjectProcurementStrategy
+	if err := c.do(ctx, "POST", fmt.Sprintf("/api/projects/%d/procurement/strategy", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateBOMItem calls PUT /api/v1/bom-items/{id}: update a bill of materials item
+func (c *Client) UpdateBOMItem(ctx context.Context, id uint, body api.BOMItemInput) (*models.BillOfMaterialsItem, error) {
+	var out models.BillOfMaterialsItem
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/api/v1/bom-items/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateBrand calls PUT /api/v1/brands/{id}: update a brand
+func (c *Client) UpdateBrand(ctx context.Context, id uint, body api.BrandInput) (*models.Brand, error) {
+	var out models.Brand
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/api/v1/brands/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateDocument calls PUT /api/v1/documents/{id}: update a document
+func (c *Client) UpdateDocument(ctx context.Context, id uint, body api.DocumentInput) (*models.Document, error) {
+	var out models.Document
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/api/v1/documents/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateForexRate calls PUT /api/v1/forex/{id}: update a forex rate
+func (c *Client) UpdateForexRate(ctx context.Context, id uint, body api.ForexInput) (*models.Forex, error) {
+	var out models.Forex
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/api/v1/forex/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateProduct calls PUT /api/v1/products/{id}: update a product
+func (c *Client) UpdateProduct(ctx context.Context, id uint, body api.ProductInput) (*models.Product, error) {
+	var out models.Product
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/api/v1/products/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateProject calls PUT /api/v1/projects/{id}: update a project
+func (c *Client) UpdateProject(ctx context.Context, id uint, body api.ProjectInput) (*models.Project, error) {
+	var out models.Project
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/api/v1/projects/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdatePurchaseOrder calls PUT /api/v1/purchase-orders/{id}: update a purchase order
+func (c *Client) UpdatePurchaseOrder(ctx context.Context, id uint, body api.PurchaseOrderUpdate) (*models.PurchaseOrder, error) {
+	var out models.PurchaseOrder
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/api/v1/purchase-orders/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateQuote calls PUT /api/v1/quotes/{id}: update a quote
+func (c *Client) UpdateQuote(ctx context.Context, id uint, body api.QuoteInput) (*models.Quote, error) {
+	var out models.Quote
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/api/v1/quotes/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateRequisition calls PUT /api/v1/requisitions/{id}: update a requisition
+func (c *Client) UpdateRequisition(ctx context.Context, id uint, body api.RequisitionInput) (*models.Requisition, error) {
+	var out models.Requisition
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/api/v1/requisitions/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateSpecification calls PUT /api/v1/specifications/{id}: update a specification
+func (c *Client) UpdateSpecification(ctx context.Context, id uint, body api.SpecificationInput) (*models.Specification, error) {
+	var out models.Specification
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/api/v1/specifications/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateVendor calls PUT /api/v1/vendors/{id}: update a vendor
+func (c *Client) UpdateVendor(ctx context.Context, id uint, body api.VendorInput) (*models.Vendor, error) {
+	var out models.Vendor
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/api/v1/vendors/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateVendorRating calls PUT /api/v1/vendor-ratings/{id}: update a vendor rating
+func (c *Client) UpdateVendorRating(ctx context.Context, id uint, body api.VendorRatingInput) (*models.VendorRating, error) {
+	var out models.VendorRating
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/api/v1/vendor-ratings/%d", id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -453,13 +453,22 @@ var addProjectRequisitionCmd = &cobra.Command{
 }
 
 var addPurchaseOrderCmd = &cobra.Command{
-	Use:   "purchase-order --quote-id [id] --po-number [number] --quantity [qty]",
+	Use:   "purchase-order --quote-id [id] --quantity [qty]",
 	Short: "Add a new purchase order from a quote",
+	Long: `Add a new purchase order from a quote.
+
+Without --po-number the order is numbered automatically from the pattern in
+BUYER_PO_NUMBER_PATTERN (default ` + services.DefaultPONumberPattern + `).
+
+Without --cost-center or --gl-account the order takes the coding of its
+requisition, then of its project (see buyer coding set).`,
 	Run: func(cmd *cobra.Command, args []string) {
 		quoteID, _ := cmd.Flags().GetUint("quote-id")
 		poNumber, _ := cmd.Flags().GetString("po-number")
 		quantity, _ := cmd.Flags().GetInt("quantity")
 		requisitionID, _ := cmd.Flags().GetUint("requisition-id")
+		projectID, _ := cmd.Flags().GetUint("project-id")
+		projectItemID, _ := cmd.Flags().GetUint("project-requisition-item-id")
 		expectedDeliveryStr, _ := cmd.Flags().GetString("expected-delivery")
 		shippingCost, _ := cmd.Flags().GetFloat64("shipping-cost")
 		tax, _ := cmd.Flags().GetFloat64("tax")
@@ -469,10 +478,6 @@ var addPurchaseOrderCmd = &cobra.Command{
 			fmt.Fprintln(os.Stderr, "Error: --quote-id flag is required")
 			os.Exit(1)
 		}
-		if poNumber == "" {
-			fmt.Fprintln(os.Stderr, "Error: --po-number flag is required")
-			os.Exit(1)
-		}
 		if quantity == 0 {
 			fmt.Fprintln(os.Stderr, "Error: --quantity flag is required")
 			os.Exit(1)
@@ -492,17 +497,40 @@ var addPurchaseOrderCmd = &cobra.Command{
 		if requisitionID != 0 {
 			reqIDPtr = &requisitionID
 		}
+		var projectIDPtr *uint
+		if projectID != 0 {
+			projectIDPtr = &projectID
+		}
+		var projectItemIDPtr *uint
+		if projectItemID != 0 {
+			projectItemIDPtr = &projectItemID
+		}
+		coding, err := codingFromFlags(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		// An empty code leaves the order to its defaults
+		for _, id := range []**uint{&coding.CostCenterID, &coding.GLAccountID} {
+			if *id != nil && **id == 0 {
+				*id = nil
+			}
+		}
 
-		svc := services.NewPurchaseOrderService(cfg.DB)
+		svc := services.NewPurchaseOrderService(cfg.DB).WithPONumberPattern(poNumberPattern())
 		po, err := svc.Create(services.CreatePurchaseOrderInput{
-			QuoteID:          quoteID,
-			RequisitionID:    reqIDPtr,
-			PONumber:         poNumber,
-			Quantity:         quantity,
-			ExpectedDelivery: expectedDelivery,
-			ShippingCost:     shippingCost,
-			Tax:              tax,
-			Notes:            notes,
+			QuoteID:                  quoteID,
+			RequisitionID:            reqIDPtr,
+			ProjectRequisitionItemID: projectItemIDPtr,
+			ProjectID:                projectIDPtr,
+			PONumber:                 poNumber,
+			Quantity:                 quantity,
+			ExpectedDelivery:         expectedDelivery,
+			ShippingCost:             shippingCost,
+			Tax:                      tax,
+			Notes:                    notes,
+			CostCenterID:             coding.CostCenterID,
+			GLAccountID:              coding.GLAccountID,
 		})
 		if err != nil {
 			slog.Error("failed to create purchase order",
@@ -527,6 +555,12 @@ var addPurchaseOrderCmd = &cobra.Command{
 		if po.Product != nil {
 			fmt.Printf("  Product: %s\n", po.Product.Name)
 		}
+		if po.CostCenter != nil {
+			fmt.Printf("  Cost Center: %s\n", po.CostCenter.Code)
+		}
+		if po.GLAccount != nil {
+			fmt.Printf("  GL Account: %s\n", po.GLAccount.Code)
+		}
 		fmt.Printf("  Quantity: %d\n", po.Quantity)
 		fmt.Printf("  Unit Price: %.2f %s\n", po.UnitPrice, po.Currency)
 		fmt.Printf("  Total Amount: %.2f %s\n", po.TotalAmount, po.Currency)
@@ -743,13 +777,17 @@ func init() {
 
 	// Purchase Order flags
 	addPurchaseOrderCmd.Flags().Uint("quote-id", 0, "Quote ID (required)")
-	addPurchaseOrderCmd.Flags().String("po-number", "", "PO number (required)")
+	addPurchaseOrderCmd.Flags().String("po-number", "", "PO number (default: numbered automatically)")
 	addPurchaseOrderCmd.Flags().Int("quantity", 0, "Quantity (required)")
 	addPurchaseOrderCmd.Flags().Uint("requisition-id", 0, "Requisition ID (optional)")
+	addPurchaseOrderCmd.Flags().Uint("project-id", 0, "Project ID (optional)")
+	addPurchaseOrderCmd.Flags().Uint("project-requisition-item-id", 0, "Project requisition item the order fulfils (optional)")
 	addPurchaseOrderCmd.Flags().String("expected-delivery", "", "Expected delivery date (YYYY-MM-DD)")
 	addPurchaseOrderCmd.Flags().Float64("shipping-cost", 0, "Shipping cost")
 	addPurchaseOrderCmd.Flags().Float64("tax", 0, "Tax amount")
 	addPurchaseOrderCmd.Flags().String("notes", "", "Additional notes")
+	addPurchaseOrderCmd.Flags().String("cost-center", "", "Cost center code (default: from the requisition or project)")
+	addPurchaseOrderCmd.Flags().String("gl-account", "", "GL account code (default: from the requisition or project)")
 
 	// Forex flags
 	addForexCmd.Flags().String("from", "", "From currency code (required)")
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rodaine/table"
+	"github.com/shakfu/buyer/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var approvalsCmd = &cobra.Command{
+	Use:   "approvals",
+	Short: "Approve or reject purchase orders",
+	Long: `Approve or reject purchase orders under the approval policy.
+
+The policy is a list of rules. Each rule requires an approval step for
+purchase orders whose grand total, in the base currency (USD), is at least
+its minimum amount. Steps are approved in order, each by a different person,
+and approving the last step moves the order to approved. A rule for a project
+or a requisition replaces the general rules of its step for that project's or
+requisition's orders. A rule without approvers lets anyone decide; in the web
+interface that means anyone with the po:approve permission.
+
+Pending orders that need approvals cannot be moved on with
+'buyer update purchase-order --status' until they are approved. Orders no rule
+applies to work as before.
+
+Approvals from the CLI are recorded as BUYER_ACTOR, or the current system user.
+
+Examples:
+  buyer approvals rule add --min-amount 10000 --approvers alice,bob
+  buyer approvals rule add --step 2 --min-amount 50000 --approvers carol
+  buyer approvals list --mine
+  buyer approvals approve 12 --comment "Within budget"
+  buyer approvals reject 13 --comment "Get a second quote"`,
+}
+
+var approvalsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List purchase orders waiting for approval",
+	Run: func(cmd *cobra.Command, args []string) {
+		mine, _ := cmd.Flags().GetBool("mine")
+
+		approver := ""
+		if mine {
+			approver = cliActor()
+		}
+		queue, err := services.NewApprovalService(cfg.DB).Queue(approver)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(queue) == 0 {
+			fmt.Println("No purchase orders are waiting for approval.")
+			return
+		}
+
+		tbl := table.New("ID", "PO Number", "Vendor", "Product", "Total", "Base Amount", "Approved", "Next Step", "Approvers")
+		for _, approvals := range queue {
+			po := approvals.PurchaseOrder
+			vendorName := ""
+			if po.Vendor != nil {
+				vendorName = po.Vendor.Name
+			}
+			productName := ""
+			if po.Product != nil {
+				productName = po.Product.Name
+			}
+			next := approvals.NextStep()
+			tbl.AddRow(po.ID, po.PONumber, vendorName, productName,
+				fmt.Sprintf("%.2f %s", po.GrandTotal, po.Currency), fmt.Sprintf("%.2f", approvals.BaseAmount),
+				fmt.Sprintf("%d/%d", approvals.Approved(), len(approvals.Steps)), next.Step, approverList(next.Approvers))
+		}
+		tbl.Print()
+	},
+}
+
+var approvalsApproveCmd = &cobra.Command{
+	Use:   "approve [po-id]",
+	Short: "Approve the next step of a purchase order",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		comment, _ := cmd.Flags().GetString("comment")
+		decideApproval(args[0], comment, true)
+	},
+}
+
+var approvalsRejectCmd = &cobra.Command{
+	Use:   "reject [po-id]",
+	Short: "Reject a purchase order, with a comment explaining why",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		comment, _ := cmd.Flags().GetString("comment")
+		decideApproval(args[0], comment, false)
+	},
+}
+
+func decideApproval(idArg, comment string, approve bool) {
+	id, err := strconv.ParseUint(idArg, 10, 32)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid ID: %v\n", err)
+		os.Exit(1)
+	}
+
+	svc := services.NewApprovalService(cfg.DB)
+	decide := svc.Reject
+	if approve {
+		decide = svc.Approve
+	}
+	approvals, err := decide(uint(id), cliActor(), comment)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	po := approvals.PurchaseOrder
+	switch {
+	case approvals.Rejected():
+		fmt.Printf("Purchase order %s rejected.\n", po.PONumber)
+	case approvals.Complete():
+		fmt.Printf("Purchase order %s approved.\n", po.PONumber)
+	default:
+		next := approvals.NextStep()
+		fmt.Printf("Purchase order %s: %d of %d steps approved; step %d waits for %s.\n",
+			po.PONumber, approvals.Approved(), len(approvals.Steps), next.Step, approverList(next.Approvers))
+	}
+}
+
+var approvalsRuleCmd = &cobra.Command{
+	Use:   "rule",
+	Short: "Manage the approval policy",
+}
+
+var approvalsRuleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the approval rules",
+	Run: func(cmd *cobra.Command, args []string) {
+		rules, err := services.NewApprovalService(cfg.DB).ListRules()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(rules) == 0 {
+			fmt.Println("No approval rules; purchase orders need no approval.")
+			return
+		}
+
+		tbl := table.New("ID", "Step", "Min Amount", "Applies To", "Approvers")
+		for _, rule := range rules {
+			appliesTo := "all purchase orders"
+			if rule.Project != nil {
+				appliesTo = "project " + rule.Project.Name
+			} else if rule.Requisition != nil {
+				appliesTo = "requisition " + rule.Requisition.Name
+			}
+			tbl.AddRow(rule.ID, rule.Step, fmt.Sprintf("%.2f", rule.MinAmount), appliesTo,
+				approverList(strings.Split(rule.Approvers, ",")))
+		}
+		tbl.Print()
+	},
+}
+
+var approvalsRuleAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add an approval rule",
+	Run: func(cmd *cobra.Command, args []string) {
+		step, _ := cmd.Flags().GetInt("step")
+		minAmount, _ := cmd.Flags().GetFloat64("min-amount")
+		approvers, _ := cmd.Flags().GetStringSlice("approvers")
+		projectID, _ := cmd.Flags().GetUint("project-id")
+		requisitionID, _ := cmd.Flags().GetUint("requisition-id")
+
+		input := services.ApprovalRuleInput{Step: step, MinAmount: minAmount, Approvers: approvers}
+		if projectID != 0 {
+			input.ProjectID = &projectID
+		}
+		if requisitionID != 0 {
+			input.RequisitionID = &requisitionID
+		}
+		rule, err := services.NewApprovalService(cfg.DB).CreateRule(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Approval rule created (ID: %d): step %d from %.2f\n", rule.ID, rule.Step, rule.MinAmount)
+	},
+}
+
+var approvalsRuleRemoveCmd = &cobra.Command{
+	Use:   "remove [id]",
+	Short: "Remove an approval rule",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid ID: %v\n", err)
+			os.Exit(1)
+		}
+		if err := services.NewApprovalService(cfg.DB).DeleteRule(uint(id)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Approval rule removed (ID: %d)\n", id)
+	},
+}
+
+// approverList describes the approvers of a step
+func approverList(approvers []string) string {
+	var names []string
+	for _, approver := range approvers {
+		if approver = strings.TrimSpace(approver); approver != "" {
+			names = append(names, approver)
+		}
+	}
+	if len(names) == 0 {
+		return "any approver"
+	}
+	return strings.Join(names, ", ")
+}
+
+func init() {
+	approvalsCmd.AddCommand(approvalsListCmd)
+	approvalsCmd.AddCommand(approvalsApproveCmd)
+	approvalsCmd.AddCommand(approvalsRejectCmd)
+	approvalsCmd.AddCommand(approvalsRuleCmd)
+	approvalsRuleCmd.AddCommand(approvalsRuleListCmd)
+	approvalsRuleCmd.AddCommand(approvalsRuleAddCmd)
+	approvalsRuleCmd.AddCommand(approvalsRuleRemoveCmd)
+
+	approvalsListCmd.Flags().Bool("mine", false, "Only orders whose next step BUYER_ACTOR may approve")
+	approvalsApproveCmd.Flags().String("comment", "", "Comment recorded with the approval")
+	approvalsRejectCmd.Flags().String("comment", "", "Reason for the rejection (required)")
+
+	approvalsRuleAddCmd.Flags().Int("step", 1, "Approval step; steps are approved in order")
+	approvalsRuleAddCmd.Flags().Float64("min-amount", 0, "Minimum grand total in the base currency (USD)")
+	approvalsRuleAddCmd.Flags().StringSlice("approvers", nil, "Usernames who may approve (comma-separated; default: any approver)")
+	approvalsRuleAddCmd.Flags().Uint("project-id", 0, "Only for purchase orders of this project")
+	approvalsRuleAddCmd.Flags().Uint("requisition-id", 0, "Only for purchase orders of this requisition")
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rodaine/table"
+	"github.com/shakfu/buyer/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the audit log of data changes",
+	Long: `Show recorded creates, updates and deletes, newest first, one row per
+changed field.
+
+Every change made through the CLI, the web UI or the JSON API is recorded with
+the user who made it and where it came from (cli, web or api). CLI changes are
+recorded as BUYER_ACTOR, or the current system user when it is not set.
+
+Examples:
+  buyer audit --entity quote --id 12
+  buyer audit --entity purchase-order --limit 20
+  buyer audit --actor alice --source web`,
+	Run: func(cmd *cobra.Command, args []string) {
+		entity, _ := cmd.Flags().GetString("entity")
+		id, _ := cmd.Flags().GetUint("id")
+		actor, _ := cmd.Flags().GetString("actor")
+		source, _ := cmd.Flags().GetString("source")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		if id != 0 && entity == "" {
+			fmt.Fprintln(os.Stderr, "Error: --id requires --entity")
+			os.Exit(1)
+		}
+
+		entries, err := services.NewAuditService(cfg.DB).List(services.AuditFilter{
+			Entity:   entity,
+			EntityID: id,
+			Actor:    actor,
+			Source:   strings.ToLower(source),
+			Limit:    limit,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(entries) == 0 {
+			fmt.Println("No changes recorded.")
+			return
+		}
+
+		tbl := table.New("Time", "Entity", "ID", "Action", "Actor", "Source", "Field", "Old", "New")
+		for _, entry := range entries {
+			when := entry.CreatedAt.Format("2006-01-02 15:04:05")
+			actor := entry.Actor
+			if actor == "" {
+				actor = "-"
+			}
+			if len(entry.Fields) == 0 {
+				tbl.AddRow(when, entry.Entity, entry.EntityID, entry.Action, actor, entry.Source, "", "", "")
+				continue
+			}
+			for i, field := range entry.Fields {
+				if i == 0 {
+					tbl.AddRow(when, entry.Entity, entry.EntityID, entry.Action, actor, entry.Source, field.Field, field.Old, field.New)
+				} else {
+					tbl.AddRow("", "", "", "", "", "", field.Field, field.Old, field.New)
+				}
+			}
+		}
+		tbl.Print()
+	},
+}
+
+func init() {
+	auditCmd.Flags().String("entity", "", "Entity to show, such as quote, product, vendor or purchase-order")
+	auditCmd.Flags().Uint("id", 0, "ID of the record to show (requires --entity)")
+	auditCmd.Flags().String("actor", "", "Only changes made by this user")
+	auditCmd.Flags().String("source", "", "Only changes from this source: cli, web or api")
+	auditCmd.Flags().Int("limit", 100, "Maximum number of changes to show (0 for all)")
+}
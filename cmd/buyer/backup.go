@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/shakfu/buyer/internal/services"
+	"github.com/shakfu/buyer/internal/storage"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup [file]",
+	Short: "Back up the database to a portable archive",
+	Long: `Write a complete backup of the database to a zip archive.
+
+The archive holds a manifest, one JSON file per table and the files
+referenced by documents, each with a SHA-256 checksum. Archives are
+independent of the database engine: a backup taken from SQLite can be
+restored into PostgreSQL and vice versa.
+
+Without a file argument the archive is written to --dir with a
+timestamped name, and --keep removes all but the newest archives there.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		skipFiles, _ := cmd.Flags().GetBool("no-files")
+		opts := services.BackupOptions{AppVersion: Version, SkipFiles: skipFiles}
+		store, err := documentStorage()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening document storage: %v\n", err)
+			os.Exit(1)
+		}
+		svc := services.NewBackupService(cfg.DB).WithStorage(store)
+
+		var target string
+		var manifest *services.BackupManifest
+		if len(args) == 1 {
+			target = args[0]
+			file, err := os.Create(target)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating backup file: %v\n", err)
+				os.Exit(1)
+			}
+			manifest, err = svc.Backup(file, opts)
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				_ = os.Remove(target)
+				fmt.Fprintf(os.Stderr, "Error creating backup: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			dir, _ := cmd.Flags().GetString("dir")
+			keep, _ := cmd.Flags().GetInt("keep")
+			target, manifest, err = svc.BackupToDir(dir, opts)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating backup: %v\n", err)
+				os.Exit(1)
+			}
+			removed, err := services.PruneBackups(dir, keep)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error pruning old backups: %v\n", err)
+				os.Exit(1)
+			}
+			for _, old := range removed {
+				fmt.Printf("Removed old backup: %s\n", old)
+			}
+		}
+
+		fmt.Printf("Backup written to %s\n", target)
+		printBackupManifest(manifest)
+	},
+}
+
+var backupVerifyCmd = &cobra.Command{
+	Use:   "verify [file]",
+	Short: "Check a backup archive's checksums and versions",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file, info, err := openBackupFile(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+
+		manifest, err := services.NewBackupService(cfg.DB).Verify(file, info.Size())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Backup is not valid: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Backup %s is valid\n", args[0])
+		printBackupManifest(manifest)
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [file] | restore [entity] [id]",
+	Short: "Restore the database from a backup archive, or a record from the trash",
+	Long: `With an entity and an ID, take a deleted record out of the trash, along with
+the records deleted with it (see 'buyer trash').
+
+With a file, restore a backup archive written by 'buyer backup' into the configured
+database (SQLite, or PostgreSQL when DATABASE_URL or BUYER_DB_HOST is set).
+
+IDs and relationships are preserved. Every checksum is verified before
+anything is written, archives from a newer schema are refused, and the
+whole restore runs in one transaction.
+
+The target database must be empty unless --force is given, in which case
+all existing data is replaced. Document files are written back to the
+document storage selected by DOCUMENT_STORAGE_TYPE, or below --files-dir.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 2 {
+			restoreFromTrash(args[0], args[1])
+			return
+		}
+
+		force, _ := cmd.Flags().GetBool("force")
+		filesDir, _ := cmd.Flags().GetString("files-dir")
+
+		file, info, err := openBackupFile(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+
+		store, err := documentStorage()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening document storage: %v\n", err)
+			os.Exit(1)
+		}
+		result, err := services.NewBackupService(cfg.DB).WithStorage(store).Restore(file, info.Size(), services.RestoreOptions{
+			Force:    force,
+			FilesDir: filesDir,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error restoring backup: %v\n", err)
+			os.Exit(1)
+		}
+
+		total := 0
+		for _, n := range result.Rows {
+			total += n
+		}
+		fmt.Printf("Restored %d rows in %d tables and %d files from %s\n", total, len(result.Rows), result.Files, args[0])
+		for _, warning := range result.Warnings {
+			fmt.Printf("  Warning: %s\n", warning)
+		}
+	},
+}
+
+// openBackupFile opens an archive for random access
+func openBackupFile(name string) (*os.File, os.FileInfo, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+	return file, info, nil
+}
+
+// printBackupManifest prints an archive's versions and contents
+func printBackupManifest(manifest *services.BackupManifest) {
+	rows := 0
+	for _, t := range manifest.Tables {
+		rows += t.Rows
+	}
+	fmt.Printf("  Created:        %s\n", manifest.CreatedAt.Format(time.RFC3339))
+	fmt.Printf("  Source:         %s (buyer %s)\n", manifest.Driver, manifest.AppVersion)
+	fmt.Printf("  Schema version: %d\n", manifest.SchemaVersion)
+	fmt.Printf("  Tables:         %d (%d rows)\n", len(manifest.Tables), rows)
+	fmt.Printf("  Files:          %d\n", len(manifest.Files))
+	for _, missing := range manifest.MissingFiles {
+		fmt.Printf("  Warning: document file not found: %s\n", missing)
+	}
+}
+
+// defaultBackupDir returns ~/.buyer/backups, next to the default database
+func defaultBackupDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "backups"
+	}
+	return filepath.Join(home, ".buyer", "backups")
+}
+
+// backupScheduleFromFlags reads the web command's backup flags, falling back to the
+// BUYER_BACKUP_INTERVAL, BUYER_BACKUP_DIR and BUYER_BACKUP_KEEP environment variables
+func backupScheduleFromFlags(cmd *cobra.Command) (time.Duration, string, int, error) {
+	interval, _ := cmd.Flags().GetDuration("backup-interval")
+	dir, _ := cmd.Flags().GetString("backup-dir")
+	keep, _ := cmd.Flags().GetInt("backup-keep")
+
+	if !cmd.Flags().Changed("backup-interval") {
+		if val := os.Getenv("BUYER_BACKUP_INTERVAL"); val != "" {
+			parsed, err := time.ParseDuration(val)
+			if err != nil {
+				return 0, "", 0, fmt.Errorf("invalid BUYER_BACKUP_INTERVAL %q: %w", val, err)
+			}
+			interval = parsed
+		}
+	}
+	if dir == "" {
+		dir = os.Getenv("BUYER_BACKUP_DIR")
+	}
+	if dir == "" {
+		dir = defaultBackupDir()
+	}
+	if !cmd.Flags().Changed("backup-keep") {
+		if val := os.Getenv("BUYER_BACKUP_KEEP"); val != "" {
+			parsed, err := strconv.Atoi(val)
+			if err != nil {
+				return 0, "", 0, fmt.Errorf("invalid BUYER_BACKUP_KEEP %q: %w", val, err)
+			}
+			keep = parsed
+		}
+	}
+	if interval < 0 {
+		return 0, "", 0, fmt.Errorf("backup interval must not be negative")
+	}
+	return interval, dir, keep, nil
+}
+
+// startBackupScheduler writes a backup, with the document files in store, to
+// dir every interval, keeping the newest keep archives, until the returned
+// stop function is called
+func startBackupScheduler(db *gorm.DB, store storage.StorageBackend, interval time.Duration, dir string, keep int) func() {
+	svc := services.NewBackupService(db).WithStorage(store)
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				path, _, err := svc.BackupToDir(dir, services.BackupOptions{AppVersion: Version})
+				if err != nil {
+					slog.Error("scheduled backup failed", slog.String("error", err.Error()))
+					continue
+				}
+				slog.Info("scheduled backup written", slog.String("path", path))
+				removed, err := services.PruneBackups(dir, keep)
+				if err != nil {
+					slog.Error("failed to prune old backups", slog.String("error", err.Error()))
+				}
+				for _, old := range removed {
+					slog.Debug("removed old backup", slog.String("path", old))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+func init() {
+	backupCmd.AddCommand(backupVerifyCmd)
+
+	backupCmd.Flags().String("dir", defaultBackupDir(), "Directory for timestamped backups when no file is given")
+	backupCmd.Flags().Int("keep", 0, "Keep only the newest N backups in --dir (0 keeps all)")
+	backupCmd.Flags().Bool("no-files", false, "Do not include document files")
+
+	restoreCmd.Flags().Bool("force", false, "Replace existing data in the target database")
+	restoreCmd.Flags().String("files-dir", "", "Restore document files below this directory instead of the document storage")
+}
@@ -18,27 +18,7 @@ func setupTestDB(t *testing.T) *config.Config {
 	}
 
 	// Run auto-migration
-	if err := testCfg.AutoMigrate(
-		&models.Vendor{},
-		&models.Brand{},
-		&models.Specification{},
-		&models.SpecificationAttribute{},
-		&models.Product{},
-		&models.ProductAttribute{},
-		&models.Requisition{},
-		&models.RequisitionItem{},
-		&models.Quote{},
-		&models.Forex{},
-		&models.Project{},
-		&models.BillOfMaterials{},
-		&models.BillOfMaterialsItem{},
-		&models.ProjectRequisition{},
-		&models.ProjectRequisitionItem{},
-		&models.ProjectProcurementStrategy{},
-		&models.PurchaseOrder{},
-		&models.Document{},
-		&models.VendorRating{},
-	); err != nil {
+	if err := testCfg.AutoMigrate(models.All()...); err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
 
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rodaine/table"
+	"github.com/shakfu/buyer/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var costCenterCmd = &cobra.Command{
+	Use:   "cost-center",
+	Short: "Manage cost centers and report spend per cost center",
+}
+
+var glAccountCmd = &cobra.Command{
+	Use:   "gl-account",
+	Short: "Manage general-ledger accounts",
+}
+
+var codingCmd = &cobra.Command{
+	Use:   "coding",
+	Short: "Code requisitions, projects and purchase orders to cost centers and GL accounts",
+}
+
+// accountingCode is a cost center or GL account as the commands show it
+type accountingCode struct {
+	ID         uint
+	Code, Name string
+	Active     bool
+}
+
+// accountingCodeCommands returns the add, list, update and delete commands
+// of cost centers or GL accounts
+func accountingCodeCommands(noun string,
+	create func(code, name string) (uint, error),
+	list func(activeOnly bool) ([]accountingCode, error),
+	getByCode func(code string) (*accountingCode, error),
+	update func(id uint, name string, active bool) error,
+	remove func(id uint) error,
+) []*cobra.Command {
+	add := &cobra.Command{
+		Use:   "add <code> <name>",
+		Short: "Add a " + noun,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := create(args[0], args[1])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Created %s %s (ID: %d)\n", noun, args[0], id)
+			return nil
+		},
+	}
+
+	ls := &cobra.Command{
+		Use:   "list",
+		Short: "List " + noun + "s",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			activeOnly, _ := cmd.Flags().GetBool("active")
+			codes, err := list(activeOnly)
+			if err != nil {
+				return err
+			}
+			if len(codes) == 0 {
+				fmt.Printf("No %ss found.\n", noun)
+				return nil
+			}
+			tbl := table.New("ID", "Code", "Name", "Status")
+			for _, code := range codes {
+				status := "active"
+				if !code.Active {
+					status = "inactive"
+				}
+				tbl.AddRow(code.ID, code.Code, code.Name, status)
+			}
+			tbl.Print()
+			return nil
+		},
+	}
+	ls.Flags().Bool("active", false, "Only list active "+noun+"s")
+
+	upd := &cobra.Command{
+		Use:   "update <code>",
+		Short: "Rename, activate or deactivate a " + noun,
+		Long: "Rename, activate or deactivate a " + noun + ". Orders already coded to an\n" +
+			"inactive " + noun + " keep it, but it cannot be used for new coding.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code, err := getByCode(args[0])
+			if err != nil {
+				return err
+			}
+			name, active := code.Name, code.Active
+			if cmd.Flags().Changed("name") {
+				name, _ = cmd.Flags().GetString("name")
+			}
+			if cmd.Flags().Changed("active") {
+				active, _ = cmd.Flags().GetBool("active")
+			}
+			if err := update(code.ID, name, active); err != nil {
+				return err
+			}
+			fmt.Printf("Updated %s %s\n", noun, code.Code)
+			return nil
+		},
+	}
+	upd.Flags().String("name", "", "New name")
+	upd.Flags().Bool("active", true, "Whether the "+noun+" can be used for new coding")
+
+	del := &cobra.Command{
+		Use:   "delete <code>",
+		Short: "Delete a " + noun + " that no purchase order uses",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code, err := getByCode(args[0])
+			if err != nil {
+				return err
+			}
+			force, _ := cmd.Flags().GetBool("force")
+			if !force && !confirmDelete(noun, code.ID) {
+				fmt.Println("Deletion cancelled.")
+				return nil
+			}
+			if err := remove(code.ID); err != nil {
+				return err
+			}
+			fmt.Printf("Deleted %s %s\n", noun, code.Code)
+			return nil
+		},
+	}
+	del.Flags().BoolP("force", "f", false, "Skip confirmation prompt")
+
+	return []*cobra.Command{add, ls, upd, del}
+}
+
+var costCenterSpendCmd = &cobra.Command{
+	Use:   "spend",
+	Short: "Show committed, received and invoiced spend per cost center",
+	Long: `Show the spend of purchase orders per cost center, in USD.
+
+Committed spend is every order that is not cancelled or rejected, received
+spend the received orders, and invoiced spend the orders with an invoice
+number. Orders without a cost center are listed last.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spend, err := services.NewDashboardService(cfg.DB).GetSpendByCostCenter()
+		if err != nil {
+			return err
+		}
+		if len(spend) == 0 {
+			fmt.Println("No purchase orders found.")
+			return nil
+		}
+		tbl := table.New("Cost Center", "Name", "Orders", "Committed", "Received", "Invoiced")
+		for _, row := range spend {
+			code, name := "-", "No cost center"
+			if row.CostCenter != nil {
+				code, name = row.CostCenter.Code, row.CostCenter.Name
+			}
+			tbl.AddRow(code, name, row.Orders,
+				fmt.Sprintf("$%.2f", row.Committed), fmt.Sprintf("$%.2f", row.Received), fmt.Sprintf("$%.2f", row.Invoiced))
+		}
+		tbl.Print()
+		return nil
+	},
+}
+
+var codingSetCmd = &cobra.Command{
+	Use:   "set (--requisition ID | --project ID | --purchase-order ID) [--cost-center CODE] [--gl-account CODE]",
+	Short: "Set the cost center and GL account of a requisition, project or purchase order",
+	Long: `Set the cost center and GL account of a requisition, project or purchase
+order. An empty code clears it; a flag left out keeps the current value.
+
+Requisitions and projects hold the default coding of their purchase orders:
+an order takes its requisition's coding, then its project's, unless it sets
+its own. Once any cost center exists, an order needs one before it can be
+approved; the same goes for GL accounts.
+
+Examples:
+  buyer coding set --project 3 --cost-center ENG --gl-account 6100
+  buyer coding set --purchase-order 12 --cost-center OPS
+  buyer coding set --requisition 5 --gl-account ""`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		coding, err := codingFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if coding.CostCenterID == nil && coding.GLAccountID == nil {
+			return fmt.Errorf("give --cost-center, --gl-account or both")
+		}
+
+		requisitionID, _ := cmd.Flags().GetUint("requisition")
+		projectID, _ := cmd.Flags().GetUint("project")
+		poID, _ := cmd.Flags().GetUint("purchase-order")
+		switch {
+		case requisitionID != 0 && projectID == 0 && poID == 0:
+			requisition, err := services.NewRequisitionService(cfg.DB).SetCoding(requisitionID, coding)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Requisition %s: %s\n", requisition.Name, describeCoding(requisition.CostCenterID, requisition.GLAccountID))
+		case projectID != 0 && requisitionID == 0 && poID == 0:
+			project, err := services.NewProjectService(cfg.DB).SetCoding(projectID, coding)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Project %s: %s\n", project.Name, describeCoding(project.CostCenterID, project.GLAccountID))
+		case poID != 0 && requisitionID == 0 && projectID == 0:
+			po, err := services.NewPurchaseOrderService(cfg.DB).SetCoding(poID, coding)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Purchase order %s: %s\n", po.PONumber, describeCoding(po.CostCenterID, po.GLAccountID))
+		default:
+			return fmt.Errorf("give exactly one of --requisition, --project and --purchase-order")
+		}
+		return nil
+	},
+}
+
+// codingFromFlags resolves the --cost-center and --gl-account codes of a
+// command. A flag left out is nil and an empty code is 0, which clears it.
+func codingFromFlags(cmd *cobra.Command) (services.Coding, error) {
+	var coding services.Coding
+	zero := uint(0)
+	if cmd.Flags().Changed("cost-center") {
+		code, _ := cmd.Flags().GetString("cost-center")
+		coding.CostCenterID = &zero
+		if code != "" {
+			center, err := services.NewCostCenterService(cfg.DB).GetByCode(code)
+			if err != nil {
+				return coding, err
+			}
+			coding.CostCenterID = &center.ID
+		}
+	}
+	if cmd.Flags().Changed("gl-account") {
+		code, _ := cmd.Flags().GetString("gl-account")
+		coding.GLAccountID = &zero
+		if code != "" {
+			account, err := services.NewGLAccountService(cfg.DB).GetByCode(code)
+			if err != nil {
+				return coding, err
+			}
+			coding.GLAccountID = &account.ID
+		}
+	}
+	return coding, nil
+}
+
+// describeCoding names the cost center and GL account with the given IDs
+func describeCoding(costCenterID, glAccountID *uint) string {
+	costCenter, glAccount := "no cost center", "no GL account"
+	if costCenterID != nil {
+		if center, err := services.NewCostCenterService(cfg.DB).GetByID(*costCenterID); err == nil {
+			costCenter = "cost center " + center.Code
+		}
+	}
+	if glAccountID != nil {
+		if account, err := services.NewGLAccountService(cfg.DB).GetByID(*glAccountID); err == nil {
+			glAccount = "GL account " + account.Code
+		}
+	}
+	return costCenter + ", " + glAccount
+}
+
+func init() {
+	costCenterCmd.AddCommand(accountingCodeCommands("cost center",
+		func(code, name string) (uint, error) {
+			center, err := services.NewCostCenterService(cfg.DB).Create(code, name)
+			if err != nil {
+				return 0, err
+			}
+			return center.ID, nil
+		},
+		func(activeOnly bool) ([]accountingCode, error) {
+			centers, err := services.NewCostCenterService(cfg.DB).List(activeOnly)
+			codes := make([]accountingCode, len(centers))
+			for i, center := range centers {
+				codes[i] = accountingCode{center.ID, center.Code, center.Name, center.Active}
+			}
+			return codes, err
+		},
+		func(code string) (*accountingCode, error) {
+			center, err := services.NewCostCenterService(cfg.DB).GetByCode(code)
+			if err != nil {
+				return nil, err
+			}
+			return &accountingCode{center.ID, center.Code, center.Name, center.Active}, nil
+		},
+		func(id uint, name string, active bool) error {
+			_, err := services.NewCostCenterService(cfg.DB).Update(id, name, active)
+			return err
+		},
+		func(id uint) error {
+			return services.NewCostCenterService(cfg.DB).Delete(id)
+		},
+	)...)
+	costCenterCmd.AddCommand(costCenterSpendCmd)
+
+	glAccountCmd.AddCommand(accountingCodeCommands("GL account",
+		func(code, name string) (uint, error) {
+			account, err := services.NewGLAccountService(cfg.DB).Create(code, name)
+			if err != nil {
+				return 0, err
+			}
+			return account.ID, nil
+		},
+		func(activeOnly bool) ([]accountingCode, error) {
+			accounts, err := services.NewGLAccountService(cfg.DB).List(activeOnly)
+			codes := make([]accountingCode, len(accounts))
+			for i, account := range accounts {
+				codes[i] = accountingCode{account.ID, account.Code, account.Name, account.Active}
+			}
+			return codes, err
+		},
+		func(code string) (*accountingCode, error) {
+			account, err := services.NewGLAccountService(cfg.DB).GetByCode(code)
+			if err != nil {
+				return nil, err
+			}
+			return &accountingCode{account.ID, account.Code, account.Name, account.Active}, nil
+		},
+		func(id uint, name string, active bool) error {
+			_, err := services.NewGLAccountService(cfg.DB).Update(id, name, active)
+			return err
+		},
+		func(id uint) error {
+			return services.NewGLAccountService(cfg.DB).Delete(id)
+		},
+	)...)
+
+	codingSetCmd.Flags().Uint("requisition", 0, "Requisition ID")
+	codingSetCmd.Flags().Uint("project", 0, "Project ID")
+	codingSetCmd.Flags().Uint("purchase-order", 0, "Purchase order ID")
+	codingSetCmd.Flags().String("cost-center", "", "Cost center code (empty clears it)")
+	codingSetCmd.Flags().String("gl-account", "", "GL account code (empty clears it)")
+	codingCmd.AddCommand(codingSetCmd)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rodaine/table"
+	"github.com/shakfu/buyer/internal/config"
+	"github.com/shakfu/buyer/internal/migrations"
+	"github.com/shakfu/buyer/internal/services"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Database maintenance commands",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Database commands open the databases they are given
+	},
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply schema migrations, or copy data between databases",
+	Long: `Without --from and --to, apply all pending schema migrations to the
+configured database. Other commands refuse to run until the schema is up
+to date. Back up first with 'buyer backup'.
+
+With --from and --to, copy every table from one database to another, for
+example from the default SQLite file to a shared PostgreSQL server:
+
+  buyer db migrate --from sqlite:~/.buyer/buyer.db \
+                   --to postgres:"host=db.example.com user=buyer dbname=buyer"
+
+Databases are given as sqlite:PATH or postgres:URL (postgres:// URLs may be
+given as is). The source must be at the current schema version; the target
+schema is created if needed. Tables are copied in dependency order in
+committed batches, keeping IDs, and PostgreSQL sequences are reset
+afterwards. Row counts and foreign keys are then verified.
+
+The copy target must be empty. If a copy is interrupted, run the same
+command with --resume to copy only the remaining rows.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+		resume, _ := cmd.Flags().GetBool("resume")
+		batchSize, _ := cmd.Flags().GetInt("batch-size")
+
+		if from == "" && to == "" {
+			runSchemaMigrate()
+			return
+		}
+		if from == "" || to == "" {
+			fmt.Fprintln(os.Stderr, "Error: --from and --to must be given together")
+			os.Exit(1)
+		}
+
+		src, err := openDatabaseSpec(from, true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening source database: %v\n", err)
+			os.Exit(1)
+		}
+		dst, err := openDatabaseSpec(to, false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening target database: %v\n", err)
+			os.Exit(1)
+		}
+
+		svc := services.NewDatabaseCopyService(src, dst)
+		result, err := svc.Copy(services.CopyOptions{
+			Resume:    resume,
+			BatchSize: batchSize,
+			Progress: func(name string, copied, total int64) {
+				fmt.Printf("\r  %-32s %d/%d", name, copied, total)
+				if copied >= total {
+					fmt.Println()
+				}
+			},
+		})
+
+		if err != nil && (result == nil || len(result.Problems) == 0) {
+			fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+			var validationErr *services.ValidationError
+			if !errors.As(err, &validationErr) {
+				fmt.Fprintln(os.Stderr, "Rows copied so far were kept; rerun with --resume to continue.")
+			}
+			os.Exit(1)
+		}
+
+		fmt.Println()
+		tbl := table.New("Table", "Source", "Copied", "Target")
+		for _, t := range result.Tables {
+			tbl.AddRow(t.Name, t.SourceRows, t.Copied, t.TargetRows)
+		}
+		tbl.Print()
+		for _, warning := range result.Warnings {
+			fmt.Printf("Warning: %s\n", warning)
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "\nVerification failed:")
+			for _, problem := range result.Problems {
+				fmt.Fprintf(os.Stderr, "  - %s\n", problem)
+			}
+			os.Exit(1)
+		}
+
+		fmt.Println("\nCopy complete: row counts and foreign keys verified")
+	},
+}
+
+var dbStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending schema migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		migrator := openMigrator()
+		statuses, err := migrator.Status()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		current := 0
+		tbl := table.New("Version", "Name", "Applied")
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+				current = status.Version
+			}
+			tbl.AddRow(fmt.Sprintf("%04d", status.Version), status.Name, applied)
+		}
+		tbl.Print()
+
+		fmt.Printf("\nSchema version %d of %d", current, migrator.Latest())
+		if current < migrator.Latest() {
+			fmt.Print(" - run 'buyer db migrate' to apply pending migrations")
+		}
+		fmt.Println()
+	},
+}
+
+var dbRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Revert the most recent schema migrations",
+	Long: `Revert the most recent schema migrations by running their down scripts,
+newest first. Columns and tables added by those migrations are dropped
+along with their data, so back up first with 'buyer backup'.
+
+Rolling back the baseline migration drops every table and requires --force.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		steps, _ := cmd.Flags().GetInt("steps")
+		force, _ := cmd.Flags().GetBool("force")
+		if steps < 1 {
+			fmt.Fprintln(os.Stderr, "Error: --steps must be at least 1")
+			os.Exit(1)
+		}
+
+		migrator := openMigrator()
+		current, err := migrator.Current()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if current == 0 {
+			fmt.Println("No migrations to roll back.")
+			return
+		}
+		if steps >= current && !force {
+			fmt.Fprintln(os.Stderr, "Error: this would roll back the baseline migration and drop every table; use --force to confirm")
+			os.Exit(1)
+		}
+
+		rolledBack, err := migrator.Rollback(steps)
+		for _, migration := range rolledBack {
+			fmt.Printf("Rolled back %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// runSchemaMigrate applies pending migrations to the configured database
+func runSchemaMigrate() {
+	migrator := openMigrator()
+	applied, err := migrator.Up()
+	for _, migration := range applied {
+		fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(applied) == 0 {
+		fmt.Printf("Schema is up to date (version %d)\n", migrator.Latest())
+		return
+	}
+	fmt.Printf("Schema migrated to version %d\n", migrator.Latest())
+}
+
+// openMigrator opens the configured database, without the schema check that
+// other commands perform, and returns its migrator
+func openMigrator() *migrations.Migrator {
+	openConfig()
+	migrator, err := migrations.NewMigrator(cfg.DB)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return migrator
+}
+
+// openDatabaseSpec opens a database given as sqlite:PATH or postgres:URL. When
+// mustExist is set, a missing SQLite file is an error rather than created empty.
+func openDatabaseSpec(spec string, mustExist bool) (*gorm.DB, error) {
+	driver, dsn, err := config.ParseDatabaseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if driver == "sqlite" {
+		dsn = expandHome(dsn)
+		if mustExist {
+			if _, err := os.Stat(dsn); err != nil {
+				return nil, err
+			}
+		}
+	}
+	logLevel := logger.Silent
+	if verbose {
+		logLevel = logger.Info
+	}
+	return config.OpenDatabase(driver, dsn, logLevel)
+}
+
+// expandHome replaces a leading ~/ with the user's home directory
+func expandHome(path string) string {
+	if len(path) < 2 || path[:2] != "~/" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return home + path[1:]
+}
+
+func init() {
+	dbCmd.AddCommand(dbMigrateCmd)
+	dbCmd.AddCommand(dbStatusCmd)
+	dbCmd.AddCommand(dbRollbackCmd)
+
+	dbMigrateCmd.Flags().String("from", "", "Copy data from this database (sqlite:PATH or postgres:URL)")
+	dbMigrateCmd.Flags().String("to", "", "Copy data to this database (sqlite:PATH or postgres:URL)")
+	dbMigrateCmd.Flags().Bool("resume", false, "Continue an interrupted copy into a non-empty target")
+	dbMigrateCmd.Flags().Int("batch-size", services.DefaultCopyBatchSize, "Rows copied per transaction")
+
+	dbRollbackCmd.Flags().Int("steps", 1, "Number of migrations to roll back")
+	dbRollbackCmd.Flags().Bool("force", false, "Allow rolling back the baseline migration")
+}
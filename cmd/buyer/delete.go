@@ -40,7 +40,7 @@ var deleteSpecificationCmd = &cobra.Command{
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Printf("Specification ID %d deleted successfully.\n", id)
+		fmt.Printf("Specification ID %d moved to the trash.\n", id)
 	},
 }
 
@@ -67,7 +67,7 @@ var deleteRequisitionCmd = &cobra.Command{
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Printf("Requisition ID %d deleted successfully.\n", id)
+		fmt.Printf("Requisition ID %d moved to the trash.\n", id)
 	},
 }
 
@@ -122,7 +122,7 @@ var deleteBrandCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		fmt.Printf("Brand deleted (ID: %d)\n", id)
+		fmt.Printf("Brand moved to the trash (ID: %d)\n", id)
 	},
 }
 
@@ -150,7 +150,7 @@ var deleteProductCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		fmt.Printf("Product deleted (ID: %d)\n", id)
+		fmt.Printf("Product and its quotes moved to the trash (ID: %d)\n", id)
 	},
 }
 
@@ -178,7 +178,7 @@ var deleteVendorCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		fmt.Printf("Vendor deleted (ID: %d)\n", id)
+		fmt.Printf("Vendor and its ratings moved to the trash (ID: %d)\n", id)
 	},
 }
 
@@ -206,7 +206,7 @@ var deleteQuoteCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		fmt.Printf("Quote deleted (ID: %d)\n", id)
+		fmt.Printf("Quote moved to the trash (ID: %d)\n", id)
 	},
 }
 
@@ -262,7 +262,7 @@ var deleteProjectCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		fmt.Printf("Project deleted (ID: %d)\n", id)
+		fmt.Printf("Project moved to the trash (ID: %d)\n", id)
 	},
 }
 
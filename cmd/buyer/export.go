@@ -10,8 +10,9 @@ import (
 
 var exportCmd = &cobra.Command{
 	Use:   "export",
-	Short: "Export data to CSV or Excel",
-	Long:  `Export brands, vendors, products, quotes, or forex rates to CSV or Excel files.`,
+	Short: "Export data to CSV, Excel or an accounting package",
+	Long: `Export brands, vendors, products, quotes, or forex rates to CSV or Excel files,
+or invoices and received purchase orders to an accounting package.`,
 }
 
 var exportBrandsCmd = &cobra.Command{
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rodaine/table"
+	"github.com/shakfu/buyer/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var exportAccountingCmd = &cobra.Command{
+	Use:   "accounting --format FORMAT --from YYYY-MM-DD --to YYYY-MM-DD",
+	Short: "Export invoices or received purchase orders to an accounting package",
+	Long: `Export purchase orders as journal entries or bills for an accounting package.
+
+Formats:
+  journal  Generic double-entry journal CSV
+  iif      QuickBooks Desktop IIF (bills, or journal entries for received orders)
+  xero     Xero bills import CSV
+
+The invoices source exports orders past approval with an invoice number,
+dated when the invoice number was recorded; the received source exports
+received orders by delivery date, crediting the accrual account. Both dates
+are included. Each order is exported once per source: orders exported before
+are left out, unless --dry-run was used, which marks nothing.
+
+Goods are debited to the order's GL account, or --expense-account without
+one, and the cost center is passed as a class or tracking category.
+
+Examples:
+  buyer export accounting --format xero --from 2026-09-01 --to 2026-09-30 -o bills.csv
+  buyer export accounting --format journal --source received --from 2026-09-01 --to 2026-09-30
+  buyer export accounting --history`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		svc := services.NewAccountingExportService(cfg.DB)
+		if history, _ := cmd.Flags().GetBool("history"); history {
+			return printAccountingExports(svc)
+		}
+
+		input := services.AccountingExportInput{}
+		input.Format, _ = cmd.Flags().GetString("format")
+		input.Source, _ = cmd.Flags().GetString("source")
+		input.DryRun, _ = cmd.Flags().GetBool("dry-run")
+		input.Accounts.Payable, _ = cmd.Flags().GetString("payable-account")
+		input.Accounts.Accrued, _ = cmd.Flags().GetString("accrued-account")
+		input.Accounts.Expense, _ = cmd.Flags().GetString("expense-account")
+		input.Accounts.Shipping, _ = cmd.Flags().GetString("shipping-account")
+		input.Accounts.Tax, _ = cmd.Flags().GetString("tax-account")
+		for flag, target := range map[string]*time.Time{"from": &input.From, "to": &input.To} {
+			value, _ := cmd.Flags().GetString(flag)
+			if value == "" {
+				return fmt.Errorf("--%s is required", flag)
+			}
+			date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+			if err != nil {
+				return fmt.Errorf("invalid --%s date %q (use YYYY-MM-DD)", flag, value)
+			}
+			*target = date
+		}
+		if _, err := services.GetAccountingFormat(input.Format); err != nil {
+			return err
+		}
+
+		// The export goes to stdout without --output, so the summary goes
+		// to stderr
+		var out io.Writer = os.Stdout
+		summary := os.Stderr
+		filename, _ := cmd.Flags().GetString("output")
+		var file *os.File
+		if filename != "" {
+			var err error
+			if file, err = os.Create(filename); err != nil {
+				return err
+			}
+			defer file.Close()
+			out, summary = file, os.Stdout
+		}
+
+		batch, err := svc.Export(out, input)
+		if err != nil {
+			if file != nil {
+				_ = file.Close()
+				_ = os.Remove(filename)
+			}
+			return err
+		}
+		if file != nil {
+			if err := file.Close(); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case batch.Records == 0:
+			fmt.Fprintf(summary, "No %s to export from %s to %s.\n", input.Source, batch.FromDate.Format("2006-01-02"), batch.ToDate.Format("2006-01-02"))
+		case input.DryRun:
+			fmt.Fprintf(summary, "Dry run: %d purchase orders exported as %s, not marked exported.\n", batch.Records, batch.Format)
+		default:
+			fmt.Fprintf(summary, "Exported %d purchase orders as %s (export %d).\n", batch.Records, batch.Format, batch.ID)
+		}
+		if filename != "" {
+			fmt.Fprintf(summary, "Written to %s\n", filename)
+		}
+		return nil
+	},
+}
+
+// printAccountingExports lists the saved accounting exports
+func printAccountingExports(svc *services.AccountingExportService) error {
+	exports, err := svc.List()
+	if err != nil {
+		return err
+	}
+	if len(exports) == 0 {
+		fmt.Println("No accounting exports found.")
+		return nil
+	}
+	tbl := table.New("ID", "Format", "Source", "From", "To", "Orders", "Exported By", "Exported At")
+	for _, e := range exports {
+		tbl.AddRow(e.ID, e.Format, e.Source, e.FromDate.Format("2006-01-02"), e.ToDate.Format("2006-01-02"),
+			e.Records, e.ExportedBy, e.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	tbl.Print()
+	return nil
+}
+
+func init() {
+	accounts := services.DefaultAccountingAccounts
+	exportAccountingCmd.Flags().String("format", "journal", "Export format ("+strings.Join(services.AccountingFormats(), ", ")+")")
+	exportAccountingCmd.Flags().String("source", services.AccountingSourceInvoices, "What to export (invoices or received)")
+	exportAccountingCmd.Flags().String("from", "", "First date to export (YYYY-MM-DD)")
+	exportAccountingCmd.Flags().String("to", "", "Last date to export (YYYY-MM-DD)")
+	exportAccountingCmd.Flags().StringP("output", "o", "", "Output file (default stdout)")
+	exportAccountingCmd.Flags().Bool("dry-run", false, "Write the export without marking the orders exported")
+	exportAccountingCmd.Flags().Bool("history", false, "List previous exports instead of exporting")
+	exportAccountingCmd.Flags().String("payable-account", accounts.Payable, "Account credited for invoices")
+	exportAccountingCmd.Flags().String("accrued-account", accounts.Accrued, "Account credited for received orders")
+	exportAccountingCmd.Flags().String("expense-account", accounts.Expense, "Account debited for orders without a GL account")
+	exportAccountingCmd.Flags().String("shipping-account", accounts.Shipping, "Account debited with shipping")
+	exportAccountingCmd.Flags().String("tax-account", accounts.Tax, "Account debited with tax")
+	exportCmd.AddCommand(exportAccountingCmd)
+}
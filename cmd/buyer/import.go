@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/shakfu/buyer/internal/services"
@@ -10,140 +11,253 @@ import (
 
 var importCmd = &cobra.Command{
 	Use:   "import",
-	Short: "Import data from CSV files",
-	Long:  `Import brands, vendors, or forex rates from CSV files.`,
+	Short: "Import data from CSV or Excel files",
+	Long: `Import brands, vendors, products, quotes, or forex rates from CSV or Excel files.
+Format is determined by file extension (.csv or .xlsx). Excel workbooks use
+the same column layout as 'buyer export', so exported files can be edited
+and imported back. Use --sheet to read a sheet other than the default.
+
+Import modes (--mode):
+  create  Only create new records (default). Duplicates are reported as errors.
+  update  Only update existing records. Rows with no match are skipped.
+  upsert  Update existing records and create the rest.
+
+Records are matched with --key: id, name, or sku (products only).
+The whole import runs in one transaction: if any row fails, nothing is
+written unless --continue-on-error is given. Use --dry-run to see what
+would be created, updated or skipped, with field-level changes.
+
+Files with their own column names, such as vendor price lists, can be
+imported with a saved mapping profile (see 'buyer import profile'):
+
+  buyer import --profile acme-prices acme_pricelist.xlsx --dry-run`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		profileName, _ := cmd.Flags().GetString("profile")
+		if profileName == "" {
+			if len(args) > 0 {
+				fmt.Fprintf(os.Stderr, "Error: specify what to import (brands, vendors, products, quotes, forex) or use --profile\n")
+				os.Exit(1)
+			}
+			_ = cmd.Help()
+			return
+		}
+		if len(args) != 1 {
+			fmt.Fprintf(os.Stderr, "Error: a file to import is required with --profile\n")
+			os.Exit(1)
+		}
+
+		profile, err := services.NewImportProfileService(cfg.DB).GetByName(profileName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		exportSvc := services.NewExportImportService(cfg.DB)
+		runImportCommand(cmd, args[0], profile.Entity,
+			func(r io.Reader, opts services.ImportOptions) (*services.ImportResult, error) {
+				return exportSvc.ImportCSVWithProfile(r, profile, opts)
+			},
+			func(r io.Reader, opts services.ImportOptions) (*services.ImportResult, error) {
+				return exportSvc.ImportExcelWithProfile(r, profile, opts)
+			})
+	},
 }
 
 var importBrandsCmd = &cobra.Command{
 	Use:   "brands [filename]",
-	Short: "Import brands from CSV",
-	Long: `Import brands from a CSV file.
+	Short: "Import brands from CSV or Excel",
+	Long: `Import brands from a CSV or Excel (.xlsx) file.
 
 CSV Format:
 ID,Name,CreatedAt,UpdatedAt
 1,Apple,2024-01-01T00:00:00Z,2024-01-01T00:00:00Z
 2,Samsung,2024-01-01T00:00:00Z,2024-01-01T00:00:00Z
 
-Note: ID field is ignored during import. New IDs are auto-generated.`,
+Brands can be matched by name (default) or id. In create mode the ID
+field is ignored and new IDs are auto-generated.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		filename := args[0]
 		exportSvc := services.NewExportImportService(cfg.DB)
-
-		file, err := os.Open(filename)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
-			os.Exit(1)
-		}
-		defer file.Close()
-
-		result, err := exportSvc.ImportBrandsCSV(file)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error importing brands: %v\n", err)
-			os.Exit(1)
-		}
-
-		fmt.Printf("\nImport Summary:\n")
-		fmt.Printf("  Successfully imported: %d brands\n", result.SuccessCount)
-		fmt.Printf("  Errors: %d\n", result.ErrorCount)
-
-		if result.ErrorCount > 0 {
-			fmt.Printf("\nError Details:\n")
-			for _, errMsg := range result.Errors {
-				fmt.Printf("  - %s\n", errMsg)
-			}
-		}
+		runImportCommand(cmd, args[0], "brands", exportSvc.ImportBrandsCSVWithOptions, exportSvc.ImportBrandsExcel)
 	},
 }
 
 var importVendorsCmd = &cobra.Command{
 	Use:   "vendors [filename]",
-	Short: "Import vendors from CSV",
-	Long: `Import vendors from a CSV file.
+	Short: "Import vendors from CSV or Excel",
+	Long: `Import vendors from a CSV or Excel (.xlsx) file.
 
 CSV Format:
 ID,Name,Currency,DiscountCode
 1,B&H Photo,USD,SAVE10
 2,Adorama,USD,SUMMER15
 
-Note: ID field is ignored during import. New IDs are auto-generated.
-Only Name, Currency, and DiscountCode are required. Other fields can be added manually.`,
+Only Name is required. Any other column produced by 'buyer export vendors'
+(ContactPerson, Email, AddressLine1, TaxID, PaymentTerms, ...) is also
+imported. When updating, only columns present in the file are changed.
+Vendors can be matched by name (default) or id.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		filename := args[0]
 		exportSvc := services.NewExportImportService(cfg.DB)
+		runImportCommand(cmd, args[0], "vendors", exportSvc.ImportVendorsCSVWithOptions, exportSvc.ImportVendorsExcel)
+	},
+}
 
-		file, err := os.Open(filename)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
-			os.Exit(1)
-		}
-		defer file.Close()
+var importProductsCmd = &cobra.Command{
+	Use:   "products [filename]",
+	Short: "Import products from CSV or Excel",
+	Long: `Import products from a CSV or Excel (.xlsx) file.
 
-		result, err := exportSvc.ImportVendorsCSV(file)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error importing vendors: %v\n", err)
-			os.Exit(1)
-		}
+CSV Format (as produced by 'buyer export products'):
+ID,Name,BrandID,BrandName,SpecificationID,SpecificationName,SKU,Description,UnitOfMeasure,MinOrderQty,LeadTimeDays,IsActive
+0,iPhone 15 Pro,,Apple,,Smartphone,IPHONE15PRO,Flagship phone,each,1,7,true
 
-		fmt.Printf("\nImport Summary:\n")
-		fmt.Printf("  Successfully imported: %d vendors\n", result.SuccessCount)
-		fmt.Printf("  Errors: %d\n", result.ErrorCount)
+Brands and specifications are resolved by ID, or by name when the ID is empty.
+Products can be matched by name (default), id, or sku.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		exportSvc := services.NewExportImportService(cfg.DB)
+		runImportCommand(cmd, args[0], "products", exportSvc.ImportProductsCSVWithOptions, exportSvc.ImportProductsExcel)
+	},
+}
 
-		if result.ErrorCount > 0 {
-			fmt.Printf("\nError Details:\n")
-			for _, errMsg := range result.Errors {
-				fmt.Printf("  - %s\n", errMsg)
-			}
-		}
+var importQuotesCmd = &cobra.Command{
+	Use:   "quotes [filename]",
+	Short: "Import quotes from CSV or Excel",
+	Long: `Import quotes from a CSV or Excel (.xlsx) file.
+
+CSV Format:
+VendorName,ProductSKU,Price,Currency,MinQuantity,QuoteDate,ValidUntil,Notes
+B&H Photo,IPHONE15PRO,999.00,USD,1,2024-01-01,2024-03-31,Q1 pricing
+
+Vendors are resolved by VendorID or VendorName. Products are resolved by
+ProductID, ProductSKU or ProductName. Currency defaults to the vendor's
+currency. Quotes are always created as new records (create mode only).`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		exportSvc := services.NewExportImportService(cfg.DB)
+		runImportCommand(cmd, args[0], "quotes", exportSvc.ImportQuotesCSVWithOptions, exportSvc.ImportQuotesExcel)
 	},
 }
 
 var importForexCmd = &cobra.Command{
 	Use:   "forex [filename]",
-	Short: "Import forex rates from CSV",
-	Long: `Import forex exchange rates from a CSV file.
+	Short: "Import forex rates from CSV or Excel",
+	Long: `Import forex exchange rates from a CSV or Excel (.xlsx) file.
 
 CSV Format:
 ID,FromCurrency,ToCurrency,Rate,EffectiveDate
 1,EUR,USD,1.20,2024-01-01T00:00:00Z
 2,GBP,USD,1.35,2024-01-01T00:00:00Z
 
-Note: ID field is ignored during import. New IDs are auto-generated.
-EffectiveDate must be in RFC3339 format (YYYY-MM-DDTHH:MM:SSZ).`,
+EffectiveDate must be in RFC3339 (YYYY-MM-DDTHH:MM:SSZ) or YYYY-MM-DD format.
+Forex rates can only be matched by id when updating.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		filename := args[0]
 		exportSvc := services.NewExportImportService(cfg.DB)
+		runImportCommand(cmd, args[0], "forex rates", exportSvc.ImportForexCSVWithOptions, exportSvc.ImportForexExcel)
+	},
+}
 
-		file, err := os.Open(filename)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
-			os.Exit(1)
-		}
-		defer file.Close()
+// importFunc is the signature shared by the ExportImportService CSV and Excel importers
+type importFunc func(r io.Reader, opts services.ImportOptions) (*services.ImportResult, error)
 
-		result, err := exportSvc.ImportForexCSV(file)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error importing forex rates: %v\n", err)
-			os.Exit(1)
-		}
+// runImportCommand opens the file, runs the CSV or Excel importer (chosen by file
+// extension) with options from the command flags and prints the summary
+func runImportCommand(cmd *cobra.Command, filename, entity string, csvImporter, excelImporter importFunc) {
+	opts := importOptionsFromFlags(cmd)
+	importer := csvImporter
+	if isExcelFile(filename) {
+		importer = excelImporter
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	result, err := importer(file, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing %s: %v\n", entity, err)
+		os.Exit(1)
+	}
 
-		fmt.Printf("\nImport Summary:\n")
-		fmt.Printf("  Successfully imported: %d forex rates\n", result.SuccessCount)
-		fmt.Printf("  Errors: %d\n", result.ErrorCount)
+	printImportResult(result, entity)
+}
+
+// importOptionsFromFlags builds import options from the persistent import flags
+func importOptionsFromFlags(cmd *cobra.Command) services.ImportOptions {
+	mode, _ := cmd.Flags().GetString("mode")
+	key, _ := cmd.Flags().GetString("key")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	continueOnError, _ := cmd.Flags().GetBool("continue-on-error")
+	sheet, _ := cmd.Flags().GetString("sheet")
+
+	return services.ImportOptions{
+		Mode:            services.ImportMode(mode),
+		Key:             services.ImportKey(key),
+		DryRun:          dryRun,
+		ContinueOnError: continueOnError,
+		Sheet:           sheet,
+	}
+}
 
-		if result.ErrorCount > 0 {
-			fmt.Printf("\nError Details:\n")
-			for _, errMsg := range result.Errors {
-				fmt.Printf("  - %s\n", errMsg)
+// printImportResult prints the import summary, the per-row plan and any errors
+func printImportResult(result *services.ImportResult, entity string) {
+	if result.DryRun {
+		fmt.Printf("\nDry Run - no changes were written\n")
+		for _, row := range result.Rows {
+			line := fmt.Sprintf("  Row %d: %s", row.Row, row.Action)
+			if row.Key != "" {
+				line += fmt.Sprintf(" %q", row.Key)
+			}
+			if row.Message != "" {
+				line += fmt.Sprintf(" (%s)", row.Message)
+			}
+			fmt.Println(line)
+			for _, change := range row.Changes {
+				fmt.Printf("      %s: %q -> %q\n", change.Field, change.Old, change.New)
 			}
 		}
-	},
+	}
+
+	fmt.Printf("\nImport Summary:\n")
+	if result.DryRun {
+		fmt.Printf("  Would create: %d %s\n", result.CreatedCount, entity)
+		fmt.Printf("  Would update: %d %s\n", result.UpdatedCount, entity)
+	} else {
+		fmt.Printf("  Created: %d %s\n", result.CreatedCount, entity)
+		fmt.Printf("  Updated: %d %s\n", result.UpdatedCount, entity)
+	}
+	fmt.Printf("  Skipped: %d\n", result.SkippedCount)
+	fmt.Printf("  Errors: %d\n", result.ErrorCount)
+
+	if result.ErrorCount > 0 {
+		fmt.Printf("\nError Details:\n")
+		for _, errMsg := range result.Errors {
+			fmt.Printf("  - %s\n", errMsg)
+		}
+		if result.RolledBack && !result.DryRun {
+			fmt.Printf("\nImport rolled back: no changes were written (use --continue-on-error to keep valid rows)\n")
+		}
+	}
 }
 
 func init() {
 	importCmd.AddCommand(importBrandsCmd)
 	importCmd.AddCommand(importVendorsCmd)
+	importCmd.AddCommand(importProductsCmd)
+	importCmd.AddCommand(importQuotesCmd)
 	importCmd.AddCommand(importForexCmd)
+
+	importCmd.Flags().String("profile", "", "Import a file using a saved mapping profile")
+
+	importCmd.PersistentFlags().String("mode", "create", "Import mode: create, update, or upsert")
+	importCmd.PersistentFlags().String("key", "", "Field used to match existing records: id, name, or sku (default: name, or id for forex)")
+	importCmd.PersistentFlags().Bool("dry-run", false, "Report what would be created, updated or skipped without writing anything")
+	importCmd.PersistentFlags().Bool("continue-on-error", false, "Keep valid rows even if other rows fail")
+	importCmd.PersistentFlags().String("sheet", "", "Excel sheet to import (default: the sheet written by 'buyer export')")
 }
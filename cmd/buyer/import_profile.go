@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rodaine/table"
+	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var importProfileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage column-mapping import profiles",
+	Long: `Manage import profiles that map the columns of external files, such as
+vendor price lists, to import fields. Profiles are stored in the database,
+optionally per vendor, and used with 'buyer import --profile NAME FILE'.`,
+}
+
+var importProfileCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create an import profile",
+	Long: `Create an import profile.
+
+Each --map flag maps a target field to a source column, optionally followed
+by pipe-separated transforms:
+
+  --map "ProductSKU=Part #"
+  --map "Price=Net EUR|decimal-comma|multiply:1.2"
+  --map "QuoteDate=Date|date:DD/MM/YYYY"
+
+Each --const flag sets a target field to a fixed value:
+
+  --const "Currency=EUR"
+
+Transforms: trim, upper, lower, decimal-comma, multiply:N, date:LAYOUT
+(LAYOUT uses YYYY, YY, MM and DD tokens).
+
+For quote profiles with --vendor, imported quotes belong to that vendor
+unless VendorID or VendorName is mapped.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		entity, _ := cmd.Flags().GetString("entity")
+		vendorName, _ := cmd.Flags().GetString("vendor")
+		sheet, _ := cmd.Flags().GetString("sheet")
+		mappings, _ := cmd.Flags().GetStringArray("map")
+		constants, _ := cmd.Flags().GetStringArray("const")
+
+		input := services.CreateImportProfileInput{
+			Name:   args[0],
+			Entity: entity,
+			Sheet:  sheet,
+		}
+
+		if vendorName != "" {
+			vendor, err := services.NewVendorService(cfg.DB).GetByName(vendorName)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			input.VendorID = &vendor.ID
+		}
+
+		for _, m := range mappings {
+			field, err := parseProfileMapping(m)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			input.Fields = append(input.Fields, field)
+		}
+		for _, c := range constants {
+			name, value, ok := strings.Cut(c, "=")
+			if !ok || strings.TrimSpace(name) == "" {
+				fmt.Fprintf(os.Stderr, "Error: invalid constant %q (expected FIELD=VALUE)\n", c)
+				os.Exit(1)
+			}
+			input.Fields = append(input.Fields, models.ImportProfileField{Field: strings.TrimSpace(name), Constant: value})
+		}
+
+		profile, err := services.NewImportProfileService(cfg.DB).Create(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating profile: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Created import profile: %s (ID: %d)\n", profile.Name, profile.ID)
+		printProfileFields(profile)
+	},
+}
+
+var importProfileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List import profiles",
+	Run: func(cmd *cobra.Command, args []string) {
+		vendorName, _ := cmd.Flags().GetString("vendor")
+		svc := services.NewImportProfileService(cfg.DB)
+
+		var profiles []models.ImportProfile
+		var err error
+		if vendorName != "" {
+			vendor, verr := services.NewVendorService(cfg.DB).GetByName(vendorName)
+			if verr != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", verr)
+				os.Exit(1)
+			}
+			profiles, err = svc.ListByVendor(vendor.ID)
+		} else {
+			profiles, err = svc.List()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(profiles) == 0 {
+			fmt.Println("No import profiles found.")
+			return
+		}
+
+		tbl := table.New("ID", "Name", "Entity", "Vendor", "Fields")
+		for _, profile := range profiles {
+			vendor := ""
+			if profile.Vendor != nil {
+				vendor = profile.Vendor.Name
+			}
+			tbl.AddRow(profile.ID, profile.Name, profile.Entity, vendor, len(profile.Fields))
+		}
+		tbl.Print()
+	},
+}
+
+var importProfileShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show an import profile's field mappings",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		profile, err := services.NewImportProfileService(cfg.DB).GetByName(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Profile: %s (ID: %d)\n", profile.Name, profile.ID)
+		fmt.Printf("Entity:  %s\n", profile.Entity)
+		if profile.Vendor != nil {
+			fmt.Printf("Vendor:  %s\n", profile.Vendor.Name)
+		}
+		if profile.Sheet != "" {
+			fmt.Printf("Sheet:   %s\n", profile.Sheet)
+		}
+		printProfileFields(profile)
+	},
+}
+
+var importProfileDeleteCmd = &cobra.Command{
+	Use:   "delete [name]",
+	Short: "Delete an import profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		svc := services.NewImportProfileService(cfg.DB)
+		profile, err := svc.GetByName(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := svc.Delete(profile.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "Error deleting profile: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Deleted import profile: %s\n", profile.Name)
+	},
+}
+
+// parseProfileMapping parses a --map value of the form FIELD=COLUMN[|transform...]
+func parseProfileMapping(value string) (models.ImportProfileField, error) {
+	field, rest, ok := strings.Cut(value, "=")
+	if !ok || strings.TrimSpace(field) == "" {
+		return models.ImportProfileField{}, fmt.Errorf("invalid mapping %q (expected FIELD=COLUMN[|transform...])", value)
+	}
+	column, transform, _ := strings.Cut(rest, "|")
+	return models.ImportProfileField{
+		Field:     strings.TrimSpace(field),
+		Column:    strings.TrimSpace(column),
+		Transform: transform,
+	}, nil
+}
+
+// printProfileFields prints a profile's field mappings as a table
+func printProfileFields(profile *models.ImportProfile) {
+	fmt.Println()
+	tbl := table.New("Field", "Source", "Transform")
+	for _, f := range profile.Fields {
+		source := f.Column
+		if source == "" {
+			source = fmt.Sprintf("= %q", f.Constant)
+		}
+		tbl.AddRow(f.Field, source, f.Transform)
+	}
+	tbl.Print()
+}
+
+func init() {
+	importProfileCmd.AddCommand(importProfileCreateCmd)
+	importProfileCmd.AddCommand(importProfileListCmd)
+	importProfileCmd.AddCommand(importProfileShowCmd)
+	importProfileCmd.AddCommand(importProfileDeleteCmd)
+	importCmd.AddCommand(importProfileCmd)
+
+	importProfileCreateCmd.Flags().String("entity", "quotes", "Import target: brands, vendors, products, quotes, or forex")
+	importProfileCreateCmd.Flags().String("vendor", "", "Vendor the profile belongs to")
+	importProfileCreateCmd.Flags().String("sheet", "", "Excel sheet to read (default: the active sheet)")
+	importProfileCreateCmd.Flags().StringArray("map", nil, "Field mapping FIELD=COLUMN[|transform...] (repeatable)")
+	importProfileCreateCmd.Flags().StringArray("const", nil, "Constant field value FIELD=VALUE (repeatable)")
+
+	importProfileListCmd.Flags().String("vendor", "", "Only list profiles for this vendor")
+}
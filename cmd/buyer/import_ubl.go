@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/shakfu/buyer/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var importUBLCmd = &cobra.Command{
+	Use:   "ubl <file>",
+	Short: "Import a UBL invoice or credit note",
+	Long: `Import a UBL 2.1 Invoice or CreditNote XML document, such as a PEPPOL
+BIS Billing 3.0 invoice, from a vendor.
+
+The supplier is matched to a vendor by tax ID (with or without a country
+prefix), then by name. Each line is matched to a purchase order by its buyer
+order reference, or the document's, and checked against the order's SKU,
+unit price, currency and quantity; a line without a reference matches the
+vendor's only approved order for its SKU. Purchase orders are not changed.
+
+The document is saved as matched, ready for the three-way match, when the
+vendor and every line matched, and as unmatched otherwise. Either way the
+problems are reported. Use --dry-run to see the report without saving.
+
+Examples:
+  buyer import ubl acme-inv-1042.xml
+  buyer import ubl acme-credit-7.xml --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		result, err := services.NewInvoiceService(cfg.DB).ImportUBL(file, dryRun)
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		printInvoiceImport(result, dryRun)
+		return nil
+	},
+}
+
+// printInvoiceImport prints an imported invoice and its problems
+func printInvoiceImport(result *services.InvoiceImportResult, dryRun bool) {
+	invoice := result.Invoice
+	kind := strings.ReplaceAll(invoice.DocumentType, "_", " ")
+	supplier := invoice.SupplierName
+	if invoice.Vendor != nil {
+		supplier = invoice.Vendor.Name
+	}
+	if dryRun {
+		fmt.Printf("Dry run: %s %s from %s would be imported as %s\n", kind, invoice.InvoiceNumber, supplier, invoice.Status)
+	} else {
+		fmt.Printf("Imported %s %s from %s as %s (ID %d)\n", kind, invoice.InvoiceNumber, supplier, invoice.Status, invoice.ID)
+	}
+	fmt.Printf("  %d lines, net %.2f, tax %.2f, total %.2f %s\n", len(invoice.Lines), invoice.NetAmount, invoice.TaxAmount, invoice.TotalAmount, invoice.Currency)
+	for _, line := range invoice.Lines {
+		order := "-"
+		if line.PurchaseOrder != nil {
+			order = line.PurchaseOrder.PONumber
+		}
+		item := line.SKU
+		if item == "" {
+			item = line.Description
+		}
+		fmt.Printf("  line %s: %g x %s at %.2f -> %s\n", line.LineNumber, line.Quantity, item, line.UnitPrice, order)
+	}
+	if len(result.Problems) > 0 {
+		fmt.Println("Problems:")
+		for _, problem := range result.Problems {
+			fmt.Printf("  %s\n", problem)
+		}
+	}
+}
+
+func init() {
+	importCmd.AddCommand(importUBLCmd)
+}
@@ -216,7 +216,7 @@ var listRequisitionsCmd = &cobra.Command{
 			return
 		}
 
-		tbl := table.New("ID", "Name", "Items", "Budget", "Justification")
+		tbl := table.New("ID", "Name", "Status", "Items", "Budget", "Justification")
 		for _, req := range reqs {
 			budgetStr := "-"
 			if req.Budget > 0 {
@@ -226,7 +226,7 @@ var listRequisitionsCmd = &cobra.Command{
 			if len(just) > 40 {
 				just = just[:37] + "..."
 			}
-			tbl.AddRow(req.ID, req.Name, len(req.Items), budgetStr, just)
+			tbl.AddRow(req.ID, req.Name, req.Status, len(req.Items), budgetStr, just)
 		}
 		tbl.Print()
 	},
@@ -352,7 +352,7 @@ var listProjectRequisitionsCmd = &cobra.Command{
 			return
 		}
 
-		tbl := table.New("ID", "Project ID", "Name", "Budget", "Items", "Created")
+		tbl := table.New("ID", "Project ID", "Name", "Status", "Budget", "Items", "Created")
 		for _, req := range requisitions {
 			budgetStr := "-"
 			if req.Budget > 0 {
@@ -361,7 +361,7 @@ var listProjectRequisitionsCmd = &cobra.Command{
 
 			createdStr := req.CreatedAt.Format("2006-01-02")
 
-			tbl.AddRow(req.ID, req.ProjectID, req.Name, budgetStr, len(req.Items), createdStr)
+			tbl.AddRow(req.ID, req.ProjectID, req.Name, req.Status, budgetStr, len(req.Items), createdStr)
 		}
 		tbl.Print()
 	},
@@ -554,7 +554,7 @@ func init() {
 	}
 
 	// Purchase order specific flags
-	listPurchaseOrdersCmd.Flags().String("status", "", "Filter by status (pending, approved, ordered, shipped, received, cancelled)")
+	listPurchaseOrdersCmd.Flags().String("status", "", "Filter by status (pending, approved, rejected, ordered, shipped, received, cancelled)")
 
 	// Document specific flags
 	listDocumentsCmd.Flags().String("entity-type", "", "Filter by entity type (vendor, brand, product, quote, purchase_order, requisition, project)")
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/user"
 
 	"github.com/joho/godotenv"
+	"github.com/shakfu/buyer/internal/audit"
 	"github.com/shakfu/buyer/internal/config"
+	"github.com/shakfu/buyer/internal/migrations"
 	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/storage"
 	"github.com/spf13/cobra"
 )
 
@@ -18,7 +23,9 @@ var (
 	Version = "dev"
 )
 
-func initConfig() {
+// openConfig initializes logging and opens the configured database without
+// checking its schema
+func openConfig() *slog.Logger {
 	// Initialize configuration
 	env := config.GetEnv()
 	logger := config.SetupLogger(env, verbose)
@@ -38,34 +45,58 @@ func initConfig() {
 	logger.Debug("database configured",
 		slog.String("path", cfg.DatabasePath))
 
-	// Run migrations
-	if err := cfg.AutoMigrate(
-		&models.Vendor{},
-		&models.Brand{},
-		&models.Specification{},
-		&models.SpecificationAttribute{},
-		&models.Product{},
-		&models.ProductAttribute{},
-		&models.Requisition{},
-		&models.RequisitionItem{},
-		&models.Quote{},
-		&models.PurchaseOrder{},
-		&models.VendorRating{},
-		&models.Forex{},
-		&models.Project{},
-		&models.BillOfMaterials{},
-		&models.BillOfMaterialsItem{},
-		&models.ProjectRequisition{},
-		&models.ProjectRequisitionItem{},
-		&models.ProjectProcurementStrategy{},
-		&models.Document{},
-	); err != nil {
-		logger.Error("failed to run migrations", slog.String("error", err.Error()))
-		fmt.Fprintf(os.Stderr, "Failed to run migrations: %v\n", err)
+	// Record every change in the audit log, as made by the CLI user until a
+	// command (such as the web server) says otherwise
+	if err := audit.Register(cfg.DB); err != nil {
+		logger.Error("failed to enable the audit log", slog.String("error", err.Error()))
+		fmt.Fprintf(os.Stderr, "Failed to enable the audit log: %v\n", err)
 		os.Exit(1)
 	}
+	cfg.DB = cfg.DB.WithContext(models.WithSource(models.WithActor(context.Background(), cliActor()), models.SourceCLI))
+
+	return logger
+}
+
+// cliActor names the user running the CLI in the audit log: BUYER_ACTOR if
+// set, otherwise the operating system user
+func cliActor() string {
+	if actor := os.Getenv("BUYER_ACTOR"); actor != "" {
+		return actor
+	}
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
+
+// poNumberPattern is the pattern for automatic PO numbers,
+// BUYER_PO_NUMBER_PATTERN or services.DefaultPONumberPattern if unset
+func poNumberPattern() string {
+	return os.Getenv("BUYER_PO_NUMBER_PATTERN")
+}
+
+// documentStorage returns the storage of document files selected by
+// DOCUMENT_STORAGE_TYPE (see config.StorageFromEnv)
+func documentStorage() (storage.StorageBackend, error) {
+	return storage.NewStorageBackend(config.StorageFromEnv())
+}
 
-	logger.Info("database migrations completed successfully")
+func initConfig() {
+	logger := openConfig()
+
+	// Refuse to run against an out-of-date schema; new databases are initialized
+	migrator, err := migrations.NewMigrator(cfg.DB)
+	if err == nil {
+		var initialized bool
+		if initialized, err = migrator.EnsureCurrent(); initialized {
+			logger.Info("database initialized", slog.Int("schema_version", migrator.Latest()))
+		}
+	}
+	if err != nil {
+		logger.Error("database schema check failed", slog.String("error", err.Error()))
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 }
 
 func main() {
@@ -116,5 +147,21 @@ func init() {
 	rootCmd.AddCommand(webCmd)
 	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(trashCmd)
+	rootCmd.AddCommand(purgeCmd)
+	rootCmd.AddCommand(dbCmd)
+	rootCmd.AddCommand(tokenCmd)
+	rootCmd.AddCommand(userCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(approvalsCmd)
+	rootCmd.AddCommand(requisitionCmd)
+	rootCmd.AddCommand(notificationsCmd)
+	rootCmd.AddCommand(projectCmd)
+	rootCmd.AddCommand(costCenterCmd)
+	rootCmd.AddCommand(glAccountCmd)
+	rootCmd.AddCommand(codingCmd)
+	rootCmd.AddCommand(poCmd)
 	rootCmd.AddCommand(versionCmd)
 }
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/shakfu/buyer/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var poCmd = &cobra.Command{
+	Use:   "po",
+	Short: "Exchange purchase orders with vendors",
+}
+
+// orderExchangeOptions returns the buyer's EDI identity and ship-to address
+// from the environment: BUYER_EDI_ID, BUYER_EDI_QUALIFIER, BUYER_CXML_DOMAIN,
+// BUYER_CXML_SHARED_SECRET and the BUYER_SHIP_TO_* variables
+func orderExchangeOptions() services.OrderExchangeOptions {
+	return services.OrderExchangeOptions{
+		BuyerID:      os.Getenv("BUYER_EDI_ID"),
+		Qualifier:    os.Getenv("BUYER_EDI_QUALIFIER"),
+		Domain:       os.Getenv("BUYER_CXML_DOMAIN"),
+		SharedSecret: os.Getenv("BUYER_CXML_SHARED_SECRET"),
+		ShipTo: services.PostalAddress{
+			Name:       os.Getenv("BUYER_SHIP_TO_NAME"),
+			Street1:    os.Getenv("BUYER_SHIP_TO_STREET1"),
+			Street2:    os.Getenv("BUYER_SHIP_TO_STREET2"),
+			City:       os.Getenv("BUYER_SHIP_TO_CITY"),
+			State:      os.Getenv("BUYER_SHIP_TO_STATE"),
+			PostalCode: os.Getenv("BUYER_SHIP_TO_POSTAL_CODE"),
+			Country:    os.Getenv("BUYER_SHIP_TO_COUNTRY"),
+		},
+	}
+}
+
+// orderDocumentCommand returns the command that writes a purchase order as
+// an electronic document
+func orderDocumentCommand(use, short, long string, write func(svc *services.OrderExchangeService, w io.Writer, id uint, opts services.OrderExchangeOptions) error) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   use + " <id>",
+		Short: short,
+		Long:  long,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.ParseUint(args[0], 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid purchase order ID: %s", args[0])
+			}
+			opts := orderExchangeOptions()
+			if cmd.Flags().Changed("buyer-id") {
+				opts.BuyerID, _ = cmd.Flags().GetString("buyer-id")
+			}
+			opts.VendorID, _ = cmd.Flags().GetString("vendor-id")
+			opts.Test, _ = cmd.Flags().GetBool("test")
+			svc := services.NewOrderExchangeService(cfg.DB)
+
+			filename, _ := cmd.Flags().GetString("output")
+			if filename == "" {
+				return write(svc, os.Stdout, uint(id), opts)
+			}
+			file, err := os.Create(filename)
+			if err != nil {
+				return err
+			}
+			if err := write(svc, file, uint(id), opts); err != nil {
+				_ = file.Close()
+				_ = os.Remove(filename)
+				return err
+			}
+			if err := file.Close(); err != nil {
+				return err
+			}
+			fmt.Printf("Purchase order %d written to %s\n", id, filename)
+			return nil
+		},
+	}
+	cmd.Flags().StringP("output", "o", "", "Output file (default stdout)")
+	cmd.Flags().String("buyer-id", "", "Buyer's EDI identity (default BUYER_EDI_ID)")
+	cmd.Flags().String("vendor-id", "", "Vendor's EDI identity (default its tax ID, then its name)")
+	cmd.Flags().Bool("test", false, "Mark the document as test data")
+	return cmd
+}
+
+const orderExchangeEnv = `
+The buyer is identified by BUYER_EDI_ID (required), with BUYER_EDI_QUALIFIER
+(X12, default ZZ), BUYER_CXML_DOMAIN (default NetworkID) and
+BUYER_CXML_SHARED_SECRET. BUYER_SHIP_TO_NAME, _STREET1, _STREET2, _CITY,
+_STATE, _POSTAL_CODE and _COUNTRY give the ship-to address.
+
+Only approved orders, or orders further along, can be sent.`
+
+var poCXMLCmd = orderDocumentCommand("cxml",
+	"Write a purchase order as a cXML OrderRequest",
+	"Write a purchase order as a cXML OrderRequest document for the vendor.\n"+orderExchangeEnv,
+	(*services.OrderExchangeService).WriteCXMLOrderRequest)
+
+var poX12Cmd = orderDocumentCommand("x12",
+	"Write a purchase order as an X12 850",
+	"Write a purchase order as an ANSI X12 004010 850 interchange for the vendor.\n"+
+		"The interchange control number is the purchase order ID.\n"+orderExchangeEnv,
+	(*services.OrderExchangeService).WriteX12PurchaseOrder)
+
+var poResponseCmd = &cobra.Command{
+	Use:   "response <file>...",
+	Short: "Apply vendor order confirmations and ship notices",
+	Long: `Apply order confirmations (cXML ConfirmationRequest, X12 855) and ship
+notices (cXML ShipNoticeRequest, X12 856) from vendors to their purchase orders.
+
+A confirmation moves an approved order to ordered and a ship notice moves it
+to shipped; orders never move back. A rejection cancels an order not yet
+shipped. The delivery date of the response becomes the expected delivery.
+
+Examples:
+  buyer po response acme-855.edi
+  buyer po response inbox/*.xml --dry-run`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		svc := services.NewOrderExchangeService(cfg.DB)
+		failed := 0
+		for _, filename := range args {
+			file, err := os.Open(filename)
+			if err != nil {
+				return err
+			}
+			var results []services.OrderResponseResult
+			if dryRun {
+				var responses []services.OrderResponse
+				if responses, err = services.ParseOrderResponses(file); err == nil {
+					for _, response := range responses {
+						results = append(results, services.OrderResponseResult{OrderResponse: response})
+					}
+				}
+			} else {
+				results, err = svc.ApplyOrderResponses(file)
+			}
+			_ = file.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %w", filename, err)
+			}
+
+			for _, result := range results {
+				fmt.Printf("%s: %s for %s", filename, result.Document, result.PONumber)
+				switch {
+				case result.Error != "":
+					failed++
+					fmt.Printf(": %s\n", result.Error)
+				case dryRun:
+					fmt.Printf(": %s", result.Status)
+					if result.ExpectedDelivery != nil {
+						fmt.Printf(", expected %s", result.ExpectedDelivery.Format("2006-01-02"))
+					}
+					fmt.Println()
+				case len(result.Changes) == 0:
+					fmt.Println(": no change")
+				default:
+					fmt.Printf(": %s\n", strings.Join(result.Changes, ", "))
+				}
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d responses could not be applied", failed)
+		}
+		return nil
+	},
+}
+
+// purchaseOrderPDFOptions returns the company header, ship-to address and
+// layout of purchase order PDFs from the environment: the BUYER_COMPANY_*
+// variables, the BUYER_SHIP_TO_* variables and BUYER_PO_TEMPLATE, the path
+// of a layout template. templatePath, when set, overrides BUYER_PO_TEMPLATE.
+func purchaseOrderPDFOptions(templatePath string) (services.PurchaseOrderPDFOptions, error) {
+	opts := services.PurchaseOrderPDFOptions{
+		Company: services.CompanyDetails{
+			PostalAddress: services.PostalAddress{
+				Name:       os.Getenv("BUYER_COMPANY_NAME"),
+				Street1:    os.Getenv("BUYER_COMPANY_STREET1"),
+				Street2:    os.Getenv("BUYER_COMPANY_STREET2"),
+				City:       os.Getenv("BUYER_COMPANY_CITY"),
+				State:      os.Getenv("BUYER_COMPANY_STATE"),
+				PostalCode: os.Getenv("BUYER_COMPANY_POSTAL_CODE"),
+				Country:    os.Getenv("BUYER_COMPANY_COUNTRY"),
+			},
+			Phone: os.Getenv("BUYER_COMPANY_PHONE"),
+			Email: os.Getenv("BUYER_COMPANY_EMAIL"),
+			TaxID: os.Getenv("BUYER_COMPANY_TAX_ID"),
+		},
+		ShipTo: orderExchangeOptions().ShipTo,
+	}
+	if templatePath == "" {
+		templatePath = os.Getenv("BUYER_PO_TEMPLATE")
+	}
+	if templatePath != "" {
+		text, err := os.ReadFile(templatePath)
+		if err != nil {
+			return opts, fmt.Errorf("failed to read purchase order template: %w", err)
+		}
+		opts.Template = string(text)
+	}
+	return opts, nil
+}
+
+var poPDFCmd = &cobra.Command{
+	Use:   "pdf <id>",
+	Short: "Print a purchase order as a PDF",
+	Long: `Print a purchase order as a PDF to send to the vendor, with the company
+header, the vendor's address, the order line, shipping, tax, total, the
+vendor's payment terms and the notes.
+
+Each PDF is also stored as a document of the order, in the document storage
+selected by DOCUMENT_STORAGE_TYPE.
+
+The company header comes from BUYER_COMPANY_NAME, _STREET1, _STREET2, _CITY,
+_STATE, _POSTAL_CODE, _COUNTRY, _PHONE, _EMAIL and _TAX_ID, and the ship-to
+address from the BUYER_SHIP_TO_* variables.
+
+The layout is a Go text/template producing simple line-based markup; start
+from the default with --default-template and select yours with --template or
+BUYER_PO_TEMPLATE.
+
+Examples:
+  buyer po pdf 12
+  buyer po pdf 12 -o ~/outbox/acme-order.pdf
+  buyer po pdf --default-template > po.tmpl
+  buyer po pdf 12 --template po.tmpl`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if show, _ := cmd.Flags().GetBool("default-template"); show {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if show, _ := cmd.Flags().GetBool("default-template"); show {
+			fmt.Print(services.DefaultPurchaseOrderTemplate)
+			return nil
+		}
+		id, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid purchase order ID: %s", args[0])
+		}
+		templatePath, _ := cmd.Flags().GetString("template")
+		opts, err := purchaseOrderPDFOptions(templatePath)
+		if err != nil {
+			return err
+		}
+		store, err := documentStorage()
+		if err != nil {
+			return err
+		}
+
+		doc, data, err := services.NewPurchaseOrderPDFService(cfg.DB).SavePDF(uint(id), services.NewDocumentService(cfg.DB).WithStorage(store), opts)
+		if err != nil {
+			return err
+		}
+		filename, _ := cmd.Flags().GetString("output")
+		if filename == "" {
+			filename = doc.FileName
+		}
+		if err := os.WriteFile(filename, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("Purchase order %d written to %s (document %d)\n", id, filename, doc.ID)
+		return nil
+	},
+}
+
+func init() {
+	poResponseCmd.Flags().Bool("dry-run", false, "Show what the files say without updating orders")
+	poPDFCmd.Flags().StringP("output", "o", "", "Output file (default <PO number>.pdf)")
+	poPDFCmd.Flags().String("template", "", "Layout template file (default BUYER_PO_TEMPLATE, then the built-in layout)")
+	poPDFCmd.Flags().Bool("default-template", false, "Print the built-in layout template and exit")
+	poCmd.AddCommand(poCXMLCmd, poX12Cmd, poResponseCmd, poPDFCmd)
+}
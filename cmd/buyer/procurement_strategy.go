@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"strconv"
 
 	"github.com/rodaine/table"
@@ -21,19 +20,17 @@ var strategyShowCmd = &cobra.Command{
 	Use:   "show <project-id>",
 	Short: "Show current procurement strategy",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		id, err := strconv.ParseUint(args[0], 10, 32)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: invalid project ID: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("invalid project ID: %w", err)
 		}
 		projectID := uint(id)
 
 		var strategy models.ProjectProcurementStrategy
 		err = cfg.DB.Where("project_id = ?", projectID).First(&strategy).Error
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: No strategy found for project %d\n", projectID)
-			os.Exit(1)
+			return fmt.Errorf("no strategy found for project %d", projectID)
 		}
 
 		fmt.Printf("\nProcurement Strategy for Project ID %d\n", projectID)
@@ -46,6 +43,7 @@ var strategyShowCmd = &cobra.Command{
 			fmt.Printf("Min Vendor Rating: %.1f\n", *strategy.MinVendorRating)
 		}
 		fmt.Printf("Allow Partial Fulfill: %v\n", strategy.AllowPartialFulfill)
+		return nil
 	},
 }
 
@@ -54,11 +52,10 @@ var strategySetCmd = &cobra.Command{
 	Short: "Set procurement strategy type",
 	Long:  "Set strategy type: lowest_cost, fewest_vendors, balanced, quality_focused",
 	Args:  cobra.ExactArgs(2),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		id, err := strconv.ParseUint(args[0], 10, 32)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: invalid project ID: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("invalid project ID: %w", err)
 		}
 		projectID := uint(id)
 
@@ -70,8 +67,7 @@ var strategySetCmd = &cobra.Command{
 			"quality_focused": true,
 		}
 		if !validTypes[strategyType] {
-			fmt.Fprintf(os.Stderr, "Error: Invalid strategy type\n")
-			os.Exit(1)
+			return fmt.Errorf("invalid strategy type: %s", strategyType)
 		}
 
 		var strategy models.ProjectProcurementStrategy
@@ -82,18 +78,17 @@ var strategySetCmd = &cobra.Command{
 				Strategy:  strategyType,
 			}
 			if err := cfg.DB.Create(&strategy).Error; err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
+				return err
 			}
 			fmt.Printf("Strategy created: %s\n", strategyType)
 		} else {
 			strategy.Strategy = strategyType
 			if err := cfg.DB.Save(&strategy).Error; err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
+				return err
 			}
 			fmt.Printf("Strategy updated: %s\n", strategyType)
 		}
+		return nil
 	},
 }
 
@@ -101,11 +96,10 @@ var strategyCompareCmd = &cobra.Command{
 	Use:   "compare <project-id>",
 	Short: "Compare all strategy scenarios",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		id, err := strconv.ParseUint(args[0], 10, 32)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: invalid project ID: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("invalid project ID: %w", err)
 		}
 		projectID := uint(id)
 
@@ -115,8 +109,7 @@ var strategyCompareCmd = &cobra.Command{
 
 		scenarios, err := procurementSvc.CompareScenarios(projectID)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			return err
 		}
 
 		fmt.Printf("\nScenario Comparison for Project ID %d\n", projectID)
@@ -132,6 +125,7 @@ var strategyCompareCmd = &cobra.Command{
 			)
 		}
 		tbl.Print()
+		return nil
 	},
 }
 
@@ -144,11 +138,10 @@ var recommendGenerateCmd = &cobra.Command{
 	Use:   "generate <project-id>",
 	Short: "Generate vendor recommendations",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		id, err := strconv.ParseUint(args[0], 10, 32)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: invalid project ID: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("invalid project ID: %w", err)
 		}
 		projectID := uint(id)
 
@@ -163,14 +156,13 @@ var recommendGenerateCmd = &cobra.Command{
 
 		recommendations, err := procurementSvc.GenerateVendorRecommendations(projectID, strategyType)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			return err
 		}
 
 		fmt.Printf("\nVendor Recommendations (%s strategy)\n", strategyType)
 		if len(recommendations) == 0 {
 			fmt.Println("No recommendations")
-			return
+			return nil
 		}
 
 		for _, rec := range recommendations {
@@ -181,6 +173,7 @@ var recommendGenerateCmd = &cobra.Command{
 				fmt.Printf("Rationale: %s\n", rec.Rationale)
 			}
 		}
+		return nil
 	},
 }
 
@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/shakfu/buyer/internal/config"
 	"github.com/shakfu/buyer/internal/models"
 	"github.com/shakfu/buyer/internal/services"
+	"github.com/spf13/cobra"
 )
 
 func setupProcurementTestData(t *testing.T) (*config.Config, uint) {
@@ -19,27 +21,7 @@ func setupProcurementTestData(t *testing.T) (*config.Config, uint) {
 	}
 
 	// Run migrations for all models
-	if err := cfg.AutoMigrate(
-		&models.Vendor{},
-		&models.Brand{},
-		&models.Specification{},
-		&models.SpecificationAttribute{},
-		&models.Product{},
-		&models.ProductAttribute{},
-		&models.Quote{},
-		&models.Forex{},
-		&models.Requisition{},
-		&models.RequisitionItem{},
-		&models.PurchaseOrder{},
-		&models.Document{},
-		&models.VendorRating{},
-		&models.Project{},
-		&models.BillOfMaterials{},
-		&models.BillOfMaterialsItem{},
-		&models.ProjectRequisition{},
-		&models.ProjectRequisitionItem{},
-		&models.ProjectProcurementStrategy{},
-	); err != nil {
+	if err := cfg.AutoMigrate(models.All()...); err != nil {
 		t.Fatalf("Failed to run migrations: %v", err)
 	}
 
@@ -253,6 +235,322 @@ func TestProcurementSavingsCommand(t *testing.T) {
 	}
 }
 
+func TestProjectBudgetCommand(t *testing.T) {
+	cfg, projectID := setupProcurementTestData(t)
+	defer func() { _ = cfg.Close() }()
+
+	setTestConfig(cfg)
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	rootCmd.SetArgs([]string{"project", "budget", fmt.Sprintf("%d", projectID)})
+	err := rootCmd.Execute()
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Errorf("Budget command failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	output := buf.String()
+
+	for _, section := range []string{"Budget for Project:", "Committed:", "Remaining:", "Spend by BOM Item:"} {
+		if !contains(output, section) {
+			t.Errorf("Expected output to contain '%s'", section)
+		}
+	}
+}
+
+func TestCodingCommands(t *testing.T) {
+	cfg, projectID := setupProcurementTestData(t)
+	defer func() { _ = cfg.Close() }()
+
+	setTestConfig(cfg)
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	var err error
+	for _, args := range [][]string{
+		{"cost-center", "add", "ENG", "Engineering"},
+		{"gl-account", "add", "6100", "IT Equipment"},
+		{"coding", "set", "--project", fmt.Sprintf("%d", projectID), "--cost-center", "ENG", "--gl-account", "6100"},
+		{"cost-center", "list"},
+		{"cost-center", "spend"},
+	} {
+		rootCmd.SetArgs(args)
+		if err = rootCmd.Execute(); err != nil {
+			break
+		}
+	}
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("Coding command failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	output := buf.String()
+
+	for _, want := range []string{"Created cost center ENG", "Created GL account 6100", "cost center ENG, GL account 6100"} {
+		if !contains(output, want) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", want, output)
+		}
+	}
+
+	var project models.Project
+	if err := cfg.DB.First(&project, projectID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if project.CostCenterID == nil || project.GLAccountID == nil {
+		t.Errorf("Expected the project to be coded, got %+v", project)
+	}
+}
+
+func TestExportAccountingCommand(t *testing.T) {
+	cfg, _ := setupProcurementTestData(t)
+	defer func() { _ = cfg.Close() }()
+
+	setTestConfig(cfg)
+
+	var quote models.Quote
+	if err := cfg.DB.First(&quote).Error; err != nil {
+		t.Fatal(err)
+	}
+	poSvc := services.NewPurchaseOrderService(cfg.DB)
+	po, err := poSvc.Create(services.CreatePurchaseOrderInput{QuoteID: quote.ID, Quantity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := poSvc.UpdateStatus(po.ID, "approved"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := poSvc.UpdateInvoiceNumber(po.ID, "INV-77"); err != nil {
+		t.Fatal(err)
+	}
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	today := time.Now().Format("2006-01-02")
+	file := filepath.Join(t.TempDir(), "bills.csv")
+	for _, args := range [][]string{
+		{"export", "accounting", "--format", "xero", "--from", today, "--to", today, "-o", file},
+		{"export", "accounting", "--format", "xero", "--from", today, "--to", today, "-o", file + ".again"},
+	} {
+		rootCmd.SetArgs(args)
+		if err = rootCmd.Execute(); err != nil {
+			break
+		}
+	}
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("Export accounting command failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	output := buf.String()
+
+	for _, want := range []string{"Exported 1 purchase orders as xero", "No invoices to export"} {
+		if !contains(output, want) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", want, output)
+		}
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(string(content), "INV-77") {
+		t.Errorf("Expected the bills to contain the invoice, got:\n%s", content)
+	}
+}
+
+func TestPOExchangeCommands(t *testing.T) {
+	cfg, _ := setupProcurementTestData(t)
+	defer func() { _ = cfg.Close() }()
+
+	setTestConfig(cfg)
+	t.Setenv("BUYER_EDI_ID", "BUYERCO")
+
+	var quote models.Quote
+	if err := cfg.DB.First(&quote).Error; err != nil {
+		t.Fatal(err)
+	}
+	poSvc := services.NewPurchaseOrderService(cfg.DB)
+	po, err := poSvc.Create(services.CreatePurchaseOrderInput{QuoteID: quote.ID, PONumber: "PO-EDI-1", Quantity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := poSvc.UpdateStatus(po.ID, "approved"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	ack := filepath.Join(dir, "ack.edi")
+	if err := os.WriteFile(ack, []byte("ST*855*0001~BAK*00*AC*PO-EDI-1*20261018~DTM*067*20261112~SE*4*0001~"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	for _, args := range [][]string{
+		{"po", "x12", fmt.Sprintf("%d", po.ID), "-o", filepath.Join(dir, "po.edi")},
+		{"po", "response", ack},
+	} {
+		rootCmd.SetArgs(args)
+		if err = rootCmd.Execute(); err != nil {
+			break
+		}
+	}
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("PO exchange command failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	output := buf.String()
+
+	for _, want := range []string{"written to", "X12 855 for PO-EDI-1: status approved -> ordered, expected delivery 2026-11-12"} {
+		if !contains(output, want) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", want, output)
+		}
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "po.edi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(string(content), "BEG*00*SA*PO-EDI-1") {
+		t.Errorf("Expected an 850 for the order, got:\n%s", content)
+	}
+}
+
+func TestImportUBLCommand(t *testing.T) {
+	cfg, _ := setupProcurementTestData(t)
+	defer func() { _ = cfg.Close() }()
+
+	setTestConfig(cfg)
+
+	var quote models.Quote
+	if err := cfg.DB.Preload("Vendor").First(&quote).Error; err != nil {
+		t.Fatal(err)
+	}
+	poSvc := services.NewPurchaseOrderService(cfg.DB)
+	po, err := poSvc.Create(services.CreatePurchaseOrderInput{QuoteID: quote.ID, PONumber: "PO-UBL-1", Quantity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := poSvc.UpdateStatus(po.ID, "approved"); err != nil {
+		t.Fatal(err)
+	}
+
+	invoice := filepath.Join(t.TempDir(), "invoice.xml")
+	document := fmt.Sprintf(`<?xml version="1.0"?>
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">
+  <cbc:ID>INV-UBL-1</cbc:ID><cbc:IssueDate>2026-10-15</cbc:IssueDate><cbc:DocumentCurrencyCode>%[1]s</cbc:DocumentCurrencyCode>
+  <cac:OrderReference><cbc:ID>PO-UBL-1</cbc:ID></cac:OrderReference>
+  <cac:AccountingSupplierParty><cac:Party><cac:PartyName><cbc:Name>%[2]s</cbc:Name></cac:PartyName></cac:Party></cac:AccountingSupplierParty>
+  <cac:InvoiceLine><cbc:ID>1</cbc:ID><cbc:InvoicedQuantity>2</cbc:InvoicedQuantity><cbc:LineExtensionAmount>%.2[3]f</cbc:LineExtensionAmount>
+    <cac:Item><cbc:Name>Laptop</cbc:Name></cac:Item><cac:Price><cbc:PriceAmount>%.2[4]f</cbc:PriceAmount></cac:Price></cac:InvoiceLine>
+</Invoice>`, quote.Currency, quote.Vendor.Name, 2*quote.Price, quote.Price)
+	if err := os.WriteFile(invoice, []byte(document), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	rootCmd.SetArgs([]string{"import", "ubl", invoice, "--dry-run=false"})
+	err = rootCmd.Execute()
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("UBL import command failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	output := buf.String()
+
+	for _, want := range []string{"Imported invoice INV-UBL-1 from " + quote.Vendor.Name + " as matched", "line 1: 2 x Laptop at", "-> PO-UBL-1"} {
+		if !contains(output, want) {
+			t.Errorf("Expected output to contain '%s', got:\n%s", want, output)
+		}
+	}
+	if contains(output, "Problems:") {
+		t.Errorf("Expected no problems, got:\n%s", output)
+	}
+}
+
+func TestPOPDFCommand(t *testing.T) {
+	cfg, _ := setupProcurementTestData(t)
+	defer func() { _ = cfg.Close() }()
+
+	setTestConfig(cfg)
+	t.Setenv("DOCUMENT_STORAGE_LOCAL_PATH", t.TempDir())
+	t.Setenv("BUYER_COMPANY_NAME", "Buyer Co")
+
+	var quote models.Quote
+	if err := cfg.DB.First(&quote).Error; err != nil {
+		t.Fatal(err)
+	}
+	po, err := services.NewPurchaseOrderService(cfg.DB).Create(services.CreatePurchaseOrderInput{QuoteID: quote.ID, PONumber: "PO-PDF-1", Quantity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(t.TempDir(), "order.pdf")
+
+	oldStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	rootCmd.SetArgs([]string{"po", "pdf", fmt.Sprintf("%d", po.ID), "-o", output})
+	err = rootCmd.Execute()
+
+	w.Close()
+	os.Stdout = oldStdout
+
+	if err != nil {
+		t.Fatalf("PO PDF command failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	if want := fmt.Sprintf("Purchase order %d written to %s (document", po.ID, output); !contains(buf.String(), want) {
+		t.Errorf("Expected output to contain '%s', got:\n%s", want, buf.String())
+	}
+	data, err := os.ReadFile(output)
+	if err != nil || !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Errorf("Expected a PDF at %s, got %v", output, err)
+	}
+	docs, _ := services.NewDocumentService(cfg.DB).ListByEntity("purchase_order", po.ID)
+	if len(docs) != 1 || docs[0].FileName != "PO-PDF-1.pdf" {
+		t.Errorf("Expected the PDF stored as a document of the order, got %+v", docs)
+	}
+}
+
 func TestStrategySetCommand(t *testing.T) {
 	cfg, projectID := setupProcurementTestData(t)
 	defer func() { _ = cfg.Close() }()
@@ -468,7 +766,7 @@ func TestStrategySetInvalidType(t *testing.T) {
 	buf.ReadFrom(r)
 	output := buf.String()
 
-	if !contains(output, "Error") || !contains(output, "Invalid") {
+	if !contains(output, "Error") || !contains(output, "invalid strategy type") {
 		t.Error("Expected error message about invalid strategy type")
 	}
 }
@@ -476,6 +774,8 @@ func TestStrategySetInvalidType(t *testing.T) {
 // Helper function to set the global config for testing
 func setTestConfig(testCfg *config.Config) {
 	cfg = testCfg
+	// Prevent initConfig from replacing the test database with the default one
+	rootCmd.PersistentPreRun = nil
 }
 
 // Helper function to check if string contains substring
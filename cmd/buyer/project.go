@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rodaine/table"
+	"github.com/shakfu/buyer/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var projectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Project reports",
+}
+
+var projectBudgetCmd = &cobra.Command{
+	Use:   "budget <project-id>",
+	Short: "Show committed, received and invoiced spend against a project budget",
+	Long: `Compare a project's purchase orders with its budget, in USD.
+
+Committed spend is every order that is not cancelled or rejected, received
+spend the received orders, and invoiced spend the orders with an invoice
+number. Spend is broken down by bill of materials item; orders for products
+outside the bill of materials are listed as unallocated. An alert is shown
+once committed spend reaches 80% or 100% of the budget.
+
+Example:
+  buyer project budget 3`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid project ID: %w", err)
+		}
+
+		spend, err := services.NewDashboardService(cfg.DB).GetProjectSpend(uint(id))
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\nBudget for Project: %s\n", spend.Project.Name)
+		fmt.Printf("  Budget: $%.2f\n", spend.Project.Budget)
+		fmt.Printf("  Committed: $%.2f", spend.Committed)
+		if spend.Project.Budget > 0 {
+			fmt.Printf(" (%.1f%%)", spend.PercentCommitted())
+		}
+		fmt.Println()
+		fmt.Printf("  Open: $%.2f\n", spend.Open())
+		fmt.Printf("  Received: $%.2f\n", spend.Received)
+		fmt.Printf("  Invoiced: $%.2f\n", spend.Invoiced)
+		fmt.Printf("  Remaining: $%.2f\n", spend.Remaining())
+		if spend.Alert != nil {
+			fmt.Printf("\n[%s] %s\n", spend.Alert.Level, spend.Alert.Message())
+		}
+
+		if len(spend.Items) > 0 || spend.Unallocated.Committed > 0 {
+			fmt.Println("\nSpend by BOM Item:")
+			tbl := table.New("Item", "Specification", "Qty", "Orders", "Committed", "Received", "Invoiced")
+			for _, item := range spend.Items {
+				specName := "N/A"
+				if item.Item.Specification != nil {
+					specName = item.Item.Specification.Name
+				}
+				tbl.AddRow(item.Item.ID, specName, item.Item.Quantity, item.Orders,
+					fmt.Sprintf("$%.2f", item.Committed), fmt.Sprintf("$%.2f", item.Received), fmt.Sprintf("$%.2f", item.Invoiced))
+			}
+			if spend.Unallocated.Committed > 0 {
+				tbl.AddRow("-", "Unallocated", "", "",
+					fmt.Sprintf("$%.2f", spend.Unallocated.Committed), fmt.Sprintf("$%.2f", spend.Unallocated.Received), fmt.Sprintf("$%.2f", spend.Unallocated.Invoiced))
+			}
+			tbl.Print()
+		}
+
+		if len(spend.BurnDown) > 0 {
+			fmt.Println("\nBurn-down:")
+			tbl := table.New("Date", "Committed", "Received", "Remaining")
+			for _, point := range spend.BurnDown {
+				tbl.AddRow(point.Date.Format("2006-01-02"),
+					fmt.Sprintf("$%.2f", point.Committed), fmt.Sprintf("$%.2f", point.Received), fmt.Sprintf("$%.2f", point.Remaining))
+			}
+			tbl.Print()
+		}
+		return nil
+	},
+}
+
+func init() {
+	projectCmd.AddCommand(projectBudgetCmd)
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"strconv"
 
 	"github.com/rodaine/table"
@@ -20,11 +19,10 @@ var procurementAnalyzeCmd = &cobra.Command{
 	Use:   "analyze <project-id>",
 	Short: "Analyze procurement options for a project",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		id, err := strconv.ParseUint(args[0], 10, 32)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: invalid project ID: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("invalid project ID: %w", err)
 		}
 		projectID := uint(id)
 
@@ -34,8 +32,7 @@ var procurementAnalyzeCmd = &cobra.Command{
 
 		comparison, err := procurementSvc.GetProjectProcurementComparison(projectID)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			return err
 		}
 
 		fmt.Printf("\nProcurement Analysis for Project: %s\n", comparison.Project.Name)
@@ -73,6 +70,7 @@ var procurementAnalyzeCmd = &cobra.Command{
 		fmt.Printf("Fully Covered: %d, Partially Covered: %d, Uncovered: %d\n",
 			comparison.FullyCoveredItems, comparison.PartiallyCoveredItems, comparison.UncoveredItems)
 		fmt.Printf("Total Vendors Needed: %d\n", comparison.TotalVendorsNeeded)
+		return nil
 	},
 }
 
@@ -80,11 +78,10 @@ var procurementRisksCmd = &cobra.Command{
 	Use:   "risks <project-id>",
 	Short: "Assess procurement risks for a project",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		id, err := strconv.ParseUint(args[0], 10, 32)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: invalid project ID: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("invalid project ID: %w", err)
 		}
 		projectID := uint(id)
 
@@ -94,8 +91,7 @@ var procurementRisksCmd = &cobra.Command{
 
 		risks, err := procurementSvc.AssessEnhancedProjectRisks(projectID)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			return err
 		}
 
 		fmt.Printf("\nRisk Assessment for Project ID %d\n", projectID)
@@ -126,6 +122,7 @@ var procurementRisksCmd = &cobra.Command{
 			fmt.Printf("  Medium Priority: %d\n", mediumPriority)
 			fmt.Printf("  Total Actions: %d\n", len(risks.MitigationActions))
 		}
+		return nil
 	},
 }
 
@@ -133,11 +130,10 @@ var procurementDashboardCmd = &cobra.Command{
 	Use:   "dashboard <project-id>",
 	Short: "Show project procurement dashboard",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		id, err := strconv.ParseUint(args[0], 10, 32)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: invalid project ID: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("invalid project ID: %w", err)
 		}
 		projectID := uint(id)
 
@@ -147,8 +143,7 @@ var procurementDashboardCmd = &cobra.Command{
 
 		dashboard, err := procurementSvc.GetProjectDashboard(projectID)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			return err
 		}
 
 		fmt.Printf("\nDashboard for Project: %s\n", dashboard.Project.Name)
@@ -205,6 +200,7 @@ var procurementDashboardCmd = &cobra.Command{
 				fmt.Printf("  %d. [%s] %s: %s\n", i+1, risk.Level, risk.Category, risk.TopIssue)
 			}
 		}
+		return nil
 	},
 }
 
@@ -212,11 +208,10 @@ var procurementSavingsCmd = &cobra.Command{
 	Use:   "savings <project-id>",
 	Short: "Calculate potential savings for a project",
 	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		id, err := strconv.ParseUint(args[0], 10, 32)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: invalid project ID: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("invalid project ID: %w", err)
 		}
 		projectID := uint(id)
 
@@ -226,8 +221,7 @@ var procurementSavingsCmd = &cobra.Command{
 
 		savings, err := procurementSvc.CalculateProjectSavings(projectID)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			return err
 		}
 
 		fmt.Printf("\nSavings Analysis for Project ID %d\n", projectID)
@@ -262,6 +256,7 @@ var procurementSavingsCmd = &cobra.Command{
 			}
 			tbl.Print()
 		}
+		return nil
 	},
 }
 
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rodaine/table"
+	"github.com/shakfu/buyer/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var requisitionCmd = &cobra.Command{
+	Use:   "requisition",
+	Short: "Submit, approve and reject requisitions",
+	Long: `Review requisitions and project requisitions.
+
+A requisition starts as a draft. Submitting it sends it for review and
+notifies the users who may approve requisitions. A reviewer then approves or
+rejects it; either way the submitter is notified. A submitted or approved
+requisition can no longer be changed, and only approved requisitions can be
+ordered. A rejected requisition can be changed and submitted again.
+
+Approval is blocked when the best-case estimate (the best current quote for
+every item, in USD) exceeds the requisition budget or, for a project
+requisition, what remains of the project budget after its other approved
+requisitions. --override gives the reason to approve anyway.
+
+Add --project-requisition to work on a project requisition instead of a
+requisition. Reviews from the CLI are recorded as BUYER_ACTOR, or the current
+system user.
+
+Examples:
+  buyer requisition submit 3 --comment "For the Q3 hires"
+  buyer requisition queue
+  buyer requisition approve 3 --override "Prices rose this quarter"
+  buyer requisition reject 4 --project-requisition --comment "Split into phases"
+  buyer requisition history 3
+  buyer requisition order 3 --dry-run`,
+}
+
+var requisitionSubmitCmd = &cobra.Command{
+	Use:   "submit [id]",
+	Short: "Submit a requisition for review",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		kind, id := requisitionArgs(cmd, args)
+		comment, _ := cmd.Flags().GetString("comment")
+
+		reviewed, err := services.NewRequisitionReviewService(cfg.DB).Submit(kind, id, cliActor(), comment)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Submitted %s for review.\n", reviewed.Label())
+		printBudgetCheck(reviewed.Check)
+	},
+}
+
+var requisitionApproveCmd = &cobra.Command{
+	Use:   "approve [id]",
+	Short: "Approve a submitted requisition",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		kind, id := requisitionArgs(cmd, args)
+		comment, _ := cmd.Flags().GetString("comment")
+		override, _ := cmd.Flags().GetString("override")
+
+		reviewed, err := services.NewRequisitionReviewService(cfg.DB).Approve(kind, id, cliActor(), comment, override)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Approved %s.\n", reviewed.Label())
+		printBudgetCheck(reviewed.Check)
+	},
+}
+
+var requisitionRejectCmd = &cobra.Command{
+	Use:   "reject [id]",
+	Short: "Reject a submitted requisition, with a comment explaining why",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		kind, id := requisitionArgs(cmd, args)
+		comment, _ := cmd.Flags().GetString("comment")
+
+		reviewed, err := services.NewRequisitionReviewService(cfg.DB).Reject(kind, id, cliActor(), comment)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Rejected %s.\n", reviewed.Label())
+	},
+}
+
+var requisitionQueueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "List the requisitions waiting for review",
+	Run: func(cmd *cobra.Command, args []string) {
+		queue, err := services.NewRequisitionReviewService(cfg.DB).Queue()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(queue) == 0 {
+			fmt.Println("No requisitions are waiting for review.")
+			return
+		}
+
+		tbl := table.New("Kind", "ID", "Name", "Project", "Submitted By", "Items", "Estimate", "Budget", "Budget Check")
+		for _, reviewed := range queue {
+			budget := "-"
+			if reviewed.Budget > 0 {
+				budget = fmt.Sprintf("%.2f", reviewed.Budget)
+			}
+			project := reviewed.ProjectName
+			if project == "" {
+				project = "-"
+			}
+			check := "ok"
+			if !reviewed.Check.OK() {
+				check = "over budget"
+			}
+			tbl.AddRow(reviewed.Kind, reviewed.ID, reviewed.Name, project, reviewed.SubmittedBy, reviewed.Items,
+				fmt.Sprintf("%.2f", reviewed.Check.Estimate), budget, check)
+		}
+		tbl.Print()
+	},
+}
+
+var requisitionHistoryCmd = &cobra.Command{
+	Use:   "history [id]",
+	Short: "Show the review history of a requisition",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		kind, id := requisitionArgs(cmd, args)
+
+		svc := services.NewRequisitionReviewService(cfg.DB)
+		reviewed, err := svc.Get(kind, id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		reviews, err := svc.History(kind, id)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s is %s.\n", reviewed.Label(), reviewed.Status)
+		printBudgetCheck(reviewed.Check)
+		if len(reviews) == 0 {
+			return
+		}
+		fmt.Println()
+		tbl := table.New("Time", "Action", "By", "Comment", "Override Reason")
+		for _, review := range reviews {
+			tbl.AddRow(review.CreatedAt.Local().Format("2006-01-02 15:04"), review.Action, review.Actor, review.Comment, review.OverrideReason)
+		}
+		tbl.Print()
+	},
+}
+
+var requisitionOrderCmd = &cobra.Command{
+	Use:   "order [id]",
+	Short: "Convert an approved requisition to purchase orders",
+	Long: `Create purchase orders for the items of an approved requisition, grouped by
+vendor. Each item is ordered from its best current quote unless --quote picks
+another one, and gets a purchase order with an automatic PO number linked to
+the requisition. Items without a valid quote, or already on an open purchase
+order, are skipped. Either every order is created or none is.
+
+Examples:
+  buyer requisition order 3 --dry-run
+  buyer requisition order 3
+  buyer requisition order 3 --item 7 --item 8 --quote 8=21
+  buyer requisition order 3 --expected-delivery 2025-03-01`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, id := requisitionArgs(cmd, args)
+		itemIDs, _ := cmd.Flags().GetUintSlice("item")
+		quotes, _ := cmd.Flags().GetStringSlice("quote")
+		deliveryStr, _ := cmd.Flags().GetString("expected-delivery")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		input := services.RequisitionOrderInput{RequisitionID: id, ItemIDs: itemIDs, Quotes: map[uint]uint{}}
+		for _, quote := range quotes {
+			itemStr, quoteStr, ok := strings.Cut(quote, "=")
+			itemID, itemErr := strconv.ParseUint(itemStr, 10, 32)
+			quoteID, quoteErr := strconv.ParseUint(quoteStr, 10, 32)
+			if !ok || itemErr != nil || quoteErr != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --quote %q, expected ITEM_ID=QUOTE_ID\n", quote)
+				os.Exit(1)
+			}
+			input.Quotes[uint(itemID)] = uint(quoteID)
+		}
+		if deliveryStr != "" {
+			delivery, err := time.Parse("2006-01-02", deliveryStr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid expected delivery date (use YYYY-MM-DD): %v\n", err)
+				os.Exit(1)
+			}
+			input.ExpectedDelivery = &delivery
+		}
+
+		svc := services.NewPurchaseOrderService(cfg.DB).WithPONumberPattern(poNumberPattern())
+		var order *services.RequisitionOrder
+		var err error
+		if dryRun {
+			order, err = svc.PlanRequisitionOrder(input)
+		} else {
+			order, err = svc.OrderRequisition(input)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(order.Vendors) > 0 {
+			tbl := table.New("Vendor", "PO Number", "Item", "Specification", "Product", "Quantity", "Unit Price", "Total (USD)")
+			for _, vendor := range order.Vendors {
+				for _, line := range vendor.Lines {
+					number := "-"
+					if line.PurchaseOrder != nil {
+						number = line.PurchaseOrder.PONumber
+					}
+					spec, product := "-", "-"
+					if line.Item.Specification != nil {
+						spec = line.Item.Specification.Name
+					}
+					if line.Quote.Product != nil {
+						product = line.Quote.Product.Name
+					}
+					tbl.AddRow(vendor.Vendor.Name, number, line.Item.ID, spec, product, line.Item.Quantity,
+						fmt.Sprintf("%.2f %s", line.Quote.Price, line.Quote.Currency), fmt.Sprintf("%.2f", line.Total))
+				}
+			}
+			tbl.Print()
+		}
+		for _, skipped := range order.Skipped {
+			fmt.Printf("Skipped item %d: %s\n", skipped.Item.ID, skipped.Reason)
+		}
+		if dryRun {
+			lines := 0
+			for _, vendor := range order.Vendors {
+				lines += len(vendor.Lines)
+			}
+			fmt.Printf("Would create %d purchase orders from %d vendors, %.2f USD in total.\n",
+				lines, len(order.Vendors), order.Total())
+			return
+		}
+		fmt.Printf("Created %d purchase orders from %d vendors, %.2f USD in total.\n",
+			len(order.PurchaseOrders()), len(order.Vendors), order.Total())
+	},
+}
+
+// requisitionArgs returns the kind and ID of the requisition a review command
+// works on
+func requisitionArgs(cmd *cobra.Command, args []string) (string, uint) {
+	id, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid ID: %v\n", err)
+		os.Exit(1)
+	}
+	kind := services.KindRequisition
+	if project, _ := cmd.Flags().GetBool("project-requisition"); project {
+		kind = services.KindProjectRequisition
+	}
+	return kind, uint(id)
+}
+
+func printBudgetCheck(check *services.BudgetCheck) {
+	if check == nil {
+		return
+	}
+	fmt.Printf("Best-case estimate: %.2f USD", check.Estimate)
+	if check.MissingQuotes > 0 {
+		fmt.Printf(" (%d items without quotes)", check.MissingQuotes)
+	}
+	fmt.Println()
+	if check.Budget > 0 {
+		fmt.Printf("Requisition budget: %.2f\n", check.Budget)
+	}
+	if check.ProjectBudget > 0 {
+		fmt.Printf("Remaining project budget: %.2f of %.2f\n", check.ProjectRemaining(), check.ProjectBudget)
+	}
+	for _, problem := range check.Problems() {
+		fmt.Printf("Warning: %s\n", problem)
+	}
+}
+
+var notificationsCmd = &cobra.Command{
+	Use:   "notifications",
+	Short: "Show your notifications",
+	Long: `Show the unread notifications of BUYER_ACTOR, or the current system user,
+such as requisitions waiting for review or decisions on your requisitions.
+
+Examples:
+  buyer notifications
+  buyer notifications --all
+  buyer notifications --mark-read`,
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		markRead, _ := cmd.Flags().GetBool("mark-read")
+
+		svc := services.NewNotificationService(cfg.DB)
+		recipient := cliActor()
+		notifications, err := svc.List(recipient, !all, 0)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(notifications) == 0 {
+			fmt.Printf("No notifications for %s.\n", recipient)
+			return
+		}
+
+		tbl := table.New("Time", "Message", "Read")
+		for _, notification := range notifications {
+			read := "no"
+			if notification.ReadAt != nil {
+				read = "yes"
+			}
+			tbl.AddRow(notification.CreatedAt.Local().Format("2006-01-02 15:04"), notification.Message, read)
+		}
+		tbl.Print()
+
+		if markRead {
+			if _, err := svc.MarkAllRead(recipient); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	requisitionCmd.AddCommand(requisitionSubmitCmd)
+	requisitionCmd.AddCommand(requisitionApproveCmd)
+	requisitionCmd.AddCommand(requisitionRejectCmd)
+	requisitionCmd.AddCommand(requisitionQueueCmd)
+	requisitionCmd.AddCommand(requisitionHistoryCmd)
+	requisitionCmd.AddCommand(requisitionOrderCmd)
+
+	for _, cmd := range []*cobra.Command{requisitionSubmitCmd, requisitionApproveCmd, requisitionRejectCmd, requisitionHistoryCmd} {
+		cmd.Flags().BoolP("project-requisition", "p", false, "The ID is a project requisition")
+	}
+	requisitionSubmitCmd.Flags().String("comment", "", "Comment for the reviewers")
+	requisitionApproveCmd.Flags().String("comment", "", "Comment recorded with the approval")
+	requisitionApproveCmd.Flags().String("override", "", "Reason to approve although the estimate exceeds the budget")
+	requisitionRejectCmd.Flags().String("comment", "", "Reason for the rejection (required)")
+	requisitionOrderCmd.Flags().UintSlice("item", nil, "Order only this requisition item (repeatable)")
+	requisitionOrderCmd.Flags().StringSlice("quote", nil, "Order an item from another quote, as ITEM_ID=QUOTE_ID (repeatable)")
+	requisitionOrderCmd.Flags().String("expected-delivery", "", "Expected delivery date of the orders (YYYY-MM-DD)")
+	requisitionOrderCmd.Flags().Bool("dry-run", false, "Show what would be ordered without creating purchase orders")
+
+	notificationsCmd.Flags().Bool("all", false, "Include notifications already read")
+	notificationsCmd.Flags().Bool("mark-read", false, "Mark the notifications as read after showing them")
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rodaine/table"
+	"github.com/shakfu/buyer/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens",
+	Long: `Manage personal access tokens and service account tokens for the web
+server. When BUYER_ENABLE_AUTH=true, requests may authenticate with
+"Authorization: Bearer TOKEN" instead of basic auth.
+
+Scopes:
+  read          read-only access (every token can read)
+  quotes:write  create, update, delete and import quotes
+  po:approve    approve or reject purchase orders and their approval steps
+  admin         every operation
+
+Only a hash of each token is stored; the token is shown once, when it is
+created.`,
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create an API token",
+	Long: `Create an API token and print it. The token cannot be shown again.
+
+Personal tokens belong to --owner (default: BUYER_USERNAME, then the current
+user). With --service-account NAME the token belongs to that service account
+instead, for automation that should not use a person's credentials.
+
+--expires takes a duration such as 90d or 12h, a date (YYYY-MM-DD), or
+"never".`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		owner, _ := cmd.Flags().GetString("owner")
+		serviceAccount, _ := cmd.Flags().GetString("service-account")
+		scopes, _ := cmd.Flags().GetStringSlice("scope")
+		expires, _ := cmd.Flags().GetString("expires")
+
+		if serviceAccount != "" && owner != "" {
+			fmt.Fprintln(os.Stderr, "Error: --owner and --service-account cannot be used together")
+			os.Exit(1)
+		}
+		if serviceAccount != "" {
+			owner = serviceAccount
+		}
+		if owner == "" {
+			owner = defaultTokenOwner()
+		}
+
+		expiresAt, err := parseTokenExpiry(expires, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		token, plaintext, err := services.NewTokenService(cfg.DB).Create(services.CreateTokenInput{
+			Name:           args[0],
+			Owner:          owner,
+			ServiceAccount: serviceAccount != "",
+			Scopes:         scopes,
+			ExpiresAt:      expiresAt,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating token: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Created token: %s (ID: %d)\n", token.Name, token.ID)
+		fmt.Printf("Owner:   %s\n", tokenOwner(token.Owner, token.ServiceAccount))
+		fmt.Printf("Scopes:  %s\n", token.Scopes)
+		fmt.Printf("Expires: %s\n", formatTokenTime(token.ExpiresAt, "never"))
+		fmt.Println()
+		fmt.Println(plaintext)
+		fmt.Println()
+		fmt.Println("Store this token now; it cannot be shown again.")
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API tokens",
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		tokens, err := services.NewTokenService(cfg.DB).List(all)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(tokens) == 0 {
+			fmt.Println("No tokens found.")
+			return
+		}
+
+		now := time.Now()
+		tbl := table.New("ID", "Name", "Owner", "Prefix", "Scopes", "Expires", "Last Used", "Status")
+		for _, token := range tokens {
+			status := "active"
+			switch {
+			case token.RevokedAt != nil:
+				status = "revoked"
+			case token.ExpiresAt != nil && !token.ExpiresAt.After(now):
+				status = "expired"
+			}
+			tbl.AddRow(token.ID, token.Name, tokenOwner(token.Owner, token.ServiceAccount), token.Prefix+"...",
+				token.Scopes, formatTokenTime(token.ExpiresAt, "never"), formatTokenTime(token.LastUsedAt, "-"), status)
+		}
+		tbl.Print()
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke [id]",
+	Short: "Revoke an API token",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid token ID: %s\n", args[0])
+			os.Exit(1)
+		}
+		token, err := services.NewTokenService(cfg.DB).Revoke(uint(id))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error revoking token: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Revoked token: %s (ID: %d, owner: %s)\n", token.Name, token.ID, token.Owner)
+	},
+}
+
+// defaultTokenOwner returns the owner of a personal token when none is given
+func defaultTokenOwner() string {
+	if username := os.Getenv("BUYER_USERNAME"); username != "" {
+		return username
+	}
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	return "admin"
+}
+
+// parseTokenExpiry parses --expires: a duration with an optional d (days)
+// suffix, a YYYY-MM-DD date, or "never"
+func parseTokenExpiry(value string, now time.Time) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "never" {
+		return nil, nil
+	}
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return &date, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid expiry %q", value)
+		}
+		expires := now.AddDate(0, 0, n)
+		return &expires, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("invalid expiry %q (use e.g. 90d, 12h, 2026-12-31 or never)", value)
+	}
+	expires := now.Add(d)
+	return &expires, nil
+}
+
+func tokenOwner(owner string, serviceAccount bool) string {
+	if serviceAccount {
+		return owner + " (service account)"
+	}
+	return owner
+}
+
+func formatTokenTime(t *time.Time, empty string) string {
+	if t == nil {
+		return empty
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+
+	tokenCreateCmd.Flags().StringSlice("scope", []string{services.ScopeRead}, "Scopes: read, quotes:write, po:approve, admin (repeatable or comma-separated)")
+	tokenCreateCmd.Flags().String("owner", "", "User the personal token belongs to")
+	tokenCreateCmd.Flags().String("service-account", "", "Create the token for this service account")
+	tokenCreateCmd.Flags().String("expires", "90d", "Expiry: duration (90d, 12h), date (YYYY-MM-DD) or never")
+
+	tokenListCmd.Flags().Bool("all", false, "Include revoked tokens")
+}
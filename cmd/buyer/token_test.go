@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/services"
+	"gorm.io/gorm"
+)
+
+// setupTokenApp returns an app with user accounts and token auth enabled in
+// front of the JSON API and the HTML routes
+func setupTokenApp(t *testing.T) (*fiber.App, *gorm.DB, *services.TokenService) {
+	t.Helper()
+	_, db := setupTestApp(t)
+	seedTestData(t, db)
+
+	users := services.NewUserService(db)
+	if _, err := users.Create(services.CreateUserInput{Username: "admin", Role: services.RoleAdmin, Password: "Correct-Horse-9"}); err != nil {
+		t.Fatal(err)
+	}
+	tokens := services.NewTokenService(db)
+
+	app := fiber.New()
+	SetupSecurityMiddleware(app, SecurityConfig{
+		EnableAuth: true,
+		EnableCSRF: true,
+		Users:      users,
+		Tokens:     tokens,
+	})
+	setupRoutes(app, db, services.NewSpecificationService(db), services.NewBrandService(db), services.NewProductService(db),
+		services.NewVendorService(db), services.NewRequisitionService(db), services.NewQuoteService(db), services.NewForexService(db),
+		services.NewDashboardService(db), services.NewProjectService(db), services.NewProjectRequisitionService(db),
+		services.NewPurchaseOrderService(db), services.NewDocumentService(db), services.NewVendorRatingService(db))
+	return app, db, tokens
+}
+
+func TestTokenAuth_Scopes(t *testing.T) {
+	app, _, tokens := setupTokenApp(t)
+
+	create := func(name string, scopes ...string) string {
+		_, plaintext, err := tokens.Create(services.CreateTokenInput{Name: name, Owner: "bot", ServiceAccount: true, Scopes: scopes})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return plaintext
+	}
+	readOnly := create("reader", services.ScopeRead)
+	quoter := create("quoter", services.ScopeQuotesWrite)
+	approver := create("approver", services.ScopePOApprove)
+	revoked := create("revoked", services.ScopeAdmin)
+	revokedToken, _ := tokens.Authenticate(revoked)
+	if _, err := tokens.Revoke(revokedToken.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	quote := `{"vendor_id":1,"product_id":1,"price":10,"currency":"USD"}`
+	po := `{"quote_id":1,"po_number":"PO-TOKEN","quantity":1}`
+
+	tests := []struct {
+		name   string
+		auth   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"no credentials", "", "GET", "/api/v1/brands", "", fiber.StatusUnauthorized},
+		{"basic auth", "basic", "GET", "/api/v1/brands", "", fiber.StatusOK},
+		{"unknown token", "Bearer buyer_nope", "GET", "/api/v1/brands", "", fiber.StatusUnauthorized},
+		{"revoked token", "Bearer " + revoked, "GET", "/api/v1/brands", "", fiber.StatusUnauthorized},
+		{"read-only reads", "Bearer " + readOnly, "GET", "/api/v1/brands", "", fiber.StatusOK},
+		{"read-only reads HTML", "Bearer " + readOnly, "GET", "/brands", "", fiber.StatusOK},
+		{"read-only cannot write", "Bearer " + readOnly, "POST", "/api/v1/brands", `{"name":"X"}`, fiber.StatusForbidden},
+		{"read-only cannot add quotes", "Bearer " + readOnly, "POST", "/api/v1/quotes", quote, fiber.StatusForbidden},
+		{"quotes:write adds quotes", "Bearer " + quoter, "POST", "/api/v1/quotes", quote, fiber.StatusCreated},
+		{"quotes:write cannot add brands", "Bearer " + quoter, "POST", "/api/v1/brands", `{"name":"X"}`, fiber.StatusForbidden},
+		{"quotes:write cannot create orders", "Bearer " + quoter, "POST", "/api/v1/purchase-orders", po, fiber.StatusForbidden},
+		{"po:approve cannot add quotes", "Bearer " + approver, "POST", "/api/v1/quotes", quote, fiber.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			switch {
+			case tt.auth == "basic":
+				req.SetBasicAuth("admin", "Correct-Horse-9")
+			case tt.auth != "":
+				req.Header.Set("Authorization", tt.auth)
+			}
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, resp.StatusCode)
+			}
+			if resp.StatusCode == fiber.StatusForbidden && resp.Header.Get("Content-Type") != "application/problem+json" {
+				t.Errorf("expected a problem response, got %q", resp.Header.Get("Content-Type"))
+			}
+		})
+	}
+
+	// po:approve may approve or reject purchase orders, and nothing else
+	_, admin, err := tokens.Create(services.CreateTokenInput{Name: "admin", Owner: "admin", Scopes: []string{services.ScopeAdmin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/api/v1/purchase-orders", strings.NewReader(po))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+admin)
+	if resp, _ := app.Test(req, -1); resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("admin token could not create a purchase order: %d", resp.StatusCode)
+	}
+	req = httptest.NewRequest("PUT", "/api/v1/purchase-orders/1", strings.NewReader(`{"status":"approved"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+approver)
+	if resp, _ := app.Test(req, -1); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("po:approve token could not approve a purchase order: %d", resp.StatusCode)
+	}
+	for _, update := range []struct{ path, body string }{
+		{"/api/v1/purchase-orders/1", `{"status":"ordered"}`},
+		{"/api/v1/purchase-orders/1", `{"status":"approved","invoice_number":"INV-1"}`},
+		{"/purchase-orders/1?status=cancelled", ""},
+		{"/purchase-orders/1?status=approved&invoice=INV-1", ""},
+	} {
+		req = httptest.NewRequest("PUT", update.path, strings.NewReader(update.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+approver)
+		if resp, _ := app.Test(req, -1); resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("expected po:approve token refused %s %s, got %d", update.path, update.body, resp.StatusCode)
+		}
+	}
+}
+
+func TestTokenAuth_ApprovalSteps(t *testing.T) {
+	app, db, tokens := setupTokenApp(t)
+	_, approver, err := tokens.Create(services.CreateTokenInput{Name: "approver", Owner: "bot", ServiceAccount: true, Scopes: []string{services.ScopePOApprove}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := services.NewApprovalService(db).CreateRule(services.ApprovalRuleInput{Step: 1}); err != nil {
+		t.Fatal(err)
+	}
+	po, err := services.NewPurchaseOrderService(db).Create(services.CreatePurchaseOrderInput{QuoteID: 1, PONumber: "PO-STEPS", Quantity: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := func(method, path, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+approver)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	// Approving the order directly waits for its approval steps
+	if status := send("PUT", fmt.Sprintf("/api/v1/purchase-orders/%d", po.ID), `{"status":"approved"}`); status == fiber.StatusOK || status == fiber.StatusForbidden {
+		t.Errorf("expected the order's approval steps to be required, got %d", status)
+	}
+	if status := send("POST", fmt.Sprintf("/approvals/%d/approve", po.ID), ""); status != fiber.StatusOK {
+		t.Fatalf("expected a po:approve token to approve the step, got %d", status)
+	}
+	var approved models.PurchaseOrder
+	if err := db.First(&approved, po.ID).Error; err != nil || approved.Status != "approved" {
+		t.Errorf("expected the order approved, got %q (err: %v)", approved.Status, err)
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		method, path, decision, want string
+	}{
+		{"GET", "/api/v1/purchase-orders", "", services.ScopeRead},
+		{"HEAD", "/quotes", "", services.ScopeRead},
+		{"POST", "/api/v1/quotes", "", services.ScopeQuotesWrite},
+		{"PUT", "/api/v1/quotes/3", "", services.ScopeQuotesWrite},
+		{"DELETE", "/quotes/3", "", services.ScopeQuotesWrite},
+		{"POST", "/import/quotes", "", services.ScopeQuotesWrite},
+		{"PUT", "/api/v1/purchase-orders/3", "approved", services.ScopePOApprove},
+		{"PUT", "/purchase-orders/3", "rejected", services.ScopePOApprove},
+		{"POST", "/approvals/3/approve", "", services.ScopePOApprove},
+		{"POST", "/approvals/3/reject", "", services.ScopePOApprove},
+		{"PUT", "/api/v1/purchase-orders/3", "ordered", services.ScopeAdmin},
+		{"PUT", "/purchase-orders/3", "", services.ScopeAdmin},
+		{"POST", "/api/v1/purchase-orders", "", services.ScopeAdmin},
+		{"DELETE", "/api/v1/purchase-orders/3", "", services.ScopeAdmin},
+		{"POST", "/api/v1/brands", "", services.ScopeAdmin},
+		{"POST", "/import/brands", "", services.ScopeAdmin},
+	}
+	for _, tt := range tests {
+		if got := requiredScope(tt.method, tt.path, tt.decision); got != tt.want {
+			t.Errorf("requiredScope(%s %s %q) = %s, want %s", tt.method, tt.path, tt.decision, got, tt.want)
+		}
+	}
+}
+
+func TestParseTokenExpiry(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value   string
+		want    *time.Time
+		wantErr bool
+	}{
+		{value: "never"},
+		{value: ""},
+		{value: "30d", want: timePtr(now.AddDate(0, 0, 30))},
+		{value: "12h", want: timePtr(now.Add(12 * time.Hour))},
+		{value: "2025-12-31", want: timePtr(time.Date(2025, 12, 31, 0, 0, 0, 0, time.Local))},
+		{value: "0d", wantErr: true},
+		{value: "-1h", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseTokenExpiry(tt.value, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseTokenExpiry(%q): expected an error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseTokenExpiry(%q): %v", tt.value, err)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+			t.Errorf("parseTokenExpiry(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
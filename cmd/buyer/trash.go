@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rodaine/table"
+	"github.com/shakfu/buyer/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var trashCmd = &cobra.Command{
+	Use:   "trash [entity]",
+	Short: "List deleted records that can be restored",
+	Long: `List the records in the trash, most recently deleted first, optionally for
+one entity only.
+
+Deleting a brand, product, vendor, quote, specification, requisition, project,
+purchase order or vendor rating moves it to the trash. Records deleted along
+with it, such as the quotes of a product or the ratings of a vendor, are listed
+below it and are restored with it.
+
+Examples:
+  buyer trash
+  buyer trash product
+  buyer restore product 12
+  buyer purge --older-than 30d`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		entity := ""
+		if len(args) == 1 {
+			var err error
+			if entity, err = services.TrashEntity(args[0]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		items, err := services.NewTrashService(cfg.DB).List(entity)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(items) == 0 {
+			fmt.Println("The trash is empty.")
+			return
+		}
+
+		tbl := table.New("Entity", "ID", "Name", "Deleted", "Deleted By")
+		var addRows func(items []services.TrashItem, indent string)
+		addRows = func(items []services.TrashItem, indent string) {
+			for _, item := range items {
+				deletedBy := item.DeletedBy
+				if deletedBy == "" {
+					deletedBy = "-"
+				}
+				tbl.AddRow(indent+item.Entity, item.ID, item.Title, item.DeletedAt.Local().Format("2006-01-02 15:04:05"), deletedBy)
+				addRows(item.Dependents, indent+"  ")
+			}
+		}
+		addRows(items, "")
+		tbl.Print()
+	},
+}
+
+// restoreFromTrash runs 'buyer restore <entity> <id>'
+func restoreFromTrash(entityArg, idArg string) {
+	entity, err := services.TrashEntity(entityArg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	id, err := strconv.ParseUint(idArg, 10, 32)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid ID: %v\n", err)
+		os.Exit(1)
+	}
+
+	item, err := services.NewTrashService(cfg.DB).Restore(entity, uint(id))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Restored %s %d (%s)\n", item.Entity, item.ID, item.Title)
+	for _, dependent := range item.Dependents {
+		fmt.Printf("  and %s %d (%s)\n", dependent.Entity, dependent.ID, dependent.Title)
+	}
+}
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Permanently delete records that have been in the trash too long",
+	Long: `Permanently delete the records that were moved to the trash longer ago than
+--older-than. Purged records cannot be restored.
+
+Examples:
+  buyer purge --dry-run
+  buyer purge --older-than 90d
+  buyer purge --older-than 0 --force`,
+	Run: func(cmd *cobra.Command, args []string) {
+		olderThan, _ := cmd.Flags().GetString("older-than")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		force, _ := cmd.Flags().GetBool("force")
+
+		retention, err := parseRetention(olderThan)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		svc := services.NewTrashService(cfg.DB)
+		counts, err := svc.Purge(retention, true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(counts) == 0 {
+			fmt.Println("Nothing to purge.")
+			return
+		}
+		printPurgeCounts(counts)
+		if dryRun {
+			fmt.Println("Dry run: nothing was deleted.")
+			return
+		}
+		if !force && !confirmPurge() {
+			fmt.Println("Purge cancelled.")
+			return
+		}
+
+		if counts, err = svc.Purge(retention, false); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		total := int64(0)
+		for _, count := range counts {
+			total += count.Count
+		}
+		fmt.Printf("Purged %d records.\n", total)
+	},
+}
+
+func printPurgeCounts(counts []services.PurgeCount) {
+	tbl := table.New("Entity", "Records")
+	for _, count := range counts {
+		tbl.AddRow(count.Entity, count.Count)
+	}
+	tbl.Print()
+}
+
+func confirmPurge() bool {
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Print("Permanently delete these records? (y/N): ")
+	response, _ := reader.ReadString('\n')
+	response = strings.ToLower(strings.TrimSpace(response))
+	return response == "y" || response == "yes"
+}
+
+// parseRetention parses --older-than: a duration with an optional d (days)
+// suffix
+func parseRetention(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid retention %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid retention %q (use e.g. 30d, 12h or 0)", value)
+	}
+	return d, nil
+}
+
+func init() {
+	purgeCmd.Flags().String("older-than", "30d", "Only purge records deleted longer ago than this (e.g. 30d, 12h, 0 for all)")
+	purgeCmd.Flags().Bool("dry-run", false, "Show what would be purged without deleting anything")
+	purgeCmd.Flags().BoolP("force", "f", false, "Skip confirmation prompt")
+}
@@ -299,7 +299,7 @@ func init() {
 	updateSpecificationCmd.Flags().String("description", "", "New description for the specification")
 
 	// Purchase Order flags
-	updatePurchaseOrderCmd.Flags().String("status", "", "New status (pending, approved, ordered, shipped, received, cancelled)")
+	updatePurchaseOrderCmd.Flags().String("status", "", "New status (pending, approved, rejected, ordered, shipped, received, cancelled)")
 	updatePurchaseOrderCmd.Flags().String("invoice", "", "Invoice number")
 	updatePurchaseOrderCmd.Flags().String("actual-delivery", "", "Actual delivery date (YYYY-MM-DD)")
 
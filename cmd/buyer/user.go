@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rodaine/table"
+	"github.com/shakfu/buyer/internal/services"
+	"github.com/spf13/cobra"
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage web user accounts",
+	Long: `Manage the user accounts that sign in to the web server when
+BUYER_ENABLE_AUTH=true. Every user may read; the role decides what they may
+change:
+
+  requester  requisitions and project requisitions
+  buyer      requisitions, quotes, purchase orders (except approval), the
+             catalog (specifications, brands, products), vendors and projects
+  approver   approving purchase orders
+  finance    forex rates and vendors
+  admin      everything, including imports with profiles
+
+All roles may attach documents.`,
+}
+
+var userAddCmd = &cobra.Command{
+	Use:   "add [username]",
+	Short: "Add a user",
+	Long: `Add a user. The password is read from standard input, either after a
+prompt or, with --password-stdin, as the first line of piped input. It must be
+at least 12 characters and mix upper and lower case letters, digits and
+special characters.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		role, _ := cmd.Flags().GetString("role")
+		name, _ := cmd.Flags().GetString("name")
+		email, _ := cmd.Flags().GetString("email")
+		fromStdin, _ := cmd.Flags().GetBool("password-stdin")
+
+		password, err := readPassword(os.Stdin, fromStdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		user, err := services.NewUserService(cfg.DB).Create(services.CreateUserInput{
+			Username: args[0],
+			Name:     name,
+			Email:    email,
+			Role:     role,
+			Password: password,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating user: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Created user: %s (ID: %d, role: %s)\n", user.Username, user.ID, user.Role)
+	},
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List users",
+	Run: func(cmd *cobra.Command, args []string) {
+		users, err := services.NewUserService(cfg.DB).List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(users) == 0 {
+			fmt.Println("No users found.")
+			return
+		}
+
+		tbl := table.New("ID", "Username", "Name", "Email", "Role", "Last Login", "Status")
+		for _, user := range users {
+			status := "active"
+			if user.Disabled {
+				status = "disabled"
+			}
+			tbl.AddRow(user.ID, user.Username, user.Name, user.Email, user.Role,
+				formatTokenTime(user.LastLoginAt, "never"), status)
+		}
+		tbl.Print()
+	},
+}
+
+var userSetRoleCmd = &cobra.Command{
+	Use:   "set-role [username] [role]",
+	Short: "Change a user's role",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		user, err := services.NewUserService(cfg.DB).SetRole(args[0], args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("User %s now has role %s\n", user.Username, user.Role)
+	},
+}
+
+var userPasswdCmd = &cobra.Command{
+	Use:   "passwd [username]",
+	Short: "Set a user's password",
+	Long:  "Set a user's password and sign out their sessions. The password is read as for 'user add'.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fromStdin, _ := cmd.Flags().GetBool("password-stdin")
+		password, err := readPassword(os.Stdin, fromStdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		user, err := services.NewUserService(cfg.DB).SetPassword(args[0], password)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Password changed for %s\n", user.Username)
+	},
+}
+
+var userDisableCmd = &cobra.Command{
+	Use:   "disable [username]",
+	Short: "Disable a user and sign out their sessions",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		user, err := services.NewUserService(cfg.DB).SetDisabled(args[0], true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Disabled user %s\n", user.Username)
+	},
+}
+
+var userEnableCmd = &cobra.Command{
+	Use:   "enable [username]",
+	Short: "Re-enable a disabled user",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		user, err := services.NewUserService(cfg.DB).SetDisabled(args[0], false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Enabled user %s\n", user.Username)
+	},
+}
+
+// readPassword reads a password from the first line of r, prompting on
+// stderr unless the input is piped (fromStdin)
+func readPassword(r io.Reader, fromStdin bool) (string, error) {
+	if !fromStdin {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("password cannot be empty")
+	}
+	return password, nil
+}
+
+func init() {
+	userCmd.AddCommand(userAddCmd)
+	userCmd.AddCommand(userListCmd)
+	userCmd.AddCommand(userSetRoleCmd)
+	userCmd.AddCommand(userPasswdCmd)
+	userCmd.AddCommand(userDisableCmd)
+	userCmd.AddCommand(userEnableCmd)
+
+	userAddCmd.Flags().String("role", services.RoleRequester, "Role: requester, buyer, approver, finance or admin")
+	userAddCmd.Flags().String("name", "", "Full name")
+	userAddCmd.Flags().String("email", "", "Email address")
+	userAddCmd.Flags().Bool("password-stdin", false, "Read the password from piped standard input without a prompt")
+	userPasswdCmd.Flags().Bool("password-stdin", false, "Read the password from piped standard input without a prompt")
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"io/fs"
@@ -8,6 +11,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
@@ -24,11 +28,17 @@ import (
 	"gorm.io/gorm"
 )
 
+// maxUploadSize is the largest request body, such as an uploaded document
+const maxUploadSize = 32 << 20
+
 var webCmd = &cobra.Command{
 	Use:   "web",
 	Short: "Start the web server",
 	Long:  "Start the FastAPI-inspired web server with HTMX support",
 	Run: func(cmd *cobra.Command, args []string) {
+		// Changes are made by signed-in users here, not by the CLI user
+		cfg.DB = cfg.DB.WithContext(models.WithSource(context.Background(), models.SourceWeb))
+
 		// Get port from flag or config (which reads from environment)
 		port, _ := cmd.Flags().GetInt("port")
 		// If port is still default 8080, check if config has a different value from env
@@ -37,7 +47,8 @@ var webCmd = &cobra.Command{
 		}
 
 		app := fiber.New(fiber.Config{
-			AppName: fmt.Sprintf("Buyer %s", Version),
+			AppName:   fmt.Sprintf("Buyer %s", Version),
+			BodyLimit: maxUploadSize,
 		})
 
 		// Middleware
@@ -49,49 +60,40 @@ var webCmd = &cobra.Command{
 		var securityConfig SecurityConfig
 
 		if enableAuth {
-			// If auth is enabled, username and password are required (no defaults)
-			username := os.Getenv("BUYER_USERNAME")
-			password := os.Getenv("BUYER_PASSWORD")
-
-			if username == "" {
-				slog.Error("BUYER_USERNAME is required when BUYER_ENABLE_AUTH=true")
-				fmt.Fprintln(os.Stderr, "Error: BUYER_USERNAME environment variable is required when authentication is enabled")
+			users := services.NewUserService(cfg.DB)
+			sso, err := oidcConfigFromEnv(context.Background())
+			if err != nil {
+				slog.Error("single sign-on setup failed", slog.String("error", err.Error()))
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
-
-			if password == "" {
-				slog.Error("BUYER_PASSWORD is required when BUYER_ENABLE_AUTH=true")
-				fmt.Fprintln(os.Stderr, "Error: BUYER_PASSWORD environment variable is required when authentication is enabled")
-				os.Exit(1)
+			if sso != nil {
+				slog.Info("single sign-on enabled", slog.String("issuer", sso.Provider.Metadata().Issuer))
 			}
-
-			// Validate password strength
-			if err := ValidatePassword(password); err != nil {
-				slog.Error("invalid password", slog.String("error", err.Error()))
-				fmt.Fprintf(os.Stderr, "Error: Invalid password - %v\n", err)
-				fmt.Fprintln(os.Stderr, "Password requirements:")
-				fmt.Fprintln(os.Stderr, "  - At least 12 characters long")
-				fmt.Fprintln(os.Stderr, "  - Contains at least one uppercase letter")
-				fmt.Fprintln(os.Stderr, "  - Contains at least one lowercase letter")
-				fmt.Fprintln(os.Stderr, "  - Contains at least one digit")
-				fmt.Fprintln(os.Stderr, "  - Contains at least one special character")
+			if err := bootstrapAdmin(users, sso != nil); err != nil {
+				slog.Error("no user accounts", slog.String("error", err.Error()))
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
 
-			// Hash the password
-			passwordHash, err := HashPassword(password)
-			if err != nil {
-				slog.Error("failed to hash password", slog.String("error", err.Error()))
-				fmt.Fprintf(os.Stderr, "Error: Failed to hash password - %v\n", err)
-				os.Exit(1)
+			sessionTTL := services.DefaultSessionTTL
+			if value := os.Getenv("BUYER_SESSION_TTL"); value != "" {
+				parsed, err := time.ParseDuration(value)
+				if err != nil || parsed <= 0 {
+					fmt.Fprintf(os.Stderr, "Error: invalid BUYER_SESSION_TTL %q (use e.g. 8h)\n", value)
+					os.Exit(1)
+				}
+				sessionTTL = parsed
 			}
 
 			securityConfig = SecurityConfig{
 				EnableAuth:        true,
 				EnableCSRF:        os.Getenv("BUYER_ENABLE_CSRF") == "true",
 				EnableRateLimiter: true, // Always enabled for security
-				Username:          username,
-				PasswordHash:      passwordHash,
+				Users:             users,
+				Tokens:            services.NewTokenService(cfg.DB),
+				SessionTTL:        sessionTTL,
+				OIDC:              sso,
 			}
 		} else {
 			securityConfig = SecurityConfig{
@@ -103,6 +105,11 @@ var webCmd = &cobra.Command{
 
 		SetupSecurityMiddleware(app, securityConfig)
 
+		if _, err := services.ParsePONumberPattern(poNumberPattern()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid BUYER_PO_NUMBER_PATTERN: %v\n", err)
+			os.Exit(1)
+		}
+
 		// Static files - extract subdirectory from embedded FS
 		staticSubFS, err := fs.Sub(web.StaticFS, "static")
 		if err != nil {
@@ -119,6 +126,13 @@ var webCmd = &cobra.Command{
 			slog.Bool("csrf_enabled", securityConfig.EnableCSRF),
 			slog.Bool("rate_limiter_enabled", securityConfig.EnableRateLimiter))
 
+		// Document files, in the storage selected by DOCUMENT_STORAGE_TYPE
+		store, err := documentStorage()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 		// Services
 		specSvc := services.NewSpecificationService(cfg.DB)
 		brandSvc := services.NewBrandService(cfg.DB)
@@ -130,8 +144,8 @@ var webCmd = &cobra.Command{
 		dashboardSvc := services.NewDashboardService(cfg.DB)
 		projectSvc := services.NewProjectService(cfg.DB)
 		projectReqSvc := services.NewProjectRequisitionService(cfg.DB)
-		poSvc := services.NewPurchaseOrderService(cfg.DB)
-		docSvc := services.NewDocumentService(cfg.DB)
+		poSvc := services.NewPurchaseOrderService(cfg.DB).WithPONumberPattern(poNumberPattern())
+		docSvc := services.NewDocumentService(cfg.DB).WithStorage(store)
 		ratingsSvc := services.NewVendorRatingService(cfg.DB)
 
 		slog.Debug("services initialized successfully")
@@ -139,6 +153,21 @@ var webCmd = &cobra.Command{
 		// Routes
 		setupRoutes(app, cfg.DB, specSvc, brandSvc, productSvc, vendorSvc, requisitionSvc, quoteSvc, forexSvc, dashboardSvc, projectSvc, projectReqSvc, poSvc, docSvc, ratingsSvc)
 
+		// Optional scheduled backups
+		backupInterval, backupDir, backupKeep, err := backupScheduleFromFlags(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if backupInterval > 0 {
+			stopBackups := startBackupScheduler(cfg.DB, store, backupInterval, backupDir, backupKeep)
+			defer stopBackups()
+			slog.Info("scheduled backups enabled",
+				slog.String("interval", backupInterval.String()),
+				slog.String("dir", backupDir),
+				slog.Int("keep", backupKeep))
+		}
+
 		// Setup graceful shutdown
 		c := make(chan os.Signal, 1)
 		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
@@ -170,6 +199,35 @@ var webCmd = &cobra.Command{
 	},
 }
 
+// bootstrapAdmin makes sure a user can sign in when authentication is on. If
+// there are no users yet, an admin is created from BUYER_USERNAME and
+// BUYER_PASSWORD. With single sign-on, users may instead arrive from the
+// identity provider, so no local account is required.
+func bootstrapAdmin(users *services.UserService, sso bool) error {
+	count, err := users.Count()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	username := os.Getenv("BUYER_USERNAME")
+	password := os.Getenv("BUYER_PASSWORD")
+	if username == "" || password == "" {
+		if sso {
+			return nil
+		}
+		return fmt.Errorf("authentication is enabled but there are no users; create one with 'buyer user add NAME --role admin' or set BUYER_USERNAME and BUYER_PASSWORD to create the first admin")
+	}
+	user, err := users.Create(services.CreateUserInput{Username: username, Role: services.RoleAdmin, Password: password})
+	if err != nil {
+		return fmt.Errorf("failed to create admin %s from BUYER_USERNAME/BUYER_PASSWORD: %w", username, err)
+	}
+	slog.Info("created admin user from BUYER_USERNAME", slog.String("username", user.Username))
+	return nil
+}
+
 func setupRoutes(
 	app *fiber.App,
 	db *gorm.DB,
@@ -187,6 +245,12 @@ func setupRoutes(
 	docSvc *services.DocumentService,
 	ratingsSvc *services.VendorRatingService,
 ) {
+	// Role checks for signed-in users; see requiredPermission
+	app.Use(authorizeRoutes)
+
+	// Record whether changes came from a page or the JSON API in the audit log
+	app.Use(auditSource)
+
 	// Home page
 	app.Get("/", func(c *fiber.Ctx) error {
 		return renderTemplate(c, "index.html", fiber.Map{
@@ -332,7 +396,7 @@ func setupRoutes(
 			Description:     description,
 		}
 
-		if err := db.Create(attr).Error; err != nil {
+		if err := db.WithContext(c.UserContext()).Create(attr).Error; err != nil {
 			return c.Status(400).SendString(err.Error())
 		}
 
@@ -401,7 +465,7 @@ func setupRoutes(
 		}
 
 		// Delete the attribute (cascade will delete product attributes)
-		if err := db.Delete(&models.SpecificationAttribute{}, attrID).Error; err != nil {
+		if err := db.WithContext(c.UserContext()).Delete(&models.SpecificationAttribute{}, attrID).Error; err != nil {
 			return c.Status(400).SendString(err.Error())
 		}
 
@@ -764,11 +828,26 @@ func setupRoutes(
 		if err != nil {
 			return err
 		}
+		projects, err := projectSvc.List(0, 0)
+		if err != nil {
+			return err
+		}
+		projectRequisitions, err := projectReqSvc.List(0, 0)
+		if err != nil {
+			return err
+		}
+		coding, err := codingForm(db, "", nil, nil)
+		if err != nil {
+			return err
+		}
 		return renderTemplate(c, "purchase-orders.html", fiber.Map{
-			"Title":          "Purchase Orders",
-			"PurchaseOrders": orders,
-			"Quotes":         quotes,
-			"Requisitions":   requisitions,
+			"Title":               "Purchase Orders",
+			"PurchaseOrders":      orders,
+			"Coding":              coding,
+			"Quotes":              quotes,
+			"Requisitions":        requisitions,
+			"Projects":            projects,
+			"ProjectRequisitions": projectRequisitions,
 			"Breadcrumb": []map[string]interface{}{
 				{"Name": "Purchase Orders", "Active": true},
 			},
@@ -784,9 +863,19 @@ func setupRoutes(
 		if err != nil {
 			return c.Status(404).SendString("Purchase order not found")
 		}
+		approvals, err := services.NewApprovalService(db).WithContext(c.UserContext()).ForPurchaseOrder(po.ID)
+		if err != nil {
+			return err
+		}
+		coding, err := codingForm(db, fmt.Sprintf("/purchase-orders/%d/coding", po.ID), po.CostCenterID, po.GLAccountID)
+		if err != nil {
+			return err
+		}
 		return renderTemplate(c, "purchase-order-detail.html", fiber.Map{
 			"Title":         po.PONumber,
 			"PurchaseOrder": po,
+			"Approvals":     approvals,
+			"Coding":        coding,
 			"Breadcrumb": []map[string]interface{}{
 				{"Name": "Purchase Orders", "URL": "/purchase-orders"},
 				{"Name": po.PONumber, "Active": true},
@@ -794,6 +883,54 @@ func setupRoutes(
 		})
 	})
 
+	// Printing a purchase order only renders it; saving it as a document of
+	// the order is a POST, so that it needs the permission to issue orders
+	app.Get("/purchase-orders/:id/pdf", func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			return c.Status(400).SendString("Invalid purchase order ID")
+		}
+		opts, err := purchaseOrderPDFOptions("")
+		if err != nil {
+			return err
+		}
+		po, err := poSvc.WithContext(c.UserContext()).GetByID(uint(id))
+		if err != nil {
+			var notFoundErr *services.NotFoundError
+			if errors.As(err, &notFoundErr) {
+				return c.Status(404).SendString("Purchase order not found")
+			}
+			return err
+		}
+		var buf bytes.Buffer
+		if err := services.NewPurchaseOrderPDFService(db).WithContext(c.UserContext()).WritePDF(&buf, po.ID, opts); err != nil {
+			return err
+		}
+		c.Set("Content-Type", "application/pdf")
+		c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", po.PONumber+".pdf"))
+		return c.Send(buf.Bytes())
+	})
+
+	app.Post("/purchase-orders/:id/pdf", func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			return c.Status(400).SendString("Invalid purchase order ID")
+		}
+		opts, err := purchaseOrderPDFOptions("")
+		if err != nil {
+			return err
+		}
+		if _, _, err := services.NewPurchaseOrderPDFService(db).WithContext(c.UserContext()).SavePDF(uint(id), docSvc, opts); err != nil {
+			var notFoundErr *services.NotFoundError
+			if errors.As(err, &notFoundErr) {
+				return c.Status(404).SendString("Purchase order not found")
+			}
+			return err
+		}
+		c.Set("HX-Refresh", "true")
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
 	// Requisition routes
 	app.Get("/requisitions", func(c *fiber.Ctx) error {
 		requisitions, err := requisitionSvc.List(0, 0)
@@ -962,9 +1099,15 @@ func setupRoutes(
 			return err
 		}
 
+		coding, err := codingForm(db, fmt.Sprintf("/projects/%d/coding", project.ID), project.CostCenterID, project.GLAccountID)
+		if err != nil {
+			return err
+		}
+
 		return renderTemplate(c, "project-detail.html", fiber.Map{
 			"Title":               project.Name,
 			"Project":             project,
+			"Coding":              coding,
 			"Specifications":      specs,
 			"ProjectRequisitions": projectReqs,
 			"Breadcrumb": []map[string]interface{}{
@@ -1000,12 +1143,18 @@ func setupRoutes(
 			return err
 		}
 
+		projectSpend, err := dashboardSvc.GetProjectSpend(uint(id))
+		if err != nil {
+			return err
+		}
+
 		return renderTemplate(c, "project-dashboard.html", fiber.Map{
 			"Title":              project.Name + " - Dashboard",
 			"Project":            project,
 			"ProjectStats":       projectStats,
 			"BOMItemQuantities":  bomItemQuantities,
 			"RequisitionBudgets": requisitionBudgets,
+			"ProjectSpend":       projectSpend,
 			"Breadcrumb": []map[string]interface{}{
 				{"Name": "Projects", "URL": "/projects"},
 				{"Name": project.Name, "URL": fmt.Sprintf("/projects/%d", project.ID)},
@@ -1053,6 +1202,48 @@ func setupRoutes(
 		return c.SendString(html.String())
 	})
 
+	// Convert the items ticked on the comparison page to purchase orders
+	app.Post("/requisitions/:id/order", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
+		}
+
+		input := services.RequisitionOrderInput{RequisitionID: uint(id), Quotes: map[uint]uint{}}
+		for i := 0; ; i++ {
+			itemIDStr := c.FormValue(fmt.Sprintf("items[%d][item_id]", i))
+			if itemIDStr == "" {
+				break // No more items
+			}
+			if c.FormValue(fmt.Sprintf("items[%d][order]", i)) != "true" {
+				continue
+			}
+			itemID, err := strconv.ParseUint(itemIDStr, 10, 32)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString("Invalid item ID")
+			}
+			quoteID, err := strconv.ParseUint(c.FormValue(fmt.Sprintf("items[%d][quote_id]", i)), 10, 32)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString("Invalid quote ID")
+			}
+			input.ItemIDs = append(input.ItemIDs, uint(itemID))
+			input.Quotes[uint(itemID)] = uint(quoteID)
+		}
+		if len(input.ItemIDs) == 0 {
+			return c.Status(fiber.StatusBadRequest).SendString("Select at least one item to order")
+		}
+
+		order, err := poSvc.WithContext(c.UserContext()).OrderRequisition(input)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+		}
+		html, err := RenderRequisitionOrder(order)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to render order")
+		}
+		return c.SendString(html.String())
+	})
+
 	// Setup CRUD handlers for all entities
 	SetupCRUDHandlers(app, db, specSvc, brandSvc, productSvc, vendorSvc, requisitionSvc, quoteSvc, forexSvc)
 
@@ -1077,6 +1268,26 @@ func setupRoutes(
 			reqIDPtr = &reqIDUint
 		}
 
+		var projectIDPtr *uint
+		if projectIDStr := c.FormValue("project_id"); projectIDStr != "" {
+			projectID, err := strconv.ParseUint(projectIDStr, 10, 32)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString("Invalid project ID")
+			}
+			projectIDUint := uint(projectID)
+			projectIDPtr = &projectIDUint
+		}
+
+		var projectItemIDPtr *uint
+		if projectItemIDStr := c.FormValue("project_requisition_item_id"); projectItemIDStr != "" {
+			projectItemID, err := strconv.ParseUint(projectItemIDStr, 10, 32)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString("Invalid project requisition item ID")
+			}
+			projectItemIDUint := uint(projectItemID)
+			projectItemIDPtr = &projectItemIDUint
+		}
+
 		quantity, err := strconv.Atoi(c.FormValue("quantity"))
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid quantity")
@@ -1092,18 +1303,31 @@ func setupRoutes(
 			expectedDelivery = &parsed
 		}
 
+		costCenterID, err := formCodingID(c, "cost_center_id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid cost center ID")
+		}
+		glAccountID, err := formCodingID(c, "gl_account_id")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid GL account ID")
+		}
+
 		shippingCost, _ := strconv.ParseFloat(c.FormValue("shipping_cost"), 64)
 		tax, _ := strconv.ParseFloat(c.FormValue("tax"), 64)
 
-		po, err := poSvc.Create(services.CreatePurchaseOrderInput{
-			QuoteID:          uint(quoteID),
-			RequisitionID:    reqIDPtr,
-			PONumber:         c.FormValue("po_number"),
-			Quantity:         quantity,
-			ExpectedDelivery: expectedDelivery,
-			ShippingCost:     shippingCost,
-			Tax:              tax,
-			Notes:            c.FormValue("notes"),
+		po, err := poSvc.WithContext(c.UserContext()).Create(services.CreatePurchaseOrderInput{
+			QuoteID:                  uint(quoteID),
+			RequisitionID:            reqIDPtr,
+			ProjectRequisitionItemID: projectItemIDPtr,
+			ProjectID:                projectIDPtr,
+			PONumber:                 c.FormValue("po_number"),
+			Quantity:                 quantity,
+			ExpectedDelivery:         expectedDelivery,
+			ShippingCost:             shippingCost,
+			Tax:                      tax,
+			Notes:                    c.FormValue("notes"),
+			CostCenterID:             costCenterID,
+			GLAccountID:              glAccountID,
 		})
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
@@ -1125,6 +1349,7 @@ func setupRoutes(
 		status := c.Query("status")
 		invoice := c.Query("invoice")
 		actualDeliveryStr := c.Query("actual_delivery")
+		poSvc := poSvc.WithContext(c.UserContext())
 
 		if status != "" {
 			_, err := poSvc.UpdateStatus(uint(id), status)
@@ -1168,34 +1393,44 @@ func setupRoutes(
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
 		}
-		if err := poSvc.Delete(uint(id)); err != nil {
+		if err := poSvc.WithContext(c.UserContext()).Delete(uint(id)); err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
 		return c.SendString("")
 	})
 
 	// Document CRUD handlers
+	// Documents are uploaded as multipart forms with the file in "file"
 	app.Post("/documents", func(c *fiber.Ctx) error {
 		entityType := c.FormValue("entity_type")
 		entityID, err := strconv.ParseUint(c.FormValue("entity_id"), 10, 32)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid entity ID")
 		}
+		file, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("No file uploaded")
+		}
+		src, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to open uploaded file")
+		}
+		defer src.Close()
 
-		fileSize, _ := strconv.ParseInt(c.FormValue("file_size"), 10, 64)
-
-		doc, err := docSvc.Create(services.CreateDocumentInput{
+		doc, err := docSvc.WithContext(c.UserContext()).Upload(services.CreateDocumentInput{
 			EntityType:  entityType,
 			EntityID:    uint(entityID),
-			FileName:    c.FormValue("file_name"),
-			FileType:    c.FormValue("file_type"),
-			FileSize:    fileSize,
-			FilePath:    c.FormValue("file_path"),
+			FileName:    filepath.Base(file.Filename),
+			FileSize:    file.Size,
 			Description: c.FormValue("description"),
-			UploadedBy:  c.FormValue("uploaded_by"),
-		})
+			UploadedBy:  actorOr(c.UserContext(), c.FormValue("uploaded_by")),
+		}, src)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+			var validationErr *services.ValidationError
+			if errors.As(err, &validationErr) {
+				return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+			}
+			return err
 		}
 
 		html, err := RenderDocumentRow(doc)
@@ -1205,12 +1440,30 @@ func setupRoutes(
 		return c.SendString(html.String())
 	})
 
+	// The file of a document, streamed from storage
+	app.Get("/documents/:id/download", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
+		}
+		reader, doc, err := docSvc.WithContext(c.UserContext()).Download(uint(id))
+		if err != nil {
+			var notFoundErr *services.NotFoundError
+			if errors.As(err, &notFoundErr) {
+				return c.Status(fiber.StatusNotFound).SendString(escapeHTML(err.Error()))
+			}
+			return err
+		}
+		c.Attachment(doc.FileName)
+		return c.SendStream(reader)
+	})
+
 	app.Delete("/documents/:id", func(c *fiber.Ctx) error {
 		id, err := strconv.ParseUint(c.Params("id"), 10, 32)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
 		}
-		if err := docSvc.Delete(uint(id)); err != nil {
+		if err := docSvc.WithContext(c.UserContext()).Delete(uint(id)); err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
 		return c.SendString("")
@@ -1256,7 +1509,7 @@ func setupRoutes(
 			}
 		}
 
-		rating, err := ratingsSvc.Create(services.CreateVendorRatingInput{
+		rating, err := ratingsSvc.WithContext(c.UserContext()).Create(services.CreateVendorRatingInput{
 			VendorID:        uint(vendorID),
 			PurchaseOrderID: poIDPtr,
 			PriceRating:     pricePtr,
@@ -1264,7 +1517,7 @@ func setupRoutes(
 			DeliveryRating:  deliveryPtr,
 			ServiceRating:   servicePtr,
 			Comments:        c.FormValue("comments"),
-			RatedBy:         c.FormValue("rated_by"),
+			RatedBy:         actorOr(c.UserContext(), c.FormValue("rated_by")),
 		})
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
@@ -1282,7 +1535,7 @@ func setupRoutes(
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
 		}
-		if err := ratingsSvc.Delete(uint(id)); err != nil {
+		if err := ratingsSvc.WithContext(c.UserContext()).Delete(uint(id)); err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
 		return c.SendString("")
@@ -1293,12 +1546,36 @@ func setupRoutes(
 
 	// Setup procurement handlers
 	registerProcurementRoutes(app)
+
+	// Change history of a record, loaded into detail pages
+	registerHistoryRoutes(app, db)
+
+	// Deleted records and restoring them
+	registerTrashRoutes(app, db)
+
+	// Purchase order approvals queue
+	registerApprovalRoutes(app, db)
+
+	// Requisition reviews and notifications
+	registerRequisitionReviewRoutes(app, db)
+
+	// Cost centers, GL accounts and coding
+	registerCodingRoutes(app, db)
+
+	// Vendor invoices and UBL invoice import
+	registerInvoiceRoutes(app, db)
+
+	// Versioned JSON API
+	registerAPIRoutes(app, db, docSvc)
 }
 
 func renderTemplate(c *fiber.Ctx, templateName string, data fiber.Map) error {
 	// Create template with custom functions
 	tmpl := template.New("base.html").Funcs(template.FuncMap{
 		"add": func(a, b int) int { return a + b },
+		"history": func(entity string, id uint) fiber.Map {
+			return fiber.Map{"Entity": entity, "ID": id}
+		},
 		"sub": func(a, b float64) float64 { return a - b },
 		"mul": func(a, b float64) float64 { return a * b },
 		"div": func(a, b float64) float64 {
@@ -1346,10 +1623,20 @@ func renderTemplate(c *fiber.Ctx, templateName string, data fiber.Map) error {
 
 	c.Set("Content-Type", "text/html; charset=utf-8")
 
+	// The signed-in user, shown in the navigation bar
+	if _, ok := data["CurrentUser"]; !ok {
+		if user := currentUser(c); user != nil {
+			data["CurrentUser"] = user
+		}
+	}
+
 	// Execute the base template
 	return tmpl.ExecuteTemplate(c.Response().BodyWriter(), "base.html", data)
 }
 
 func init() {
 	webCmd.Flags().IntP("port", "p", 8080, "Port to run the web server on")
+	webCmd.Flags().Duration("backup-interval", 0, "Write a backup at this interval, e.g. 24h (env BUYER_BACKUP_INTERVAL; 0 disables)")
+	webCmd.Flags().String("backup-dir", "", "Directory for scheduled backups (env BUYER_BACKUP_DIR; default ~/.buyer/backups)")
+	webCmd.Flags().Int("backup-keep", 7, "Number of scheduled backups to keep (env BUYER_BACKUP_KEEP)")
 }
@@ -0,0 +1,806 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/api"
+	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/openapi"
+	"github.com/shakfu/buyer/internal/services"
+	"gorm.io/gorm"
+)
+
+// apiPrefix is the base path of the versioned JSON API
+const apiPrefix = "/api/v1"
+
+// openAPIPath is where the OpenAPI document is served
+const openAPIPath = "/api/openapi.json"
+
+// Error statuses documented for /api/v1 operations
+var (
+	apiReadErrors  = []int{fiber.StatusBadRequest, fiber.StatusNotFound, fiber.StatusInternalServerError}
+	apiWriteErrors = []int{fiber.StatusBadRequest, fiber.StatusNotFound, fiber.StatusConflict, fiber.StatusUnprocessableEntity, fiber.StatusInternalServerError}
+)
+
+// apiRoute is one JSON endpoint: its OpenAPI description and its handler.
+// Routes are registered and documented from the same list, so the published
+// document always matches the server.
+type apiRoute struct {
+	openapi.Route
+	Handler fiber.Handler
+}
+
+// registerAPIRoutes adds the versioned JSON API, the project procurement JSON
+// endpoints and the OpenAPI document describing them. Document files are kept
+// in the storage of docSvc.
+func registerAPIRoutes(app *fiber.App, db *gorm.DB, docSvc *services.DocumentService) {
+	routes := allAPIRoutes(db, docSvc)
+	for _, route := range routes {
+		if route.Method == fiber.MethodGet {
+			app.Get(fiberPath(route.Path), route.Handler) // Also answers HEAD
+		} else {
+			app.Add(route.Method, fiberPath(route.Path), route.Handler)
+		}
+	}
+
+	doc, err := json.Marshal(buildOpenAPI(routes))
+	if err != nil {
+		slog.Error("failed to build OpenAPI document", slog.String("error", err.Error()))
+	}
+	app.Get(openAPIPath, func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(doc)
+	})
+
+	// Anything else under the API prefix is a JSON 404 rather than an HTML page
+	app.Use(apiPrefix, func(c *fiber.Ctx) error {
+		return apiProblem(c, fiber.StatusNotFound, "no such endpoint: "+c.Method()+" "+c.Path(), "")
+	})
+}
+
+// allAPIRoutes returns every documented JSON endpoint
+func allAPIRoutes(db *gorm.DB, docSvc *services.DocumentService) []apiRoute {
+	return append(apiRoutes(db, docSvc), procurementAPIRoutes()...)
+}
+
+// buildOpenAPI describes routes in an OpenAPI document
+func buildOpenAPI(routes []apiRoute) *openapi.Document {
+	builder := openapi.NewBuilder("buyer API", Version)
+	for _, route := range routes {
+		builder.Add(route.Route)
+	}
+	return builder.Document()
+}
+
+// fiberPath converts an OpenAPI path template to a Fiber route,
+// /brands/{id} to /brands/:id
+func fiberPath(path string) string {
+	for _, name := range openapi.PathParams(path) {
+		path = strings.Replace(path, "{"+name+"}", ":"+name, 1)
+	}
+	return path
+}
+
+// apiResource describes the list, get, create, update and delete endpoints of
+// one entity. Create, Update and Delete may be nil, and NoList drops the list
+// endpoint, for entities that are managed elsewhere. Create and Update receive
+// the request context, which names the signed-in user (see models.WithActor).
+type apiResource[T any, C any, U any] struct {
+	Path     string // Under apiPrefix, e.g. /brands
+	Singular string // Used in operation IDs, e.g. Brand
+	Plural   string
+	Noun     string // Used in summaries, e.g. brand
+	NoList   bool
+	Preloads []string
+	Get      func(id uint) (*T, error)
+	Create   func(ctx context.Context, body C) (*T, error)
+	Update   func(ctx context.Context, id uint, body U) (*T, error)
+	Delete   func(ctx context.Context, id uint) error
+}
+
+// routes returns the endpoints of the resource
+func (r apiResource[T, C, U]) routes(db *gorm.DB) []apiRoute {
+	var routes []apiRoute
+	base := apiPrefix + r.Path
+	item := base + "/{id}"
+	route := func(method, path, id, summary string, status int, errs []int, request, response interface{}, handler fiber.Handler) {
+		routes = append(routes, apiRoute{
+			Route: openapi.Route{
+				Method:      method,
+				Path:        path,
+				OperationID: id,
+				Summary:     summary,
+				Tag:         r.Plural,
+				List:        method == fiber.MethodGet && path == base,
+				Request:     request,
+				Response:    response,
+				Status:      status,
+				Errors:      errs,
+				Error:       api.Problem{},
+			},
+			Handler: handler,
+		})
+	}
+
+	var zero T
+	var create C
+	var update U
+	if !r.NoList {
+		route(fiber.MethodGet, base, "List"+r.Plural, "List "+r.Noun+"s", fiber.StatusOK, apiReadErrors,
+			nil, services.ListPage[T]{}, apiList[T](db, r.Preloads...))
+	}
+	route(fiber.MethodGet, item, "Get"+r.Singular, "Get a "+r.Noun, fiber.StatusOK, apiReadErrors,
+		nil, zero, apiGet(r.Get))
+	if r.Create != nil {
+		route(fiber.MethodPost, base, "Create"+r.Singular, "Create a "+r.Noun, fiber.StatusCreated, apiWriteErrors,
+			create, zero, apiCreate(r.Create))
+	}
+	if r.Update != nil {
+		route(fiber.MethodPut, item, "Update"+r.Singular, "Update a "+r.Noun, fiber.StatusOK, apiWriteErrors,
+			update, zero, apiUpdate(r.Update))
+	}
+	if r.Delete != nil {
+		route(fiber.MethodDelete, item, "Delete"+r.Singular, "Delete a "+r.Noun, fiber.StatusNoContent, apiWriteErrors,
+			nil, nil, apiDelete(r.Delete))
+	}
+	return routes
+}
+
+// apiRoutes returns the /api/v1 endpoints. Every entity has list, get,
+// create, update and delete endpoints backed by the services layer; lists take
+// limit, offset (or page), sort and field filters (see parseListQuery).
+// Documents are created by uploading their file (see documentFileRoutes).
+func apiRoutes(db *gorm.DB, docSvc *services.DocumentService) []apiRoute {
+	brandSvc := services.NewBrandService(db)
+	productSvc := services.NewProductService(db)
+	specSvc := services.NewSpecificationService(db)
+	vendorSvc := services.NewVendorService(db)
+	quoteSvc := services.NewQuoteService(db)
+	forexSvc := services.NewForexService(db)
+	requisitionSvc := services.NewRequisitionService(db)
+	projectSvc := services.NewProjectService(db)
+	poSvc := services.NewPurchaseOrderService(db).WithPONumberPattern(poNumberPattern())
+	ratingSvc := services.NewVendorRatingService(db)
+
+	var routes []apiRoute
+
+	routes = append(routes, apiResource[models.Brand, api.BrandInput, api.BrandInput]{
+		Path: "/brands", Singular: "Brand", Plural: "Brands", Noun: "brand",
+		Preloads: []string{"Vendors"},
+		Get:      brandSvc.GetByID,
+		Create: func(ctx context.Context, body api.BrandInput) (*models.Brand, error) {
+			return brandSvc.WithContext(ctx).Create(body.Name)
+		},
+		Update: func(ctx context.Context, id uint, body api.BrandInput) (*models.Brand, error) {
+			return brandSvc.WithContext(ctx).Update(id, body.Name)
+		},
+		Delete: func(ctx context.Context, id uint) error {
+			return brandSvc.WithContext(ctx).Delete(id)
+		},
+	}.routes(db)...)
+
+	routes = append(routes, apiResource[models.Product, api.ProductInput, api.ProductInput]{
+		Path: "/products", Singular: "Product", Plural: "Products", Noun: "product",
+		Preloads: []string{"Brand", "Specification"},
+		Get:      productSvc.GetByID,
+		Create: func(ctx context.Context, body api.ProductInput) (*models.Product, error) {
+			return productSvc.WithContext(ctx).Create(body.Name, body.BrandID, body.SpecificationID)
+		},
+		Update: func(ctx context.Context, id uint, body api.ProductInput) (*models.Product, error) {
+			return productSvc.WithContext(ctx).Update(id, body.Name, body.SpecificationID)
+		},
+		Delete: func(ctx context.Context, id uint) error {
+			return productSvc.WithContext(ctx).Delete(id)
+		},
+	}.routes(db)...)
+
+	routes = append(routes, apiResource[models.Specification, api.SpecificationInput, api.SpecificationInput]{
+		Path: "/specifications", Singular: "Specification", Plural: "Specifications", Noun: "specification",
+		Get: specSvc.GetByID,
+		Create: func(ctx context.Context, body api.SpecificationInput) (*models.Specification, error) {
+			return specSvc.WithContext(ctx).Create(body.Name, body.Description)
+		},
+		Update: func(ctx context.Context, id uint, body api.SpecificationInput) (*models.Specification, error) {
+			return specSvc.WithContext(ctx).Update(id, body.Name, body.Description)
+		},
+		Delete: func(ctx context.Context, id uint) error {
+			return specSvc.WithContext(ctx).Delete(id)
+		},
+	}.routes(db)...)
+
+	routes = append(routes, apiResource[models.Vendor, api.VendorInput, api.VendorInput]{
+		Path: "/vendors", Singular: "Vendor", Plural: "Vendors", Noun: "vendor",
+		Preloads: []string{"Brands"},
+		Get:      vendorSvc.GetByID,
+		Create: func(ctx context.Context, body api.VendorInput) (*models.Vendor, error) {
+			return vendorSvc.WithContext(ctx).Create(body.Name, body.Currency, body.DiscountCode)
+		},
+		Update: func(ctx context.Context, id uint, body api.VendorInput) (*models.Vendor, error) {
+			return vendorSvc.WithContext(ctx).Update(id, body.Name)
+		},
+		Delete: func(ctx context.Context, id uint) error {
+			return vendorSvc.WithContext(ctx).Delete(id)
+		},
+	}.routes(db)...)
+
+	routes = append(routes, apiResource[models.Quote, api.QuoteInput, api.QuoteInput]{
+		Path: "/quotes", Singular: "Quote", Plural: "Quotes", Noun: "quote",
+		Preloads: []string{"Vendor", "Product.Brand"},
+		Get:      quoteSvc.GetByID,
+		Create: func(ctx context.Context, body api.QuoteInput) (*models.Quote, error) {
+			return quoteSvc.WithContext(ctx).Create(services.CreateQuoteInput{
+				VendorID:   body.VendorID,
+				ProductID:  body.ProductID,
+				Price:      body.Price,
+				Currency:   body.Currency,
+				QuoteDate:  body.QuoteDate.Value(),
+				ValidUntil: body.ValidUntil.Ptr(),
+				Notes:      body.Notes,
+			})
+		},
+		Update: func(ctx context.Context, id uint, body api.QuoteInput) (*models.Quote, error) {
+			return quoteSvc.WithContext(ctx).Update(id, services.UpdateQuoteInput{
+				Price:      body.Price,
+				Currency:   body.Currency,
+				ValidUntil: body.ValidUntil.Ptr(),
+				Notes:      body.Notes,
+			})
+		},
+		Delete: func(ctx context.Context, id uint) error {
+			return quoteSvc.WithContext(ctx).Delete(id)
+		},
+	}.routes(db)...)
+
+	routes = append(routes, apiResource[models.Forex, api.ForexInput, api.ForexInput]{
+		Path: "/forex", Singular: "ForexRate", Plural: "ForexRates", Noun: "forex rate",
+		Get: forexSvc.GetByID,
+		Create: func(ctx context.Context, body api.ForexInput) (*models.Forex, error) {
+			return forexSvc.WithContext(ctx).Create(body.FromCurrency, body.ToCurrency, body.Rate, body.EffectiveDate.Value())
+		},
+		Update: func(ctx context.Context, id uint, body api.ForexInput) (*models.Forex, error) {
+			return forexSvc.WithContext(ctx).Update(id, body.Rate, body.EffectiveDate.Value())
+		},
+		Delete: func(ctx context.Context, id uint) error {
+			return forexSvc.WithContext(ctx).Delete(id)
+		},
+	}.routes(db)...)
+
+	routes = append(routes, apiResource[models.Requisition, api.RequisitionInput, api.RequisitionInput]{
+		Path: "/requisitions", Singular: "Requisition", Plural: "Requisitions", Noun: "requisition",
+		Preloads: []string{"Items.Specification"},
+		Get:      requisitionSvc.GetByID,
+		Create: func(ctx context.Context, body api.RequisitionInput) (*models.Requisition, error) {
+			items := make([]services.RequisitionItemInput, 0, len(body.Items))
+			for _, item := range body.Items {
+				items = append(items, services.RequisitionItemInput{
+					SpecificationID: item.SpecificationID,
+					Quantity:        item.Quantity,
+					BudgetPerUnit:   item.BudgetPerUnit,
+					Description:     item.Description,
+				})
+			}
+			return requisitionSvc.WithContext(ctx).Create(body.Name, body.Justification, body.Budget, items)
+		},
+		Update: func(ctx context.Context, id uint, body api.RequisitionInput) (*models.Requisition, error) {
+			return requisitionSvc.WithContext(ctx).Update(id, body.Name, body.Justification, body.Budget)
+		},
+		Delete: func(ctx context.Context, id uint) error {
+			return requisitionSvc.WithContext(ctx).Delete(id)
+		},
+	}.routes(db)...)
+
+	routes = append(routes, apiResource[models.Project, api.ProjectInput, api.ProjectInput]{
+		Path: "/projects", Singular: "Project", Plural: "Projects", Noun: "project",
+		Preloads: []string{"BillOfMaterials"},
+		Get:      projectSvc.GetByID,
+		Create: func(ctx context.Context, body api.ProjectInput) (*models.Project, error) {
+			return projectSvc.WithContext(ctx).Create(body.Name, body.Description, body.Budget, body.Deadline.Ptr())
+		},
+		Update: func(ctx context.Context, id uint, body api.ProjectInput) (*models.Project, error) {
+			return projectSvc.WithContext(ctx).Update(id, body.Name, body.Description, body.Budget, body.Deadline.Ptr(), body.Status)
+		},
+		Delete: func(ctx context.Context, id uint) error {
+			return projectSvc.WithContext(ctx).Delete(id)
+		},
+	}.routes(db)...)
+
+	// Bill of materials items are listed and created per project
+	routes = append(routes,
+		apiRoute{
+			Route: openapi.Route{
+				Method:      fiber.MethodGet,
+				Path:        apiPrefix + "/projects/{id}/bom-items",
+				OperationID: "ListProjectBOMItems",
+				Summary:     "List the bill of materials items of a project",
+				Tag:         "BOMItems",
+				List:        true,
+				Response:    services.ListPage[models.BillOfMaterialsItem]{},
+				Errors:      apiReadErrors,
+				Error:       api.Problem{},
+			},
+			Handler: func(c *fiber.Ctx) error {
+				id, err := apiID(c)
+				if err != nil {
+					return apiError(c, err)
+				}
+				project, err := projectSvc.GetByID(id)
+				if err != nil {
+					return apiError(c, err)
+				}
+				bomID := uint(0)
+				if project.BillOfMaterials != nil {
+					bomID = project.BillOfMaterials.ID
+				}
+				return apiListWith[models.BillOfMaterialsItem](c, db, []services.ListFilter{
+					{Field: "bill_of_materials_id", Op: services.FilterEq, Value: strconv.FormatUint(uint64(bomID), 10)},
+				}, "Specification")
+			},
+		},
+		apiRoute{
+			Route: openapi.Route{
+				Method:      fiber.MethodPost,
+				Path:        apiPrefix + "/projects/{id}/bom-items",
+				OperationID: "CreateProjectBOMItem",
+				Summary:     "Add an item to the bill of materials of a project",
+				Tag:         "BOMItems",
+				Request:     api.BOMItemInput{},
+				Response:    models.BillOfMaterialsItem{},
+				Status:      fiber.StatusCreated,
+				Errors:      apiWriteErrors,
+				Error:       api.Problem{},
+			},
+			Handler: func(c *fiber.Ctx) error {
+				id, err := apiID(c)
+				if err != nil {
+					return apiError(c, err)
+				}
+				var body api.BOMItemInput
+				if err := apiBody(c, &body); err != nil {
+					return apiError(c, err)
+				}
+				item, err := projectSvc.WithContext(c.UserContext()).AddBillOfMaterialsItem(id, body.SpecificationID, body.Quantity, body.Notes)
+				if err != nil {
+					return apiError(c, err)
+				}
+				return c.Status(fiber.StatusCreated).JSON(item)
+			},
+		},
+	)
+	routes = append(routes, apiResource[models.BillOfMaterialsItem, api.BOMItemInput, api.BOMItemInput]{
+		Path: "/bom-items", Singular: "BOMItem", Plural: "BOMItems", Noun: "bill of materials item",
+		NoList: true,
+		Get:    projectSvc.GetBillOfMaterialsItem,
+		Update: func(ctx context.Context, id uint, body api.BOMItemInput) (*models.BillOfMaterialsItem, error) {
+			return projectSvc.WithContext(ctx).UpdateBillOfMaterialsItem(id, body.Quantity, body.Notes)
+		},
+		Delete: func(ctx context.Context, id uint) error {
+			return projectSvc.WithContext(ctx).DeleteBillOfMaterialsItem(id)
+		},
+	}.routes(db)...)
+
+	routes = append(routes, apiResource[models.PurchaseOrder, api.PurchaseOrderInput, api.PurchaseOrderUpdate]{
+		Path: "/purchase-orders", Singular: "PurchaseOrder", Plural: "PurchaseOrders", Noun: "purchase order",
+		Preloads: []string{"Vendor", "Product", "Requisition", "Project"},
+		Get:      poSvc.GetByID,
+		Create: func(ctx context.Context, body api.PurchaseOrderInput) (*models.PurchaseOrder, error) {
+			return poSvc.WithContext(ctx).Create(services.CreatePurchaseOrderInput{
+				QuoteID:                  body.QuoteID,
+				RequisitionID:            body.RequisitionID,
+				ProjectRequisitionItemID: body.ProjectRequisitionItemID,
+				ProjectID:                body.ProjectID,
+				PONumber:                 body.PONumber,
+				Quantity:                 body.Quantity,
+				ExpectedDelivery:         body.ExpectedDelivery.Ptr(),
+				ShippingCost:             body.ShippingCost,
+				Tax:                      body.Tax,
+				Notes:                    body.Notes,
+			})
+		},
+		Update: func(ctx context.Context, id uint, body api.PurchaseOrderUpdate) (*models.PurchaseOrder, error) {
+			return updatePurchaseOrder(poSvc.WithContext(ctx), id, body)
+		},
+		Delete: func(ctx context.Context, id uint) error {
+			return poSvc.WithContext(ctx).Delete(id)
+		},
+	}.routes(db)...)
+
+	routes = append(routes, apiResource[models.Document, api.DocumentInput, api.DocumentInput]{
+		Path: "/documents", Singular: "Document", Plural: "Documents", Noun: "document",
+		Get: docSvc.GetByID,
+		Update: func(ctx context.Context, id uint, body api.DocumentInput) (*models.Document, error) {
+			return docSvc.WithContext(ctx).Update(id, documentInput(ctx, body))
+		},
+		Delete: func(ctx context.Context, id uint) error {
+			return docSvc.WithContext(ctx).Delete(id)
+		},
+	}.routes(db)...)
+	routes = append(routes, documentFileRoutes(docSvc)...)
+
+	routes = append(routes, apiResource[models.VendorRating, api.VendorRatingInput, api.VendorRatingInput]{
+		Path: "/vendor-ratings", Singular: "VendorRating", Plural: "VendorRatings", Noun: "vendor rating",
+		Preloads: []string{"Vendor"},
+		Get:      ratingSvc.GetByID,
+		Create: func(ctx context.Context, body api.VendorRatingInput) (*models.VendorRating, error) {
+			return ratingSvc.WithContext(ctx).Create(vendorRatingInput(ctx, body))
+		},
+		Update: func(ctx context.Context, id uint, body api.VendorRatingInput) (*models.VendorRating, error) {
+			return ratingSvc.WithContext(ctx).Update(id, vendorRatingInput(ctx, body))
+		},
+		Delete: func(ctx context.Context, id uint) error {
+			return ratingSvc.WithContext(ctx).Delete(id)
+		},
+	}.routes(db)...)
+
+	return routes
+}
+
+// documentInput converts a document body; documents are recorded as
+// uploaded by the signed-in user, if there is one
+func documentInput(ctx context.Context, body api.DocumentInput) services.CreateDocumentInput {
+	return services.CreateDocumentInput{
+		EntityType:  body.EntityType,
+		EntityID:    body.EntityID,
+		FileName:    body.FileName,
+		FileType:    body.FileType,
+		Description: body.Description,
+		UploadedBy:  actorOr(ctx, body.UploadedBy),
+	}
+}
+
+// documentFileRoutes returns the endpoints carrying document files: uploads,
+// sent as multipart/form-data with the file in "file" and the fields of
+// api.DocumentInput as the other parts, and downloads streamed from storage
+func documentFileRoutes(docSvc *services.DocumentService) []apiRoute {
+	return []apiRoute{
+		{
+			Route: openapi.Route{
+				Method:      fiber.MethodPost,
+				Path:        apiPrefix + "/documents",
+				OperationID: "CreateDocument",
+				Summary:     "Upload a document",
+				Tag:         "Documents",
+				Request:     api.DocumentInput{},
+				File:        "file",
+				Response:    models.Document{},
+				Status:      fiber.StatusCreated,
+				Errors:      apiWriteErrors,
+				Error:       api.Problem{},
+			},
+			Handler: apiUploadDocument(docSvc),
+		},
+		{
+			Route: openapi.Route{
+				Method:      fiber.MethodGet,
+				Path:        apiPrefix + "/documents/{id}/download",
+				OperationID: "DownloadDocument",
+				Summary:     "Download a document's file",
+				Tag:         "Documents",
+				Download:    true,
+				Errors:      apiReadErrors,
+				Error:       api.Problem{},
+			},
+			Handler: apiDownloadDocument(docSvc),
+		},
+	}
+}
+
+// apiUploadDocument returns a handler storing an uploaded file and recording
+// its document, responding 201 Created
+func apiUploadDocument(docSvc *services.DocumentService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return apiError(c, &apiBadRequest{Message: "expected a multipart/form-data upload with the file in \"file\""})
+		}
+		body := api.DocumentInput{
+			EntityType:  c.FormValue("entity_type"),
+			FileName:    c.FormValue("file_name"),
+			FileType:    c.FormValue("file_type"),
+			Description: c.FormValue("description"),
+			UploadedBy:  c.FormValue("uploaded_by"),
+		}
+		if value := c.FormValue("entity_id"); value != "" {
+			entityID, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return apiError(c, &services.ValidationError{Field: "entity_id", Message: "entity ID must be a number"})
+			}
+			body.EntityID = uint(entityID)
+		}
+		if body.FileName == "" {
+			body.FileName = filepath.Base(file.Filename)
+		}
+
+		src, err := file.Open()
+		if err != nil {
+			return apiError(c, err)
+		}
+		defer src.Close()
+		input := documentInput(c.UserContext(), body)
+		input.FileSize = file.Size
+		doc, err := docSvc.WithContext(c.UserContext()).Upload(input, src)
+		if err != nil {
+			return apiError(c, err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(doc)
+	}
+}
+
+// apiDownloadDocument returns a handler streaming the file of the document
+// with the ID in the path as an attachment
+func apiDownloadDocument(docSvc *services.DocumentService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := apiID(c)
+		if err != nil {
+			return apiError(c, err)
+		}
+		reader, doc, err := docSvc.WithContext(c.UserContext()).Download(id)
+		if err != nil {
+			return apiError(c, err)
+		}
+		c.Attachment(doc.FileName)
+		return c.SendStream(reader)
+	}
+}
+
+// vendorRatingInput converts a vendor rating body; ratings are recorded as
+// made by the signed-in user, if there is one
+func vendorRatingInput(ctx context.Context, body api.VendorRatingInput) services.CreateVendorRatingInput {
+	return services.CreateVendorRatingInput{
+		VendorID:        body.VendorID,
+		PurchaseOrderID: body.PurchaseOrderID,
+		PriceRating:     body.PriceRating,
+		QualityRating:   body.QualityRating,
+		DeliveryRating:  body.DeliveryRating,
+		ServiceRating:   body.ServiceRating,
+		Comments:        body.Comments,
+		RatedBy:         actorOr(ctx, body.RatedBy),
+	}
+}
+
+// updatePurchaseOrder applies the fields present in an update body
+func updatePurchaseOrder(poSvc *services.PurchaseOrderService, id uint, body api.PurchaseOrderUpdate) (*models.PurchaseOrder, error) {
+	po, err := poSvc.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if body.Status != nil && *body.Status != po.Status {
+		if po, err = poSvc.UpdateStatus(id, *body.Status); err != nil {
+			return nil, err
+		}
+	}
+	if body.ExpectedDelivery != nil || body.ActualDelivery != nil {
+		expected, actual := po.ExpectedDelivery, po.ActualDelivery
+		if body.ExpectedDelivery != nil {
+			expected = body.ExpectedDelivery.Ptr()
+		}
+		if body.ActualDelivery != nil {
+			actual = body.ActualDelivery.Ptr()
+		}
+		if _, err = poSvc.UpdateDeliveryDates(id, expected, actual); err != nil {
+			return nil, err
+		}
+	}
+	if body.InvoiceNumber != nil {
+		if _, err = poSvc.UpdateInvoiceNumber(id, *body.InvoiceNumber); err != nil {
+			return nil, err
+		}
+	}
+	return poSvc.GetByID(id)
+}
+
+// apiList returns a handler listing models of type T with pagination,
+// filtering and sorting
+func apiList[T any](db *gorm.DB, preloads ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return apiListWith[T](c, db, nil, preloads...)
+	}
+}
+
+// apiListWith lists models of type T, adding fixed filters to those given in
+// the query string
+func apiListWith[T any](c *fiber.Ctx, db *gorm.DB, filters []services.ListFilter, preloads ...string) error {
+	query, err := parseListQuery(c)
+	if err != nil {
+		return apiError(c, err)
+	}
+	query.Filters = append(query.Filters, filters...)
+
+	page, err := services.QueryList[T](db, query, preloads...)
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			return apiProblem(c, fiber.StatusBadRequest, validationErr.Message, validationErr.Field)
+		}
+		return apiError(c, err)
+	}
+	c.Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	return c.JSON(page)
+}
+
+// parseListQuery reads limit, offset, page, sort and filters from the query
+// string. Any other parameter filters on a field: name=Acme for equality, or
+// price[gte]=10 with one of eq, ne, gt, gte, lt, lte, like or in.
+func parseListQuery(c *fiber.Ctx) (services.ListQuery, error) {
+	var query services.ListQuery
+	var page int
+	for key, value := range c.Queries() {
+		var err error
+		switch key {
+		case "limit", "per_page":
+			query.Limit, err = strconv.Atoi(value)
+		case "offset":
+			query.Offset, err = strconv.Atoi(value)
+		case "page":
+			page, err = strconv.Atoi(value)
+			if err == nil && page < 1 {
+				err = errors.New("must be at least 1")
+			}
+		case "sort":
+			query.Sort = value
+		default:
+			field, op := key, services.FilterEq
+			if open := strings.Index(key, "["); open > 0 && strings.HasSuffix(key, "]") {
+				field, op = key[:open], key[open+1:len(key)-1]
+			}
+			query.Filters = append(query.Filters, services.ListFilter{Field: field, Op: op, Value: value})
+		}
+		if err != nil {
+			return query, &apiBadRequest{Message: "invalid " + key + " parameter: " + value}
+		}
+	}
+	if page > 0 {
+		if query.Limit <= 0 {
+			query.Limit = services.DefaultListLimit
+		}
+		query.Offset = (page - 1) * query.Limit
+	}
+	return query, nil
+}
+
+// apiGet returns a handler fetching one model by ID
+func apiGet[T any](get func(id uint) (T, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := apiID(c)
+		if err != nil {
+			return apiError(c, err)
+		}
+		item, err := get(id)
+		if err != nil {
+			return apiError(c, err)
+		}
+		return c.JSON(item)
+	}
+}
+
+// apiCreate returns a handler decoding a JSON body of type B and creating a
+// model from it, responding 201 Created
+func apiCreate[B any, T any](create func(ctx context.Context, body B) (T, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body B
+		if err := apiBody(c, &body); err != nil {
+			return apiError(c, err)
+		}
+		item, err := create(c.UserContext(), body)
+		if err != nil {
+			return apiError(c, err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(item)
+	}
+}
+
+// apiUpdate returns a handler decoding a JSON body of type B and updating the
+// model with the ID in the path
+func apiUpdate[B any, T any](update func(ctx context.Context, id uint, body B) (T, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := apiID(c)
+		if err != nil {
+			return apiError(c, err)
+		}
+		var body B
+		if err := apiBody(c, &body); err != nil {
+			return apiError(c, err)
+		}
+		item, err := update(c.UserContext(), id, body)
+		if err != nil {
+			return apiError(c, err)
+		}
+		return c.JSON(item)
+	}
+}
+
+// apiDelete returns a handler deleting the model with the ID in the path,
+// responding 204 No Content
+func apiDelete(del func(ctx context.Context, id uint) error) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := apiID(c)
+		if err != nil {
+			return apiError(c, err)
+		}
+		if err := del(c.UserContext(), id); err != nil {
+			return apiError(c, err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// apiID parses the :id path parameter
+func apiID(c *fiber.Ctx) (uint, error) {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil || id == 0 {
+		return 0, &apiBadRequest{Message: "invalid id: " + c.Params("id")}
+	}
+	return uint(id), nil
+}
+
+// apiBody decodes a JSON request body
+func apiBody(c *fiber.Ctx, dest interface{}) error {
+	if err := json.Unmarshal(c.Body(), dest); err != nil {
+		var dateErr *api.DateError
+		if errors.As(err, &dateErr) {
+			return &services.ValidationError{Field: "date", Message: dateErr.Error()}
+		}
+		return &apiBadRequest{Message: "invalid JSON body: " + err.Error()}
+	}
+	return nil
+}
+
+// apiBadRequest is a malformed request, reported as 400
+type apiBadRequest struct {
+	Message string
+}
+
+func (e *apiBadRequest) Error() string {
+	return e.Message
+}
+
+// apiError maps errors to problem responses: ValidationError is 422,
+// DuplicateError 409, NotFoundError 404 and apiBadRequest 400. Anything else
+// is logged and reported as a 500 without details.
+func apiError(c *fiber.Ctx, err error) error {
+	var badRequest *apiBadRequest
+	var validationErr *services.ValidationError
+	var duplicateErr *services.DuplicateError
+	var notFoundErr *services.NotFoundError
+
+	switch {
+	case errors.As(err, &badRequest):
+		return apiProblem(c, fiber.StatusBadRequest, badRequest.Message, "")
+	case errors.As(err, &validationErr):
+		return apiProblem(c, fiber.StatusUnprocessableEntity, validationErr.Message, validationErr.Field)
+	case errors.As(err, &duplicateErr):
+		return apiProblem(c, fiber.StatusConflict, duplicateErr.Error(), "")
+	case errors.As(err, &notFoundErr):
+		return apiProblem(c, fiber.StatusNotFound, notFoundErr.Error(), "")
+	}
+
+	slog.Error("api request failed",
+		slog.String("method", c.Method()),
+		slog.String("path", c.Path()),
+		slog.String("error", err.Error()))
+	return apiProblem(c, fiber.StatusInternalServerError, "internal server error", "")
+}
+
+// apiProblem writes an RFC 9457 problem details response; field names the
+// invalid request field, if any
+func apiProblem(c *fiber.Ctx, status int, detail, field string) error {
+	body, err := json.Marshal(api.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Field:  field,
+	})
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, openapi.ProblemContentType)
+	return c.Status(status).Send(body)
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/storage"
+	"github.com/shakfu/buyer/internal/storage/s3test"
+)
+
+// apiRequest sends a JSON request to the test app and decodes the response
+func apiRequest(t *testing.T, app *fiber.App, method, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	result := map[string]interface{}{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatalf("%s %s: response is not a JSON object: %s", method, path, data)
+		}
+	}
+	if resp.StatusCode >= 400 && resp.Header.Get("Content-Type") != "application/problem+json" {
+		t.Errorf("%s %s: expected problem+json error, got %q", method, path, resp.Header.Get("Content-Type"))
+	}
+	return resp.StatusCode, result
+}
+
+func TestAPI_BrandCRUD(t *testing.T) {
+	app, _ := setupTestApp(t)
+
+	status, brand := apiRequest(t, app, "POST", "/api/v1/brands", `{"name":"Acme"}`)
+	if status != 201 {
+		t.Fatalf("expected 201, got %d: %v", status, brand)
+	}
+	id := int(brand["id"].(float64))
+
+	status, _ = apiRequest(t, app, "POST", "/api/v1/brands", `{"name":"Acme"}`)
+	if status != 409 {
+		t.Errorf("expected 409 for duplicate brand, got %d", status)
+	}
+
+	status, problem := apiRequest(t, app, "POST", "/api/v1/brands", `{"name":""}`)
+	if status != 422 || problem["field"] != "name" {
+		t.Errorf("expected 422 on field name, got %d: %v", status, problem)
+	}
+
+	status, brand = apiRequest(t, app, "PUT", "/api/v1/brands/"+strconv.Itoa(id), `{"name":"Acme Corp"}`)
+	if status != 200 || brand["name"] != "Acme Corp" {
+		t.Errorf("expected updated brand, got %d: %v", status, brand)
+	}
+
+	status, brand = apiRequest(t, app, "GET", "/api/v1/brands/"+strconv.Itoa(id), "")
+	if status != 200 || brand["name"] != "Acme Corp" {
+		t.Errorf("expected brand, got %d: %v", status, brand)
+	}
+
+	status, _ = apiRequest(t, app, "DELETE", "/api/v1/brands/"+strconv.Itoa(id), "")
+	if status != 204 {
+		t.Errorf("expected 204, got %d", status)
+	}
+
+	status, problem = apiRequest(t, app, "GET", "/api/v1/brands/"+strconv.Itoa(id), "")
+	if status != 404 || problem["status"].(float64) != 404 || problem["title"] != "Not Found" {
+		t.Errorf("expected 404 problem, got %d: %v", status, problem)
+	}
+}
+
+func TestAPI_ListPaginationFilterSort(t *testing.T) {
+	app, _ := setupTestApp(t)
+	for _, name := range []string{"Delta", "Alpha", "Charlie", "Bravo", "Echo"} {
+		if status, body := apiRequest(t, app, "POST", "/api/v1/brands", `{"name":"`+name+`"}`); status != 201 {
+			t.Fatalf("failed to create brand: %d %v", status, body)
+		}
+	}
+
+	names := func(page map[string]interface{}) []string {
+		var out []string
+		for _, item := range page["items"].([]interface{}) {
+			out = append(out, item.(map[string]interface{})["name"].(string))
+		}
+		return out
+	}
+
+	status, page := apiRequest(t, app, "GET", "/api/v1/brands?sort=name&limit=2&offset=1", "")
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %v", status, page)
+	}
+	if got := strings.Join(names(page), ","); got != "Bravo,Charlie" {
+		t.Errorf("expected Bravo,Charlie, got %s", got)
+	}
+	if page["total"].(float64) != 5 || page["limit"].(float64) != 2 {
+		t.Errorf("expected total 5 and limit 2, got %v", page)
+	}
+
+	_, page = apiRequest(t, app, "GET", "/api/v1/brands?sort=-name&per_page=2&page=2", "")
+	if got := strings.Join(names(page), ","); got != "Charlie,Bravo" {
+		t.Errorf("expected Charlie,Bravo on page 2, got %s", got)
+	}
+
+	_, page = apiRequest(t, app, "GET", "/api/v1/brands?name[like]=a&sort=name", "")
+	if got := strings.Join(names(page), ","); got != "Alpha,Bravo,Charlie,Delta" {
+		t.Errorf("expected names containing a, got %s", got)
+	}
+
+	_, page = apiRequest(t, app, "GET", "/api/v1/brands?name=Echo", "")
+	if got := strings.Join(names(page), ","); got != "Echo" || page["total"].(float64) != 1 {
+		t.Errorf("expected only Echo, got %s", got)
+	}
+
+	for _, query := range []string{"colour=red", "sort=colour", "name[near]=x", "id[gt]=abc", "limit=x", "page=0"} {
+		if status, problem := apiRequest(t, app, "GET", "/api/v1/brands?"+query, ""); status != 400 {
+			t.Errorf("%s: expected 400, got %d: %v", query, status, problem)
+		}
+	}
+}
+
+func TestAPI_QuotesAndPurchaseOrders(t *testing.T) {
+	app, db := setupTestApp(t)
+	seedTestData(t, db)
+
+	// Filter quotes by numeric field and update one
+	status, page := apiRequest(t, app, "GET", "/api/v1/quotes?price[gte]=1&currency=USD", "")
+	if status != 200 || page["total"].(float64) < 1 {
+		t.Fatalf("expected quotes, got %d: %v", status, page)
+	}
+	quote := page["items"].([]interface{})[0].(map[string]interface{})
+	quoteID := int(quote["id"].(float64))
+
+	status, updated := apiRequest(t, app, "PUT", "/api/v1/quotes/"+strconv.Itoa(quoteID), `{"price":42.5,"valid_until":"2030-01-31","notes":"revised"}`)
+	if status != 200 || updated["price"].(float64) != 42.5 || updated["notes"] != "revised" {
+		t.Errorf("expected updated quote, got %d: %v", status, updated)
+	}
+
+	// Create a purchase order from the quote, then move it along
+	status, po := apiRequest(t, app, "POST", "/api/v1/purchase-orders", `{"quote_id":`+strconv.Itoa(quoteID)+`,"po_number":"PO-API-1","quantity":3}`)
+	if status != 201 {
+		t.Fatalf("expected 201, got %d: %v", status, po)
+	}
+	poID := int(po["id"].(float64))
+
+	status, po = apiRequest(t, app, "PUT", "/api/v1/purchase-orders/"+strconv.Itoa(poID), `{"status":"approved","invoice_number":"INV-9"}`)
+	if status != 200 || po["status"] != "approved" || po["invoice_number"] != "INV-9" {
+		t.Errorf("expected approved PO with invoice, got %d: %v", status, po)
+	}
+
+	status, problem := apiRequest(t, app, "PUT", "/api/v1/purchase-orders/"+strconv.Itoa(poID), `{"status":"bogus"}`)
+	if status != 422 {
+		t.Errorf("expected 422 for invalid status, got %d: %v", status, problem)
+	}
+
+	status, page = apiRequest(t, app, "GET", "/api/v1/purchase-orders?status=approved", "")
+	if status != 200 || page["total"].(float64) != 1 {
+		t.Errorf("expected one approved PO, got %d: %v", status, page)
+	}
+}
+
+func TestAPI_ProjectBOMItems(t *testing.T) {
+	app, db := setupTestApp(t)
+	seedTestData(t, db)
+
+	status, page := apiRequest(t, app, "GET", "/api/v1/projects?name[like]=test", "")
+	if status != 200 || page["total"].(float64) != 1 {
+		t.Fatalf("expected one project, got %d: %v", status, page)
+	}
+	projectID := int(page["items"].([]interface{})[0].(map[string]interface{})["id"].(float64))
+
+	status, page = apiRequest(t, app, "GET", "/api/v1/projects/"+strconv.Itoa(projectID)+"/bom-items", "")
+	if status != 200 || page["total"].(float64) != 1 {
+		t.Fatalf("expected one BOM item, got %d: %v", status, page)
+	}
+	itemID := int(page["items"].([]interface{})[0].(map[string]interface{})["id"].(float64))
+
+	status, item := apiRequest(t, app, "PUT", "/api/v1/bom-items/"+strconv.Itoa(itemID), `{"quantity":25,"notes":"more"}`)
+	if status != 200 || item["quantity"].(float64) != 25 {
+		t.Errorf("expected updated BOM item, got %d: %v", status, item)
+	}
+
+	status, _ = apiRequest(t, app, "DELETE", "/api/v1/bom-items/"+strconv.Itoa(itemID), "")
+	if status != 204 {
+		t.Errorf("expected 204, got %d", status)
+	}
+}
+
+func TestAPI_Documents(t *testing.T) {
+	server := s3test.New("buyer", "secret")
+	defer server.Close()
+	backend, err := storage.NewMinioBackend(server.Endpoint, "buyer", "secret", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, db := setupTestAppWithStorage(t, backend)
+	seedTestData(t, db)
+
+	upload := func(fields map[string]string, filename, content string) (int, map[string]interface{}) {
+		t.Helper()
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		for name, value := range fields {
+			_ = writer.WriteField(name, value)
+		}
+		if filename != "" {
+			part, _ := writer.CreateFormFile("file", filename)
+			_, _ = part.Write([]byte(content))
+		}
+		_ = writer.Close()
+		req := httptest.NewRequest("POST", "/api/v1/documents", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		result := map[string]interface{}{}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	// Documents are no longer recorded from a client-supplied path
+	if status, _ := apiRequest(t, app, "POST", "/api/v1/documents", `{"entity_type":"vendor","entity_id":1,"file_name":"x","file_path":"/etc/passwd"}`); status != 400 {
+		t.Errorf("expected 400 for a JSON body, got %d", status)
+	}
+	if status, problem := upload(map[string]string{"entity_type": "planet", "entity_id": "1"}, "w9.pdf", "x"); status != 422 || problem["field"] != "entity_type" {
+		t.Errorf("expected 422 on field entity_type, got %d: %v", status, problem)
+	}
+
+	status, doc := upload(map[string]string{"entity_type": "vendor", "entity_id": "1", "description": "Signed W-9"}, "w9.pdf", "%PDF-1.4 signed")
+	if status != 201 || doc["file_name"] != "w9.pdf" || doc["file_size"].(float64) != 15 {
+		t.Fatalf("expected the document, got %d: %v", status, doc)
+	}
+	id := strconv.Itoa(int(doc["id"].(float64)))
+	key := doc["file_path"].(string)
+	if data, ok := server.Object("vendor-docs", key); !ok || string(data) != "%PDF-1.4 signed" {
+		t.Errorf("expected the file stored in the vendor-docs bucket, got %q", data)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/documents/"+id+"/download", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readBody(t, resp); resp.StatusCode != 200 || body != "%PDF-1.4 signed" ||
+		resp.Header.Get("Content-Disposition") != `attachment; filename="w9.pdf"` {
+		t.Errorf("expected the file as an attachment, got %d: %q", resp.StatusCode, body)
+	}
+
+	if status, _ := apiRequest(t, app, "DELETE", "/api/v1/documents/"+id, ""); status != 204 {
+		t.Errorf("expected 204, got %d", status)
+	}
+	if _, ok := server.Object("vendor-docs", key); ok {
+		t.Error("expected the file deleted with the document")
+	}
+	if status, _ := apiRequest(t, app, "GET", "/api/v1/documents/"+id+"/download", ""); status != 404 {
+		t.Errorf("expected 404 for a deleted document, got %d", status)
+	}
+}
+
+func TestAPI_BadRequests(t *testing.T) {
+	app, _ := setupTestApp(t)
+
+	tests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/api/v1/brands/abc", "", 400},
+		{"POST", "/api/v1/brands", `{"name":`, 400},
+		{"POST", "/api/v1/forex", `{"from_currency":"EUR","to_currency":"USD","rate":1.1,"effective_date":"soon"}`, 422},
+		{"GET", "/api/v1/widgets", "", 404},
+		{"DELETE", "/api/v1/vendors/999", "", 404},
+	}
+	for _, tt := range tests {
+		if status, problem := apiRequest(t, app, tt.method, tt.path, tt.body); status != tt.status {
+			t.Errorf("%s %s: expected %d, got %d: %v", tt.method, tt.path, tt.status, status, problem)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/services"
+	"gorm.io/gorm"
+)
+
+// registerApprovalRoutes adds the approvals queue and the approve and reject
+// actions on it
+func registerApprovalRoutes(app *fiber.App, db *gorm.DB) {
+	approvalSvc := services.NewApprovalService(db)
+
+	app.Get("/approvals", func(c *fiber.Ctx) error {
+		approver := ""
+		if c.Query("mine") == "true" {
+			approver = actorOr(c.UserContext(), "")
+		}
+		queue, err := approvalSvc.WithContext(c.UserContext()).Queue(approver)
+		if err != nil {
+			return err
+		}
+		return renderTemplate(c, "approvals.html", fiber.Map{
+			"Title": "Approvals",
+			"Queue": queue,
+			"Mine":  approver != "",
+			"Breadcrumb": []map[string]interface{}{
+				{"Name": "Approvals", "Active": true},
+			},
+		})
+	})
+
+	decide := func(approve bool) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
+			}
+			svc := approvalSvc.WithContext(c.UserContext())
+			decide := svc.Reject
+			if approve {
+				decide = svc.Approve
+			}
+			if _, err := decide(uint(id), actorOr(c.UserContext(), "web"), c.FormValue("comment")); err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+			}
+			return c.SendString("")
+		}
+	}
+	app.Post("/approvals/:id/approve", decide(true))
+	app.Post("/approvals/:id/reject", decide(false))
+}
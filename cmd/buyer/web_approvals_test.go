@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/services"
+)
+
+func TestApprovalsPage(t *testing.T) {
+	app, db := setupTestApp(t)
+
+	vendor, _ := services.NewVendorService(db).Create("Acme", "USD", "")
+	brand, _ := services.NewBrandService(db).Create("Apple")
+	product, _ := services.NewProductService(db).Create("MacBook", brand.ID, nil)
+	quote, err := services.NewQuoteService(db).Create(services.CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 500, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := services.NewApprovalService(db).CreateRule(services.ApprovalRuleInput{Step: 1, MinAmount: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	po, err := services.NewPurchaseOrderService(db).Create(services.CreatePurchaseOrderInput{QuoteID: quote.ID, PONumber: "PO-APPROVE", Quantity: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.Itoa(int(po.ID))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/approvals", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readBody(t, resp); !strings.Contains(body, "PO-APPROVE") || !strings.Contains(body, "/approvals/"+id+"/approve") {
+		t.Errorf("Expected the order in the queue, got %s", body)
+	}
+
+	form := url.Values{"comment": {"Looks good"}}
+	req := httptest.NewRequest("POST", "/approvals/"+id+"/approve", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if resp, err = app.Test(req); err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Failed to approve: %v %v", resp.StatusCode, err)
+	}
+	if po, _ := services.NewPurchaseOrderService(db).GetByID(po.ID); po.Status != "approved" {
+		t.Errorf("Expected status approved, got %s", po.Status)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest("POST", "/approvals/"+id+"/reject", nil))
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected 400 rejecting an approved order, got %d", resp.StatusCode)
+	}
+}
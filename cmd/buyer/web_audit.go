@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/services"
+	"gorm.io/gorm"
+)
+
+// auditSource marks changes made through the JSON API as such; everything
+// else served by the web server is recorded as a web change
+func auditSource(c *fiber.Ctx) error {
+	source := models.SourceWeb
+	if strings.HasPrefix(c.Path(), "/api/") {
+		source = models.SourceAPI
+	}
+	c.SetUserContext(models.WithSource(c.UserContext(), source))
+	return c.Next()
+}
+
+// registerHistoryRoutes adds the change history fragment shown on detail pages
+func registerHistoryRoutes(app *fiber.App, db *gorm.DB) {
+	auditSvc := services.NewAuditService(db)
+
+	app.Get("/history/:entity/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
+		}
+		entries, err := auditSvc.History(c.Params("entity"), uint(id))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+		}
+		html, err := RenderAuditHistory(entries)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to render history")
+		}
+		return c.SendString(html.String())
+	})
+}
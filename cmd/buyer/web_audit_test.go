@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/audit"
+	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/services"
+)
+
+func TestAuditHistory(t *testing.T) {
+	app, db := setupTestApp(t)
+	if err := audit.Register(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// A brand created on a page and renamed through the API
+	form := url.Values{"name": {"Acme"}}
+	req := httptest.NewRequest("POST", "/brands", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if resp, err := app.Test(req); err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Failed to create brand: %v", err)
+	}
+	var brand models.Brand
+	if err := db.Where("name = ?", "Acme").First(&brand).Error; err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.Itoa(int(brand.ID))
+	if status, _ := apiRequest(t, app, "PUT", "/api/v1/brands/"+id, `{"name":"Acme Corp"}`); status != fiber.StatusOK {
+		t.Fatalf("Failed to rename brand: %d", status)
+	}
+
+	entries, err := services.NewAuditService(db).History("brand", brand.ID)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Expected two changes, got %+v, %v", entries, err)
+	}
+	if entries[0].Source != models.SourceAPI || entries[1].Source != models.SourceWeb {
+		t.Errorf("Expected an api update after a web create, got %s and %s", entries[0].Source, entries[1].Source)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/history/brand/"+id, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := readBody(t, resp)
+	for _, want := range []string{"<del>Acme</del>", "Acme Corp", "update", "create"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected history to contain %q, got %s", want, body)
+		}
+	}
+
+	resp, _ = app.Test(httptest.NewRequest("GET", "/history/spaceship/1", nil))
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown entity, got %d", resp.StatusCode)
+	}
+}
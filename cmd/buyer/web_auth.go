@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/services"
+)
+
+const (
+	loginPath     = "/login"
+	logoutPath    = "/logout"
+	sessionCookie = "buyer_session"
+
+	// userLocal is the Fiber local holding the *models.User of a signed-in request
+	userLocal = "user"
+)
+
+// registerLoginRoutes adds the login form and the login and logout actions,
+// and the single sign-on routes when OIDC is configured
+func registerLoginRoutes(app *fiber.App, config SecurityConfig) {
+	app.Get(loginPath, func(c *fiber.Ctx) error {
+		next := safeNext(c.Query("next"))
+		if _, err := config.Users.SessionUser(c.Cookies(sessionCookie)); err == nil {
+			return c.Redirect(next)
+		}
+		return renderLogin(c, config, fiber.Map{"Next": next})
+	})
+
+	app.Post(loginPath, func(c *fiber.Ctx) error {
+		next := safeNext(c.FormValue("next"))
+		username := strings.TrimSpace(c.FormValue("username"))
+
+		user, err := config.Users.Authenticate(username, c.FormValue("password"))
+		if err != nil {
+			if !errors.Is(err, services.ErrInvalidCredentials) {
+				return err
+			}
+			slog.Warn("failed login", slog.String("username", username), slog.String("ip", c.IP()))
+			c.Status(fiber.StatusUnauthorized)
+			return renderLogin(c, config, fiber.Map{
+				"Next":     next,
+				"Username": username,
+				"Error":    "Invalid username or password",
+			})
+		}
+
+		if err := startSession(c, config, user); err != nil {
+			return err
+		}
+		slog.Info("user signed in", slog.String("username", user.Username), slog.String("role", user.Role))
+		return c.Redirect(next)
+	})
+
+	app.Post(logoutPath, func(c *fiber.Ctx) error {
+		target := loginPath
+		if plaintext := c.Cookies(sessionCookie); plaintext != "" {
+			if user, err := config.Users.SessionUser(plaintext); err == nil {
+				if logoutURL := ssoLogoutURL(c, config, user.ID); logoutURL != "" {
+					target = logoutURL
+				}
+			}
+			if err := config.Users.DeleteSession(plaintext); err != nil {
+				return err
+			}
+		}
+		c.Cookie(&fiber.Cookie{
+			Name:     sessionCookie,
+			Value:    "",
+			Path:     "/",
+			Expires:  time.Unix(0, 0),
+			HTTPOnly: true,
+			SameSite: fiber.CookieSameSiteLaxMode,
+		})
+		return c.Redirect(target)
+	})
+
+	if config.OIDC != nil {
+		registerOIDCRoutes(app, config)
+	}
+}
+
+// renderLogin renders the login form, offering single sign-on when it is
+// configured
+func renderLogin(c *fiber.Ctx, config SecurityConfig, data fiber.Map) error {
+	data["Title"] = "Sign in"
+	data["HideNav"] = true
+	if config.OIDC != nil {
+		name := config.OIDC.Name
+		if name == "" {
+			name = "single sign-on"
+		}
+		data["SSOName"] = name
+		data["SSOPath"] = oidcLoginPath + "?next=" + url.QueryEscape(fmt.Sprint(data["Next"]))
+	}
+	return renderTemplate(c, "login.html", data)
+}
+
+// startSession signs a user in and sets the session cookie
+func startSession(c *fiber.Ctx, config SecurityConfig, user *models.User) error {
+	plaintext, session, err := config.Users.CreateSession(user, config.SessionTTL)
+	if err != nil {
+		return err
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     sessionCookie,
+		Value:    plaintext,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HTTPOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	return nil
+}
+
+// authMiddleware identifies the user of each request from, in order, a Bearer
+// token, a session cookie or basic auth credentials. Browsers without a
+// session are sent to the login page; other clients get 401.
+func authMiddleware(config SecurityConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		path := c.Path()
+		if path == loginPath || path == oidcLoginPath || path == oidcCallbackPath ||
+			path == "/static" || strings.HasPrefix(path, "/static/") {
+			return c.Next()
+		}
+
+		if config.Tokens != nil && bearerToken(c) != "" {
+			return tokenAuth(c, config.Tokens)
+		}
+
+		if plaintext := c.Cookies(sessionCookie); plaintext != "" {
+			user, err := config.Users.SessionUser(plaintext)
+			if err == nil {
+				return signedIn(c, user)
+			}
+			if !errors.Is(err, services.ErrInvalidSession) {
+				return err
+			}
+		}
+
+		if username, password, ok := basicCredentials(c); ok {
+			user, err := config.Users.Authenticate(username, password)
+			if err == nil {
+				return signedIn(c, user)
+			}
+			if !errors.Is(err, services.ErrInvalidCredentials) {
+				return err
+			}
+			c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="Buyer Application"`)
+			return authError(c, fiber.StatusUnauthorized, services.ErrInvalidCredentials.Error())
+		}
+
+		return loginRequired(c)
+	}
+}
+
+// signedIn records the user of a request and continues. The user is also put
+// in the request context, so saves record them in CreatedBy and UpdatedBy.
+func signedIn(c *fiber.Ctx, user *models.User) error {
+	c.Locals(userLocal, user)
+	c.Locals("username", user.Username)
+	c.SetUserContext(models.WithActor(c.UserContext(), user.Username))
+	return c.Next()
+}
+
+// loginRequired answers a request without credentials: page requests are
+// redirected to the login form, htmx requests are told to go there, and
+// everything else gets 401
+func loginRequired(c *fiber.Ctx) error {
+	target := loginPath + "?next=" + url.QueryEscape(string(c.Request().RequestURI()))
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", loginPath)
+		return c.Status(fiber.StatusUnauthorized).SendString("Sign in required")
+	}
+	if c.Method() == fiber.MethodGet && !strings.HasPrefix(c.Path(), "/api/") {
+		return c.Redirect(target)
+	}
+	c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="Buyer Application"`)
+	return authError(c, fiber.StatusUnauthorized, "authentication required")
+}
+
+// basicCredentials returns the credentials of an "Authorization: Basic" header
+func basicCredentials(c *fiber.Ctx) (string, string, bool) {
+	scheme, encoded, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Basic") {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
+
+// safeNext returns a local path to go to after signing in, so the login form
+// cannot be used to redirect to another site
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// currentUser returns the signed-in user, or nil when authentication is off
+// or the request used an API token
+func currentUser(c *fiber.Ctx) *models.User {
+	user, _ := c.Locals(userLocal).(*models.User)
+	return user
+}
+
+// actorOr returns the signed-in user named in ctx, or name if there is none
+func actorOr(ctx context.Context, name string) string {
+	if actor := models.ActorFrom(ctx); actor != "" {
+		return actor
+	}
+	return name
+}
+
+// authorizeRoutes checks that the role of the signed-in user allows a
+// request. Every user may read; changes need the permission returned by
+// requiredPermission. Requests without a user (authentication off, or an API
+// token, which is limited by its scopes instead) are not checked.
+func authorizeRoutes(c *fiber.Ctx) error {
+	user := currentUser(c)
+	if user == nil {
+		return c.Next()
+	}
+	permission := requiredPermission(c)
+	if permission == "" || services.RoleHasPermission(user.Role, permission) {
+		return c.Next()
+	}
+	return authError(c, fiber.StatusForbidden,
+		fmt.Sprintf("%s (%s) lacks the %s permission", user.Username, user.Role, permission))
+}
+
+// requiredPermission returns the permission a request needs, or "" for reads.
+// Both /api/v1 and the HTML routes are matched by their resource path.
+func requiredPermission(c *fiber.Ctx) string {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return ""
+	}
+	path := c.Path()
+	if path == logoutPath {
+		return ""
+	}
+	path = strings.TrimPrefix(path, apiPrefix)
+	path = strings.TrimPrefix(path, "/api")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if segments[len(segments)-1] == "coding" {
+		// Cost center and GL account coding of a record
+		return services.PermAccountingWrite
+	}
+
+	switch segments[0] {
+	case "specifications", "brands", "products":
+		return services.PermCatalogWrite
+	case "vendors", "vendor-ratings":
+		return services.PermVendorsWrite
+	case "quotes":
+		return services.PermQuotesWrite
+	case "forex":
+		return services.PermForexWrite
+	case "coding", "invoices":
+		return services.PermAccountingWrite
+	case "requisitions", "project-requisitions":
+		switch segments[len(segments)-1] {
+		case "approve", "reject":
+			return services.PermRequisitionsApprove
+		case "order":
+			return services.PermPOIssue
+		}
+		return services.PermRequisitionsWrite
+	case "notifications":
+		// Users mark their own notifications as read
+		return ""
+	case "projects", "bom-items":
+		return services.PermProjectsWrite
+	case "documents":
+		return services.PermDocumentsWrite
+	case "trash":
+		// Restoring a record needs the permission to write it
+		if len(segments) > 1 {
+			if permission, ok := trashPermissions[segments[1]]; ok {
+				return permission
+			}
+		}
+	case "approvals":
+		return services.PermPOApprove
+	case "purchase-orders":
+		if c.Method() == fiber.MethodPut {
+			switch purchaseOrderStatus(c) {
+			case "approved", "rejected":
+				if purchaseOrderDecision(c) == "" {
+					// Deciding on an order while changing it needs both
+					// po:approve and po:issue, which only admins hold
+					return services.PermAdmin
+				}
+				return services.PermPOApprove
+			}
+		}
+		return services.PermPOIssue
+	case "import":
+		if len(segments) > 1 {
+			switch segments[1] {
+			case "brands", "products":
+				return services.PermCatalogWrite
+			case "vendors":
+				return services.PermVendorsWrite
+			case "quotes":
+				return services.PermQuotesWrite
+			case "forex":
+				return services.PermForexWrite
+			case "ubl":
+				return services.PermAccountingWrite
+			}
+		}
+	}
+	return services.PermAdmin
+}
+
+// trashPermissions maps the entities in the trash to the permission needed
+// to restore them
+var trashPermissions = map[string]string{
+	"specification":  services.PermCatalogWrite,
+	"brand":          services.PermCatalogWrite,
+	"product":        services.PermCatalogWrite,
+	"vendor":         services.PermVendorsWrite,
+	"vendor_rating":  services.PermVendorsWrite,
+	"quote":          services.PermQuotesWrite,
+	"requisition":    services.PermRequisitionsWrite,
+	"project":        services.PermProjectsWrite,
+	"purchase_order": services.PermPOIssue,
+}
+
+// purchaseOrderUpdate returns the fields a purchase order update sets: the
+// JSON body under the API, and the query parameters of the HTML form elsewhere
+func purchaseOrderUpdate(c *fiber.Ctx) map[string]string {
+	fields := make(map[string]string)
+	if strings.HasPrefix(c.Path(), apiPrefix+"/") {
+		var body map[string]json.RawMessage
+		_ = json.Unmarshal(c.Body(), &body)
+		for name, raw := range body {
+			var value string
+			if json.Unmarshal(raw, &value) != nil {
+				value = string(raw)
+			}
+			fields[name] = value
+		}
+		return fields
+	}
+	c.Request().URI().QueryArgs().VisitAll(func(key, value []byte) {
+		fields[string(key)] = string(value)
+	})
+	return fields
+}
+
+// purchaseOrderStatus returns the status a purchase order update sets
+func purchaseOrderStatus(c *fiber.Ctx) string {
+	return purchaseOrderUpdate(c)["status"]
+}
+
+// purchaseOrderDecision returns the status a purchase order update sets when
+// the status is all it changes, and "" for any other update
+func purchaseOrderDecision(c *fiber.Ctx) string {
+	fields := purchaseOrderUpdate(c)
+	if len(fields) != 1 {
+		return ""
+	}
+	return fields["status"]
+}
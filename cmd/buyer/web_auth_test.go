@@ -0,0 +1,313 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/services"
+	"gorm.io/gorm"
+)
+
+const authTestPassword = "Correct-Horse-9"
+
+// setupAuthApp returns an app with user accounts enabled and one user per
+// role, named after the role
+func setupAuthApp(t *testing.T) (*fiber.App, *gorm.DB) {
+	t.Helper()
+	_, db := setupTestApp(t)
+	seedTestData(t, db)
+
+	users := services.NewUserService(db)
+	for _, role := range services.Roles {
+		if _, err := users.Create(services.CreateUserInput{Username: role, Role: role, Password: authTestPassword}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := fiber.New()
+	SetupSecurityMiddleware(app, SecurityConfig{EnableAuth: true, Users: users})
+	setupRoutes(app, db, services.NewSpecificationService(db), services.NewBrandService(db), services.NewProductService(db),
+		services.NewVendorService(db), services.NewRequisitionService(db), services.NewQuoteService(db), services.NewForexService(db),
+		services.NewDashboardService(db), services.NewProjectService(db), services.NewProjectRequisitionService(db),
+		services.NewPurchaseOrderService(db), services.NewDocumentService(db), services.NewVendorRatingService(db))
+	return app, db
+}
+
+// login signs in through the login form and returns the session cookie
+func login(t *testing.T, app *fiber.App, username string) *http.Cookie {
+	t.Helper()
+	form := url.Values{"username": {username}, "password": {authTestPassword}, "next": {"/quotes"}}
+	req := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusFound || resp.Header.Get("Location") != "/quotes" {
+		t.Fatalf("login as %s: expected a redirect to /quotes, got %d %q", username, resp.StatusCode, resp.Header.Get("Location"))
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == sessionCookie {
+			if !cookie.HttpOnly {
+				t.Error("expected an HttpOnly session cookie")
+			}
+			return cookie
+		}
+	}
+	t.Fatalf("login as %s: no session cookie", username)
+	return nil
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestLoginFlow(t *testing.T) {
+	app, _ := setupAuthApp(t)
+
+	// Pages redirect to the login form; API requests get 401
+	resp, _ := app.Test(httptest.NewRequest("GET", "/quotes?sort=price", nil), -1)
+	if resp.StatusCode != fiber.StatusFound || resp.Header.Get("Location") != "/login?next="+url.QueryEscape("/quotes?sort=price") {
+		t.Errorf("expected a redirect to the login form, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	resp, _ = app.Test(httptest.NewRequest("GET", "/api/v1/quotes", nil), -1)
+	if resp.StatusCode != fiber.StatusUnauthorized || resp.Header.Get("Content-Type") != "application/problem+json" {
+		t.Errorf("expected a 401 problem, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	req := httptest.NewRequest("DELETE", "/brands/1", nil)
+	req.Header.Set("HX-Request", "true")
+	resp, _ = app.Test(req, -1)
+	if resp.StatusCode != fiber.StatusUnauthorized || resp.Header.Get("HX-Redirect") != loginPath {
+		t.Errorf("expected htmx to be sent to the login form, got %d %q", resp.StatusCode, resp.Header.Get("HX-Redirect"))
+	}
+
+	// The login form renders, and a wrong password is refused
+	resp, _ = app.Test(httptest.NewRequest("GET", "/login", nil), -1)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("expected the login form, got %d", resp.StatusCode)
+	}
+	form := url.Values{"username": {"buyer"}, "password": {"Wrong-Horse-9"}}
+	req = httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, _ = app.Test(req, -1)
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("expected a wrong password to be refused, got %d", resp.StatusCode)
+	}
+
+	// A session opens pages and shows the user
+	cookie := login(t, app, "buyer")
+	req = httptest.NewRequest("GET", "/quotes", nil)
+	req.AddCookie(cookie)
+	resp, _ = app.Test(req, -1)
+	body := readBody(t, resp)
+	if resp.StatusCode != fiber.StatusOK || !strings.Contains(body, "Sign out") {
+		t.Errorf("expected the quotes page with a sign out button, got %d", resp.StatusCode)
+	}
+
+	// Basic auth still works for scripts
+	req = httptest.NewRequest("GET", "/api/v1/quotes", nil)
+	req.SetBasicAuth("buyer", authTestPassword)
+	if resp, _ := app.Test(req, -1); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("expected basic auth to be accepted, got %d", resp.StatusCode)
+	}
+	req = httptest.NewRequest("GET", "/api/v1/quotes", nil)
+	req.SetBasicAuth("buyer", "Wrong-Horse-9")
+	if resp, _ := app.Test(req, -1); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("expected wrong basic auth to be refused, got %d", resp.StatusCode)
+	}
+
+	// Signing out ends the session
+	req = httptest.NewRequest("POST", "/logout", nil)
+	req.AddCookie(cookie)
+	if resp, _ := app.Test(req, -1); resp.StatusCode != fiber.StatusFound {
+		t.Errorf("expected a redirect after signing out, got %d", resp.StatusCode)
+	}
+	req = httptest.NewRequest("GET", "/api/v1/quotes", nil)
+	req.AddCookie(cookie)
+	if resp, _ := app.Test(req, -1); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("expected the session to end, got %d", resp.StatusCode)
+	}
+}
+
+func TestRolePermissions(t *testing.T) {
+	app, _ := setupAuthApp(t)
+
+	cookies := make(map[string]*http.Cookie)
+	for _, role := range services.Roles {
+		cookies[role] = login(t, app, role)
+	}
+
+	quote := `{"vendor_id":1,"product_id":1,"price":10,"currency":"USD"}`
+	tests := []struct {
+		name   string
+		user   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"requester reads", "requester", "GET", "/api/v1/purchase-orders", "", fiber.StatusOK},
+		{"requester adds requisitions", "requester", "POST", "/api/v1/requisitions", `{"name":"Laptops"}`, fiber.StatusCreated},
+		{"requester cannot approve requisitions", "requester", "POST", "/requisitions/1/approve", "", fiber.StatusForbidden},
+		{"approver reviews requisitions", "approver", "POST", "/requisitions/1/approve", "", fiber.StatusBadRequest},
+		{"requester cannot order requisitions", "requester", "POST", "/requisitions/1/order", "", fiber.StatusForbidden},
+		{"buyer orders requisitions", "buyer", "POST", "/requisitions/1/order", "", fiber.StatusBadRequest},
+		{"requester cannot add quotes", "requester", "POST", "/api/v1/quotes", quote, fiber.StatusForbidden},
+		{"buyer adds quotes", "buyer", "POST", "/api/v1/quotes", quote, fiber.StatusCreated},
+		{"buyer adds brands", "buyer", "POST", "/api/v1/brands", `{"name":"Acme"}`, fiber.StatusCreated},
+		{"buyer cannot edit forex", "buyer", "POST", "/api/v1/forex", `{"from_currency":"GBP","to_currency":"USD","rate":1.3}`, fiber.StatusForbidden},
+		{"finance edits forex", "finance", "POST", "/api/v1/forex", `{"from_currency":"GBP","to_currency":"USD","rate":1.3}`, fiber.StatusCreated},
+		{"finance edits vendors", "finance", "POST", "/api/v1/vendors", `{"name":"Globex","currency":"USD"}`, fiber.StatusCreated},
+		{"approver cannot add quotes", "approver", "POST", "/api/v1/quotes", quote, fiber.StatusForbidden},
+		{"requester cannot import profiles", "requester", "POST", "/import/profile", "", fiber.StatusForbidden},
+		{"buyer cannot delete forex in HTML", "buyer", "DELETE", "/forex/1", "", fiber.StatusForbidden},
+		{"admin deletes forex in HTML", "admin", "DELETE", "/forex/1", "", fiber.StatusOK},
+		{"buyer cannot add cost centers", "buyer", "POST", "/coding/cost-centers", "", fiber.StatusForbidden},
+		{"finance adds cost centers", "finance", "POST", "/coding/cost-centers", "", fiber.StatusBadRequest},
+		{"buyer cannot recode purchase orders", "buyer", "POST", "/purchase-orders/1/coding", "", fiber.StatusForbidden},
+		{"buyer cannot import invoices", "buyer", "POST", "/import/ubl", "", fiber.StatusForbidden},
+		{"finance imports invoices", "finance", "POST", "/import/ubl", "", fiber.StatusBadRequest},
+		{"requester cannot save purchase order PDFs", "requester", "POST", "/purchase-orders/999/pdf", "", fiber.StatusForbidden},
+		{"buyer saves purchase order PDFs", "buyer", "POST", "/purchase-orders/999/pdf", "", fiber.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.AddCookie(cookies[tt.user])
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("expected status %d, got %d: %s", tt.want, resp.StatusCode, readBody(t, resp))
+			}
+		})
+	}
+}
+
+func TestPurchaseOrderApprovalNeedsApprover(t *testing.T) {
+	app, db := setupAuthApp(t)
+	buyer := login(t, app, "buyer")
+	approver := login(t, app, "approver")
+
+	send := func(cookie *http.Cookie, method, path, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(cookie)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	if status := send(buyer, "POST", "/api/v1/purchase-orders", `{"quote_id":1,"po_number":"PO-RBAC","quantity":2}`); status != fiber.StatusCreated {
+		t.Fatalf("expected the buyer to issue a purchase order, got %d", status)
+	}
+	if status := send(approver, "POST", "/api/v1/purchase-orders", `{"quote_id":1,"po_number":"PO-RBAC-2","quantity":2}`); status != fiber.StatusForbidden {
+		t.Errorf("expected the approver not to issue purchase orders, got %d", status)
+	}
+	if status := send(buyer, "PUT", "/api/v1/purchase-orders/1", `{"status":"approved"}`); status != fiber.StatusForbidden {
+		t.Errorf("expected the buyer not to approve, got %d", status)
+	}
+	if status := send(buyer, "PUT", "/purchase-orders/1?status=approved", ""); status != fiber.StatusForbidden {
+		t.Errorf("expected the buyer not to approve from the HTML page, got %d", status)
+	}
+	// Approving while changing the order needs po:issue as well
+	mixed := []struct{ path, body string }{
+		{"/purchase-orders/1?status=approved&invoice=INV-X&actual_delivery=2026-10-01", ""},
+		{"/api/v1/purchase-orders/1", `{"status":"approved","invoice_number":"INV-X","actual_delivery":"2026-10-01"}`},
+		{"/api/v1/purchase-orders/1", `{"status":"rejected","invoice_number":"INV-X"}`},
+	}
+	for _, update := range mixed {
+		for _, cookie := range []*http.Cookie{approver, buyer} {
+			if status := send(cookie, "PUT", update.path, update.body); status != fiber.StatusForbidden {
+				t.Errorf("expected %s %s to need po:issue and po:approve, got %d", update.path, update.body, status)
+			}
+		}
+	}
+	if status := send(approver, "PUT", "/api/v1/purchase-orders/1", `{"invoice_number":"INV-X"}`); status != fiber.StatusForbidden {
+		t.Errorf("expected the approver not to record invoices, got %d", status)
+	}
+	if status := send(approver, "PUT", "/api/v1/purchase-orders/1", `{"status":"approved"}`); status != fiber.StatusOK {
+		t.Errorf("expected the approver to approve, got %d", status)
+	}
+	if status := send(buyer, "PUT", "/api/v1/purchase-orders/1", `{"status":"ordered"}`); status != fiber.StatusOK {
+		t.Errorf("expected the buyer to mark the order placed, got %d", status)
+	}
+
+	var po models.PurchaseOrder
+	if err := db.First(&po, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if po.InvoiceNumber != "" {
+		t.Errorf("expected no invoice number from the refused updates, got %q", po.InvoiceNumber)
+	}
+	if po.CreatedBy != "buyer" || po.UpdatedBy != "buyer" {
+		t.Errorf("expected the purchase order to record its users, got %q/%q", po.CreatedBy, po.UpdatedBy)
+	}
+}
+
+func TestSignedInUserRecorded(t *testing.T) {
+	app, db := setupAuthApp(t)
+	buyer := login(t, app, "buyer")
+
+	req := httptest.NewRequest("POST", "/api/v1/vendor-ratings", strings.NewReader(`{"vendor_id":1,"price_rating":4,"rated_by":"someone else"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(buyer)
+	if resp, _ := app.Test(req, -1); resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected the rating to be created, got %d", resp.StatusCode)
+	}
+
+	form := url.Values{"vendor_id": {"1"}, "product_id": {"1"}, "price": {"12.5"}, "currency": {"USD"}}
+	req = httptest.NewRequest("POST", "/quotes", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(buyer)
+	if resp, _ := app.Test(req, -1); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected the quote to be created, got %d", resp.StatusCode)
+	}
+
+	var rating models.VendorRating
+	if err := db.Last(&rating).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rating.RatedBy != "buyer" {
+		t.Errorf("expected the rating to be made by the signed-in user, got %q", rating.RatedBy)
+	}
+	var quote models.Quote
+	if err := db.Last(&quote).Error; err != nil {
+		t.Fatal(err)
+	}
+	if quote.CreatedBy != "buyer" {
+		t.Errorf("expected the quote to be created by the signed-in user, got %q", quote.CreatedBy)
+	}
+}
+
+func TestSafeNext(t *testing.T) {
+	tests := map[string]string{
+		"":                    "/",
+		"/quotes?sort=price":  "/quotes?sort=price",
+		"https://evil.com":    "/",
+		"//evil.com":          "/",
+		"/\\evil.com":         "/",
+		"javascript:alert(1)": "/",
+	}
+	for next, want := range tests {
+		if got := safeNext(next); got != want {
+			t.Errorf("safeNext(%q) = %q, want %q", next, got, want)
+		}
+	}
+}
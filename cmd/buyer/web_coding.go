@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/services"
+	"gorm.io/gorm"
+)
+
+// codingOption is one choice of a cost center or GL account select
+type codingOption struct {
+	ID       uint
+	Label    string
+	Selected bool
+}
+
+// codingForm returns the data of the "coding-form" template: the active cost
+// centers and GL accounts, plus the current ones if they are inactive, with
+// the current ones selected. action is where the form posts.
+func codingForm(db *gorm.DB, action string, costCenterID, glAccountID *uint) (fiber.Map, error) {
+	centers, err := services.NewCostCenterService(db).List(false)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := services.NewGLAccountService(db).List(false)
+	if err != nil {
+		return nil, err
+	}
+	selected := func(current *uint, id uint) bool { return current != nil && *current == id }
+
+	var centerOptions, accountOptions []codingOption
+	for _, center := range centers {
+		if center.Active || selected(costCenterID, center.ID) {
+			centerOptions = append(centerOptions, codingOption{center.ID, center.Code + " - " + center.Name, selected(costCenterID, center.ID)})
+		}
+	}
+	for _, account := range accounts {
+		if account.Active || selected(glAccountID, account.ID) {
+			accountOptions = append(accountOptions, codingOption{account.ID, account.Code + " - " + account.Name, selected(glAccountID, account.ID)})
+		}
+	}
+	return fiber.Map{"Action": action, "CostCenters": centerOptions, "GLAccounts": accountOptions}, nil
+}
+
+// formCodingID parses an optional cost center or GL account ID from a form;
+// an empty value is nil
+func formCodingID(c *fiber.Ctx, field string) (*uint, error) {
+	value := c.FormValue(field)
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s", field)
+	}
+	uid := uint(id)
+	return &uid, nil
+}
+
+// registerCodingRoutes adds the cost center and GL account pages and the
+// coding forms of projects, requisitions and purchase orders
+func registerCodingRoutes(app *fiber.App, db *gorm.DB) {
+	centerSvc := services.NewCostCenterService(db)
+	accountSvc := services.NewGLAccountService(db)
+
+	app.Get("/coding", func(c *fiber.Ctx) error {
+		centers, err := centerSvc.WithContext(c.UserContext()).List(false)
+		if err != nil {
+			return err
+		}
+		accounts, err := accountSvc.WithContext(c.UserContext()).List(false)
+		if err != nil {
+			return err
+		}
+		spend, err := services.NewDashboardService(db.WithContext(c.UserContext())).GetSpendByCostCenter()
+		if err != nil {
+			return err
+		}
+		return renderTemplate(c, "coding.html", fiber.Map{
+			"Title":           "Cost Centers & GL Accounts",
+			"CostCenters":     centers,
+			"GLAccounts":      accounts,
+			"CostCenterSpend": spend,
+			"Breadcrumb": []map[string]interface{}{
+				{"Name": "Cost Centers & GL Accounts", "Active": true},
+			},
+		})
+	})
+
+	// Master data changes reload the page, which lists both kinds of code
+	app.Post("/coding/:kind", func(c *fiber.Ctx) error {
+		var err error
+		switch c.Params("kind") {
+		case "cost-centers":
+			_, err = centerSvc.WithContext(c.UserContext()).Create(c.FormValue("code"), c.FormValue("name"))
+		case "gl-accounts":
+			_, err = accountSvc.WithContext(c.UserContext()).Create(c.FormValue("code"), c.FormValue("name"))
+		default:
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+		}
+		c.Set("HX-Refresh", "true")
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	app.Put("/coding/:kind/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
+		}
+		active := c.FormValue("active") == "true"
+		switch c.Params("kind") {
+		case "cost-centers":
+			_, err = centerSvc.WithContext(c.UserContext()).Update(uint(id), c.FormValue("name"), active)
+		case "gl-accounts":
+			_, err = accountSvc.WithContext(c.UserContext()).Update(uint(id), c.FormValue("name"), active)
+		default:
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+		}
+		c.Set("HX-Refresh", "true")
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	app.Delete("/coding/:kind/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
+		}
+		switch c.Params("kind") {
+		case "cost-centers":
+			err = centerSvc.WithContext(c.UserContext()).Delete(uint(id))
+		case "gl-accounts":
+			err = accountSvc.WithContext(c.UserContext()).Delete(uint(id))
+		default:
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+		}
+		return c.SendString("")
+	})
+
+	// setCoding sets the coding of one record from a coding form, where an
+	// empty select clears the code
+	setCoding := func(set func(c *fiber.Ctx, id uint, coding services.Coding) error) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
+			}
+			var coding services.Coding
+			var zero uint
+			for field, target := range map[string]**uint{"cost_center_id": &coding.CostCenterID, "gl_account_id": &coding.GLAccountID} {
+				value, err := formCodingID(c, field)
+				if err != nil {
+					return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+				}
+				if value == nil {
+					value = &zero
+				}
+				*target = value
+			}
+			if err := set(c, uint(id), coding); err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+			}
+			c.Set("HX-Refresh", "true")
+			return c.SendStatus(fiber.StatusOK)
+		}
+	}
+	app.Post("/projects/:id/coding", setCoding(func(c *fiber.Ctx, id uint, coding services.Coding) error {
+		_, err := services.NewProjectService(db).WithContext(c.UserContext()).SetCoding(id, coding)
+		return err
+	}))
+	app.Post("/requisitions/:id/coding", setCoding(func(c *fiber.Ctx, id uint, coding services.Coding) error {
+		_, err := services.NewRequisitionService(db).WithContext(c.UserContext()).SetCoding(id, coding)
+		return err
+	}))
+	app.Post("/purchase-orders/:id/coding", setCoding(func(c *fiber.Ctx, id uint, coding services.Coding) error {
+		_, err := services.NewPurchaseOrderService(db).WithContext(c.UserContext()).SetCoding(id, coding)
+		return err
+	}))
+}
@@ -2,12 +2,15 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
+	"strconv"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/models"
 	"github.com/shakfu/buyer/internal/services"
 	"gorm.io/gorm"
 )
@@ -160,98 +163,206 @@ func SetupExportHandlers(app *fiber.App, db *gorm.DB) {
 
 	// ==================== Import Endpoints ====================
 
-	// Import brands
+	// Accepts .csv or .xlsx uploads. Optional form fields: mode (create|update|upsert),
+	// key (id|name|sku), sheet (Excel only), dry_run and continue_on_error (true|false)
 	app.Post("/import/brands", func(c *fiber.Ctx) error {
-		file, err := c.FormFile("file")
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).SendString("No file uploaded")
-		}
+		exportSvc := exportSvc.WithContext(c.UserContext())
+		return handleImportUpload(c, exportSvc.ImportBrandsCSVWithOptions, exportSvc.ImportBrandsExcel)
+	})
 
-		// Check file extension
-		if !strings.HasSuffix(strings.ToLower(file.Filename), ".csv") {
-			return c.Status(fiber.StatusBadRequest).SendString("Only CSV files are supported for import")
-		}
+	app.Post("/import/vendors", func(c *fiber.Ctx) error {
+		exportSvc := exportSvc.WithContext(c.UserContext())
+		return handleImportUpload(c, exportSvc.ImportVendorsCSVWithOptions, exportSvc.ImportVendorsExcel)
+	})
 
-		// Open the uploaded file
-		src, err := file.Open()
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("Failed to open uploaded file")
-		}
-		defer src.Close()
+	app.Post("/import/products", func(c *fiber.Ctx) error {
+		exportSvc := exportSvc.WithContext(c.UserContext())
+		return handleImportUpload(c, exportSvc.ImportProductsCSVWithOptions, exportSvc.ImportProductsExcel)
+	})
 
-		// Import the data
-		result, err := exportSvc.ImportBrandsCSV(src)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString(fmt.Sprintf("Import failed: %v", err))
-		}
+	app.Post("/import/quotes", func(c *fiber.Ctx) error {
+		exportSvc := exportSvc.WithContext(c.UserContext())
+		return handleImportUpload(c, exportSvc.ImportQuotesCSVWithOptions, exportSvc.ImportQuotesExcel)
+	})
 
-		// Return import summary
-		return c.JSON(fiber.Map{
-			"success":       result.SuccessCount,
-			"errors":        result.ErrorCount,
-			"error_details": result.Errors,
-		})
+	app.Post("/import/forex", func(c *fiber.Ctx) error {
+		exportSvc := exportSvc.WithContext(c.UserContext())
+		return handleImportUpload(c, exportSvc.ImportForexCSVWithOptions, exportSvc.ImportForexExcel)
 	})
 
-	// Import vendors
-	app.Post("/import/vendors", func(c *fiber.Ctx) error {
-		file, err := c.FormFile("file")
+	// ==================== Profile Import ====================
+
+	profileSvc := services.NewImportProfileService(db)
+
+	// Import page: pick a mapping profile, preview the mapped rows, then import
+	app.Get("/import", func(c *fiber.Ctx) error {
+		profiles, err := profileSvc.List()
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).SendString("No file uploaded")
+			return err
 		}
+		return renderTemplate(c, "import.html", fiber.Map{
+			"Title":    "Import",
+			"Profiles": profiles,
+			"Breadcrumb": []map[string]interface{}{
+				{"Name": "Import", "Active": true},
+			},
+		})
+	})
 
-		if !strings.HasSuffix(strings.ToLower(file.Filename), ".csv") {
-			return c.Status(fiber.StatusBadRequest).SendString("Only CSV files are supported for import")
+	// Preview the first rows of an uploaded file mapped through a profile (HTML fragment)
+	app.Post("/import/profile/preview", func(c *fiber.Ctx) error {
+		profile, err := importProfileFromForm(c, profileSvc)
+		if err != nil {
+			return sendFiberError(c, err)
 		}
-
-		src, err := file.Open()
+		src, excel, err := openImportUpload(c)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("Failed to open uploaded file")
+			return sendFiberError(c, err)
 		}
 		defer src.Close()
 
-		result, err := exportSvc.ImportVendorsCSV(src)
+		var preview *services.ImportPreview
+		if excel {
+			preview, err = exportSvc.PreviewExcelWithProfile(src, profile, importOptionsFromForm(c), importPreviewRows)
+		} else {
+			preview, err = exportSvc.PreviewCSVWithProfile(src, profile, importOptionsFromForm(c), importPreviewRows)
+		}
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString(fmt.Sprintf("Import failed: %v", err))
+			return sendImportError(c, err)
 		}
 
-		return c.JSON(fiber.Map{
-			"success":       result.SuccessCount,
-			"errors":        result.ErrorCount,
-			"error_details": result.Errors,
-		})
+		html, err := RenderImportPreview(preview)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to render response")
+		}
+		return c.SendString(html.String())
 	})
 
-	// Import forex rates
-	app.Post("/import/forex", func(c *fiber.Ctx) error {
-		file, err := c.FormFile("file")
+	// Import an uploaded file mapped through a profile
+	app.Post("/import/profile", func(c *fiber.Ctx) error {
+		profile, err := importProfileFromForm(c, profileSvc)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).SendString("No file uploaded")
+			return sendFiberError(c, err)
 		}
+		exportSvc := exportSvc.WithContext(c.UserContext())
+		return handleImportUpload(c,
+			func(r io.Reader, opts services.ImportOptions) (*services.ImportResult, error) {
+				return exportSvc.ImportCSVWithProfile(r, profile, opts)
+			},
+			func(r io.Reader, opts services.ImportOptions) (*services.ImportResult, error) {
+				return exportSvc.ImportExcelWithProfile(r, profile, opts)
+			})
+	})
+}
 
-		if !strings.HasSuffix(strings.ToLower(file.Filename), ".csv") {
-			return c.Status(fiber.StatusBadRequest).SendString("Only CSV files are supported for import")
-		}
+// importPreviewRows is the number of mapped rows shown by the import preview
+const importPreviewRows = 10
 
-		src, err := file.Open()
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString("Failed to open uploaded file")
-		}
-		defer src.Close()
+// handleImportUpload runs the CSV or Excel importer against an uploaded file. The summary
+// is returned as JSON, or as an HTML fragment for HTMX requests.
+func handleImportUpload(c *fiber.Ctx, csvImporter, excelImporter importFunc) error {
+	src, excel, err := openImportUpload(c)
+	if err != nil {
+		return sendFiberError(c, err)
+	}
+	defer src.Close()
 
-		result, err := exportSvc.ImportForexCSV(src)
+	importer := csvImporter
+	if excel {
+		importer = excelImporter
+	}
+
+	// Import the data
+	result, err := importer(src, importOptionsFromForm(c))
+	if err != nil {
+		return sendImportError(c, err)
+	}
+
+	if c.Get("HX-Request") == "true" {
+		html, err := RenderImportResult(result)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).SendString(fmt.Sprintf("Import failed: %v", err))
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to render response")
 		}
+		return c.SendString(html.String())
+	}
 
-		return c.JSON(fiber.Map{
-			"success":       result.SuccessCount,
-			"errors":        result.ErrorCount,
-			"error_details": result.Errors,
-		})
+	// Return import summary
+	return c.JSON(fiber.Map{
+		"success":       result.SuccessCount,
+		"errors":        result.ErrorCount,
+		"error_details": result.Errors,
+		"created":       result.CreatedCount,
+		"updated":       result.UpdatedCount,
+		"skipped":       result.SkippedCount,
+		"dry_run":       result.DryRun,
+		"rolled_back":   result.RolledBack,
+		"rows":          result.Rows,
 	})
 }
 
+// openImportUpload opens the uploaded "file" form field and reports whether it is an Excel workbook
+func openImportUpload(c *fiber.Ctx) (multipart.File, bool, error) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return nil, false, fiber.NewError(fiber.StatusBadRequest, "No file uploaded")
+	}
+
+	// Pick the format by file extension
+	excel := isExcelFile(file.Filename)
+	if !excel && !strings.HasSuffix(strings.ToLower(file.Filename), ".csv") {
+		return nil, false, fiber.NewError(fiber.StatusBadRequest, "Only CSV and Excel (.xlsx) files are supported for import")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, false, fiber.NewError(fiber.StatusInternalServerError, "Failed to open uploaded file")
+	}
+	return src, excel, nil
+}
+
+// importOptionsFromForm builds import options from the optional form fields
+func importOptionsFromForm(c *fiber.Ctx) services.ImportOptions {
+	return services.ImportOptions{
+		Mode:            services.ImportMode(c.FormValue("mode")),
+		Key:             services.ImportKey(c.FormValue("key")),
+		DryRun:          c.FormValue("dry_run") == "true",
+		ContinueOnError: c.FormValue("continue_on_error") == "true",
+		Sheet:           c.FormValue("sheet"),
+	}
+}
+
+// sendImportError maps an import error to a 400 (invalid input) or 500 response
+func sendImportError(c *fiber.Ctx, err error) error {
+	var validationErr *services.ValidationError
+	var notFoundErr *services.NotFoundError
+	if errors.As(err, &validationErr) || errors.As(err, &notFoundErr) {
+		return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("Import failed: %v", err))
+	}
+	return c.Status(fiber.StatusInternalServerError).SendString(fmt.Sprintf("Import failed: %v", err))
+}
+
+// sendFiberError writes a *fiber.Error as a plain-text response
+func sendFiberError(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return c.Status(fiberErr.Code).SendString(fiberErr.Message)
+	}
+	return c.Status(fiber.StatusInternalServerError).SendString(escapeHTML(err.Error()))
+}
+
+// importProfileFromForm loads the profile selected by the "profile_id" form field
+func importProfileFromForm(c *fiber.Ctx, profileSvc *services.ImportProfileService) (*models.ImportProfile, error) {
+	id, err := strconv.ParseUint(c.FormValue("profile_id"), 10, 32)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid profile ID")
+	}
+	profile, err := profileSvc.GetByID(uint(id))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, escapeHTML(err.Error()))
+	}
+	return profile, nil
+}
+
 // Helper function to read multipart file
 func readMultipartFile(fileHeader *multipart.FileHeader) ([]byte, error) {
 	file, err := fileHeader.Open()
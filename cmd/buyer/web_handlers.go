@@ -28,7 +28,7 @@ func SetupCRUDHandlers(
 	// CRUD endpoints for Brands
 	app.Post("/brands", func(c *fiber.Ctx) error {
 		name := c.FormValue("name")
-		brand, err := brandSvc.Create(name)
+		brand, err := brandSvc.WithContext(c.UserContext()).Create(name)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -45,7 +45,7 @@ func SetupCRUDHandlers(
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
 		}
 		name := c.FormValue("name")
-		brand, err := brandSvc.Update(uint(id), name)
+		brand, err := brandSvc.WithContext(c.UserContext()).Update(uint(id), name)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -61,7 +61,7 @@ func SetupCRUDHandlers(
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
 		}
-		if err := brandSvc.Delete(uint(id)); err != nil {
+		if err := brandSvc.WithContext(c.UserContext()).Delete(uint(id)); err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
 		return c.SendString("")
@@ -86,7 +86,7 @@ func SetupCRUDHandlers(
 			specIDPtr = &specIDUint
 		}
 
-		product, err := productSvc.Create(name, uint(brandID), specIDPtr)
+		product, err := productSvc.WithContext(c.UserContext()).Create(name, uint(brandID), specIDPtr)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -115,7 +115,7 @@ func SetupCRUDHandlers(
 			specIDPtr = &specIDUint
 		}
 
-		product, err := productSvc.Update(uint(id), name, specIDPtr)
+		product, err := productSvc.WithContext(c.UserContext()).Update(uint(id), name, specIDPtr)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -131,7 +131,7 @@ func SetupCRUDHandlers(
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
 		}
-		if err := productSvc.Delete(uint(id)); err != nil {
+		if err := productSvc.WithContext(c.UserContext()).Delete(uint(id)); err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
 		return c.SendString("")
@@ -218,7 +218,7 @@ func SetupCRUDHandlers(
 
 			// Only create if we have a value
 			if prodAttr.ValueNumber != nil || prodAttr.ValueText != nil || prodAttr.ValueBoolean != nil {
-				if err := db.Create(prodAttr).Error; err != nil {
+				if err := db.WithContext(c.UserContext()).Create(prodAttr).Error; err != nil {
 					return c.Status(fiber.StatusInternalServerError).
 						SendString(fmt.Sprintf("Failed to save %s: %s", attr.Name, err.Error()))
 				}
@@ -270,7 +270,7 @@ func SetupCRUDHandlers(
 		name := c.FormValue("name")
 		currency := c.FormValue("currency")
 		discountCode := c.FormValue("discount_code")
-		vendor, err := vendorSvc.Create(name, currency, discountCode)
+		vendor, err := vendorSvc.WithContext(c.UserContext()).Create(name, currency, discountCode)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -287,7 +287,7 @@ func SetupCRUDHandlers(
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
 		}
 		name := c.FormValue("name")
-		vendor, err := vendorSvc.Update(uint(id), name)
+		vendor, err := vendorSvc.WithContext(c.UserContext()).Update(uint(id), name)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -303,7 +303,7 @@ func SetupCRUDHandlers(
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
 		}
-		if err := vendorSvc.Delete(uint(id)); err != nil {
+		if err := vendorSvc.WithContext(c.UserContext()).Delete(uint(id)); err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
 		return c.SendString("")
@@ -318,7 +318,7 @@ func SetupCRUDHandlers(
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid rate")
 		}
-		forex, err := forexSvc.Create(fromCurrency, toCurrency, rate, time.Now())
+		forex, err := forexSvc.WithContext(c.UserContext()).Create(fromCurrency, toCurrency, rate, time.Now())
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -334,7 +334,7 @@ func SetupCRUDHandlers(
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
 		}
-		if err := forexSvc.Delete(uint(id)); err != nil {
+		if err := forexSvc.WithContext(c.UserContext()).Delete(uint(id)); err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
 		return c.SendString("")
@@ -344,7 +344,7 @@ func SetupCRUDHandlers(
 	app.Post("/specifications", func(c *fiber.Ctx) error {
 		name := c.FormValue("name")
 		description := c.FormValue("description")
-		spec, err := specSvc.Create(name, description)
+		spec, err := specSvc.WithContext(c.UserContext()).Create(name, description)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -362,7 +362,7 @@ func SetupCRUDHandlers(
 		}
 		name := c.FormValue("name")
 		description := c.FormValue("description")
-		spec, err := specSvc.Update(uint(id), name, description)
+		spec, err := specSvc.WithContext(c.UserContext()).Update(uint(id), name, description)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -378,7 +378,7 @@ func SetupCRUDHandlers(
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
 		}
-		if err := specSvc.Delete(uint(id)); err != nil {
+		if err := specSvc.WithContext(c.UserContext()).Delete(uint(id)); err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
 		return c.SendString("")
@@ -442,7 +442,7 @@ func SetupCRUDHandlers(
 			return c.Status(fiber.StatusBadRequest).SendString("At least one line item is required")
 		}
 
-		req, err := requisitionSvc.Create(name, justification, budget, items)
+		req, err := requisitionSvc.WithContext(c.UserContext()).Create(name, justification, budget, items)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -459,7 +459,7 @@ func SetupCRUDHandlers(
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
 		}
-		if err := requisitionSvc.Delete(uint(id)); err != nil {
+		if err := requisitionSvc.WithContext(c.UserContext()).Delete(uint(id)); err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
 		return c.SendString("")
@@ -493,7 +493,7 @@ func SetupCRUDHandlers(
 			}
 		}
 
-		quote, err := quoteSvc.Create(services.CreateQuoteInput{
+		quote, err := quoteSvc.WithContext(c.UserContext()).Create(services.CreateQuoteInput{
 			VendorID:   uint(vendorID),
 			ProductID:  uint(productID),
 			Price:      price,
@@ -517,7 +517,7 @@ func SetupCRUDHandlers(
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
 		}
-		if err := quoteSvc.Delete(uint(id)); err != nil {
+		if err := quoteSvc.WithContext(c.UserContext()).Delete(uint(id)); err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
 		return c.SendString("")
@@ -556,7 +556,7 @@ func SetupProjectHandlers(
 			}
 		}
 
-		project, err := projectSvc.Create(name, description, budget, deadline)
+		project, err := projectSvc.WithContext(c.UserContext()).Create(name, description, budget, deadline)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -595,7 +595,7 @@ func SetupProjectHandlers(
 			}
 		}
 
-		project, err := projectSvc.Update(uint(id), name, description, budget, deadline, status)
+		project, err := projectSvc.WithContext(c.UserContext()).Update(uint(id), name, description, budget, deadline, status)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -611,7 +611,7 @@ func SetupProjectHandlers(
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
 		}
-		if err := projectSvc.Delete(uint(id)); err != nil {
+		if err := projectSvc.WithContext(c.UserContext()).Delete(uint(id)); err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
 		return c.SendString("")
@@ -636,7 +636,7 @@ func SetupProjectHandlers(
 
 		notes := c.FormValue("notes")
 
-		bomItem, err := projectSvc.AddBillOfMaterialsItem(uint(projectID), uint(specID), quantity, notes)
+		bomItem, err := projectSvc.WithContext(c.UserContext()).AddBillOfMaterialsItem(uint(projectID), uint(specID), quantity, notes)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -660,7 +660,7 @@ func SetupProjectHandlers(
 
 		notes := c.FormValue("notes")
 
-		bomItem, err := projectSvc.UpdateBillOfMaterialsItem(uint(itemID), quantity, notes)
+		bomItem, err := projectSvc.WithContext(c.UserContext()).UpdateBillOfMaterialsItem(uint(itemID), quantity, notes)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -676,7 +676,7 @@ func SetupProjectHandlers(
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid item ID")
 		}
-		if err := projectSvc.DeleteBillOfMaterialsItem(uint(itemID)); err != nil {
+		if err := projectSvc.WithContext(c.UserContext()).DeleteBillOfMaterialsItem(uint(itemID)); err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
 		return c.SendString("")
@@ -733,7 +733,7 @@ func SetupProjectHandlers(
 			return c.Status(fiber.StatusBadRequest).SendString("At least one BOM item is required")
 		}
 
-		projectReq, err := projectReqSvc.Create(uint(projectID), name, justification, budget, items)
+		projectReq, err := projectReqSvc.WithContext(c.UserContext()).Create(uint(projectID), name, justification, budget, items)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -763,7 +763,7 @@ func SetupProjectHandlers(
 			}
 		}
 
-		projectReq, err := projectReqSvc.Update(uint(id), name, justification, budget)
+		projectReq, err := projectReqSvc.WithContext(c.UserContext()).Update(uint(id), name, justification, budget)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
@@ -780,7 +780,7 @@ func SetupProjectHandlers(
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
 		}
-		if err := projectReqSvc.Delete(uint(id)); err != nil {
+		if err := projectReqSvc.WithContext(c.UserContext()).Delete(uint(id)); err != nil {
 			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
 		}
 		return c.SendString("")
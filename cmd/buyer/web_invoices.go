@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/services"
+	"gorm.io/gorm"
+)
+
+// registerInvoiceRoutes adds the vendor invoice pages and the UBL invoice
+// upload
+func registerInvoiceRoutes(app *fiber.App, db *gorm.DB) {
+	invoiceSvc := services.NewInvoiceService(db)
+
+	app.Get("/invoices", func(c *fiber.Ctx) error {
+		status := c.Query("status")
+		invoices, err := invoiceSvc.WithContext(c.UserContext()).List(status)
+		if err != nil {
+			return err
+		}
+		return renderTemplate(c, "invoices.html", fiber.Map{
+			"Title":    "Invoices",
+			"Invoices": invoices,
+			"Status":   status,
+			"Breadcrumb": []map[string]interface{}{
+				{"Name": "Invoices", "Active": true},
+			},
+		})
+	})
+
+	app.Get("/invoices/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
+		}
+		invoice, err := invoiceSvc.WithContext(c.UserContext()).GetByID(uint(id))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).SendString(escapeHTML(err.Error()))
+		}
+		return renderTemplate(c, "invoice-detail.html", fiber.Map{
+			"Title":   "Invoice " + invoice.InvoiceNumber,
+			"Invoice": invoice,
+			"Breadcrumb": []map[string]interface{}{
+				{"Name": "Invoices", "URL": "/invoices"},
+				{"Name": invoice.InvoiceNumber, "Active": true},
+			},
+		})
+	})
+
+	// Import an uploaded UBL invoice or credit note. The report is returned
+	// as JSON, or as an HTML fragment for HTMX requests.
+	app.Post("/import/ubl", func(c *fiber.Ctx) error {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("No file uploaded")
+		}
+		if !strings.HasSuffix(strings.ToLower(file.Filename), ".xml") {
+			return c.Status(fiber.StatusBadRequest).SendString("Only UBL XML (.xml) files are supported")
+		}
+		src, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to open uploaded file")
+		}
+		defer src.Close()
+
+		result, err := invoiceSvc.WithContext(c.UserContext()).ImportUBL(src, c.FormValue("dry_run") == "true")
+		if err != nil {
+			var validationErr *services.ValidationError
+			var duplicateErr *services.DuplicateError
+			if errors.As(err, &validationErr) || errors.As(err, &duplicateErr) {
+				return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(fmt.Sprintf("Import failed: %v", err)))
+			}
+			return c.Status(fiber.StatusInternalServerError).SendString(escapeHTML(fmt.Sprintf("Import failed: %v", err)))
+		}
+
+		if c.Get("HX-Request") == "true" {
+			html, err := RenderInvoiceImport(result)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).SendString("Failed to render response")
+			}
+			return c.SendString(html.String())
+		}
+		status := fiber.StatusCreated
+		if result.Invoice.ID == 0 {
+			status = fiber.StatusOK
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"invoice":  result.Invoice,
+			"problems": result.Problems,
+		})
+	})
+}
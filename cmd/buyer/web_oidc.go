@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/oidc"
+	"github.com/shakfu/buyer/internal/services"
+)
+
+const (
+	oidcLoginPath    = "/auth/oidc/login"
+	oidcCallbackPath = "/auth/oidc/callback"
+
+	// oidcCookie carries the state, nonce and PKCE verifier of a sign-in in
+	// progress from the redirect to the provider to the callback
+	oidcCookie    = "buyer_oidc"
+	oidcCookieTTL = 10 * time.Minute
+)
+
+// OIDCConfig enables single sign-on through an OpenID Connect provider
+// alongside the local accounts
+type OIDCConfig struct {
+	Provider      *oidc.Provider
+	Name          string            // Provider name on the login button
+	UsernameClaim string            // ID token claim with the username; defaults to preferred_username
+	GroupsClaim   string            // ID token claim with the user's groups; defaults to groups
+	RoleMap       map[string]string // Provider group to buyer role
+	DefaultRole   string            // Role of users in no mapped group; "" refuses them
+}
+
+// oidcConfigFromEnv discovers the provider named by BUYER_OIDC_ISSUER. It
+// returns nil when single sign-on is not configured.
+func oidcConfigFromEnv(ctx context.Context) (*OIDCConfig, error) {
+	issuer := os.Getenv("BUYER_OIDC_ISSUER")
+	if issuer == "" {
+		return nil, nil
+	}
+	roleMap, err := parseRoleMap(os.Getenv("BUYER_OIDC_ROLE_MAP"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BUYER_OIDC_ROLE_MAP: %w", err)
+	}
+	defaultRole := os.Getenv("BUYER_OIDC_DEFAULT_ROLE")
+	if defaultRole != "" && !isRole(defaultRole) {
+		return nil, fmt.Errorf("invalid BUYER_OIDC_DEFAULT_ROLE %q (must be one of: %s)", defaultRole, strings.Join(services.Roles, ", "))
+	}
+
+	provider, err := oidc.Discover(ctx, oidc.Config{
+		Issuer:       issuer,
+		ClientID:     os.Getenv("BUYER_OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("BUYER_OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("BUYER_OIDC_REDIRECT_URL"),
+		Scopes:       strings.Fields(strings.ReplaceAll(os.Getenv("BUYER_OIDC_SCOPES"), ",", " ")),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCConfig{
+		Provider:      provider,
+		Name:          os.Getenv("BUYER_OIDC_NAME"),
+		UsernameClaim: os.Getenv("BUYER_OIDC_USERNAME_CLAIM"),
+		GroupsClaim:   os.Getenv("BUYER_OIDC_GROUPS_CLAIM"),
+		RoleMap:       roleMap,
+		DefaultRole:   defaultRole,
+	}, nil
+}
+
+// parseRoleMap parses "group=role,group=role"
+func parseRoleMap(value string) (map[string]string, error) {
+	roleMap := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" {
+			return nil, fmt.Errorf("%q is not group=role", pair)
+		}
+		if !isRole(role) {
+			return nil, fmt.Errorf("unknown role %q for group %q (must be one of: %s)", role, group, strings.Join(services.Roles, ", "))
+		}
+		roleMap[group] = role
+	}
+	return roleMap, nil
+}
+
+func isRole(role string) bool {
+	for _, known := range services.Roles {
+		if role == known {
+			return true
+		}
+	}
+	return false
+}
+
+// oidcState is the content of oidcCookie
+type oidcState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Next     string `json:"next"`
+}
+
+// registerOIDCRoutes adds the redirect to the provider and the callback that
+// signs the user in
+func registerOIDCRoutes(app *fiber.App, config SecurityConfig) {
+	sso := config.OIDC
+
+	app.Get(oidcLoginPath, func(c *fiber.Ctx) error {
+		verifier, challenge := oidc.NewPKCE()
+		pending := oidcState{
+			State:    oidc.RandomString(),
+			Nonce:    oidc.RandomString(),
+			Verifier: verifier,
+			Next:     safeNext(c.Query("next")),
+		}
+		encoded, err := json.Marshal(pending)
+		if err != nil {
+			return err
+		}
+		c.Cookie(&fiber.Cookie{
+			Name:     oidcCookie,
+			Value:    base64.RawURLEncoding.EncodeToString(encoded),
+			Path:     "/auth/oidc",
+			Expires:  time.Now().Add(oidcCookieTTL),
+			HTTPOnly: true,
+			Secure:   c.Protocol() == "https",
+			SameSite: fiber.CookieSameSiteLaxMode, // Sent on the provider's redirect back
+		})
+		return c.Redirect(sso.Provider.AuthCodeURL(pending.State, pending.Nonce, challenge))
+	})
+
+	app.Get(oidcCallbackPath, func(c *fiber.Ctx) error {
+		pending, ok := readOIDCState(c)
+		c.Cookie(&fiber.Cookie{Name: oidcCookie, Path: "/auth/oidc", Expires: time.Unix(0, 0), HTTPOnly: true})
+
+		if providerErr := c.Query("error"); providerErr != "" {
+			slog.Warn("single sign-on refused by provider", slog.String("error", providerErr), slog.String("description", c.Query("error_description")))
+			return ssoFailed(c, config, fiber.StatusUnauthorized, "Single sign-on was cancelled or refused")
+		}
+		if !ok || c.Query("state") == "" || c.Query("state") != pending.State {
+			return ssoFailed(c, config, fiber.StatusBadRequest, "Single sign-on expired; please try again")
+		}
+
+		token, err := sso.Provider.Exchange(c.UserContext(), c.Query("code"), pending.Verifier)
+		if err != nil {
+			slog.Error("single sign-on code exchange failed", slog.String("error", err.Error()))
+			return ssoFailed(c, config, fiber.StatusBadGateway, "Single sign-on failed; please try again")
+		}
+		idToken, err := sso.Provider.VerifyIDToken(c.UserContext(), token.IDToken, pending.Nonce)
+		if err != nil {
+			slog.Error("single sign-on ID token rejected", slog.String("error", err.Error()))
+			return ssoFailed(c, config, fiber.StatusUnauthorized, "Single sign-on failed; please try again")
+		}
+
+		username := ssoUsername(idToken, sso.UsernameClaim)
+		groupsClaim := sso.GroupsClaim
+		if groupsClaim == "" {
+			groupsClaim = "groups"
+		}
+		role := services.RoleForGroups(idToken.StringsClaim(groupsClaim), sso.RoleMap, sso.DefaultRole)
+		if role == "" {
+			slog.Warn("single sign-on user has no role", slog.String("username", username))
+			return ssoFailed(c, config, fiber.StatusForbidden, fmt.Sprintf("%s is not in a group with access to Buyer", username))
+		}
+
+		user, err := config.Users.SignInExternal(services.ExternalUserInput{
+			Issuer:   idToken.Issuer,
+			Subject:  idToken.Subject,
+			Username: username,
+			Name:     idToken.StringClaim("name"),
+			Email:    idToken.StringClaim("email"),
+			Role:     role,
+		})
+		if err != nil {
+			var duplicateErr *services.DuplicateError
+			switch {
+			case errors.As(err, &duplicateErr):
+				return ssoFailed(c, config, fiber.StatusForbidden,
+					fmt.Sprintf("The username %s belongs to a local account; ask an administrator to rename it", username))
+			case errors.Is(err, services.ErrInvalidCredentials):
+				return ssoFailed(c, config, fiber.StatusForbidden, fmt.Sprintf("The account %s is disabled", username))
+			}
+			return err
+		}
+
+		if err := startSession(c, config, user); err != nil {
+			return err
+		}
+		slog.Info("user signed in with single sign-on", slog.String("username", user.Username), slog.String("role", user.Role))
+		return c.Redirect(pending.Next)
+	})
+}
+
+// readOIDCState decodes oidcCookie
+func readOIDCState(c *fiber.Ctx) (oidcState, bool) {
+	var pending oidcState
+	data, err := base64.RawURLEncoding.DecodeString(c.Cookies(oidcCookie))
+	if err != nil || json.Unmarshal(data, &pending) != nil || pending.State == "" {
+		return oidcState{}, false
+	}
+	return pending, true
+}
+
+// ssoUsername returns the username claim of an ID token, falling back to the
+// email address and then the subject
+func ssoUsername(idToken *oidc.IDToken, claim string) string {
+	if claim == "" {
+		claim = "preferred_username"
+	}
+	for _, name := range []string{claim, "email"} {
+		if value := strings.TrimSpace(idToken.StringClaim(name)); value != "" {
+			return value
+		}
+	}
+	return idToken.Subject
+}
+
+// ssoFailed shows the login page with an error
+func ssoFailed(c *fiber.Ctx, config SecurityConfig, status int, message string) error {
+	c.Status(status)
+	return renderLogin(c, config, fiber.Map{"Next": "/", "Error": message})
+}
+
+// ssoLogoutURL returns where to send a user after signing out: the
+// provider's logout page for single sign-on users, so the provider session
+// ends too, or "" to go to the login page
+func ssoLogoutURL(c *fiber.Ctx, config SecurityConfig, userID uint) string {
+	if config.OIDC == nil {
+		return ""
+	}
+	linked, err := config.Users.HasIdentity(userID, config.OIDC.Provider.Metadata().Issuer)
+	if err != nil || !linked {
+		return ""
+	}
+	return config.OIDC.Provider.EndSessionURL(c.BaseURL() + loginPath)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/oidc"
+	"github.com/shakfu/buyer/internal/oidc/oidctest"
+	"github.com/shakfu/buyer/internal/services"
+	"gorm.io/gorm"
+)
+
+// setupOIDCApp returns an app offering single sign-on through a mock issuer,
+// with a local admin account as well
+func setupOIDCApp(t *testing.T) (*fiber.App, *gorm.DB, *oidctest.Issuer) {
+	t.Helper()
+	_, db := setupTestApp(t)
+	seedTestData(t, db)
+
+	issuer := oidctest.New("buyer", "s3cret")
+	t.Cleanup(issuer.Close)
+	provider, err := oidc.Discover(context.Background(), oidc.Config{
+		Issuer:       issuer.URL,
+		ClientID:     issuer.ClientID,
+		ClientSecret: issuer.ClientSecret,
+		RedirectURL:  "http://buyer.test" + oidcCallbackPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := services.NewUserService(db)
+	if _, err := users.Create(services.CreateUserInput{Username: "admin", Role: services.RoleAdmin, Password: authTestPassword}); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	SetupSecurityMiddleware(app, SecurityConfig{EnableAuth: true, Users: users, OIDC: &OIDCConfig{
+		Provider: provider,
+		Name:     "Acme SSO",
+		RoleMap:  map[string]string{"purchasing": services.RoleBuyer, "approvers": services.RoleApprover},
+	}})
+	setupRoutes(app, db, services.NewSpecificationService(db), services.NewBrandService(db), services.NewProductService(db),
+		services.NewVendorService(db), services.NewRequisitionService(db), services.NewQuoteService(db), services.NewForexService(db),
+		services.NewDashboardService(db), services.NewProjectService(db), services.NewProjectRequisitionService(db),
+		services.NewPurchaseOrderService(db), services.NewDocumentService(db), services.NewVendorRatingService(db))
+	return app, db, issuer
+}
+
+// ssoLogin goes through the single sign-on flow and returns the callback
+// response
+func ssoLogin(t *testing.T, app *fiber.App) *http.Response {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest("GET", oidcLoginPath+"?next=/quotes", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("Expected a redirect to the provider, got %d", resp.StatusCode)
+	}
+	var pending *http.Cookie
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == oidcCookie {
+			pending = cookie
+		}
+	}
+	if pending == nil {
+		t.Fatal("Expected a cookie holding the sign-in state")
+	}
+
+	// The provider signs the user in and redirects back with a code
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	providerResp, err := client.Get(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = providerResp.Body.Close()
+	callback, err := url.Parse(providerResp.Header.Get("Location"))
+	if err != nil || callback.Path != oidcCallbackPath {
+		t.Fatalf("Expected the provider to redirect to the callback, got %q", providerResp.Header.Get("Location"))
+	}
+
+	req := httptest.NewRequest("GET", callback.RequestURI(), nil)
+	req.AddCookie(pending)
+	resp, err = app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestOIDCLogin(t *testing.T) {
+	app, db, issuer := setupOIDCApp(t)
+
+	resp, _ := app.Test(httptest.NewRequest("GET", "/login", nil), -1)
+	if body := readBody(t, resp); !strings.Contains(body, "Sign in with Acme SSO") {
+		t.Error("Expected the login page to offer single sign-on")
+	}
+
+	issuer.SetUser("sub-42", map[string]interface{}{
+		"preferred_username": "dana",
+		"email":              "dana@example.com",
+		"groups":             []string{"staff", "purchasing"},
+	})
+	resp = ssoLogin(t, app)
+	if resp.StatusCode != fiber.StatusFound || resp.Header.Get("Location") != "/quotes" {
+		t.Fatalf("Expected a redirect to /quotes, got %d %q: %s", resp.StatusCode, resp.Header.Get("Location"), readBody(t, resp))
+	}
+	var session *http.Cookie
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == sessionCookie {
+			session = cookie
+		}
+	}
+	if session == nil {
+		t.Fatal("Expected a session cookie")
+	}
+
+	user, err := services.NewUserService(db).GetByUsername("dana")
+	if err != nil || user.Role != services.RoleBuyer || user.Email != "dana@example.com" {
+		t.Fatalf("Expected dana to be created as a buyer, got %+v, %v", user, err)
+	}
+
+	req := httptest.NewRequest("GET", "/quotes", nil)
+	req.AddCookie(session)
+	if resp, _ := app.Test(req, -1); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("Expected the session to sign dana in, got %d", resp.StatusCode)
+	}
+
+	// Signing out ends the provider session too
+	req = httptest.NewRequest("POST", logoutPath, nil)
+	req.AddCookie(session)
+	resp, _ = app.Test(req, -1)
+	if location := resp.Header.Get("Location"); !strings.HasPrefix(location, issuer.URL+"/logout?") {
+		t.Errorf("Expected a redirect to the provider's logout, got %q", location)
+	}
+	req = httptest.NewRequest("GET", "/quotes", nil)
+	req.AddCookie(session)
+	if resp, _ := app.Test(req, -1); resp.StatusCode != fiber.StatusFound {
+		t.Errorf("Expected the session to have ended, got %d", resp.StatusCode)
+	}
+
+	// A group change at the provider changes the role at the next sign-in
+	issuer.SetUser("sub-42", map[string]interface{}{"preferred_username": "dana", "groups": []string{"approvers"}})
+	if resp := ssoLogin(t, app); resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("Expected the second sign-in to succeed, got %d", resp.StatusCode)
+	}
+	if user, _ := services.NewUserService(db).GetByUsername("dana"); user.Role != services.RoleApprover {
+		t.Errorf("Expected dana to be an approver now, got %s", user.Role)
+	}
+}
+
+func TestOIDCLogin_Refused(t *testing.T) {
+	app, _, issuer := setupOIDCApp(t)
+
+	t.Run("no mapped group", func(t *testing.T) {
+		issuer.SetUser("sub-1", map[string]interface{}{"preferred_username": "eve", "groups": []string{"marketing"}})
+		if resp := ssoLogin(t, app); resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("Expected 403, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("local username", func(t *testing.T) {
+		issuer.SetUser("sub-2", map[string]interface{}{"preferred_username": "admin", "groups": []string{"purchasing"}})
+		if resp := ssoLogin(t, app); resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("Expected 403 for a local account's username, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("callback without sign-in state", func(t *testing.T) {
+		resp, _ := app.Test(httptest.NewRequest("GET", oidcCallbackPath+"?code=abc&state=xyz", nil), -1)
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		resp, _ := app.Test(httptest.NewRequest("GET", oidcCallbackPath+"?error=access_denied", nil), -1)
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("Expected 401, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestParseRoleMap(t *testing.T) {
+	roleMap, err := parseRoleMap(" purchasing=buyer, it admins = admin ,")
+	if err != nil || roleMap["purchasing"] != services.RoleBuyer || roleMap["it admins"] != services.RoleAdmin {
+		t.Errorf("Unexpected role map %v, %v", roleMap, err)
+	}
+	for _, bad := range []string{"purchasing", "purchasing=owner", "=buyer"} {
+		if _, err := parseRoleMap(bad); err == nil {
+			t.Errorf("Expected %q to be refused", bad)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/client"
+	"github.com/shakfu/buyer/internal/api"
+	"github.com/shakfu/buyer/internal/config"
+	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/openapi"
+	"github.com/shakfu/buyer/internal/services"
+)
+
+var updateClient = flag.Bool("update-client", false, "rewrite client/client_gen.go from the OpenAPI document")
+
+const clientGenPath = "../../client/client_gen.go"
+
+// contractBodies are sample request bodies for operations that take one
+var contractBodies = map[string]string{
+	"CreateBrand":            `{"name":"Contract Brand"}`,
+	"UpdateBrand":            `{"name":"Renamed Brand"}`,
+	"CreateProduct":          `{"name":"Contract Product","brand_id":1,"specification_id":1}`,
+	"UpdateProduct":          `{"name":"Renamed Product","specification_id":1}`,
+	"CreateSpecification":    `{"name":"Contract Spec","description":"From the contract test"}`,
+	"UpdateSpecification":    `{"name":"Renamed Spec","description":"Renamed"}`,
+	"CreateVendor":           `{"name":"Contract Vendor","currency":"USD"}`,
+	"UpdateVendor":           `{"name":"Renamed Vendor"}`,
+	"CreateQuote":            `{"vendor_id":1,"product_id":1,"price":50,"currency":"USD","valid_until":"2030-01-01"}`,
+	"UpdateQuote":            `{"price":55,"currency":"USD"}`,
+	"CreateForexRate":        `{"from_currency":"GBP","to_currency":"USD","rate":1.3,"effective_date":"2025-01-01"}`,
+	"UpdateForexRate":        `{"rate":1.25,"effective_date":"2025-02-01"}`,
+	"CreateRequisition":      `{"name":"Contract Requisition","budget":100,"items":[{"specification_id":1,"quantity":2}]}`,
+	"UpdateRequisition":      `{"name":"Renamed Requisition","budget":200}`,
+	"CreateProject":          `{"name":"Contract Project","budget":1000,"deadline":"2030-06-30"}`,
+	"UpdateProject":          `{"name":"Renamed Project","budget":2000,"status":"active"}`,
+	"CreateProjectBOMItem":   `{"specification_id":2,"quantity":3}`,
+	"UpdateBOMItem":          `{"quantity":4,"notes":"Updated"}`,
+	"CreatePurchaseOrder":    `{"quote_id":1,"po_number":"PO-CONTRACT","quantity":2,"expected_delivery":"2030-01-15"}`,
+	"UpdatePurchaseOrder":    `{"status":"cancelled","invoice_number":"INV-1"}`,
+	"CreateDocument":         `{"entity_type":"vendor","entity_id":1,"description":"Terms"}`,
+	"UpdateDocument":         `{"entity_type":"vendor","entity_id":1,"file_name":"terms-v2.pdf","file_type":"pdf"}`,
+	"CreateVendorRating":     `{"vendor_id":1,"price_rating":4,"quality_rating":5}`,
+	"UpdateVendorRating":     `{"vendor_id":1,"price_rating":3}`,
+	"SetProcurementStrategy": `{"strategy":"lowest_cost"}`,
+}
+
+// setupContractApp returns a seeded test app with cfg pointing at its
+// database, since the procurement handlers use cfg.DB
+func setupContractApp(t *testing.T) (*fiber.App, *openapi.Document) {
+	t.Helper()
+	app, db := setupTestApp(t)
+	seedTestData(t, db)
+	// A second specification, so a BOM item can be added to the seeded project
+	if err := db.Create(&models.Specification{Name: "Second Spec"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	oldCfg := cfg
+	cfg = &config.Config{DB: db}
+	t.Cleanup(func() { cfg = oldCfg })
+
+	return app, buildOpenAPI(allAPIRoutes(db, services.NewDocumentService(db)))
+}
+
+// contractRequest sends a request and checks the response against the
+// document, returning the status and body. Bodies of uploads are sent as
+// form fields with a file.
+func contractRequest(t *testing.T, app *fiber.App, doc *openapi.Document, method, path, body string) (int, []byte) {
+	t.Helper()
+	var reader io.Reader
+	contentType := "application/json"
+	if body != "" {
+		reader = strings.NewReader(body)
+		if op, ok := doc.Find(method, path); ok && op.RequestBody != nil && op.RequestBody.Content[openapi.MultipartContentType] != nil {
+			reader, contentType = contractForm(t, body)
+		}
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", contentType)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+
+	if err := doc.ValidateResponse(method, path, resp.StatusCode, resp.Header.Get("Content-Type"), data); err != nil {
+		t.Errorf("%v\nbody: %s", err, data)
+	}
+	return resp.StatusCode, data
+}
+
+// contractForm returns the fields of a JSON body as a multipart form with a
+// file in "file", and its content type
+func contractForm(t *testing.T, body string) (io.Reader, string) {
+	t.Helper()
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &fields); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for name, value := range fields {
+		_ = writer.WriteField(name, fmt.Sprint(value))
+	}
+	part, _ := writer.CreateFormFile("file", "contract.pdf")
+	_, _ = part.Write([]byte("%PDF-1.4 contract"))
+	_ = writer.Close()
+	return &buf, writer.FormDataContentType()
+}
+
+// TestOpenAPI_RoutesMatchDocument checks that every JSON route the server
+// registers is documented, and every documented operation is registered
+func TestOpenAPI_RoutesMatchDocument(t *testing.T) {
+	app, doc := setupContractApp(t)
+
+	var registered []string
+	for _, route := range app.GetRoutes(true) {
+		if !strings.HasPrefix(route.Path, "/api/") || route.Path == openAPIPath || route.Method == fiber.MethodHead {
+			continue
+		}
+		registered = append(registered, route.Method+" "+route.Path)
+	}
+	var documented []string
+	for _, op := range doc.Operations() {
+		documented = append(documented, op.Method+" "+fiberPath(op.Path))
+	}
+	sort.Strings(registered)
+	sort.Strings(documented)
+
+	if strings.Join(registered, "\n") != strings.Join(documented, "\n") {
+		t.Errorf("registered routes do not match the document\nregistered:\n%s\ndocumented:\n%s",
+			strings.Join(registered, "\n"), strings.Join(documented, "\n"))
+	}
+}
+
+// TestOpenAPI_ResponsesMatchSchema exercises every documented operation and
+// validates each response, success and error, against the document
+func TestOpenAPI_ResponsesMatchSchema(t *testing.T) {
+	app, doc := setupContractApp(t)
+
+	// Created IDs by collection path, so later operations use fresh rows
+	created := map[string]string{}
+	concrete := func(op openapi.OperationRef) string {
+		collection, _, _ := strings.Cut(op.Path, "/{id}")
+		if op.OperationID == "DeleteBOMItem" || op.OperationID == "UpdateBOMItem" || op.OperationID == "GetBOMItem" {
+			collection = apiPrefix + "/bom-items"
+		}
+		id, ok := created[collection]
+		if !ok || strings.HasPrefix(op.Path, apiPrefix+"/projects/{id}/") {
+			id = "1"
+		}
+		return strings.Replace(op.Path, "{id}", id, 1)
+	}
+
+	// Creates first, then reads and updates, then deletes
+	phase := func(op openapi.OperationRef) int {
+		switch {
+		case op.Method == fiber.MethodPost && strings.HasPrefix(op.OperationID, "Create"):
+			return 0
+		case op.Method == fiber.MethodDelete:
+			return 2
+		}
+		return 1
+	}
+	ops := doc.Operations()
+	sort.SliceStable(ops, func(i, j int) bool { return phase(ops[i]) < phase(ops[j]) })
+
+	exercised := map[string]bool{}
+	for _, op := range ops {
+		path := concrete(op)
+		body := contractBodies[op.OperationID]
+		if op.RequestBody != nil && body == "" {
+			t.Fatalf("%s: no sample request body", op.OperationID)
+		}
+
+		status, data := contractRequest(t, app, doc, op.Method, path, body)
+		if status >= 300 {
+			t.Errorf("%s %s: status %d: %s", op.Method, path, status, data)
+			continue
+		}
+		exercised[op.OperationID] = true
+
+		if phase(op) == 0 {
+			var result struct {
+				ID uint `json:"id"`
+			}
+			if err := json.Unmarshal(data, &result); err != nil || result.ID == 0 {
+				t.Fatalf("%s: response has no id: %s", op.OperationID, data)
+			}
+			collection := op.Path
+			if op.OperationID == "CreateProjectBOMItem" {
+				collection = apiPrefix + "/bom-items"
+			}
+			created[collection] = strconv.FormatUint(uint64(result.ID), 10)
+		}
+
+		// Error responses must match the document too
+		if len(openapi.PathParams(op.Path)) > 0 {
+			missing := strings.Replace(op.Path, "{id}", "999", 1)
+			if strings.HasPrefix(op.Path, "/api/projects/") {
+				missing = strings.Replace(op.Path, "{id}", "abc", 1)
+			}
+			if status, _ := contractRequest(t, app, doc, op.Method, missing, body); status < 400 {
+				t.Errorf("%s %s: expected an error, got %d", op.Method, missing, status)
+			}
+		}
+	}
+
+	for _, op := range doc.Operations() {
+		if !exercised[op.OperationID] {
+			t.Errorf("operation %s was not exercised", op.OperationID)
+		}
+	}
+}
+
+// TestOpenAPI_DocumentServed checks the document served by the web server
+func TestOpenAPI_DocumentServed(t *testing.T) {
+	app, doc := setupContractApp(t)
+
+	req := httptest.NewRequest("GET", openAPIPath, nil)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	served, _ := io.ReadAll(resp.Body)
+	expected, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(served, expected) {
+		t.Error("served document differs from the document built from the routes")
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(served, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed["openapi"] != openapi.Version {
+		t.Errorf("expected openapi %s, got %v", openapi.Version, parsed["openapi"])
+	}
+}
+
+// TestOpenAPI_GeneratedClient checks that client/client_gen.go is up to date.
+// Run with -update-client (or go generate ./client) to rewrite it.
+func TestOpenAPI_GeneratedClient(t *testing.T) {
+	_, doc := setupContractApp(t)
+
+	generated, err := openapi.GenerateClient(doc, "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *updateClient {
+		if err := os.WriteFile(clientGenPath, generated, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	current, err := os.ReadFile(clientGenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(current, generated) {
+		t.Error("client/client_gen.go is out of date; run go generate ./client")
+	}
+}
+
+// TestOpenAPI_ClientRoundTrip uses the generated client against a running
+// server
+func TestOpenAPI_ClientRoundTrip(t *testing.T) {
+	app, _ := setupContractApp(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	go func() { _ = app.Listener(listener) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	c := client.New("http://" + listener.Addr().String())
+	ctx := context.Background()
+
+	brand, err := c.CreateBrand(ctx, api.BrandInput{Name: "Client Brand"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if brand.ID == 0 || brand.Name != "Client Brand" {
+		t.Errorf("unexpected brand %+v", brand)
+	}
+
+	page, err := c.ListBrands(ctx, client.ListParams{Sort: "-id", Filters: map[string]string{"name[like]": "client"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || len(page.Items) != 1 || page.Items[0].ID != brand.ID {
+		t.Errorf("unexpected page %+v", page)
+	}
+
+	quote, err := c.CreateQuote(ctx, api.QuoteInput{VendorID: 1, ProductID: 1, Price: 20, Currency: "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.ConvertedPrice != 22 {
+		t.Errorf("expected converted price 22, got %v", quote.ConvertedPrice)
+	}
+
+	_, err = c.GetBrand(ctx, 999)
+	var problem *api.Problem
+	if !errors.As(err, &problem) || problem.Status != 404 {
+		t.Errorf("expected a 404 problem, got %v", err)
+	}
+
+	if err := c.DeleteBrand(ctx, brand.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	document, err := c.CreateDocument(ctx, api.DocumentInput{EntityType: "vendor", EntityID: 1, Description: "Terms"}, "terms.pdf", strings.NewReader("%PDF-1.4 terms"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document.FileName != "terms.pdf" || document.FileType != "pdf" || document.FileSize != 14 || document.Description != "Terms" {
+		t.Errorf("unexpected document %+v", document)
+	}
+	file, err := c.DownloadDocument(ctx, document.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "%PDF-1.4 terms" {
+		t.Errorf("expected the uploaded file, got %q", data)
+	}
+	if _, err := c.DownloadDocument(ctx, 999); !errors.As(err, &problem) || problem.Status != 404 {
+		t.Errorf("expected a 404 problem, got %v", err)
+	}
+
+	_, err = c.GetProcurementAnalysis(ctx, 999)
+	if !errors.As(err, &problem) || problem.Status != 500 || problem.Detail == "" {
+		t.Errorf("expected a 500 problem with detail, got %v", err)
+	}
+}
@@ -4,26 +4,66 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/api"
+	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/openapi"
 	"github.com/shakfu/buyer/internal/services"
 )
 
-// registerProcurementRoutes adds procurement-related routes to the app
+// registerProcurementRoutes adds the procurement analysis page. Its JSON
+// endpoints are registered with the rest of the API (see procurementAPIRoutes).
 func registerProcurementRoutes(app *fiber.App) {
-	// Procurement analysis page
 	app.Get("/projects/:id/procurement", handleProjectProcurement)
+}
+
+// procurementAPIRoutes returns the JSON endpoints used by the procurement
+// analysis page
+func procurementAPIRoutes() []apiRoute {
+	route := func(method, name, id, summary string, request, response interface{}, handler fiber.Handler) apiRoute {
+		return apiRoute{
+			Route: openapi.Route{
+				Method:      method,
+				Path:        "/api/projects/{id}/procurement/" + name,
+				OperationID: id,
+				Summary:     summary,
+				Tag:         "Procurement",
+				Request:     request,
+				Response:    response,
+				Errors:      []int{fiber.StatusBadRequest, fiber.StatusInternalServerError},
+				Error:       api.ErrorMessage{},
+			},
+			Handler: handler,
+		}
+	}
 
-	// API endpoints for AJAX requests
-	app.Get("/api/projects/:id/procurement/analysis", handleProcurementAnalysisAPI)
-	app.Get("/api/projects/:id/procurement/dashboard", handleProcurementDashboardAPI)
-	app.Get("/api/projects/:id/procurement/risks", handleProcurementRisksAPI)
-	app.Get("/api/projects/:id/procurement/savings", handleProcurementSavingsAPI)
-	app.Get("/api/projects/:id/procurement/consolidation", handleVendorConsolidationAPI)
-	app.Get("/api/projects/:id/procurement/scenarios", handleScenariosComparisonAPI)
-	app.Get("/api/projects/:id/procurement/recommendations", handleRecommendationsAPI)
-
-	// Strategy management
-	app.Get("/api/projects/:id/procurement/strategy", handleGetStrategyAPI)
-	app.Post("/api/projects/:id/procurement/strategy", handleSetStrategyAPI)
+	recommendations := route(fiber.MethodGet, "recommendations", "GetProcurementRecommendations",
+		"Recommend vendors for a project", nil, []services.VendorRecommendation{}, handleRecommendationsAPI)
+	recommendations.Query = []openapi.Parameter{{
+		Name:        "strategy",
+		In:          "query",
+		Description: "Procurement strategy (default balanced)",
+		Schema:      &openapi.Schema{Type: "string"},
+	}}
+
+	return []apiRoute{
+		route(fiber.MethodGet, "analysis", "GetProcurementAnalysis",
+			"Compare quotes for a project's bill of materials", nil, services.ProjectProcurementComparison{}, handleProcurementAnalysisAPI),
+		route(fiber.MethodGet, "dashboard", "GetProcurementDashboard",
+			"Summarize procurement progress for a project", nil, services.ProjectDashboard{}, handleProcurementDashboardAPI),
+		route(fiber.MethodGet, "risks", "GetProcurementRisks",
+			"Assess procurement risks for a project", nil, services.EnhancedRiskAssessment{}, handleProcurementRisksAPI),
+		route(fiber.MethodGet, "savings", "GetProcurementSavings",
+			"Estimate savings for a project", nil, services.ProjectSavingsSummary{}, handleProcurementSavingsAPI),
+		route(fiber.MethodGet, "consolidation", "GetVendorConsolidation",
+			"Analyze vendor consolidation for a project", nil, []services.VendorConsolidationAnalysis{}, handleVendorConsolidationAPI),
+		route(fiber.MethodGet, "scenarios", "GetProcurementScenarios",
+			"Compare procurement scenarios for a project", nil, []services.ProcurementScenario{}, handleScenariosComparisonAPI),
+		recommendations,
+		route(fiber.MethodGet, "strategy", "GetProcurementStrategy",
+			"Get a project's procurement strategy", nil, models.ProjectProcurementStrategy{}, handleGetStrategyAPI),
+		route(fiber.MethodPost, "strategy", "SetProcurementStrategy",
+			"Set a project's procurement strategy", api.StrategyInput{}, models.ProjectProcurementStrategy{}, handleSetStrategyAPI),
+	}
 }
 
 // handleProjectProcurement renders the main procurement analysis page
@@ -206,10 +246,7 @@ func handleSetStrategyAPI(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid project ID"})
 	}
 
-	var input struct {
-		Strategy string `json:"strategy"`
-	}
-
+	var input api.StrategyInput
 	if err := c.BodyParser(&input); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid input"})
 	}
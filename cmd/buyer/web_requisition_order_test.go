@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/services"
+)
+
+func TestRequisitionOrderRoute(t *testing.T) {
+	app, db := setupTestApp(t)
+
+	spec, _ := services.NewSpecificationService(db).Create("Laptop", "")
+	vendor, _ := services.NewVendorService(db).Create("Acme", "USD", "")
+	brand, _ := services.NewBrandService(db).Create("Apple")
+	product, _ := services.NewProductService(db).Create("MacBook", brand.ID, &spec.ID)
+	quote, err := services.NewQuoteService(db).Create(services.CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 1000, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := services.NewRequisitionService(db).Create("Laptops", "", 0, []services.RequisitionItemInput{{SpecificationID: spec.ID, Quantity: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.Itoa(int(req.ID))
+
+	results := func() string {
+		resp, err := app.Test(httptest.NewRequest("GET", "/requisition-comparison/results?requisition_id="+id, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return readBody(t, resp)
+	}
+	if body := results(); strings.Contains(body, "Convert to POs") || !strings.Contains(body, "only approved requisitions can be ordered") {
+		t.Errorf("Expected a draft requisition not to be ordered, got %s", body)
+	}
+	db.Model(req).Update("status", services.RequisitionApproved)
+	if body := results(); !strings.Contains(body, "/requisitions/"+id+"/order") {
+		t.Errorf("Expected the Convert to POs form, got %s", body)
+	}
+
+	order := func(form url.Values) (int, string) {
+		request := httptest.NewRequest("POST", "/requisitions/"+id+"/order", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, readBody(t, resp)
+	}
+	item := strconv.Itoa(int(req.Items[0].ID))
+	if status, _ := order(url.Values{"items[0][item_id]": {item}}); status != fiber.StatusBadRequest {
+		t.Errorf("Expected 400 with no item ticked, got %d", status)
+	}
+	form := url.Values{
+		"items[0][item_id]":  {item},
+		"items[0][order]":    {"true"},
+		"items[0][quote_id]": {strconv.Itoa(int(quote.ID))},
+	}
+	status, body := order(form)
+	if status != fiber.StatusOK || !strings.Contains(body, "Acme") || !strings.Contains(body, "PO-") {
+		t.Fatalf("Failed to convert to POs: %d %s", status, body)
+	}
+	if status, _ := order(form); status != fiber.StatusBadRequest {
+		t.Errorf("Expected 400 ordering the same item twice, got %d", status)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/services"
+	"gorm.io/gorm"
+)
+
+// registerRequisitionReviewRoutes adds the review queue, the submit, approve
+// and reject actions on requisitions and project requisitions, and the
+// notifications page
+func registerRequisitionReviewRoutes(app *fiber.App, db *gorm.DB) {
+	reviewSvc := services.NewRequisitionReviewService(db)
+	notificationSvc := services.NewNotificationService(db)
+
+	app.Get("/requisition-reviews", func(c *fiber.Ctx) error {
+		queue, err := reviewSvc.WithContext(c.UserContext()).Queue()
+		if err != nil {
+			return err
+		}
+		return renderTemplate(c, "requisition-reviews.html", fiber.Map{
+			"Title": "Requisition Reviews",
+			"Queue": queue,
+			"Breadcrumb": []map[string]interface{}{
+				{"Name": "Requisitions", "URL": "/requisitions", "Active": false},
+				{"Name": "Reviews", "Active": true},
+			},
+		})
+	})
+
+	// review runs one review action and answers with the requisition's new
+	// status, or 400 with the reason it was refused
+	review := func(kind, action string) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
+			}
+			svc := reviewSvc.WithContext(c.UserContext())
+			actor := actorOr(c.UserContext(), "web")
+			comment := c.FormValue("comment")
+
+			var reviewed *services.ReviewedRequisition
+			switch action {
+			case "submit":
+				reviewed, err = svc.Submit(kind, uint(id), actor, comment)
+			case "approve":
+				reviewed, err = svc.Approve(kind, uint(id), actor, comment, c.FormValue("override_reason"))
+			default:
+				reviewed, err = svc.Reject(kind, uint(id), actor, comment)
+			}
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+			}
+			return c.SendString(reviewed.Status)
+		}
+	}
+	for _, route := range []struct{ path, kind string }{
+		{"/requisitions", services.KindRequisition},
+		{"/project-requisitions", services.KindProjectRequisition},
+	} {
+		for _, action := range []string{"submit", "approve", "reject"} {
+			app.Post(route.path+"/:id/"+action, review(route.kind, action))
+		}
+	}
+
+	app.Get("/notifications", func(c *fiber.Ctx) error {
+		recipient := actorOr(c.UserContext(), "")
+		var notifications interface{}
+		if recipient != "" {
+			list, err := notificationSvc.WithContext(c.UserContext()).List(recipient, c.Query("all") != "true", 100)
+			if err != nil {
+				return err
+			}
+			notifications = list
+		}
+		return renderTemplate(c, "notifications.html", fiber.Map{
+			"Title":         "Notifications",
+			"Recipient":     recipient,
+			"Notifications": notifications,
+			"All":           c.Query("all") == "true",
+			"Breadcrumb": []map[string]interface{}{
+				{"Name": "Notifications", "Active": true},
+			},
+		})
+	})
+
+	app.Post("/notifications/read-all", func(c *fiber.Ctx) error {
+		recipient := actorOr(c.UserContext(), "")
+		if recipient == "" {
+			return c.Status(fiber.StatusBadRequest).SendString("Sign in to see notifications")
+		}
+		if _, err := notificationSvc.WithContext(c.UserContext()).MarkAllRead(recipient); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+		}
+		c.Set("HX-Refresh", "true")
+		return c.SendString("")
+	})
+
+	app.Post("/notifications/:id/read", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
+		}
+		if err := notificationSvc.WithContext(c.UserContext()).MarkRead(uint(id), actorOr(c.UserContext(), "")); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+		}
+		return c.SendString("")
+	})
+}
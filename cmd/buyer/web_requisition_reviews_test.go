@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/services"
+)
+
+func TestRequisitionReviewRoutes(t *testing.T) {
+	app, db := setupTestApp(t)
+
+	spec, _ := services.NewSpecificationService(db).Create("Laptop", "")
+	req, err := services.NewRequisitionService(db).Create("Laptops", "", 0, []services.RequisitionItemInput{{SpecificationID: spec.ID, Quantity: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.Itoa(int(req.ID))
+
+	post := func(path string, form url.Values) (int, string) {
+		request := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, readBody(t, resp)
+	}
+
+	if status, body := post("/requisitions/"+id+"/submit", nil); status != fiber.StatusOK || body != services.RequisitionSubmitted {
+		t.Fatalf("Failed to submit: %d %s", status, body)
+	}
+	resp, err := app.Test(httptest.NewRequest("GET", "/requisition-reviews", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readBody(t, resp); !strings.Contains(body, "Laptops") || !strings.Contains(body, "/requisitions/"+id+"/approve") {
+		t.Errorf("Expected the requisition in the review queue, got %s", body)
+	}
+
+	if status, _ := post("/requisitions/"+id+"/reject", nil); status != fiber.StatusBadRequest {
+		t.Errorf("Expected 400 rejecting without a comment, got %d", status)
+	}
+	if status, body := post("/requisitions/"+id+"/approve", url.Values{"comment": {"OK"}}); status != fiber.StatusOK || body != services.RequisitionApproved {
+		t.Errorf("Failed to approve: %d %s", status, body)
+	}
+	if status, _ := post("/project-requisitions/999/submit", nil); status != fiber.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown project requisition, got %d", status)
+	}
+}
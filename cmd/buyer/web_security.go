@@ -4,18 +4,18 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"html/template"
+	"log/slog"
+	"strings"
 	"time"
-	"unicode"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/basicauth"
 	"github.com/gofiber/fiber/v2/middleware/csrf"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/shakfu/buyer/internal/models"
 	"github.com/shakfu/buyer/internal/services"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // SecurityConfig holds security-related configuration
@@ -23,8 +23,10 @@ type SecurityConfig struct {
 	EnableAuth        bool
 	EnableCSRF        bool
 	EnableRateLimiter bool
-	Username          string
-	PasswordHash      string // bcrypt hash of the password
+	Users             *services.UserService  // Accounts for the login page and basic auth; required with EnableAuth
+	Tokens            *services.TokenService // Accepts Bearer tokens alongside user accounts, if set
+	SessionTTL        time.Duration          // Session lifetime; defaults to services.DefaultSessionTTL
+	OIDC              *OIDCConfig            // Offers single sign-on on the login page, if set
 }
 
 // SetupSecurityMiddleware adds all security middleware to the Fiber app
@@ -51,7 +53,7 @@ func SetupSecurityMiddleware(app *fiber.App, config SecurityConfig) {
 		}))
 	}
 
-	// Authentication-specific rate limiting (stricter)
+	// Login rate limiting (stricter), counting failed attempts only
 	if config.EnableAuth {
 		authLimiter := limiter.New(limiter.Config{
 			Max:        5,
@@ -62,11 +64,10 @@ func SetupSecurityMiddleware(app *fiber.App, config SecurityConfig) {
 			LimitReached: func(c *fiber.Ctx) error {
 				return c.Status(fiber.StatusTooManyRequests).SendString("Too many authentication attempts. Please try again later.")
 			},
-			SkipFailedRequests:     true,
 			SkipSuccessfulRequests: true,
 			Next: func(c *fiber.Ctx) bool {
-				// Only apply to non-static requests (auth is checked on these)
-				return c.Path() == "/static" || len(c.Path()) > 7 && c.Path()[:7] == "/static"
+				// Only apply to the login form
+				return c.Method() != fiber.MethodPost || c.Path() != loginPath
 			},
 		})
 		app.Use(authLimiter)
@@ -80,89 +81,101 @@ func SetupSecurityMiddleware(app *fiber.App, config SecurityConfig) {
 			CookieSameSite: "Strict",
 			Expiration:     1 * time.Hour,
 			KeyGenerator:   func() string { return generateCSRFToken() },
-		}))
-	}
-
-	// Basic authentication with bcrypt password verification
-	if config.EnableAuth {
-		app.Use(basicauth.New(basicauth.Config{
-			Authorizer: func(username, password string) bool {
-				if username != config.Username {
-					return false
-				}
-				// Use bcrypt to compare password with stored hash
-				err := bcrypt.CompareHashAndPassword([]byte(config.PasswordHash), []byte(password))
-				return err == nil
-			},
-			Realm: "Buyer Application",
 			Next: func(c *fiber.Ctx) bool {
-				// Skip auth for static files
-				return c.Path() == "/static" || len(c.Path()) > 7 && c.Path()[:7] == "/static"
+				// Browsers never send Bearer tokens on their own, so token
+				// requests cannot be forged cross-site. The login and logout
+				// forms are plain HTML forms without the CSRF header.
+				if c.Path() == loginPath || c.Path() == logoutPath {
+					return true
+				}
+				return config.Tokens != nil && bearerToken(c) != ""
 			},
 		}))
 	}
-}
 
-// generateCSRFToken generates a cryptographically secure random CSRF token
-func generateCSRFToken() string {
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
-		panic(fmt.Sprintf("failed to generate CSRF token: %v", err))
+	// Sign-in with user accounts, single sign-on, sessions and tokens
+	if config.EnableAuth {
+		registerLoginRoutes(app, config)
+		app.Use(authMiddleware(config))
 	}
-	return base64.URLEncoding.EncodeToString(b)
 }
 
-// Password validation and hashing functions
-
-// ValidatePassword checks if a password meets security requirements
-func ValidatePassword(password string) error {
-	if len(password) < 12 {
-		return fmt.Errorf("password must be at least 12 characters long")
-	}
-
-	var (
-		hasUpper   bool
-		hasLower   bool
-		hasDigit   bool
-		hasSpecial bool
-	)
-
-	for _, c := range password {
-		switch {
-		case unicode.IsUpper(c):
-			hasUpper = true
-		case unicode.IsLower(c):
-			hasLower = true
-		case unicode.IsDigit(c):
-			hasDigit = true
-		case unicode.IsPunct(c) || unicode.IsSymbol(c):
-			hasSpecial = true
+// tokenLocal is the Fiber local holding the *models.APIToken of a request
+// authenticated by Bearer token
+const tokenLocal = "api_token"
+
+// tokenAuth authenticates a request that carries a Bearer token and checks
+// that the token's scopes allow the request
+func tokenAuth(c *fiber.Ctx, tokens *services.TokenService) error {
+	token, err := tokens.Authenticate(bearerToken(c))
+	if err != nil {
+		if !errors.Is(err, services.ErrInvalidToken) {
+			slog.Error("token authentication failed", slog.String("error", err.Error()))
 		}
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="Buyer Application"`)
+		return authError(c, fiber.StatusUnauthorized, services.ErrInvalidToken.Error())
 	}
 
-	if !hasUpper {
-		return fmt.Errorf("password must contain at least one uppercase letter")
+	scope := requiredScope(c.Method(), c.Path(), purchaseOrderDecision(c))
+	if !services.TokenHasScope(token, scope) {
+		return authError(c, fiber.StatusForbidden, fmt.Sprintf("token %s lacks the %s scope", token.Prefix, scope))
 	}
-	if !hasLower {
-		return fmt.Errorf("password must contain at least one lowercase letter")
+
+	c.Locals(tokenLocal, token)
+	c.Locals("username", token.Owner)
+	c.SetUserContext(models.WithActor(c.UserContext(), token.Owner))
+	return c.Next()
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(c *fiber.Ctx) string {
+	scheme, token, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
 	}
-	if !hasDigit {
-		return fmt.Errorf("password must contain at least one digit")
+	return strings.TrimSpace(token)
+}
+
+// requiredScope returns the token scope a request needs: read for safe
+// methods, quotes:write for quote changes and imports, po:approve for
+// decisions on approval steps and for purchase order updates that only
+// approve or reject the order (decision is the status such an update sets),
+// and admin for any other change
+func requiredScope(method, path, decision string) string {
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return services.ScopeRead
 	}
-	if !hasSpecial {
-		return fmt.Errorf("password must contain at least one special character")
+	resource := strings.TrimPrefix(strings.TrimPrefix(path, apiPrefix), "/")
+	switch {
+	case resource == "quotes" || strings.HasPrefix(resource, "quotes/") || resource == "import/quotes":
+		return services.ScopeQuotesWrite
+	case strings.HasPrefix(resource, "approvals/") && method == fiber.MethodPost &&
+		(strings.HasSuffix(resource, "/approve") || strings.HasSuffix(resource, "/reject")):
+		return services.ScopePOApprove
+	case strings.HasPrefix(resource, "purchase-orders/") && method == fiber.MethodPut &&
+		(decision == "approved" || decision == "rejected"):
+		return services.ScopePOApprove
 	}
+	return services.ScopeAdmin
+}
 
-	return nil
+// authError responds to a failed token check, with problem details under the
+// JSON API and plain text elsewhere
+func authError(c *fiber.Ctx, status int, detail string) error {
+	if strings.HasPrefix(c.Path(), "/api/") {
+		return apiProblem(c, status, detail, "")
+	}
+	return c.Status(status).SendString(detail)
 }
 
-// HashPassword hashes a password using bcrypt
-func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", fmt.Errorf("failed to hash password: %w", err)
+// generateCSRFToken generates a cryptographically secure random CSRF token
+func generateCSRFToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate CSRF token: %v", err))
 	}
-	return string(hash), nil
+	return base64.URLEncoding.EncodeToString(b)
 }
 
 // HTML escaping helper functions
@@ -481,6 +494,7 @@ func RenderRequisitionRow(req *models.Requisition) (SafeHTML, error) {
 			{{if .Justification}}<br><small>{{.Justification}}</small>{{end}}
 			{{if gt .Budget 0.0}}<br><strong>Budget: {{printf "%.2f" .Budget}}</strong>{{end}}
 		</td>
+		<td id="req-status-{{.ID}}">{{.Status}}</td>
 		<td>
 			<ul>
 				{{range .Items}}
@@ -493,6 +507,12 @@ func RenderRequisitionRow(req *models.Requisition) (SafeHTML, error) {
 		</td>
 		<td>
 			<div class="actions">
+				<button class="btn-sm"
+						hx-post="/requisitions/{{.ID}}/submit"
+						hx-target="#req-status-{{.ID}}"
+						hx-confirm="Submit this requisition for review? It cannot be changed while under review.">
+					Submit
+				</button>
 				<button class="btn-sm contrast"
 						hx-delete="/requisitions/{{.ID}}"
 						hx-target="#req-{{.ID}}"
@@ -509,12 +529,14 @@ func RenderRequisitionRow(req *models.Requisition) (SafeHTML, error) {
 		Name          string
 		Justification string
 		Budget        float64
+		Status        string
 		Items         []ItemData
 	}{
 		ID:            req.ID,
 		Name:          req.Name,
 		Justification: req.Justification,
 		Budget:        req.Budget,
+		Status:        req.Status,
 		Items:         items,
 	}
 
@@ -571,6 +593,51 @@ func RenderRequisitionComparison(comparison *services.RequisitionQuoteComparison
 				</tr>
 			</table>
 		</section>
+
+		<section>
+			<h3>Convert to Purchase Orders</h3>
+			{{if eq .Requisition.Status "approved"}}
+			<form hx-post="/requisitions/{{.Requisition.ID}}/order" hx-target="#requisition-order-result" hx-swap="innerHTML">
+				<table>
+					<thead>
+						<tr>
+							<th>Order</th>
+							<th>Specification</th>
+							<th>Quantity</th>
+							<th>Quote</th>
+						</tr>
+					</thead>
+					<tbody>
+						{{range $i, $item := .ItemComparisons}}
+						<tr>
+							<td>
+								<input type="hidden" name="items[{{$i}}][item_id]" value="{{$item.Item.ID}}">
+								{{if $item.HasQuotes}}<input type="checkbox" name="items[{{$i}}][order]" value="true" checked>{{end}}
+							</td>
+							<td>{{if $item.Specification}}{{$item.Specification.Name}}{{end}}</td>
+							<td>{{$item.Item.Quantity}}</td>
+							<td>
+								{{if $item.HasQuotes}}
+								<select name="items[{{$i}}][quote_id]">
+									{{range $item.Quotes}}
+									<option value="{{.ID}}">{{if .Vendor}}{{.Vendor.Name}}{{end}} - {{if .Product}}{{.Product.Name}}{{end}} - {{formatPrice .Price}} {{.Currency}}</option>
+									{{end}}
+								</select>
+								{{else}}
+								<em>No valid quotes</em>
+								{{end}}
+							</td>
+						</tr>
+						{{end}}
+					</tbody>
+				</table>
+				<button type="submit">Convert to POs</button>
+			</form>
+			<div id="requisition-order-result"></div>
+			{{else}}
+			<p><em>This requisition is {{if .Requisition.Status}}{{.Requisition.Status}}{{else}}draft{{end}}; only approved requisitions can be ordered.</em></p>
+			{{end}}
+		</section>
 	</article>`))
 
 	var buf bytes.Buffer
@@ -580,6 +647,55 @@ func RenderRequisitionComparison(comparison *services.RequisitionQuoteComparison
 	return SafeHTML{content: buf.String()}, nil
 }
 
+// RenderRequisitionOrder safely renders the purchase orders created from a
+// requisition, grouped by vendor
+func RenderRequisitionOrder(order *services.RequisitionOrder) (SafeHTML, error) {
+	tmpl := template.Must(template.New("requisitionOrder").Funcs(template.FuncMap{
+		"formatPrice": func(price float64) string {
+			return fmt.Sprintf("%.2f", price)
+		},
+	}).Parse(`<article>
+		<h4>Purchase orders created for {{.Requisition.Name}}</h4>
+		{{range .Vendors}}
+		<h5>{{.Vendor.Name}} (${{formatPrice .Total}})</h5>
+		<table>
+			<thead>
+				<tr>
+					<th>PO Number</th>
+					<th>Product</th>
+					<th>Quantity</th>
+					<th>Total</th>
+				</tr>
+			</thead>
+			<tbody>
+				{{range .Lines}}
+				<tr>
+					<td><a href="/purchase-orders/{{.PurchaseOrder.ID}}">{{.PurchaseOrder.PONumber}}</a></td>
+					<td>{{if .Quote.Product}}{{.Quote.Product.Name}}{{end}}</td>
+					<td>{{.PurchaseOrder.Quantity}}</td>
+					<td>{{formatPrice .PurchaseOrder.TotalAmount}} {{.PurchaseOrder.Currency}}</td>
+				</tr>
+				{{end}}
+			</tbody>
+		</table>
+		{{end}}
+		{{if .Skipped}}
+		<p><strong>Not ordered:</strong></p>
+		<ul>
+			{{range .Skipped}}
+			<li>{{if .Item.Specification}}{{.Item.Specification.Name}}{{else}}Item {{.Item.ID}}{{end}}: {{.Reason}}</li>
+			{{end}}
+		</ul>
+		{{end}}
+	</article>`))
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, order); err != nil {
+		return SafeHTML{}, err
+	}
+	return SafeHTML{content: buf.String()}, nil
+}
+
 // RenderProjectRow safely renders a project table row
 func RenderProjectRow(project *models.Project) (SafeHTML, error) {
 	tmpl := template.Must(template.New("projectRow").Parse(`<tr id="project-{{.ID}}">
@@ -708,10 +824,19 @@ func RenderBOMItemRow(item *models.BillOfMaterialsItem) (SafeHTML, error) {
 func RenderProjectRequisitionRow(projectReq *models.ProjectRequisition) (SafeHTML, error) {
 	tmpl := template.Must(template.New("projectReqRow").Parse(`<tr id="project-req-{{.ID}}">
 		<td>{{.Name}}</td>
+		<td id="project-req-status-{{.ID}}">{{.Status}}</td>
 		<td>{{.Budget}}</td>
 		<td>{{.ItemCount}}</td>
 		<td>
 			<div class="actions">
+				{{if or (eq .Status "draft") (eq .Status "rejected")}}
+				<button class="btn-sm"
+						hx-post="/project-requisitions/{{.ID}}/submit"
+						hx-target="#project-req-status-{{.ID}}"
+						hx-confirm="Submit this project requisition for review? It cannot be changed while under review.">
+					Submit
+				</button>
+				{{end}}
 				<button class="btn-sm secondary" onclick="editProjectRequisition({{.ID}})">Edit</button>
 				<button class="btn-sm contrast"
 						hx-delete="/project-requisitions/{{.ID}}"
@@ -732,11 +857,13 @@ func RenderProjectRequisitionRow(projectReq *models.ProjectRequisition) (SafeHTM
 	data := struct {
 		ID        uint
 		Name      string
+		Status    string
 		Budget    float64
 		ItemCount int
 	}{
 		ID:        projectReq.ID,
 		Name:      projectReq.Name,
+		Status:    projectReq.Status,
 		Budget:    projectReq.Budget,
 		ItemCount: itemCount,
 	}
@@ -776,7 +903,7 @@ func RenderProjectRequisitionsList(projectReqs []models.ProjectRequisition) (Saf
 	}
 
 	var buf bytes.Buffer
-	buf.WriteString("<table><thead><tr><th>Name</th><th>Budget</th><th>Items</th><th>Actions</th></tr></thead><tbody>")
+	buf.WriteString("<table><thead><tr><th>Name</th><th>Status</th><th>Budget</th><th>Items</th><th>Actions</th></tr></thead><tbody>")
 
 	for _, projectReq := range projectReqs {
 		html, err := RenderProjectRequisitionRow(&projectReq)
@@ -848,7 +975,7 @@ func RenderDocumentRow(doc *models.Document) (SafeHTML, error) {
 	<td>{{.ID}}</td>
 	<td>{{.EntityType}}</td>
 	<td>{{.EntityID}}</td>
-	<td><code>{{.FileName}}</code></td>
+	<td><a href="/documents/{{.ID}}/download"><code>{{.FileName}}</code></a></td>
 	<td>{{.FileType}}</td>
 	<td>
 		{{if gt .FileSize 0}}
@@ -953,3 +1080,153 @@ func RenderVendorRatingRow(rating *models.VendorRating) (SafeHTML, error) {
 
 	return SafeHTML{content: buf.String()}, nil
 }
+
+// RenderImportPreview safely renders the mapped preview rows of a profile import
+func RenderImportPreview(preview *services.ImportPreview) (SafeHTML, error) {
+	tmpl := `<article id="import-preview">
+	<header>
+		<strong>Preview: {{.Profile}}</strong> ({{.Entity}}) &mdash;
+		showing {{len .Rows}} of {{.TotalRows}} rows.
+		Would create {{.Result.CreatedCount}}, update {{.Result.UpdatedCount}}, skip {{.Result.SkippedCount}}, errors {{.Result.ErrorCount}}.
+	</header>
+	<figure>
+		<table role="grid">
+			<thead>
+				<tr>
+					<th>Row</th>
+					{{range .Fields}}<th>{{.}}</th>{{end}}
+					<th>Action</th>
+				</tr>
+			</thead>
+			<tbody>
+				{{range .Rows}}
+				<tr>
+					<td>{{.Row}}</td>
+					{{range .Values}}<td>{{.}}</td>{{end}}
+					<td>
+						<span class="badge badge-{{.Action}}">{{.Action}}</span>
+						{{if .Message}}<small>{{.Message}}</small>{{end}}
+					</td>
+				</tr>
+				{{end}}
+			</tbody>
+		</table>
+	</figure>
+	{{if .Result.Errors}}
+	<details>
+		<summary>{{len .Result.Errors}} error(s)</summary>
+		<ul>{{range .Result.Errors}}<li>{{.}}</li>{{end}}</ul>
+	</details>
+	{{end}}
+</article>
+`
+
+	t, err := template.New("import-preview").Parse(tmpl)
+	if err != nil {
+		return SafeHTML{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, preview); err != nil {
+		return SafeHTML{}, err
+	}
+
+	return SafeHTML{content: buf.String()}, nil
+}
+
+// RenderImportResult safely renders an import summary
+func RenderImportResult(result *services.ImportResult) (SafeHTML, error) {
+	tmpl := `<article id="import-result">
+	<header><strong>{{if .DryRun}}Dry run - no changes were written{{else if .RolledBack}}Import rolled back - no changes were written{{else}}Import complete{{end}}</strong></header>
+	<p>Created: {{.CreatedCount}} &middot; Updated: {{.UpdatedCount}} &middot; Skipped: {{.SkippedCount}} &middot; Errors: {{.ErrorCount}}</p>
+	{{if .Errors}}
+	<ul>{{range .Errors}}<li>{{.}}</li>{{end}}</ul>
+	{{end}}
+</article>
+`
+
+	t, err := template.New("import-result").Parse(tmpl)
+	if err != nil {
+		return SafeHTML{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, result); err != nil {
+		return SafeHTML{}, err
+	}
+
+	return SafeHTML{content: buf.String()}, nil
+}
+
+// RenderInvoiceImport safely renders the report of an imported UBL invoice
+func RenderInvoiceImport(result *services.InvoiceImportResult) (SafeHTML, error) {
+	tmpl := `<article id="import-result">
+	<header><strong>{{if .Invoice.ID}}Imported{{else}}Dry run - not saved:{{end}}
+		{{if eq .Invoice.DocumentType "credit_note"}}credit note{{else}}invoice{{end}}
+		{{if .Invoice.ID}}<a href="/invoices/{{.Invoice.ID}}">{{.Invoice.InvoiceNumber}}</a>{{else}}{{.Invoice.InvoiceNumber}}{{end}}
+		from {{if .Invoice.Vendor}}{{.Invoice.Vendor.Name}}{{else}}{{.Invoice.SupplierName}}{{end}}, {{.Invoice.Status}}</strong></header>
+	<p>Lines: {{len .Invoice.Lines}} &middot; Net: {{printf "%.2f" .Invoice.NetAmount}} &middot; Tax: {{printf "%.2f" .Invoice.TaxAmount}} &middot; Total: {{printf "%.2f" .Invoice.TotalAmount}} {{.Invoice.Currency}}</p>
+	{{if .Problems}}
+	<ul>{{range .Problems}}<li>{{.}}</li>{{end}}</ul>
+	{{end}}
+</article>
+`
+
+	t, err := template.New("invoice-import").Parse(tmpl)
+	if err != nil {
+		return SafeHTML{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, result); err != nil {
+		return SafeHTML{}, err
+	}
+
+	return SafeHTML{content: buf.String()}, nil
+}
+
+// RenderAuditHistory safely renders the change history of a record, newest
+// first
+func RenderAuditHistory(entries []services.AuditEntry) (SafeHTML, error) {
+	tmpl := `{{if .}}<figure>
+	<table role="grid" class="audit-history">
+		<thead>
+			<tr>
+				<th>When</th>
+				<th>Action</th>
+				<th>By</th>
+				<th>Changes</th>
+			</tr>
+		</thead>
+		<tbody>
+			{{range .}}
+			<tr>
+				<td>{{.CreatedAt.Format "2006-01-02 15:04"}}</td>
+				<td><span class="badge badge-{{.Action}}">{{.Action}}</span></td>
+				<td>{{if .Actor}}{{.Actor}}{{else}}<span style="color: gray;">—</span>{{end}}{{if .Source}} <small>({{.Source}})</small>{{end}}</td>
+				<td>
+					<ul>
+						{{range .Fields}}
+						<li><strong>{{.Field}}</strong>: {{if .Old}}<del>{{.Old}}</del> &rarr; {{end}}{{.New}}</li>
+						{{end}}
+					</ul>
+				</td>
+			</tr>
+			{{end}}
+		</tbody>
+	</table>
+</figure>{{else}}<p>No recorded changes.</p>{{end}}
+`
+
+	t, err := template.New("audit-history").Parse(tmpl)
+	if err != nil {
+		return SafeHTML{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, entries); err != nil {
+		return SafeHTML{}, err
+	}
+
+	return SafeHTML{content: buf.String()}, nil
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"mime/multipart"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
@@ -13,12 +16,25 @@ import (
 	"github.com/shakfu/buyer/internal/config"
 	"github.com/shakfu/buyer/internal/models"
 	"github.com/shakfu/buyer/internal/services"
+	"github.com/shakfu/buyer/internal/storage"
+	"github.com/shakfu/buyer/internal/storage/s3test"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-// setupTestApp creates a Fiber app with test database and services
+// setupTestApp creates a Fiber app with test database and services, keeping
+// document files in a temporary directory
 func setupTestApp(t *testing.T) (*fiber.App, *gorm.DB) {
+	store, err := storage.NewLocalBackend(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return setupTestAppWithStorage(t, store)
+}
+
+// setupTestAppWithStorage creates a Fiber app with test database and
+// services, keeping document files in store
+func setupTestAppWithStorage(t *testing.T, store storage.StorageBackend) (*fiber.App, *gorm.DB) {
 	// Create in-memory database
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	if err != nil {
@@ -26,27 +42,7 @@ func setupTestApp(t *testing.T) (*fiber.App, *gorm.DB) {
 	}
 
 	// Auto-migrate models
-	err = db.AutoMigrate(
-		&models.Vendor{},
-		&models.Brand{},
-		&models.Specification{},
-		&models.SpecificationAttribute{},
-		&models.Product{},
-		&models.ProductAttribute{},
-		&models.Requisition{},
-		&models.RequisitionItem{},
-		&models.Quote{},
-		&models.Forex{},
-		&models.Project{},
-		&models.BillOfMaterials{},
-		&models.BillOfMaterialsItem{},
-		&models.ProjectRequisition{},
-		&models.ProjectRequisitionItem{},
-		&models.ProjectProcurementStrategy{},
-		&models.PurchaseOrder{},
-		&models.Document{},
-		&models.VendorRating{},
-	)
+	err = db.AutoMigrate(models.All()...)
 	if err != nil {
 		t.Fatalf("failed to migrate test database: %v", err)
 	}
@@ -63,7 +59,7 @@ func setupTestApp(t *testing.T) (*fiber.App, *gorm.DB) {
 	projectSvc := services.NewProjectService(db)
 	projectReqSvc := services.NewProjectRequisitionService(db)
 	poSvc := services.NewPurchaseOrderService(db)
-	docSvc := services.NewDocumentService(db)
+	docSvc := services.NewDocumentService(db).WithStorage(store)
 	ratingsSvc := services.NewVendorRatingService(db)
 
 	// Create Fiber app
@@ -373,6 +369,9 @@ func TestWebHandler_ProjectDashboard(t *testing.T) {
 	if resp.StatusCode != 200 {
 		t.Errorf("expected status 200, got %d", resp.StatusCode)
 	}
+	if body := readBody(t, resp); !strings.Contains(body, "Spend Against Budget") {
+		t.Errorf("expected the spend section, got %s", body)
+	}
 }
 
 func TestWebHandler_RequisitionComparison(t *testing.T) {
@@ -578,6 +577,259 @@ func TestWebHandler_CreateForex(t *testing.T) {
 	}
 }
 
+func TestWebHandler_Coding(t *testing.T) {
+	app, db := setupTestApp(t)
+	seedTestData(t, db)
+
+	post := func(path string, form url.Values) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := post("/coding/cost-centers", url.Values{"code": {"ENG"}, "name": {"Engineering"}}); resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected status 201, got %d", resp.StatusCode)
+	}
+	if resp := post("/coding/gl-accounts", url.Values{"code": {"6100"}, "name": {"IT Equipment"}}); resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("expected status 201, got %d", resp.StatusCode)
+	}
+	if resp := post("/coding/cost-centers", url.Values{"code": {"ENG"}, "name": {"Again"}}); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("expected status 400 for a duplicate code, got %d", resp.StatusCode)
+	}
+
+	var project models.Project
+	if err := db.First(&project).Error; err != nil {
+		t.Fatal(err)
+	}
+	if resp := post(fmt.Sprintf("/projects/%d/coding", project.ID), url.Values{"cost_center_id": {"1"}, "gl_account_id": {"1"}}); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if db.First(&project, project.ID); project.CostCenterID == nil || project.GLAccountID == nil {
+		t.Errorf("expected the project to be coded, got %+v", project)
+	}
+
+	req := httptest.NewRequest("GET", "/coding", nil)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := readBody(t, resp)
+	if resp.StatusCode != 200 || !strings.Contains(body, "Engineering") || !strings.Contains(body, "Spend by Cost Center") {
+		t.Errorf("expected the coding page, got %d", resp.StatusCode)
+	}
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/projects/%d", project.ID), nil)
+	if resp, err = app.Test(req, -1); err != nil {
+		t.Fatal(err)
+	}
+	if body := readBody(t, resp); !strings.Contains(body, "Default coding") || !strings.Contains(body, "ENG - Engineering") {
+		t.Error("expected the project's coding form")
+	}
+}
+
+func TestWebHandler_ImportUBL(t *testing.T) {
+	app, _ := setupTestApp(t)
+
+	upload := func(filename, content string) (*http.Response, string) {
+		t.Helper()
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		part, _ := writer.CreateFormFile("file", filename)
+		_, _ = part.Write([]byte(content))
+		_ = writer.Close()
+		req := httptest.NewRequest("POST", "/import/ubl", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("HX-Request", "true")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, readBody(t, resp)
+	}
+
+	if resp, _ := upload("invoice.csv", "a,b\n"); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("expected status 400 for a CSV file, got %d", resp.StatusCode)
+	}
+
+	document := `<?xml version="1.0"?>
+<Invoice xmlns="urn:oasis:names:specification:ubl:schema:xsd:Invoice-2" xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2" xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">
+  <cbc:ID>INV-7</cbc:ID><cbc:IssueDate>2026-10-15</cbc:IssueDate><cbc:DocumentCurrencyCode>USD</cbc:DocumentCurrencyCode>
+  <cac:AccountingSupplierParty><cac:Party><cac:PartyName><cbc:Name>Test Vendor</cbc:Name></cac:PartyName></cac:Party></cac:AccountingSupplierParty>
+  <cac:LegalMonetaryTotal><cbc:PayableAmount currencyID="USD">100.00</cbc:PayableAmount></cac:LegalMonetaryTotal>
+  <cac:InvoiceLine><cbc:ID>1</cbc:ID><cbc:InvoicedQuantity>1</cbc:InvoicedQuantity><cbc:LineExtensionAmount currencyID="USD">100.00</cbc:LineExtensionAmount>
+    <cac:Item><cbc:Name>Widget</cbc:Name></cac:Item></cac:InvoiceLine>
+</Invoice>`
+	resp, body := upload("inv-7.xml", document)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", resp.StatusCode, body)
+	}
+	for _, want := range []string{"INV-7", "Test Vendor, unmatched", "line 1: no order reference or SKU to match"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected the report to contain %q, got: %s", want, body)
+		}
+	}
+	if resp, _ := upload("inv-7.xml", document); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("expected status 400 importing the invoice again, got %d", resp.StatusCode)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/invoices?status=unmatched", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readBody(t, resp); resp.StatusCode != 200 || !strings.Contains(body, `<a href="/invoices/1">INV-7</a>`) {
+		t.Errorf("expected the invoices page to list INV-7, got %d", resp.StatusCode)
+	}
+	resp, err = app.Test(httptest.NewRequest("GET", "/invoices/1", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readBody(t, resp); resp.StatusCode != 200 || !strings.Contains(body, "Widget") || !strings.Contains(body, "no order reference or SKU to match") ||
+		!strings.Contains(body, `hx-get="/history/invoice/1"`) {
+		t.Errorf("expected the invoice page with its lines, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebHandler_PurchaseOrderPDF(t *testing.T) {
+	app, db := setupTestApp(t)
+
+	vendor, _ := services.NewVendorService(db).Create("PDF Vendor", "USD", "")
+	brand, _ := services.NewBrandService(db).Create("PDF Brand")
+	product, _ := services.NewProductService(db).Create("PDF Product", brand.ID, nil)
+	quote, err := services.NewQuoteService(db).Create(services.CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 10, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivery := time.Now().AddDate(0, 0, 14)
+	po, err := services.NewPurchaseOrderService(db).Create(services.CreatePurchaseOrderInput{QuoteID: quote.ID, PONumber: "PO-WEB-PDF", Quantity: 3, ExpectedDelivery: &delivery})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", fmt.Sprintf("/purchase-orders/%d", po.ID), nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readBody(t, resp); !strings.Contains(body, fmt.Sprintf(`href="/purchase-orders/%d/pdf"`, po.ID)) {
+		t.Errorf("expected a download button on the purchase order page, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", fmt.Sprintf("/purchase-orders/%d/pdf", po.ID), nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := readBody(t, resp)
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "application/pdf" || !strings.HasPrefix(body, "%PDF-") {
+		t.Fatalf("expected a PDF, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if disposition := resp.Header.Get("Content-Disposition"); disposition != `attachment; filename="PO-WEB-PDF.pdf"` {
+		t.Errorf("unexpected Content-Disposition %q", disposition)
+	}
+	if docs, _ := services.NewDocumentService(db).ListByEntity("purchase_order", po.ID); len(docs) != 0 {
+		t.Errorf("expected downloading the PDF to store nothing, got %d documents", len(docs))
+	}
+
+	resp, err = app.Test(httptest.NewRequest("POST", fmt.Sprintf("/purchase-orders/%d/pdf", po.ID), nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusCreated || resp.Header.Get("HX-Refresh") != "true" {
+		t.Errorf("expected the PDF saved, got %d", resp.StatusCode)
+	}
+	if docs, _ := services.NewDocumentService(db).ListByEntity("purchase_order", po.ID); len(docs) != 1 || docs[0].FileName != "PO-WEB-PDF.pdf" {
+		t.Errorf("expected the PDF stored as a document of the order, got %+v", docs)
+	}
+
+	for _, method := range []string{"GET", "POST"} {
+		resp, err = app.Test(httptest.NewRequest(method, "/purchase-orders/999/pdf", nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != 404 {
+			t.Errorf("expected status 404 for %s of an unknown order, got %d", method, resp.StatusCode)
+		}
+	}
+}
+
+func TestWebHandler_Documents(t *testing.T) {
+	server := s3test.New("buyer", "secret")
+	defer server.Close()
+	backend, err := storage.NewMinioBackend(server.Endpoint, "buyer", "secret", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, db := setupTestAppWithStorage(t, backend)
+
+	vendor, _ := services.NewVendorService(db).Create("Doc Vendor", "USD", "")
+	upload := func(fields map[string]string, filename, content string) (*http.Response, string) {
+		t.Helper()
+		var buf bytes.Buffer
+		writer := multipart.NewWriter(&buf)
+		for name, value := range fields {
+			_ = writer.WriteField(name, value)
+		}
+		if filename != "" {
+			part, _ := writer.CreateFormFile("file", filename)
+			_, _ = part.Write([]byte(content))
+		}
+		_ = writer.Close()
+		req := httptest.NewRequest("POST", "/documents", &buf)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, readBody(t, resp)
+	}
+
+	fields := map[string]string{"entity_type": "vendor", "entity_id": fmt.Sprint(vendor.ID), "description": "Signed W-9"}
+	if resp, _ := upload(fields, "", ""); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("expected status 400 without a file, got %d", resp.StatusCode)
+	}
+	if resp, _ := upload(map[string]string{"entity_type": "planet", "entity_id": "1"}, "w9.pdf", "x"); resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("expected status 400 for an unknown entity type, got %d", resp.StatusCode)
+	}
+	resp, body := upload(fields, "w9.pdf", "%PDF-1.4 signed")
+	if resp.StatusCode != 200 || !strings.Contains(body, `href="/documents/1/download"`) {
+		t.Fatalf("expected the document row, got %d: %s", resp.StatusCode, body)
+	}
+	doc, err := services.NewDocumentService(db).GetByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := server.Object("vendor-docs", doc.FilePath); !ok || string(data) != "%PDF-1.4 signed" || doc.FileSize != 15 || doc.FileType != "pdf" {
+		t.Errorf("expected the file stored in the vendor-docs bucket, got %+v", doc)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/documents/1/download", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readBody(t, resp); resp.StatusCode != 200 || body != "%PDF-1.4 signed" || resp.Header.Get("Content-Type") != "application/pdf" ||
+		resp.Header.Get("Content-Disposition") != `attachment; filename="w9.pdf"` {
+		t.Errorf("expected the file as an attachment, got %d %v: %q", resp.StatusCode, resp.Header, body)
+	}
+	resp, err = app.Test(httptest.NewRequest("GET", "/documents/99/download", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 404 {
+		t.Errorf("expected status 404 for an unknown document, got %d", resp.StatusCode)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("DELETE", "/documents/1", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := server.Object("vendor-docs", doc.FilePath); resp.StatusCode != 200 || ok {
+		t.Errorf("expected the document and its file deleted, got %d", resp.StatusCode)
+	}
+}
+
 func TestWebHandler_CreateProject(t *testing.T) {
 	app, _ := setupTestApp(t)
 
@@ -1314,3 +1566,62 @@ func TestWebHandler_ProductAttributeValidation(t *testing.T) {
 func ptrFloat64(f float64) *float64 {
 	return &f
 }
+
+func TestWebHandler_ImportProfilePreview(t *testing.T) {
+	app, db := setupTestApp(t)
+	seedTestData(t, db)
+
+	var vendor models.Vendor
+	db.First(&vendor, "name = ?", "Test Vendor")
+
+	profile, err := services.NewImportProfileService(db).Create(services.CreateImportProfileInput{
+		Name:     "test-prices",
+		Entity:   "quotes",
+		VendorID: &vendor.ID,
+		Fields: []models.ImportProfileField{
+			{Field: "ProductName", Column: "Item"},
+			{Field: "Price", Column: "Unit Cost", Transform: "multiply:2"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create profile: %v", err)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/import", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || !strings.Contains(string(body), "test-prices") {
+		t.Errorf("expected import page listing the profile, got status %d", resp.StatusCode)
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	_ = writer.WriteField("profile_id", fmt.Sprintf("%d", profile.ID))
+	part, _ := writer.CreateFormFile("file", "prices.csv")
+	_, _ = part.Write([]byte("Item,Unit Cost\nTest Product,12.5\nUnknown,3\n"))
+	_ = writer.Close()
+
+	req := httptest.NewRequest("POST", "/import/profile/preview", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	resp, err = app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d: %s", resp.StatusCode, body)
+	}
+	for _, want := range []string{"Test Product", "25", "showing 2 of 2 rows", "badge-error"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected preview to contain %q, got: %s", want, body)
+		}
+	}
+
+	var count int64
+	db.Model(&models.Quote{}).Where("vendor_id = ? AND price = ?", vendor.ID, 25.0).Count(&count)
+	if count != 0 {
+		t.Errorf("preview must not create quotes, found %d", count)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/services"
+	"gorm.io/gorm"
+)
+
+// registerTrashRoutes adds the trash page and restoring records from it
+func registerTrashRoutes(app *fiber.App, db *gorm.DB) {
+	trashSvc := services.NewTrashService(db)
+
+	app.Get("/trash", func(c *fiber.Ctx) error {
+		entity := c.Query("entity")
+		if entity != "" {
+			var err error
+			if entity, err = services.TrashEntity(entity); err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+			}
+		}
+		items, err := trashSvc.WithContext(c.UserContext()).List(entity)
+		if err != nil {
+			return err
+		}
+		return renderTemplate(c, "trash.html", fiber.Map{
+			"Title":    "Trash",
+			"Items":    items,
+			"Entity":   entity,
+			"Entities": services.TrashEntities(),
+			"Breadcrumb": []map[string]interface{}{
+				{"Name": "Trash", "Active": true},
+			},
+		})
+	})
+
+	app.Post("/trash/:entity/:id/restore", func(c *fiber.Ctx) error {
+		entity, err := services.TrashEntity(c.Params("entity"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+		}
+		id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Invalid ID")
+		}
+		if _, err := trashSvc.WithContext(c.UserContext()).Restore(entity, uint(id)); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(escapeHTML(err.Error()))
+		}
+		return c.SendString("")
+	})
+}
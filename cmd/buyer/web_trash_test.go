@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shakfu/buyer/internal/services"
+)
+
+func TestTrashPage(t *testing.T) {
+	app, db := setupTestApp(t)
+
+	brand, err := services.NewBrandService(db).Create("Acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.Itoa(int(brand.ID))
+	if resp, err := app.Test(httptest.NewRequest("DELETE", "/brands/"+id, nil)); err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Failed to delete brand: %v", err)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/trash", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := readBody(t, resp); !strings.Contains(body, "Acme") || !strings.Contains(body, "/trash/brand/"+id+"/restore") {
+		t.Errorf("Expected the brand in the trash, got %s", body)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/trash/brand/"+id+"/restore", nil))
+	if err != nil || resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Failed to restore brand: %v %v", resp.StatusCode, err)
+	}
+	if _, err := services.NewBrandService(db).GetByID(brand.ID); err != nil {
+		t.Errorf("Expected the brand to be restored: %v", err)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest("POST", "/trash/brand/"+id+"/restore", nil))
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected 400 restoring a live brand, got %d", resp.StatusCode)
+	}
+}
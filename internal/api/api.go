@@ -0,0 +1,245 @@
+// Package api defines the request and error bodies of the buyer JSON API. The
+// web server decodes requests into these types, the OpenAPI document is
+// generated from them and the Go client in package client sends them.
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shakfu/buyer/internal/openapi"
+)
+
+// Problem is an RFC 9457 problem details body, returned for every error
+// under /api/v1
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+	Field  string `json:"field,omitempty"` // Set on validation errors
+}
+
+func (p *Problem) Error() string {
+	if p.Field != "" {
+		return fmt.Sprintf("%d %s: %s (field %s)", p.Status, p.Title, p.Detail, p.Field)
+	}
+	return fmt.Sprintf("%d %s: %s", p.Status, p.Title, p.Detail)
+}
+
+// ProblemDetails marks Problem as sent with the problem+json media type
+func (Problem) ProblemDetails() bool { return true }
+
+// ErrorMessage is the error body of the project procurement endpoints
+type ErrorMessage struct {
+	Error string `json:"error"`
+}
+
+// Date is a date in a request body, given as YYYY-MM-DD or RFC 3339. A missing,
+// empty or null date is the zero value.
+type Date struct {
+	time.Time
+}
+
+// NewDate returns a Date for t
+func NewDate(t time.Time) *Date {
+	return &Date{Time: t}
+}
+
+// UnmarshalJSON accepts YYYY-MM-DD or RFC 3339 strings
+func (d *Date) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		d.Time = time.Time{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		d.Time = time.Time{}
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			d.Time = t
+			return nil
+		}
+	}
+	return &DateError{Value: s}
+}
+
+// MarshalJSON writes the date in RFC 3339, or null if it is zero
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.Format(time.RFC3339))
+}
+
+// OpenAPISchema describes Date as a string
+func (Date) OpenAPISchema() *openapi.Schema {
+	return &openapi.Schema{Type: "string", Description: "YYYY-MM-DD or RFC 3339 date-time", Nullable: true}
+}
+
+// Value returns the date, or the zero time if it was not given
+func (d *Date) Value() time.Time {
+	if d == nil {
+		return time.Time{}
+	}
+	return d.Time
+}
+
+// Ptr returns the date, or nil if it was not given
+func (d *Date) Ptr() *time.Time {
+	if d == nil || d.IsZero() {
+		return nil
+	}
+	t := d.Time
+	return &t
+}
+
+// DateError reports a date that could not be parsed
+type DateError struct {
+	Value string
+}
+
+func (e *DateError) Error() string {
+	return fmt.Sprintf("'%s' is not a date (YYYY-MM-DD or RFC 3339)", e.Value)
+}
+
+// BrandInput creates or renames a brand
+type BrandInput struct {
+	Name string `json:"name"`
+}
+
+// ProductInput creates a product, or renames it and changes its
+// specification; the brand cannot be changed
+type ProductInput struct {
+	Name            string `json:"name"`
+	BrandID         uint   `json:"brand_id"`
+	SpecificationID *uint  `json:"specification_id"`
+}
+
+// SpecificationInput creates or updates a specification
+type SpecificationInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// VendorInput creates a vendor, or renames it; currency and discount code are
+// only used on create
+type VendorInput struct {
+	Name         string `json:"name"`
+	Currency     string `json:"currency"`
+	DiscountCode string `json:"discount_code"`
+}
+
+// QuoteInput creates a quote. Updates change only price, currency,
+// valid_until and notes.
+type QuoteInput struct {
+	VendorID   uint    `json:"vendor_id"`
+	ProductID  uint    `json:"product_id"`
+	Price      float64 `json:"price"`
+	Currency   string  `json:"currency"`
+	QuoteDate  *Date   `json:"quote_date"`
+	ValidUntil *Date   `json:"valid_until"`
+	Notes      string  `json:"notes"`
+}
+
+// ForexInput creates a forex rate. Updates change only rate and
+// effective_date.
+type ForexInput struct {
+	FromCurrency  string  `json:"from_currency"`
+	ToCurrency    string  `json:"to_currency"`
+	Rate          float64 `json:"rate"`
+	EffectiveDate *Date   `json:"effective_date"`
+}
+
+// RequisitionInput creates a requisition with its items. Updates change only
+// name, justification and budget.
+type RequisitionInput struct {
+	Name          string                 `json:"name"`
+	Justification string                 `json:"justification"`
+	Budget        float64                `json:"budget"`
+	Items         []RequisitionItemInput `json:"items"`
+}
+
+// RequisitionItemInput is one line of a new requisition
+type RequisitionItemInput struct {
+	SpecificationID uint    `json:"specification_id"`
+	Quantity        int     `json:"quantity"`
+	BudgetPerUnit   float64 `json:"budget_per_unit"`
+	Description     string  `json:"description"`
+}
+
+// ProjectInput creates or updates a project; status is only used on update
+type ProjectInput struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Budget      float64 `json:"budget"`
+	Deadline    *Date   `json:"deadline"`
+	Status      string  `json:"status"`
+}
+
+// BOMItemInput adds an item to a project's bill of materials. Updates change
+// only quantity and notes.
+type BOMItemInput struct {
+	SpecificationID uint   `json:"specification_id"`
+	Quantity        int    `json:"quantity"`
+	Notes           string `json:"notes"`
+}
+
+// PurchaseOrderInput creates a purchase order from a quote
+type PurchaseOrderInput struct {
+	QuoteID                  uint    `json:"quote_id"`
+	RequisitionID            *uint   `json:"requisition_id"`
+	ProjectRequisitionItemID *uint   `json:"project_requisition_item_id"`
+	ProjectID                *uint   `json:"project_id"`
+	PONumber                 string  `json:"po_number"`
+	Quantity                 int     `json:"quantity"`
+	ExpectedDelivery         *Date   `json:"expected_delivery"`
+	ShippingCost             float64 `json:"shipping_cost"`
+	Tax                      float64 `json:"tax"`
+	Notes                    string  `json:"notes"`
+}
+
+// PurchaseOrderUpdate changes a purchase order after it is created; fields
+// left out are not changed
+type PurchaseOrderUpdate struct {
+	Status           *string `json:"status,omitempty"`
+	ExpectedDelivery *Date   `json:"expected_delivery,omitempty"`
+	ActualDelivery   *Date   `json:"actual_delivery,omitempty"`
+	InvoiceNumber    *string `json:"invoice_number,omitempty"`
+}
+
+// DocumentInput holds the form fields uploaded with a document's file, or
+// the JSON body updating a document. The file name and type default to the
+// uploaded file's.
+type DocumentInput struct {
+	EntityType  string `json:"entity_type"`
+	EntityID    uint   `json:"entity_id"`
+	FileName    string `json:"file_name"`
+	FileType    string `json:"file_type"`
+	Description string `json:"description"`
+	UploadedBy  string `json:"uploaded_by"`
+}
+
+// VendorRatingInput creates or updates a vendor rating; ratings are 1 to 5
+type VendorRatingInput struct {
+	VendorID        uint   `json:"vendor_id"`
+	PurchaseOrderID *uint  `json:"purchase_order_id"`
+	PriceRating     *int   `json:"price_rating"`
+	QualityRating   *int   `json:"quality_rating"`
+	DeliveryRating  *int   `json:"delivery_rating"`
+	ServiceRating   *int   `json:"service_rating"`
+	Comments        string `json:"comments"`
+	RatedBy         string `json:"rated_by"`
+}
+
+// StrategyInput sets a project's procurement strategy
+type StrategyInput struct {
+	Strategy string `json:"strategy"`
+}
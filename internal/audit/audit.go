@@ -0,0 +1,406 @@
+// Package audit records every create, update and delete made through GORM in
+// the audit_logs table. Register installs callbacks that read the affected
+// rows before and after each write and store a field-by-field diff, together
+// with the actor and source carried by the statement's context (see
+// models.WithActor and models.WithSource). The audit rows are written in the
+// same transaction as the change they describe.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Actions recorded in AuditLog.Action
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionRestore = "restore" // A record taken back out of the trash
+)
+
+// Change is the old and new value of one column. Old is nil for creates and
+// New is nil for deletes.
+type Change struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// skippedTables are not audited: the audit log itself, sign-in bookkeeping
+// that changes on every request, user notifications, PO number sequences and
+// the orders of accounting exports, which the export batch itself records
+var skippedTables = map[string]bool{
+	"audit_logs":                true,
+	"sessions":                  true,
+	"schema_migrations":         true,
+	"notifications":             true,
+	"po_number_sequences":       true,
+	"accounting_export_entries": true,
+}
+
+// ignoredColumns change on their own and would make every save look like a
+// change; an update that only touches them is not recorded
+var ignoredColumns = map[string]bool{
+	"created_at":    true,
+	"updated_at":    true,
+	"last_used_at":  true,
+	"last_seen_at":  true,
+	"last_login_at": true,
+}
+
+// redactedColumns are recorded as changed without their values
+var redactedColumns = map[string]bool{
+	"password_hash": true,
+	"token_hash":    true,
+}
+
+const redacted = "[redacted]"
+
+// trashColumn is set on records in the trash; see models.DeletedAt
+const trashColumn = "deleted_at"
+
+// beforeKey holds the rows read before an update or delete
+const beforeKey = "audit:before"
+
+// Register installs the audit callbacks on db
+func Register(db *gorm.DB) error {
+	callbacks := db.Callback()
+	steps := []struct {
+		register func(string, func(*gorm.DB)) error
+		name     string
+		fn       func(*gorm.DB)
+	}{
+		{callbacks.Create().After("gorm:create").Register, "audit:after_create", afterCreate},
+		{callbacks.Update().Before("gorm:update").Register, "audit:before_update", captureBefore},
+		{callbacks.Update().After("gorm:update").Register, "audit:after_update", afterUpdate},
+		{callbacks.Delete().Before("gorm:delete").Register, "audit:before_delete", captureBefore},
+		{callbacks.Delete().After("gorm:delete").Register, "audit:after_delete", afterDelete},
+	}
+	for _, step := range steps {
+		if err := step.register(step.name, step.fn); err != nil {
+			return fmt.Errorf("failed to register %s: %w", step.name, err)
+		}
+	}
+	return nil
+}
+
+// audited reports whether the statement's writes are recorded. Statements
+// that skip hooks, such as restoring a backup, are not.
+func audited(db *gorm.DB) bool {
+	stmt := db.Statement
+	return db.Error == nil && !stmt.SkipHooks && stmt.Schema != nil &&
+		stmt.Schema.PrioritizedPrimaryField != nil && !skippedTables[stmt.Table]
+}
+
+func afterCreate(db *gorm.DB) {
+	if !audited(db) || db.RowsAffected == 0 {
+		return
+	}
+	ids := modelIDs(db)
+	if len(ids) == 0 {
+		return
+	}
+	rows, err := readRows(db, ids, clause.Where{})
+	if err != nil {
+		_ = db.AddError(fmt.Errorf("audit: %w", err))
+		return
+	}
+	var entries []models.AuditLog
+	for _, value := range ids {
+		id, _ := toUint(value)
+		if row, ok := rows[id]; ok {
+			entries = appendEntry(entries, db, ActionCreate, id, diff(nil, row))
+		}
+	}
+	write(db, entries)
+}
+
+// captureBefore reads the rows an update or delete is about to change
+func captureBefore(db *gorm.DB) {
+	if !audited(db) {
+		return
+	}
+	where, hasWhere := whereClause(db)
+	ids := modelIDs(db)
+	if len(ids) == 0 && !hasWhere {
+		return // GORM refuses global updates and deletes
+	}
+	rows, err := readRows(db, ids, where)
+	if err != nil {
+		_ = db.AddError(fmt.Errorf("audit: %w", err))
+		return
+	}
+	db.InstanceSet(beforeKey, rows)
+}
+
+func afterUpdate(db *gorm.DB) {
+	before, ok := capturedRows(db)
+	if !ok || db.RowsAffected == 0 {
+		return
+	}
+	ids := make([]interface{}, 0, len(before))
+	for id := range before {
+		ids = append(ids, id)
+	}
+	after, err := readRows(db, ids, clause.Where{})
+	if err != nil {
+		_ = db.AddError(fmt.Errorf("audit: %w", err))
+		return
+	}
+	var entries []models.AuditLog
+	for _, id := range sortedIDs(before) {
+		if changes := diff(before[id], after[id]); len(changes) > 0 {
+			entries = appendEntry(entries, db, updateAction(before[id], after[id]), id, changes)
+		}
+	}
+	write(db, entries)
+}
+
+func afterDelete(db *gorm.DB) {
+	before, ok := capturedRows(db)
+	if !ok || db.RowsAffected == 0 {
+		return
+	}
+	var entries []models.AuditLog
+	for _, id := range sortedIDs(before) {
+		entries = appendEntry(entries, db, ActionDelete, id, diff(before[id], nil))
+	}
+	write(db, entries)
+}
+
+// updateAction returns the action of an update: moving a record to the trash
+// is recorded as a delete and taking it out again as a restore
+func updateAction(before, after map[string]interface{}) string {
+	if _, ok := before[trashColumn]; !ok {
+		return ActionUpdate
+	}
+	_, wasTrashed := normalize(before[trashColumn])
+	_, isTrashed := normalize(after[trashColumn])
+	switch {
+	case isTrashed && !wasTrashed:
+		return ActionDelete
+	case wasTrashed && !isTrashed:
+		return ActionRestore
+	}
+	return ActionUpdate
+}
+
+func capturedRows(db *gorm.DB) (map[uint]map[string]interface{}, bool) {
+	if !audited(db) {
+		return nil, false
+	}
+	value, ok := db.InstanceGet(beforeKey)
+	if !ok {
+		return nil, false
+	}
+	rows, ok := value.(map[uint]map[string]interface{})
+	return rows, ok && len(rows) > 0
+}
+
+// modelIDs returns the primary keys of the statement's model, which may be a
+// struct or a slice of structs; zero keys are left out
+func modelIDs(db *gorm.DB) []interface{} {
+	stmt := db.Statement
+	field := stmt.Schema.PrioritizedPrimaryField
+	value := stmt.ReflectValue
+	var ids []interface{}
+	add := func(v reflect.Value) {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct || v.Type() != stmt.Schema.ModelType {
+			return
+		}
+		if id, zero := field.ValueOf(stmt.Context, v); !zero {
+			ids = append(ids, id)
+		}
+	}
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			add(value.Index(i))
+		}
+	default:
+		add(value)
+	}
+	return ids
+}
+
+// whereClause returns the conditions of the statement
+func whereClause(db *gorm.DB) (clause.Where, bool) {
+	c, ok := db.Statement.Clauses["WHERE"]
+	if !ok {
+		return clause.Where{}, false
+	}
+	where, ok := c.Expression.(clause.Where)
+	return where, ok && len(where.Exprs) > 0
+}
+
+// readRows reads the rows of the statement's table with the given primary
+// keys and conditions, keyed by primary key. It runs on the statement's
+// connection, so it sees the statement's transaction.
+func readRows(db *gorm.DB, ids []interface{}, where clause.Where) (map[uint]map[string]interface{}, error) {
+	stmt := db.Statement
+	pk := stmt.Schema.PrioritizedPrimaryField.DBName
+	model := reflect.New(stmt.Schema.ModelType).Interface()
+	query := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Unscoped().Model(model).Table(stmt.Table)
+	if len(where.Exprs) > 0 {
+		query = query.Clauses(where)
+	}
+	if len(ids) > 0 {
+		query = query.Where(clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: pk}, Values: ids})
+	}
+	var found []map[string]interface{}
+	if err := query.Find(&found).Error; err != nil {
+		return nil, err
+	}
+	rows := make(map[uint]map[string]interface{}, len(found))
+	for _, row := range found {
+		if id, ok := toUint(row[pk]); ok {
+			rows[id] = row
+		}
+	}
+	return rows, nil
+}
+
+// diff compares two rows column by column. before is nil for creates and
+// after is nil for deletes.
+func diff(before, after map[string]interface{}) map[string]Change {
+	changes := map[string]Change{}
+	columns := map[string]bool{}
+	for column := range before {
+		columns[column] = true
+	}
+	for column := range after {
+		columns[column] = true
+	}
+	for column := range columns {
+		if ignoredColumns[column] {
+			continue
+		}
+		old, hasOld := normalize(before[column])
+		current, hasNew := normalize(after[column])
+		if before != nil && after != nil && equal(old, current) {
+			continue
+		}
+		if before == nil && !hasNew || after == nil && !hasOld {
+			continue // Leave empty columns out of creates and deletes
+		}
+		if redactedColumns[column] {
+			old, current = redactValue(old), redactValue(current)
+		}
+		changes[column] = Change{Old: old, New: current}
+	}
+	return changes
+}
+
+// normalize turns driver values into JSON-friendly ones; the second result
+// is false for NULL
+func normalize(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, false
+	case []byte:
+		return string(v), true
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano), true
+	}
+	return value, true
+}
+
+func equal(a, b interface{}) bool {
+	aj, _ := json.Marshal(a)
+	bj, _ := json.Marshal(b)
+	return string(aj) == string(bj)
+}
+
+func redactValue(value interface{}) interface{} {
+	if value == nil || value == "" {
+		return value
+	}
+	return redacted
+}
+
+func appendEntry(entries []models.AuditLog, db *gorm.DB, action string, id uint, changes map[string]Change) []models.AuditLog {
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		_ = db.AddError(fmt.Errorf("audit: %w", err))
+		return entries
+	}
+	return append(entries, models.AuditLog{
+		Entity:   EntityName(db.Statement.Schema.Name),
+		EntityID: id,
+		Action:   action,
+		Actor:    models.ActorFrom(db.Statement.Context),
+		Source:   models.SourceFrom(db.Statement.Context),
+		Changes:  string(encoded),
+	})
+}
+
+// write stores entries on the statement's connection
+func write(db *gorm.DB, entries []models.AuditLog) {
+	if len(entries) == 0 {
+		return
+	}
+	if err := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Create(&entries).Error; err != nil {
+		_ = db.AddError(fmt.Errorf("audit: %w", err))
+	}
+}
+
+// EntityName converts a model name to the entity name used in the audit log:
+// PurchaseOrder becomes purchase_order and APIToken becomes api_token
+func EntityName(model string) string {
+	runes := []rune(model)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			startsWord := i > 0 && (unicode.IsLower(runes[i-1]) ||
+				i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))
+			if startsWord {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func toUint(value interface{}) (uint, bool) {
+	switch v := value.(type) {
+	case int64:
+		return uint(v), v >= 0
+	case int32:
+		return uint(v), v >= 0
+	case int:
+		return uint(v), v >= 0
+	case uint:
+		return v, true
+	case uint64:
+		return uint(v), true
+	case uint32:
+		return uint(v), true
+	}
+	return 0, false
+}
+
+func sortedIDs(rows map[uint]map[string]interface{}) []uint {
+	ids := make([]uint, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
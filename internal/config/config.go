@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
@@ -30,6 +31,40 @@ type Config struct {
 	WebPort      int
 	LogLevel     logger.LogLevel
 	DB           *gorm.DB
+	StorageConfig
+}
+
+// StorageConfig selects where document files are kept
+type StorageConfig struct {
+	StorageType      string // local or minio
+	StorageLocalPath string
+	MinioEndpoint    string // host:port
+	MinioAccessKey   string
+	MinioSecretKey   string
+	MinioUseSSL      bool
+	MinioRegion      string
+}
+
+// StorageFromEnv reads the document storage settings: DOCUMENT_STORAGE_TYPE
+// (default local), DOCUMENT_STORAGE_LOCAL_PATH (default ~/.buyer/documents)
+// and, for minio, MINIO_ENDPOINT (default localhost:9000), MINIO_ACCESS_KEY,
+// MINIO_SECRET_KEY, MINIO_USE_SSL and MINIO_REGION
+func StorageFromEnv() StorageConfig {
+	storage := StorageConfig{
+		StorageType:      strings.ToLower(getEnvString("DOCUMENT_STORAGE_TYPE", "local")),
+		StorageLocalPath: os.Getenv("DOCUMENT_STORAGE_LOCAL_PATH"),
+		MinioEndpoint:    getEnvString("MINIO_ENDPOINT", "localhost:9000"),
+		MinioAccessKey:   os.Getenv("MINIO_ACCESS_KEY"),
+		MinioSecretKey:   os.Getenv("MINIO_SECRET_KEY"),
+		MinioUseSSL:      os.Getenv("MINIO_USE_SSL") == "true",
+		MinioRegion:      os.Getenv("MINIO_REGION"),
+	}
+	if storage.StorageLocalPath == "" {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			storage.StorageLocalPath = filepath.Join(homeDir, ".buyer", "documents")
+		}
+	}
+	return storage
 }
 
 // NewConfig creates a new configuration based on environment
@@ -40,6 +75,7 @@ func NewConfig(env Environment, verbose bool) (*Config, error) {
 
 	// Set web port from environment variable or default
 	config.WebPort = getEnvInt("BUYER_WEB_PORT", 8080)
+	config.StorageConfig = StorageFromEnv()
 
 	// Set database path/URL based on environment
 	switch env {
@@ -103,38 +139,103 @@ func NewConfig(env Environment, verbose bool) (*Config, error) {
 	return config, nil
 }
 
+// Load creates the configuration of the environment named by BUYER_ENV
+func Load() (*Config, error) {
+	return NewConfig(GetEnv(), false)
+}
+
 // InitDB initializes the database connection
 func (c *Config) InitDB() error {
 	var db *gorm.DB
 	var err error
 
 	if c.DatabaseURL != "" {
-		// Use PostgreSQL
-		db, err = gorm.Open(postgres.Open(c.DatabaseURL), &gorm.Config{
-			Logger: logger.Default.LogMode(c.LogLevel),
-		})
+		db, err = OpenDatabase("postgres", c.DatabaseURL, c.LogLevel)
+	} else if c.DatabasePath != "" {
+		db, err = OpenDatabase("sqlite", c.DatabasePath, c.LogLevel)
+	} else {
+		return fmt.Errorf("no database configuration provided")
+	}
+	if err != nil {
+		return err
+	}
+
+	c.DB = db
+	return nil
+}
+
+// OpenDatabase opens a SQLite database file or a PostgreSQL connection string.
+// Foreign key constraints are enabled for SQLite. SQLite transactions take the
+// write lock when they begin and wait up to five seconds for it, so concurrent
+// writers, such as two web requests numbering purchase orders, queue instead of
+// failing with "database is locked".
+func OpenDatabase(driver, dsn string, logLevel logger.LogLevel) (*gorm.DB, error) {
+	gormConfig := &gorm.Config{
+		Logger: logger.Default.LogMode(logLevel),
+	}
+
+	switch driver {
+	case "postgres":
+		db, err := gorm.Open(postgres.Open(dsn), gormConfig)
 		if err != nil {
-			return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+			return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 		}
-	} else if c.DatabasePath != "" {
-		// Use SQLite
-		db, err = gorm.Open(sqlite.Open(c.DatabasePath), &gorm.Config{
-			Logger: logger.Default.LogMode(c.LogLevel),
-		})
+		return db, nil
+	case "sqlite":
+		db, err := gorm.Open(sqlite.Open(sqliteDSN(dsn)), gormConfig)
 		if err != nil {
-			return fmt.Errorf("failed to connect to SQLite: %w", err)
+			return nil, fmt.Errorf("failed to connect to SQLite: %w", err)
 		}
 
 		// Enable foreign key constraints for SQLite
 		if err := db.Exec("PRAGMA foreign_keys = ON").Error; err != nil {
-			return fmt.Errorf("failed to enable foreign key constraints: %w", err)
+			return nil, fmt.Errorf("failed to enable foreign key constraints: %w", err)
 		}
-	} else {
-		return fmt.Errorf("no database configuration provided")
+		return db, nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", driver)
 	}
+}
 
-	c.DB = db
-	return nil
+// sqliteDSN adds the busy timeout and immediate transactions to a SQLite
+// path, keeping any options it already has
+func sqliteDSN(dsn string) string {
+	options := []string{"_busy_timeout=5000", "_txlock=immediate"}
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	for _, option := range options {
+		name, _, _ := strings.Cut(option, "=")
+		if strings.Contains(dsn, name+"=") {
+			continue
+		}
+		dsn += separator + option
+		separator = "&"
+	}
+	return dsn
+}
+
+// ParseDatabaseSpec splits a database reference of the form sqlite:PATH or
+// postgres:URL into a driver and a connection string. Bare postgres:// and
+// postgresql:// URLs are also accepted.
+func ParseDatabaseSpec(spec string) (driver, dsn string, err error) {
+	if strings.HasPrefix(spec, "postgres://") || strings.HasPrefix(spec, "postgresql://") {
+		return "postgres", spec, nil
+	}
+
+	driver, dsn, ok := strings.Cut(spec, ":")
+	if !ok || dsn == "" {
+		return "", "", fmt.Errorf("invalid database %q (expected sqlite:PATH or postgres:URL)", spec)
+	}
+	switch driver {
+	case "sqlite", "sqlite3":
+		return "sqlite", dsn, nil
+	case "postgres", "postgresql", "pg":
+		return "postgres", dsn, nil
+	default:
+		return "", "", fmt.Errorf("unsupported database driver %q (expected sqlite or postgres)", driver)
+	}
 }
 
 // Close closes the database connection
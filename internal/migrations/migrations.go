@@ -0,0 +1,347 @@
+// Package migrations manages the database schema with numbered up/down SQL
+// migrations for SQLite and PostgreSQL. Applied versions are recorded in the
+// schema_migrations table.
+//
+// Migrations live in sqlite/ and postgres/ as NNNN_name.up.sql and
+// NNNN_name.down.sql. Every version must exist for both dialects, and the
+// result must match the models in internal/models (see migrations_test.go).
+// SQLite scripts may use ALTER TABLE ... ADD COLUMN IF NOT EXISTS, which
+// SQLite itself lacks; see execScript.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sqlite/*.sql postgres/*.sql
+var files embed.FS
+
+// ErrOutOfDate is returned by EnsureCurrent when the database needs migrating
+var ErrOutOfDate = errors.New("database schema is out of date")
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status reports whether a migration has been applied
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// schemaMigration is a row of the schema_migrations table
+type schemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// Migrator applies and rolls back migrations on one database
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for the database's dialect
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := load(dialect(db))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// LatestVersion returns the newest migration version, which is the schema
+// version this build of buyer expects
+func LatestVersion() int {
+	migrations, err := load("sqlite")
+	if err != nil || len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Migrations returns every migration in version order
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Latest returns the newest migration version
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Current returns the highest applied version, or 0 if none has been applied
+func (m *Migrator) Current() (int, error) {
+	if !m.db.Migrator().HasTable(&schemaMigration{}) {
+		return 0, nil
+	}
+	var version int
+	err := m.db.Model(&schemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// Status lists every migration with the time it was applied, if it was
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction,
+// and returns the migrations that were applied
+func (m *Migrator) Up() ([]Migration, error) {
+	if err := m.db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := execScript(tx, migration.Up); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Rollback reverts the last steps applied migrations, newest first, and returns
+// the migrations that were rolled back
+func (m *Migrator) Rollback(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := execScript(tx, migration.Down); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("rollback of %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// EnsureCurrent checks that the schema is up to date before the application uses
+// the database. A database without any tables is initialized by applying every
+// migration; any other database that is behind returns an error wrapping
+// ErrOutOfDate rather than being migrated implicitly.
+func (m *Migrator) EnsureCurrent() (initialized bool, err error) {
+	current, err := m.Current()
+	if err != nil {
+		return false, err
+	}
+	latest := m.Latest()
+
+	switch {
+	case current == latest:
+		return false, nil
+	case current > latest:
+		return false, fmt.Errorf("database schema version %d is newer than this version of buyer supports (%d)", current, latest)
+	}
+
+	tables, err := m.db.Migrator().GetTables()
+	if err != nil {
+		return false, err
+	}
+	empty := true
+	for _, table := range tables {
+		if table != (schemaMigration{}).TableName() && !strings.HasPrefix(table, "sqlite_") {
+			empty = false
+			break
+		}
+	}
+	if !empty {
+		return false, fmt.Errorf("%w: version %d, buyer requires %d; run 'buyer db migrate' after backing up the database", ErrOutOfDate, current, latest)
+	}
+
+	if _, err := m.Up(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// applied returns the applied migrations by version
+func (m *Migrator) applied() (map[int]schemaMigration, error) {
+	applied := make(map[int]schemaMigration)
+	if !m.db.Migrator().HasTable(&schemaMigration{}) {
+		return applied, nil
+	}
+	var rows []schemaMigration
+	if err := m.db.Order("version ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// dialect maps a GORM dialector to a migrations directory
+func dialect(db *gorm.DB) string {
+	if db.Dialector.Name() == "postgres" {
+		return "postgres"
+	}
+	return "sqlite"
+}
+
+// load reads the migrations for a dialect, checking that every version has
+// both an up and a down script
+func load(dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %s", name)
+		}
+		number, label, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %s", name)
+		}
+
+		data, err := files.ReadFile(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: label}
+			byVersion[version] = migration
+		} else if migration.Name != label {
+			return nil, fmt.Errorf("migration %04d has two names: %s and %s", version, migration.Name, label)
+		}
+		if direction == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both up and down scripts", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// execScript runs each statement of a migration script. Statements end with a
+// semicolon at the end of a line; lines starting with -- are comments.
+func execScript(tx *gorm.DB, script string) error {
+	exec := func(stmt string) error {
+		if dialect(tx) == "sqlite" {
+			if table, column, rest, ok := addColumnIfNotExists(stmt); ok {
+				if tx.Migrator().HasColumn(table, column) {
+					return nil
+				}
+				stmt = fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `%s`%s", table, column, rest)
+			}
+		}
+		return tx.Exec(stmt).Error
+	}
+
+	var stmt strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		stmt.WriteString(line)
+		stmt.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			if err := exec(stmt.String()); err != nil {
+				return err
+			}
+			stmt.Reset()
+		}
+	}
+	if strings.TrimSpace(stmt.String()) != "" {
+		return exec(stmt.String())
+	}
+	return nil
+}
+
+var addColumnPattern = regexp.MustCompile("(?is)^\\s*ALTER\\s+TABLE\\s+[`\"]?(\\w+)[`\"]?\\s+ADD\\s+COLUMN\\s+IF\\s+NOT\\s+EXISTS\\s+[`\"]?(\\w+)[`\"]?(.*)$")
+
+// addColumnIfNotExists parses "ALTER TABLE t ADD COLUMN IF NOT EXISTS c ...",
+// which SQLite does not support, so that execScript can skip columns that
+// already exist, such as those of a database created by AutoMigrate
+func addColumnIfNotExists(stmt string) (table, column, rest string, ok bool) {
+	m := addColumnPattern.FindStringSubmatch(stmt)
+	if m == nil {
+		return "", "", "", false
+	}
+	return m[1], m[2], m[3], true
+}
@@ -0,0 +1,193 @@
+package migrations
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.Exec("PRAGMA foreign_keys = ON").Error; err != nil {
+		t.Fatalf("Failed to enable foreign keys: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestLoad_DialectsMatch(t *testing.T) {
+	sqliteMigrations, err := load("sqlite")
+	if err != nil {
+		t.Fatalf("Failed to load sqlite migrations: %v", err)
+	}
+	postgresMigrations, err := load("postgres")
+	if err != nil {
+		t.Fatalf("Failed to load postgres migrations: %v", err)
+	}
+	if len(sqliteMigrations) != len(postgresMigrations) {
+		t.Fatalf("Expected the same number of migrations, got %d sqlite and %d postgres", len(sqliteMigrations), len(postgresMigrations))
+	}
+	for i := range sqliteMigrations {
+		s, p := sqliteMigrations[i], postgresMigrations[i]
+		if s.Version != p.Version || s.Name != p.Name {
+			t.Errorf("Migration %d differs: sqlite %04d_%s, postgres %04d_%s", i, s.Version, s.Name, p.Version, p.Name)
+		}
+		if s.Version != i+1 {
+			t.Errorf("Expected migration versions without gaps, got %04d at position %d", s.Version, i)
+		}
+	}
+	if LatestVersion() != len(sqliteMigrations) {
+		t.Errorf("Expected latest version %d, got %d", len(sqliteMigrations), LatestVersion())
+	}
+}
+
+// TestMigrator_MatchesModels checks that the migrations create every table and
+// column of the models, so that a model change without a migration fails here
+func TestMigrator_MatchesModels(t *testing.T) {
+	db := openTestDB(t)
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("Failed to create migrator: %v", err)
+	}
+	if _, err := m.Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	cache := &sync.Map{}
+	for _, model := range models.All() {
+		sch, err := schema.Parse(model, cache, db.NamingStrategy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !db.Migrator().HasTable(sch.Table) {
+			t.Errorf("Table %s is missing; add a migration for it", sch.Table)
+			continue
+		}
+		for _, field := range sch.Fields {
+			if field.DBName != "" && !db.Migrator().HasColumn(sch.Table, field.DBName) {
+				t.Errorf("Column %s.%s is missing; add a migration for it", sch.Table, field.DBName)
+			}
+		}
+		for _, rel := range sch.Relationships.Many2Many {
+			if !db.Migrator().HasTable(rel.JoinTable.Table) {
+				t.Errorf("Join table %s is missing; add a migration for it", rel.JoinTable.Table)
+			}
+		}
+	}
+}
+
+func TestMigrator_UpAndRollback(t *testing.T) {
+	db := openTestDB(t)
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("Failed to create migrator: %v", err)
+	}
+
+	applied, err := m.Up()
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(applied) != len(m.Migrations()) {
+		t.Errorf("Expected %d migrations to be applied, got %d", len(m.Migrations()), len(applied))
+	}
+	if current, _ := m.Current(); current != m.Latest() {
+		t.Errorf("Expected version %d, got %d", m.Latest(), current)
+	}
+	if again, err := m.Up(); err != nil || len(again) != 0 {
+		t.Errorf("Expected nothing to apply the second time, got %d (err: %v)", len(again), err)
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("Expected %04d_%s to be applied", status.Version, status.Name)
+		}
+	}
+
+	rolledBack, err := m.Rollback(len(m.Migrations()))
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if len(rolledBack) != len(m.Migrations()) || rolledBack[0].Version != m.Latest() {
+		t.Errorf("Expected every migration to be rolled back newest first, got %+v", rolledBack)
+	}
+	if current, _ := m.Current(); current != 0 {
+		t.Errorf("Expected version 0 after rollback, got %d", current)
+	}
+	if db.Migrator().HasTable("vendors") {
+		t.Error("Expected baseline tables to be dropped")
+	}
+
+	if _, err := m.Up(); err != nil {
+		t.Fatalf("Up after rollback failed: %v", err)
+	}
+}
+
+func TestMigrator_EnsureCurrent(t *testing.T) {
+	t.Run("empty database is initialized", func(t *testing.T) {
+		db := openTestDB(t)
+		m, _ := NewMigrator(db)
+		initialized, err := m.EnsureCurrent()
+		if err != nil || !initialized {
+			t.Fatalf("Expected empty database to be initialized, got %v (err: %v)", initialized, err)
+		}
+		if initialized, err := m.EnsureCurrent(); err != nil || initialized {
+			t.Errorf("Expected current database to be left alone, got %v (err: %v)", initialized, err)
+		}
+	})
+
+	t.Run("database created by AutoMigrate is refused, then adopted", func(t *testing.T) {
+		db := openTestDB(t)
+		if err := db.AutoMigrate(models.All()...); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Create(&models.Brand{Name: "Apple"}).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		m, _ := NewMigrator(db)
+		if _, err := m.EnsureCurrent(); !errors.Is(err, ErrOutOfDate) {
+			t.Fatalf("Expected ErrOutOfDate, got %v", err)
+		}
+		if _, err := m.Up(); err != nil {
+			t.Fatalf("Up on AutoMigrate database failed: %v", err)
+		}
+		if _, err := m.EnsureCurrent(); err != nil {
+			t.Errorf("Expected migrated database to be current: %v", err)
+		}
+		var count int64
+		db.Model(&models.Brand{}).Count(&count)
+		if count != 1 {
+			t.Errorf("Expected existing data to be kept, got %d brands", count)
+		}
+	})
+
+	t.Run("newer database is refused", func(t *testing.T) {
+		db := openTestDB(t)
+		m, _ := NewMigrator(db)
+		if _, err := m.Up(); err != nil {
+			t.Fatal(err)
+		}
+		db.Create(&schemaMigration{Version: m.Latest() + 1, Name: "future"})
+		if _, err := m.EnsureCurrent(); err == nil || errors.Is(err, ErrOutOfDate) {
+			t.Errorf("Expected newer schema error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,22 @@
+DROP TABLE IF EXISTS "vendor_brands";
+DROP TABLE IF EXISTS "import_profile_fields";
+DROP TABLE IF EXISTS "import_profiles";
+DROP TABLE IF EXISTS "documents";
+DROP TABLE IF EXISTS "project_procurement_strategies";
+DROP TABLE IF EXISTS "project_requisition_items";
+DROP TABLE IF EXISTS "project_requisitions";
+DROP TABLE IF EXISTS "bill_of_materials_items";
+DROP TABLE IF EXISTS "bills_of_materials";
+DROP TABLE IF EXISTS "projects";
+DROP TABLE IF EXISTS "forex";
+DROP TABLE IF EXISTS "vendor_ratings";
+DROP TABLE IF EXISTS "purchase_orders";
+DROP TABLE IF EXISTS "quotes";
+DROP TABLE IF EXISTS "requisition_items";
+DROP TABLE IF EXISTS "requisitions";
+DROP TABLE IF EXISTS "product_attributes";
+DROP TABLE IF EXISTS "products";
+DROP TABLE IF EXISTS "specification_attributes";
+DROP TABLE IF EXISTS "specifications";
+DROP TABLE IF EXISTS "brands";
+DROP TABLE IF EXISTS "vendors";
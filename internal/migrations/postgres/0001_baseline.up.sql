@@ -0,0 +1,367 @@
+-- Baseline schema: the tables created by AutoMigrate before versioned migrations.
+-- IF NOT EXISTS lets databases created by AutoMigrate adopt this version.
+
+CREATE TABLE IF NOT EXISTS "vendors" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "currency" varchar(3) NOT NULL,
+    "discount_code" varchar(50),
+    "contact_person" varchar(100),
+    "email" varchar(255),
+    "phone" varchar(50),
+    "website" varchar(255),
+    "address_line1" varchar(255),
+    "address_line2" varchar(255),
+    "city" varchar(100),
+    "state" varchar(100),
+    "postal_code" varchar(20),
+    "country" varchar(2),
+    "tax_id" varchar(50),
+    "payment_terms" varchar(100),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_vendors_name" ON "vendors" ("name");
+
+CREATE TABLE IF NOT EXISTS "brands" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_brands_name" ON "brands" ("name");
+
+CREATE TABLE IF NOT EXISTS "specifications" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "description" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_specifications_name" ON "specifications" ("name");
+
+CREATE TABLE IF NOT EXISTS "specification_attributes" (
+    "id" bigserial,
+    "specification_id" bigint NOT NULL,
+    "name" varchar(100) NOT NULL,
+    "data_type" varchar(20) NOT NULL DEFAULT 'text',
+    "unit" varchar(50),
+    "is_required" boolean DEFAULT false,
+    "min_value" decimal,
+    "max_value" decimal,
+    "description" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_specifications_attributes" FOREIGN KEY ("specification_id") REFERENCES "specifications"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_spec_attr" ON "specification_attributes" ("specification_id","name");
+
+CREATE TABLE IF NOT EXISTS "products" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "sku" varchar(100),
+    "description" text,
+    "brand_id" bigint NOT NULL,
+    "specification_id" bigint,
+    "unit_of_measure" varchar(20) DEFAULT 'each',
+    "min_order_qty" bigint,
+    "lead_time_days" bigint,
+    "is_active" boolean DEFAULT true,
+    "discontinued_at" timestamptz,
+    "created_by" varchar(100),
+    "updated_by" varchar(100),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_specifications_products" FOREIGN KEY ("specification_id") REFERENCES "specifications"("id") ON DELETE SET NULL,
+    CONSTRAINT "fk_brands_products" FOREIGN KEY ("brand_id") REFERENCES "brands"("id") ON DELETE RESTRICT
+);
+CREATE INDEX IF NOT EXISTS "idx_products_specification_id" ON "products" ("specification_id");
+CREATE INDEX IF NOT EXISTS "idx_products_brand_id" ON "products" ("brand_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_products_sku" ON "products" ("sku");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_products_name" ON "products" ("name");
+
+CREATE TABLE IF NOT EXISTS "product_attributes" (
+    "id" bigserial,
+    "product_id" bigint NOT NULL,
+    "specification_attribute_id" bigint NOT NULL,
+    "value_text" text,
+    "value_number" decimal,
+    "value_boolean" boolean,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_product_attributes_specification_attribute" FOREIGN KEY ("specification_attribute_id") REFERENCES "specification_attributes"("id") ON DELETE RESTRICT,
+    CONSTRAINT "fk_products_attributes" FOREIGN KEY ("product_id") REFERENCES "products"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_prod_attr" ON "product_attributes" ("product_id","specification_attribute_id");
+
+CREATE TABLE IF NOT EXISTS "requisitions" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "justification" text,
+    "budget" decimal,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_requisitions_name" ON "requisitions" ("name");
+
+CREATE TABLE IF NOT EXISTS "requisition_items" (
+    "id" bigserial,
+    "requisition_id" bigint NOT NULL,
+    "specification_id" bigint NOT NULL,
+    "quantity" bigint NOT NULL,
+    "budget_per_unit" decimal,
+    "description" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_requisitions_items" FOREIGN KEY ("requisition_id") REFERENCES "requisitions"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_requisition_items_specification" FOREIGN KEY ("specification_id") REFERENCES "specifications"("id") ON DELETE RESTRICT
+);
+CREATE INDEX IF NOT EXISTS "idx_requisition_items_specification_id" ON "requisition_items" ("specification_id");
+CREATE INDEX IF NOT EXISTS "idx_requisition_items_requisition_id" ON "requisition_items" ("requisition_id");
+
+CREATE TABLE IF NOT EXISTS "quotes" (
+    "id" bigserial,
+    "vendor_id" bigint NOT NULL,
+    "product_id" bigint NOT NULL,
+    "version" bigint NOT NULL DEFAULT 1,
+    "previous_quote_id" bigint,
+    "replaced_by" bigint,
+    "price" decimal NOT NULL,
+    "currency" varchar(3) NOT NULL,
+    "converted_price" decimal NOT NULL,
+    "conversion_rate" decimal NOT NULL,
+    "min_quantity" bigint,
+    "quote_date" timestamptz NOT NULL,
+    "valid_until" timestamptz,
+    "status" varchar(20) DEFAULT 'active',
+    "notes" text,
+    "created_by" varchar(100),
+    "updated_by" varchar(100),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_products_quotes" FOREIGN KEY ("product_id") REFERENCES "products"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_vendors_quotes" FOREIGN KEY ("vendor_id") REFERENCES "vendors"("id") ON DELETE RESTRICT
+);
+CREATE INDEX IF NOT EXISTS "idx_quotes_valid_until" ON "quotes" ("valid_until");
+CREATE INDEX IF NOT EXISTS "idx_quotes_quote_date" ON "quotes" ("quote_date");
+CREATE INDEX IF NOT EXISTS "idx_quotes_replaced_by" ON "quotes" ("replaced_by");
+CREATE INDEX IF NOT EXISTS "idx_quotes_previous_quote_id" ON "quotes" ("previous_quote_id");
+CREATE INDEX IF NOT EXISTS "idx_quotes_product_id" ON "quotes" ("product_id");
+CREATE INDEX IF NOT EXISTS "idx_quotes_vendor_id" ON "quotes" ("vendor_id");
+
+CREATE TABLE IF NOT EXISTS "purchase_orders" (
+    "id" bigserial,
+    "quote_id" bigint NOT NULL,
+    "vendor_id" bigint NOT NULL,
+    "product_id" bigint NOT NULL,
+    "requisition_id" bigint,
+    "po_number" varchar(50) NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'pending',
+    "order_date" timestamptz NOT NULL,
+    "expected_delivery" timestamptz,
+    "actual_delivery" timestamptz,
+    "quantity" bigint NOT NULL,
+    "unit_price" decimal NOT NULL,
+    "currency" varchar(3) NOT NULL,
+    "total_amount" decimal NOT NULL,
+    "shipping_cost" decimal,
+    "tax" decimal,
+    "grand_total" decimal NOT NULL,
+    "invoice_number" varchar(100),
+    "notes" text,
+    "created_by" varchar(100),
+    "updated_by" varchar(100),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_purchase_orders_product" FOREIGN KEY ("product_id") REFERENCES "products"("id") ON DELETE RESTRICT,
+    CONSTRAINT "fk_requisitions_purchase_orders" FOREIGN KEY ("requisition_id") REFERENCES "requisitions"("id") ON DELETE SET NULL,
+    CONSTRAINT "fk_quotes_purchase_orders" FOREIGN KEY ("quote_id") REFERENCES "quotes"("id") ON DELETE RESTRICT,
+    CONSTRAINT "fk_vendors_purchase_orders" FOREIGN KEY ("vendor_id") REFERENCES "vendors"("id") ON DELETE RESTRICT
+);
+CREATE INDEX IF NOT EXISTS "idx_purchase_orders_order_date" ON "purchase_orders" ("order_date");
+CREATE INDEX IF NOT EXISTS "idx_purchase_orders_status" ON "purchase_orders" ("status");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_purchase_orders_po_number" ON "purchase_orders" ("po_number");
+CREATE INDEX IF NOT EXISTS "idx_purchase_orders_requisition_id" ON "purchase_orders" ("requisition_id");
+CREATE INDEX IF NOT EXISTS "idx_purchase_orders_product_id" ON "purchase_orders" ("product_id");
+CREATE INDEX IF NOT EXISTS "idx_purchase_orders_vendor_id" ON "purchase_orders" ("vendor_id");
+CREATE INDEX IF NOT EXISTS "idx_purchase_orders_quote_id" ON "purchase_orders" ("quote_id");
+
+CREATE TABLE IF NOT EXISTS "vendor_ratings" (
+    "id" bigserial,
+    "vendor_id" bigint NOT NULL,
+    "purchase_order_id" bigint,
+    "price_rating" bigint,
+    "quality_rating" bigint,
+    "delivery_rating" bigint,
+    "service_rating" bigint,
+    "comments" text,
+    "rated_by" varchar(100),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_purchase_orders_vendor_ratings" FOREIGN KEY ("purchase_order_id") REFERENCES "purchase_orders"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_vendors_vendor_ratings" FOREIGN KEY ("vendor_id") REFERENCES "vendors"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_vendor_ratings_purchase_order_id" ON "vendor_ratings" ("purchase_order_id");
+CREATE INDEX IF NOT EXISTS "idx_vendor_ratings_vendor_id" ON "vendor_ratings" ("vendor_id");
+
+CREATE TABLE IF NOT EXISTS "forex" (
+    "id" bigserial,
+    "from_currency" varchar(3) NOT NULL,
+    "to_currency" varchar(3) NOT NULL,
+    "rate" decimal NOT NULL,
+    "effective_date" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_forex_effective_date" ON "forex" ("effective_date");
+CREATE INDEX IF NOT EXISTS "idx_forex_pair" ON "forex" ("from_currency","to_currency");
+
+CREATE TABLE IF NOT EXISTS "projects" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "description" text,
+    "budget" decimal,
+    "deadline" timestamptz,
+    "status" varchar(20) DEFAULT 'planning',
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_projects_name" ON "projects" ("name");
+
+CREATE TABLE IF NOT EXISTS "bills_of_materials" (
+    "id" bigserial,
+    "project_id" bigint NOT NULL,
+    "notes" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_projects_bill_of_materials" FOREIGN KEY ("project_id") REFERENCES "projects"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_bills_of_materials_project_id" ON "bills_of_materials" ("project_id");
+
+CREATE TABLE IF NOT EXISTS "bill_of_materials_items" (
+    "id" bigserial,
+    "bill_of_materials_id" bigint NOT NULL,
+    "specification_id" bigint NOT NULL,
+    "quantity" bigint NOT NULL,
+    "notes" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_bill_of_materials_items_specification" FOREIGN KEY ("specification_id") REFERENCES "specifications"("id") ON DELETE RESTRICT,
+    CONSTRAINT "fk_bills_of_materials_items" FOREIGN KEY ("bill_of_materials_id") REFERENCES "bills_of_materials"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_bom_spec_unique" ON "bill_of_materials_items" ("specification_id");
+CREATE INDEX IF NOT EXISTS "idx_bom_spec" ON "bill_of_materials_items" ("bill_of_materials_id","specification_id");
+
+CREATE TABLE IF NOT EXISTS "project_requisitions" (
+    "id" bigserial,
+    "project_id" bigint NOT NULL,
+    "name" text NOT NULL,
+    "justification" text,
+    "budget" decimal,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_projects_requisitions" FOREIGN KEY ("project_id") REFERENCES "projects"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_project_requisitions_project_id" ON "project_requisitions" ("project_id");
+
+CREATE TABLE IF NOT EXISTS "project_requisition_items" (
+    "id" bigserial,
+    "project_requisition_id" bigint NOT NULL,
+    "bill_of_materials_item_id" bigint NOT NULL,
+    "quantity_requested" bigint NOT NULL,
+    "selected_quote_id" bigint,
+    "target_unit_price" decimal,
+    "actual_unit_price" decimal,
+    "procurement_status" varchar(20) DEFAULT 'pending',
+    "notes" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_project_requisition_items_bom_item" FOREIGN KEY ("bill_of_materials_item_id") REFERENCES "bill_of_materials_items"("id") ON DELETE RESTRICT,
+    CONSTRAINT "fk_project_requisition_items_selected_quote" FOREIGN KEY ("selected_quote_id") REFERENCES "quotes"("id") ON DELETE SET NULL,
+    CONSTRAINT "fk_project_requisitions_items" FOREIGN KEY ("project_requisition_id") REFERENCES "project_requisitions"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_project_requisition_items_selected_quote_id" ON "project_requisition_items" ("selected_quote_id");
+CREATE INDEX IF NOT EXISTS "idx_project_requisition_items_bill_of_materials_item_id" ON "project_requisition_items" ("bill_of_materials_item_id");
+CREATE INDEX IF NOT EXISTS "idx_project_requisition_items_project_requisition_id" ON "project_requisition_items" ("project_requisition_id");
+
+CREATE TABLE IF NOT EXISTS "project_procurement_strategies" (
+    "id" bigserial,
+    "project_id" bigint NOT NULL,
+    "strategy" varchar(30) DEFAULT 'lowest_cost',
+    "max_vendors" bigint,
+    "min_vendor_rating" decimal,
+    "preferred_vendor_ids" text,
+    "excluded_vendor_ids" text,
+    "allow_partial_fulfill" boolean DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_project_procurement_strategies_project" FOREIGN KEY ("project_id") REFERENCES "projects"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_project_procurement_strategies_project_id" ON "project_procurement_strategies" ("project_id");
+
+CREATE TABLE IF NOT EXISTS "documents" (
+    "id" bigserial,
+    "entity_type" varchar(50) NOT NULL,
+    "entity_id" bigint NOT NULL,
+    "file_name" text NOT NULL,
+    "file_type" varchar(50),
+    "file_size" bigint,
+    "file_path" text NOT NULL,
+    "description" text,
+    "uploaded_by" varchar(100),
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_entity" ON "documents" ("entity_type","entity_id");
+
+CREATE TABLE IF NOT EXISTS "import_profiles" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "entity" varchar(20) NOT NULL,
+    "vendor_id" bigint,
+    "sheet" varchar(100),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_import_profiles_vendor" FOREIGN KEY ("vendor_id") REFERENCES "vendors"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_import_profiles_vendor_id" ON "import_profiles" ("vendor_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_import_profiles_name" ON "import_profiles" ("name");
+
+CREATE TABLE IF NOT EXISTS "import_profile_fields" (
+    "id" bigserial,
+    "profile_id" bigint NOT NULL,
+    "field" varchar(50) NOT NULL,
+    "column" varchar(100),
+    "constant" varchar(255),
+    "transform" varchar(255),
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_import_profiles_fields" FOREIGN KEY ("profile_id") REFERENCES "import_profiles"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_import_profile_fields_profile_id" ON "import_profile_fields" ("profile_id");
+
+CREATE TABLE IF NOT EXISTS "vendor_brands" (
+    "vendor_id" bigint,
+    "brand_id" bigint,
+    PRIMARY KEY ("vendor_id","brand_id"),
+    CONSTRAINT "fk_vendor_brands_vendor" FOREIGN KEY ("vendor_id") REFERENCES "vendors"("id"),
+    CONSTRAINT "fk_vendor_brands_brand" FOREIGN KEY ("brand_id") REFERENCES "brands"("id")
+);
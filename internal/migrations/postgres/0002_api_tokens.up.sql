@@ -0,0 +1,18 @@
+-- Personal access tokens and service account tokens for the web server.
+
+CREATE TABLE IF NOT EXISTS "api_tokens" (
+    "id" bigserial,
+    "name" varchar(100) NOT NULL,
+    "owner" varchar(100) NOT NULL,
+    "service_account" boolean NOT NULL DEFAULT false,
+    "prefix" varchar(16) NOT NULL,
+    "token_hash" varchar(64) NOT NULL,
+    "scopes" varchar(255) NOT NULL,
+    "expires_at" timestamptz,
+    "revoked_at" timestamptz,
+    "last_used_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_api_tokens_token_hash" ON "api_tokens" ("token_hash");
+CREATE INDEX IF NOT EXISTS "idx_api_tokens_owner" ON "api_tokens" ("owner");
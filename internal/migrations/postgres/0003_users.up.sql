@@ -0,0 +1,29 @@
+-- User accounts with roles, and their browser sessions.
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" bigserial,
+    "username" varchar(100) NOT NULL,
+    "name" varchar(200),
+    "email" varchar(255),
+    "role" varchar(20) NOT NULL,
+    "password_hash" varchar(100),
+    "disabled" boolean NOT NULL DEFAULT false,
+    "last_login_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_username" ON "users" ("username");
+
+CREATE TABLE IF NOT EXISTS "sessions" (
+    "id" bigserial,
+    "token_hash" varchar(64) NOT NULL,
+    "user_id" bigint NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "last_seen_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_sessions_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_sessions_user_id" ON "sessions" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_sessions_token_hash" ON "sessions" ("token_hash");
@@ -0,0 +1,14 @@
+-- Links between users and their accounts at an OpenID Connect provider.
+
+CREATE TABLE IF NOT EXISTS "user_identities" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "issuer" varchar(255) NOT NULL,
+    "subject" varchar(255) NOT NULL,
+    "last_login_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_user_identities_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_identity_subject" ON "user_identities" ("issuer","subject");
+CREATE INDEX IF NOT EXISTS "idx_user_identities_user_id" ON "user_identities" ("user_id");
@@ -0,0 +1,15 @@
+-- Audit log of every create, update and delete.
+
+CREATE TABLE IF NOT EXISTS "audit_logs" (
+    "id" bigserial,
+    "entity" varchar(50) NOT NULL,
+    "entity_id" bigint NOT NULL,
+    "action" varchar(10) NOT NULL,
+    "actor" varchar(100),
+    "source" varchar(10),
+    "changes" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_created_at" ON "audit_logs" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_audit_entity" ON "audit_logs" ("entity","entity_id");
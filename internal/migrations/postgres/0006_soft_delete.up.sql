@@ -0,0 +1,20 @@
+-- Soft delete: records moved to the trash keep their row with deleted_at set.
+
+ALTER TABLE "vendors" ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_vendors_deleted_at" ON "vendors" ("deleted_at");
+ALTER TABLE "brands" ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_brands_deleted_at" ON "brands" ("deleted_at");
+ALTER TABLE "specifications" ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_specifications_deleted_at" ON "specifications" ("deleted_at");
+ALTER TABLE "products" ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_products_deleted_at" ON "products" ("deleted_at");
+ALTER TABLE "requisitions" ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_requisitions_deleted_at" ON "requisitions" ("deleted_at");
+ALTER TABLE "quotes" ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_quotes_deleted_at" ON "quotes" ("deleted_at");
+ALTER TABLE "purchase_orders" ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_purchase_orders_deleted_at" ON "purchase_orders" ("deleted_at");
+ALTER TABLE "vendor_ratings" ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_vendor_ratings_deleted_at" ON "vendor_ratings" ("deleted_at");
+ALTER TABLE "projects" ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_projects_deleted_at" ON "projects" ("deleted_at");
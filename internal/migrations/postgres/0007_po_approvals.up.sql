@@ -0,0 +1,34 @@
+-- Purchase order approval workflow: approval rules, the decisions taken on
+-- each step, and an optional project link on purchase orders.
+
+ALTER TABLE "purchase_orders" ADD COLUMN IF NOT EXISTS "project_id" bigint CONSTRAINT "fk_purchase_orders_project" REFERENCES "projects"("id") ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS "idx_purchase_orders_project_id" ON "purchase_orders" ("project_id");
+
+CREATE TABLE IF NOT EXISTS "approval_rules" (
+    "id" bigserial,
+    "step" bigint NOT NULL DEFAULT 1,
+    "min_amount" decimal NOT NULL DEFAULT 0,
+    "approvers" varchar(500),
+    "project_id" bigint,
+    "requisition_id" bigint,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_approval_rules_project" FOREIGN KEY ("project_id") REFERENCES "projects"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_approval_rules_requisition" FOREIGN KEY ("requisition_id") REFERENCES "requisitions"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_approval_rules_requisition_id" ON "approval_rules" ("requisition_id");
+CREATE INDEX IF NOT EXISTS "idx_approval_rules_project_id" ON "approval_rules" ("project_id");
+
+CREATE TABLE IF NOT EXISTS "purchase_order_approvals" (
+    "id" bigserial,
+    "purchase_order_id" bigint NOT NULL,
+    "step" bigint NOT NULL,
+    "approver" varchar(100) NOT NULL,
+    "decision" varchar(10) NOT NULL,
+    "comment" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_purchase_order_approvals_purchase_order" FOREIGN KEY ("purchase_order_id") REFERENCES "purchase_orders"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_purchase_order_approvals_purchase_order_id" ON "purchase_order_approvals" ("purchase_order_id");
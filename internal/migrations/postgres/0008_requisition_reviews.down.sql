@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS "notifications";
+DROP TABLE IF EXISTS "requisition_reviews";
+ALTER TABLE "project_requisitions" DROP COLUMN IF EXISTS "submitted_by";
+ALTER TABLE "project_requisitions" DROP COLUMN IF EXISTS "status";
+ALTER TABLE "requisitions" DROP COLUMN IF EXISTS "submitted_by";
+ALTER TABLE "requisitions" DROP COLUMN IF EXISTS "status";
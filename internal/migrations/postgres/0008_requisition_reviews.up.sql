@@ -0,0 +1,38 @@
+-- Requisition review workflow: a status on requisitions and project
+-- requisitions, the history of their reviews, and notifications for users.
+-- Requisitions that already have purchase orders are treated as approved.
+
+ALTER TABLE "requisitions" ADD COLUMN IF NOT EXISTS "status" varchar(20) DEFAULT 'draft';
+ALTER TABLE "requisitions" ADD COLUMN IF NOT EXISTS "submitted_by" varchar(100);
+ALTER TABLE "project_requisitions" ADD COLUMN IF NOT EXISTS "status" varchar(20) DEFAULT 'draft';
+ALTER TABLE "project_requisitions" ADD COLUMN IF NOT EXISTS "submitted_by" varchar(100);
+UPDATE "requisitions" SET "status" = 'approved' WHERE "id" IN (SELECT "requisition_id" FROM "purchase_orders" WHERE "requisition_id" IS NOT NULL);
+
+CREATE TABLE IF NOT EXISTS "requisition_reviews" (
+    "id" bigserial,
+    "requisition_id" bigint,
+    "project_requisition_id" bigint,
+    "action" varchar(20) NOT NULL,
+    "actor" varchar(100) NOT NULL,
+    "comment" text,
+    "estimate" decimal,
+    "override_reason" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_project_requisitions_reviews" FOREIGN KEY ("project_requisition_id") REFERENCES "project_requisitions"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_requisitions_reviews" FOREIGN KEY ("requisition_id") REFERENCES "requisitions"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_requisition_reviews_project_requisition_id" ON "requisition_reviews" ("project_requisition_id");
+CREATE INDEX IF NOT EXISTS "idx_requisition_reviews_requisition_id" ON "requisition_reviews" ("requisition_id");
+
+CREATE TABLE IF NOT EXISTS "notifications" (
+    "id" bigserial,
+    "recipient" varchar(100) NOT NULL,
+    "message" varchar(500) NOT NULL,
+    "link" varchar(200),
+    "read_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_notifications_created_at" ON "notifications" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_notifications_recipient" ON "notifications" ("recipient");
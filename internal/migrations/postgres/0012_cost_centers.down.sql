@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS "idx_purchase_orders_gl_account_id";
+ALTER TABLE "purchase_orders" DROP COLUMN IF EXISTS "gl_account_id";
+DROP INDEX IF EXISTS "idx_purchase_orders_cost_center_id";
+ALTER TABLE "purchase_orders" DROP COLUMN IF EXISTS "cost_center_id";
+DROP INDEX IF EXISTS "idx_projects_gl_account_id";
+ALTER TABLE "projects" DROP COLUMN IF EXISTS "gl_account_id";
+DROP INDEX IF EXISTS "idx_projects_cost_center_id";
+ALTER TABLE "projects" DROP COLUMN IF EXISTS "cost_center_id";
+DROP INDEX IF EXISTS "idx_requisitions_gl_account_id";
+ALTER TABLE "requisitions" DROP COLUMN IF EXISTS "gl_account_id";
+DROP INDEX IF EXISTS "idx_requisitions_cost_center_id";
+ALTER TABLE "requisitions" DROP COLUMN IF EXISTS "cost_center_id";
+DROP TABLE IF EXISTS "gl_accounts";
+DROP TABLE IF EXISTS "cost_centers";
@@ -0,0 +1,3 @@
+ALTER TABLE "purchase_orders" DROP COLUMN IF EXISTS "invoice_date";
+DROP TABLE IF EXISTS "accounting_export_entries";
+DROP TABLE IF EXISTS "accounting_exports";
@@ -0,0 +1,29 @@
+-- Accounting export batches, the purchase orders each one exported, and the
+-- date an order's invoice number was recorded.
+
+CREATE TABLE IF NOT EXISTS "accounting_exports" (
+    "id" bigserial,
+    "format" varchar(20) NOT NULL,
+    "source" varchar(20) NOT NULL,
+    "from_date" timestamptz NOT NULL,
+    "to_date" timestamptz NOT NULL,
+    "records" bigint NOT NULL,
+    "exported_by" varchar(100),
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "accounting_export_entries" (
+    "id" bigserial,
+    "accounting_export_id" bigint NOT NULL,
+    "purchase_order_id" bigint NOT NULL,
+    "source" varchar(20) NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_accounting_exports_entries" FOREIGN KEY ("accounting_export_id") REFERENCES "accounting_exports"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_accounting_export_entries_purchase_order" FOREIGN KEY ("purchase_order_id") REFERENCES "purchase_orders"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_accounting_export_entries_order_source" ON "accounting_export_entries" ("purchase_order_id","source");
+CREATE INDEX IF NOT EXISTS "idx_accounting_export_entries_accounting_export_id" ON "accounting_export_entries" ("accounting_export_id");
+
+ALTER TABLE "purchase_orders" ADD COLUMN IF NOT EXISTS "invoice_date" timestamptz;
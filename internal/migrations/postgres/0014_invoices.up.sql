@@ -0,0 +1,49 @@
+-- Vendor invoices and credit notes, with their lines matched to purchase
+-- orders.
+
+CREATE TABLE IF NOT EXISTS "invoices" (
+    "id" bigserial,
+    "document_type" varchar(20) NOT NULL DEFAULT 'invoice',
+    "invoice_number" varchar(100) NOT NULL,
+    "vendor_id" bigint,
+    "supplier_name" varchar(255),
+    "supplier_tax_id" varchar(50),
+    "order_reference" varchar(100),
+    "issue_date" timestamptz NOT NULL,
+    "due_date" timestamptz,
+    "currency" varchar(3) NOT NULL,
+    "net_amount" decimal NOT NULL,
+    "tax_amount" decimal NOT NULL,
+    "total_amount" decimal NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'unmatched',
+    "source" varchar(20),
+    "created_by" varchar(100),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_invoices_vendor" FOREIGN KEY ("vendor_id") REFERENCES "vendors"("id") ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS "idx_invoices_status" ON "invoices" ("status");
+CREATE INDEX IF NOT EXISTS "idx_invoices_issue_date" ON "invoices" ("issue_date");
+CREATE INDEX IF NOT EXISTS "idx_invoices_vendor_id" ON "invoices" ("vendor_id");
+CREATE INDEX IF NOT EXISTS "idx_invoices_invoice_number" ON "invoices" ("invoice_number");
+
+CREATE TABLE IF NOT EXISTS "invoice_lines" (
+    "id" bigserial,
+    "invoice_id" bigint NOT NULL,
+    "line_number" varchar(50),
+    "purchase_order_id" bigint,
+    "order_reference" varchar(100),
+    "sku" varchar(100),
+    "description" text,
+    "quantity" decimal NOT NULL,
+    "unit_price" decimal NOT NULL,
+    "line_amount" decimal NOT NULL,
+    "matched" boolean NOT NULL DEFAULT false,
+    "match_note" text,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_invoices_lines" FOREIGN KEY ("invoice_id") REFERENCES "invoices"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_invoice_lines_purchase_order" FOREIGN KEY ("purchase_order_id") REFERENCES "purchase_orders"("id") ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS "idx_invoice_lines_purchase_order_id" ON "invoice_lines" ("purchase_order_id");
+CREATE INDEX IF NOT EXISTS "idx_invoice_lines_invoice_id" ON "invoice_lines" ("invoice_id");
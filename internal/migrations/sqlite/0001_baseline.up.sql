@@ -0,0 +1,346 @@
+-- Baseline schema: the tables created by AutoMigrate before versioned migrations.
+-- IF NOT EXISTS lets databases created by AutoMigrate adopt this version.
+
+CREATE TABLE IF NOT EXISTS `vendors` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `name` text NOT NULL,
+    `currency` text NOT NULL,
+    `discount_code` text,
+    `contact_person` text,
+    `email` text,
+    `phone` text,
+    `website` text,
+    `address_line1` text,
+    `address_line2` text,
+    `city` text,
+    `state` text,
+    `postal_code` text,
+    `country` text,
+    `tax_id` text,
+    `payment_terms` text,
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_vendors_name` ON `vendors`(`name`);
+
+CREATE TABLE IF NOT EXISTS `brands` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `name` text NOT NULL,
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_brands_name` ON `brands`(`name`);
+
+CREATE TABLE IF NOT EXISTS `specifications` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `name` text NOT NULL,
+    `description` text,
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_specifications_name` ON `specifications`(`name`);
+
+CREATE TABLE IF NOT EXISTS `specification_attributes` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `specification_id` integer NOT NULL,
+    `name` text NOT NULL,
+    `data_type` text NOT NULL DEFAULT "text",
+    `unit` text,
+    `is_required` numeric DEFAULT false,
+    `min_value` real,
+    `max_value` real,
+    `description` text,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_specifications_attributes` FOREIGN KEY (`specification_id`) REFERENCES `specifications`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_spec_attr` ON `specification_attributes`(`specification_id`,`name`);
+
+CREATE TABLE IF NOT EXISTS `products` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `name` text NOT NULL,
+    `sku` text,
+    `description` text,
+    `brand_id` integer NOT NULL,
+    `specification_id` integer,
+    `unit_of_measure` text DEFAULT "each",
+    `min_order_qty` integer,
+    `lead_time_days` integer,
+    `is_active` numeric DEFAULT true,
+    `discontinued_at` datetime,
+    `created_by` text,
+    `updated_by` text,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_specifications_products` FOREIGN KEY (`specification_id`) REFERENCES `specifications`(`id`) ON DELETE SET NULL,
+    CONSTRAINT `fk_brands_products` FOREIGN KEY (`brand_id`) REFERENCES `brands`(`id`) ON DELETE RESTRICT
+);
+CREATE INDEX IF NOT EXISTS `idx_products_specification_id` ON `products`(`specification_id`);
+CREATE INDEX IF NOT EXISTS `idx_products_brand_id` ON `products`(`brand_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_products_sku` ON `products`(`sku`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_products_name` ON `products`(`name`);
+
+CREATE TABLE IF NOT EXISTS `product_attributes` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `product_id` integer NOT NULL,
+    `specification_attribute_id` integer NOT NULL,
+    `value_text` text,
+    `value_number` real,
+    `value_boolean` numeric,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_product_attributes_specification_attribute` FOREIGN KEY (`specification_attribute_id`) REFERENCES `specification_attributes`(`id`) ON DELETE RESTRICT,
+    CONSTRAINT `fk_products_attributes` FOREIGN KEY (`product_id`) REFERENCES `products`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_prod_attr` ON `product_attributes`(`product_id`,`specification_attribute_id`);
+
+CREATE TABLE IF NOT EXISTS `requisitions` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `name` text NOT NULL,
+    `justification` text,
+    `budget` real,
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_requisitions_name` ON `requisitions`(`name`);
+
+CREATE TABLE IF NOT EXISTS `requisition_items` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `requisition_id` integer NOT NULL,
+    `specification_id` integer NOT NULL,
+    `quantity` integer NOT NULL,
+    `budget_per_unit` real,
+    `description` text,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_requisitions_items` FOREIGN KEY (`requisition_id`) REFERENCES `requisitions`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_requisition_items_specification` FOREIGN KEY (`specification_id`) REFERENCES `specifications`(`id`) ON DELETE RESTRICT
+);
+CREATE INDEX IF NOT EXISTS `idx_requisition_items_specification_id` ON `requisition_items`(`specification_id`);
+CREATE INDEX IF NOT EXISTS `idx_requisition_items_requisition_id` ON `requisition_items`(`requisition_id`);
+
+CREATE TABLE IF NOT EXISTS `quotes` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `vendor_id` integer NOT NULL,
+    `product_id` integer NOT NULL,
+    `version` integer NOT NULL DEFAULT 1,
+    `previous_quote_id` integer,
+    `replaced_by` integer,
+    `price` real NOT NULL,
+    `currency` text NOT NULL,
+    `converted_price` real NOT NULL,
+    `conversion_rate` real NOT NULL,
+    `min_quantity` integer,
+    `quote_date` datetime NOT NULL,
+    `valid_until` datetime,
+    `status` text DEFAULT "active",
+    `notes` text,
+    `created_by` text,
+    `updated_by` text,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_products_quotes` FOREIGN KEY (`product_id`) REFERENCES `products`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_vendors_quotes` FOREIGN KEY (`vendor_id`) REFERENCES `vendors`(`id`) ON DELETE RESTRICT
+);
+CREATE INDEX IF NOT EXISTS `idx_quotes_valid_until` ON `quotes`(`valid_until`);
+CREATE INDEX IF NOT EXISTS `idx_quotes_quote_date` ON `quotes`(`quote_date`);
+CREATE INDEX IF NOT EXISTS `idx_quotes_replaced_by` ON `quotes`(`replaced_by`);
+CREATE INDEX IF NOT EXISTS `idx_quotes_previous_quote_id` ON `quotes`(`previous_quote_id`);
+CREATE INDEX IF NOT EXISTS `idx_quotes_product_id` ON `quotes`(`product_id`);
+CREATE INDEX IF NOT EXISTS `idx_quotes_vendor_id` ON `quotes`(`vendor_id`);
+
+CREATE TABLE IF NOT EXISTS `purchase_orders` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `quote_id` integer NOT NULL,
+    `vendor_id` integer NOT NULL,
+    `product_id` integer NOT NULL,
+    `requisition_id` integer,
+    `po_number` text NOT NULL,
+    `status` text NOT NULL DEFAULT "pending",
+    `order_date` datetime NOT NULL,
+    `expected_delivery` datetime,
+    `actual_delivery` datetime,
+    `quantity` integer NOT NULL,
+    `unit_price` real NOT NULL,
+    `currency` text NOT NULL,
+    `total_amount` real NOT NULL,
+    `shipping_cost` real,
+    `tax` real,
+    `grand_total` real NOT NULL,
+    `invoice_number` text,
+    `notes` text,
+    `created_by` text,
+    `updated_by` text,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_quotes_purchase_orders` FOREIGN KEY (`quote_id`) REFERENCES `quotes`(`id`) ON DELETE RESTRICT,
+    CONSTRAINT `fk_vendors_purchase_orders` FOREIGN KEY (`vendor_id`) REFERENCES `vendors`(`id`) ON DELETE RESTRICT,
+    CONSTRAINT `fk_purchase_orders_product` FOREIGN KEY (`product_id`) REFERENCES `products`(`id`) ON DELETE RESTRICT,
+    CONSTRAINT `fk_requisitions_purchase_orders` FOREIGN KEY (`requisition_id`) REFERENCES `requisitions`(`id`) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS `idx_purchase_orders_order_date` ON `purchase_orders`(`order_date`);
+CREATE INDEX IF NOT EXISTS `idx_purchase_orders_status` ON `purchase_orders`(`status`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_purchase_orders_po_number` ON `purchase_orders`(`po_number`);
+CREATE INDEX IF NOT EXISTS `idx_purchase_orders_requisition_id` ON `purchase_orders`(`requisition_id`);
+CREATE INDEX IF NOT EXISTS `idx_purchase_orders_product_id` ON `purchase_orders`(`product_id`);
+CREATE INDEX IF NOT EXISTS `idx_purchase_orders_vendor_id` ON `purchase_orders`(`vendor_id`);
+CREATE INDEX IF NOT EXISTS `idx_purchase_orders_quote_id` ON `purchase_orders`(`quote_id`);
+
+CREATE TABLE IF NOT EXISTS `vendor_ratings` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `vendor_id` integer NOT NULL,
+    `purchase_order_id` integer,
+    `price_rating` integer,
+    `quality_rating` integer,
+    `delivery_rating` integer,
+    `service_rating` integer,
+    `comments` text,
+    `rated_by` text,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_purchase_orders_vendor_ratings` FOREIGN KEY (`purchase_order_id`) REFERENCES `purchase_orders`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_vendors_vendor_ratings` FOREIGN KEY (`vendor_id`) REFERENCES `vendors`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_vendor_ratings_purchase_order_id` ON `vendor_ratings`(`purchase_order_id`);
+CREATE INDEX IF NOT EXISTS `idx_vendor_ratings_vendor_id` ON `vendor_ratings`(`vendor_id`);
+
+CREATE TABLE IF NOT EXISTS `forex` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `from_currency` text NOT NULL,
+    `to_currency` text NOT NULL,
+    `rate` real NOT NULL,
+    `effective_date` datetime NOT NULL,
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_forex_effective_date` ON `forex`(`effective_date`);
+CREATE INDEX IF NOT EXISTS `idx_forex_pair` ON `forex`(`from_currency`,`to_currency`);
+
+CREATE TABLE IF NOT EXISTS `projects` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `name` text NOT NULL,
+    `description` text,
+    `budget` real,
+    `deadline` datetime,
+    `status` text DEFAULT "planning",
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_projects_name` ON `projects`(`name`);
+
+CREATE TABLE IF NOT EXISTS `bills_of_materials` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `project_id` integer NOT NULL,
+    `notes` text,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_projects_bill_of_materials` FOREIGN KEY (`project_id`) REFERENCES `projects`(`id`) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_bills_of_materials_project_id` ON `bills_of_materials`(`project_id`);
+
+CREATE TABLE IF NOT EXISTS `bill_of_materials_items` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `bill_of_materials_id` integer NOT NULL,
+    `specification_id` integer NOT NULL,
+    `quantity` integer NOT NULL,
+    `notes` text,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_bill_of_materials_items_specification` FOREIGN KEY (`specification_id`) REFERENCES `specifications`(`id`) ON DELETE RESTRICT,
+    CONSTRAINT `fk_bills_of_materials_items` FOREIGN KEY (`bill_of_materials_id`) REFERENCES `bills_of_materials`(`id`) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_bom_spec_unique` ON `bill_of_materials_items`(`specification_id`);
+CREATE INDEX IF NOT EXISTS `idx_bom_spec` ON `bill_of_materials_items`(`bill_of_materials_id`,`specification_id`);
+
+CREATE TABLE IF NOT EXISTS `project_requisitions` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `project_id` integer NOT NULL,
+    `name` text NOT NULL,
+    `justification` text,
+    `budget` real,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_projects_requisitions` FOREIGN KEY (`project_id`) REFERENCES `projects`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_project_requisitions_project_id` ON `project_requisitions`(`project_id`);
+
+CREATE TABLE IF NOT EXISTS `project_requisition_items` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `project_requisition_id` integer NOT NULL,
+    `bill_of_materials_item_id` integer NOT NULL,
+    `quantity_requested` integer NOT NULL,
+    `selected_quote_id` integer,
+    `target_unit_price` real,
+    `actual_unit_price` real,
+    `procurement_status` text DEFAULT "pending",
+    `notes` text,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_project_requisition_items_bom_item` FOREIGN KEY (`bill_of_materials_item_id`) REFERENCES `bill_of_materials_items`(`id`) ON DELETE RESTRICT,
+    CONSTRAINT `fk_project_requisition_items_selected_quote` FOREIGN KEY (`selected_quote_id`) REFERENCES `quotes`(`id`) ON DELETE SET NULL,
+    CONSTRAINT `fk_project_requisitions_items` FOREIGN KEY (`project_requisition_id`) REFERENCES `project_requisitions`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_project_requisition_items_selected_quote_id` ON `project_requisition_items`(`selected_quote_id`);
+CREATE INDEX IF NOT EXISTS `idx_project_requisition_items_bill_of_materials_item_id` ON `project_requisition_items`(`bill_of_materials_item_id`);
+CREATE INDEX IF NOT EXISTS `idx_project_requisition_items_project_requisition_id` ON `project_requisition_items`(`project_requisition_id`);
+
+CREATE TABLE IF NOT EXISTS `project_procurement_strategies` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `project_id` integer NOT NULL,
+    `strategy` text DEFAULT "lowest_cost",
+    `max_vendors` integer,
+    `min_vendor_rating` real,
+    `preferred_vendor_ids` text,
+    `excluded_vendor_ids` text,
+    `allow_partial_fulfill` numeric DEFAULT true,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_project_procurement_strategies_project` FOREIGN KEY (`project_id`) REFERENCES `projects`(`id`) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_project_procurement_strategies_project_id` ON `project_procurement_strategies`(`project_id`);
+
+CREATE TABLE IF NOT EXISTS `documents` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `entity_type` text NOT NULL,
+    `entity_id` integer NOT NULL,
+    `file_name` text NOT NULL,
+    `file_type` text,
+    `file_size` integer,
+    `file_path` text NOT NULL,
+    `description` text,
+    `uploaded_by` text,
+    `created_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_entity` ON `documents`(`entity_type`,`entity_id`);
+
+CREATE TABLE IF NOT EXISTS `import_profiles` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `name` text NOT NULL,
+    `entity` text NOT NULL,
+    `vendor_id` integer,
+    `sheet` text,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_import_profiles_vendor` FOREIGN KEY (`vendor_id`) REFERENCES `vendors`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_import_profiles_vendor_id` ON `import_profiles`(`vendor_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_import_profiles_name` ON `import_profiles`(`name`);
+
+CREATE TABLE IF NOT EXISTS `import_profile_fields` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `profile_id` integer NOT NULL,
+    `field` text NOT NULL,
+    `column` text,
+    `constant` text,
+    `transform` text,
+    CONSTRAINT `fk_import_profiles_fields` FOREIGN KEY (`profile_id`) REFERENCES `import_profiles`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_import_profile_fields_profile_id` ON `import_profile_fields`(`profile_id`);
+
+CREATE TABLE IF NOT EXISTS `vendor_brands` (
+    `brand_id` integer,
+    `vendor_id` integer,
+    PRIMARY KEY (`brand_id`,`vendor_id`),
+    CONSTRAINT `fk_vendor_brands_brand` FOREIGN KEY (`brand_id`) REFERENCES `brands`(`id`),
+    CONSTRAINT `fk_vendor_brands_vendor` FOREIGN KEY (`vendor_id`) REFERENCES `vendors`(`id`)
+);
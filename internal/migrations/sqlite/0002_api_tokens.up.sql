@@ -0,0 +1,17 @@
+-- Personal access tokens and service account tokens for the web server.
+
+CREATE TABLE IF NOT EXISTS `api_tokens` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `name` text NOT NULL,
+    `owner` text NOT NULL,
+    `service_account` numeric NOT NULL DEFAULT false,
+    `prefix` text NOT NULL,
+    `token_hash` text NOT NULL,
+    `scopes` text NOT NULL,
+    `expires_at` datetime,
+    `revoked_at` datetime,
+    `last_used_at` datetime,
+    `created_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_api_tokens_token_hash` ON `api_tokens`(`token_hash`);
+CREATE INDEX IF NOT EXISTS `idx_api_tokens_owner` ON `api_tokens`(`owner`);
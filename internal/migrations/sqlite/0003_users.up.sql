@@ -0,0 +1,27 @@
+-- User accounts with roles, and their browser sessions.
+
+CREATE TABLE IF NOT EXISTS `users` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `username` text NOT NULL,
+    `name` text,
+    `email` text,
+    `role` text NOT NULL,
+    `password_hash` text,
+    `disabled` numeric NOT NULL DEFAULT false,
+    `last_login_at` datetime,
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_users_username` ON `users`(`username`);
+
+CREATE TABLE IF NOT EXISTS `sessions` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `token_hash` text NOT NULL,
+    `user_id` integer NOT NULL,
+    `expires_at` datetime NOT NULL,
+    `last_seen_at` datetime,
+    `created_at` datetime,
+    CONSTRAINT `fk_sessions_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_sessions_user_id` ON `sessions`(`user_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_sessions_token_hash` ON `sessions`(`token_hash`);
@@ -0,0 +1,13 @@
+-- Links between users and their accounts at an OpenID Connect provider.
+
+CREATE TABLE IF NOT EXISTS `user_identities` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `user_id` integer NOT NULL,
+    `issuer` text NOT NULL,
+    `subject` text NOT NULL,
+    `last_login_at` datetime,
+    `created_at` datetime,
+    CONSTRAINT `fk_user_identities_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_user_identity_subject` ON `user_identities`(`issuer`,`subject`);
+CREATE INDEX IF NOT EXISTS `idx_user_identities_user_id` ON `user_identities`(`user_id`);
@@ -0,0 +1,14 @@
+-- Audit log of every create, update and delete.
+
+CREATE TABLE IF NOT EXISTS `audit_logs` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `entity` text NOT NULL,
+    `entity_id` integer NOT NULL,
+    `action` text NOT NULL,
+    `actor` text,
+    `source` text,
+    `changes` text,
+    `created_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_audit_logs_created_at` ON `audit_logs`(`created_at`);
+CREATE INDEX IF NOT EXISTS `idx_audit_entity` ON `audit_logs`(`entity`,`entity_id`);
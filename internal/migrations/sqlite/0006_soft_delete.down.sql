@@ -0,0 +1,18 @@
+DROP INDEX IF EXISTS `idx_projects_deleted_at`;
+ALTER TABLE `projects` DROP COLUMN `deleted_at`;
+DROP INDEX IF EXISTS `idx_vendor_ratings_deleted_at`;
+ALTER TABLE `vendor_ratings` DROP COLUMN `deleted_at`;
+DROP INDEX IF EXISTS `idx_purchase_orders_deleted_at`;
+ALTER TABLE `purchase_orders` DROP COLUMN `deleted_at`;
+DROP INDEX IF EXISTS `idx_quotes_deleted_at`;
+ALTER TABLE `quotes` DROP COLUMN `deleted_at`;
+DROP INDEX IF EXISTS `idx_requisitions_deleted_at`;
+ALTER TABLE `requisitions` DROP COLUMN `deleted_at`;
+DROP INDEX IF EXISTS `idx_products_deleted_at`;
+ALTER TABLE `products` DROP COLUMN `deleted_at`;
+DROP INDEX IF EXISTS `idx_specifications_deleted_at`;
+ALTER TABLE `specifications` DROP COLUMN `deleted_at`;
+DROP INDEX IF EXISTS `idx_brands_deleted_at`;
+ALTER TABLE `brands` DROP COLUMN `deleted_at`;
+DROP INDEX IF EXISTS `idx_vendors_deleted_at`;
+ALTER TABLE `vendors` DROP COLUMN `deleted_at`;
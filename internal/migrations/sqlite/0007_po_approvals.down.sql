@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `purchase_order_approvals`;
+DROP TABLE IF EXISTS `approval_rules`;
+DROP INDEX IF EXISTS `idx_purchase_orders_project_id`;
+ALTER TABLE `purchase_orders` DROP COLUMN `project_id`;
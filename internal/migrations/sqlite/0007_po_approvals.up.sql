@@ -0,0 +1,32 @@
+-- Purchase order approval workflow: approval rules, the decisions taken on
+-- each step, and an optional project link on purchase orders.
+
+ALTER TABLE `purchase_orders` ADD COLUMN IF NOT EXISTS `project_id` integer REFERENCES `projects`(`id`) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS `idx_purchase_orders_project_id` ON `purchase_orders`(`project_id`);
+
+CREATE TABLE IF NOT EXISTS `approval_rules` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `step` integer NOT NULL DEFAULT 1,
+    `min_amount` real NOT NULL DEFAULT 0,
+    `approvers` text,
+    `project_id` integer,
+    `requisition_id` integer,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_approval_rules_project` FOREIGN KEY (`project_id`) REFERENCES `projects`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_approval_rules_requisition` FOREIGN KEY (`requisition_id`) REFERENCES `requisitions`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_approval_rules_requisition_id` ON `approval_rules`(`requisition_id`);
+CREATE INDEX IF NOT EXISTS `idx_approval_rules_project_id` ON `approval_rules`(`project_id`);
+
+CREATE TABLE IF NOT EXISTS `purchase_order_approvals` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `purchase_order_id` integer NOT NULL,
+    `step` integer NOT NULL,
+    `approver` text NOT NULL,
+    `decision` text NOT NULL,
+    `comment` text,
+    `created_at` datetime,
+    CONSTRAINT `fk_purchase_order_approvals_purchase_order` FOREIGN KEY (`purchase_order_id`) REFERENCES `purchase_orders`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_purchase_order_approvals_purchase_order_id` ON `purchase_order_approvals`(`purchase_order_id`);
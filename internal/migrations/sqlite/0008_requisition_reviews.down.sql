@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS `notifications`;
+DROP TABLE IF EXISTS `requisition_reviews`;
+ALTER TABLE `project_requisitions` DROP COLUMN `submitted_by`;
+ALTER TABLE `project_requisitions` DROP COLUMN `status`;
+ALTER TABLE `requisitions` DROP COLUMN `submitted_by`;
+ALTER TABLE `requisitions` DROP COLUMN `status`;
@@ -0,0 +1,36 @@
+-- Requisition review workflow: a status on requisitions and project
+-- requisitions, the history of their reviews, and notifications for users.
+-- Requisitions that already have purchase orders are treated as approved.
+
+ALTER TABLE `requisitions` ADD COLUMN IF NOT EXISTS `status` text DEFAULT 'draft';
+ALTER TABLE `requisitions` ADD COLUMN IF NOT EXISTS `submitted_by` text;
+ALTER TABLE `project_requisitions` ADD COLUMN IF NOT EXISTS `status` text DEFAULT 'draft';
+ALTER TABLE `project_requisitions` ADD COLUMN IF NOT EXISTS `submitted_by` text;
+UPDATE `requisitions` SET `status` = 'approved' WHERE `id` IN (SELECT `requisition_id` FROM `purchase_orders` WHERE `requisition_id` IS NOT NULL);
+
+CREATE TABLE IF NOT EXISTS `requisition_reviews` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `requisition_id` integer,
+    `project_requisition_id` integer,
+    `action` text NOT NULL,
+    `actor` text NOT NULL,
+    `comment` text,
+    `estimate` real,
+    `override_reason` text,
+    `created_at` datetime,
+    CONSTRAINT `fk_project_requisitions_reviews` FOREIGN KEY (`project_requisition_id`) REFERENCES `project_requisitions`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_requisitions_reviews` FOREIGN KEY (`requisition_id`) REFERENCES `requisitions`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_requisition_reviews_project_requisition_id` ON `requisition_reviews`(`project_requisition_id`);
+CREATE INDEX IF NOT EXISTS `idx_requisition_reviews_requisition_id` ON `requisition_reviews`(`requisition_id`);
+
+CREATE TABLE IF NOT EXISTS `notifications` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `recipient` text NOT NULL,
+    `message` text NOT NULL,
+    `link` text,
+    `read_at` datetime,
+    `created_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_notifications_created_at` ON `notifications`(`created_at`);
+CREATE INDEX IF NOT EXISTS `idx_notifications_recipient` ON `notifications`(`recipient`);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS `idx_purchase_orders_requisition_item_id`;
+ALTER TABLE `purchase_orders` DROP COLUMN `requisition_item_id`;
@@ -0,0 +1,5 @@
+-- Link purchase orders to the requisition line they fulfil, so converting a
+-- requisition to purchase orders does not order the same line twice.
+
+ALTER TABLE `purchase_orders` ADD COLUMN IF NOT EXISTS `requisition_item_id` integer REFERENCES `requisition_items`(`id`) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS `idx_purchase_orders_requisition_item_id` ON `purchase_orders`(`requisition_item_id`);
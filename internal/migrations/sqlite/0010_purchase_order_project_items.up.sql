@@ -0,0 +1,5 @@
+-- Link purchase orders to the project requisition line they fulfil, so the
+-- line's procurement status and actual price follow its orders.
+
+ALTER TABLE `purchase_orders` ADD COLUMN IF NOT EXISTS `project_requisition_item_id` integer REFERENCES `project_requisition_items`(`id`) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS `idx_purchase_orders_project_requisition_item_id` ON `purchase_orders`(`project_requisition_item_id`);
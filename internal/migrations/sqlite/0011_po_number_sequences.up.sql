@@ -0,0 +1,10 @@
+-- Sequences for automatic PO numbers, one row per pattern scope (for
+-- example PO-2025-{seq:5}), bumped atomically when a number is handed out.
+
+CREATE TABLE IF NOT EXISTS `po_number_sequences` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `scope` text NOT NULL,
+    `value` integer NOT NULL DEFAULT 0,
+    `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_po_number_sequences_scope` ON `po_number_sequences`(`scope`);
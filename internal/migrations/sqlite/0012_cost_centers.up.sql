@@ -0,0 +1,37 @@
+-- Cost centers and general-ledger accounts, with default coding on
+-- requisitions and projects and the coding of each purchase order.
+
+CREATE TABLE IF NOT EXISTS `cost_centers` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `code` text NOT NULL,
+    `name` text NOT NULL,
+    `active` numeric NOT NULL DEFAULT true,
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_cost_centers_code` ON `cost_centers`(`code`);
+
+CREATE TABLE IF NOT EXISTS `gl_accounts` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `code` text NOT NULL,
+    `name` text NOT NULL,
+    `active` numeric NOT NULL DEFAULT true,
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_gl_accounts_code` ON `gl_accounts`(`code`);
+
+ALTER TABLE `requisitions` ADD COLUMN IF NOT EXISTS `cost_center_id` integer REFERENCES `cost_centers`(`id`) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS `idx_requisitions_cost_center_id` ON `requisitions`(`cost_center_id`);
+ALTER TABLE `requisitions` ADD COLUMN IF NOT EXISTS `gl_account_id` integer REFERENCES `gl_accounts`(`id`) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS `idx_requisitions_gl_account_id` ON `requisitions`(`gl_account_id`);
+
+ALTER TABLE `projects` ADD COLUMN IF NOT EXISTS `cost_center_id` integer REFERENCES `cost_centers`(`id`) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS `idx_projects_cost_center_id` ON `projects`(`cost_center_id`);
+ALTER TABLE `projects` ADD COLUMN IF NOT EXISTS `gl_account_id` integer REFERENCES `gl_accounts`(`id`) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS `idx_projects_gl_account_id` ON `projects`(`gl_account_id`);
+
+ALTER TABLE `purchase_orders` ADD COLUMN IF NOT EXISTS `cost_center_id` integer REFERENCES `cost_centers`(`id`) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS `idx_purchase_orders_cost_center_id` ON `purchase_orders`(`cost_center_id`);
+ALTER TABLE `purchase_orders` ADD COLUMN IF NOT EXISTS `gl_account_id` integer REFERENCES `gl_accounts`(`id`) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS `idx_purchase_orders_gl_account_id` ON `purchase_orders`(`gl_account_id`);
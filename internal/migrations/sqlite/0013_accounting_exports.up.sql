@@ -0,0 +1,27 @@
+-- Accounting export batches, the purchase orders each one exported, and the
+-- date an order's invoice number was recorded.
+
+CREATE TABLE IF NOT EXISTS `accounting_exports` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `format` text NOT NULL,
+    `source` text NOT NULL,
+    `from_date` datetime NOT NULL,
+    `to_date` datetime NOT NULL,
+    `records` integer NOT NULL,
+    `exported_by` text,
+    `created_at` datetime
+);
+
+CREATE TABLE IF NOT EXISTS `accounting_export_entries` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `accounting_export_id` integer NOT NULL,
+    `purchase_order_id` integer NOT NULL,
+    `source` text NOT NULL,
+    `created_at` datetime,
+    CONSTRAINT `fk_accounting_exports_entries` FOREIGN KEY (`accounting_export_id`) REFERENCES `accounting_exports`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_accounting_export_entries_purchase_order` FOREIGN KEY (`purchase_order_id`) REFERENCES `purchase_orders`(`id`) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_accounting_export_entries_order_source` ON `accounting_export_entries`(`purchase_order_id`,`source`);
+CREATE INDEX IF NOT EXISTS `idx_accounting_export_entries_accounting_export_id` ON `accounting_export_entries`(`accounting_export_id`);
+
+ALTER TABLE `purchase_orders` ADD COLUMN IF NOT EXISTS `invoice_date` datetime;
@@ -0,0 +1,47 @@
+-- Vendor invoices and credit notes, with their lines matched to purchase
+-- orders.
+
+CREATE TABLE IF NOT EXISTS `invoices` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `document_type` text NOT NULL DEFAULT "invoice",
+    `invoice_number` text NOT NULL,
+    `vendor_id` integer,
+    `supplier_name` text,
+    `supplier_tax_id` text,
+    `order_reference` text,
+    `issue_date` datetime NOT NULL,
+    `due_date` datetime,
+    `currency` text NOT NULL,
+    `net_amount` real NOT NULL,
+    `tax_amount` real NOT NULL,
+    `total_amount` real NOT NULL,
+    `status` text NOT NULL DEFAULT "unmatched",
+    `source` text,
+    `created_by` text,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_invoices_vendor` FOREIGN KEY (`vendor_id`) REFERENCES `vendors`(`id`) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS `idx_invoices_status` ON `invoices`(`status`);
+CREATE INDEX IF NOT EXISTS `idx_invoices_issue_date` ON `invoices`(`issue_date`);
+CREATE INDEX IF NOT EXISTS `idx_invoices_vendor_id` ON `invoices`(`vendor_id`);
+CREATE INDEX IF NOT EXISTS `idx_invoices_invoice_number` ON `invoices`(`invoice_number`);
+
+CREATE TABLE IF NOT EXISTS `invoice_lines` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `invoice_id` integer NOT NULL,
+    `line_number` text,
+    `purchase_order_id` integer,
+    `order_reference` text,
+    `sku` text,
+    `description` text,
+    `quantity` real NOT NULL,
+    `unit_price` real NOT NULL,
+    `line_amount` real NOT NULL,
+    `matched` numeric NOT NULL DEFAULT false,
+    `match_note` text,
+    CONSTRAINT `fk_invoices_lines` FOREIGN KEY (`invoice_id`) REFERENCES `invoices`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_invoice_lines_purchase_order` FOREIGN KEY (`purchase_order_id`) REFERENCES `purchase_orders`(`id`) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS `idx_invoice_lines_purchase_order_id` ON `invoice_lines`(`purchase_order_id`);
+CREATE INDEX IF NOT EXISTS `idx_invoice_lines_invoice_id` ON `invoice_lines`(`invoice_id`);
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type actorKey struct{}
+
+type sourceKey struct{}
+
+// Sources of a change, recorded in the audit log
+const (
+	SourceCLI = "cli" // The buyer command
+	SourceWeb = "web" // The web interface
+	SourceAPI = "api" // The JSON API
+)
+
+// WithActor returns a copy of ctx naming the user who makes changes. Saves
+// through a *gorm.DB carrying the context record the user in the CreatedBy
+// and UpdatedBy columns.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the user recorded by WithActor, or "" if there is none
+func ActorFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// WithSource returns a copy of ctx naming where changes come from: SourceCLI,
+// SourceWeb or SourceAPI
+func WithSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// SourceFrom returns the source recorded by WithSource, or "" if there is none
+func SourceFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	source, _ := ctx.Value(sourceKey{}).(string)
+	return source
+}
+
+// statementActor returns the actor of the statement being executed
+func statementActor(tx *gorm.DB) string {
+	if tx.Statement == nil {
+		return ""
+	}
+	return ActorFrom(tx.Statement.Context)
+}
@@ -40,6 +40,7 @@ type Vendor struct {
 	VendorRatings  []VendorRating  `gorm:"foreignKey:VendorID;constraint:OnDelete:CASCADE" json:"vendor_ratings,omitempty"`
 	CreatedAt      time.Time       `json:"created_at"`
 	UpdatedAt      time.Time       `json:"updated_at"`
+	DeletedAt      DeletedAt       `gorm:"index" json:"deleted_at,omitzero"`
 }
 
 // Brand represents a manufacturing entity
@@ -50,6 +51,7 @@ type Brand struct {
 	Products  []Product `gorm:"foreignKey:BrandID;constraint:OnDelete:RESTRICT" json:"products,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	DeletedAt DeletedAt `gorm:"index" json:"deleted_at,omitzero"`
 }
 
 // Specification represents a general description of a type of product
@@ -61,6 +63,7 @@ type Specification struct {
 	Attributes  []SpecificationAttribute `gorm:"foreignKey:SpecificationID;constraint:OnDelete:CASCADE" json:"attributes,omitempty"`
 	CreatedAt   time.Time                `json:"created_at"`
 	UpdatedAt   time.Time                `json:"updated_at"`
+	DeletedAt   DeletedAt                `gorm:"index" json:"deleted_at,omitzero"`
 }
 
 // SpecificationAttribute defines what attributes a specification type should have
@@ -108,6 +111,7 @@ type Product struct {
 	UpdatedBy string    `gorm:"size:100" json:"updated_by,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	DeletedAt DeletedAt `gorm:"index" json:"deleted_at,omitzero"`
 }
 
 // ProductAttribute stores actual attribute values for a specific product
@@ -127,14 +131,22 @@ type ProductAttribute struct {
 
 // Requisition represents a purchasing requirement
 type Requisition struct {
-	ID             uint              `gorm:"primaryKey" json:"id"`
-	Name           string            `gorm:"uniqueIndex;not null" json:"name"`
-	Justification  string            `gorm:"type:text" json:"justification,omitempty"`
-	Budget         float64           `json:"budget,omitempty"` // Optional overall budget limit
-	Items          []RequisitionItem `gorm:"foreignKey:RequisitionID;constraint:OnDelete:CASCADE" json:"items,omitempty"`
-	PurchaseOrders []PurchaseOrder   `gorm:"foreignKey:RequisitionID;constraint:OnDelete:SET NULL" json:"purchase_orders,omitempty"`
-	CreatedAt      time.Time         `json:"created_at"`
-	UpdatedAt      time.Time         `json:"updated_at"`
+	ID             uint                `gorm:"primaryKey" json:"id"`
+	Name           string              `gorm:"uniqueIndex;not null" json:"name"`
+	Justification  string              `gorm:"type:text" json:"justification,omitempty"`
+	Budget         float64             `json:"budget,omitempty"`                      // Optional overall budget limit
+	Status         string              `gorm:"size:20;default:'draft'" json:"status"` // draft, submitted, approved, rejected
+	SubmittedBy    string              `gorm:"size:100" json:"submitted_by,omitempty"`
+	CostCenterID   *uint               `gorm:"index" json:"cost_center_id,omitempty"` // Default cost center of its orders
+	CostCenter     *CostCenter         `gorm:"foreignKey:CostCenterID;constraint:OnDelete:SET NULL" json:"cost_center,omitempty"`
+	GLAccountID    *uint               `gorm:"index" json:"gl_account_id,omitempty"` // Default GL account of its orders
+	GLAccount      *GLAccount          `gorm:"foreignKey:GLAccountID;constraint:OnDelete:SET NULL" json:"gl_account,omitempty"`
+	Items          []RequisitionItem   `gorm:"foreignKey:RequisitionID;constraint:OnDelete:CASCADE" json:"items,omitempty"`
+	PurchaseOrders []PurchaseOrder     `gorm:"foreignKey:RequisitionID;constraint:OnDelete:SET NULL" json:"purchase_orders,omitempty"`
+	Reviews        []RequisitionReview `gorm:"foreignKey:RequisitionID;constraint:OnDelete:CASCADE" json:"reviews,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+	DeletedAt      DeletedAt           `gorm:"index" json:"deleted_at,omitzero"`
 }
 
 // RequisitionItem represents a line item in a requisition
@@ -186,33 +198,45 @@ type Quote struct {
 	UpdatedBy string    `gorm:"size:100" json:"updated_by,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	DeletedAt DeletedAt `gorm:"index" json:"deleted_at,omitzero"`
 }
 
 // PurchaseOrder represents an accepted quote that has been ordered
 type PurchaseOrder struct {
-	ID               uint         `gorm:"primaryKey" json:"id"`
-	QuoteID          uint         `gorm:"not null;index" json:"quote_id"`
-	Quote            *Quote       `gorm:"foreignKey:QuoteID;constraint:OnDelete:RESTRICT" json:"quote,omitempty"`
-	VendorID         uint         `gorm:"not null;index" json:"vendor_id"` // Denormalized for easier queries
-	Vendor           *Vendor      `gorm:"foreignKey:VendorID;constraint:OnDelete:RESTRICT" json:"vendor,omitempty"`
-	ProductID        uint         `gorm:"not null;index" json:"product_id"` // Denormalized for easier queries
-	Product          *Product     `gorm:"foreignKey:ProductID;constraint:OnDelete:RESTRICT" json:"product,omitempty"`
-	RequisitionID    *uint        `gorm:"index" json:"requisition_id,omitempty"` // Optional link to requisition
-	Requisition      *Requisition `gorm:"foreignKey:RequisitionID;constraint:OnDelete:SET NULL" json:"requisition,omitempty"`
-	PONumber         string       `gorm:"uniqueIndex;not null;size:50" json:"po_number"`          // Generated or manual PO number
-	Status           string       `gorm:"size:20;not null;default:'pending';index" json:"status"` // pending, approved, ordered, shipped, received, cancelled
-	OrderDate        time.Time    `gorm:"not null;index" json:"order_date"`
-	ExpectedDelivery *time.Time   `json:"expected_delivery,omitempty"`
-	ActualDelivery   *time.Time   `json:"actual_delivery,omitempty"`
-	Quantity         int          `gorm:"not null" json:"quantity"`        // Can order multiple units
-	UnitPrice        float64      `gorm:"not null" json:"unit_price"`      // Price per unit in original currency
-	Currency         string       `gorm:"size:3;not null" json:"currency"` // Quote currency
-	TotalAmount      float64      `gorm:"not null" json:"total_amount"`    // Total cost (unit_price * quantity)
-	ShippingCost     float64      `json:"shipping_cost,omitempty"`
-	Tax              float64      `json:"tax,omitempty"`
-	GrandTotal       float64      `gorm:"not null" json:"grand_total"` // total_amount + shipping_cost + tax
-	InvoiceNumber    string       `gorm:"size:100" json:"invoice_number,omitempty"`
-	Notes            string       `gorm:"type:text" json:"notes,omitempty"`
+	ID                       uint                    `gorm:"primaryKey" json:"id"`
+	QuoteID                  uint                    `gorm:"not null;index" json:"quote_id"`
+	Quote                    *Quote                  `gorm:"foreignKey:QuoteID;constraint:OnDelete:RESTRICT" json:"quote,omitempty"`
+	VendorID                 uint                    `gorm:"not null;index" json:"vendor_id"` // Denormalized for easier queries
+	Vendor                   *Vendor                 `gorm:"foreignKey:VendorID;constraint:OnDelete:RESTRICT" json:"vendor,omitempty"`
+	ProductID                uint                    `gorm:"not null;index" json:"product_id"` // Denormalized for easier queries
+	Product                  *Product                `gorm:"foreignKey:ProductID;constraint:OnDelete:RESTRICT" json:"product,omitempty"`
+	RequisitionID            *uint                   `gorm:"index" json:"requisition_id,omitempty"` // Optional link to requisition
+	Requisition              *Requisition            `gorm:"foreignKey:RequisitionID;constraint:OnDelete:SET NULL" json:"requisition,omitempty"`
+	RequisitionItemID        *uint                   `gorm:"index" json:"requisition_item_id,omitempty"` // Requisition line this order fulfils
+	RequisitionItem          *RequisitionItem        `gorm:"foreignKey:RequisitionItemID;constraint:OnDelete:SET NULL" json:"requisition_item,omitempty"`
+	ProjectRequisitionItemID *uint                   `gorm:"index" json:"project_requisition_item_id,omitempty"` // Project requisition line this order fulfils
+	ProjectRequisitionItem   *ProjectRequisitionItem `gorm:"foreignKey:ProjectRequisitionItemID;constraint:OnDelete:SET NULL" json:"project_requisition_item,omitempty"`
+	ProjectID                *uint                   `gorm:"index" json:"project_id,omitempty"` // Optional link to project
+	Project                  *Project                `gorm:"foreignKey:ProjectID;constraint:OnDelete:SET NULL" json:"project,omitempty"`
+	CostCenterID             *uint                   `gorm:"index" json:"cost_center_id,omitempty"` // Cost center charged
+	CostCenter               *CostCenter             `gorm:"foreignKey:CostCenterID;constraint:OnDelete:SET NULL" json:"cost_center,omitempty"`
+	GLAccountID              *uint                   `gorm:"index" json:"gl_account_id,omitempty"` // General-ledger account charged
+	GLAccount                *GLAccount              `gorm:"foreignKey:GLAccountID;constraint:OnDelete:SET NULL" json:"gl_account,omitempty"`
+	PONumber                 string                  `gorm:"uniqueIndex;not null;size:50" json:"po_number"`          // Generated or manual PO number
+	Status                   string                  `gorm:"size:20;not null;default:'pending';index" json:"status"` // pending, approved, rejected, ordered, shipped, received, cancelled
+	OrderDate                time.Time               `gorm:"not null;index" json:"order_date"`
+	ExpectedDelivery         *time.Time              `json:"expected_delivery,omitempty"`
+	ActualDelivery           *time.Time              `json:"actual_delivery,omitempty"`
+	Quantity                 int                     `gorm:"not null" json:"quantity"`        // Can order multiple units
+	UnitPrice                float64                 `gorm:"not null" json:"unit_price"`      // Price per unit in original currency
+	Currency                 string                  `gorm:"size:3;not null" json:"currency"` // Quote currency
+	TotalAmount              float64                 `gorm:"not null" json:"total_amount"`    // Total cost (unit_price * quantity)
+	ShippingCost             float64                 `json:"shipping_cost,omitempty"`
+	Tax                      float64                 `json:"tax,omitempty"`
+	GrandTotal               float64                 `gorm:"not null" json:"grand_total"` // total_amount + shipping_cost + tax
+	InvoiceNumber            string                  `gorm:"size:100" json:"invoice_number,omitempty"`
+	InvoiceDate              *time.Time              `json:"invoice_date,omitempty"` // When the invoice number was recorded
+	Notes                    string                  `gorm:"type:text" json:"notes,omitempty"`
 
 	// Relationships
 	Documents     []Document     `gorm:"-" json:"documents,omitempty"` // Polymorphic - query via EntityType="purchase_order" and EntityID=ID
@@ -223,6 +247,108 @@ type PurchaseOrder struct {
 	UpdatedBy string    `gorm:"size:100" json:"updated_by,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	DeletedAt DeletedAt `gorm:"index" json:"deleted_at,omitzero"`
+}
+
+// CostCenter is a cost center that purchases are charged to
+type CostCenter struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Code      string    `gorm:"uniqueIndex;not null;size:20" json:"code"`
+	Name      string    `gorm:"not null;size:100" json:"name"`
+	Active    bool      `gorm:"not null;default:true" json:"active"` // Inactive codes stay on old orders but cannot be used again
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GLAccount is a general-ledger account that purchases are booked to
+type GLAccount struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Code      string    `gorm:"uniqueIndex;not null;size:20" json:"code"`
+	Name      string    `gorm:"not null;size:100" json:"name"`
+	Active    bool      `gorm:"not null;default:true" json:"active"` // Inactive codes stay on old orders but cannot be used again
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Invoice is a vendor invoice or credit note, with its lines matched to the
+// purchase orders they bill for the three-way match of order, receipt and
+// invoice
+type Invoice struct {
+	ID             uint          `gorm:"primaryKey" json:"id"`
+	DocumentType   string        `gorm:"size:20;not null;default:'invoice'" json:"document_type"` // invoice or credit_note
+	InvoiceNumber  string        `gorm:"size:100;not null;index" json:"invoice_number"`
+	VendorID       *uint         `gorm:"index" json:"vendor_id,omitempty"` // Nil when the supplier matched no vendor
+	Vendor         *Vendor       `gorm:"foreignKey:VendorID;constraint:OnDelete:SET NULL" json:"vendor,omitempty"`
+	SupplierName   string        `gorm:"size:255" json:"supplier_name,omitempty"`   // As on the document
+	SupplierTaxID  string        `gorm:"size:50" json:"supplier_tax_id,omitempty"`  // As on the document
+	OrderReference string        `gorm:"size:100" json:"order_reference,omitempty"` // Buyer's order reference of the document
+	IssueDate      time.Time     `gorm:"not null;index" json:"issue_date"`
+	DueDate        *time.Time    `json:"due_date,omitempty"`
+	Currency       string        `gorm:"size:3;not null" json:"currency"`
+	NetAmount      float64       `gorm:"not null" json:"net_amount"`                               // Total without tax
+	TaxAmount      float64       `gorm:"not null" json:"tax_amount"`                               // Total tax
+	TotalAmount    float64       `gorm:"not null" json:"total_amount"`                             // Amount payable, or credited for credit notes
+	Status         string        `gorm:"size:20;not null;default:'unmatched';index" json:"status"` // matched or unmatched
+	Source         string        `gorm:"size:20" json:"source,omitempty"`                          // ubl
+	Lines          []InvoiceLine `gorm:"foreignKey:InvoiceID;constraint:OnDelete:CASCADE" json:"lines,omitempty"`
+
+	// Audit fields
+	CreatedBy string    `gorm:"size:100" json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// InvoiceLine is one line of an invoice or credit note
+type InvoiceLine struct {
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	InvoiceID       uint           `gorm:"not null;index" json:"invoice_id"`
+	LineNumber      string         `gorm:"size:50" json:"line_number"`               // As on the document
+	PurchaseOrderID *uint          `gorm:"index" json:"purchase_order_id,omitempty"` // Nil when the line matched no order
+	PurchaseOrder   *PurchaseOrder `gorm:"foreignKey:PurchaseOrderID;constraint:OnDelete:SET NULL" json:"purchase_order,omitempty"`
+	OrderReference  string         `gorm:"size:100" json:"order_reference,omitempty"`
+	SKU             string         `gorm:"size:100" json:"sku,omitempty"` // Seller's item ID
+	Description     string         `gorm:"type:text" json:"description,omitempty"`
+	Quantity        float64        `gorm:"not null" json:"quantity"`
+	UnitPrice       float64        `gorm:"not null" json:"unit_price"`
+	LineAmount      float64        `gorm:"not null" json:"line_amount"` // Without tax
+	Matched         bool           `gorm:"not null;default:false" json:"matched"`
+	MatchNote       string         `gorm:"type:text" json:"match_note,omitempty"` // Why the line did not match, or its differences from the order
+}
+
+// AccountingExport is one export of purchase orders or invoices to an
+// accounting package
+type AccountingExport struct {
+	ID         uint                    `gorm:"primaryKey" json:"id"`
+	Format     string                  `gorm:"size:20;not null" json:"format"` // journal, iif or xero
+	Source     string                  `gorm:"size:20;not null" json:"source"` // invoices or received
+	FromDate   time.Time               `gorm:"not null" json:"from_date"`
+	ToDate     time.Time               `gorm:"not null" json:"to_date"`
+	Records    int                     `gorm:"not null" json:"records"`
+	ExportedBy string                  `gorm:"size:100" json:"exported_by,omitempty"`
+	Entries    []AccountingExportEntry `gorm:"foreignKey:AccountingExportID;constraint:OnDelete:CASCADE" json:"entries,omitempty"`
+	CreatedAt  time.Time               `json:"created_at"`
+}
+
+// AccountingExportEntry marks a purchase order as exported from one source,
+// so that it is not exported twice
+type AccountingExportEntry struct {
+	ID                 uint           `gorm:"primaryKey" json:"id"`
+	AccountingExportID uint           `gorm:"not null;index" json:"accounting_export_id"`
+	PurchaseOrderID    uint           `gorm:"not null;uniqueIndex:idx_accounting_export_entries_order_source,priority:1" json:"purchase_order_id"`
+	PurchaseOrder      *PurchaseOrder `gorm:"foreignKey:PurchaseOrderID;constraint:OnDelete:CASCADE" json:"purchase_order,omitempty"`
+	Source             string         `gorm:"size:20;not null;uniqueIndex:idx_accounting_export_entries_order_source,priority:2" json:"source"`
+	CreatedAt          time.Time      `json:"created_at"`
+}
+
+// PONumberSequence holds the last number handed out by one automatic PO
+// numbering sequence. Scope is the PO number pattern with everything but the
+// sequence filled in, so a pattern with {YYYY} numbers each year from 1 and
+// one with {project} each project.
+type PONumberSequence struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Scope     string    `gorm:"uniqueIndex;not null;size:200" json:"scope"`
+	Value     int64     `gorm:"not null;default:0" json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // VendorRating represents performance ratings for vendors
@@ -243,6 +369,7 @@ type VendorRating struct {
 	RatedBy   string    `gorm:"size:100" json:"rated_by,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	DeletedAt DeletedAt `gorm:"index" json:"deleted_at,omitzero"`
 }
 
 // Forex represents currency exchange rates
@@ -264,10 +391,15 @@ type Project struct {
 	Budget          float64              `json:"budget,omitempty"`                         // Overall project budget
 	Deadline        *time.Time           `json:"deadline,omitempty"`                       // Project deadline
 	Status          string               `gorm:"size:20;default:'planning'" json:"status"` // planning, active, completed, cancelled
+	CostCenterID    *uint                `gorm:"index" json:"cost_center_id,omitempty"`    // Default cost center of its orders
+	CostCenter      *CostCenter          `gorm:"foreignKey:CostCenterID;constraint:OnDelete:SET NULL" json:"cost_center,omitempty"`
+	GLAccountID     *uint                `gorm:"index" json:"gl_account_id,omitempty"` // Default GL account of its orders
+	GLAccount       *GLAccount           `gorm:"foreignKey:GLAccountID;constraint:OnDelete:SET NULL" json:"gl_account,omitempty"`
 	BillOfMaterials *BillOfMaterials     `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"bill_of_materials,omitempty"`
 	Requisitions    []ProjectRequisition `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"requisitions,omitempty"` // Project-based requisitions
 	CreatedAt       time.Time            `json:"created_at"`
 	UpdatedAt       time.Time            `json:"updated_at"`
+	DeletedAt       DeletedAt            `gorm:"index" json:"deleted_at,omitzero"`
 }
 
 // BillOfMaterials represents the master list of specifications needed for a project
@@ -303,7 +435,10 @@ type ProjectRequisition struct {
 	Name          string                   `gorm:"not null" json:"name"`
 	Justification string                   `gorm:"type:text" json:"justification,omitempty"`
 	Budget        float64                  `json:"budget,omitempty"`
+	Status        string                   `gorm:"size:20;default:'draft'" json:"status"` // draft, submitted, approved, rejected
+	SubmittedBy   string                   `gorm:"size:100" json:"submitted_by,omitempty"`
 	Items         []ProjectRequisitionItem `gorm:"foreignKey:ProjectRequisitionID;constraint:OnDelete:CASCADE" json:"items,omitempty"`
+	Reviews       []RequisitionReview      `gorm:"foreignKey:ProjectRequisitionID;constraint:OnDelete:CASCADE" json:"reviews,omitempty"`
 	CreatedAt     time.Time                `json:"created_at"`
 	UpdatedAt     time.Time                `json:"updated_at"`
 }
@@ -368,6 +503,67 @@ func (ProjectRequisition) TableName() string          { return "project_requisit
 func (ProjectRequisitionItem) TableName() string      { return "project_requisition_items" }
 func (ProjectProcurementStrategy) TableName() string  { return "project_procurement_strategies" }
 func (Document) TableName() string                    { return "documents" }
+func (ImportProfile) TableName() string               { return "import_profiles" }
+func (ImportProfileField) TableName() string          { return "import_profile_fields" }
+func (User) TableName() string                        { return "users" }
+func (Session) TableName() string                     { return "sessions" }
+func (UserIdentity) TableName() string                { return "user_identities" }
+func (AuditLog) TableName() string                    { return "audit_logs" }
+func (ApprovalRule) TableName() string                { return "approval_rules" }
+func (PurchaseOrderApproval) TableName() string       { return "purchase_order_approvals" }
+func (RequisitionReview) TableName() string           { return "requisition_reviews" }
+func (Notification) TableName() string                { return "notifications" }
+func (PONumberSequence) TableName() string            { return "po_number_sequences" }
+func (CostCenter) TableName() string                  { return "cost_centers" }
+func (GLAccount) TableName() string                   { return "gl_accounts" }
+func (AccountingExport) TableName() string            { return "accounting_exports" }
+func (AccountingExportEntry) TableName() string       { return "accounting_export_entries" }
+func (Invoice) TableName() string                     { return "invoices" }
+func (InvoiceLine) TableName() string                 { return "invoice_lines" }
+
+// All returns every model, ordered so that referenced tables come before the
+// tables that reference them
+func All() []interface{} {
+	return []interface{}{
+		&CostCenter{},
+		&GLAccount{},
+		&Vendor{},
+		&Brand{},
+		&Specification{},
+		&SpecificationAttribute{},
+		&Product{},
+		&ProductAttribute{},
+		&Requisition{},
+		&RequisitionItem{},
+		&Quote{},
+		&Forex{},
+		&Project{},
+		&BillOfMaterials{},
+		&BillOfMaterialsItem{},
+		&ProjectRequisition{},
+		&ProjectRequisitionItem{},
+		&PurchaseOrder{},
+		&VendorRating{},
+		&ProjectProcurementStrategy{},
+		&Document{},
+		&ImportProfile{},
+		&ImportProfileField{},
+		&APIToken{},
+		&User{},
+		&Session{},
+		&UserIdentity{},
+		&AuditLog{},
+		&ApprovalRule{},
+		&PurchaseOrderApproval{},
+		&RequisitionReview{},
+		&Notification{},
+		&PONumberSequence{},
+		&AccountingExport{},
+		&AccountingExportEntry{},
+		&Invoice{},
+		&InvoiceLine{},
+	}
+}
 
 // Document represents file attachments for various entities
 type Document struct {
@@ -383,6 +579,158 @@ type Document struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// ImportProfile maps the columns of an external file, such as a vendor's price list,
+// to the fields of an import target (brands, vendors, products, quotes, forex)
+type ImportProfile struct {
+	ID        uint                 `gorm:"primaryKey" json:"id"`
+	Name      string               `gorm:"uniqueIndex;not null" json:"name"`
+	Entity    string               `gorm:"size:20;not null" json:"entity"` // brands, vendors, products, quotes, forex
+	VendorID  *uint                `gorm:"index" json:"vendor_id,omitempty"`
+	Vendor    *Vendor              `gorm:"foreignKey:VendorID;constraint:OnDelete:CASCADE" json:"vendor,omitempty"`
+	Sheet     string               `gorm:"size:100" json:"sheet,omitempty"` // Excel sheet to read (optional)
+	Fields    []ImportProfileField `gorm:"foreignKey:ProfileID;constraint:OnDelete:CASCADE" json:"fields"`
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// ImportProfileField maps one source column (or a constant) to a target field
+type ImportProfileField struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProfileID uint   `gorm:"not null;index" json:"profile_id"`
+	Field     string `gorm:"size:50;not null" json:"field"`       // Target field, e.g. "Price"
+	Column    string `gorm:"size:100" json:"column,omitempty"`    // Source column header, e.g. "Unit Cost"
+	Constant  string `gorm:"size:255" json:"constant,omitempty"`  // Fixed value used instead of a column
+	Transform string `gorm:"size:255" json:"transform,omitempty"` // Pipe-separated, e.g. "trim|multiply:1.2"
+}
+
+// APIToken is a personal access token or service account token for the web
+// server. Only the SHA-256 hash of the token is stored; the token itself is
+// shown once, when it is created.
+type APIToken struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Name           string     `gorm:"size:100;not null" json:"name"`
+	Owner          string     `gorm:"size:100;not null;index" json:"owner"` // User or service account name
+	ServiceAccount bool       `gorm:"not null;default:false" json:"service_account"`
+	Prefix         string     `gorm:"size:16;not null" json:"prefix"` // Leading characters of the token, to identify it
+	TokenHash      string     `gorm:"size:64;uniqueIndex;not null" json:"token_hash"`
+	Scopes         string     `gorm:"size:255;not null" json:"scopes"` // Comma-separated, e.g. "read,quotes:write"
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// User is a person who signs in to the web interface. Role decides what the
+// user may change; see services.RolePermissions.
+type User struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Username     string     `gorm:"size:100;uniqueIndex;not null" json:"username"`
+	Name         string     `gorm:"size:200" json:"name,omitempty"`
+	Email        string     `gorm:"size:255" json:"email,omitempty"`
+	Role         string     `gorm:"size:20;not null" json:"role"` // requester, buyer, approver, finance, admin
+	PasswordHash string     `gorm:"size:100" json:"password_hash,omitempty"`
+	Disabled     bool       `gorm:"not null;default:false" json:"disabled"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Session is a signed-in browser session. Only the SHA-256 hash of the
+// session cookie is stored.
+type Session struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	TokenHash  string    `gorm:"size:64;uniqueIndex;not null" json:"token_hash"`
+	UserID     uint      `gorm:"not null;index" json:"user_id"`
+	User       *User     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	ExpiresAt  time.Time `gorm:"not null" json:"expires_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// UserIdentity links a user to an account at an external identity provider,
+// so single sign-on finds the same user by the provider's stable subject
+// even when the username claim changes
+type UserIdentity struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;index" json:"user_id"`
+	User        *User     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	Issuer      string    `gorm:"size:255;not null;uniqueIndex:idx_user_identity_subject" json:"issuer"`
+	Subject     string    `gorm:"size:255;not null;uniqueIndex:idx_user_identity_subject" json:"subject"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AuditLog records one create, update or delete of a row. Changes holds a
+// JSON object mapping each changed column to its old and new values.
+type AuditLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Entity    string    `gorm:"size:50;not null;index:idx_audit_entity" json:"entity"` // Model name in snake case, e.g. purchase_order
+	EntityID  uint      `gorm:"not null;index:idx_audit_entity" json:"entity_id"`
+	Action    string    `gorm:"size:10;not null" json:"action"` // create, update, delete, restore
+	Actor     string    `gorm:"size:100" json:"actor,omitempty"`
+	Source    string    `gorm:"size:10" json:"source,omitempty"` // cli, web, api
+	Changes   string    `gorm:"type:text" json:"changes"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// ApprovalRule requires an approval step for purchase orders whose grand
+// total, in the base currency, is at least MinAmount. Steps are approved in
+// order. A rule for a requisition or a project replaces the general rules of
+// its step for the purchase orders of that requisition or project.
+type ApprovalRule struct {
+	ID            uint         `gorm:"primaryKey" json:"id"`
+	Step          int          `gorm:"not null;default:1" json:"step"`
+	MinAmount     float64      `gorm:"not null;default:0" json:"min_amount"` // In the base currency (USD)
+	Approvers     string       `gorm:"size:500" json:"approvers,omitempty"`  // Comma-separated usernames; empty allows any approver
+	ProjectID     *uint        `gorm:"index" json:"project_id,omitempty"`
+	Project       *Project     `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"project,omitempty"`
+	RequisitionID *uint        `gorm:"index" json:"requisition_id,omitempty"`
+	Requisition   *Requisition `gorm:"foreignKey:RequisitionID;constraint:OnDelete:CASCADE" json:"requisition,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// PurchaseOrderApproval records an approver's decision on one approval step
+// of a purchase order
+type PurchaseOrderApproval struct {
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	PurchaseOrderID uint           `gorm:"not null;index" json:"purchase_order_id"`
+	PurchaseOrder   *PurchaseOrder `gorm:"foreignKey:PurchaseOrderID;constraint:OnDelete:CASCADE" json:"purchase_order,omitempty"`
+	Step            int            `gorm:"not null" json:"step"`
+	Approver        string         `gorm:"size:100;not null" json:"approver"`
+	Decision        string         `gorm:"size:10;not null" json:"decision"` // approved, rejected
+	Comment         string         `gorm:"type:text" json:"comment,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+}
+
+// RequisitionReview records one step in the review of a requisition or a
+// project requisition: its submission, approval or rejection. Exactly one of
+// RequisitionID and ProjectRequisitionID is set.
+type RequisitionReview struct {
+	ID                   uint                `gorm:"primaryKey" json:"id"`
+	RequisitionID        *uint               `gorm:"index" json:"requisition_id,omitempty"`
+	Requisition          *Requisition        `gorm:"foreignKey:RequisitionID;constraint:OnDelete:CASCADE" json:"requisition,omitempty"`
+	ProjectRequisitionID *uint               `gorm:"index" json:"project_requisition_id,omitempty"`
+	ProjectRequisition   *ProjectRequisition `gorm:"foreignKey:ProjectRequisitionID;constraint:OnDelete:CASCADE" json:"project_requisition,omitempty"`
+	Action               string              `gorm:"size:20;not null" json:"action"` // submitted, approved, rejected
+	Actor                string              `gorm:"size:100;not null" json:"actor"`
+	Comment              string              `gorm:"type:text" json:"comment,omitempty"`
+	Estimate             float64             `json:"estimate,omitempty"`                         // Best-case estimate in the base currency (USD) when approved
+	OverrideReason       string              `gorm:"type:text" json:"override_reason,omitempty"` // Why an approval over budget went ahead
+	CreatedAt            time.Time           `json:"created_at"`
+}
+
+// Notification is a message for one user, such as a requisition waiting for
+// their review
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Recipient string     `gorm:"size:100;not null;index" json:"recipient"` // Username
+	Message   string     `gorm:"size:500;not null" json:"message"`
+	Link      string     `gorm:"size:200" json:"link,omitempty"` // Web interface path
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+}
+
 // BeforeSave hook for RequisitionItem - validates constraints
 func (ri *RequisitionItem) BeforeSave(tx *gorm.DB) error {
 	// Validate positive quantity
@@ -410,6 +758,18 @@ func (p *Product) BeforeSave(tx *gorm.DB) error {
 		return fmt.Errorf("product lead time days cannot be negative, got %d", p.LeadTimeDays)
 	}
 
+	if actor := statementActor(tx); actor != "" {
+		p.UpdatedBy = actor
+	}
+
+	return nil
+}
+
+// BeforeCreate hook for Product - records the creating user
+func (p *Product) BeforeCreate(tx *gorm.DB) error {
+	if actor := statementActor(tx); actor != "" && p.CreatedBy == "" {
+		p.CreatedBy = actor
+	}
 	return nil
 }
 
@@ -421,11 +781,15 @@ func (v *Vendor) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
-// BeforeCreate hook for Quote - sets quote_date to now if not set
+// BeforeCreate hook for Quote - sets quote_date to now if not set and records
+// the creating user
 func (q *Quote) BeforeCreate(tx *gorm.DB) error {
 	if q.QuoteDate.IsZero() {
 		q.QuoteDate = time.Now()
 	}
+	if actor := statementActor(tx); actor != "" && q.CreatedBy == "" {
+		q.CreatedBy = actor
+	}
 	return nil
 }
 
@@ -456,6 +820,10 @@ func (q *Quote) BeforeSave(tx *gorm.DB) error {
 		return fmt.Errorf("quote minimum quantity cannot be negative, got %d", q.MinQuantity)
 	}
 
+	if actor := statementActor(tx); actor != "" {
+		q.UpdatedBy = actor
+	}
+
 	return nil
 }
 
@@ -485,11 +853,15 @@ func (p *Project) BeforeSave(tx *gorm.DB) error {
 	return nil
 }
 
-// BeforeCreate hook for PurchaseOrder - sets defaults
+// BeforeCreate hook for PurchaseOrder - sets defaults and records the
+// creating user
 func (po *PurchaseOrder) BeforeCreate(tx *gorm.DB) error {
 	if po.OrderDate.IsZero() {
 		po.OrderDate = time.Now()
 	}
+	if actor := statementActor(tx); actor != "" && po.CreatedBy == "" {
+		po.CreatedBy = actor
+	}
 	if po.Status == "" {
 		po.Status = "pending"
 	}
@@ -504,11 +876,11 @@ func (po *PurchaseOrder) BeforeCreate(tx *gorm.DB) error {
 func (po *PurchaseOrder) BeforeSave(tx *gorm.DB) error {
 	// Validate status enum
 	validStatuses := map[string]bool{
-		"pending": true, "approved": true, "ordered": true,
+		"pending": true, "approved": true, "rejected": true, "ordered": true,
 		"shipped": true, "received": true, "cancelled": true,
 	}
 	if po.Status != "" && !validStatuses[po.Status] {
-		return fmt.Errorf("invalid purchase order status: %s (must be one of: pending, approved, ordered, shipped, received, cancelled)", po.Status)
+		return fmt.Errorf("invalid purchase order status: %s (must be one of: pending, approved, rejected, ordered, shipped, received, cancelled)", po.Status)
 	}
 
 	// Validate positive quantity
@@ -533,6 +905,10 @@ func (po *PurchaseOrder) BeforeSave(tx *gorm.DB) error {
 	// Note: We don't validate that actual delivery must be after expected delivery
 	// because items can arrive early, and that's a valid scenario
 
+	if actor := statementActor(tx); actor != "" {
+		po.UpdatedBy = actor
+	}
+
 	return nil
 }
 
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -418,3 +419,47 @@ func TestPreloadRelationships(t *testing.T) {
 		t.Errorf("Expected specification name 'Laptop', got '%s'", loadedProject.BillOfMaterials.Items[0].Specification.Name)
 	}
 }
+
+func TestActorRecordedOnSave(t *testing.T) {
+	db := setupTestDB(t)
+
+	vendor := &Vendor{Name: "Acme", Currency: "USD"}
+	brand := &Brand{Name: "Acme"}
+	if err := db.Create(vendor).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(brand).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Without an actor nothing is recorded
+	product := &Product{Name: "Widget", BrandID: brand.ID}
+	if err := db.Create(product).Error; err != nil {
+		t.Fatal(err)
+	}
+	if product.CreatedBy != "" || product.UpdatedBy != "" {
+		t.Errorf("Expected no actor, got %q/%q", product.CreatedBy, product.UpdatedBy)
+	}
+
+	alice := db.WithContext(WithActor(context.Background(), "alice"))
+	quote := &Quote{VendorID: vendor.ID, ProductID: product.ID, Price: 10, Currency: "USD", ConvertedPrice: 10, ConversionRate: 1}
+	if err := alice.Create(quote).Error; err != nil {
+		t.Fatal(err)
+	}
+	if quote.CreatedBy != "alice" || quote.UpdatedBy != "alice" {
+		t.Errorf("Expected alice to create the quote, got %q/%q", quote.CreatedBy, quote.UpdatedBy)
+	}
+
+	bob := db.WithContext(WithActor(context.Background(), "bob"))
+	quote.Notes = "revised"
+	if err := bob.Save(quote).Error; err != nil {
+		t.Fatal(err)
+	}
+	var saved Quote
+	if err := db.First(&saved, quote.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved.CreatedBy != "alice" || saved.UpdatedBy != "bob" {
+		t.Errorf("Expected created by alice and updated by bob, got %q/%q", saved.CreatedBy, saved.UpdatedBy)
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/shakfu/buyer/internal/openapi"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// DeletedAt marks a record as moved to the trash. Queries and updates leave
+// out trashed records, as with gorm.DeletedAt; use Unscoped to reach them.
+//
+// Unlike gorm.DeletedAt, Delete still removes the row, so that database
+// constraints keep working and purging the trash is a plain delete. Records
+// are moved to the trash by the Delete methods of the services.
+type DeletedAt sql.NullTime
+
+// Scan implements the sql.Scanner interface
+func (n *DeletedAt) Scan(value interface{}) error {
+	return (*sql.NullTime)(n).Scan(value)
+}
+
+// Value implements the driver.Valuer interface
+func (n DeletedAt) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Time, nil
+}
+
+// IsZero reports whether the record is not in the trash
+func (n DeletedAt) IsZero() bool {
+	return !n.Valid
+}
+
+// MarshalJSON encodes the deletion time, or null
+func (n DeletedAt) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.Time)
+}
+
+// UnmarshalJSON decodes a deletion time or null
+func (n *DeletedAt) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*n = DeletedAt{}
+		return nil
+	}
+	var t time.Time
+	if err := json.Unmarshal(b, &t); err != nil {
+		return err
+	}
+	*n = DeletedAt{Time: t, Valid: true}
+	return nil
+}
+
+// OpenAPISchema describes DeletedAt as a nullable date-time
+func (DeletedAt) OpenAPISchema() *openapi.Schema {
+	return &openapi.Schema{Type: "string", Format: "date-time", Nullable: true}
+}
+
+// QueryClauses leaves trashed records out of queries
+func (DeletedAt) QueryClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{gorm.SoftDeleteQueryClause{Field: f}}
+}
+
+// UpdateClauses leaves trashed records out of updates
+func (DeletedAt) UpdateClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{gorm.SoftDeleteUpdateClause{Field: f}}
+}
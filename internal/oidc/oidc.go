@@ -0,0 +1,233 @@
+// Package oidc implements the relying party side of OpenID Connect: provider
+// discovery, the authorization code flow with PKCE, and ID token
+// verification. Only the standard library is used; ID tokens must be signed
+// with RS256, RS384, RS512, ES256 or ES384.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultScopes are requested when Config.Scopes is empty
+var DefaultScopes = []string{"openid", "profile", "email"}
+
+// Config describes the client registered with an OpenID provider
+type Config struct {
+	Issuer       string       // Issuer URL; discovery reads Issuer/.well-known/openid-configuration
+	ClientID     string       // Client ID registered with the provider
+	ClientSecret string       // Client secret; empty for public clients, which rely on PKCE alone
+	RedirectURL  string       // Callback URL registered with the provider
+	Scopes       []string     // Requested scopes; "openid" is always included
+	HTTPClient   *http.Client // Client for discovery, token and key requests; defaults to one with a 10s timeout
+}
+
+// Metadata is the part of the provider's discovery document the client uses
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint,omitempty"`
+}
+
+// Provider is a discovered OpenID provider
+type Provider struct {
+	config   Config
+	metadata Metadata
+	keys     *keySet
+}
+
+// Discover fetches the provider's discovery document and checks that it is
+// for the configured issuer
+func Discover(ctx context.Context, config Config) (*Provider, error) {
+	if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, errors.New("oidc: issuer, client ID and redirect URL are required")
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	config.Scopes = withOpenID(config.Scopes)
+
+	wellKnown := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	var metadata Metadata
+	if err := getJSON(ctx, config.HTTPClient, wellKnown, &metadata); err != nil {
+		return nil, fmt.Errorf("oidc: discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(metadata.Issuer, "/") != strings.TrimSuffix(config.Issuer, "/") {
+		return nil, fmt.Errorf("oidc: discovery document is for issuer %q, not %q", metadata.Issuer, config.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery document lacks the authorization, token or jwks endpoint")
+	}
+
+	return &Provider{
+		config:   config,
+		metadata: metadata,
+		keys:     &keySet{uri: metadata.JWKSURI, client: config.HTTPClient},
+	}, nil
+}
+
+// Metadata returns the provider's discovery document
+func (p *Provider) Metadata() Metadata {
+	return p.metadata
+}
+
+// AuthCodeURL returns the URL of the provider's sign-in page. The state and
+// nonce are checked on the way back; challenge is the PKCE code challenge of
+// the verifier later passed to Exchange.
+func (p *Provider) AuthCodeURL(state, nonce, challenge string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	return appendQuery(p.metadata.AuthorizationEndpoint, query)
+}
+
+// EndSessionURL returns the provider's logout URL, which sends the browser
+// back to postLogoutRedirect, or "" if the provider does not support
+// RP-initiated logout
+func (p *Provider) EndSessionURL(postLogoutRedirect string) string {
+	if p.metadata.EndSessionEndpoint == "" {
+		return ""
+	}
+	query := url.Values{"client_id": {p.config.ClientID}}
+	if postLogoutRedirect != "" {
+		query.Set("post_logout_redirect_uri", postLogoutRedirect)
+	}
+	return appendQuery(p.metadata.EndSessionEndpoint, query)
+}
+
+// Token is the response of the token endpoint
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Exchange trades an authorization code and its PKCE verifier for tokens
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (*Token, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: token request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: token request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var problem struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &problem) == nil && problem.Error != "" {
+			return nil, fmt.Errorf("oidc: token request refused: %s %s", problem.Error, problem.Description)
+		}
+		return nil, fmt.Errorf("oidc: token request failed with status %d", resp.StatusCode)
+	}
+
+	var token Token
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("oidc: invalid token response: %w", err)
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("oidc: token response has no id_token")
+	}
+	return &token, nil
+}
+
+// NewPKCE returns a random PKCE code verifier and its S256 code challenge
+func NewPKCE() (verifier, challenge string) {
+	verifier = RandomString()
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// RandomString returns 32 random bytes, base64url encoded, for use as a
+// state, nonce or PKCE verifier
+func RandomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("oidc: failed to read random bytes: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// withOpenID returns scopes, or DefaultScopes if empty, starting with "openid"
+func withOpenID(scopes []string) []string {
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+	result := []string{"openid"}
+	for _, scope := range scopes {
+		if scope != "openid" && scope != "" {
+			result = append(result, scope)
+		}
+	}
+	return result
+}
+
+// appendQuery adds query to endpoint, keeping any query it already has
+func appendQuery(endpoint string, query url.Values) string {
+	separator := "?"
+	if strings.Contains(endpoint, "?") {
+		separator = "&"
+	}
+	return endpoint + separator + query.Encode()
+}
+
+// getJSON decodes the JSON document at url into v
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
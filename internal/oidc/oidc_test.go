@@ -0,0 +1,159 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shakfu/buyer/internal/oidc"
+	"github.com/shakfu/buyer/internal/oidc/oidctest"
+)
+
+const redirectURL = "http://buyer.test/auth/oidc/callback"
+
+func discover(t *testing.T, issuer *oidctest.Issuer) *oidc.Provider {
+	t.Helper()
+	provider, err := oidc.Discover(context.Background(), oidc.Config{
+		Issuer:       issuer.URL,
+		ClientID:     issuer.ClientID,
+		ClientSecret: issuer.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"profile", "groups"},
+	})
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	return provider
+}
+
+// authorize follows the sign-in URL and returns the code and state the
+// issuer redirects back with
+func authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected a redirect from the authorization endpoint, got %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || !strings.HasPrefix(location.String(), redirectURL) {
+		t.Fatalf("Expected a redirect to the callback, got %q", resp.Header.Get("Location"))
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	for _, secret := range []string{"s3cret", ""} {
+		t.Run("secret="+secret, func(t *testing.T) {
+			issuer := oidctest.New("buyer", secret)
+			defer issuer.Close()
+			issuer.SetUser("user-1", map[string]interface{}{
+				"preferred_username": "alice",
+				"groups":             []string{"purchasing", "staff"},
+			})
+			provider := discover(t, issuer)
+
+			verifier, challenge := oidc.NewPKCE()
+			authURL := provider.AuthCodeURL("state-1", "nonce-1", challenge)
+			if !strings.Contains(authURL, "scope=openid+profile+groups") {
+				t.Errorf("Expected openid to lead the requested scopes: %s", authURL)
+			}
+			code, state := authorize(t, authURL)
+			if state != "state-1" {
+				t.Errorf("Expected the state to come back, got %q", state)
+			}
+
+			if _, err := provider.Exchange(context.Background(), code, "wrong-verifier"); err == nil {
+				t.Error("Expected a wrong PKCE verifier to be refused")
+			}
+			code, _ = authorize(t, authURL)
+			token, err := provider.Exchange(context.Background(), code, verifier)
+			if err != nil {
+				t.Fatalf("Exchange failed: %v", err)
+			}
+			idToken, err := provider.VerifyIDToken(context.Background(), token.IDToken, "nonce-1")
+			if err != nil {
+				t.Fatalf("VerifyIDToken failed: %v", err)
+			}
+			if idToken.Subject != "user-1" || idToken.StringClaim("preferred_username") != "alice" {
+				t.Errorf("Unexpected claims: %+v", idToken.Claims)
+			}
+			if groups := idToken.StringsClaim("groups"); len(groups) != 2 || groups[0] != "purchasing" {
+				t.Errorf("Expected two groups, got %v", groups)
+			}
+		})
+	}
+}
+
+func TestVerifyIDToken_Rejects(t *testing.T) {
+	issuer := oidctest.New("buyer", "")
+	defer issuer.Close()
+	provider := discover(t, issuer)
+
+	valid := issuer.Claims("user-1", "nonce-1", nil)
+	with := func(name string, value interface{}) map[string]interface{} {
+		claims := issuer.Claims("user-1", "nonce-1", nil)
+		claims[name] = value
+		return claims
+	}
+	signed := issuer.SignIDToken(valid)
+
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+	}{
+		{"wrong nonce", signed, "nonce-2"},
+		{"expired", issuer.SignIDToken(with("exp", time.Now().Add(-time.Hour).Unix())), "nonce-1"},
+		{"other audience", issuer.SignIDToken(with("aud", "someone-else")), "nonce-1"},
+		{"other issuer", issuer.SignIDToken(with("iss", "https://evil.example")), "nonce-1"},
+		{"no subject", issuer.SignIDToken(with("sub", "")), "nonce-1"},
+		{"tampered", signed[:strings.LastIndex(signed, ".")] + ".AAAA", "nonce-1"},
+		{"unsigned", "eyJhbGciOiJub25lIn0." + strings.Split(signed, ".")[1] + ".", "nonce-1"},
+		{"malformed", "not-a-jwt", "nonce-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := provider.VerifyIDToken(context.Background(), tt.token, tt.nonce); !errors.Is(err, oidc.ErrInvalidIDToken) {
+				t.Errorf("Expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+
+	if _, err := provider.VerifyIDToken(context.Background(), signed, "nonce-1"); err != nil {
+		t.Errorf("Expected the untouched token to verify, got %v", err)
+	}
+}
+
+func TestDiscover_IssuerMismatch(t *testing.T) {
+	issuer := oidctest.New("buyer", "")
+	defer issuer.Close()
+
+	_, err := oidc.Discover(context.Background(), oidc.Config{
+		Issuer:      strings.Replace(issuer.URL, "127.0.0.1", "localhost", 1),
+		ClientID:    "buyer",
+		RedirectURL: redirectURL,
+	})
+	if err == nil {
+		t.Error("Expected a discovery document for another issuer to be refused")
+	}
+}
+
+func TestEndSessionURL(t *testing.T) {
+	issuer := oidctest.New("buyer", "")
+	defer issuer.Close()
+	provider := discover(t, issuer)
+
+	got := provider.EndSessionURL("http://buyer.test/login")
+	if !strings.HasPrefix(got, issuer.URL+"/logout?") || !strings.Contains(got, "post_logout_redirect_uri=http%3A%2F%2Fbuyer.test%2Flogin") {
+		t.Errorf("Unexpected end session URL %q", got)
+	}
+}
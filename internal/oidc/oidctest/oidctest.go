@@ -0,0 +1,244 @@
+// Package oidctest provides an in-process OpenID provider for tests. It
+// serves discovery, authorization, token, key and logout endpoints from an
+// httptest.Server, signs ID tokens with a fresh RSA key, and signs in
+// whichever user the test last set with SetUser.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// keyID names the issuer's signing key in its JWKS
+const keyID = "oidctest-1"
+
+// Issuer is a running mock OpenID provider
+type Issuer struct {
+	URL          string
+	ClientID     string
+	ClientSecret string // Empty to accept a public client
+
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu      sync.Mutex
+	subject string
+	claims  map[string]interface{}
+	grants  map[string]grant
+}
+
+// grant is an issued authorization code waiting to be exchanged
+type grant struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	subject     string
+	claims      map[string]interface{}
+}
+
+// New starts an issuer for the given client. Close it when done.
+func New(clientID, clientSecret string) *Issuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	issuer := &Issuer{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		grants:       map[string]grant{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.discovery)
+	mux.HandleFunc("/authorize", issuer.authorize)
+	mux.HandleFunc("/token", issuer.token)
+	mux.HandleFunc("/jwks", issuer.jwks)
+	mux.HandleFunc("/logout", issuer.logout)
+	issuer.server = httptest.NewServer(mux)
+	issuer.URL = issuer.server.URL
+	return issuer
+}
+
+// Close shuts the issuer down
+func (i *Issuer) Close() {
+	i.server.Close()
+}
+
+// SetUser chooses who signs in at the authorization endpoint: the subject
+// and any extra ID token claims, such as preferred_username or groups
+func (i *Issuer) SetUser(subject string, claims map[string]interface{}) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.subject = subject
+	i.claims = claims
+}
+
+// SignIDToken returns an RS256 ID token with the given claims, signed with
+// the issuer's key
+func (i *Issuer) SignIDToken(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// Claims returns standard claims for subject issued now, merged with extra
+func (i *Issuer) Claims(subject, nonce string, extra map[string]interface{}) map[string]interface{} {
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": i.URL,
+		"sub": subject,
+		"aud": i.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	for name, value := range extra {
+		claims[name] = value
+	}
+	return claims
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                i.URL,
+		"authorization_endpoint":                i.URL + "/authorize",
+		"token_endpoint":                        i.URL + "/token",
+		"jwks_uri":                              i.URL + "/jwks",
+		"end_session_endpoint":                  i.URL + "/logout",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorize signs in the current user without a prompt and redirects back
+// with a code
+func (i *Issuer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI := query.Get("redirect_uri")
+	switch {
+	case query.Get("client_id") != i.ClientID:
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	case query.Get("response_type") != "code" || redirectURI == "":
+		http.Error(w, "unsupported request", http.StatusBadRequest)
+		return
+	case query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "":
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	i.mu.Lock()
+	code := randomString()
+	i.grants[code] = grant{
+		redirectURI: redirectURI,
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		subject:     i.subject,
+		claims:      i.claims,
+	}
+	i.mu.Unlock()
+
+	target, _ := url.Parse(redirectURI)
+	values := target.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	target.RawQuery = values.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// token exchanges a code for an ID token after checking the client and the
+// PKCE verifier
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+	}
+	if clientID != i.ClientID || secret != i.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	i.mu.Lock()
+	code := r.PostForm.Get("code")
+	g, found := i.grants[code]
+	delete(i.grants, code)
+	i.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case !found || g.redirectURI != r.PostForm.Get("redirect_uri"):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     i.SignIDToken(i.Claims(g.subject, g.nonce, g.claims)),
+	})
+}
+
+func (i *Issuer) jwks(w http.ResponseWriter, r *http.Request) {
+	public := i.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}},
+	})
+}
+
+func (i *Issuer) logout(w http.ResponseWriter, r *http.Request) {
+	if target := r.URL.Query().Get("post_logout_redirect_uri"); target != "" {
+		http.Redirect(w, r, target, http.StatusFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,304 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // SHA-256 for RS256 and ES256
+	_ "crypto/sha512" // SHA-384 and SHA-512 for RS384, RS512 and ES384
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockSkew is the leeway allowed when checking token times
+const clockSkew = time.Minute
+
+// ErrInvalidIDToken is wrapped by every ID token verification failure
+var ErrInvalidIDToken = errors.New("oidc: invalid ID token")
+
+// IDToken is a verified ID token
+type IDToken struct {
+	Issuer   string
+	Subject  string
+	Audience []string
+	Expiry   time.Time
+	IssuedAt time.Time
+	Nonce    string
+	Claims   map[string]interface{} // Every claim of the token, including the ones above
+}
+
+// StringClaim returns a string claim, or "" if it is missing or not a string
+func (t *IDToken) StringClaim(name string) string {
+	value, _ := t.Claims[name].(string)
+	return value
+}
+
+// StringsClaim returns a claim holding a list of strings. A single string is
+// returned as a list of one, as some providers do for one-group users.
+func (t *IDToken) StringsClaim(name string) []string {
+	switch value := t.Claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// VerifyIDToken checks the signature of a raw ID token against the
+// provider's keys, and that it was issued by the provider for this client,
+// has not expired, and carries the expected nonce
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*IDToken, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed JWT", ErrInvalidIDToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: bad header: %v", ErrInvalidIDToken, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad signature encoding", ErrInvalidIDToken)
+	}
+	key, err := p.keys.get(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: bad claims: %v", ErrInvalidIDToken, err)
+	}
+	token := &IDToken{Claims: claims}
+	token.Issuer, _ = claims["iss"].(string)
+	token.Subject, _ = claims["sub"].(string)
+	token.Nonce, _ = claims["nonce"].(string)
+	token.Audience = token.StringsClaim("aud")
+	token.Expiry = numericDate(claims["exp"])
+	token.IssuedAt = numericDate(claims["iat"])
+
+	now := time.Now()
+	switch {
+	case token.Issuer != p.metadata.Issuer:
+		return nil, fmt.Errorf("%w: issued by %q, not %q", ErrInvalidIDToken, token.Issuer, p.metadata.Issuer)
+	case !contains(token.Audience, p.config.ClientID):
+		return nil, fmt.Errorf("%w: not issued for client %q", ErrInvalidIDToken, p.config.ClientID)
+	case len(token.Audience) > 1 && claims["azp"] != p.config.ClientID:
+		return nil, fmt.Errorf("%w: authorized party is not client %q", ErrInvalidIDToken, p.config.ClientID)
+	case token.Subject == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	case token.Expiry.IsZero() || now.After(token.Expiry.Add(clockSkew)):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	case token.IssuedAt.After(now.Add(clockSkew)):
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidIDToken)
+	case nonce != "" && token.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidIDToken)
+	}
+	return token, nil
+}
+
+// verifySignature checks a JWS signature made with the given algorithm
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("algorithm %s does not match an RSA key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(key, hash, digest, signature); err != nil {
+			return errors.New("bad signature")
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(signature) != 2*size {
+			return fmt.Errorf("algorithm %s does not match an EC key", alg)
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return errors.New("bad signature")
+		}
+	default:
+		return errors.New("unsupported key type")
+	}
+	return nil
+}
+
+// keySet caches the provider's signing keys, refetching them when a token
+// names a key it has not seen, so key rotation needs no restart
+type keySet struct {
+	uri    string
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// minRefetch limits how often an unknown key ID triggers a refetch
+const minRefetch = 30 * time.Second
+
+// get returns the key with the given ID. An empty ID matches the only key of
+// a single-key set.
+func (k *keySet) get(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if key, ok := k.lookup(kid); ok {
+		return key, nil
+	}
+	if time.Since(k.fetched) >= minRefetch || k.keys == nil {
+		if err := k.fetch(ctx); err != nil {
+			return nil, err
+		}
+		if key, ok := k.lookup(kid); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidIDToken, kid)
+}
+
+func (k *keySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key, true
+		}
+	}
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+func (k *keySet) fetch(ctx context.Context) error {
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, k.client, k.uri, &document); err != nil {
+		return fmt.Errorf("oidc: fetching signing keys failed: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(document.Keys))
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue // Skip key types this package does not verify
+		}
+		keys[jwk.Kid] = key
+	}
+	k.keys = keys
+	k.fetched = time.Now()
+	return nil
+}
+
+// jsonWebKey is an RSA or EC public key in JWK form (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("bad RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if errX != nil || errY != nil {
+			return nil, errors.New("bad EC point")
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+// decodeSegment decodes a base64url JSON segment of a JWT
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// numericDate converts a JWT NumericDate claim to a time
+func numericDate(value interface{}) time.Time {
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(seconds), 0)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
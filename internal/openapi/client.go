@@ -0,0 +1,268 @@
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// GenerateClient writes Go source for one method per operation of the
+// document on a Client type in package pkg. The hand-written part of the
+// package provides Client.do, Client.upload, Client.download and ListParams.
+func GenerateClient(doc *Document, pkg string) ([]byte, error) {
+	packages := make(map[string]string)
+	for _, schema := range doc.Components.Schemas {
+		if schema.GoPackage != "" {
+			packages[packageName(schema.GoPackage)] = schema.GoPackage
+		}
+	}
+
+	var body bytes.Buffer
+	imports := map[string]bool{"context": true}
+	for _, op := range doc.Operations() {
+		if err := writeClientMethod(&body, doc, op, imports); err != nil {
+			return nil, err
+		}
+	}
+
+	qualified := regexp.MustCompile(`\b([a-z][a-z0-9]*)\.[A-Z]`)
+	for _, match := range qualified.FindAllStringSubmatch(body.String(), -1) {
+		if path, ok := packages[match[1]]; ok {
+			imports[path] = true
+		}
+	}
+	if strings.Contains(body.String(), "time.Time") {
+		imports["time"] = true
+	}
+	paths := make([]string, 0, len(imports))
+	for path := range imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated from the buyer OpenAPI document. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\nimport (\n", pkg)
+	// Standard library first, then module packages
+	for _, std := range []bool{true, false} {
+		for _, path := range paths {
+			first, _, _ := strings.Cut(path, "/")
+			if strings.Contains(first, ".") != std {
+				fmt.Fprintf(&src, "\t%q\n", path)
+			}
+		}
+		if std {
+			src.WriteString("\n")
+		}
+	}
+	src.WriteString(")\n")
+	src.Write(body.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated client does not compile: %w\n%s", err, src.Bytes())
+	}
+	return formatted, nil
+}
+
+// writeClientMethod writes the client method for one operation
+func writeClientMethod(w *bytes.Buffer, doc *Document, op OperationRef, imports map[string]bool) error {
+	args := []string{"ctx context.Context"}
+	var pathArgs []string
+	list := false
+	var queryParams []Parameter
+	for _, param := range op.Parameters {
+		switch {
+		case param.In == "path":
+			args = append(args, goIdent(param.Name)+" uint")
+			pathArgs = append(pathArgs, goIdent(param.Name))
+		case isListParameter(param.Name):
+			list = true
+		default:
+			queryParams = append(queryParams, param)
+			args = append(args, goIdent(param.Name)+" string")
+		}
+	}
+	if list {
+		args = append(args, "params ListParams")
+	}
+	filePart := ""
+	if op.RequestBody != nil {
+		var schema *Schema
+		if media, ok := op.RequestBody.Content[MultipartContentType]; ok {
+			// The form fields, then an object with the file part
+			if len(media.Schema.AllOf) != 2 || len(media.Schema.AllOf[1].Required) != 1 {
+				return fmt.Errorf("%s: multipart body is not fields and a file", op.OperationID)
+			}
+			schema, filePart = media.Schema.AllOf[0], media.Schema.AllOf[1].Required[0]
+		} else if media, ok := op.RequestBody.Content["application/json"]; ok {
+			schema = media.Schema
+		} else {
+			return fmt.Errorf("%s: request body is not JSON or multipart", op.OperationID)
+		}
+		bodyType, err := goType(doc, schema)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op.OperationID, err)
+		}
+		args = append(args, "body "+bodyType)
+		if filePart != "" {
+			imports["io"] = true
+			args = append(args, "fileName string", "file io.Reader")
+		}
+	}
+
+	resultType := ""
+	download := false
+	for code, response := range op.Responses {
+		if !strings.HasPrefix(code, "2") || len(response.Content) == 0 {
+			continue
+		}
+		if _, ok := response.Content[FileContentType]; ok {
+			imports["io"] = true
+			download = true
+			continue
+		}
+		t, err := goType(doc, response.Content["application/json"].Schema)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op.OperationID, err)
+		}
+		resultType = t
+	}
+
+	fmt.Fprintf(w, "\n// %s calls %s %s: %s\n", op.OperationID, op.Method, op.Path, lowerFirst(op.Summary))
+	switch {
+	case download:
+		fmt.Fprintf(w, "func (c *Client) %s(%s) (io.ReadCloser, error) {\n", op.OperationID, strings.Join(args, ", "))
+	case resultType == "":
+		fmt.Fprintf(w, "func (c *Client) %s(%s) error {\n", op.OperationID, strings.Join(args, ", "))
+	case strings.HasPrefix(resultType, "[]") || strings.HasPrefix(resultType, "map["):
+		fmt.Fprintf(w, "func (c *Client) %s(%s) (%s, error) {\n", op.OperationID, strings.Join(args, ", "), resultType)
+	default:
+		fmt.Fprintf(w, "func (c *Client) %s(%s) (*%s, error) {\n", op.OperationID, strings.Join(args, ", "), resultType)
+	}
+
+	path := fmt.Sprintf("%q", op.Path)
+	if len(pathArgs) > 0 {
+		imports["fmt"] = true
+		format := op.Path
+		for _, name := range PathParams(op.Path) {
+			format = strings.Replace(format, "{"+name+"}", "%d", 1)
+		}
+		path = fmt.Sprintf("fmt.Sprintf(%q, %s)", format, strings.Join(pathArgs, ", "))
+	}
+
+	query := "nil"
+	if list || len(queryParams) > 0 {
+		imports["net/url"] = true
+		query = "query"
+		if list {
+			w.WriteString("\tquery := params.Values()\n")
+		} else {
+			w.WriteString("\tquery := url.Values{}\n")
+		}
+		for _, param := range queryParams {
+			fmt.Fprintf(w, "\tif %s != \"\" {\n\t\tquery.Set(%q, %s)\n\t}\n", goIdent(param.Name), param.Name, goIdent(param.Name))
+		}
+	}
+
+	if download {
+		fmt.Fprintf(w, "\treturn c.download(ctx, %q, %s, %s)\n}\n", op.Method, path, query)
+		return nil
+	}
+
+	// The call up to its out argument; uploads send the body as form fields
+	call := fmt.Sprintf("c.do(ctx, %q, %s, %s, nil", op.Method, path, query)
+	if filePart != "" {
+		call = fmt.Sprintf("c.upload(ctx, %q, %s, body, %q, fileName, file", op.Method, path, filePart)
+	} else if op.RequestBody != nil {
+		call = fmt.Sprintf("c.do(ctx, %q, %s, %s, body", op.Method, path, query)
+	}
+
+	switch {
+	case resultType == "":
+		fmt.Fprintf(w, "\treturn %s, nil)\n}\n", call)
+	case strings.HasPrefix(resultType, "[]") || strings.HasPrefix(resultType, "map["):
+		fmt.Fprintf(w, "\tvar out %s\n", resultType)
+		fmt.Fprintf(w, "\tif err := %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", call)
+		w.WriteString("\treturn out, nil\n}\n")
+	default:
+		fmt.Fprintf(w, "\tvar out %s\n", resultType)
+		fmt.Fprintf(w, "\tif err := %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", call)
+		w.WriteString("\treturn &out, nil\n}\n")
+	}
+	return nil
+}
+
+// goType returns the Go type a schema was built from
+func goType(doc *Document, schema *Schema) (string, error) {
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		component, ok := doc.Components.Schemas[name]
+		if !ok || component.GoType == "" {
+			return "", fmt.Errorf("schema %s has no Go type", name)
+		}
+		return component.GoType, nil
+	}
+	if len(schema.AllOf) == 1 {
+		t, err := goType(doc, schema.AllOf[0])
+		return "*" + t, err
+	}
+
+	switch schema.Type {
+	case "array":
+		item, err := goType(doc, schema.Items)
+		return "[]" + item, err
+	case "object":
+		if schema.AdditionalProperties != nil {
+			value, err := goType(doc, schema.AdditionalProperties)
+			return "map[string]" + value, err
+		}
+		return "map[string]interface{}", nil
+	case "string":
+		if schema.Format == "date-time" {
+			return "time.Time", nil
+		}
+		return "string", nil
+	case "integer":
+		return "int64", nil
+	case "number":
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	}
+	return "interface{}", nil
+}
+
+func isListParameter(name string) bool {
+	switch name {
+	case "limit", "offset", "page", "per_page", "sort", "filters":
+		return true
+	}
+	return false
+}
+
+// goIdent turns a parameter name such as vendor_id into vendorID
+func goIdent(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] == "id" {
+			parts[i] = "ID"
+		} else if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
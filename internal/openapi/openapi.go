@@ -0,0 +1,447 @@
+// Package openapi builds an OpenAPI 3 document from route descriptions and Go
+// types, so that the published API description cannot drift from the
+// handlers. It also validates JSON responses against the document and
+// generates the typed Go client in the client package.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.0.3"
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem holds the operations on one path, keyed by lower-case method
+type PathItem map[string]*Operation
+
+// Components holds the named schemas
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation is one method on one path
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is a JSON or multipart/form-data request body
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response is one documented response
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema used by OpenAPI 3.0 that buyer needs.
+// GoType and GoPackage record the Go type a named schema was built from, for
+// the client generator.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	GoType               string             `json:"x-go-type,omitempty"`
+	GoPackage            string             `json:"x-go-package,omitempty"`
+}
+
+// Schemer is implemented by types whose JSON form is not their Go structure,
+// such as types with a custom MarshalJSON
+type Schemer interface {
+	OpenAPISchema() *Schema
+}
+
+// Route describes one endpoint. Path uses OpenAPI templates such as
+// /api/v1/brands/{id}; path parameters are unsigned integers.
+type Route struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	Tag         string
+	Query       []Parameter // Query parameters besides the list parameters
+	List        bool        // Accepts limit, offset, page, per_page, sort and field filters
+	Request     interface{} // Zero value of the request body type, or nil
+	File        string      // Form part of an uploaded file; the request is then multipart/form-data with Request's fields as the other parts
+	Response    interface{} // Zero value of the success response type, or nil for no body
+	Download    bool        // The success response is a file of any media type rather than Response
+	Status      int         // Success status; defaults to 200
+	Errors      []int       // Documented error statuses
+	Error       interface{} // Zero value of the error body type
+}
+
+// Builder accumulates routes into a Document
+type Builder struct {
+	doc   *Document
+	types map[reflect.Type]string
+}
+
+// NewBuilder starts a document with the given title and version
+func NewBuilder(title, version string) *Builder {
+	return &Builder{
+		doc: &Document{
+			OpenAPI:    Version,
+			Info:       Info{Title: title, Version: version},
+			Paths:      make(map[string]*PathItem),
+			Components: Components{Schemas: make(map[string]*Schema)},
+		},
+		types: make(map[reflect.Type]string),
+	}
+}
+
+// Add describes a route in the document
+func (b *Builder) Add(route Route) {
+	item, ok := b.doc.Paths[route.Path]
+	if !ok {
+		item = &PathItem{}
+		b.doc.Paths[route.Path] = item
+	}
+
+	op := &Operation{
+		OperationID: route.OperationID,
+		Summary:     route.Summary,
+		Responses:   make(map[string]*Response),
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+
+	for _, name := range PathParams(route.Path) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "integer", Format: "int64"},
+		})
+	}
+	if route.List {
+		op.Parameters = append(op.Parameters, listParameters()...)
+	}
+	op.Parameters = append(op.Parameters, route.Query...)
+
+	if route.Request != nil {
+		schema := b.schemaFor(reflect.TypeOf(route.Request), true)
+		content := jsonContent(schema)
+		if route.File != "" {
+			file := &Schema{
+				Type:       "object",
+				Properties: map[string]*Schema{route.File: {Type: "string", Format: "binary"}},
+				Required:   []string{route.File},
+			}
+			content = map[string]*MediaType{MultipartContentType: {Schema: &Schema{AllOf: []*Schema{schema, file}}}}
+		}
+		op.RequestBody = &RequestBody{Required: true, Content: content}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if route.Download {
+		success.Content = map[string]*MediaType{FileContentType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+	} else if route.Response != nil {
+		success.Content = jsonContent(b.schemaFor(reflect.TypeOf(route.Response), false))
+	}
+	op.Responses[fmt.Sprint(status)] = success
+
+	for _, code := range route.Errors {
+		response := &Response{Description: http.StatusText(code)}
+		if route.Error != nil {
+			response.Content = map[string]*MediaType{
+				ErrorContentType(route.Error): {Schema: b.schemaFor(reflect.TypeOf(route.Error), false)},
+			}
+		}
+		op.Responses[fmt.Sprint(code)] = response
+	}
+
+	(*item)[strings.ToLower(route.Method)] = op
+}
+
+// Document returns the document built so far
+func (b *Builder) Document() *Document {
+	return b.doc
+}
+
+// ProblemContentType is the media type of RFC 9457 problem details
+const ProblemContentType = "application/problem+json"
+
+// MultipartContentType is the media type of requests uploading a file
+const MultipartContentType = "multipart/form-data"
+
+// FileContentType is the media range of downloaded files, which are sent with
+// their own media type
+const FileContentType = "*/*"
+
+// ErrorContentType returns the media type an error body is sent as: problem
+// details for types that say so, plain JSON otherwise
+func ErrorContentType(errorBody interface{}) string {
+	if p, ok := errorBody.(interface{ ProblemDetails() bool }); ok && p.ProblemDetails() {
+		return ProblemContentType
+	}
+	return "application/json"
+}
+
+// PathParams returns the names of the {templated} segments of a path
+func PathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, segment[1:len(segment)-1])
+		}
+	}
+	return names
+}
+
+// Operations returns every operation with its method and path, sorted by
+// operation ID
+func (d *Document) Operations() []OperationRef {
+	var ops []OperationRef
+	for path, item := range d.Paths {
+		for method, op := range *item {
+			ops = append(ops, OperationRef{Method: strings.ToUpper(method), Path: path, Operation: op})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].OperationID < ops[j].OperationID })
+	return ops
+}
+
+// OperationRef is an operation with the method and path it is found at
+type OperationRef struct {
+	Method string
+	Path   string
+	*Operation
+}
+
+// listParameters are the query parameters accepted by list operations
+func listParameters() []Parameter {
+	integer := &Schema{Type: "integer"}
+	return []Parameter{
+		{Name: "limit", In: "query", Description: "Page size (default 50, at most 500)", Schema: integer},
+		{Name: "offset", In: "query", Description: "Number of items to skip", Schema: integer},
+		{Name: "page", In: "query", Description: "1-based page number, an alternative to offset", Schema: integer},
+		{Name: "per_page", In: "query", Description: "Alias for limit", Schema: integer},
+		{Name: "sort", In: "query", Description: "Comma-separated fields; prefix with - for descending", Schema: &Schema{Type: "string"}},
+		{Name: "filters", In: "query", Description: "field=value or field[op]=value with op one of eq, ne, gt, gte, lt, lte, like, in",
+			Schema: &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}}},
+	}
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: schema}}
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	schemerType = reflect.TypeOf((*Schemer)(nil)).Elem()
+)
+
+// schemaFor returns the schema of a Go type. Named struct types become
+// components referenced by $ref. Request schemas mark no fields as required,
+// since omitted fields take their zero values.
+func (b *Builder) schemaFor(t reflect.Type, request bool) *Schema {
+	if t.Kind() == reflect.Pointer {
+		return nullable(b.schemaFor(t.Elem(), request))
+	}
+	if t.Implements(schemerType) {
+		return reflect.Zero(t).Interface().(Schemer).OpenAPISchema()
+	}
+	if reflect.PointerTo(t).Implements(schemerType) {
+		return reflect.New(t).Interface().(Schemer).OpenAPISchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte", Nullable: true}
+		}
+		return &Schema{Type: "array", Items: b.schemaFor(t.Elem(), request), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaFor(t.Elem(), request), Nullable: true}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return b.structSchema(t, request)
+		}
+		return &Schema{Ref: "#/components/schemas/" + b.component(t, request)}
+	}
+	return &Schema{}
+}
+
+// component registers a named struct type and returns its component name
+func (b *Builder) component(t reflect.Type, request bool) string {
+	if name, ok := b.types[t]; ok {
+		return name
+	}
+	name := componentName(t)
+	if _, taken := b.doc.Components.Schemas[name]; taken {
+		name = packageName(t.PkgPath()) + name
+	}
+	b.types[t] = name
+
+	// Register before building so that recursive types terminate
+	b.doc.Components.Schemas[name] = &Schema{}
+	schema := b.structSchema(t, request)
+	schema.GoType = goTypeName(t)
+	schema.GoPackage = t.PkgPath()
+	b.doc.Components.Schemas[name] = schema
+	return name
+}
+
+// structSchema builds an object schema from a struct's JSON fields
+func (b *Builder) structSchema(t reflect.Type, request bool) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	b.addFields(schema, t, request)
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (b *Builder) addFields(schema *Schema, t reflect.Type, request bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				b.addFields(schema, embedded, request)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = b.schemaFor(field.Type, request)
+		if !request && !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// nullable marks a schema as allowing null, wrapping references since
+// OpenAPI 3.0 ignores siblings of $ref
+func nullable(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		return &Schema{Nullable: true, AllOf: []*Schema{schema}}
+	}
+	copied := *schema
+	copied.Nullable = true
+	return &copied
+}
+
+// componentName names a schema after its Go type; instances of generic types
+// append their type arguments, so ListPage[models.Brand] is ListPageBrand
+func componentName(t reflect.Type) string {
+	name := t.Name()
+	base, args, generic := strings.Cut(name, "[")
+	if !generic {
+		return name
+	}
+	var b strings.Builder
+	b.WriteString(base)
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		arg = strings.TrimLeft(arg, "*[]")
+		if dot := strings.LastIndex(arg, "."); dot >= 0 {
+			arg = arg[dot+1:]
+		}
+		b.WriteString(arg)
+	}
+	return b.String()
+}
+
+// goTypeName returns a type as Go source would write it, such as
+// services.ListPage[models.Brand]
+func goTypeName(t reflect.Type) string {
+	name := t.Name()
+	base, args, generic := strings.Cut(name, "[")
+	qualified := packageName(t.PkgPath()) + "." + base
+	if !generic {
+		return qualified
+	}
+	var parts []string
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		prefix := arg[:len(arg)-len(strings.TrimLeft(arg, "*[]"))]
+		arg = arg[len(prefix):]
+		if slash := strings.LastIndex(arg, "/"); slash >= 0 {
+			arg = arg[slash+1:]
+		}
+		parts = append(parts, prefix+arg)
+	}
+	return qualified + "[" + strings.Join(parts, ",") + "]"
+}
+
+func packageName(pkgPath string) string {
+	return pkgPath[strings.LastIndex(pkgPath, "/")+1:]
+}
@@ -0,0 +1,186 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type testItem struct {
+	ID       uint       `json:"id"`
+	Name     string     `json:"name"`
+	Note     string     `json:"note,omitempty"`
+	Parent   *testItem  `json:"parent,omitempty"`
+	Tags     []string   `json:"tags"`
+	Created  time.Time  `json:"created_at"`
+	Deadline *time.Time `json:"deadline"`
+	internal string
+}
+
+type testPage[T any] struct {
+	Items []T   `json:"items"`
+	Total int64 `json:"total"`
+}
+
+type testInput struct {
+	Name string `json:"name"`
+}
+
+type testProblem struct {
+	Detail string `json:"detail"`
+}
+
+func (testProblem) ProblemDetails() bool { return true }
+
+func testDocument() *Document {
+	b := NewBuilder("test", "1.0")
+	b.Add(Route{
+		Method: "GET", Path: "/items", OperationID: "ListItems", Summary: "List items",
+		List: true, Response: testPage[testItem]{}, Errors: []int{400}, Error: testProblem{},
+	})
+	b.Add(Route{
+		Method: "POST", Path: "/items", OperationID: "CreateItem", Summary: "Create an item",
+		Request: testInput{}, Response: testItem{}, Status: 201, Errors: []int{422}, Error: testProblem{},
+	})
+	b.Add(Route{
+		Method: "DELETE", Path: "/items/{id}", OperationID: "DeleteItem", Summary: "Delete an item",
+		Status: 204, Errors: []int{404}, Error: testProblem{},
+	})
+	b.Add(Route{
+		Method: "POST", Path: "/items/{id}/files", OperationID: "UploadFile", Summary: "Upload a file",
+		Request: testInput{}, File: "file", Response: testItem{}, Status: 201, Errors: []int{422}, Error: testProblem{},
+	})
+	b.Add(Route{
+		Method: "GET", Path: "/items/{id}/file", OperationID: "DownloadFile", Summary: "Download a file",
+		Download: true, Errors: []int{404}, Error: testProblem{},
+	})
+	return b.Document()
+}
+
+func TestBuilder_Schemas(t *testing.T) {
+	doc := testDocument()
+
+	item, ok := doc.Components.Schemas["testItem"]
+	if !ok {
+		t.Fatalf("expected a testItem component, got %v", doc.Components.Schemas)
+	}
+	if strings.Join(item.Required, ",") != "created_at,deadline,id,name,tags" {
+		t.Errorf("unexpected required properties %v", item.Required)
+	}
+	if _, ok := item.Properties["internal"]; ok {
+		t.Error("unexported field should not be documented")
+	}
+	if parent := item.Properties["parent"]; !parent.Nullable || parent.AllOf[0].Ref != "#/components/schemas/testItem" {
+		t.Errorf("expected a nullable reference for parent, got %+v", parent)
+	}
+	if created := item.Properties["created_at"]; created.Format != "date-time" {
+		t.Errorf("expected date-time, got %+v", created)
+	}
+	if item.GoType != "openapi.testItem" {
+		t.Errorf("expected Go type openapi.testItem, got %s", item.GoType)
+	}
+
+	page, ok := doc.Components.Schemas["testPagetestItem"]
+	if !ok || page.GoType != "openapi.testPage[openapi.testItem]" {
+		t.Errorf("unexpected generic component %+v", page)
+	}
+
+	if input := doc.Components.Schemas["testInput"]; len(input.Required) != 0 {
+		t.Errorf("request schemas should not require fields, got %v", input.Required)
+	}
+
+	del := (*doc.Paths["/items/{id}"])["delete"]
+	if len(del.Parameters) != 1 || del.Parameters[0].In != "path" {
+		t.Errorf("expected one path parameter, got %+v", del.Parameters)
+	}
+	if _, ok := del.Responses["404"].Content[ProblemContentType]; !ok {
+		t.Error("expected problem+json error content")
+	}
+
+	upload := (*doc.Paths["/items/{id}/files"])["post"]
+	form, ok := upload.RequestBody.Content[MultipartContentType]
+	if !ok || len(form.Schema.AllOf) != 2 || form.Schema.AllOf[0].Ref != "#/components/schemas/testInput" ||
+		form.Schema.AllOf[1].Properties["file"].Format != "binary" {
+		t.Errorf("expected the input fields and a file part, got %+v", upload.RequestBody.Content)
+	}
+	download := (*doc.Paths["/items/{id}/file"])["get"]
+	if file, ok := download.Responses["200"].Content[FileContentType]; !ok || file.Schema.Format != "binary" {
+		t.Errorf("expected a file response, got %+v", download.Responses["200"].Content)
+	}
+}
+
+func TestDocument_ValidateResponse(t *testing.T) {
+	doc := testDocument()
+	valid := `{"id":1,"name":"a","tags":null,"created_at":"2025-01-01T00:00:00Z","deadline":null}`
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		status      int
+		contentType string
+		body        string
+		wantErr     string
+	}{
+		{"valid item", "POST", "/items", 201, "application/json", valid, ""},
+		{"valid page", "GET", "/items", 200, "application/json; charset=utf-8", `{"items":[` + valid + `],"total":1}`, ""},
+		{"no content", "DELETE", "/items/3", 204, "", "", ""},
+		{"problem", "DELETE", "/items/3", 404, ProblemContentType, `{"detail":"gone"}`, ""},
+		{"undocumented status", "POST", "/items", 200, "application/json", valid, "status 200"},
+		{"undocumented path", "GET", "/other", 200, "application/json", `{}`, "not in the document"},
+		{"wrong content type", "DELETE", "/items/3", 404, "application/json", `{"detail":"gone"}`, "content type"},
+		{"missing property", "POST", "/items", 201, "application/json", `{"id":1}`, "missing required property"},
+		{"extra property", "POST", "/items", 201, "application/json", strings.Replace(valid, `"id":1`, `"id":1,"x":2`, 1), "undocumented property"},
+		{"wrong type", "POST", "/items", 201, "application/json", strings.Replace(valid, `"id":1`, `"id":"1"`, 1), "expected integer"},
+		{"null not allowed", "POST", "/items", 201, "application/json", strings.Replace(valid, `"name":"a"`, `"name":null`, 1), "null is not allowed"},
+		{"bad date", "POST", "/items", 201, "application/json", strings.Replace(valid, "2025-01-01T00:00:00Z", "yesterday", 1), "not a date-time"},
+		{"body on 204", "DELETE", "/items/3", 204, "", `{}`, "no body"},
+		{"file", "GET", "/items/3/file", 200, "application/pdf", "%PDF-1.4", ""},
+		{"file error", "GET", "/items/3/file", 404, ProblemContentType, `{"detail":"gone"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := doc.ValidateResponse(tt.method, tt.path, tt.status, tt.contentType, []byte(tt.body))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGenerateClient(t *testing.T) {
+	src, err := GenerateClient(testDocument(), "testclient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := string(src)
+	for _, want := range []string{
+		"func (c *Client) ListItems(ctx context.Context, params ListParams) (*openapi.testPage[openapi.testItem], error)",
+		"func (c *Client) CreateItem(ctx context.Context, body openapi.testInput) (*openapi.testItem, error)",
+		"func (c *Client) DeleteItem(ctx context.Context, id uint) error",
+		`fmt.Sprintf("/items/%d", id)`,
+		"query := params.Values()",
+		"func (c *Client) UploadFile(ctx context.Context, id uint, body openapi.testInput, fileName string, file io.Reader) (*openapi.testItem, error)",
+		`c.upload(ctx, "POST", fmt.Sprintf("/items/%d/files", id), body, "file", fileName, file, &out)`,
+		"func (c *Client) DownloadFile(ctx context.Context, id uint) (io.ReadCloser, error)",
+		`return c.download(ctx, "GET", fmt.Sprintf("/items/%d/file", id), nil)`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated client is missing %q:\n%s", want, code)
+		}
+	}
+}
+
+func TestGoIdent(t *testing.T) {
+	for name, want := range map[string]string{"id": "id", "vendor_id": "vendorID", "strategy": "strategy", "per_page": "perPage"} {
+		if got := goIdent(name); got != want {
+			t.Errorf("goIdent(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Find returns the operation for a method and a concrete request path such as
+// /api/v1/brands/3
+func (d *Document) Find(method, path string) (*OperationRef, bool) {
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	for template, item := range d.Paths {
+		op, ok := (*item)[strings.ToLower(method)]
+		if !ok || !matchPath(strings.Split(template, "/"), segments) {
+			continue
+		}
+		return &OperationRef{Method: strings.ToUpper(method), Path: template, Operation: op}, true
+	}
+	return nil, false
+}
+
+func matchPath(template, segments []string) bool {
+	if len(template) != len(segments) {
+		return false
+	}
+	for i, part := range template {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			if segments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if part != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateResponse checks that a response to method and path is documented:
+// the status code must be listed for the operation and the body must match
+// the schema for its content type. Downloaded files are not inspected.
+func (d *Document) ValidateResponse(method, path string, status int, contentType string, body []byte) error {
+	op, ok := d.Find(method, path)
+	if !ok {
+		return fmt.Errorf("%s %s is not in the document", method, path)
+	}
+	response, ok := op.Responses[fmt.Sprint(status)]
+	if !ok {
+		return fmt.Errorf("%s %s: status %d is not documented for %s", method, path, status, op.OperationID)
+	}
+
+	if len(response.Content) == 0 {
+		if len(bytes.TrimSpace(body)) > 0 {
+			return fmt.Errorf("%s %s: status %d should have no body", method, path, status)
+		}
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	media, ok := response.Content[mediaType]
+	if !ok {
+		media, ok = response.Content[FileContentType]
+	}
+	if !ok {
+		return fmt.Errorf("%s %s: content type %q is not documented for status %d", method, path, contentType, status)
+	}
+	if media.Schema.Format == "binary" {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("%s %s: invalid JSON: %w", method, path, err)
+	}
+	if err := d.validate(media.Schema, value, "$"); err != nil {
+		return fmt.Errorf("%s %s (%s): %w", method, path, op.OperationID, err)
+	}
+	return nil
+}
+
+// validate checks a decoded JSON value against a schema
+func (d *Document) validate(schema *Schema, value interface{}, at string) error {
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		resolved, ok := d.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", at, schema.Ref)
+		}
+		return d.validate(resolved, value, at)
+	}
+	if value == nil {
+		if schema.Nullable || (schema.Type == "" && len(schema.AllOf) == 0) {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not allowed", at)
+	}
+	for _, part := range schema.AllOf {
+		if err := d.validate(part, value, at); err != nil {
+			return err
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %T", at, value)
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", at, name)
+			}
+		}
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			property, ok := schema.Properties[key]
+			if !ok {
+				property = schema.AdditionalProperties
+			}
+			if property == nil {
+				return fmt.Errorf("%s: undocumented property %q", at, key)
+			}
+			if err := d.validate(property, object[key], at+"."+key); err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %T", at, value)
+		}
+		for i, item := range array {
+			if err := d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string, got %T", at, value)
+		}
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				return fmt.Errorf("%s: %q is not a date-time", at, s)
+			}
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, s) {
+			return fmt.Errorf("%s: %q is not one of %v", at, s, schema.Enum)
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: expected integer, got %T", at, value)
+		}
+		if _, err := n.Int64(); err != nil {
+			return fmt.Errorf("%s: %s is not an integer", at, n)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return fmt.Errorf("%s: expected number, got %T", at, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean, got %T", at, value)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,275 @@
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Layout margins and spacing, in points
+const (
+	margin      = 50
+	footerY     = 30
+	lineSpacing = 1.3  // Line height as a multiple of the font size
+	columnGap   = 6    // Space kept free to the right of left-aligned cells
+	defaultSize = 10.0 // Font size of text and table rows
+)
+
+// column is one column of the tables laid out by Render
+type column struct {
+	width float64
+	right bool // Right-aligned, as for amounts
+}
+
+// layout is the state of Render: the document, the current page and how far
+// down it the content has come
+type layout struct {
+	doc     *Document
+	page    *Page
+	y       float64 // Bottom of the last line laid out
+	size    float64
+	columns []column
+	footer  string
+}
+
+// Render lays out markup as a PDF document and writes it to w. The markup is
+// line based, so that a text/template can produce it:
+//
+//	# Title               18 pt bold
+//	## Heading            12 pt bold, with space above
+//	**Text**              a bold line
+//	Text                  a line of text, wrapped at the margin
+//	---                   a rule across the page
+//	(blank line)          half a line of space
+//	| cell | **cell** |   a table row; cells wrap within their column, and
+//	                      a cell in ** is bold
+//	@columns * 50r 80r    the widths of the columns of later rows, in points;
+//	                      * takes the width left and r right-aligns
+//	@size 9               the font size of later text and rows
+//	@space 12             vertical space, in points
+//	@page a4              the page size, letter (the default) or a4, before
+//	                      any content
+//	@title Text           the document title shown by PDF readers
+//	@footer Page {page} of {pages}
+//	                      a footer on every page
+//
+// Content flows onto new pages as needed.
+func Render(w io.Writer, markup string) error {
+	l := &layout{doc: New(Letter), size: defaultSize}
+	for i, line := range strings.Split(strings.ReplaceAll(markup, "\r\n", "\n"), "\n") {
+		if err := l.line(strings.TrimRight(line, " \t")); err != nil {
+			return fmt.Errorf("layout line %d: %w", i+1, err)
+		}
+	}
+	if l.footer != "" {
+		for i, page := range l.doc.pages {
+			text := strings.NewReplacer("{page}", strconv.Itoa(i+1), "{pages}", strconv.Itoa(len(l.doc.pages))).Replace(l.footer)
+			page.Text(margin, footerY, Regular, 8, text)
+		}
+	}
+	_, err := l.doc.WriteTo(w)
+	return err
+}
+
+// contentWidth is the width between the margins
+func (l *layout) contentWidth() float64 {
+	return l.doc.Size.Width - 2*margin
+}
+
+// advance moves down by height, starting a new page when it does not fit
+func (l *layout) advance(height float64) {
+	if l.page == nil || l.y-height < margin {
+		l.page = l.doc.AddPage()
+		l.y = l.doc.Size.Height - margin
+	}
+	l.y -= height
+}
+
+// line lays out one line of markup
+func (l *layout) line(line string) error {
+	switch {
+	case strings.HasPrefix(line, "@"):
+		return l.directive(line)
+	case line == "":
+		if l.page != nil {
+			l.y -= l.size * lineSpacing / 2
+		}
+	case line == "---":
+		l.advance(l.size * 0.6)
+		l.page.Line(margin, l.y, l.doc.Size.Width-margin, l.y, 0.5)
+		l.y -= l.size * 0.4
+	case strings.HasPrefix(line, "# "):
+		l.text(strings.TrimSpace(line[2:]), Bold, 18)
+	case strings.HasPrefix(line, "## "):
+		if l.page != nil {
+			l.y -= 6
+		}
+		l.text(strings.TrimSpace(line[3:]), Bold, 12)
+	case strings.HasPrefix(line, "|"):
+		l.row(line)
+	default:
+		text, font := bold(line)
+		l.text(text, font, l.size)
+	}
+	return nil
+}
+
+// directive applies an @ line
+func (l *layout) directive(line string) error {
+	name, arg, _ := strings.Cut(line[1:], " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "columns":
+		var columns []column
+		for _, field := range strings.Fields(arg) {
+			c := column{right: strings.HasSuffix(field, "r")}
+			field = strings.TrimSuffix(field, "r")
+			if field != "*" {
+				width, err := strconv.ParseFloat(field, 64)
+				if err != nil || width <= 0 {
+					return fmt.Errorf("invalid column width %q", field)
+				}
+				c.width = width
+			}
+			columns = append(columns, c)
+		}
+		used, rest := 0.0, 0
+		for _, c := range columns {
+			used += c.width
+			if c.width == 0 {
+				rest++
+			}
+		}
+		for i := range columns {
+			if columns[i].width == 0 {
+				columns[i].width = (l.contentWidth() - used) / float64(rest)
+			}
+		}
+		l.columns = columns
+	case "size":
+		size, err := strconv.ParseFloat(arg, 64)
+		if err != nil || size < 4 || size > 72 {
+			return fmt.Errorf("invalid font size %q", arg)
+		}
+		l.size = size
+	case "space":
+		space, err := strconv.ParseFloat(arg, 64)
+		if err != nil || space < 0 {
+			return fmt.Errorf("invalid space %q", arg)
+		}
+		if l.page != nil {
+			l.y -= space
+		}
+	case "page":
+		if l.page != nil {
+			return fmt.Errorf("@page must come before the content")
+		}
+		switch strings.ToLower(arg) {
+		case "letter":
+			l.doc.Size = Letter
+		case "a4":
+			l.doc.Size = A4
+		default:
+			return fmt.Errorf("unknown page size %q (letter or a4)", arg)
+		}
+	case "title":
+		l.doc.Title = arg
+	case "footer":
+		l.footer = arg
+	default:
+		return fmt.Errorf("unknown directive @%s", name)
+	}
+	return nil
+}
+
+// bold strips the ** around bold text
+func bold(text string) (string, Font) {
+	if len(text) > 4 && strings.HasPrefix(text, "**") && strings.HasSuffix(text, "**") {
+		return text[2 : len(text)-2], Bold
+	}
+	return text, Regular
+}
+
+// text lays out a paragraph, wrapped at the margin
+func (l *layout) text(text string, font Font, size float64) {
+	for _, line := range wrap(text, font, size, l.contentWidth()) {
+		l.advance(size * lineSpacing)
+		l.page.Text(margin, l.y+size*0.25, font, size, line)
+	}
+}
+
+// row lays out a table row, wrapping each cell within its column
+func (l *layout) row(line string) {
+	cells := strings.Split(strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|"), "|")
+	columns := l.columns
+	if len(columns) < len(cells) {
+		// Without enough @columns, the cells share the width equally
+		columns = make([]column, len(cells))
+		for i := range columns {
+			columns[i].width = l.contentWidth() / float64(len(cells))
+		}
+	}
+
+	lines := make([][]string, len(cells))
+	fonts := make([]Font, len(cells))
+	height := 1
+	for i, cell := range cells {
+		var text string
+		text, fonts[i] = bold(strings.TrimSpace(cell))
+		lines[i] = wrap(text, fonts[i], l.size, columns[i].width-columnGap)
+		if len(lines[i]) > height {
+			height = len(lines[i])
+		}
+	}
+
+	lineHeight := l.size * lineSpacing
+	l.advance(lineHeight * float64(height))
+	top := l.y + lineHeight*float64(height)
+	x := float64(margin)
+	for i := range cells {
+		for j, text := range lines[i] {
+			baseline := top - lineHeight*float64(j+1) + l.size*0.25
+			if columns[i].right {
+				l.page.Text(x+columns[i].width-TextWidth(text, fonts[i], l.size), baseline, fonts[i], l.size, text)
+			} else {
+				l.page.Text(x, baseline, fonts[i], l.size, text)
+			}
+		}
+		x += columns[i].width
+	}
+}
+
+// wrap breaks text into lines no wider than width, breaking words that do
+// not fit on a line of their own
+func wrap(text string, font Font, size, width float64) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if TextWidth(candidate, font, size) <= width {
+			current = candidate
+			continue
+		}
+		if current != "" {
+			lines = append(lines, current)
+		}
+		for TextWidth(word, font, size) > width && len([]rune(word)) > 1 {
+			runes := []rune(word)
+			n := len(runes) - 1
+			for n > 1 && TextWidth(string(runes[:n]), font, size) > width {
+				n--
+			}
+			lines = append(lines, string(runes[:n]))
+			word = string(runes[n:])
+		}
+		current = word
+	}
+	if current != "" || len(lines) == 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
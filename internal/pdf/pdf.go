@@ -0,0 +1,211 @@
+// Package pdf writes simple PDF documents: pages of text in the standard
+// Helvetica fonts and ruled lines, with no dependencies beyond the standard
+// library. Text is encoded as WinAnsi (Windows-1252), so Western European
+// text prints as is and other characters print as "?". Render lays out the
+// line-based markup described there, which is what templates produce.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Font is one of the standard fonts every PDF reader has
+type Font int
+
+const (
+	Regular Font = iota // Helvetica
+	Bold                // Helvetica-Bold
+)
+
+var fontNames = [...]string{"Helvetica", "Helvetica-Bold"}
+
+// PageSize is the size of a page in points (1/72 inch)
+type PageSize struct {
+	Width, Height float64
+}
+
+// Page sizes
+var (
+	Letter = PageSize{612, 792}
+	A4     = PageSize{595.28, 841.89}
+)
+
+// Document is a PDF document being built, page by page
+type Document struct {
+	Title string // Shown by PDF readers, optional
+	Size  PageSize
+	pages []*Page
+}
+
+// Page is one page of a document. Coordinates are in points from the
+// bottom left corner.
+type Page struct {
+	content bytes.Buffer
+}
+
+// New returns an empty document with pages of the given size
+func New(size PageSize) *Document {
+	return &Document{Size: size}
+}
+
+// AddPage adds a blank page at the end of the document
+func (d *Document) AddPage() *Page {
+	page := &Page{}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Pages returns the number of pages
+func (d *Document) Pages() int {
+	return len(d.pages)
+}
+
+// Text draws s with its baseline starting at x, y
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td (%s) Tj ET\n", font+1, num(size), num(x), num(y), escape(winAnsi(s)))
+}
+
+// Line draws a line of the given width in points
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", num(width), num(x1), num(y1), num(x2), num(y2))
+}
+
+// num formats a coordinate or size with at most two decimals
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+// winAnsi encodes s as WinAnsi (Windows-1252)
+func winAnsi(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			b = append(b, ' ')
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			b = append(b, byte(r))
+		default:
+			if c, ok := winAnsiExtra[r]; ok {
+				b = append(b, c)
+			} else {
+				b = append(b, '?')
+			}
+		}
+	}
+	return b
+}
+
+// winAnsiExtra maps the characters of Windows-1252 outside Latin-1
+var winAnsiExtra = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// escape escapes the delimiters of a PDF literal string
+func escape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if c == '(' || c == ')' || c == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// TextWidth returns the width of s in points
+func TextWidth(s string, font Font, size float64) float64 {
+	widths := &helveticaWidths
+	if font == Bold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, c := range winAnsi(s) {
+		if c >= 0x20 && c < 0x7f {
+			total += widths[c-0x20]
+		} else {
+			total += 556 // Close to the average of the accented letters
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// WriteTo writes the document as PDF 1.4, with one blank page if none was
+// added
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	pages := d.pages
+	if len(pages) == 0 {
+		pages = []*Page{{}}
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1-5 are the catalog, the page tree, the two fonts and the
+	// document information; each page is followed by its content stream
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	for _, name := range fontNames {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+	object(fmt.Sprintf("<< /Producer (buyer) /Title (%s) >>", escape(winAnsi(d.Title))))
+	for i, page := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(d.Size.Width), num(d.Size.Height), 7+2*i))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(page.content.Bytes()); err != nil {
+			return 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return 0, err
+		}
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.WriteTo(w)
+}
+
+// Glyph widths of the printable ASCII characters, from the Adobe font
+// metrics, in 1/1000 of the font size
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
@@ -0,0 +1,113 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// pageContents returns the decompressed content streams of a PDF written by
+// this package, checking its cross-reference table on the way
+func pageContents(t *testing.T, data []byte) []string {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("Expected a PDF header and trailer, got %q...", data[:20])
+	}
+	xref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	start, _ := strconv.Atoi(string(xref[1]))
+	if !bytes.HasPrefix(data[start:], []byte("xref\n")) {
+		t.Fatalf("Expected startxref to point at the xref table")
+	}
+	for i, offset := range regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[start:], -1) {
+		at, _ := strconv.Atoi(string(offset[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(data[at:], []byte(want)) {
+			t.Errorf("Expected object %d at offset %d", i+1, at)
+		}
+	}
+
+	var contents []string
+	for _, stream := range regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindAllSubmatch(data, -1) {
+		r, err := zlib.NewReader(bytes.NewReader(stream[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(r)
+		contents = append(contents, string(content))
+	}
+	return contents
+}
+
+func TestRender(t *testing.T) {
+	markup := `@title Purchase Order (PO-1)
+@footer Page {page} of {pages}
+# PURCHASE ORDER
+## Vendor
+**Acme Café**
+---
+@columns * 60r 90r
+| **Item** | **Qty** | **Amount** |
+| MacBook | 2 | 2,599.00 |
+`
+	var buf bytes.Buffer
+	if err := Render(&buf, markup); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `/Title (Purchase Order \(PO-1\))`) {
+		t.Errorf("Expected an escaped title")
+	}
+	contents := pageContents(t, buf.Bytes())
+	if len(contents) != 1 {
+		t.Fatalf("Expected one page, got %d", len(contents))
+	}
+	page := contents[0]
+	for _, want := range []string{
+		"/F2 18 Tf 50 ", "(PURCHASE ORDER) Tj",
+		"(Acme Caf\xe9) Tj", // WinAnsi
+		"0.5 w 50 ",
+		"(Page 1 of 1) Tj",
+		// The amount ends at the right margin: 562 - width of "2,599.00"
+		fmt.Sprintf("/F1 10 Tf %s ", num(562-TextWidth("2,599.00", Regular, 10))),
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected the page to contain %q, got:\n%s", want, page)
+		}
+	}
+
+	// Content flows onto more pages
+	buf.Reset()
+	if err := Render(&buf, "@page a4\n"+strings.Repeat("A line of text\n", 100)); err != nil {
+		t.Fatal(err)
+	}
+	if contents := pageContents(t, buf.Bytes()); len(contents) != 2 || !strings.Contains(buf.String(), "/MediaBox [0 0 595.28 841.89]") {
+		t.Errorf("Expected two A4 pages, got %d", len(contents))
+	}
+
+	for _, bad := range []string{"@colour red", "@columns wide", "# Title\n@page a4", "@size 0"} {
+		if err := Render(io.Discard, bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	if width := TextWidth("Hello", Regular, 10); width != 22.78 {
+		t.Errorf("Expected Hello to be 22.78 points wide, got %v", width)
+	}
+	lines := wrap("The quick brown fox jumps over the lazy dog", Regular, 10, 80)
+	for _, line := range lines {
+		if TextWidth(line, Regular, 10) > 80 {
+			t.Errorf("Line %q is wider than 80 points", line)
+		}
+	}
+	if strings.Join(lines, " ") != "The quick brown fox jumps over the lazy dog" || len(lines) < 3 {
+		t.Errorf("Expected the text wrapped on word boundaries, got %q", lines)
+	}
+	if lines := wrap("Supercalifragilisticexpialidocious", Regular, 10, 50); len(lines) < 2 || strings.Join(lines, "") != "Supercalifragilisticexpialidocious" {
+		t.Errorf("Expected a long word broken up, got %q", lines)
+	}
+}
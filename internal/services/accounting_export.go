@@ -0,0 +1,446 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// Sources of accounting exports
+const (
+	AccountingSourceInvoices = "invoices" // Invoiced orders, dated by their invoice
+	AccountingSourceReceived = "received" // Received orders, dated by their delivery
+)
+
+// AccountingAccounts names the ledger accounts that exports post to. An
+// order's own GL account, when it has one, replaces Expense.
+type AccountingAccounts struct {
+	Payable  string // Credited for invoices
+	Accrued  string // Credited for goods received but not yet invoiced
+	Expense  string // Debited with the goods of orders without a GL account
+	Shipping string // Debited with shipping
+	Tax      string // Debited with tax
+}
+
+// DefaultAccountingAccounts are the accounts used when none are given
+var DefaultAccountingAccounts = AccountingAccounts{
+	Payable:  "Accounts Payable",
+	Accrued:  "Goods Received Not Invoiced",
+	Expense:  "Purchases",
+	Shipping: "Freight",
+	Tax:      "Tax",
+}
+
+// withDefaults fills in the accounts left empty from DefaultAccountingAccounts
+func (a AccountingAccounts) withDefaults() AccountingAccounts {
+	for _, field := range []struct {
+		value    *string
+		fallback string
+	}{
+		{&a.Payable, DefaultAccountingAccounts.Payable},
+		{&a.Accrued, DefaultAccountingAccounts.Accrued},
+		{&a.Expense, DefaultAccountingAccounts.Expense},
+		{&a.Shipping, DefaultAccountingAccounts.Shipping},
+		{&a.Tax, DefaultAccountingAccounts.Tax},
+	} {
+		if strings.TrimSpace(*field.value) == "" {
+			*field.value = field.fallback
+		}
+	}
+	return a
+}
+
+// AccountingEntry is one purchase order as an accounting export posts it
+type AccountingEntry struct {
+	PurchaseOrder *models.PurchaseOrder // With Vendor, Product, CostCenter and GLAccount
+	Source        string
+	Date          time.Time // Invoice or delivery date
+	DueDate       time.Time // Date plus the vendor's "Net N" payment terms
+	Reference     string    // Invoice number, or PO number for received orders
+}
+
+// ExpenseAccount returns the account the goods of the entry are debited to
+func (e AccountingEntry) ExpenseAccount(accounts AccountingAccounts) string {
+	if e.PurchaseOrder.GLAccount != nil {
+		return e.PurchaseOrder.GLAccount.Code
+	}
+	return accounts.Expense
+}
+
+// CreditAccount returns the account the entry's total is credited to
+func (e AccountingEntry) CreditAccount(accounts AccountingAccounts) string {
+	if e.Source == AccountingSourceReceived {
+		return accounts.Accrued
+	}
+	return accounts.Payable
+}
+
+// CostCenter returns the code of the entry's cost center, or ""
+func (e AccountingEntry) CostCenter() string {
+	if e.PurchaseOrder.CostCenter != nil {
+		return e.PurchaseOrder.CostCenter.Code
+	}
+	return ""
+}
+
+// Description describes the goods of the entry
+func (e AccountingEntry) Description() string {
+	po := e.PurchaseOrder
+	product := fmt.Sprintf("product %d", po.ProductID)
+	if po.Product != nil {
+		product = po.Product.Name
+	}
+	return fmt.Sprintf("%s: %d x %s", po.PONumber, po.Quantity, product)
+}
+
+// accountingLine is one debit of an entry
+type accountingLine struct {
+	Account     string
+	Description string
+	Amount      float64
+}
+
+// debits splits the entry into its goods, shipping and tax debits, leaving
+// out shipping and tax when there is none
+func (e AccountingEntry) debits(accounts AccountingAccounts) []accountingLine {
+	po := e.PurchaseOrder
+	lines := []accountingLine{{e.ExpenseAccount(accounts), e.Description(), roundCents(po.TotalAmount)}}
+	if po.ShippingCost != 0 {
+		lines = append(lines, accountingLine{accounts.Shipping, po.PONumber + ": shipping", roundCents(po.ShippingCost)})
+	}
+	if po.Tax != 0 {
+		lines = append(lines, accountingLine{accounts.Tax, po.PONumber + ": tax", roundCents(po.Tax)})
+	}
+	return lines
+}
+
+// total returns the sum of the entry's debits, which is what it credits
+func (e AccountingEntry) total(accounts AccountingAccounts) float64 {
+	var total float64
+	for _, line := range e.debits(accounts) {
+		total += line.Amount
+	}
+	return roundCents(total)
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(roundCents(amount), 'f', 2, 64)
+}
+
+// AccountingFormat writes entries in the import format of an accounting
+// package
+type AccountingFormat interface {
+	// Extension is the file extension of the format, without the dot
+	Extension() string
+	Write(w io.Writer, entries []AccountingEntry, accounts AccountingAccounts) error
+}
+
+var accountingFormats = map[string]AccountingFormat{
+	"journal": journalFormat{},
+	"iif":     iifFormat{},
+	"xero":    xeroFormat{},
+}
+
+// RegisterAccountingFormat adds or replaces the accounting export format
+// with name
+func RegisterAccountingFormat(name string, format AccountingFormat) {
+	accountingFormats[strings.ToLower(name)] = format
+}
+
+// AccountingFormats returns the names of the accounting export formats
+func AccountingFormats() []string {
+	names := make([]string, 0, len(accountingFormats))
+	for name := range accountingFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetAccountingFormat returns the accounting export format with name
+func GetAccountingFormat(name string) (AccountingFormat, error) {
+	format, ok := accountingFormats[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, &ValidationError{Field: "format", Message: fmt.Sprintf("unknown accounting format %q (must be one of: %s)", name, strings.Join(AccountingFormats(), ", "))}
+	}
+	return format, nil
+}
+
+// journalFormat is a generic double-entry journal: one CSV row per debit
+// and one for the credit of each order
+type journalFormat struct{}
+
+func (journalFormat) Extension() string { return "csv" }
+
+func (journalFormat) Write(w io.Writer, entries []AccountingEntry, accounts AccountingAccounts) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"Date", "Journal", "Reference", "Account", "Cost Center", "Description", "Debit", "Credit", "Currency", "Vendor"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		po := e.PurchaseOrder
+		journal := "Purchases"
+		if e.Source == AccountingSourceReceived {
+			journal = "Accruals"
+		}
+		date, vendor := e.Date.Format("2006-01-02"), po.Vendor.Name
+		for _, line := range e.debits(accounts) {
+			if err := writer.Write([]string{date, journal, e.Reference, line.Account, e.CostCenter(), line.Description, formatAmount(line.Amount), "", po.Currency, vendor}); err != nil {
+				return err
+			}
+		}
+		if err := writer.Write([]string{date, journal, e.Reference, e.CreditAccount(accounts), e.CostCenter(), e.Description(), "", formatAmount(e.total(accounts)), po.Currency, vendor}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// iifFormat is the QuickBooks Desktop Intuit Interchange Format: invoices
+// become bills and received orders general journal entries
+type iifFormat struct{}
+
+func (iifFormat) Extension() string { return "iif" }
+
+func (iifFormat) Write(w io.Writer, entries []AccountingEntry, accounts AccountingAccounts) error {
+	var b strings.Builder
+	row := func(fields ...string) {
+		for i, field := range fields {
+			// IIF has no quoting, so tabs and line breaks cannot appear
+			fields[i] = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(field)
+		}
+		b.WriteString(strings.Join(fields, "\t"))
+		b.WriteString("\r\n")
+	}
+	row("!TRNS", "TRNSTYPE", "DATE", "ACCNT", "NAME", "CLASS", "AMOUNT", "DOCNUM", "MEMO", "DUEDATE")
+	row("!SPL", "TRNSTYPE", "DATE", "ACCNT", "NAME", "CLASS", "AMOUNT", "DOCNUM", "MEMO")
+	row("!ENDTRNS")
+	for _, e := range entries {
+		trnsType := "BILL"
+		if e.Source == AccountingSourceReceived {
+			trnsType = "GENERAL JOURNAL"
+		}
+		date, vendor := e.Date.Format("01/02/2006"), e.PurchaseOrder.Vendor.Name
+		row("TRNS", trnsType, date, e.CreditAccount(accounts), vendor, e.CostCenter(), formatAmount(-e.total(accounts)), e.Reference, e.Description(), e.DueDate.Format("01/02/2006"))
+		for _, line := range e.debits(accounts) {
+			row("SPL", trnsType, date, line.Account, vendor, e.CostCenter(), formatAmount(line.Amount), e.Reference, line.Description)
+		}
+		row("ENDTRNS")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// xeroFormat is the Xero bills import template, one bill per order with a
+// line for the goods and one for shipping. The cost center is tracking
+// category "Cost Center".
+type xeroFormat struct{}
+
+func (xeroFormat) Extension() string { return "csv" }
+
+func (xeroFormat) Write(w io.Writer, entries []AccountingEntry, accounts AccountingAccounts) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{
+		"*ContactName", "EmailAddress", "POAddressLine1", "POAddressLine2", "POAddressLine3", "POAddressLine4",
+		"POCity", "PORegion", "POPostalCode", "POCountry", "*InvoiceNumber", "*InvoiceDate", "*DueDate", "Total",
+		"InventoryItemCode", "Description", "*Quantity", "*UnitAmount", "*AccountCode", "*TaxType", "TaxAmount",
+		"TrackingName1", "TrackingOption1", "TrackingName2", "TrackingOption2", "Currency",
+	}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		po, v := e.PurchaseOrder, e.PurchaseOrder.Vendor
+		trackingName := ""
+		if e.CostCenter() != "" {
+			trackingName = "Cost Center"
+		}
+		taxType := "Tax Exempt"
+		if po.Tax != 0 {
+			taxType = "Tax on Purchases"
+		}
+		sku := ""
+		if po.Product != nil && po.Product.SKU != nil {
+			sku = *po.Product.SKU
+		}
+		bill := func(itemCode, description string, quantity int, unitAmount float64, account, taxType, taxAmount string) []string {
+			return []string{
+				v.Name, v.Email, v.AddressLine1, v.AddressLine2, "", "",
+				v.City, v.State, v.PostalCode, v.Country, e.Reference, e.Date.Format("2006-01-02"), e.DueDate.Format("2006-01-02"), formatAmount(e.total(accounts)),
+				itemCode, description, strconv.Itoa(quantity), strconv.FormatFloat(unitAmount, 'f', -1, 64), account, taxType, taxAmount,
+				trackingName, e.CostCenter(), "", "", po.Currency,
+			}
+		}
+		if err := writer.Write(bill(sku, e.Description(), po.Quantity, po.UnitPrice, e.ExpenseAccount(accounts), taxType, formatAmount(po.Tax))); err != nil {
+			return err
+		}
+		if po.ShippingCost != 0 {
+			if err := writer.Write(bill("", po.PONumber+": shipping", 1, roundCents(po.ShippingCost), accounts.Shipping, "Tax Exempt", "")); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// AccountingExportService exports purchase orders to accounting packages
+type AccountingExportService struct {
+	db *gorm.DB
+}
+
+// NewAccountingExportService creates a new accounting export service
+func NewAccountingExportService(db *gorm.DB) *AccountingExportService {
+	return &AccountingExportService{db: db}
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *AccountingExportService) WithContext(ctx context.Context) *AccountingExportService {
+	return NewAccountingExportService(s.db.WithContext(ctx))
+}
+
+// AccountingExportInput selects what to export and how
+type AccountingExportInput struct {
+	Format   string // Name of a registered AccountingFormat
+	Source   string // AccountingSourceInvoices or AccountingSourceReceived
+	From, To time.Time
+	Accounts AccountingAccounts // Empty accounts take DefaultAccountingAccounts
+	DryRun   bool               // Write the export without marking the orders exported
+}
+
+// Export writes the orders of the source dated from input.From to input.To,
+// both days included, that have not been exported from that source before,
+// and marks them exported. Invoices are orders past approval with an invoice
+// number, dated when it was recorded; received orders are dated by their
+// delivery. The returned batch is not saved on a dry run or when there was
+// nothing to export.
+func (s *AccountingExportService) Export(w io.Writer, input AccountingExportInput) (*models.AccountingExport, error) {
+	format, err := GetAccountingFormat(input.Format)
+	if err != nil {
+		return nil, err
+	}
+	if input.Source != AccountingSourceInvoices && input.Source != AccountingSourceReceived {
+		return nil, &ValidationError{Field: "source", Message: fmt.Sprintf("unknown source %q (must be %s or %s)", input.Source, AccountingSourceInvoices, AccountingSourceReceived)}
+	}
+	if input.From.IsZero() || input.To.IsZero() {
+		return nil, &ValidationError{Field: "from", Message: "an export needs a from and a to date"}
+	}
+	from := time.Date(input.From.Year(), input.From.Month(), input.From.Day(), 0, 0, 0, 0, input.From.Location())
+	to := time.Date(input.To.Year(), input.To.Month(), input.To.Day(), 0, 0, 0, 0, input.To.Location())
+	if to.Before(from) {
+		return nil, &ValidationError{Field: "to", Message: "the to date is before the from date"}
+	}
+	accounts := input.Accounts.withDefaults()
+
+	batch := &models.AccountingExport{
+		Format:     strings.ToLower(strings.TrimSpace(input.Format)),
+		Source:     input.Source,
+		FromDate:   from,
+		ToDate:     to,
+		ExportedBy: models.ActorFrom(s.db.Statement.Context),
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		entries, err := accountingEntries(tx, input.Source, from, to.AddDate(0, 0, 1))
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := format.Write(&buf, entries, accounts); err != nil {
+			return err
+		}
+
+		batch.Records = len(entries)
+		if !input.DryRun && len(entries) > 0 {
+			for _, e := range entries {
+				batch.Entries = append(batch.Entries, models.AccountingExportEntry{PurchaseOrderID: e.PurchaseOrder.ID, Source: input.Source})
+			}
+			if err := tx.Create(batch).Error; err != nil {
+				return err
+			}
+		}
+		// Written last, so that a failed write leaves the orders unexported
+		_, err = w.Write(buf.Bytes())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// List returns the saved accounting exports, newest first
+func (s *AccountingExportService) List() ([]models.AccountingExport, error) {
+	var exports []models.AccountingExport
+	err := s.db.Order("created_at DESC, id DESC").Find(&exports).Error
+	return exports, err
+}
+
+var netTermsPattern = regexp.MustCompile(`(?i)\bnet\s*(\d+)\b`)
+
+// accountingEntries returns the orders of source dated in [from, until) and
+// not yet exported from it, in date order
+func accountingEntries(db *gorm.DB, source string, from, until time.Time) ([]AccountingEntry, error) {
+	query := db.Preload("Vendor", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Product", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("CostCenter").Preload("GLAccount").
+		Where("NOT EXISTS (SELECT 1 FROM accounting_export_entries e WHERE e.purchase_order_id = purchase_orders.id AND e.source = ?)", source)
+	if source == AccountingSourceInvoices {
+		query = query.Where("invoice_number <> '' AND status NOT IN ?", []string{"pending", "rejected", "cancelled"})
+	} else {
+		query = query.Where("status = ?", "received")
+	}
+	var orders []models.PurchaseOrder
+	if err := query.Order("id").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	// Dates are compared here rather than in SQL, where SQLite compares the
+	// stored text
+	var entries []AccountingEntry
+	for i := range orders {
+		po := &orders[i]
+		entry := AccountingEntry{PurchaseOrder: po, Source: source, Reference: po.InvoiceNumber}
+		switch {
+		case source == AccountingSourceReceived:
+			entry.Date, entry.Reference = po.UpdatedAt, po.PONumber
+			if po.ActualDelivery != nil {
+				entry.Date = *po.ActualDelivery
+			}
+		case po.InvoiceDate != nil:
+			entry.Date = *po.InvoiceDate
+		default:
+			// Invoices recorded before invoice dates were kept
+			entry.Date = po.UpdatedAt
+		}
+		if entry.Date.Before(from) || !entry.Date.Before(until) {
+			continue
+		}
+		entry.DueDate = entry.Date
+		if po.Vendor != nil {
+			if m := netTermsPattern.FindStringSubmatch(po.Vendor.PaymentTerms); m != nil {
+				days, _ := strconv.Atoi(m[1])
+				entry.DueDate = entry.Date.AddDate(0, 0, days)
+			}
+		} else {
+			po.Vendor = &models.Vendor{Name: fmt.Sprintf("Vendor %d", po.VendorID)}
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date.Before(entries[j].Date) })
+	return entries, nil
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+)
+
+func TestAccountingExport(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	vendor, _ := NewVendorService(cfg.DB).Create("Acme, Inc.", "USD", "")
+	cfg.DB.Model(vendor).Updates(map[string]interface{}{"payment_terms": "Net 30", "email": "ap@acme.test"})
+	brand, _ := NewBrandService(cfg.DB).Create("Apple")
+	product, _ := NewProductService(cfg.DB).Create("MacBook", brand.ID, nil)
+	quote, err := NewQuoteService(cfg.DB).Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 100, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	eng, _ := NewCostCenterService(cfg.DB).Create("ENG", "Engineering")
+	it, _ := NewGLAccountService(cfg.DB).Create("6100", "IT Equipment")
+
+	poSvc := NewPurchaseOrderService(cfg.DB)
+	newPO := func(status, invoice string) *models.PurchaseOrder {
+		t.Helper()
+		po, err := poSvc.Create(CreatePurchaseOrderInput{QuoteID: quote.ID, Quantity: 2, ShippingCost: 15, Tax: 17.5, CostCenterID: &eng.ID, GLAccountID: &it.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := poSvc.UpdateStatus(po.ID, status); err != nil {
+			t.Fatal(err)
+		}
+		if invoice != "" {
+			if po, err = poSvc.UpdateInvoiceNumber(po.ID, invoice); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return po
+	}
+	invoiced := newPO("ordered", "INV-1")
+	if invoiced.InvoiceDate == nil {
+		t.Fatal("Expected the invoice date to be recorded")
+	}
+	newPO("pending", "INV-PENDING") // Not approved, so never exported
+	received := newPO("received", "")
+	delivered := time.Now().AddDate(0, 0, -40)
+	cfg.DB.Model(received).Update("actual_delivery", delivered)
+
+	today := time.Now()
+	svc := NewAccountingExportService(cfg.DB)
+	export := func(input AccountingExportInput) (string, *models.AccountingExport) {
+		t.Helper()
+		var buf bytes.Buffer
+		batch, err := svc.Export(&buf, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.String(), batch
+	}
+
+	// A dry run writes the export without marking anything
+	out, batch := export(AccountingExportInput{Format: "journal", Source: AccountingSourceInvoices, From: today, To: today, DryRun: true})
+	if batch.Records != 1 || batch.ID != 0 {
+		t.Errorf("Expected one unsaved record, got %+v", batch)
+	}
+	for _, want := range []string{
+		"Date,Journal,Reference,Account,Cost Center,Description,Debit,Credit,Currency,Vendor",
+		",Purchases,INV-1,6100,ENG," + invoiced.PONumber + ": 2 x MacBook,200.00,,USD,\"Acme, Inc.\"",
+		",Purchases,INV-1,Freight,ENG," + invoiced.PONumber + ": shipping,15.00,,USD",
+		",Purchases,INV-1,Tax,ENG,",
+		",Purchases,INV-1,Accounts Payable,ENG," + invoiced.PONumber + ": 2 x MacBook,,232.50,USD",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected journal to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	// Xero bills, with the order's due date from the vendor's payment terms
+	out, batch = export(AccountingExportInput{Format: "xero", Source: AccountingSourceInvoices, From: today, To: today})
+	if batch.Records != 1 || batch.ID == 0 || batch.Format != "xero" {
+		t.Errorf("Expected a saved batch with one record, got %+v", batch)
+	}
+	due := invoiced.InvoiceDate.AddDate(0, 0, 30).Format("2006-01-02")
+	for _, want := range []string{
+		"*ContactName,EmailAddress,",
+		"\"Acme, Inc.\",ap@acme.test,",
+		",INV-1," + invoiced.InvoiceDate.Format("2006-01-02") + "," + due + ",232.50,",
+		",2,100,6100,Tax on Purchases,17.50,Cost Center,ENG,,,USD",
+		invoiced.PONumber + ": shipping,1,15,Freight,Tax Exempt,,Cost Center,ENG",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected Xero bills to contain %q, got:\n%s", want, out)
+		}
+	}
+
+	// Exported invoices are not exported again, in any format
+	if _, batch = export(AccountingExportInput{Format: "iif", Source: AccountingSourceInvoices, From: today, To: today}); batch.Records != 0 {
+		t.Errorf("Expected the invoice to be exported once, got %+v", batch)
+	}
+
+	// Received orders are accrued on their delivery date
+	from := delivered.AddDate(0, 0, -1)
+	out, batch = export(AccountingExportInput{Format: "iif", Source: AccountingSourceReceived, From: from, To: delivered, Accounts: AccountingAccounts{Accrued: "GRNI"}})
+	if batch.Records != 1 {
+		t.Fatalf("Expected one received order, got %+v", batch)
+	}
+	for _, want := range []string{
+		"!TRNS\tTRNSTYPE\tDATE\tACCNT\tNAME\tCLASS\tAMOUNT\tDOCNUM\tMEMO\tDUEDATE\r\n",
+		"TRNS\tGENERAL JOURNAL\t" + delivered.Format("01/02/2006") + "\tGRNI\tAcme, Inc.\tENG\t-232.50\t" + received.PONumber + "\t",
+		"SPL\tGENERAL JOURNAL\t" + delivered.Format("01/02/2006") + "\t6100\tAcme, Inc.\tENG\t200.00\t",
+		"ENDTRNS\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected IIF to contain %q, got:\n%q", want, out)
+		}
+	}
+	if _, batch = export(AccountingExportInput{Format: "journal", Source: AccountingSourceReceived, From: from, To: today}); batch.Records != 0 {
+		t.Errorf("Expected the received order to be exported once, got %+v", batch)
+	}
+
+	if exports, err := svc.List(); err != nil || len(exports) != 2 || exports[0].Source != AccountingSourceReceived {
+		t.Errorf("Expected two saved exports, newest first, got %+v, %v", exports, err)
+	}
+
+	var validationErr *ValidationError
+	var buf bytes.Buffer
+	if _, err := svc.Export(&buf, AccountingExportInput{Format: "sage", Source: AccountingSourceInvoices, From: today, To: today}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for an unknown format, got %v", err)
+	}
+	if _, err := svc.Export(&buf, AccountingExportInput{Format: "journal", Source: AccountingSourceInvoices, From: today, To: from}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for reversed dates, got %v", err)
+	}
+}
@@ -0,0 +1,454 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// Decisions recorded in PurchaseOrderApproval.Decision
+const (
+	DecisionApproved = "approved"
+	DecisionRejected = "rejected"
+)
+
+// ApprovalService manages the approval policy for purchase orders and the
+// approvals given under it
+type ApprovalService struct {
+	db *gorm.DB
+}
+
+// NewApprovalService creates a new approval service
+func NewApprovalService(db *gorm.DB) *ApprovalService {
+	return &ApprovalService{db: db}
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *ApprovalService) WithContext(ctx context.Context) *ApprovalService {
+	return &ApprovalService{db: s.db.WithContext(ctx)}
+}
+
+// ApprovalRuleInput represents input for creating an approval rule
+type ApprovalRuleInput struct {
+	Step          int
+	MinAmount     float64
+	Approvers     []string
+	ProjectID     *uint
+	RequisitionID *uint
+}
+
+// CreateRule adds a rule to the approval policy
+func (s *ApprovalService) CreateRule(input ApprovalRuleInput) (*models.ApprovalRule, error) {
+	if input.Step <= 0 {
+		return nil, &ValidationError{Field: "step", Message: "step must be at least 1"}
+	}
+	if input.MinAmount < 0 {
+		return nil, &ValidationError{Field: "min_amount", Message: "minimum amount cannot be negative"}
+	}
+	if input.ProjectID != nil && input.RequisitionID != nil {
+		return nil, &ValidationError{Field: "project_id", Message: "a rule applies to a project or a requisition, not both"}
+	}
+	if input.ProjectID != nil {
+		if err := s.db.First(&models.Project{}, *input.ProjectID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, &NotFoundError{Entity: "Project", ID: *input.ProjectID}
+			}
+			return nil, err
+		}
+	}
+	if input.RequisitionID != nil {
+		if err := s.db.First(&models.Requisition{}, *input.RequisitionID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, &NotFoundError{Entity: "Requisition", ID: *input.RequisitionID}
+			}
+			return nil, err
+		}
+	}
+
+	var approvers []string
+	for _, approver := range input.Approvers {
+		if approver = strings.TrimSpace(approver); approver != "" {
+			approvers = append(approvers, approver)
+		}
+	}
+
+	rule := &models.ApprovalRule{
+		Step:          input.Step,
+		MinAmount:     input.MinAmount,
+		Approvers:     strings.Join(approvers, ","),
+		ProjectID:     input.ProjectID,
+		RequisitionID: input.RequisitionID,
+	}
+	if err := s.db.Create(rule).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// ListRules returns the approval policy ordered by step and amount
+func (s *ApprovalService) ListRules() ([]models.ApprovalRule, error) {
+	var rules []models.ApprovalRule
+	err := s.db.Preload("Project").Preload("Requisition").
+		Order("step, min_amount, id").Find(&rules).Error
+	return rules, err
+}
+
+// DeleteRule removes a rule from the approval policy. Approvals already
+// given are kept.
+func (s *ApprovalService) DeleteRule(id uint) error {
+	result := s.db.Delete(&models.ApprovalRule{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &NotFoundError{Entity: "approval rule", ID: id}
+	}
+	return nil
+}
+
+// ApprovalStep is one step a purchase order needs approved, with the decision
+// taken on it, if any
+type ApprovalStep struct {
+	Step      int
+	Approvers []string // Empty when any approver may decide
+	Decision  *models.PurchaseOrderApproval
+}
+
+// MayDecide reports whether a user is one of the step's approvers
+func (st ApprovalStep) MayDecide(approver string) bool {
+	if len(st.Approvers) == 0 {
+		return true
+	}
+	for _, allowed := range st.Approvers {
+		if strings.EqualFold(allowed, approver) {
+			return true
+		}
+	}
+	return false
+}
+
+// PurchaseOrderApprovals is the approval state of a purchase order
+type PurchaseOrderApprovals struct {
+	PurchaseOrder *models.PurchaseOrder
+	BaseAmount    float64 // Grand total in the base currency
+	Steps         []ApprovalStep
+}
+
+// Required reports whether the approval policy applies to the purchase order
+func (a *PurchaseOrderApprovals) Required() bool {
+	return len(a.Steps) > 0
+}
+
+// Rejected reports whether an approver rejected the purchase order
+func (a *PurchaseOrderApprovals) Rejected() bool {
+	for _, step := range a.Steps {
+		if step.Decision != nil && step.Decision.Decision == DecisionRejected {
+			return true
+		}
+	}
+	return false
+}
+
+// Approved returns the number of approved steps
+func (a *PurchaseOrderApprovals) Approved() int {
+	approved := 0
+	for _, step := range a.Steps {
+		if step.Decision != nil && step.Decision.Decision == DecisionApproved {
+			approved++
+		}
+	}
+	return approved
+}
+
+// NextStep returns the first step still waiting for a decision, or nil when
+// every step is approved or one was rejected
+func (a *PurchaseOrderApprovals) NextStep() *ApprovalStep {
+	if a.Rejected() {
+		return nil
+	}
+	for i := range a.Steps {
+		if a.Steps[i].Decision == nil {
+			return &a.Steps[i]
+		}
+	}
+	return nil
+}
+
+// Complete reports whether every required step is approved
+func (a *PurchaseOrderApprovals) Complete() bool {
+	return !a.Rejected() && a.NextStep() == nil
+}
+
+// ForPurchaseOrder returns the approval state of a purchase order
+func (s *ApprovalService) ForPurchaseOrder(id uint) (*PurchaseOrderApprovals, error) {
+	var po models.PurchaseOrder
+	if err := s.db.Preload("Quote").Preload("Vendor").Preload("Product").
+		Preload("Requisition").Preload("Project").First(&po, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &NotFoundError{Entity: "purchase order", ID: id}
+		}
+		return nil, err
+	}
+	return purchaseOrderApprovals(s.db, &po)
+}
+
+// Queue returns the pending purchase orders waiting for an approval, oldest
+// first. With an approver, only the orders whose next step the approver may
+// decide are returned.
+func (s *ApprovalService) Queue(approver string) ([]*PurchaseOrderApprovals, error) {
+	var orders []*models.PurchaseOrder
+	if err := s.db.Preload("Quote").Preload("Vendor").Preload("Product").
+		Preload("Requisition").Preload("Project").
+		Where("status = ?", "pending").Order("order_date, id").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	var queue []*PurchaseOrderApprovals
+	for _, po := range orders {
+		approvals, err := purchaseOrderApprovals(s.db, po)
+		if err != nil {
+			return nil, err
+		}
+		next := approvals.NextStep()
+		if next == nil {
+			continue
+		}
+		if approver != "" && (!next.MayDecide(approver) || approvals.hasDecided(approver)) {
+			continue
+		}
+		queue = append(queue, approvals)
+	}
+	return queue, nil
+}
+
+// Approve records an approval of the next step of a purchase order. Approving
+// the last step moves the order to approved.
+func (s *ApprovalService) Approve(id uint, approver, comment string) (*PurchaseOrderApprovals, error) {
+	return s.decide(id, approver, DecisionApproved, comment)
+}
+
+// Reject records a rejection of the next step of a purchase order, with the
+// reason in comment, and moves the order to rejected
+func (s *ApprovalService) Reject(id uint, approver, comment string) (*PurchaseOrderApprovals, error) {
+	if strings.TrimSpace(comment) == "" {
+		return nil, &ValidationError{Field: "comment", Message: "a rejection needs a comment explaining it"}
+	}
+	return s.decide(id, approver, DecisionRejected, comment)
+}
+
+func (s *ApprovalService) decide(id uint, approver, decision, comment string) (*PurchaseOrderApprovals, error) {
+	approver = strings.TrimSpace(approver)
+	if approver == "" {
+		return nil, &ValidationError{Field: "approver", Message: "approver cannot be empty"}
+	}
+
+	var result *PurchaseOrderApprovals
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var po models.PurchaseOrder
+		if err := tx.Preload("Quote").First(&po, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &NotFoundError{Entity: "purchase order", ID: id}
+			}
+			return err
+		}
+		if po.Status != "pending" {
+			return &ValidationError{Field: "status", Message: fmt.Sprintf("purchase order %s is %s, not pending approval", po.PONumber, po.Status)}
+		}
+
+		approvals, err := purchaseOrderApprovals(tx, &po)
+		if err != nil {
+			return err
+		}
+		next := approvals.NextStep()
+		if next == nil {
+			return &ValidationError{Field: "status", Message: fmt.Sprintf("purchase order %s is not waiting for an approval", po.PONumber)}
+		}
+		if !next.MayDecide(approver) {
+			return &ValidationError{Field: "approver", Message: fmt.Sprintf("%s is not an approver for step %d of purchase order %s (approvers: %s)",
+				approver, next.Step, po.PONumber, strings.Join(next.Approvers, ", "))}
+		}
+		if approvals.hasDecided(approver) {
+			return &ValidationError{Field: "approver", Message: fmt.Sprintf("%s already approved an earlier step of purchase order %s", approver, po.PONumber)}
+		}
+
+		record := &models.PurchaseOrderApproval{
+			PurchaseOrderID: po.ID,
+			Step:            next.Step,
+			Approver:        approver,
+			Decision:        decision,
+			Comment:         strings.TrimSpace(comment),
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		next.Decision = record
+
+		switch {
+		case decision == DecisionRejected:
+			po.Status = "rejected"
+		case approvals.Complete():
+			if err := checkCoding(tx, &po, "approved"); err != nil {
+				return err
+			}
+			po.Status = "approved"
+		}
+		if po.Status != "pending" {
+			if err := tx.Model(&po).Updates(map[string]interface{}{
+				"status":         po.Status,
+				"cost_center_id": po.CostCenterID,
+				"gl_account_id":  po.GLAccountID,
+			}).Error; err != nil {
+				return err
+			}
+			if err := syncProjectRequisitionItem(tx, po.ProjectRequisitionItemID); err != nil {
+				return err
+			}
+		}
+		result = approvals
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// hasDecided reports whether approver decided a step already, so that
+// multi-step approvals need different people
+func (a *PurchaseOrderApprovals) hasDecided(approver string) bool {
+	for _, step := range a.Steps {
+		if step.Decision != nil && strings.EqualFold(step.Decision.Approver, approver) {
+			return true
+		}
+	}
+	return false
+}
+
+// purchaseOrderApprovals works out the steps the approval policy requires
+// for a purchase order and the decisions taken on them. po.Quote must be
+// loaded.
+func purchaseOrderApprovals(db *gorm.DB, po *models.PurchaseOrder) (*PurchaseOrderApprovals, error) {
+	approvals := &PurchaseOrderApprovals{PurchaseOrder: po, BaseAmount: purchaseOrderBaseAmount(po)}
+
+	var rules []models.ApprovalRule
+	query := db.Where("min_amount <= ?", approvals.BaseAmount)
+	scope := db.Where("project_id IS NULL AND requisition_id IS NULL")
+	if po.ProjectID != nil {
+		scope = scope.Or("project_id = ?", *po.ProjectID)
+	}
+	if po.RequisitionID != nil {
+		scope = scope.Or("requisition_id = ?", *po.RequisitionID)
+	}
+	if err := query.Where(scope).Order("step, id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	// Per step, the most specific rules win: requisition, project, general
+	specificity := func(rule models.ApprovalRule) int {
+		switch {
+		case rule.RequisitionID != nil:
+			return 2
+		case rule.ProjectID != nil:
+			return 1
+		}
+		return 0
+	}
+	byStep := make(map[int][]models.ApprovalRule)
+	for _, rule := range rules {
+		current := byStep[rule.Step]
+		if len(current) > 0 && specificity(current[0]) > specificity(rule) {
+			continue
+		}
+		if len(current) > 0 && specificity(current[0]) < specificity(rule) {
+			current = nil
+		}
+		byStep[rule.Step] = append(current, rule)
+	}
+
+	var decisions []models.PurchaseOrderApproval
+	if err := db.Where("purchase_order_id = ?", po.ID).Order("id").Find(&decisions).Error; err != nil {
+		return nil, err
+	}
+	decided := make(map[int]*models.PurchaseOrderApproval)
+	for i := range decisions {
+		decided[decisions[i].Step] = &decisions[i]
+	}
+
+	steps := make([]int, 0, len(byStep))
+	for step := range byStep {
+		steps = append(steps, step)
+	}
+	sort.Ints(steps)
+	for _, step := range steps {
+		approvalStep := ApprovalStep{Step: step, Approvers: ruleApprovers(byStep[step]), Decision: decided[step]}
+		approvals.Steps = append(approvals.Steps, approvalStep)
+	}
+	return approvals, nil
+}
+
+// ruleApprovers merges the approver lists of the rules of a step. A rule
+// without approvers lets any approver decide.
+func ruleApprovers(rules []models.ApprovalRule) []string {
+	seen := make(map[string]bool)
+	var approvers []string
+	for _, rule := range rules {
+		if strings.TrimSpace(rule.Approvers) == "" {
+			return nil
+		}
+		for _, approver := range strings.Split(rule.Approvers, ",") {
+			approver = strings.TrimSpace(approver)
+			if approver != "" && !seen[strings.ToLower(approver)] {
+				seen[strings.ToLower(approver)] = true
+				approvers = append(approvers, approver)
+			}
+		}
+	}
+	return approvers
+}
+
+// purchaseOrderBaseAmount converts a purchase order's grand total to the base
+// currency at the rate of its quote
+func purchaseOrderBaseAmount(po *models.PurchaseOrder) float64 {
+	if po.Quote != nil && po.Quote.ConversionRate > 0 {
+		return po.GrandTotal * po.Quote.ConversionRate
+	}
+	return po.GrandTotal
+}
+
+// checkApprovals returns a ValidationError when a pending purchase order may
+// not move on to status because its approvals are not complete
+func checkApprovals(db *gorm.DB, po *models.PurchaseOrder, status string) error {
+	if po.Status == "rejected" && status != "rejected" && status != "cancelled" {
+		return &ValidationError{Field: "status", Message: fmt.Sprintf("purchase order %s was rejected; it can only be cancelled", po.PONumber)}
+	}
+	if po.Status != "pending" || status == "pending" || status == "cancelled" {
+		return nil
+	}
+	if po.Quote == nil {
+		var quote models.Quote
+		if err := db.Unscoped().First(&quote, po.QuoteID).Error; err != nil {
+			return err
+		}
+		po.Quote = &quote
+	}
+	approvals, err := purchaseOrderApprovals(db, po)
+	if err != nil {
+		return err
+	}
+	if !approvals.Required() {
+		return nil
+	}
+	if status == "rejected" {
+		return &ValidationError{Field: "status", Message: "reject purchase orders through the approvals queue, with a comment"}
+	}
+	if next := approvals.NextStep(); next != nil {
+		return &ValidationError{Field: "status", Message: fmt.Sprintf("purchase order %s needs approval of step %d of %d before it can be %s",
+			po.PONumber, next.Step, len(approvals.Steps), status)}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApprovalService(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	vendor, _ := NewVendorService(cfg.DB).Create("Acme", "USD", "")
+	brand, _ := NewBrandService(cfg.DB).Create("Apple")
+	product, _ := NewProductService(cfg.DB).Create("MacBook", brand.ID, nil)
+	quote, err := NewQuoteService(cfg.DB).Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 100, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := NewRequisitionService(cfg.DB).Create("Lab refresh", "", 0, nil)
+	cfg.DB.Model(req).Update("status", RequisitionApproved)
+
+	svc := NewApprovalService(cfg.DB)
+	rules := []ApprovalRuleInput{
+		{Step: 1, MinAmount: 1000, Approvers: []string{"alice", "bob"}},
+		{Step: 2, MinAmount: 5000, Approvers: []string{"carol"}},
+		{Step: 1, MinAmount: 1000, Approvers: []string{"dave"}, RequisitionID: &req.ID},
+	}
+	for _, rule := range rules {
+		if _, err := svc.CreateRule(rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var validationErr *ValidationError
+	if _, err := svc.CreateRule(ApprovalRuleInput{Step: 0}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for step 0, got %v", err)
+	}
+
+	poSvc := NewPurchaseOrderService(cfg.DB)
+	newPO := func(number string, quantity int, requisitionID *uint) uint {
+		po, err := poSvc.Create(CreatePurchaseOrderInput{QuoteID: quote.ID, PONumber: number, Quantity: quantity, RequisitionID: requisitionID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return po.ID
+	}
+
+	// Below every threshold nothing changes
+	small := newPO("PO-SMALL", 5, nil)
+	if _, err := poSvc.UpdateStatus(small, "approved"); err != nil {
+		t.Errorf("Expected a small order to be approved directly: %v", err)
+	}
+
+	// One step
+	single := newPO("PO-SINGLE", 20, nil)
+	if _, err := poSvc.UpdateStatus(single, "ordered"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError ordering an unapproved order, got %v", err)
+	}
+	if queue, _ := svc.Queue("alice"); len(queue) != 1 || queue[0].PurchaseOrder.ID != single {
+		t.Errorf("Expected alice to see one order, got %d", len(queue))
+	}
+	if _, err := svc.Approve(single, "eve", ""); !errors.As(err, &validationErr) {
+		t.Errorf("Expected eve not to be an approver, got %v", err)
+	}
+	approvals, err := svc.Approve(single, "alice", "ok")
+	if err != nil || !approvals.Complete() {
+		t.Fatalf("Expected the order to be approved, got %+v, %v", approvals, err)
+	}
+	if po, _ := poSvc.GetByID(single); po.Status != "approved" {
+		t.Errorf("Expected status approved, got %s", po.Status)
+	}
+
+	// Two steps, by different people
+	large := newPO("PO-LARGE", 60, nil)
+	if _, err := svc.Approve(large, "carol", ""); !errors.As(err, &validationErr) {
+		t.Errorf("Expected carol to wait for step 1, got %v", err)
+	}
+	if approvals, err = svc.Approve(large, "bob", ""); err != nil || approvals.Complete() || approvals.NextStep().Step != 2 {
+		t.Fatalf("Expected step 2 to be next, got %+v, %v", approvals, err)
+	}
+	if _, err := poSvc.UpdateStatus(large, "approved"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError approving before step 2, got %v", err)
+	}
+	if approvals, err = svc.Approve(large, "carol", ""); err != nil || !approvals.Complete() {
+		t.Fatalf("Expected the order to be approved, got %+v, %v", approvals, err)
+	}
+
+	// Requisition rules replace the general rules of their step
+	scoped := newPO("PO-SCOPED", 20, &req.ID)
+	if _, err := svc.Approve(scoped, "alice", ""); !errors.As(err, &validationErr) {
+		t.Errorf("Expected alice not to approve a requisition order, got %v", err)
+	}
+
+	// Rejection needs a comment and ends the workflow
+	if _, err := svc.Reject(scoped, "dave", " "); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError rejecting without a comment, got %v", err)
+	}
+	if approvals, err = svc.Reject(scoped, "dave", "Over budget"); err != nil || !approvals.Rejected() {
+		t.Fatalf("Expected the order to be rejected, got %+v, %v", approvals, err)
+	}
+	if _, err := poSvc.UpdateStatus(scoped, "ordered"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a rejected order not to be ordered, got %v", err)
+	}
+	if _, err := poSvc.UpdateStatus(scoped, "cancelled"); err != nil {
+		t.Errorf("Expected a rejected order to be cancelled: %v", err)
+	}
+	if queue, _ := svc.Queue(""); len(queue) != 0 {
+		t.Errorf("Expected an empty queue, got %d", len(queue))
+	}
+}
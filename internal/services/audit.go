@@ -0,0 +1,139 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shakfu/buyer/internal/audit"
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// AuditService reads the audit log written by the audit package
+type AuditService struct {
+	db *gorm.DB
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// AuditFilter selects audit log entries; zero fields match everything
+type AuditFilter struct {
+	Entity   string // Entity name such as quote or purchase_order; see AuditEntity
+	EntityID uint
+	Actor    string
+	Source   string
+	Limit    int
+}
+
+// AuditField is one changed field of an audit log entry, formatted for display
+type AuditField struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// AuditEntry is an audit log entry with its changes decoded
+type AuditEntry struct {
+	models.AuditLog
+	Fields []AuditField
+}
+
+// List returns matching audit log entries, newest first
+func (s *AuditService) List(filter AuditFilter) ([]AuditEntry, error) {
+	query := s.db.Model(&models.AuditLog{}).Order("created_at DESC, id DESC")
+	if filter.Entity != "" {
+		entity, err := AuditEntity(filter.Entity)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("entity = ?", entity)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var logs []models.AuditLog
+	if err := query.Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	entries := make([]AuditEntry, len(logs))
+	for i, log := range logs {
+		entries[i] = AuditEntry{AuditLog: log, Fields: decodeChanges(log.Changes)}
+	}
+	return entries, nil
+}
+
+// History returns the audit log of one record, newest first
+func (s *AuditService) History(entity string, id uint) ([]AuditEntry, error) {
+	return s.List(AuditFilter{Entity: entity, EntityID: id})
+}
+
+// AuditEntities lists the entity names used in the audit log, one per model
+func AuditEntities() []string {
+	var names []string
+	for _, model := range models.All() {
+		names = append(names, audit.EntityName(reflect.TypeOf(model).Elem().Name()))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AuditEntity resolves an entity name given by a user, such as "quote",
+// "quotes", "purchase-order" or "po", to the name used in the audit log
+func AuditEntity(name string) (string, error) {
+	normalized := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "_")
+	if normalized == "po" {
+		normalized = "purchase_order"
+	}
+	entities := AuditEntities()
+	for _, candidate := range []string{normalized, strings.TrimSuffix(normalized, "s")} {
+		for _, entity := range entities {
+			if candidate == entity {
+				return entity, nil
+			}
+		}
+	}
+	return "", &ValidationError{Field: "entity", Message: fmt.Sprintf("unknown entity '%s' (must be one of: %s)", name, strings.Join(entities, ", "))}
+}
+
+// decodeChanges turns the JSON changes of an entry into fields sorted by name
+func decodeChanges(encoded string) []AuditField {
+	var changes map[string]audit.Change
+	if err := json.Unmarshal([]byte(encoded), &changes); err != nil {
+		return nil
+	}
+	fields := make([]AuditField, 0, len(changes))
+	for field, change := range changes {
+		fields = append(fields, AuditField{Field: field, Old: formatAuditValue(change.Old), New: formatAuditValue(change.New)})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields
+}
+
+func formatAuditValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
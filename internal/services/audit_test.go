@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shakfu/buyer/internal/audit"
+	"github.com/shakfu/buyer/internal/models"
+)
+
+func TestAuditLog(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+	if err := audit.Register(cfg.DB); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := models.WithSource(models.WithActor(context.Background(), "alice"), models.SourceWeb)
+	db := cfg.DB.WithContext(ctx)
+	vendor, err := NewVendorService(db).Create("Acme", "USD", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	brand, _ := NewBrandService(db).Create("Apple")
+	product, _ := NewProductService(db).Create("MacBook", brand.ID, nil)
+	quoteSvc := NewQuoteService(db)
+	quote, err := quoteSvc.Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 1000, Currency: "USD", QuoteDate: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := quoteSvc.Update(quote.ID, UpdateQuoteInput{Price: 1200, Currency: "USD"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := quoteSvc.Delete(quote.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewAuditService(cfg.DB)
+	history, err := svc.History("quotes", quote.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("Expected create, update and delete, got %+v", history)
+	}
+	deleted, updated, created := history[0], history[1], history[2]
+	if created.Action != audit.ActionCreate || updated.Action != audit.ActionUpdate || deleted.Action != audit.ActionDelete {
+		t.Errorf("Unexpected actions %s, %s, %s", created.Action, updated.Action, deleted.Action)
+	}
+	if created.Actor != "alice" || created.Source != models.SourceWeb || created.Entity != "quote" {
+		t.Errorf("Expected alice's web change to a quote, got %+v", created.AuditLog)
+	}
+
+	var price *AuditField
+	for i := range updated.Fields {
+		if updated.Fields[i].Field == "price" {
+			price = &updated.Fields[i]
+		}
+		if updated.Fields[i].Field == "updated_at" {
+			t.Error("Expected timestamps to be left out of the diff")
+		}
+	}
+	if price == nil || price.Old != "1000" || price.New != "1200" {
+		t.Errorf("Expected the price change 1000 -> 1200, got %+v", updated.Fields)
+	}
+
+	// Changes through other services and batch updates are recorded as well
+	if _, err := NewVendorService(db).Update(vendor.ID, "Acme Corp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.Brand{}).Where("name = ?", "Apple").Update("name", "Apple Inc").Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, entity := range []string{"vendor", "brand"} {
+		entries, err := svc.List(AuditFilter{Entity: entity, Actor: "alice"})
+		if err != nil || len(entries) != 2 || entries[0].Action != audit.ActionUpdate {
+			t.Errorf("Expected a create and an update of the %s, got %+v, %v", entity, entries, err)
+		}
+	}
+
+	// Passwords are recorded as changed without their values
+	if _, err := NewUserService(db).Create(CreateUserInput{Username: "bob", Role: RoleBuyer, Password: testPassword}); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := svc.List(AuditFilter{Entity: "user"})
+	for _, field := range entries[0].Fields {
+		if field.Field == "password_hash" && field.New != "[redacted]" {
+			t.Errorf("Expected the password hash to be redacted, got %q", field.New)
+		}
+	}
+}
+
+func TestAuditEntity(t *testing.T) {
+	for input, want := range map[string]string{
+		"quote":          "quote",
+		"Quotes":         "quote",
+		"purchase-order": "purchase_order",
+		"po":             "purchase_order",
+		"forex":          "forex",
+		"api_tokens":     "api_token",
+	} {
+		if got, err := AuditEntity(input); err != nil || got != want {
+			t.Errorf("AuditEntity(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	var validationErr *ValidationError
+	if _, err := AuditEntity("spaceship"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected ValidationError for an unknown entity, got %v", err)
+	}
+}
@@ -0,0 +1,753 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shakfu/buyer/internal/migrations"
+	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/storage"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// BackupFormatVersion is the version of the archive layout written by Backup
+const BackupFormatVersion = 1
+
+// backupManifestName is the archive entry holding the BackupManifest
+const backupManifestName = "manifest.json"
+
+// backupFilePattern matches archive names written by BackupToDir
+const backupFilePattern = "buyer-backup-*.zip"
+
+// BackupService creates and restores portable database archives. An archive is a
+// zip file containing a manifest, one JSON file per table and the files referenced
+// by documents. Every entry is covered by a SHA-256 checksum in the manifest.
+type BackupService struct {
+	db *gorm.DB
+	// storage keeps the files of uploaded documents; without it only
+	// documents recorded with an absolute local path have their files backed up
+	storage storage.StorageBackend
+}
+
+// NewBackupService creates a new backup service
+func NewBackupService(db *gorm.DB) *BackupService {
+	return &BackupService{db: db}
+}
+
+// WithStorage returns a copy of the service that backs up and restores
+// document files through backend
+func (s *BackupService) WithStorage(backend storage.StorageBackend) *BackupService {
+	return &BackupService{db: s.db, storage: backend}
+}
+
+// BackupManifest describes the contents of a backup archive
+type BackupManifest struct {
+	FormatVersion int           `json:"format_version"`
+	SchemaVersion int           `json:"schema_version"`
+	AppVersion    string        `json:"app_version,omitempty"`
+	Driver        string        `json:"driver"`
+	CreatedAt     time.Time     `json:"created_at"`
+	Tables        []BackupTable `json:"tables"`
+	Files         []BackupFile  `json:"files"`
+	MissingFiles  []string      `json:"missing_files,omitempty"` // Document paths that could not be read
+}
+
+// BackupTable describes one table stored in an archive
+type BackupTable struct {
+	Name      string `json:"name"`
+	Entry     string `json:"entry"`
+	Rows      int    `json:"rows"`
+	SHA256    string `json:"sha256"`
+	JoinTable bool   `json:"join_table,omitempty"` // many2many table without a model
+}
+
+// BackupFile describes a document file stored in an archive
+type BackupFile struct {
+	DocumentID   uint   `json:"document_id"`
+	Entry        string `json:"entry"`
+	OriginalPath string `json:"original_path"`
+	Size         int64  `json:"size"`
+	SHA256       string `json:"sha256"`
+}
+
+// BackupOptions configures Backup
+type BackupOptions struct {
+	AppVersion string // Recorded in the manifest
+	SkipFiles  bool   // Do not include document files
+}
+
+// RestoreOptions configures Restore
+type RestoreOptions struct {
+	Force    bool   // Replace existing data instead of refusing to restore into a non-empty database
+	FilesDir string // Restore document files here (as <dir>/<document id>/<name>) instead of the document storage
+}
+
+// RestoreResult summarizes a restore
+type RestoreResult struct {
+	Manifest *BackupManifest `json:"manifest"`
+	Rows     map[string]int  `json:"rows"`
+	Files    int             `json:"files"`
+	Warnings []string        `json:"warnings,omitempty"`
+}
+
+// backupTable pairs a table name with its model (nil for many2many join tables)
+type backupTable struct {
+	name  string
+	model interface{}
+}
+
+// backupTables returns every model table in dependency order, followed by the
+// many2many join tables between them
+func backupTables(db *gorm.DB) ([]backupTable, error) {
+	cache := &sync.Map{}
+	var tables, joins []backupTable
+	seen := make(map[string]bool)
+	for _, model := range models.All() {
+		sch, err := schema.Parse(model, cache, db.NamingStrategy)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, backupTable{name: sch.Table, model: model})
+		seen[sch.Table] = true
+		for _, rel := range sch.Relationships.Many2Many {
+			if rel.JoinTable != nil && !seen[rel.JoinTable.Table] {
+				joins = append(joins, backupTable{name: rel.JoinTable.Table})
+				seen[rel.JoinTable.Table] = true
+			}
+		}
+	}
+	return append(tables, joins...), nil
+}
+
+// Backup writes a complete archive of the database to w
+func (s *BackupService) Backup(w io.Writer, opts BackupOptions) (*BackupManifest, error) {
+	tables, err := backupTables(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &BackupManifest{
+		FormatVersion: BackupFormatVersion,
+		SchemaVersion: migrations.LatestVersion(),
+		AppVersion:    opts.AppVersion,
+		Driver:        s.db.Dialector.Name(),
+		CreatedAt:     time.Now().UTC(),
+		Tables:        make([]BackupTable, 0, len(tables)),
+		Files:         make([]BackupFile, 0),
+	}
+
+	zw := zip.NewWriter(w)
+
+	// Read every table in one transaction for a consistent snapshot
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range tables {
+			data, rows, err := dumpBackupTable(tx, table)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", table.name, err)
+			}
+			entry := "data/" + table.name + ".json"
+			if err := writeZipEntry(zw, entry, data); err != nil {
+				return err
+			}
+			manifest.Tables = append(manifest.Tables, BackupTable{
+				Name:      table.name,
+				Entry:     entry,
+				Rows:      rows,
+				SHA256:    sha256Hex(data),
+				JoinTable: table.model == nil,
+			})
+		}
+
+		if opts.SkipFiles {
+			return nil
+		}
+		var docs []models.Document
+		if err := tx.Unscoped().Order("id ASC").Find(&docs).Error; err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			file, err := s.addBackupFile(zw, doc)
+			if err != nil {
+				manifest.MissingFiles = append(manifest.MissingFiles, doc.FilePath)
+				continue
+			}
+			manifest.Files = append(manifest.Files, *file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeZipEntry(zw, backupManifestName, data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// BackupToDir writes a timestamped archive into dir and returns its path
+func (s *BackupService) BackupToDir(dir string, opts BackupOptions) (string, *BackupManifest, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := fmt.Sprintf("buyer-backup-%s.zip", time.Now().UTC().Format("20060102-150405"))
+	target := filepath.Join(dir, name)
+
+	// Write to a temporary file so that a failed backup never leaves a partial archive
+	tmp, err := os.CreateTemp(dir, ".buyer-backup-*.tmp")
+	if err != nil {
+		return "", nil, err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	manifest, err := s.Backup(tmp, opts)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", nil, err
+	}
+	return target, manifest, nil
+}
+
+// Verify reads an archive's manifest and checks the format version, the schema
+// version and the checksum of every entry
+func (s *BackupService) Verify(r io.ReaderAt, size int64) (*BackupManifest, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid backup archive: %w", err)
+	}
+	manifest, _, err := verifyBackupArchive(zr)
+	return manifest, err
+}
+
+// Restore loads an archive into the database, preserving IDs and relationships.
+// The database must be empty unless opts.Force is set, in which case existing
+// data is replaced. Nothing is written if any checksum or version check fails.
+func (s *BackupService) Restore(r io.ReaderAt, size int64, opts RestoreOptions) (*RestoreResult, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid backup archive: %w", err)
+	}
+	manifest, entries, err := verifyBackupArchive(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := backupTables(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{Manifest: manifest, Rows: make(map[string]int)}
+	if manifest.SchemaVersion < migrations.LatestVersion() {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("archive schema version %d is older than %d; fields added since will use their defaults", manifest.SchemaVersion, migrations.LatestVersion()))
+	}
+
+	archived := make(map[string]BackupTable, len(manifest.Tables))
+	for _, t := range manifest.Tables {
+		archived[t.Name] = t
+	}
+	known := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		known[t.name] = true
+	}
+	for _, t := range manifest.Tables {
+		if !known[t.Name] {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("table %s is not part of this schema and was skipped", t.Name))
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := clearRestoreTarget(tx, tables, opts.Force); err != nil {
+			return err
+		}
+
+		for _, table := range tables {
+			info, ok := archived[table.name]
+			if !ok {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("table %s is not in the archive", table.name))
+				continue
+			}
+			rows, err := loadBackupTable(tx, table, entries[info.Entry])
+			if err != nil {
+				return fmt.Errorf("failed to restore %s: %w", table.name, err)
+			}
+			result.Rows[table.name] = rows
+		}
+
+		if err := resetSequences(tx, tables); err != nil {
+			return err
+		}
+
+		for _, file := range manifest.Files {
+			if err := s.restoreBackupFile(tx, zr, file, opts.FilesDir); err != nil {
+				return fmt.Errorf("failed to restore file for document %d: %w", file.DocumentID, err)
+			}
+			result.Files++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, missing := range manifest.MissingFiles {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("file was missing when the backup was taken: %s", missing))
+	}
+	return result, nil
+}
+
+// PruneBackups removes all but the newest keep archives written by BackupToDir
+// and returns the removed paths
+func PruneBackups(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, backupFilePattern))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) <= keep {
+		return nil, nil
+	}
+
+	// Names embed a UTC timestamp, so lexical order is chronological
+	sort.Strings(matches)
+	removed := make([]string, 0, len(matches)-keep)
+	for _, old := range matches[:len(matches)-keep] {
+		if err := os.Remove(old); err != nil {
+			return removed, err
+		}
+		removed = append(removed, old)
+	}
+	return removed, nil
+}
+
+// dumpBackupTable serializes every row of a table to JSON
+func dumpBackupTable(tx *gorm.DB, table backupTable) ([]byte, int, error) {
+	if table.model == nil {
+		var rows []map[string]interface{}
+		if err := tx.Table(table.name).Find(&rows).Error; err != nil {
+			return nil, 0, err
+		}
+		data, err := json.Marshal(rows)
+		return data, len(rows), err
+	}
+
+	rows := reflect.New(reflect.SliceOf(reflect.TypeOf(table.model).Elem()))
+	if err := tx.Unscoped().Order("id ASC").Find(rows.Interface()).Error; err != nil {
+		return nil, 0, err
+	}
+	data, err := json.Marshal(rows.Interface())
+	return data, rows.Elem().Len(), err
+}
+
+// loadBackupTable inserts the rows of one archived table
+func loadBackupTable(tx *gorm.DB, table backupTable, data []byte) (int, error) {
+	if table.model == nil {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var rows []map[string]interface{}
+		if err := decoder.Decode(&rows); err != nil {
+			return 0, err
+		}
+		for _, row := range rows {
+			for k, v := range row {
+				if n, ok := v.(json.Number); ok {
+					if i, err := n.Int64(); err == nil {
+						row[k] = i
+					} else if f, err := n.Float64(); err == nil {
+						row[k] = f
+					}
+				}
+			}
+		}
+		return len(rows), insertJoinRows(tx, table.name, rows)
+	}
+
+	rows := reflect.New(reflect.SliceOf(reflect.TypeOf(table.model).Elem()))
+	if err := json.Unmarshal(data, rows.Interface()); err != nil {
+		return 0, err
+	}
+	return rows.Elem().Len(), insertModelRows(tx, table, rows)
+}
+
+// insertJoinRows inserts rows of a many2many join table
+func insertJoinRows(tx *gorm.DB, name string, rows []map[string]interface{}) error {
+	for _, row := range rows {
+		if err := tx.Table(name).Create(row).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertModelRows inserts a pointer to a slice of models as they are, keeping their
+// IDs. Hooks and associations are skipped, and zero values are not replaced by
+// column defaults.
+func insertModelRows(tx *gorm.DB, table backupTable, rows reflect.Value) error {
+	if rows.Elem().Len() == 0 {
+		return nil
+	}
+	// GORM replaces zero values with column defaults on insert, such as a false
+	// bool whose column defaults to true, so note them beforehand and write them back
+	zeros, err := zeroDefaultColumns(tx, table, rows.Elem())
+	if err != nil {
+		return err
+	}
+	err = tx.Session(&gorm.Session{SkipHooks: true}).
+		Select("*").Omit(clause.Associations).
+		CreateInBatches(rows.Interface(), 100).Error
+	if err != nil {
+		return err
+	}
+	for _, zero := range zeros {
+		if err := tx.Table(table.name).Where("id = ?", zero.id).UpdateColumn(zero.column, zero.value).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zeroDefault is a zero value in a column that has a default
+type zeroDefault struct {
+	id     interface{}
+	column string
+	value  interface{}
+}
+
+// zeroDefaultColumns lists the zero values in rows for columns that have a default
+func zeroDefaultColumns(tx *gorm.DB, table backupTable, rows reflect.Value) ([]zeroDefault, error) {
+	sch, err := schema.Parse(table.model, &sync.Map{}, tx.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+	if sch.PrioritizedPrimaryField == nil {
+		return nil, nil
+	}
+
+	ctx := tx.Statement.Context
+	var zeros []zeroDefault
+	for _, field := range sch.Fields {
+		if field.DBName == "" || field.PrimaryKey || !field.HasDefaultValue || field.DefaultValue == "" {
+			continue
+		}
+		for i := 0; i < rows.Len(); i++ {
+			row := rows.Index(i)
+			value, isZero := field.ValueOf(ctx, row)
+			if !isZero {
+				continue
+			}
+			id, _ := sch.PrioritizedPrimaryField.ValueOf(ctx, row)
+			zeros = append(zeros, zeroDefault{id: id, column: field.DBName, value: value})
+		}
+	}
+	return zeros, nil
+}
+
+// clearRestoreTarget refuses to restore into a database with data, or deletes
+// that data (children first) when force is set
+func clearRestoreTarget(tx *gorm.DB, tables []backupTable, force bool) error {
+	if !force {
+		table, count, err := firstNonEmptyTable(tx, tables)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return &ValidationError{Field: "database", Message: fmt.Sprintf("target database is not empty (%s has %d rows); use force to replace existing data", table, count)}
+		}
+		return nil
+	}
+
+	for i := len(tables) - 1; i >= 0; i-- {
+		if err := tx.Exec("DELETE FROM " + tx.Statement.Quote(tables[i].name)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// firstNonEmptyTable returns the first table that has rows, and its row count
+func firstNonEmptyTable(tx *gorm.DB, tables []backupTable) (string, int64, error) {
+	for _, table := range tables {
+		var count int64
+		if err := tx.Table(table.name).Count(&count).Error; err != nil {
+			return "", 0, err
+		}
+		if count > 0 {
+			return table.name, count, nil
+		}
+	}
+	return "", 0, nil
+}
+
+// resetSequences moves PostgreSQL ID sequences past the restored IDs
+func resetSequences(tx *gorm.DB, tables []backupTable) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, table := range tables {
+		if table.model == nil {
+			continue
+		}
+		quoted := tx.Statement.Quote(table.name)
+		sql := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE((SELECT MAX(id) FROM %s), 0) + 1, false)", table.name, quoted)
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyBackupArchive checks the manifest and every checksum, returning the table entries' contents
+func verifyBackupArchive(zr *zip.Reader) (*BackupManifest, map[string][]byte, error) {
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	mf, ok := files[backupManifestName]
+	if !ok {
+		return nil, nil, fmt.Errorf("not a valid backup archive: %s is missing", backupManifestName)
+	}
+	data, err := readZipEntry(mf)
+	if err != nil {
+		return nil, nil, err
+	}
+	var manifest BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+
+	if manifest.FormatVersion > BackupFormatVersion {
+		return nil, nil, fmt.Errorf("backup format version %d is newer than supported version %d", manifest.FormatVersion, BackupFormatVersion)
+	}
+	if manifest.SchemaVersion > migrations.LatestVersion() {
+		return nil, nil, fmt.Errorf("backup schema version %d is newer than this database schema (version %d); upgrade buyer before restoring", manifest.SchemaVersion, migrations.LatestVersion())
+	}
+
+	entries := make(map[string][]byte, len(manifest.Tables))
+	for _, table := range manifest.Tables {
+		f, ok := files[table.Entry]
+		if !ok {
+			return nil, nil, fmt.Errorf("backup is incomplete: %s is missing", table.Entry)
+		}
+		data, err := readZipEntry(f)
+		if err != nil {
+			return nil, nil, err
+		}
+		if sha256Hex(data) != table.SHA256 {
+			return nil, nil, fmt.Errorf("checksum mismatch for %s", table.Entry)
+		}
+		entries[table.Entry] = data
+	}
+
+	for _, file := range manifest.Files {
+		f, ok := files[file.Entry]
+		if !ok {
+			return nil, nil, fmt.Errorf("backup is incomplete: %s is missing", file.Entry)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, nil, err
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		if hex.EncodeToString(h.Sum(nil)) != file.SHA256 {
+			return nil, nil, fmt.Errorf("checksum mismatch for %s", file.Entry)
+		}
+	}
+
+	return &manifest, entries, nil
+}
+
+// isLocalDocumentPath reports whether a document's file path is a file of its
+// own, recorded by Create, rather than a key in the document storage
+func isLocalDocumentPath(filePath string) bool {
+	return filepath.IsAbs(filePath)
+}
+
+// openDocumentFile opens a document's file, from the document storage unless
+// its path is a local file
+func (s *BackupService) openDocumentFile(doc models.Document) (io.ReadCloser, error) {
+	if !isLocalDocumentPath(doc.FilePath) {
+		if s.storage == nil {
+			return nil, errNoStorage
+		}
+		return s.storage.Download(s.db.Statement.Context, storage.BucketForEntityType(doc.EntityType), doc.FilePath)
+	}
+	info, err := os.Stat(doc.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", doc.FilePath)
+	}
+	return os.Open(doc.FilePath)
+}
+
+// addBackupFile copies a document's file into the archive
+func (s *BackupService) addBackupFile(zw *zip.Writer, doc models.Document) (*BackupFile, error) {
+	src, err := s.openDocumentFile(doc)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	entry := path.Join("files", fmt.Sprintf("%d", doc.ID), path.Base(filepath.ToSlash(doc.FilePath)))
+	w, err := zw.Create(entry)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, h), src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BackupFile{
+		DocumentID:   doc.ID,
+		Entry:        entry,
+		OriginalPath: doc.FilePath,
+		Size:         n,
+		SHA256:       hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// restoreBackupFile writes a document's file back to the document storage. A
+// file that was a local file of its own goes into the storage under a new key.
+// With filesDir the file is written below it instead. The document's path is
+// updated when it changes. Nothing is written outside the storage or filesDir.
+func (s *BackupService) restoreBackupFile(tx *gorm.DB, zr *zip.Reader, file BackupFile, filesDir string) error {
+	name := path.Base(file.Entry)
+	if name == "." || name == ".." || name == "/" || strings.ContainsRune(name, '\\') {
+		return &ValidationError{Field: "files", Message: fmt.Sprintf("invalid file entry %q", file.Entry)}
+	}
+	f, err := zr.Open(file.Entry)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if filesDir != "" {
+		target := filepath.Join(filesDir, fmt.Sprintf("%d", file.DocumentID), name)
+		if err := tx.Model(&models.Document{}).Where("id = ?", file.DocumentID).
+			Update("file_path", target).Error; err != nil {
+			return err
+		}
+		return writeRestoredFile(target, f)
+	}
+
+	if s.storage == nil {
+		return errNoStorage
+	}
+	var doc models.Document
+	if err := tx.Select("entity_type", "entity_id").First(&doc, file.DocumentID).Error; err != nil {
+		return err
+	}
+	key := file.OriginalPath
+	if isLocalDocumentPath(key) {
+		if key, err = documentKey(doc.EntityType, doc.EntityID, name, time.Now()); err != nil {
+			return err
+		}
+	}
+	if !validDocumentKey(key) {
+		return &ValidationError{Field: "files", Message: fmt.Sprintf("document %d has an invalid file path %q", file.DocumentID, key)}
+	}
+	if key != file.OriginalPath {
+		if err := tx.Model(&models.Document{}).Where("id = ?", file.DocumentID).
+			Update("file_path", key).Error; err != nil {
+			return err
+		}
+	}
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return s.storage.Upload(tx.Statement.Context, storage.BucketForEntityType(doc.EntityType), key, f, file.Size, contentType)
+}
+
+// validDocumentKey reports whether key is a relative storage key without ".."
+// segments, so that writing it cannot leave the storage
+func validDocumentKey(key string) bool {
+	if key == "" || path.IsAbs(key) || filepath.IsAbs(key) || filepath.VolumeName(key) != "" || strings.ContainsRune(key, '\\') {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// writeRestoredFile writes a restored file to target, creating its directory
+func writeRestoredFile(target string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	dst, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, r); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+func writeZipEntry(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func readZipEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
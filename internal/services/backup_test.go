@@ -0,0 +1,421 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shakfu/buyer/internal/migrations"
+	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/storage"
+	"github.com/shakfu/buyer/internal/storage/s3test"
+	"gorm.io/gorm"
+)
+
+func TestBackupService_RoundTrip(t *testing.T) {
+	source := setupTestDB(t)
+	defer func() { _ = source.Close() }()
+
+	dir := t.TempDir()
+	docPath := filepath.Join(dir, "quote.pdf")
+	if err := os.WriteFile(docPath, []byte("%PDF-1.4 quote"), 0644); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+
+	vendor, _ := NewVendorService(source.DB).Create("Acme", "EUR", "")
+	brand, _ := NewBrandService(source.DB).Create("Apple")
+	if err := NewVendorService(source.DB).AddBrand(vendor.ID, brand.ID); err != nil {
+		t.Fatalf("Failed to add brand: %v", err)
+	}
+	product, _ := NewProductService(source.DB).Create("iPhone 15", brand.ID, nil)
+	source.DB.Model(product).Update("is_active", false)
+	_, _ = NewForexService(source.DB).Create("EUR", "USD", 1.10, time.Now())
+	quote, err := NewQuoteService(source.DB).Create(CreateQuoteInput{
+		VendorID:  vendor.ID,
+		ProductID: product.ID,
+		Price:     999,
+		Currency:  "EUR",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create quote: %v", err)
+	}
+	doc, err := NewDocumentService(source.DB).Create(CreateDocumentInput{
+		EntityType: "quote",
+		EntityID:   quote.ID,
+		FileName:   "quote.pdf",
+		FilePath:   docPath,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create document: %v", err)
+	}
+
+	var buf bytes.Buffer
+	manifest, err := NewBackupService(source.DB).Backup(&buf, BackupOptions{AppVersion: "test"})
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if manifest.SchemaVersion != migrations.LatestVersion() || len(manifest.Files) != 1 {
+		t.Fatalf("Unexpected manifest: %+v", manifest)
+	}
+	if len(manifest.Tables) != len(models.All())+1 {
+		t.Errorf("Expected every model plus vendor_brands, got %d tables", len(manifest.Tables))
+	}
+
+	target := setupTestDB(t)
+	defer func() { _ = target.Close() }()
+
+	filesDir := filepath.Join(dir, "restored")
+	archive := bytes.NewReader(buf.Bytes())
+	result, err := NewBackupService(target.DB).Restore(archive, int64(buf.Len()), RestoreOptions{FilesDir: filesDir})
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if result.Rows["vendor_brands"] != 1 || result.Rows["quotes"] != 1 || result.Files != 1 {
+		t.Errorf("Unexpected restore result: %+v", result)
+	}
+
+	var restored models.Quote
+	if err := target.DB.Preload("Vendor").Preload("Product").First(&restored, quote.ID).Error; err != nil {
+		t.Fatalf("Expected quote %d to be restored: %v", quote.ID, err)
+	}
+	if restored.Vendor.Name != "Acme" || restored.Product.Name != "iPhone 15" {
+		t.Errorf("Expected relationships to be preserved, got %+v", restored)
+	}
+	if restored.Product.IsActive {
+		t.Error("Expected IsActive=false to survive restore despite the column default")
+	}
+
+	restoredVendor, err := NewVendorService(target.DB).GetByID(vendor.ID)
+	if err != nil || len(restoredVendor.Brands) != 1 {
+		t.Errorf("Expected vendor brand link to be restored (err: %v)", err)
+	}
+
+	var restoredDoc models.Document
+	target.DB.First(&restoredDoc, doc.ID)
+	content, err := os.ReadFile(restoredDoc.FilePath)
+	if err != nil || string(content) != "%PDF-1.4 quote" {
+		t.Errorf("Expected document file at %s (err: %v)", restoredDoc.FilePath, err)
+	}
+	if !strings.HasPrefix(restoredDoc.FilePath, filesDir) {
+		t.Errorf("Expected document path below %s, got %s", filesDir, restoredDoc.FilePath)
+	}
+
+	// New records continue after the restored IDs
+	next, err := NewVendorService(target.DB).Create("Globex", "USD", "")
+	if err != nil || next.ID <= vendor.ID {
+		t.Errorf("Expected new vendor ID after %d, got %v (err: %v)", vendor.ID, next, err)
+	}
+
+	t.Run("non-empty target", func(t *testing.T) {
+		_, err := NewBackupService(target.DB).Restore(archive, int64(buf.Len()), RestoreOptions{FilesDir: filesDir})
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected ValidationError, got %v", err)
+		}
+
+		if _, err := NewBackupService(target.DB).Restore(archive, int64(buf.Len()), RestoreOptions{Force: true, FilesDir: filesDir}); err != nil {
+			t.Fatalf("Forced restore failed: %v", err)
+		}
+		var count int64
+		target.DB.Model(&models.Vendor{}).Count(&count)
+		if count != 1 {
+			t.Errorf("Expected forced restore to replace data, found %d vendors", count)
+		}
+	})
+}
+
+func TestBackupService_RestoreFilePaths(t *testing.T) {
+	source := setupTestDB(t)
+	defer func() { _ = source.Close() }()
+	dir := t.TempDir()
+	docPath := filepath.Join(dir, "contract.pdf")
+	if err := os.WriteFile(docPath, []byte("%PDF-1.4 contract"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	vendor, _ := NewVendorService(source.DB).Create("Acme", "USD", "")
+	doc, err := NewDocumentService(source.DB).Create(CreateDocumentInput{EntityType: "vendor", EntityID: vendor.ID, FileName: "contract.pdf", FilePath: docPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := NewBackupService(source.DB).Backup(&buf, BackupOptions{}); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	withOriginalPath := func(originalPath string) []byte {
+		return rewriteBackup(t, buf.Bytes(), func(name string, data []byte) []byte {
+			if name != backupManifestName {
+				return data
+			}
+			var manifest BackupManifest
+			_ = json.Unmarshal(data, &manifest)
+			manifest.Files[0].OriginalPath = originalPath
+			data, _ = json.Marshal(manifest)
+			return data
+		})
+	}
+
+	root := filepath.Join(dir, "storage")
+	store, err := storage.NewLocalBackend(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("local file goes into the storage", func(t *testing.T) {
+		target := setupTestDB(t)
+		defer func() { _ = target.Close() }()
+		elsewhere := filepath.Join(dir, "elsewhere", "contract.pdf")
+		archive := withOriginalPath(elsewhere)
+		if _, err := NewBackupService(target.DB).WithStorage(store).Restore(bytes.NewReader(archive), int64(len(archive)), RestoreOptions{}); err != nil {
+			t.Fatalf("Restore failed: %v", err)
+		}
+		if _, err := os.Stat(elsewhere); err == nil {
+			t.Errorf("Expected nothing written to the original path %s", elsewhere)
+		}
+		reader, restored, err := NewDocumentService(target.DB).WithStorage(store).Download(doc.ID)
+		if err != nil {
+			t.Fatalf("Expected the file in the document storage: %v", err)
+		}
+		content, _ := io.ReadAll(reader)
+		_ = reader.Close()
+		if string(content) != "%PDF-1.4 contract" || !strings.HasPrefix(restored.FilePath, fmt.Sprintf("vendor/%d/", doc.EntityID)) {
+			t.Errorf("Unexpected restored file %s: %q", restored.FilePath, content)
+		}
+	})
+
+	for _, originalPath := range []string{"../escape.pdf", "vendor/1/../../../escape.pdf", `..\escape.pdf`} {
+		t.Run(originalPath, func(t *testing.T) {
+			target := setupTestDB(t)
+			defer func() { _ = target.Close() }()
+			archive := withOriginalPath(originalPath)
+			_, err := NewBackupService(target.DB).WithStorage(store).Restore(bytes.NewReader(archive), int64(len(archive)), RestoreOptions{})
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Errorf("Expected ValidationError, got %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "escape.pdf")); err == nil {
+				t.Error("Expected nothing written outside the storage")
+			}
+		})
+	}
+}
+
+func TestBackupService_UploadedDocument(t *testing.T) {
+	source := setupTestDB(t)
+	defer func() { _ = source.Close() }()
+	sourceStorage, err := storage.NewLocalBackend(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	vendor, _ := NewVendorService(source.DB).Create("Acme", "USD", "")
+	doc, err := NewDocumentService(source.DB).WithStorage(sourceStorage).Upload(CreateDocumentInput{
+		EntityType: "vendor",
+		EntityID:   vendor.ID,
+		FileName:   "contract.pdf",
+	}, strings.NewReader("%PDF-1.4 contract"))
+	if err != nil {
+		t.Fatalf("Failed to upload document: %v", err)
+	}
+
+	var buf bytes.Buffer
+	manifest, err := NewBackupService(source.DB).WithStorage(sourceStorage).Backup(&buf, BackupOptions{})
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if len(manifest.Files) != 1 || len(manifest.MissingFiles) != 0 {
+		t.Fatalf("Expected the uploaded file in the archive, got %+v missing %v", manifest.Files, manifest.MissingFiles)
+	}
+
+	// Restore into an object store
+	server := s3test.New("buyer", "buyer-secret")
+	defer server.Close()
+	targetStorage, _ := storage.NewMinioBackend(server.Endpoint, "buyer", "buyer-secret", false)
+	target := setupTestDB(t)
+	defer func() { _ = target.Close() }()
+	archive := bytes.NewReader(buf.Bytes())
+	if _, err := NewBackupService(target.DB).Restore(archive, int64(buf.Len()), RestoreOptions{}); err == nil {
+		t.Error("Expected an error restoring uploaded files without document storage")
+	}
+	result, err := NewBackupService(target.DB).WithStorage(targetStorage).Restore(archive, int64(buf.Len()), RestoreOptions{})
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if result.Files != 1 {
+		t.Errorf("Expected 1 file restored, got %d", result.Files)
+	}
+	if content, ok := server.Object("vendor-docs", doc.FilePath); !ok || string(content) != "%PDF-1.4 contract" {
+		t.Errorf("Expected the file in the vendor-docs bucket at %s, got %q", doc.FilePath, content)
+	}
+	reader, restored, err := NewDocumentService(target.DB).WithStorage(targetStorage).Download(doc.ID)
+	if err != nil {
+		t.Fatalf("Expected the restored document to download: %v", err)
+	}
+	defer reader.Close()
+	if restored.FilePath != doc.FilePath {
+		t.Errorf("Expected the storage key %s kept, got %s", doc.FilePath, restored.FilePath)
+	}
+}
+
+// createProjectPurchaseOrder creates a purchase order for a project
+// requisition item, so that a restore or copy must load projects and their
+// requisition items before purchase orders
+func createProjectPurchaseOrder(t *testing.T, db *gorm.DB) *models.PurchaseOrder {
+	t.Helper()
+	laptop, _ := NewSpecificationService(db).Create("Laptop", "")
+	vendor, _ := NewVendorService(db).Create("Acme", "USD", "")
+	brand, _ := NewBrandService(db).Create("Generic")
+	product, _ := NewProductService(db).Create("Laptop 14", brand.ID, &laptop.ID)
+	quote, err := NewQuoteService(db).Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 100, Currency: "USD"})
+	if err != nil {
+		t.Fatalf("Failed to create quote: %v", err)
+	}
+	projectSvc := NewProjectService(db)
+	project, err := projectSvc.Create("Office", "", 10000, nil)
+	if err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	bomItem, err := projectSvc.AddBillOfMaterialsItem(project.ID, laptop.ID, 5, "")
+	if err != nil {
+		t.Fatalf("Failed to add BOM item: %v", err)
+	}
+	projectReq, err := NewProjectRequisitionService(db).Create(project.ID, "Phase 1", "", 0,
+		[]ProjectRequisitionItemInput{{BOMItemID: bomItem.ID, QuantityRequested: 5}})
+	if err != nil {
+		t.Fatalf("Failed to create project requisition: %v", err)
+	}
+	db.Model(projectReq).Update("status", RequisitionApproved)
+	po, err := NewPurchaseOrderService(db).Create(CreatePurchaseOrderInput{QuoteID: quote.ID, ProjectRequisitionItemID: &projectReq.Items[0].ID, PONumber: "PO-PROJECT", Quantity: 2})
+	if err != nil {
+		t.Fatalf("Failed to create purchase order: %v", err)
+	}
+	return po
+}
+
+func TestBackupService_ProjectPurchaseOrder(t *testing.T) {
+	source := setupTestDB(t)
+	defer func() { _ = source.Close() }()
+	po := createProjectPurchaseOrder(t, source.DB)
+
+	var buf bytes.Buffer
+	if _, err := NewBackupService(source.DB).Backup(&buf, BackupOptions{}); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	target := setupTestDB(t)
+	defer func() { _ = target.Close() }()
+	if _, err := NewBackupService(target.DB).Restore(bytes.NewReader(buf.Bytes()), int64(buf.Len()), RestoreOptions{}); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	var restored models.PurchaseOrder
+	if err := target.DB.First(&restored, po.ID).Error; err != nil {
+		t.Fatalf("Expected purchase order %d to be restored: %v", po.ID, err)
+	}
+	if restored.ProjectID == nil || *restored.ProjectID != *po.ProjectID {
+		t.Errorf("Expected the order's project to be kept, got %v", restored.ProjectID)
+	}
+	if restored.ProjectRequisitionItemID == nil || *restored.ProjectRequisitionItemID != *po.ProjectRequisitionItemID {
+		t.Errorf("Expected the order's project requisition item to be kept, got %v", restored.ProjectRequisitionItemID)
+	}
+}
+
+func TestBackupService_Verify(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	_, _ = NewBrandService(cfg.DB).Create("Apple")
+	svc := NewBackupService(cfg.DB)
+
+	var buf bytes.Buffer
+	if _, err := svc.Backup(&buf, BackupOptions{}); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if _, err := svc.Verify(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatalf("Expected valid archive: %v", err)
+	}
+
+	t.Run("tampered data", func(t *testing.T) {
+		tampered := rewriteBackup(t, buf.Bytes(), func(name string, data []byte) []byte {
+			if name == "data/brands.json" {
+				return bytes.Replace(data, []byte("Apple"), []byte("Pear!"), 1)
+			}
+			return data
+		})
+		_, err := svc.Verify(bytes.NewReader(tampered), int64(len(tampered)))
+		if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Errorf("Expected checksum error, got %v", err)
+		}
+	})
+
+	t.Run("newer schema", func(t *testing.T) {
+		newer := rewriteBackup(t, buf.Bytes(), func(name string, data []byte) []byte {
+			if name != backupManifestName {
+				return data
+			}
+			var manifest BackupManifest
+			_ = json.Unmarshal(data, &manifest)
+			manifest.SchemaVersion = migrations.LatestVersion() + 1
+			out, _ := json.Marshal(manifest)
+			return out
+		})
+		_, err := svc.Restore(bytes.NewReader(newer), int64(len(newer)), RestoreOptions{Force: true})
+		if err == nil || !strings.Contains(err.Error(), "schema version") {
+			t.Errorf("Expected schema version error, got %v", err)
+		}
+	})
+}
+
+func TestPruneBackups(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		"buyer-backup-20240101-000000.zip",
+		"buyer-backup-20240102-000000.zip",
+		"buyer-backup-20240103-000000.zip",
+		"other.zip",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := PruneBackups(dir, 2)
+	if err != nil {
+		t.Fatalf("PruneBackups failed: %v", err)
+	}
+	if len(removed) != 1 || filepath.Base(removed[0]) != names[0] {
+		t.Errorf("Expected only the oldest backup to be removed, got %v", removed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other.zip")); err != nil {
+		t.Error("Expected unrelated files to be kept")
+	}
+}
+
+// rewriteBackup copies an archive, passing each entry through edit
+func rewriteBackup(t *testing.T, archive []byte, edit func(name string, data []byte) []byte) []byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		w, _ := zw.Create(f.Name)
+		_, _ = w.Write(edit(f.Name, data))
+	}
+	_ = zw.Close()
+	return out.Bytes()
+}
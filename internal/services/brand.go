@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"strings"
 
@@ -18,6 +19,11 @@ func NewBrandService(db *gorm.DB) *BrandService {
 	return &BrandService{db: db}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *BrandService) WithContext(ctx context.Context) *BrandService {
+	return NewBrandService(s.db.WithContext(ctx))
+}
+
 // Create creates a new brand
 func (s *BrandService) Create(name string) (*models.Brand, error) {
 	name = strings.TrimSpace(name)
@@ -27,9 +33,9 @@ func (s *BrandService) Create(name string) (*models.Brand, error) {
 
 	// Check for duplicate
 	var existing models.Brand
-	err := s.db.Where("name = ?", name).First(&existing).Error
+	err := s.db.Unscoped().Where("name = ?", name).First(&existing).Error
 	if err == nil {
-		return nil, &DuplicateError{Entity: "Brand", Name: name}
+		return nil, &DuplicateError{Entity: "Brand", Name: name, InTrash: existing.DeletedAt.Valid}
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
@@ -100,9 +106,9 @@ func (s *BrandService) Update(id uint, newName string) (*models.Brand, error) {
 
 	// Check for duplicate name
 	var existing models.Brand
-	err = s.db.Where("name = ? AND id != ?", newName, id).First(&existing).Error
+	err = s.db.Unscoped().Where("name = ? AND id != ?", newName, id).First(&existing).Error
 	if err == nil {
-		return nil, &DuplicateError{Entity: "Brand", Name: newName}
+		return nil, &DuplicateError{Entity: "Brand", Name: newName, InTrash: existing.DeletedAt.Valid}
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
@@ -116,16 +122,9 @@ func (s *BrandService) Update(id uint, newName string) (*models.Brand, error) {
 	return brand, nil
 }
 
-// Delete deletes a brand by ID
+// Delete moves a brand to the trash
 func (s *BrandService) Delete(id uint) error {
-	result := s.db.Delete(&models.Brand{}, id)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return &NotFoundError{Entity: "Brand", ID: id}
-	}
-	return nil
+	return moveToTrash(s.db, "brand", id)
 }
 
 // Count returns the total number of brands
@@ -14,27 +14,7 @@ func setupTestDB(t *testing.T) *config.Config {
 	}
 
 	// Run migrations for all models
-	if err := cfg.AutoMigrate(
-		&models.Vendor{},
-		&models.Brand{},
-		&models.Specification{},
-		&models.SpecificationAttribute{},
-		&models.Product{},
-		&models.ProductAttribute{},
-		&models.Quote{},
-		&models.Forex{},
-		&models.Requisition{},
-		&models.RequisitionItem{},
-		&models.PurchaseOrder{},
-		&models.Document{},
-		&models.VendorRating{},
-		&models.Project{},
-		&models.BillOfMaterials{},
-		&models.BillOfMaterialsItem{},
-		&models.ProjectRequisition{},
-		&models.ProjectRequisitionItem{},
-		&models.ProjectProcurementStrategy{},
-	); err != nil {
+	if err := cfg.AutoMigrate(models.All()...); err != nil {
 		t.Fatalf("Failed to run migrations: %v", err)
 	}
 
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// Coding is the cost center and GL account that a purchase is charged to.
+// A nil ID keeps the current value and an ID of 0 clears it.
+type Coding struct {
+	CostCenterID *uint
+	GLAccountID  *uint
+}
+
+// SetCoding sets the default cost center and GL account of the requisition's
+// purchase orders
+func (s *RequisitionService) SetCoding(id uint, coding Coding) (*models.Requisition, error) {
+	var requisition models.Requisition
+	if err := setCoding(s.db, &requisition, "Requisition", id, coding); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// SetCoding sets the default cost center and GL account of the project's
+// purchase orders
+func (s *ProjectService) SetCoding(id uint, coding Coding) (*models.Project, error) {
+	var project models.Project
+	if err := setCoding(s.db, &project, "Project", id, coding); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// SetCoding overrides the cost center and GL account of a purchase order.
+// Once an order is past approval its coding cannot be cleared.
+func (s *PurchaseOrderService) SetCoding(id uint, coding Coding) (*models.PurchaseOrder, error) {
+	var po models.PurchaseOrder
+	if err := setCoding(s.db, &po, "purchase order", id, coding); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// setCoding applies coding to the requisition, project or purchase order
+// record with id
+func setCoding(db *gorm.DB, record interface{}, entity string, id uint, coding Coding) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(record, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &NotFoundError{Entity: entity, ID: id}
+			}
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		for _, field := range []struct {
+			column string
+			id     *uint
+		}{
+			{"cost_center_id", coding.CostCenterID},
+			{"gl_account_id", coding.GLAccountID},
+		} {
+			switch {
+			case field.id == nil:
+			case *field.id == 0:
+				updates[field.column] = nil
+			default:
+				if err := checkCodeActive(tx, field.column, *field.id); err != nil {
+					return err
+				}
+				updates[field.column] = *field.id
+			}
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := tx.Model(record).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		// Past approval, the order must stay fully coded
+		if po, ok := record.(*models.PurchaseOrder); ok && po.Status != "pending" && po.Status != "rejected" && po.Status != "cancelled" {
+			if err := tx.First(po, id).Error; err != nil {
+				return err
+			}
+			return validateCoding(tx, po)
+		}
+		return nil
+	})
+}
+
+// checkCodeActive checks that the cost center or GL account with id exists
+// and is active, so that it can be used for new coding
+func checkCodeActive(db *gorm.DB, column string, id uint) error {
+	var record interface{} = &models.CostCenter{}
+	entity := "cost center"
+	if column == "gl_account_id" {
+		record, entity = &models.GLAccount{}, "GL account"
+	}
+	if err := db.First(record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &NotFoundError{Entity: entity, ID: id}
+		}
+		return err
+	}
+	var code string
+	var active bool
+	switch r := record.(type) {
+	case *models.CostCenter:
+		code, active = r.Code, r.Active
+	case *models.GLAccount:
+		code, active = r.Code, r.Active
+	}
+	if !active {
+		return &ValidationError{Field: column, Message: fmt.Sprintf("%s %s is inactive", entity, code)}
+	}
+	return nil
+}
+
+// applyDefaultCoding fills in the cost center and GL account a purchase order
+// lacks from its requisition and then its project
+func applyDefaultCoding(db *gorm.DB, po *models.PurchaseOrder) error {
+	var defaults []Coding
+	if po.RequisitionID != nil {
+		var requisition models.Requisition
+		if err := db.Unscoped().First(&requisition, *po.RequisitionID).Error; err != nil {
+			return err
+		}
+		defaults = append(defaults, Coding{CostCenterID: requisition.CostCenterID, GLAccountID: requisition.GLAccountID})
+	}
+	if po.ProjectID != nil {
+		var project models.Project
+		if err := db.Unscoped().First(&project, *po.ProjectID).Error; err != nil {
+			return err
+		}
+		defaults = append(defaults, Coding{CostCenterID: project.CostCenterID, GLAccountID: project.GLAccountID})
+	}
+	for _, d := range defaults {
+		if po.CostCenterID == nil {
+			po.CostCenterID = d.CostCenterID
+		}
+		if po.GLAccountID == nil {
+			po.GLAccountID = d.GLAccountID
+		}
+	}
+	return nil
+}
+
+// validateCoding checks that a purchase order is coded for accounting before
+// it is approved. Once any active cost center exists every order needs one,
+// and likewise for GL accounts; the codes used must be active.
+func validateCoding(db *gorm.DB, po *models.PurchaseOrder) error {
+	var missing []string
+	for _, field := range []struct {
+		column, label string
+		model         interface{}
+		id            *uint
+	}{
+		{"cost_center_id", "cost center", &models.CostCenter{}, po.CostCenterID},
+		{"gl_account_id", "GL account", &models.GLAccount{}, po.GLAccountID},
+	} {
+		if field.id != nil {
+			if err := checkCodeActive(db, field.column, *field.id); err != nil {
+				var notFound *NotFoundError
+				if errors.As(err, &notFound) {
+					return &ValidationError{Field: field.column, Message: fmt.Sprintf("purchase order %s is coded to a %s that no longer exists", po.PONumber, field.label)}
+				}
+				return err
+			}
+			continue
+		}
+		var count int64
+		if err := db.Model(field.model).Where("active = ?", true).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			missing = append(missing, field.label)
+		}
+	}
+	if len(missing) > 0 {
+		return &ValidationError{Field: "coding", Message: fmt.Sprintf("purchase order %s needs a %s before it can be approved", po.PONumber, strings.Join(missing, " and a "))}
+	}
+	return nil
+}
+
+// checkCoding fills in default coding and validates it when a pending
+// purchase order moves on to status, as checkApprovals does for approvals
+func checkCoding(db *gorm.DB, po *models.PurchaseOrder, status string) error {
+	if po.Status != "pending" || status == "pending" || status == "rejected" || status == "cancelled" {
+		return nil
+	}
+	if err := applyDefaultCoding(db, po); err != nil {
+		return err
+	}
+	return validateCoding(db, po)
+}
+
+// CostCenterSpend is the spend charged to one cost center
+type CostCenterSpend struct {
+	CostCenter *models.CostCenter // Nil for orders without a cost center
+	Orders     int
+	Spend
+}
+
+// GetSpendByCostCenter returns the committed, received and invoiced spend of
+// purchase orders per cost center, ordered by code, with uncoded orders last
+func (s *DashboardService) GetSpendByCostCenter() ([]CostCenterSpend, error) {
+	var orders []models.PurchaseOrder
+	if err := s.db.Preload("Quote", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("status NOT IN ?", []string{"cancelled", "rejected"}).
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	var centers []models.CostCenter
+	if err := s.db.Order("code").Find(&centers).Error; err != nil {
+		return nil, err
+	}
+	report := make([]CostCenterSpend, 0, len(centers)+1)
+	index := map[uint]int{}
+	for i := range centers {
+		report = append(report, CostCenterSpend{CostCenter: &centers[i]})
+		index[centers[i].ID] = i
+	}
+	uncoded := CostCenterSpend{}
+	for i := range orders {
+		po := &orders[i]
+		row := &uncoded
+		if po.CostCenterID != nil {
+			if j, ok := index[*po.CostCenterID]; ok {
+				row = &report[j]
+			}
+		}
+		row.Orders++
+		row.add(po)
+	}
+	if uncoded.Orders > 0 {
+		report = append(report, uncoded)
+	}
+	return report, nil
+}
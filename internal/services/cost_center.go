@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// CostCenterService handles business logic for cost centers
+type CostCenterService struct {
+	db *gorm.DB
+}
+
+// NewCostCenterService creates a new cost center service
+func NewCostCenterService(db *gorm.DB) *CostCenterService {
+	return &CostCenterService{db: db}
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *CostCenterService) WithContext(ctx context.Context) *CostCenterService {
+	return NewCostCenterService(s.db.WithContext(ctx))
+}
+
+// Create creates a new, active cost center
+func (s *CostCenterService) Create(code, name string) (*models.CostCenter, error) {
+	code, name, err := validateAccountingCode(code, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing models.CostCenter
+	err = s.db.Where("code = ?", code).First(&existing).Error
+	if err == nil {
+		return nil, &DuplicateError{Entity: "Cost center", Name: code}
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	center := &models.CostCenter{Code: code, Name: name, Active: true}
+	if err := s.db.Create(center).Error; err != nil {
+		return nil, err
+	}
+	return center, nil
+}
+
+// GetByID retrieves a cost center by ID
+func (s *CostCenterService) GetByID(id uint) (*models.CostCenter, error) {
+	var center models.CostCenter
+	err := s.db.First(&center, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &NotFoundError{Entity: "Cost center", ID: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &center, nil
+}
+
+// GetByCode retrieves a cost center by code
+func (s *CostCenterService) GetByCode(code string) (*models.CostCenter, error) {
+	code = strings.TrimSpace(code)
+	var center models.CostCenter
+	err := s.db.Where("code = ?", code).First(&center).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &NotFoundError{Entity: "Cost center", ID: code}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &center, nil
+}
+
+// List retrieves all cost centers ordered by code, optionally only the
+// active ones
+func (s *CostCenterService) List(activeOnly bool) ([]models.CostCenter, error) {
+	var centers []models.CostCenter
+	query := s.db.Order("code")
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	err := query.Find(&centers).Error
+	return centers, err
+}
+
+// Update renames a cost center and activates or deactivates it. Orders
+// already charged to an inactive cost center keep it.
+func (s *CostCenterService) Update(id uint, name string, active bool) (*models.CostCenter, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &ValidationError{Field: "name", Message: "name cannot be empty"}
+	}
+	center, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	center.Name = name
+	center.Active = active
+	if err := s.db.Save(center).Error; err != nil {
+		return nil, err
+	}
+	return center, nil
+}
+
+// Delete deletes a cost center that no purchase order is charged to.
+// Requisitions and projects using it as their default lose the default.
+func (s *CostCenterService) Delete(id uint) error {
+	center, err := s.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := checkCodeUnused(s.db, "cost_center_id", id, "cost center "+center.Code); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := clearDefaultCoding(tx, "cost_center_id", id); err != nil {
+			return err
+		}
+		return tx.Delete(center).Error
+	})
+}
+
+// GLAccountService handles business logic for general-ledger accounts
+type GLAccountService struct {
+	db *gorm.DB
+}
+
+// NewGLAccountService creates a new GL account service
+func NewGLAccountService(db *gorm.DB) *GLAccountService {
+	return &GLAccountService{db: db}
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *GLAccountService) WithContext(ctx context.Context) *GLAccountService {
+	return NewGLAccountService(s.db.WithContext(ctx))
+}
+
+// Create creates a new, active GL account
+func (s *GLAccountService) Create(code, name string) (*models.GLAccount, error) {
+	code, name, err := validateAccountingCode(code, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing models.GLAccount
+	err = s.db.Where("code = ?", code).First(&existing).Error
+	if err == nil {
+		return nil, &DuplicateError{Entity: "GL account", Name: code}
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	account := &models.GLAccount{Code: code, Name: name, Active: true}
+	if err := s.db.Create(account).Error; err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// GetByID retrieves a GL account by ID
+func (s *GLAccountService) GetByID(id uint) (*models.GLAccount, error) {
+	var account models.GLAccount
+	err := s.db.First(&account, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &NotFoundError{Entity: "GL account", ID: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// GetByCode retrieves a GL account by code
+func (s *GLAccountService) GetByCode(code string) (*models.GLAccount, error) {
+	code = strings.TrimSpace(code)
+	var account models.GLAccount
+	err := s.db.Where("code = ?", code).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &NotFoundError{Entity: "GL account", ID: code}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// List retrieves all GL accounts ordered by code, optionally only the active
+// ones
+func (s *GLAccountService) List(activeOnly bool) ([]models.GLAccount, error) {
+	var accounts []models.GLAccount
+	query := s.db.Order("code")
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	err := query.Find(&accounts).Error
+	return accounts, err
+}
+
+// Update renames a GL account and activates or deactivates it. Orders
+// already booked to an inactive account keep it.
+func (s *GLAccountService) Update(id uint, name string, active bool) (*models.GLAccount, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, &ValidationError{Field: "name", Message: "name cannot be empty"}
+	}
+	account, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	account.Name = name
+	account.Active = active
+	if err := s.db.Save(account).Error; err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// Delete deletes a GL account that no purchase order is booked to.
+// Requisitions and projects using it as their default lose the default.
+func (s *GLAccountService) Delete(id uint) error {
+	account, err := s.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := checkCodeUnused(s.db, "gl_account_id", id, "GL account "+account.Code); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := clearDefaultCoding(tx, "gl_account_id", id); err != nil {
+			return err
+		}
+		return tx.Delete(account).Error
+	})
+}
+
+// validateAccountingCode trims and checks the code and name of a cost center
+// or GL account
+func validateAccountingCode(code, name string) (string, string, error) {
+	code = strings.TrimSpace(code)
+	name = strings.TrimSpace(name)
+	if code == "" {
+		return "", "", &ValidationError{Field: "code", Message: "code cannot be empty"}
+	}
+	if len(code) > 20 {
+		return "", "", &ValidationError{Field: "code", Message: "code cannot be longer than 20 characters"}
+	}
+	if name == "" {
+		return "", "", &ValidationError{Field: "name", Message: "name cannot be empty"}
+	}
+	return code, name, nil
+}
+
+// checkCodeUnused refuses to delete a cost center or GL account that
+// purchase orders, including those in the trash, are coded to
+func checkCodeUnused(db *gorm.DB, column string, id uint, label string) error {
+	var count int64
+	if err := db.Unscoped().Model(&models.PurchaseOrder{}).Where(column+" = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return &ValidationError{Field: "id", Message: fmt.Sprintf("%s is used by %d purchase orders; deactivate it instead", label, count)}
+	}
+	return nil
+}
+
+// clearDefaultCoding removes a cost center or GL account from the
+// requisitions and projects that default to it
+func clearDefaultCoding(db *gorm.DB, column string, id uint) error {
+	for _, model := range []interface{}{&models.Requisition{}, &models.Project{}} {
+		if err := db.Unscoped().Model(model).Where(column+" = ?", id).Update(column, nil).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCostCenterService(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	svc := NewCostCenterService(cfg.DB)
+	eng, err := svc.Create(" ENG ", "Engineering")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eng.Code != "ENG" || !eng.Active {
+		t.Errorf("Expected an active cost center ENG, got %+v", eng)
+	}
+	if _, err := svc.Create("OPS", "Operations"); err != nil {
+		t.Fatal(err)
+	}
+
+	var validationErr *ValidationError
+	var duplicateErr *DuplicateError
+	var notFoundErr *NotFoundError
+	if _, err := svc.Create("ENG", "Again"); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected a DuplicateError, got %v", err)
+	}
+	if _, err := svc.Create("", "Nameless"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for an empty code, got %v", err)
+	}
+	if _, err := svc.Create("A-CODE-LONGER-THAN-20", "Long"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for a long code, got %v", err)
+	}
+	if _, err := svc.GetByCode("NOPE"); !errors.As(err, &notFoundErr) {
+		t.Errorf("Expected a NotFoundError, got %v", err)
+	}
+
+	if _, err := svc.Update(eng.ID, "Engineering & Research", false); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := svc.List(true); len(active) != 1 || active[0].Code != "OPS" {
+		t.Errorf("Expected only OPS active, got %+v", active)
+	}
+	if all, _ := svc.List(false); len(all) != 2 || all[0].Name != "Engineering & Research" {
+		t.Errorf("Expected two cost centers by code, got %+v", all)
+	}
+
+	// GL accounts behave the same way
+	accounts := NewGLAccountService(cfg.DB)
+	if _, err := accounts.Create("6100", "IT Equipment"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := accounts.Create("6100", "Duplicate"); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected a DuplicateError, got %v", err)
+	}
+	if account, err := accounts.GetByCode("6100"); err != nil || account.Name != "IT Equipment" {
+		t.Errorf("Expected GL account 6100, got %+v, %v", account, err)
+	}
+}
+
+func TestPurchaseOrderCoding(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	vendor, _ := NewVendorService(cfg.DB).Create("Acme", "USD", "")
+	brand, _ := NewBrandService(cfg.DB).Create("Apple")
+	product, _ := NewProductService(cfg.DB).Create("MacBook", brand.ID, nil)
+	quote, err := NewQuoteService(cfg.DB).Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 100, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	poSvc := NewPurchaseOrderService(cfg.DB)
+	newPO := func(input CreatePurchaseOrderInput) uint {
+		t.Helper()
+		input.QuoteID, input.Quantity = quote.ID, 1
+		po, err := poSvc.Create(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return po.ID
+	}
+
+	// Without master data nothing needs coding
+	uncoded := newPO(CreatePurchaseOrderInput{})
+	if _, err := poSvc.UpdateStatus(uncoded, "approved"); err != nil {
+		t.Errorf("Expected an order to be approved without cost centers: %v", err)
+	}
+
+	centers := NewCostCenterService(cfg.DB)
+	eng, _ := centers.Create("ENG", "Engineering")
+	ops, _ := centers.Create("OPS", "Operations")
+	old, _ := centers.Create("OLD", "Retired")
+	if _, err := centers.Update(old.ID, old.Name, false); err != nil {
+		t.Fatal(err)
+	}
+	it, _ := NewGLAccountService(cfg.DB).Create("6100", "IT Equipment")
+
+	// Requisition defaults win over project defaults, and the order can
+	// override both
+	projects := NewProjectService(cfg.DB)
+	project, _ := projects.Create("Office", "", 0, nil)
+	if _, err := projects.SetCoding(project.ID, Coding{CostCenterID: &ops.ID, GLAccountID: &it.ID}); err != nil {
+		t.Fatal(err)
+	}
+	requisitions := NewRequisitionService(cfg.DB)
+	req, _ := requisitions.Create("Laptops", "", 0, nil)
+	cfg.DB.Model(req).Update("status", RequisitionApproved)
+	if req, err = requisitions.SetCoding(req.ID, Coding{CostCenterID: &eng.ID}); err != nil || req.CostCenter == nil || req.CostCenter.Code != "ENG" {
+		t.Fatalf("Expected requisition coded to ENG, got %+v, %v", req, err)
+	}
+
+	fromDefaults := newPO(CreatePurchaseOrderInput{RequisitionID: &req.ID, ProjectID: &project.ID})
+	if po, _ := poSvc.GetByID(fromDefaults); po.CostCenter == nil || po.CostCenter.Code != "ENG" || po.GLAccount == nil || po.GLAccount.Code != "6100" {
+		t.Errorf("Expected ENG and 6100 from the defaults, got %+v", po)
+	}
+	overridden := newPO(CreatePurchaseOrderInput{ProjectID: &project.ID, CostCenterID: &eng.ID})
+	if po, _ := poSvc.GetByID(overridden); *po.CostCenterID != eng.ID || *po.GLAccountID != it.ID {
+		t.Errorf("Expected the ENG override and the project's GL account, got %+v", po)
+	}
+
+	var validationErr *ValidationError
+	if _, err := poSvc.Create(CreatePurchaseOrderInput{QuoteID: quote.ID, Quantity: 1, CostCenterID: &old.ID}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for an inactive cost center, got %v", err)
+	}
+
+	// An uncoded order cannot be approved until it is coded
+	missing := newPO(CreatePurchaseOrderInput{})
+	if _, err := poSvc.UpdateStatus(missing, "approved"); !errors.As(err, &validationErr) || validationErr.Field != "coding" {
+		t.Errorf("Expected a coding ValidationError, got %v", err)
+	}
+	if _, err := poSvc.SetCoding(missing, Coding{CostCenterID: &ops.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := poSvc.UpdateStatus(missing, "approved"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected the GL account still missing, got %v", err)
+	}
+	if _, err := poSvc.SetCoding(missing, Coding{GLAccountID: &it.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := poSvc.UpdateStatus(missing, "approved"); err != nil {
+		t.Errorf("Expected the coded order to be approved: %v", err)
+	}
+	zero := uint(0)
+	if _, err := poSvc.SetCoding(missing, Coding{CostCenterID: &zero}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected an approved order to keep its cost center, got %v", err)
+	}
+
+	// Approvals check the coding on the last step
+	if _, err := NewApprovalService(cfg.DB).CreateRule(ApprovalRuleInput{Step: 1, MinAmount: 50, Approvers: []string{"alice"}}); err != nil {
+		t.Fatal(err)
+	}
+	pending := newPO(CreatePurchaseOrderInput{})
+	if _, err := NewApprovalService(cfg.DB).Approve(pending, "alice", ""); !errors.As(err, &validationErr) {
+		t.Errorf("Expected an uncoded order not to be approved, got %v", err)
+	}
+	if _, err := NewApprovalService(cfg.DB).Approve(fromDefaults, "alice", ""); err != nil {
+		t.Errorf("Expected a coded order to be approved: %v", err)
+	}
+
+	// Codes in use cannot be deleted; unused ones are removed from defaults
+	if err := centers.Delete(eng.ID); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError deleting a cost center in use, got %v", err)
+	}
+	spare, _ := centers.Create("SPARE", "Spare")
+	if _, err := projects.SetCoding(project.ID, Coding{CostCenterID: &spare.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := centers.Delete(spare.ID); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := projects.GetByID(project.ID); p.CostCenterID != nil || p.GLAccountID == nil {
+		t.Errorf("Expected the project to lose only its cost center, got %+v", p)
+	}
+
+	// Spend by cost center
+	spend, err := NewDashboardService(cfg.DB).GetSpendByCostCenter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spend) != 4 || spend[0].CostCenter.Code != "ENG" || spend[0].Orders != 2 || spend[0].Committed != 200 ||
+		spend[2].CostCenter.Code != "OPS" || spend[2].Orders != 1 ||
+		spend[3].CostCenter != nil || spend[3].Orders != 2 {
+		t.Errorf("Expected spend for ENG, OLD, OPS and uncoded orders, got %+v", spend)
+	}
+}
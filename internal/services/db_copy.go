@@ -0,0 +1,280 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/shakfu/buyer/internal/migrations"
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultCopyBatchSize is the number of rows copied per transaction
+const DefaultCopyBatchSize = 500
+
+// DatabaseCopyService copies every table from one database to another, for
+// example from the default SQLite file to a shared PostgreSQL server
+type DatabaseCopyService struct {
+	src *gorm.DB
+	dst *gorm.DB
+}
+
+// NewDatabaseCopyService creates a new database copy service
+func NewDatabaseCopyService(src, dst *gorm.DB) *DatabaseCopyService {
+	return &DatabaseCopyService{src: src, dst: dst}
+}
+
+// CopyOptions configures Copy
+type CopyOptions struct {
+	// Resume continues an interrupted copy: rows already in the target are kept
+	// and only rows with higher IDs are copied. Without it the target must be empty.
+	Resume    bool
+	BatchSize int
+	// Progress, if set, is called after each committed batch
+	Progress func(table string, copied, total int64)
+}
+
+// CopyTableResult reports the row counts of one table
+type CopyTableResult struct {
+	Name       string `json:"name"`
+	SourceRows int64  `json:"source_rows"`
+	Copied     int64  `json:"copied"`
+	TargetRows int64  `json:"target_rows"`
+}
+
+// CopyResult summarizes a copy and its verification
+type CopyResult struct {
+	Tables   []CopyTableResult `json:"tables"`
+	Problems []string          `json:"problems,omitempty"` // Row count mismatches and foreign key violations
+	Warnings []string          `json:"warnings,omitempty"`
+}
+
+// Copy creates the schema in the target, copies every table in dependency order in
+// committed batches, resets PostgreSQL sequences and verifies the result. An error
+// is returned if verification finds row count mismatches or broken foreign keys.
+func (s *DatabaseCopyService) Copy(opts CopyOptions) (*CopyResult, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultCopyBatchSize
+	}
+
+	tables, err := backupTables(s.dst)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both databases must be at the schema version of the models used to copy rows
+	srcMigrator, err := migrations.NewMigrator(s.src)
+	if err != nil {
+		return nil, err
+	}
+	if version, err := srcMigrator.Current(); err != nil {
+		return nil, err
+	} else if version != srcMigrator.Latest() {
+		return nil, &ValidationError{Field: "source", Message: fmt.Sprintf("source database schema is at version %d, expected %d; run 'buyer db migrate' against it first", version, srcMigrator.Latest())}
+	}
+	dstMigrator, err := migrations.NewMigrator(s.dst)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dstMigrator.Up(); err != nil {
+		return nil, fmt.Errorf("failed to create target schema: %w", err)
+	}
+
+	if !opts.Resume {
+		table, count, err := firstNonEmptyTable(s.dst, tables)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, &ValidationError{Field: "target", Message: fmt.Sprintf("target database is not empty (%s has %d rows); use resume to continue an interrupted copy", table, count)}
+		}
+	}
+
+	result := &CopyResult{}
+	for _, table := range tables {
+		if !s.src.Migrator().HasTable(table.name) {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("table %s does not exist in the source and was skipped", table.name))
+			continue
+		}
+
+		var copied int64
+		if table.model == nil {
+			copied, err = s.copyJoinTable(table, opts)
+		} else {
+			copied, err = s.copyModelTable(table, opts)
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to copy %s: %w", table.name, err)
+		}
+		result.Tables = append(result.Tables, CopyTableResult{Name: table.name, Copied: copied})
+	}
+
+	if err := resetSequences(s.dst, tables); err != nil {
+		return result, fmt.Errorf("failed to reset sequences: %w", err)
+	}
+
+	if err := s.verify(result); err != nil {
+		return result, err
+	}
+	if len(result.Problems) > 0 {
+		return result, fmt.Errorf("verification failed: %s", strings.Join(result.Problems, "; "))
+	}
+	return result, nil
+}
+
+// copyModelTable copies rows with IDs above the highest ID already in the target,
+// one committed batch at a time, so that an interrupted copy can be resumed
+func (s *DatabaseCopyService) copyModelTable(table backupTable, opts CopyOptions) (int64, error) {
+	var total int64
+	if err := s.src.Table(table.name).Count(&total).Error; err != nil {
+		return 0, err
+	}
+
+	var lastID uint
+	if err := s.dst.Table(table.name).Select("COALESCE(MAX(id), 0)").Scan(&lastID).Error; err != nil {
+		return 0, err
+	}
+
+	var existing int64
+	if err := s.dst.Table(table.name).Count(&existing).Error; err != nil {
+		return 0, err
+	}
+
+	sliceType := reflect.SliceOf(reflect.TypeOf(table.model).Elem())
+	var copied int64
+	for {
+		rows := reflect.New(sliceType)
+		if err := s.src.Unscoped().Where("id > ?", lastID).Order("id ASC").
+			Limit(opts.BatchSize).Find(rows.Interface()).Error; err != nil {
+			return copied, err
+		}
+		n := rows.Elem().Len()
+		if n == 0 {
+			return copied, nil
+		}
+
+		if err := s.dst.Transaction(func(tx *gorm.DB) error {
+			return insertModelRows(tx, table, rows)
+		}); err != nil {
+			return copied, err
+		}
+
+		copied += int64(n)
+		last := rows.Elem().Index(n - 1).FieldByName("ID")
+		lastID = uint(last.Uint())
+		if opts.Progress != nil {
+			opts.Progress(table.name, existing+copied, total)
+		}
+	}
+}
+
+// copyJoinTable copies a many2many join table in one transaction. Join tables have
+// no ID to resume from, so a partially copied table is copied again.
+func (s *DatabaseCopyService) copyJoinTable(table backupTable, opts CopyOptions) (int64, error) {
+	var rows []map[string]interface{}
+	if err := s.src.Table(table.name).Find(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	var existing int64
+	if err := s.dst.Table(table.name).Count(&existing).Error; err != nil {
+		return 0, err
+	}
+	if existing == int64(len(rows)) {
+		return 0, nil
+	}
+
+	err := s.dst.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM " + tx.Statement.Quote(table.name)).Error; err != nil {
+			return err
+		}
+		return insertJoinRows(tx, table.name, rows)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if opts.Progress != nil {
+		opts.Progress(table.name, int64(len(rows)), int64(len(rows)))
+	}
+	return int64(len(rows)), nil
+}
+
+// verify compares row counts between source and target and checks every foreign
+// key in the target, recording any problems in result
+func (s *DatabaseCopyService) verify(result *CopyResult) error {
+	for i := range result.Tables {
+		t := &result.Tables[i]
+		if err := s.src.Table(t.Name).Count(&t.SourceRows).Error; err != nil {
+			return err
+		}
+		if err := s.dst.Table(t.Name).Count(&t.TargetRows).Error; err != nil {
+			return err
+		}
+		if t.SourceRows != t.TargetRows {
+			result.Problems = append(result.Problems, fmt.Sprintf("%s has %d rows in the source but %d in the target", t.Name, t.SourceRows, t.TargetRows))
+		}
+	}
+
+	refs, err := foreignKeyReferences(s.dst)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		var orphans int64
+		sql := fmt.Sprintf("SELECT COUNT(*) FROM %s c WHERE c.%s IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %s p WHERE p.%s = c.%s)",
+			s.dst.Statement.Quote(ref.child), s.dst.Statement.Quote(ref.foreignKey),
+			s.dst.Statement.Quote(ref.parent), s.dst.Statement.Quote(ref.primaryKey), s.dst.Statement.Quote(ref.foreignKey))
+		if err := s.dst.Raw(sql).Scan(&orphans).Error; err != nil {
+			return err
+		}
+		if orphans > 0 {
+			result.Problems = append(result.Problems, fmt.Sprintf("%d rows in %s reference a missing %s (%s)", orphans, ref.child, ref.parent, ref.foreignKey))
+		}
+	}
+	return nil
+}
+
+// foreignKeyReference is a column in child that refers to primaryKey in parent
+type foreignKeyReference struct {
+	child      string
+	foreignKey string
+	parent     string
+	primaryKey string
+}
+
+// foreignKeyReferences lists every foreign key between the models, including the
+// columns of many2many join tables
+func foreignKeyReferences(db *gorm.DB) ([]foreignKeyReference, error) {
+	cache := &sync.Map{}
+	seen := make(map[foreignKeyReference]bool)
+	var refs []foreignKeyReference
+
+	for _, model := range models.All() {
+		sch, err := schema.Parse(model, cache, db.NamingStrategy)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range sch.Relationships.Relations {
+			for _, ref := range rel.References {
+				// Skip polymorphic references, which compare against a constant
+				if ref.PrimaryKey == nil || ref.ForeignKey == nil || ref.PrimaryValue != "" {
+					continue
+				}
+				fk := foreignKeyReference{
+					child:      ref.ForeignKey.Schema.Table,
+					foreignKey: ref.ForeignKey.DBName,
+					parent:     ref.PrimaryKey.Schema.Table,
+					primaryKey: ref.PrimaryKey.DBName,
+				}
+				if !seen[fk] {
+					seen[fk] = true
+					refs = append(refs, fk)
+				}
+			}
+		}
+	}
+	return refs, nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shakfu/buyer/internal/migrations"
+	"github.com/shakfu/buyer/internal/models"
+)
+
+func TestDatabaseCopyService_Copy(t *testing.T) {
+	source := setupTestDB(t)
+	defer func() { _ = source.Close() }()
+	target := setupTestDB(t)
+	defer func() { _ = target.Close() }()
+
+	svc := NewDatabaseCopyService(source.DB, target.DB)
+	if _, err := svc.Copy(CopyOptions{}); err == nil || !strings.Contains(err.Error(), "buyer db migrate") {
+		t.Fatalf("Expected unversioned source to be refused, got %v", err)
+	}
+	sourceMigrator, _ := migrations.NewMigrator(source.DB)
+	if _, err := sourceMigrator.Up(); err != nil {
+		t.Fatalf("Failed to migrate source: %v", err)
+	}
+
+	vendorSvc := NewVendorService(source.DB)
+	brand, _ := NewBrandService(source.DB).Create("Apple")
+	var vendorIDs []uint
+	for _, name := range []string{"Acme", "Globex", "Initech"} {
+		vendor, _ := vendorSvc.Create(name, "USD", "")
+		vendorIDs = append(vendorIDs, vendor.ID)
+	}
+	// Leave a gap in the vendor IDs (Delete would only move it to the trash)
+	if err := source.DB.Delete(&models.Vendor{}, vendorIDs[1]).Error; err != nil {
+		t.Fatalf("Failed to delete vendor: %v", err)
+	}
+	_ = vendorSvc.AddBrand(vendorIDs[0], brand.ID)
+	_ = vendorSvc.AddBrand(vendorIDs[2], brand.ID)
+	product, _ := NewProductService(source.DB).Create("iPhone 15", brand.ID, nil)
+	source.DB.Model(product).Update("is_active", false)
+	_, _ = NewForexService(source.DB).Create("EUR", "USD", 1.10, time.Now())
+	_, err := NewQuoteService(source.DB).Create(CreateQuoteInput{VendorID: vendorIDs[2], ProductID: product.ID, Price: 999, Currency: "USD"})
+	if err != nil {
+		t.Fatalf("Failed to create quote: %v", err)
+	}
+
+	var progressCalls int
+	result, err := svc.Copy(CopyOptions{
+		BatchSize: 1,
+		Progress:  func(table string, copied, total int64) { progressCalls++ },
+	})
+	if err != nil {
+		t.Fatalf("Copy failed: %v (problems: %v)", err, result)
+	}
+
+	counts := make(map[string]CopyTableResult)
+	for _, table := range result.Tables {
+		counts[table.Name] = table
+	}
+	if counts["vendors"].Copied != 2 || counts["vendors"].TargetRows != 2 || counts["vendor_brands"].TargetRows != 2 {
+		t.Errorf("Unexpected table results: %+v %+v", counts["vendors"], counts["vendor_brands"])
+	}
+	if progressCalls < 5 {
+		t.Errorf("Expected a progress call per batch, got %d", progressCalls)
+	}
+
+	var quote models.Quote
+	if err := target.DB.Preload("Vendor").First(&quote).Error; err != nil || quote.Vendor.Name != "Initech" {
+		t.Errorf("Expected quote to keep its vendor, got %+v (err: %v)", quote.Vendor, err)
+	}
+	var copiedProduct models.Product
+	target.DB.First(&copiedProduct, product.ID)
+	if copiedProduct.IsActive {
+		t.Error("Expected IsActive=false to be copied")
+	}
+
+	t.Run("non-empty target", func(t *testing.T) {
+		_, err := svc.Copy(CopyOptions{})
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected ValidationError, got %v", err)
+		}
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		// Simulate a copy that stopped before the last quote and vendor link
+		target.DB.Exec("DELETE FROM quotes")
+		target.DB.Exec("DELETE FROM vendor_brands WHERE vendor_id = ?", vendorIDs[2])
+
+		result, err := svc.Copy(CopyOptions{Resume: true})
+		if err != nil {
+			t.Fatalf("Resume failed: %v", err)
+		}
+		for _, table := range result.Tables {
+			switch table.Name {
+			case "quotes":
+				if table.Copied != 1 {
+					t.Errorf("Expected 1 quote to be copied on resume, got %d", table.Copied)
+				}
+			case "vendors":
+				if table.Copied != 0 {
+					t.Errorf("Expected no vendors to be copied again, got %d", table.Copied)
+				}
+			}
+		}
+	})
+
+	t.Run("broken foreign key", func(t *testing.T) {
+		target.DB.Exec("PRAGMA foreign_keys = OFF")
+		target.DB.Exec("UPDATE quotes SET vendor_id = 999")
+		target.DB.Exec("PRAGMA foreign_keys = ON")
+
+		_, err := svc.Copy(CopyOptions{Resume: true})
+		if err == nil || !strings.Contains(err.Error(), "quotes reference a missing vendors") {
+			t.Errorf("Expected foreign key problem, got %v", err)
+		}
+	})
+}
+
+func TestDatabaseCopyService_ProjectPurchaseOrder(t *testing.T) {
+	source := setupTestDB(t)
+	defer func() { _ = source.Close() }()
+	target := setupTestDB(t)
+	defer func() { _ = target.Close() }()
+	sourceMigrator, _ := migrations.NewMigrator(source.DB)
+	if _, err := sourceMigrator.Up(); err != nil {
+		t.Fatalf("Failed to migrate source: %v", err)
+	}
+	po := createProjectPurchaseOrder(t, source.DB)
+
+	if result, err := NewDatabaseCopyService(source.DB, target.DB).Copy(CopyOptions{}); err != nil {
+		t.Fatalf("Copy failed: %v (problems: %v)", err, result)
+	}
+	var copied models.PurchaseOrder
+	if err := target.DB.First(&copied, po.ID).Error; err != nil {
+		t.Fatalf("Expected purchase order %d to be copied: %v", po.ID, err)
+	}
+	if copied.ProjectID == nil || *copied.ProjectID != *po.ProjectID {
+		t.Errorf("Expected the order's project to be kept, got %v", copied.ProjectID)
+	}
+	if copied.ProjectRequisitionItemID == nil || *copied.ProjectRequisitionItemID != *po.ProjectRequisitionItemID {
+		t.Errorf("Expected the order's project requisition item to be kept, got %v", copied.ProjectRequisitionItemID)
+	}
+}
@@ -0,0 +1,6 @@
+// Package services holds buyer's business logic. Each service wraps a
+// *gorm.DB and is created with NewXService(db). A service's WithContext
+// returns a copy whose queries carry a context, such as the signed-in user
+// set by models.WithActor that the audit log records; the other With methods
+// return configured copies in the same way.
+package services
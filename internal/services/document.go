@@ -1,16 +1,28 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/storage"
 	"gorm.io/gorm"
 )
 
 // DocumentService handles business logic for documents
 type DocumentService struct {
 	db *gorm.DB
+	// storage keeps the files of uploaded documents; without it documents
+	// are records only
+	storage storage.StorageBackend
 }
 
 // NewDocumentService creates a new document service
@@ -18,6 +30,17 @@ func NewDocumentService(db *gorm.DB) *DocumentService {
 	return &DocumentService{db: db}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *DocumentService) WithContext(ctx context.Context) *DocumentService {
+	return &DocumentService{db: s.db.WithContext(ctx), storage: s.storage}
+}
+
+// WithStorage returns a copy of the service that keeps document files in
+// backend, in the bucket of each document's entity type
+func (s *DocumentService) WithStorage(backend storage.StorageBackend) *DocumentService {
+	return &DocumentService{db: s.db, storage: backend}
+}
+
 // CreateDocumentInput represents input for creating a document
 type CreateDocumentInput struct {
 	EntityType  string
@@ -30,8 +53,26 @@ type CreateDocumentInput struct {
 	UploadedBy  string
 }
 
-// Create creates a new document
+// Create records a document whose file is kept elsewhere, at input.FilePath
 func (s *DocumentService) Create(input CreateDocumentInput) (*models.Document, error) {
+	doc, err := newDocument(input)
+	if err != nil {
+		return nil, err
+	}
+	if doc.FilePath == "" {
+		return nil, &ValidationError{Field: "file_path", Message: "file path cannot be empty"}
+	}
+
+	if err := s.db.Create(doc).Error; err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// newDocument validates a document's input, leaving the file path to the
+// caller
+func newDocument(input CreateDocumentInput) (*models.Document, error) {
 	// Validate required fields
 	entityType := strings.TrimSpace(input.EntityType)
 	if entityType == "" {
@@ -43,11 +84,6 @@ func (s *DocumentService) Create(input CreateDocumentInput) (*models.Document, e
 		return nil, &ValidationError{Field: "file_name", Message: "file name cannot be empty"}
 	}
 
-	filePath := strings.TrimSpace(input.FilePath)
-	if filePath == "" {
-		return nil, &ValidationError{Field: "file_path", Message: "file path cannot be empty"}
-	}
-
 	// Validate entity type
 	validEntityTypes := map[string]bool{
 		"vendor":         true,
@@ -71,24 +107,111 @@ func (s *DocumentService) Create(input CreateDocumentInput) (*models.Document, e
 		return nil, &ValidationError{Field: "entity_id", Message: "entity ID must be greater than 0"}
 	}
 
-	doc := &models.Document{
+	return &models.Document{
 		EntityType:  entityType,
 		EntityID:    input.EntityID,
 		FileName:    fileName,
 		FileType:    strings.TrimSpace(input.FileType),
 		FileSize:    input.FileSize,
-		FilePath:    filePath,
+		FilePath:    strings.TrimSpace(input.FilePath),
 		Description: strings.TrimSpace(input.Description),
 		UploadedBy:  strings.TrimSpace(input.UploadedBy),
+	}, nil
+}
+
+// errNoStorage is returned for file operations of a service without storage
+var errNoStorage = errors.New("no document storage configured")
+
+// documentKey returns a new storage key for a file of an entity:
+// <entity type>/<entity id>/<year>/<month>/<random>-<file name>
+func documentKey(entityType string, entityID uint, fileName string, now time.Time) (string, error) {
+	random := make([]byte, 6)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
 	}
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r < ' ' {
+			return '-'
+		}
+		return r
+	}, fileName)
+	return fmt.Sprintf("%s/%d/%s/%s-%s", entityType, entityID, now.Format("2006/01"), hex.EncodeToString(random), name), nil
+}
 
-	if err := s.db.Create(doc).Error; err != nil {
+// Upload stores a document's file from content and records the document.
+// The file type defaults to the file name's extension; input.FilePath is
+// replaced by the storage key and input.FileSize by the size stored.
+func (s *DocumentService) Upload(input CreateDocumentInput, content io.Reader) (*models.Document, error) {
+	if s.storage == nil {
+		return nil, errNoStorage
+	}
+	doc, err := newDocument(input)
+	if err != nil {
+		return nil, err
+	}
+	ext := strings.ToLower(filepath.Ext(doc.FileName))
+	if doc.FileType == "" {
+		doc.FileType = strings.TrimPrefix(ext, ".")
+	}
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if doc.FilePath, err = documentKey(doc.EntityType, doc.EntityID, doc.FileName, time.Now()); err != nil {
 		return nil, err
 	}
 
+	size := input.FileSize
+	if size <= 0 {
+		size = -1
+	}
+	ctx := s.db.Statement.Context
+	counter := &countingReader{r: content}
+	bucket := storage.BucketForEntityType(doc.EntityType)
+	if err := s.storage.Upload(ctx, bucket, doc.FilePath, counter, size, contentType); err != nil {
+		return nil, fmt.Errorf("failed to store %s: %w", doc.FileName, err)
+	}
+	doc.FileSize = counter.n
+
+	if err := s.db.Create(doc).Error; err != nil {
+		_ = s.storage.Delete(ctx, bucket, doc.FilePath)
+		return nil, err
+	}
 	return doc, nil
 }
 
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Download opens the file of a document; the caller closes it
+func (s *DocumentService) Download(id uint) (io.ReadCloser, *models.Document, error) {
+	if s.storage == nil {
+		return nil, nil, errNoStorage
+	}
+	doc, err := s.GetByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx := s.db.Statement.Context
+	reader, err := s.storage.Download(ctx, storage.BucketForEntityType(doc.EntityType), doc.FilePath)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		return nil, nil, &NotFoundError{Entity: "Document file", ID: id}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return reader, doc, nil
+}
+
 // GetByID retrieves a document by ID
 func (s *DocumentService) GetByID(id uint) (*models.Document, error) {
 	var doc models.Document
@@ -183,22 +306,56 @@ func (s *DocumentService) Update(id uint, input CreateDocumentInput) (*models.Do
 	return &doc, nil
 }
 
-// Delete deletes a document
+// Delete deletes a document, and its file when the service has storage
 func (s *DocumentService) Delete(id uint) error {
-	result := s.db.Delete(&models.Document{}, id)
-	if result.Error != nil {
-		return result.Error
+	doc, err := s.GetByID(id)
+	if err != nil {
+		return err
 	}
-	if result.RowsAffected == 0 {
-		return &NotFoundError{Entity: "Document", ID: id}
+	if err := s.db.Delete(doc).Error; err != nil {
+		return err
+	}
+	if err := s.deleteFile(doc); err != nil {
+		return fmt.Errorf("document deleted, but not its file: %w", err)
 	}
 	return nil
 }
 
-// DeleteByEntity deletes all documents for a specific entity
+// DeleteByEntity deletes all documents for a specific entity, and their files
+// when the service has storage
 func (s *DocumentService) DeleteByEntity(entityType string, entityID uint) error {
-	return s.db.Where("entity_type = ? AND entity_id = ?", entityType, entityID).
-		Delete(&models.Document{}).Error
+	docs, err := s.ListByEntity(entityType, entityID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Delete(&models.Document{}).Error; err != nil {
+		return err
+	}
+	var errs []error
+	for _, doc := range docs {
+		if err := s.deleteFile(doc); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", doc.FileName, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("documents deleted, but not all their files: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// deleteFile removes a document's file from storage, if the service has
+// storage. Files outside the storage, recorded by Create, are left alone.
+func (s *DocumentService) deleteFile(doc *models.Document) error {
+	if s.storage == nil {
+		return nil
+	}
+	ctx := s.db.Statement.Context
+	err := s.storage.Delete(ctx, storage.BucketForEntityType(doc.EntityType), doc.FilePath)
+	if errors.Is(err, storage.ErrInvalidKey) {
+		return nil
+	}
+	return err
 }
 
 // Count returns the total number of documents
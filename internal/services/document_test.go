@@ -1,7 +1,14 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
+
+	"github.com/shakfu/buyer/internal/storage"
+	"github.com/shakfu/buyer/internal/storage/s3test"
 )
 
 func TestDocumentService_Create(t *testing.T) {
@@ -563,3 +570,84 @@ func TestDocumentService_CountByEntity(t *testing.T) {
 		t.Errorf("CountByEntity(vendor2) = %v, want 1", count2)
 	}
 }
+
+func TestDocumentService_Upload(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+	vendor, _ := NewVendorService(cfg.DB).Create("Vendor 1", "USD", "")
+
+	server := s3test.New("buyer", "secret")
+	defer server.Close()
+	backend, err := storage.NewMinioBackend(server.Endpoint, "buyer", "secret", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewDocumentService(cfg.DB).WithStorage(backend)
+
+	if _, err := NewDocumentService(cfg.DB).Upload(CreateDocumentInput{EntityType: "vendor", EntityID: vendor.ID, FileName: "w9.pdf"}, strings.NewReader("x")); err == nil {
+		t.Errorf("Expected an error without storage")
+	}
+	var validationErr *ValidationError
+	if _, err := service.Upload(CreateDocumentInput{EntityType: "planet", EntityID: 1, FileName: "w9.pdf"}, strings.NewReader("x")); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError, got %v", err)
+	}
+
+	doc, err := service.Upload(CreateDocumentInput{EntityType: "vendor", EntityID: vendor.ID, FileName: "W9 Form.PDF"}, strings.NewReader("signed form"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.FileType != "pdf" || doc.FileSize != 11 || !strings.HasPrefix(doc.FilePath, fmt.Sprintf("vendor/%d/", vendor.ID)) || !strings.HasSuffix(doc.FilePath, "-W9 Form.PDF") {
+		t.Errorf("Unexpected document %+v", doc)
+	}
+	if data, ok := server.Object("vendor-docs", doc.FilePath); !ok || string(data) != "signed form" {
+		t.Errorf("Expected the file in the vendor-docs bucket, got %q", data)
+	}
+
+	reader, got, err := service.Download(doc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "signed form" || got.ID != doc.ID {
+		t.Errorf("Expected the uploaded file, got %q", data)
+	}
+
+	// A recorded document without a stored file
+	recorded, _ := service.Create(CreateDocumentInput{EntityType: "vendor", EntityID: vendor.ID, FileName: "old.pdf", FilePath: "/shared/old.pdf"})
+	var notFoundErr *NotFoundError
+	if _, _, err := service.Download(recorded.ID); !errors.As(err, &notFoundErr) {
+		t.Errorf("Expected a NotFoundError for a missing file, got %v", err)
+	}
+
+	if err := service.Delete(doc.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := server.Object("vendor-docs", doc.FilePath); ok {
+		t.Errorf("Expected the file deleted with the document")
+	}
+	if err := service.Delete(doc.ID); !errors.As(err, &notFoundErr) {
+		t.Errorf("Expected a NotFoundError deleting again, got %v", err)
+	}
+
+	// Deleting an entity's documents deletes their files too
+	var keys []string
+	for _, name := range []string{"a.pdf", "b.pdf"} {
+		doc, err := service.Upload(CreateDocumentInput{EntityType: "vendor", EntityID: vendor.ID, FileName: name}, strings.NewReader(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, doc.FilePath)
+	}
+	if err := service.DeleteByEntity("vendor", vendor.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if _, ok := server.Object("vendor-docs", key); ok {
+			t.Errorf("Expected %s deleted with the vendor's documents", key)
+		}
+	}
+	if count, _ := service.CountByEntity("vendor", vendor.ID); count != 0 {
+		t.Errorf("Expected no documents left, got %d", count)
+	}
+}
@@ -14,11 +14,15 @@ func (e *ValidationError) Error() string {
 
 // DuplicateError represents a duplicate entry error
 type DuplicateError struct {
-	Entity string
-	Name   string
+	Entity  string
+	Name    string
+	InTrash bool // The existing entry is in the trash
 }
 
 func (e *DuplicateError) Error() string {
+	if e.InTrash {
+		return fmt.Sprintf("%s with name '%s' already exists in the trash; restore or purge it first", e.Entity, e.Name)
+	}
 	return fmt.Sprintf("%s with name '%s' already exists", e.Entity, e.Name)
 }
 
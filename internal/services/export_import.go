@@ -1,7 +1,9 @@
 package services
 
 import (
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
@@ -11,6 +13,7 @@ import (
 	"github.com/shakfu/buyer/internal/models"
 	"github.com/xuri/excelize/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ExportImportService handles CSV and Excel export/import operations
@@ -23,6 +26,11 @@ func NewExportImportService(db *gorm.DB) *ExportImportService {
 	return &ExportImportService{db: db}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *ExportImportService) WithContext(ctx context.Context) *ExportImportService {
+	return NewExportImportService(s.db.WithContext(ctx))
+}
+
 // ExportFormat represents the export format
 type ExportFormat string
 
@@ -33,9 +41,15 @@ const (
 
 // ImportResult represents the result of an import operation
 type ImportResult struct {
-	SuccessCount int
+	SuccessCount int // Rows created or updated (or that would be, in a dry run)
 	ErrorCount   int
 	Errors       []string
+	CreatedCount int
+	UpdatedCount int
+	SkippedCount int
+	Rows         []ImportRowResult
+	DryRun       bool
+	RolledBack   bool // True when nothing was committed because of a dry run or a failed row
 }
 
 // ==================== Brand Export/Import ====================
@@ -140,51 +154,80 @@ func (s *ExportImportService) ExportBrandsExcel() (*excelize.File, error) {
 	return f, nil
 }
 
-// ImportBrandsCSV imports brands from CSV format
+// brandImportLayout is the column layout produced by ExportBrandsCSV
+var brandImportLayout = []string{"ID", "Name", "CreatedAt", "UpdatedAt"}
+
+// ImportBrandsCSV imports brands from CSV format, creating new brands only
 func (s *ExportImportService) ImportBrandsCSV(r io.Reader) (*ImportResult, error) {
-	reader := csv.NewReader(r)
-	records, err := reader.ReadAll()
+	return s.ImportBrandsCSVWithOptions(r, ImportOptions{Mode: ImportModeCreate, ContinueOnError: true})
+}
+
+// ImportBrandsCSVWithOptions imports brands from CSV format using the given import mode.
+// Brands can be matched by ID or name.
+func (s *ExportImportService) ImportBrandsCSVWithOptions(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	records, err := csv.NewReader(r).ReadAll()
 	if err != nil {
 		return nil, err
 	}
+	return s.importBrands(records, opts)
+}
 
-	if len(records) < 2 {
-		return nil, fmt.Errorf("CSV file must contain at least a header and one data row")
+// ImportBrandsExcel imports brands from an Excel workbook laid out like ExportBrandsExcel,
+// using the given import mode. The "Brands" sheet is read unless opts.Sheet is set.
+func (s *ExportImportService) ImportBrandsExcel(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	records, err := readExcelRecords(r, opts.Sheet, "Brands")
+	if err != nil {
+		return nil, err
 	}
+	return s.importBrands(records, opts)
+}
 
-	result := &ImportResult{
-		Errors: make([]string, 0),
+func (s *ExportImportService) importBrands(records [][]string, opts ImportOptions) (*ImportResult, error) {
+	opts, err := opts.resolve(ImportKeyName, ImportKeyID, ImportKeyName)
+	if err != nil {
+		return nil, err
 	}
 
-	brandSvc := NewBrandService(s.db)
-
-	// Skip header (first row)
-	for i, record := range records[1:] {
-		if len(record) < 2 {
-			result.ErrorCount++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: insufficient columns", i+2))
-			continue
-		}
-
-		name := strings.TrimSpace(record[1])
+	return s.runImport(records, brandImportLayout, opts, func(tx *gorm.DB, row importRow) (ImportRowResult, error) {
+		name := row.Get("Name")
 		if name == "" {
-			result.ErrorCount++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: name is empty", i+2))
-			continue
+			return ImportRowResult{}, &ValidationError{Field: "name", Message: "brand name cannot be empty"}
 		}
 
-		// Try to create the brand
-		_, err := brandSvc.Create(name)
+		var existing models.Brand
+		found, key, err := findImportMatch(tx, &existing, opts, row)
 		if err != nil {
-			result.ErrorCount++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: %v", i+2, err))
-			continue
+			return ImportRowResult{}, err
+		}
+		if key == "" {
+			key = name
 		}
 
-		result.SuccessCount++
-	}
+		result := ImportRowResult{Key: key, Action: opts.reconcile(found)}
+		brandSvc := NewBrandService(tx)
 
-	return result, nil
+		switch result.Action {
+		case ImportActionCreate:
+			if _, err := brandSvc.Create(name); err != nil {
+				return result, err
+			}
+		case ImportActionUpdate:
+			var diff fieldDiff
+			diff.setString("Name", &existing.Name, name)
+			if len(diff) == 0 {
+				result.Action = ImportActionSkip
+				result.Message = "unchanged"
+				return result, nil
+			}
+			result.Changes = diff
+			if _, err := brandSvc.Update(existing.ID, existing.Name); err != nil {
+				return result, err
+			}
+		case ImportActionSkip:
+			result.Message = "no matching brand"
+		}
+		return result, nil
+	})
 }
 
 // ==================== Vendor Export/Import ====================
@@ -371,57 +414,144 @@ func (s *ExportImportService) ExportVendorsExcel() (*excelize.File, error) {
 	return f, nil
 }
 
-// ImportVendorsCSV imports vendors from CSV format
+// vendorImportLayout is the column layout produced by ExportVendorsCSV
+var vendorImportLayout = []string{
+	"ID", "Name", "Currency", "DiscountCode", "ContactPerson", "Email", "Phone",
+	"Website", "AddressLine1", "AddressLine2", "City", "State", "PostalCode",
+	"Country", "TaxID", "PaymentTerms", "CreatedAt", "UpdatedAt",
+}
+
+// ImportVendorsCSV imports vendors from CSV format, creating new vendors only
 func (s *ExportImportService) ImportVendorsCSV(r io.Reader) (*ImportResult, error) {
-	reader := csv.NewReader(r)
-	records, err := reader.ReadAll()
+	return s.ImportVendorsCSVWithOptions(r, ImportOptions{Mode: ImportModeCreate, ContinueOnError: true})
+}
+
+// ImportVendorsCSVWithOptions imports vendors from CSV format using the given import mode.
+// Vendors can be matched by ID or name. Only columns present in the file are updated.
+func (s *ExportImportService) ImportVendorsCSVWithOptions(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	records, err := csv.NewReader(r).ReadAll()
 	if err != nil {
 		return nil, err
 	}
+	return s.importVendors(records, opts)
+}
 
-	if len(records) < 2 {
-		return nil, fmt.Errorf("CSV file must contain at least a header and one data row")
+// ImportVendorsExcel imports vendors from an Excel workbook laid out like ExportVendorsExcel,
+// using the given import mode. The "Vendors" sheet is read unless opts.Sheet is set.
+func (s *ExportImportService) ImportVendorsExcel(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	records, err := readExcelRecords(r, opts.Sheet, "Vendors")
+	if err != nil {
+		return nil, err
 	}
+	return s.importVendors(records, opts)
+}
 
-	result := &ImportResult{
-		Errors: make([]string, 0),
+func (s *ExportImportService) importVendors(records [][]string, opts ImportOptions) (*ImportResult, error) {
+	opts, err := opts.resolve(ImportKeyName, ImportKeyID, ImportKeyName)
+	if err != nil {
+		return nil, err
 	}
 
-	vendorSvc := NewVendorService(s.db)
-
-	// Skip header (first row)
-	for i, record := range records[1:] {
-		if len(record) < 3 {
-			result.ErrorCount++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: insufficient columns", i+2))
-			continue
+	return s.runImport(records, vendorImportLayout, opts, func(tx *gorm.DB, row importRow) (ImportRowResult, error) {
+		name := row.Get("Name")
+		if name == "" {
+			return ImportRowResult{}, &ValidationError{Field: "name", Message: "vendor name cannot be empty"}
 		}
 
-		name := strings.TrimSpace(record[1])
-		currency := strings.TrimSpace(record[2])
-		discountCode := ""
-		if len(record) > 3 {
-			discountCode = strings.TrimSpace(record[3])
+		var vendor models.Vendor
+		found, key, err := findImportMatch(tx, &vendor, opts, row)
+		if err != nil {
+			return ImportRowResult{}, err
 		}
-
-		if name == "" {
-			result.ErrorCount++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: name is empty", i+2))
-			continue
+		if key == "" {
+			key = name
 		}
 
-		// Try to create the vendor
-		_, err := vendorSvc.Create(name, currency, discountCode)
-		if err != nil {
-			result.ErrorCount++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: %v", i+2, err))
-			continue
+		result := ImportRowResult{Key: key, Action: opts.reconcile(found)}
+
+		switch result.Action {
+		case ImportActionCreate:
+			created, err := NewVendorService(tx).Create(name, row.Get("Currency"), row.Get("DiscountCode"))
+			if err != nil {
+				return result, err
+			}
+			vendor = *created
+			var diff fieldDiff
+			applyVendorDetails(&diff, &vendor, row)
+			if len(diff) > 0 {
+				if err := tx.Omit(clause.Associations).Save(&vendor).Error; err != nil {
+					return result, err
+				}
+			}
+		case ImportActionUpdate:
+			var diff fieldDiff
+			if name != vendor.Name {
+				var duplicate models.Vendor
+				err := tx.Unscoped().Where("name = ? AND id != ?", name, vendor.ID).First(&duplicate).Error
+				if err == nil {
+					return result, &DuplicateError{Entity: "Vendor", Name: name, InTrash: duplicate.DeletedAt.Valid}
+				}
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return result, err
+				}
+				diff.setString("Name", &vendor.Name, name)
+			}
+			if row.Has("Currency") {
+				currency := strings.ToUpper(row.Get("Currency"))
+				if currency == "" {
+					currency = "USD"
+				}
+				if len(currency) != 3 {
+					return result, &ValidationError{Field: "currency", Message: "currency must be a 3-letter ISO 4217 code"}
+				}
+				diff.setString("Currency", &vendor.Currency, currency)
+			}
+			if row.Has("DiscountCode") {
+				diff.setString("DiscountCode", &vendor.DiscountCode, row.Get("DiscountCode"))
+			}
+			applyVendorDetails(&diff, &vendor, row)
+
+			if len(diff) == 0 {
+				result.Action = ImportActionSkip
+				result.Message = "unchanged"
+				return result, nil
+			}
+			result.Changes = diff
+			if err := tx.Omit(clause.Associations).Save(&vendor).Error; err != nil {
+				return result, err
+			}
+		case ImportActionSkip:
+			result.Message = "no matching vendor"
 		}
+		return result, nil
+	})
+}
 
-		result.SuccessCount++
+// applyVendorDetails copies the optional contact, address and business columns
+// present in the row onto the vendor
+func applyVendorDetails(diff *fieldDiff, vendor *models.Vendor, row importRow) {
+	fields := []struct {
+		column string
+		dst    *string
+	}{
+		{"ContactPerson", &vendor.ContactPerson},
+		{"Email", &vendor.Email},
+		{"Phone", &vendor.Phone},
+		{"Website", &vendor.Website},
+		{"AddressLine1", &vendor.AddressLine1},
+		{"AddressLine2", &vendor.AddressLine2},
+		{"City", &vendor.City},
+		{"State", &vendor.State},
+		{"PostalCode", &vendor.PostalCode},
+		{"Country", &vendor.Country},
+		{"TaxID", &vendor.TaxID},
+		{"PaymentTerms", &vendor.PaymentTerms},
+	}
+	for _, f := range fields {
+		if row.Has(f.column) {
+			diff.setString(f.column, f.dst, row.Get(f.column))
+		}
 	}
-
-	return result, nil
 }
 
 // ==================== Product Export/Import ====================
@@ -653,6 +783,181 @@ func (s *ExportImportService) ExportProductsExcel() (*excelize.File, error) {
 	return f, nil
 }
 
+// productImportLayout is the column layout produced by ExportProductsCSV
+var productImportLayout = []string{
+	"ID", "Name", "BrandID", "BrandName", "SpecificationID", "SpecificationName",
+	"SKU", "Description", "UnitOfMeasure", "MinOrderQty", "LeadTimeDays",
+	"IsActive", "DiscontinuedAt", "CreatedBy", "UpdatedBy", "CreatedAt", "UpdatedAt",
+}
+
+// ImportProductsCSV imports products from CSV format, creating new products only
+func (s *ExportImportService) ImportProductsCSV(r io.Reader) (*ImportResult, error) {
+	return s.ImportProductsCSVWithOptions(r, ImportOptions{Mode: ImportModeCreate, ContinueOnError: true})
+}
+
+// ImportProductsCSVWithOptions imports products from CSV format using the given import mode.
+// Products can be matched by ID, name or SKU. Brands and specifications are resolved
+// by ID, or by name when the ID column is empty.
+func (s *ExportImportService) ImportProductsCSVWithOptions(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return s.importProducts(records, opts)
+}
+
+// ImportProductsExcel imports products from an Excel workbook laid out like ExportProductsExcel,
+// using the given import mode. The "Products" sheet is read unless opts.Sheet is set.
+func (s *ExportImportService) ImportProductsExcel(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	records, err := readExcelRecords(r, opts.Sheet, "Products")
+	if err != nil {
+		return nil, err
+	}
+	return s.importProducts(records, opts)
+}
+
+func (s *ExportImportService) importProducts(records [][]string, opts ImportOptions) (*ImportResult, error) {
+	opts, err := opts.resolve(ImportKeyName, ImportKeyID, ImportKeyName, ImportKeySKU)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.runImport(records, productImportLayout, opts, func(tx *gorm.DB, row importRow) (ImportRowResult, error) {
+		name := row.Get("Name")
+		if name == "" {
+			return ImportRowResult{}, &ValidationError{Field: "name", Message: "product name cannot be empty"}
+		}
+
+		var product models.Product
+		found, key, err := findImportMatch(tx, &product, opts, row)
+		if err != nil {
+			return ImportRowResult{}, err
+		}
+		if key == "" {
+			key = name
+		}
+
+		result := ImportRowResult{Key: key, Action: opts.reconcile(found)}
+		if result.Action == ImportActionSkip {
+			result.Message = "no matching product"
+			return result, nil
+		}
+
+		brandID, err := resolveImportReference(tx, &models.Brand{}, "Brand", row.Get("BrandID"), row.Get("BrandName"))
+		if err != nil {
+			return result, err
+		}
+		specID, err := resolveImportReference(tx, &models.Specification{}, "Specification", row.Get("SpecificationID"), row.Get("SpecificationName"))
+		if err != nil {
+			return result, err
+		}
+
+		var diff fieldDiff
+		if result.Action == ImportActionCreate {
+			if brandID == 0 {
+				return result, &ValidationError{Field: "brand", Message: "brand ID or brand name is required"}
+			}
+			var specPtr *uint
+			if specID > 0 {
+				specPtr = &specID
+			}
+			created, err := NewProductService(tx).Create(name, brandID, specPtr)
+			if err != nil {
+				return result, err
+			}
+			if err := tx.First(&product, created.ID).Error; err != nil {
+				return result, err
+			}
+		} else {
+			if name != product.Name {
+				var duplicate models.Product
+				err := tx.Unscoped().Where("name = ? AND id != ?", name, product.ID).First(&duplicate).Error
+				if err == nil {
+					return result, &DuplicateError{Entity: "Product", Name: name, InTrash: duplicate.DeletedAt.Valid}
+				}
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return result, err
+				}
+				diff.setString("Name", &product.Name, name)
+			}
+			if brandID > 0 {
+				if product.BrandID != brandID {
+					diff = append(diff, FieldChange{Field: "BrandID", Old: fmt.Sprintf("%d", product.BrandID), New: fmt.Sprintf("%d", brandID)})
+					product.BrandID = brandID
+				}
+			}
+			if row.Has("SpecificationID") || row.Has("SpecificationName") {
+				diff.setOptionalUint("SpecificationID", &product.SpecificationID, specID)
+			}
+		}
+
+		if err := applyProductDetails(tx, &diff, &product, row); err != nil {
+			return result, err
+		}
+
+		if result.Action == ImportActionUpdate {
+			if len(diff) == 0 {
+				result.Action = ImportActionSkip
+				result.Message = "unchanged"
+				return result, nil
+			}
+			result.Changes = diff
+		}
+		if len(diff) > 0 {
+			if err := tx.Omit(clause.Associations).Save(&product).Error; err != nil {
+				return result, err
+			}
+		}
+		return result, nil
+	})
+}
+
+// applyProductDetails copies the optional product columns present in the row onto the product
+func applyProductDetails(tx *gorm.DB, diff *fieldDiff, product *models.Product, row importRow) error {
+	if row.Has("SKU") {
+		sku := row.Get("SKU")
+		if sku != "" {
+			var duplicate models.Product
+			err := tx.Unscoped().Where("sku = ? AND id != ?", sku, product.ID).First(&duplicate).Error
+			if err == nil {
+				return &DuplicateError{Entity: "Product SKU", Name: sku, InTrash: duplicate.DeletedAt.Valid}
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		diff.setOptionalString("SKU", &product.SKU, sku)
+	}
+	if row.Has("Description") {
+		diff.setString("Description", &product.Description, row.Get("Description"))
+	}
+	if row.Has("UnitOfMeasure") && row.Get("UnitOfMeasure") != "" {
+		diff.setString("UnitOfMeasure", &product.UnitOfMeasure, row.Get("UnitOfMeasure"))
+	}
+	if v := row.Get("MinOrderQty"); v != "" {
+		qty, err := strconv.Atoi(v)
+		if err != nil {
+			return &ValidationError{Field: "min_order_qty", Message: fmt.Sprintf("invalid minimum order quantity: %s", v)}
+		}
+		diff.setInt("MinOrderQty", &product.MinOrderQty, qty)
+	}
+	if v := row.Get("LeadTimeDays"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil {
+			return &ValidationError{Field: "lead_time_days", Message: fmt.Sprintf("invalid lead time days: %s", v)}
+		}
+		diff.setInt("LeadTimeDays", &product.LeadTimeDays, days)
+	}
+	if v := row.Get("IsActive"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			return &ValidationError{Field: "is_active", Message: fmt.Sprintf("invalid boolean: %s", v)}
+		}
+		diff.setBool("IsActive", &product.IsActive, active)
+	}
+	return nil
+}
+
 // ==================== Quote Export/Import ====================
 
 // ExportQuotesCSV exports quotes to CSV format
@@ -871,6 +1176,121 @@ func (s *ExportImportService) ExportQuotesExcel() (*excelize.File, error) {
 	return f, nil
 }
 
+// quoteImportLayout is the column order expected when a quote file has no recognizable header.
+// Vendors and products are resolved by ID, or by name (or SKU for products) when the ID is empty.
+var quoteImportLayout = []string{
+	"ID", "VendorID", "VendorName", "ProductID", "ProductName", "ProductSKU",
+	"Price", "Currency", "MinQuantity", "QuoteDate", "ValidUntil", "Notes",
+}
+
+// ImportQuotesCSVWithOptions imports quotes from CSV format. Quotes are always
+// created as new records, so only the create mode is supported.
+func (s *ExportImportService) ImportQuotesCSVWithOptions(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return s.importQuotes(records, opts)
+}
+
+// ImportQuotesExcel imports quotes from an Excel workbook laid out like ExportQuotesExcel.
+// The "Quotes" sheet is read unless opts.Sheet is set.
+func (s *ExportImportService) ImportQuotesExcel(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	records, err := readExcelRecords(r, opts.Sheet, "Quotes")
+	if err != nil {
+		return nil, err
+	}
+	return s.importQuotes(records, opts)
+}
+
+func (s *ExportImportService) importQuotes(records [][]string, opts ImportOptions) (*ImportResult, error) {
+	opts, err := opts.resolve(ImportKeyID, ImportKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Mode != ImportModeCreate {
+		return nil, &ValidationError{Field: "mode", Message: "quotes can only be imported in create mode"}
+	}
+
+	return s.runImport(records, quoteImportLayout, opts, func(tx *gorm.DB, row importRow) (ImportRowResult, error) {
+		vendorID, err := resolveImportReference(tx, &models.Vendor{}, "Vendor", row.Get("VendorID"), row.Get("VendorName"))
+		if err != nil {
+			return ImportRowResult{}, err
+		}
+		if vendorID == 0 {
+			return ImportRowResult{}, &ValidationError{Field: "vendor", Message: "vendor is required"}
+		}
+
+		productID, err := resolveImportProduct(tx, row)
+		if err != nil {
+			return ImportRowResult{}, err
+		}
+		if productID == 0 {
+			return ImportRowResult{}, &ValidationError{Field: "product", Message: "product is required"}
+		}
+
+		price, err := strconv.ParseFloat(row.Get("Price"), 64)
+		if err != nil {
+			return ImportRowResult{}, fmt.Errorf("invalid price: %v", err)
+		}
+
+		input := CreateQuoteInput{
+			VendorID:  vendorID,
+			ProductID: productID,
+			Price:     price,
+			Currency:  strings.ToUpper(row.Get("Currency")),
+			Notes:     row.Get("Notes"),
+		}
+		if v := row.Get("QuoteDate"); v != "" {
+			if input.QuoteDate, err = parseImportDate(v); err != nil {
+				return ImportRowResult{}, err
+			}
+		}
+		if v := row.Get("ValidUntil"); v != "" {
+			validUntil, err := parseImportDate(v)
+			if err != nil {
+				return ImportRowResult{}, err
+			}
+			input.ValidUntil = &validUntil
+		}
+		minQuantity := 0
+		if v := row.Get("MinQuantity"); v != "" {
+			if minQuantity, err = strconv.Atoi(v); err != nil {
+				return ImportRowResult{}, fmt.Errorf("invalid min quantity: %s", v)
+			}
+		}
+
+		quote, err := NewQuoteService(tx).Create(input)
+		if err != nil {
+			return ImportRowResult{}, err
+		}
+		if minQuantity > 0 {
+			if err := tx.Model(quote).Update("min_quantity", minQuantity).Error; err != nil {
+				return ImportRowResult{}, err
+			}
+		}
+
+		key := quote.Product.Name
+		if quote.Vendor != nil {
+			key = quote.Vendor.Name + " / " + key
+		}
+		return ImportRowResult{Key: key, Action: ImportActionCreate}, nil
+	})
+}
+
+// resolveImportProduct resolves the product of a quote row by ID, SKU or name, in that order
+func resolveImportProduct(tx *gorm.DB, row importRow) (uint, error) {
+	if sku := row.Get("ProductSKU"); row.Get("ProductID") == "" && sku != "" {
+		var ref struct{ ID uint }
+		err := tx.Model(&models.Product{}).Where("sku = ?", sku).Take(&ref).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, &NotFoundError{Entity: "Product", ID: sku}
+		}
+		return ref.ID, err
+	}
+	return resolveImportReference(tx, &models.Product{}, "Product", row.Get("ProductID"), row.Get("ProductName"))
+}
+
 // ==================== Forex Export/Import ====================
 
 // ExportForexCSV exports forex rates to CSV format
@@ -988,67 +1408,101 @@ func (s *ExportImportService) ExportForexExcel() (*excelize.File, error) {
 	return f, nil
 }
 
-// ImportForexCSV imports forex rates from CSV format
+// forexImportLayout is the column layout produced by ExportForexCSV
+var forexImportLayout = []string{"ID", "FromCurrency", "ToCurrency", "Rate", "EffectiveDate", "CreatedAt", "UpdatedAt"}
+
+// ImportForexCSV imports forex rates from CSV format, creating new rates only
 func (s *ExportImportService) ImportForexCSV(r io.Reader) (*ImportResult, error) {
-	reader := csv.NewReader(r)
-	records, err := reader.ReadAll()
+	return s.ImportForexCSVWithOptions(r, ImportOptions{Mode: ImportModeCreate, ContinueOnError: true})
+}
+
+// ImportForexCSVWithOptions imports forex rates from CSV format using the given import mode.
+// Forex rates have no natural name, so existing rates can only be matched by ID.
+func (s *ExportImportService) ImportForexCSVWithOptions(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	records, err := csv.NewReader(r).ReadAll()
 	if err != nil {
 		return nil, err
 	}
+	return s.importForex(records, opts)
+}
 
-	if len(records) < 2 {
-		return nil, fmt.Errorf("CSV file must contain at least a header and one data row")
+// ImportForexExcel imports forex rates from an Excel workbook laid out like ExportForexExcel,
+// using the given import mode. The "Forex Rates" sheet is read unless opts.Sheet is set.
+func (s *ExportImportService) ImportForexExcel(r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	records, err := readExcelRecords(r, opts.Sheet, "Forex Rates")
+	if err != nil {
+		return nil, err
 	}
+	return s.importForex(records, opts)
+}
 
-	result := &ImportResult{
-		Errors: make([]string, 0),
+func (s *ExportImportService) importForex(records [][]string, opts ImportOptions) (*ImportResult, error) {
+	opts, err := opts.resolve(ImportKeyID, ImportKeyID)
+	if err != nil {
+		return nil, err
 	}
 
-	forexSvc := NewForexService(s.db)
-
-	// Skip header (first row)
-	for i, record := range records[1:] {
-		if len(record) < 5 {
-			result.ErrorCount++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: insufficient columns", i+2))
-			continue
-		}
-
-		fromCurrency := strings.ToUpper(strings.TrimSpace(record[1]))
-		toCurrency := strings.ToUpper(strings.TrimSpace(record[2]))
-		rateStr := strings.TrimSpace(record[3])
-		effectiveDateStr := strings.TrimSpace(record[4])
-
+	return s.runImport(records, forexImportLayout, opts, func(tx *gorm.DB, row importRow) (ImportRowResult, error) {
+		fromCurrency := strings.ToUpper(row.Get("FromCurrency"))
+		toCurrency := strings.ToUpper(row.Get("ToCurrency"))
 		if fromCurrency == "" || toCurrency == "" {
-			result.ErrorCount++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: currency codes cannot be empty", i+2))
-			continue
+			return ImportRowResult{}, fmt.Errorf("currency codes cannot be empty")
 		}
 
-		rate, err := strconv.ParseFloat(rateStr, 64)
+		rate, err := strconv.ParseFloat(row.Get("Rate"), 64)
 		if err != nil {
-			result.ErrorCount++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: invalid rate: %v", i+2, err))
-			continue
+			return ImportRowResult{}, fmt.Errorf("invalid rate: %v", err)
 		}
 
-		effectiveDate, err := time.Parse(time.RFC3339, effectiveDateStr)
+		effectiveDate, err := parseImportDate(row.Get("EffectiveDate"))
 		if err != nil {
-			result.ErrorCount++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: invalid date format: %v", i+2, err))
-			continue
+			return ImportRowResult{}, err
 		}
 
-		// Try to create the forex rate
-		_, err = forexSvc.Create(fromCurrency, toCurrency, rate, effectiveDate)
+		var forex models.Forex
+		found, key, err := findImportMatch(tx, &forex, opts, row)
 		if err != nil {
-			result.ErrorCount++
-			result.Errors = append(result.Errors, fmt.Sprintf("Row %d: %v", i+2, err))
-			continue
+			return ImportRowResult{}, err
+		}
+		if key == "" {
+			key = fromCurrency + "/" + toCurrency
 		}
 
-		result.SuccessCount++
-	}
+		result := ImportRowResult{Key: key, Action: opts.reconcile(found)}
+
+		switch result.Action {
+		case ImportActionCreate:
+			if _, err := NewForexService(tx).Create(fromCurrency, toCurrency, rate, effectiveDate); err != nil {
+				return result, err
+			}
+		case ImportActionUpdate:
+			if len(fromCurrency) != 3 {
+				return result, &ValidationError{Field: "from_currency", Message: "currency must be a 3-letter ISO 4217 code"}
+			}
+			if len(toCurrency) != 3 {
+				return result, &ValidationError{Field: "to_currency", Message: "currency must be a 3-letter ISO 4217 code"}
+			}
+			if rate <= 0 {
+				return result, &ValidationError{Field: "rate", Message: "rate must be positive"}
+			}
 
-	return result, nil
+			var diff fieldDiff
+			diff.setString("FromCurrency", &forex.FromCurrency, fromCurrency)
+			diff.setString("ToCurrency", &forex.ToCurrency, toCurrency)
+			diff.setFloat("Rate", &forex.Rate, rate)
+			diff.setTime("EffectiveDate", &forex.EffectiveDate, effectiveDate)
+			if len(diff) == 0 {
+				result.Action = ImportActionSkip
+				result.Message = "unchanged"
+				return result, nil
+			}
+			result.Changes = diff
+			if err := tx.Save(&forex).Error; err != nil {
+				return result, err
+			}
+		case ImportActionSkip:
+			result.Message = "no matching forex rate"
+		}
+		return result, nil
+	})
 }
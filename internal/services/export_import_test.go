@@ -2,11 +2,13 @@ package services
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/shakfu/buyer/internal/models"
+	"github.com/xuri/excelize/v2"
 )
 
 func TestExportImportService_BrandsCSV(t *testing.T) {
@@ -488,3 +490,347 @@ func TestExportImportService_ForexExcel(t *testing.T) {
 		}
 	})
 }
+
+func TestExportImportService_ImportModes(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	vendorSvc := NewVendorService(cfg.DB)
+	exportSvc := NewExportImportService(cfg.DB)
+
+	_, _ = vendorSvc.Create("Amazon", "USD", "PRIME")
+	_, _ = vendorSvc.Create("Newegg", "USD", "")
+
+	csvData := `ID,Name,Currency,DiscountCode,Email
+0,Amazon,EUR,PRIME,orders@amazon.example
+0,Newegg,USD,,
+0,Adorama,USD,SUMMER15,`
+
+	t.Run("Create mode rejects existing names", func(t *testing.T) {
+		result, err := exportSvc.ImportVendorsCSVWithOptions(strings.NewReader(csvData), ImportOptions{Mode: ImportModeCreate})
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if result.ErrorCount != 2 {
+			t.Errorf("Expected 2 duplicate errors, got %d: %v", result.ErrorCount, result.Errors)
+		}
+		if !result.RolledBack {
+			t.Error("Expected the batch to be rolled back")
+		}
+		if _, err := vendorSvc.GetByName("Adorama"); err == nil {
+			t.Error("Adorama should not exist after a rolled back import")
+		}
+	})
+
+	t.Run("Dry run reports changes without writing", func(t *testing.T) {
+		result, err := exportSvc.ImportVendorsCSVWithOptions(strings.NewReader(csvData), ImportOptions{Mode: ImportModeUpsert, DryRun: true})
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if result.CreatedCount != 1 || result.UpdatedCount != 1 || result.SkippedCount != 1 {
+			t.Errorf("Expected 1 create, 1 update, 1 skip, got %d/%d/%d", result.CreatedCount, result.UpdatedCount, result.SkippedCount)
+		}
+		if !result.DryRun || !result.RolledBack {
+			t.Error("Expected dry run to be rolled back")
+		}
+
+		var amazonRow *ImportRowResult
+		for i := range result.Rows {
+			if result.Rows[i].Key == "Amazon" {
+				amazonRow = &result.Rows[i]
+			}
+		}
+		if amazonRow == nil {
+			t.Fatal("Expected a row result for Amazon")
+		}
+		if len(amazonRow.Changes) != 2 {
+			t.Fatalf("Expected 2 field changes for Amazon, got %v", amazonRow.Changes)
+		}
+		if amazonRow.Changes[0].Field != "Currency" || amazonRow.Changes[0].Old != "USD" || amazonRow.Changes[0].New != "EUR" {
+			t.Errorf("Unexpected currency change: %+v", amazonRow.Changes[0])
+		}
+
+		vendor, _ := vendorSvc.GetByName("Amazon")
+		if vendor.Currency != "USD" {
+			t.Errorf("Dry run should not change currency, got %s", vendor.Currency)
+		}
+		if _, err := vendorSvc.GetByName("Adorama"); err == nil {
+			t.Error("Dry run should not create Adorama")
+		}
+	})
+
+	t.Run("Update mode skips unmatched rows", func(t *testing.T) {
+		result, err := exportSvc.ImportVendorsCSVWithOptions(strings.NewReader(csvData), ImportOptions{Mode: ImportModeUpdate})
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if result.UpdatedCount != 1 || result.SkippedCount != 2 || result.CreatedCount != 0 {
+			t.Errorf("Expected 1 update and 2 skips, got created=%d updated=%d skipped=%d",
+				result.CreatedCount, result.UpdatedCount, result.SkippedCount)
+		}
+
+		vendor, _ := vendorSvc.GetByName("Amazon")
+		if vendor.Currency != "EUR" || vendor.Email != "orders@amazon.example" {
+			t.Errorf("Expected Amazon to be updated, got currency=%s email=%s", vendor.Currency, vendor.Email)
+		}
+	})
+
+	t.Run("Upsert creates missing rows", func(t *testing.T) {
+		result, err := exportSvc.ImportVendorsCSVWithOptions(strings.NewReader(csvData), ImportOptions{Mode: ImportModeUpsert})
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if result.CreatedCount != 1 {
+			t.Errorf("Expected 1 create, got %d", result.CreatedCount)
+		}
+		if _, err := vendorSvc.GetByName("Adorama"); err != nil {
+			t.Error("Adorama should exist after upsert")
+		}
+	})
+
+	t.Run("Failed row rolls back the batch", func(t *testing.T) {
+		badData := `ID,Name,Currency
+0,B&H Photo,USD
+0,Bad Currency,DOLLARS`
+		result, err := exportSvc.ImportVendorsCSVWithOptions(strings.NewReader(badData), ImportOptions{Mode: ImportModeUpsert})
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if result.ErrorCount != 1 || !result.RolledBack {
+			t.Errorf("Expected 1 error and rollback, got %d errors, rolled back=%v", result.ErrorCount, result.RolledBack)
+		}
+		if _, err := vendorSvc.GetByName("B&H Photo"); err == nil {
+			t.Error("B&H Photo should not exist after rollback")
+		}
+
+		result, err = exportSvc.ImportVendorsCSVWithOptions(strings.NewReader(badData), ImportOptions{Mode: ImportModeUpsert, ContinueOnError: true})
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if result.RolledBack {
+			t.Error("Expected valid rows to be committed with ContinueOnError")
+		}
+		if _, err := vendorSvc.GetByName("B&H Photo"); err != nil {
+			t.Error("B&H Photo should exist with ContinueOnError")
+		}
+	})
+
+	t.Run("Invalid key is rejected", func(t *testing.T) {
+		_, err := exportSvc.ImportVendorsCSVWithOptions(strings.NewReader(csvData), ImportOptions{Mode: ImportModeUpsert, Key: ImportKeySKU})
+		if err == nil {
+			t.Error("Expected error for SKU key on vendors")
+		}
+	})
+}
+
+func TestExportImportService_ImportProductsCSV(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	brandSvc := NewBrandService(cfg.DB)
+	productSvc := NewProductService(cfg.DB)
+	exportSvc := NewExportImportService(cfg.DB)
+
+	brand, _ := brandSvc.Create("Apple")
+	_, _ = productSvc.Create("iPhone 15", brand.ID, nil)
+	sku := "IP15"
+	cfg.DB.Model(&models.Product{}).Where("name = ?", "iPhone 15").Update("sku", sku)
+
+	csvData := `ID,Name,BrandID,BrandName,SKU,Description,LeadTimeDays
+0,iPhone 15 (Blue),,Apple,IP15,Renamed by SKU,7
+0,MacBook Air,,Apple,MBA13,,`
+
+	result, err := exportSvc.ImportProductsCSVWithOptions(strings.NewReader(csvData), ImportOptions{Mode: ImportModeUpsert, Key: ImportKeySKU})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.ErrorCount != 0 {
+		t.Fatalf("Expected no errors, got %v", result.Errors)
+	}
+	if result.CreatedCount != 1 || result.UpdatedCount != 1 {
+		t.Errorf("Expected 1 create and 1 update, got %d/%d", result.CreatedCount, result.UpdatedCount)
+	}
+
+	product, err := productSvc.GetByName("iPhone 15 (Blue)")
+	if err != nil {
+		t.Fatalf("Expected product to be renamed: %v", err)
+	}
+	if product.LeadTimeDays != 7 || product.Description != "Renamed by SKU" {
+		t.Errorf("Expected product details to be updated, got lead time %d, description %q", product.LeadTimeDays, product.Description)
+	}
+
+	created, err := productSvc.GetByName("MacBook Air")
+	if err != nil {
+		t.Fatalf("Expected MacBook Air to be created: %v", err)
+	}
+	if created.SKU == nil || *created.SKU != "MBA13" {
+		t.Errorf("Expected SKU MBA13, got %v", created.SKU)
+	}
+}
+
+func TestExportImportService_ImportForexUpdateByID(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	forexSvc := NewForexService(cfg.DB)
+	exportSvc := NewExportImportService(cfg.DB)
+
+	rate, _ := forexSvc.Create("EUR", "USD", 1.10, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	csvData := "ID,FromCurrency,ToCurrency,Rate,EffectiveDate\n" +
+		fmt.Sprintf("%d,EUR,USD,1.15,2024-01-01\n", rate.ID) +
+		"999,GBP,USD,1.30,2024-01-01\n"
+
+	result, err := exportSvc.ImportForexCSVWithOptions(strings.NewReader(csvData), ImportOptions{Mode: ImportModeUpdate})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.UpdatedCount != 1 || result.SkippedCount != 1 {
+		t.Errorf("Expected 1 update and 1 skip, got %d/%d", result.UpdatedCount, result.SkippedCount)
+	}
+
+	var updated models.Forex
+	cfg.DB.First(&updated, rate.ID)
+	if updated.Rate != 1.15 {
+		t.Errorf("Expected rate 1.15, got %f", updated.Rate)
+	}
+}
+
+func TestExportImportService_ImportVendorsExcelRoundTrip(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	vendorSvc := NewVendorService(cfg.DB)
+	exportSvc := NewExportImportService(cfg.DB)
+
+	_, _ = vendorSvc.Create("Amazon", "USD", "PRIME")
+
+	f, err := exportSvc.ExportVendorsExcel()
+	if err != nil {
+		t.Fatalf("Failed to export vendors: %v", err)
+	}
+
+	// Edit the exported workbook and add a new vendor row
+	_ = f.SetCellValue("Vendors", "D2", "PRIME20")
+	_ = f.SetCellValue("Vendors", "B3", "Newegg")
+	_ = f.SetCellValue("Vendors", "C3", "EUR")
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatalf("Failed to write workbook: %v", err)
+	}
+
+	result, err := exportSvc.ImportVendorsExcel(&buf, ImportOptions{Mode: ImportModeUpsert})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.ErrorCount != 0 {
+		t.Fatalf("Expected no errors, got %v", result.Errors)
+	}
+	if result.UpdatedCount != 1 || result.CreatedCount != 1 {
+		t.Errorf("Expected 1 update and 1 create, got %d/%d", result.UpdatedCount, result.CreatedCount)
+	}
+
+	amazon, _ := vendorSvc.GetByName("Amazon")
+	if amazon.DiscountCode != "PRIME20" {
+		t.Errorf("Expected discount code PRIME20, got %s", amazon.DiscountCode)
+	}
+	newegg, err := vendorSvc.GetByName("Newegg")
+	if err != nil {
+		t.Fatalf("Expected Newegg to be created: %v", err)
+	}
+	if newegg.Currency != "EUR" {
+		t.Errorf("Expected currency EUR, got %s", newegg.Currency)
+	}
+}
+
+func TestExportImportService_ImportForexExcel(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	exportSvc := NewExportImportService(cfg.DB)
+
+	// Hand-built workbook with numeric rate and a real date cell on a custom sheet
+	f := excelize.NewFile()
+	_, _ = f.NewSheet("Rates")
+	_ = f.SetSheetRow("Rates", "A1", &[]string{"From Currency", "To Currency", "Rate", "Effective Date"})
+	_ = f.SetSheetRow("Rates", "A2", &[]interface{}{"GBP", "USD", 1.27})
+	dateStyle, _ := f.NewStyle(&excelize.Style{NumFmt: 14})
+	_ = f.SetCellValue("Rates", "D2", time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+	_ = f.SetCellStyle("Rates", "D2", "D2", dateStyle)
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatalf("Failed to write workbook: %v", err)
+	}
+	data := buf.Bytes()
+
+	t.Run("Unknown sheet", func(t *testing.T) {
+		_, err := exportSvc.ImportForexExcel(bytes.NewReader(data), ImportOptions{Sheet: "Nope"})
+		if err == nil {
+			t.Fatal("Expected error for unknown sheet")
+		}
+	})
+
+	t.Run("Named sheet", func(t *testing.T) {
+		result, err := exportSvc.ImportForexExcel(bytes.NewReader(data), ImportOptions{Sheet: "Rates"})
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if result.CreatedCount != 1 {
+			t.Fatalf("Expected 1 created, got %d (errors: %v)", result.CreatedCount, result.Errors)
+		}
+
+		var rate models.Forex
+		cfg.DB.First(&rate, "from_currency = ?", "GBP")
+		if rate.Rate != 1.27 {
+			t.Errorf("Expected rate 1.27, got %f", rate.Rate)
+		}
+		if !rate.EffectiveDate.Equal(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected effective date 2024-03-15, got %v", rate.EffectiveDate)
+		}
+	})
+}
+
+func TestExportImportService_ImportQuotesCSV(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	vendorSvc := NewVendorService(cfg.DB)
+	brandSvc := NewBrandService(cfg.DB)
+	productSvc := NewProductService(cfg.DB)
+	exportSvc := NewExportImportService(cfg.DB)
+
+	vendor, _ := vendorSvc.Create("B&H Photo", "USD", "")
+	brand, _ := brandSvc.Create("Apple")
+	product, _ := productSvc.Create("iPhone 15", brand.ID, nil)
+
+	csvData := "VendorName,ProductName,Price,MinQuantity,QuoteDate,ValidUntil,Notes\n" +
+		"B&H Photo,iPhone 15,999.00,5,2024-01-01,2024-03-31,Q1 pricing\n" +
+		"Unknown,iPhone 15,10,,,,\n"
+
+	result, err := exportSvc.ImportQuotesCSVWithOptions(strings.NewReader(csvData), ImportOptions{ContinueOnError: true})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.CreatedCount != 1 || result.ErrorCount != 1 {
+		t.Fatalf("Expected 1 created and 1 error, got %d/%d: %v", result.CreatedCount, result.ErrorCount, result.Errors)
+	}
+
+	quotes, _ := NewQuoteService(cfg.DB).ListByVendor(vendor.ID)
+	if len(quotes) != 1 {
+		t.Fatalf("Expected 1 quote, got %d", len(quotes))
+	}
+	quote := quotes[0]
+	if quote.ProductID != product.ID || quote.Price != 999 || quote.Currency != "USD" || quote.MinQuantity != 5 {
+		t.Errorf("Unexpected quote: %+v", quote)
+	}
+	if quote.ValidUntil == nil || quote.ValidUntil.Format("2006-01-02") != "2024-03-31" {
+		t.Errorf("Expected valid until 2024-03-31, got %v", quote.ValidUntil)
+	}
+
+	_, err = exportSvc.ImportQuotesCSVWithOptions(strings.NewReader(csvData), ImportOptions{Mode: ImportModeUpsert})
+	if err == nil {
+		t.Error("Expected error for upsert mode on quotes")
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"time"
@@ -19,6 +20,11 @@ func NewForexService(db *gorm.DB) *ForexService {
 	return &ForexService{db: db}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *ForexService) WithContext(ctx context.Context) *ForexService {
+	return NewForexService(s.db.WithContext(ctx))
+}
+
 // Create creates a new forex rate
 func (s *ForexService) Create(fromCurrency, toCurrency string, rate float64, effectiveDate time.Time) (*models.Forex, error) {
 	fromCurrency = strings.ToUpper(strings.TrimSpace(fromCurrency))
@@ -107,6 +113,40 @@ func (s *ForexService) List(limit, offset int) ([]models.Forex, error) {
 	return rates, err
 }
 
+// GetByID retrieves a forex rate by ID
+func (s *ForexService) GetByID(id uint) (*models.Forex, error) {
+	var forex models.Forex
+	err := s.db.First(&forex, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &NotFoundError{Entity: "Forex", ID: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &forex, nil
+}
+
+// Update changes the rate and effective date of a forex rate
+func (s *ForexService) Update(id uint, rate float64, effectiveDate time.Time) (*models.Forex, error) {
+	if rate <= 0 {
+		return nil, &ValidationError{Field: "rate", Message: "rate must be positive"}
+	}
+
+	forex, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	forex.Rate = rate
+	if !effectiveDate.IsZero() {
+		forex.EffectiveDate = effectiveDate
+	}
+	if err := s.db.Save(forex).Error; err != nil {
+		return nil, err
+	}
+	return forex, nil
+}
+
 // Delete deletes a forex rate by ID
 func (s *ForexService) Delete(id uint) error {
 	result := s.db.Delete(&models.Forex{}, id)
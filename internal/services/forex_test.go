@@ -216,3 +216,35 @@ func TestForexService_Convert(t *testing.T) {
 		})
 	}
 }
+
+func TestForexService_Update(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	svc := NewForexService(cfg.DB)
+	rate, err := svc.Create("EUR", "USD", 1.1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to create forex: %v", err)
+	}
+
+	effective := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	updated, err := svc.Update(rate.ID, 1.15, effective)
+	if err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+	if updated.Rate != 1.15 || !updated.EffectiveDate.Equal(effective) {
+		t.Errorf("Update() = %+v, want rate 1.15 effective %v", updated, effective)
+	}
+
+	got, err := svc.GetByID(rate.ID)
+	if err != nil || got.Rate != 1.15 {
+		t.Errorf("GetByID() = %+v, %v; want updated rate", got, err)
+	}
+
+	if _, err := svc.Update(rate.ID, -1, time.Time{}); err == nil {
+		t.Error("Update() with negative rate should fail")
+	}
+	if _, err := svc.GetByID(9999); err == nil {
+		t.Error("GetByID() of missing rate should fail")
+	}
+}
@@ -0,0 +1,593 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// ImportMode controls how imported rows are reconciled with existing records
+type ImportMode string
+
+const (
+	ImportModeCreate ImportMode = "create" // Only create new records
+	ImportModeUpdate ImportMode = "update" // Only update existing records; unmatched rows are skipped
+	ImportModeUpsert ImportMode = "upsert" // Update existing records and create the rest
+)
+
+// ImportKey selects the field used to match imported rows to existing records
+type ImportKey string
+
+const (
+	ImportKeyID   ImportKey = "id"
+	ImportKeyName ImportKey = "name"
+	ImportKeySKU  ImportKey = "sku"
+)
+
+// ImportAction describes what happened (or would happen in a dry run) to a row
+type ImportAction string
+
+const (
+	ImportActionCreate ImportAction = "create"
+	ImportActionUpdate ImportAction = "update"
+	ImportActionSkip   ImportAction = "skip"
+	ImportActionError  ImportAction = "error"
+)
+
+// ImportOptions configures an import run
+type ImportOptions struct {
+	Mode            ImportMode
+	Key             ImportKey // Defaults to the entity's natural key when empty
+	DryRun          bool      // Report what would change without committing anything
+	ContinueOnError bool      // Commit successful rows even if other rows fail
+	Sheet           string    // Excel sheet to read; defaults to the entity's export sheet
+
+	rowErrors map[int]error // Errors found before import (e.g. by profile transforms), keyed by row number
+}
+
+// FieldChange records a single field difference between an existing record and an imported row
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// ImportRowResult describes the outcome for a single data row
+type ImportRowResult struct {
+	Row     int           `json:"row"`
+	Action  ImportAction  `json:"action"`
+	Key     string        `json:"key,omitempty"`
+	Changes []FieldChange `json:"changes,omitempty"`
+	Message string        `json:"message,omitempty"`
+}
+
+// errImportRollback is returned from the import transaction to discard its changes
+var errImportRollback = errors.New("import rolled back")
+
+// importRowFunc applies one data row inside a transaction and reports its outcome
+type importRowFunc func(tx *gorm.DB, row importRow) (ImportRowResult, error)
+
+// importRow is a data row with header-based column lookup
+type importRow struct {
+	Number int // 1-based row number in the source file, counting the header
+	cols   importColumns
+	record []string
+}
+
+// Has reports whether the source file contains the named column
+func (r importRow) Has(name string) bool {
+	_, ok := r.cols[normalizeColumnName(name)]
+	return ok
+}
+
+// Get returns the trimmed value of the named column, or "" if absent
+func (r importRow) Get(name string) string {
+	idx, ok := r.cols[normalizeColumnName(name)]
+	if !ok || idx >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[idx])
+}
+
+// importColumns maps normalized header names to column indexes
+type importColumns map[string]int
+
+// newImportColumns builds a column map from a header row. If none of the header
+// cells match the expected layout, the file is assumed to follow the layout positionally.
+func newImportColumns(header []string, layout []string) importColumns {
+	cols := make(importColumns)
+	known := make(map[string]bool, len(layout))
+	for _, name := range layout {
+		known[normalizeColumnName(name)] = true
+	}
+
+	matched := false
+	for i, name := range header {
+		n := normalizeColumnName(name)
+		if n == "" {
+			continue
+		}
+		if _, exists := cols[n]; !exists {
+			cols[n] = i
+		}
+		if known[n] {
+			matched = true
+		}
+	}
+
+	if !matched {
+		cols = make(importColumns)
+		for i, name := range layout {
+			cols[normalizeColumnName(name)] = i
+		}
+	}
+	return cols
+}
+
+// columnAliases maps abbreviated Excel export headers to their CSV equivalents
+var columnAliases = map[string]string{
+	"specid":   "specificationid",
+	"specname": "specificationname",
+}
+
+// normalizeColumnName lowercases a header and strips separators so that
+// "DiscountCode", "Discount Code" and "discount_code" all match
+func normalizeColumnName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch r {
+		case ' ', '_', '-', '.':
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if alias, ok := columnAliases[b.String()]; ok {
+		return alias
+	}
+	return b.String()
+}
+
+// resolve fills in defaults and validates the options for an entity
+func (o ImportOptions) resolve(defaultKey ImportKey, validKeys ...ImportKey) (ImportOptions, error) {
+	if o.Mode == "" {
+		o.Mode = ImportModeCreate
+	}
+	switch o.Mode {
+	case ImportModeCreate, ImportModeUpdate, ImportModeUpsert:
+	default:
+		return o, &ValidationError{Field: "mode", Message: fmt.Sprintf("unknown import mode '%s' (must be one of: create, update, upsert)", o.Mode)}
+	}
+
+	if o.Key == "" {
+		o.Key = defaultKey
+	}
+	for _, k := range validKeys {
+		if o.Key == k {
+			return o, nil
+		}
+	}
+	names := make([]string, len(validKeys))
+	for i, k := range validKeys {
+		names[i] = string(k)
+	}
+	return o, &ValidationError{Field: "key", Message: fmt.Sprintf("unsupported import key '%s' (must be one of: %s)", o.Key, strings.Join(names, ", "))}
+}
+
+// runImport applies every data row in a single transaction. Each row runs in a
+// savepoint so that a failed row never leaves partial writes behind. The whole
+// batch is rolled back on a dry run, or if any row failed and ContinueOnError is not set.
+func (s *ExportImportService) runImport(records [][]string, layout []string, opts ImportOptions, apply importRowFunc) (*ImportResult, error) {
+	if len(records) < 2 {
+		return nil, fmt.Errorf("CSV file must contain at least a header and one data row")
+	}
+
+	cols := newImportColumns(records[0], layout)
+	result := &ImportResult{
+		Errors: make([]string, 0),
+		Rows:   make([]ImportRowResult, 0, len(records)-1),
+		DryRun: opts.DryRun,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for i, record := range records[1:] {
+			row := importRow{Number: i + 2, cols: cols, record: record}
+			if isBlankRecord(record) {
+				continue
+			}
+
+			var rowResult ImportRowResult
+			rowErr := opts.rowErrors[row.Number]
+			if rowErr == nil {
+				rowErr = tx.Transaction(func(rowTx *gorm.DB) error {
+					var err error
+					rowResult, err = apply(rowTx, row)
+					return err
+				})
+			}
+			rowResult.Row = row.Number
+
+			if rowErr != nil {
+				rowResult.Action = ImportActionError
+				rowResult.Message = rowErr.Error()
+				result.ErrorCount++
+				result.Errors = append(result.Errors, fmt.Sprintf("Row %d: %v", row.Number, rowErr))
+				result.Rows = append(result.Rows, rowResult)
+				continue
+			}
+
+			switch rowResult.Action {
+			case ImportActionCreate:
+				result.CreatedCount++
+			case ImportActionUpdate:
+				result.UpdatedCount++
+			case ImportActionSkip:
+				result.SkippedCount++
+			}
+			result.Rows = append(result.Rows, rowResult)
+		}
+
+		if opts.DryRun || (result.ErrorCount > 0 && !opts.ContinueOnError) {
+			return errImportRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportRollback) {
+		return nil, err
+	}
+
+	result.RolledBack = errors.Is(err, errImportRollback)
+	result.SuccessCount = result.CreatedCount + result.UpdatedCount
+	return result, nil
+}
+
+// findImportMatch looks up the existing record matching a row by the configured key,
+// loading it into dest. It returns whether a match was found and the key value used.
+// Create-only imports never match existing records.
+func findImportMatch(tx *gorm.DB, dest interface{}, opts ImportOptions, row importRow) (bool, string, error) {
+	if opts.Mode == ImportModeCreate {
+		return false, "", nil
+	}
+
+	var query *gorm.DB
+	var key string
+	switch opts.Key {
+	case ImportKeyID:
+		id, err := parseImportID(row.Get("ID"))
+		if err != nil {
+			return false, "", err
+		}
+		if id == 0 {
+			return false, "", nil
+		}
+		key = strconv.FormatUint(uint64(id), 10)
+		query = tx.Where("id = ?", id)
+	case ImportKeyName:
+		key = row.Get("Name")
+		if key == "" {
+			return false, "", nil
+		}
+		query = tx.Where("name = ?", key)
+	case ImportKeySKU:
+		key = row.Get("SKU")
+		if key == "" {
+			return false, "", nil
+		}
+		query = tx.Where("sku = ?", key)
+	default:
+		return false, "", &ValidationError{Field: "key", Message: fmt.Sprintf("unsupported import key '%s'", opts.Key)}
+	}
+
+	err := query.First(dest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, key, nil
+	}
+	if err != nil {
+		return false, key, err
+	}
+	return true, key, nil
+}
+
+// resolveImportReference resolves a related record by ID, or by name when the ID is empty.
+// It returns 0 when both are empty.
+func resolveImportReference(tx *gorm.DB, model interface{}, entity, idValue, name string) (uint, error) {
+	id, err := parseImportID(idValue)
+	if err != nil {
+		return 0, err
+	}
+
+	var ref struct{ ID uint }
+	switch {
+	case id > 0:
+		err = tx.Model(model).Where("id = ?", id).Take(&ref).Error
+	case name != "":
+		err = tx.Model(model).Where("name = ?", name).Take(&ref).Error
+	default:
+		return 0, nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if id > 0 {
+			return 0, &NotFoundError{Entity: entity, ID: id}
+		}
+		return 0, &NotFoundError{Entity: entity, ID: name}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return ref.ID, nil
+}
+
+// readExcelRecords reads a worksheet into string records suitable for the row importers.
+// The sheet defaults to defaultSheet if present, otherwise the workbook's active sheet.
+// Typed cells are converted to the text layout used by the CSV exports: numbers are
+// written without grouping or currency symbols, dates as RFC3339 and booleans as true/false.
+func readExcelRecords(r io.Reader, sheet, defaultSheet string) ([][]string, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Excel file: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if sheet == "" {
+		sheet = f.GetSheetName(f.GetActiveSheetIndex())
+		for _, name := range sheets {
+			if name == defaultSheet {
+				sheet = name
+				break
+			}
+		}
+	}
+	if idx, _ := f.GetSheetIndex(sheet); idx < 0 {
+		return nil, &ValidationError{Field: "sheet", Message: fmt.Sprintf("sheet '%s' not found (available: %s)", sheet, strings.Join(sheets, ", "))}
+	}
+
+	rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("Excel sheet '%s' must contain at least a header and one data row", sheet)
+	}
+
+	dateStyles := make(map[int]bool)
+	for i, row := range rows {
+		if i == 0 {
+			continue
+		}
+		for j, value := range row {
+			if value == "" {
+				continue
+			}
+			cell, err := excelize.CoordinatesToCellName(j+1, i+1)
+			if err != nil {
+				return nil, err
+			}
+			row[j], err = excelCellText(f, sheet, cell, value, dateStyles)
+			if err != nil {
+				return nil, fmt.Errorf("cell %s: %w", cell, err)
+			}
+		}
+	}
+	return rows, nil
+}
+
+// excelCellText converts a raw cell value to text based on the cell's type and number format
+func excelCellText(f *excelize.File, sheet, cell, value string, dateStyles map[int]bool) (string, error) {
+	cellType, err := f.GetCellType(sheet, cell)
+	if err != nil {
+		return "", err
+	}
+
+	switch cellType {
+	case excelize.CellTypeBool:
+		return strconv.FormatBool(value == "1" || strings.EqualFold(value, "true")), nil
+	case excelize.CellTypeDate:
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.Format(time.RFC3339), nil
+			}
+		}
+		return value, nil
+	case excelize.CellTypeNumber, excelize.CellTypeUnset, excelize.CellTypeFormula:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return value, nil
+		}
+		styleID, err := f.GetCellStyle(sheet, cell)
+		if err != nil {
+			return "", err
+		}
+		isDate, cached := dateStyles[styleID]
+		if !cached {
+			if style, err := f.GetStyle(styleID); err == nil {
+				isDate = isExcelDateFormat(style.NumFmt, style.CustomNumFmt)
+			}
+			dateStyles[styleID] = isDate
+		}
+		if isDate {
+			t, err := excelize.ExcelDateToTime(number, false)
+			if err != nil {
+				return "", err
+			}
+			return t.Format(time.RFC3339), nil
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	default:
+		return value, nil
+	}
+}
+
+// isExcelDateFormat reports whether a built-in or custom number format displays a date
+func isExcelDateFormat(numFmt int, custom *string) bool {
+	if custom != nil && *custom != "" {
+		code := strings.ToLower(*custom)
+		var b strings.Builder
+		inQuote, inBracket := false, false
+		for _, r := range code {
+			switch {
+			case r == '"':
+				inQuote = !inQuote
+			case r == '[' && !inQuote:
+				inBracket = true
+			case r == ']' && !inQuote:
+				inBracket = false
+			case !inQuote && !inBracket:
+				b.WriteRune(r)
+			}
+		}
+		return strings.ContainsAny(b.String(), "yd")
+	}
+	switch {
+	case numFmt >= 14 && numFmt <= 22,
+		numFmt >= 27 && numFmt <= 36,
+		numFmt >= 45 && numFmt <= 47,
+		numFmt >= 50 && numFmt <= 58:
+		return true
+	}
+	return false
+}
+
+// isBlankRecord reports whether every cell in a record is empty
+func isBlankRecord(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// parseImportID parses an optional ID cell; empty and zero values mean "no ID"
+func parseImportID(value string) (uint, error) {
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, &ValidationError{Field: "id", Message: fmt.Sprintf("invalid ID: %s", value)}
+	}
+	return uint(id), nil
+}
+
+// parseImportDate parses a date cell in RFC3339 or YYYY-MM-DD format
+func parseImportDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date format: %s (use RFC3339 or YYYY-MM-DD)", value)
+}
+
+// fieldDiff accumulates field changes while applying imported values to a record
+type fieldDiff []FieldChange
+
+// setString assigns value to dst, recording a change if it differs
+func (d *fieldDiff) setString(field string, dst *string, value string) {
+	if *dst != value {
+		*d = append(*d, FieldChange{Field: field, Old: *dst, New: value})
+		*dst = value
+	}
+}
+
+// setFloat assigns value to dst, recording a change if it differs
+func (d *fieldDiff) setFloat(field string, dst *float64, value float64) {
+	if *dst != value {
+		*d = append(*d, FieldChange{Field: field, Old: strconv.FormatFloat(*dst, 'f', -1, 64), New: strconv.FormatFloat(value, 'f', -1, 64)})
+		*dst = value
+	}
+}
+
+// setInt assigns value to dst, recording a change if it differs
+func (d *fieldDiff) setInt(field string, dst *int, value int) {
+	if *dst != value {
+		*d = append(*d, FieldChange{Field: field, Old: strconv.Itoa(*dst), New: strconv.Itoa(value)})
+		*dst = value
+	}
+}
+
+// setBool assigns value to dst, recording a change if it differs
+func (d *fieldDiff) setBool(field string, dst *bool, value bool) {
+	if *dst != value {
+		*d = append(*d, FieldChange{Field: field, Old: strconv.FormatBool(*dst), New: strconv.FormatBool(value)})
+		*dst = value
+	}
+}
+
+// setTime assigns value to dst, recording a change if it differs
+func (d *fieldDiff) setTime(field string, dst *time.Time, value time.Time) {
+	if !dst.Equal(value) {
+		*d = append(*d, FieldChange{Field: field, Old: dst.Format(time.RFC3339), New: value.Format(time.RFC3339)})
+		*dst = value
+	}
+}
+
+// setOptionalString assigns value to a nullable string, treating "" as NULL
+func (d *fieldDiff) setOptionalString(field string, dst **string, value string) {
+	old := ""
+	if *dst != nil {
+		old = **dst
+	}
+	if old == value {
+		return
+	}
+	*d = append(*d, FieldChange{Field: field, Old: old, New: value})
+	if value == "" {
+		*dst = nil
+	} else {
+		v := value
+		*dst = &v
+	}
+}
+
+// setOptionalUint assigns value to a nullable ID, treating 0 as NULL
+func (d *fieldDiff) setOptionalUint(field string, dst **uint, value uint) {
+	var old uint
+	if *dst != nil {
+		old = **dst
+	}
+	if old == value {
+		return
+	}
+	*d = append(*d, FieldChange{Field: field, Old: formatOptionalUint(old), New: formatOptionalUint(value)})
+	if value == 0 {
+		*dst = nil
+	} else {
+		v := value
+		*dst = &v
+	}
+}
+
+func formatOptionalUint(v uint) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+// reconcile decides what to do with a row given the import mode and whether a
+// matching record exists. Create-only imports never look up existing records;
+// the entity services reject duplicates as they always have.
+func (o ImportOptions) reconcile(exists bool) ImportAction {
+	switch o.Mode {
+	case ImportModeUpdate:
+		if !exists {
+			return ImportActionSkip
+		}
+		return ImportActionUpdate
+	case ImportModeUpsert:
+		if exists {
+			return ImportActionUpdate
+		}
+		return ImportActionCreate
+	default:
+		return ImportActionCreate
+	}
+}
@@ -0,0 +1,518 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// importTargets maps each import profile entity to the column layout of its importer
+var importTargets = map[string][]string{
+	"brands":   brandImportLayout,
+	"vendors":  vendorImportLayout,
+	"products": productImportLayout,
+	"quotes":   quoteImportLayout,
+	"forex":    forexImportLayout,
+}
+
+// ImportProfileService handles business logic for import mapping profiles
+type ImportProfileService struct {
+	db *gorm.DB
+}
+
+// NewImportProfileService creates a new import profile service
+func NewImportProfileService(db *gorm.DB) *ImportProfileService {
+	return &ImportProfileService{db: db}
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *ImportProfileService) WithContext(ctx context.Context) *ImportProfileService {
+	return NewImportProfileService(s.db.WithContext(ctx))
+}
+
+// CreateImportProfileInput holds the input for creating an import profile
+type CreateImportProfileInput struct {
+	Name     string
+	Entity   string
+	VendorID *uint
+	Sheet    string
+	Fields   []models.ImportProfileField
+}
+
+// Create validates and stores a new import profile with its field mappings
+func (s *ImportProfileService) Create(input CreateImportProfileInput) (*models.ImportProfile, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, &ValidationError{Field: "name", Message: "profile name cannot be empty"}
+	}
+
+	entity := strings.ToLower(strings.TrimSpace(input.Entity))
+	layout, ok := importTargets[entity]
+	if !ok {
+		return nil, &ValidationError{Field: "entity", Message: fmt.Sprintf("unknown import entity '%s' (must be one of: %s)", input.Entity, strings.Join(importEntityNames(), ", "))}
+	}
+
+	if input.VendorID != nil {
+		var vendor models.Vendor
+		if err := s.db.First(&vendor, *input.VendorID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, &NotFoundError{Entity: "Vendor", ID: *input.VendorID}
+			}
+			return nil, err
+		}
+	}
+
+	if len(input.Fields) == 0 {
+		return nil, &ValidationError{Field: "fields", Message: "profile must map at least one field"}
+	}
+
+	fields := make([]models.ImportProfileField, 0, len(input.Fields))
+	seen := make(map[string]bool, len(input.Fields))
+	for _, f := range input.Fields {
+		target, ok := canonicalImportField(layout, f.Field)
+		if !ok {
+			return nil, &ValidationError{Field: "fields", Message: fmt.Sprintf("unknown field '%s' for %s (valid: %s)", f.Field, entity, strings.Join(layout, ", "))}
+		}
+		if seen[target] {
+			return nil, &ValidationError{Field: "fields", Message: fmt.Sprintf("field '%s' is mapped more than once", target)}
+		}
+		seen[target] = true
+
+		column := strings.TrimSpace(f.Column)
+		if (column == "") == (f.Constant == "") {
+			return nil, &ValidationError{Field: "fields", Message: fmt.Sprintf("field '%s' needs either a source column or a constant", target)}
+		}
+		if _, err := parseImportTransforms(f.Transform); err != nil {
+			return nil, &ValidationError{Field: "fields", Message: fmt.Sprintf("field '%s': %v", target, err)}
+		}
+
+		fields = append(fields, models.ImportProfileField{
+			Field:     target,
+			Column:    column,
+			Constant:  f.Constant,
+			Transform: strings.TrimSpace(f.Transform),
+		})
+	}
+
+	// Check for duplicate
+	var existing models.ImportProfile
+	err := s.db.Where("name = ?", name).First(&existing).Error
+	if err == nil {
+		return nil, &DuplicateError{Entity: "Import profile", Name: name}
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	profile := &models.ImportProfile{
+		Name:     name,
+		Entity:   entity,
+		VendorID: input.VendorID,
+		Sheet:    strings.TrimSpace(input.Sheet),
+		Fields:   fields,
+	}
+	if err := s.db.Create(profile).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(profile.ID)
+}
+
+// GetByID retrieves an import profile by ID with its vendor and field mappings
+func (s *ImportProfileService) GetByID(id uint) (*models.ImportProfile, error) {
+	var profile models.ImportProfile
+	err := s.preload().First(&profile, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &NotFoundError{Entity: "Import profile", ID: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// GetByName retrieves an import profile by name
+func (s *ImportProfileService) GetByName(name string) (*models.ImportProfile, error) {
+	var profile models.ImportProfile
+	err := s.preload().Where("name = ?", name).First(&profile).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &NotFoundError{Entity: "Import profile", ID: name}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// List retrieves all import profiles ordered by name
+func (s *ImportProfileService) List() ([]models.ImportProfile, error) {
+	var profiles []models.ImportProfile
+	err := s.preload().Order("name ASC").Find(&profiles).Error
+	return profiles, err
+}
+
+// ListByVendor retrieves the import profiles stored for a vendor
+func (s *ImportProfileService) ListByVendor(vendorID uint) ([]models.ImportProfile, error) {
+	var profiles []models.ImportProfile
+	err := s.preload().Where("vendor_id = ?", vendorID).Order("name ASC").Find(&profiles).Error
+	return profiles, err
+}
+
+// Delete deletes an import profile and its field mappings
+func (s *ImportProfileService) Delete(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.ImportProfile{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &NotFoundError{Entity: "Import profile", ID: id}
+		}
+		return tx.Where("profile_id = ?", id).Delete(&models.ImportProfileField{}).Error
+	})
+}
+
+func (s *ImportProfileService) preload() *gorm.DB {
+	return s.db.Preload("Vendor").Preload("Fields", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	})
+}
+
+// importEntityNames returns the supported profile entities in sorted order
+func importEntityNames() []string {
+	names := make([]string, 0, len(importTargets))
+	for name := range importTargets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// canonicalImportField matches a field name against a layout, ignoring case and separators
+func canonicalImportField(layout []string, field string) (string, bool) {
+	n := normalizeColumnName(field)
+	for _, name := range layout {
+		if normalizeColumnName(name) == n {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// ==================== Profile Transforms ====================
+
+// importTransform converts a single cell value
+type importTransform func(value string) (string, error)
+
+// parseImportTransforms parses a pipe-separated transform list such as
+// "trim|decimal-comma|multiply:1.2". Supported transforms:
+//
+//	trim            remove surrounding whitespace
+//	upper, lower    change case
+//	decimal-comma   read "1.234,50" as 1234.50
+//	multiply:N      multiply a numeric value by N (e.g. for unit or currency conversion)
+//	date:LAYOUT     parse a date using YYYY, YY, MM and DD tokens (or a Go layout)
+func parseImportTransforms(spec string) ([]importTransform, error) {
+	var transforms []importTransform
+	for _, part := range strings.Split(spec, "|") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, arg, _ := strings.Cut(part, ":")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "trim":
+			transforms = append(transforms, func(v string) (string, error) {
+				return strings.TrimSpace(v), nil
+			})
+		case "upper":
+			transforms = append(transforms, func(v string) (string, error) {
+				return strings.ToUpper(v), nil
+			})
+		case "lower":
+			transforms = append(transforms, func(v string) (string, error) {
+				return strings.ToLower(v), nil
+			})
+		case "decimal-comma":
+			transforms = append(transforms, func(v string) (string, error) {
+				v = strings.ReplaceAll(strings.ReplaceAll(v, " ", ""), ".", "")
+				return strings.Replace(v, ",", ".", 1), nil
+			})
+		case "multiply":
+			factor, err := strconv.ParseFloat(strings.TrimSpace(arg), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid multiply factor '%s'", arg)
+			}
+			transforms = append(transforms, func(v string) (string, error) {
+				if v == "" {
+					return v, nil
+				}
+				n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err != nil {
+					return "", fmt.Errorf("cannot multiply non-numeric value '%s'", v)
+				}
+				// Round away float noise such as 11.000000000000002
+				n = math.Round(n*factor*1e6) / 1e6
+				return strconv.FormatFloat(n, 'f', -1, 64), nil
+			})
+		case "date":
+			if strings.TrimSpace(arg) == "" {
+				return nil, fmt.Errorf("date transform needs a layout, e.g. date:DD/MM/YYYY")
+			}
+			layout := importDateLayout(strings.TrimSpace(arg))
+			transforms = append(transforms, func(v string) (string, error) {
+				if v == "" {
+					return v, nil
+				}
+				// Typed Excel date cells already arrive as RFC3339
+				if _, err := time.Parse(time.RFC3339, v); err == nil {
+					return v, nil
+				}
+				t, err := time.Parse(layout, strings.TrimSpace(v))
+				if err != nil {
+					return "", fmt.Errorf("date '%s' does not match %s", v, arg)
+				}
+				return t.Format("2006-01-02"), nil
+			})
+		default:
+			return nil, fmt.Errorf("unknown transform '%s' (must be one of: trim, upper, lower, decimal-comma, multiply:N, date:LAYOUT)", name)
+		}
+	}
+	return transforms, nil
+}
+
+// importDateLayout converts YYYY/YY/MM/DD tokens to a Go time layout
+func importDateLayout(layout string) string {
+	return strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02").Replace(layout)
+}
+
+// mapProfileRecords rewrites source records into the profile's target layout. The first
+// record is the source header; the returned header holds the target field names. Rows
+// whose transforms fail are reported as errors keyed by row number.
+func mapProfileRecords(profile *models.ImportProfile, records [][]string) ([][]string, map[int]error, error) {
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("file must contain a header row")
+	}
+
+	header := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		n := normalizeColumnName(name)
+		if _, exists := header[n]; !exists && n != "" {
+			header[n] = i
+		}
+	}
+
+	type mappedField struct {
+		index      int // Source column index, or -1 for constants
+		constant   string
+		transforms []importTransform
+	}
+
+	targets := make([]string, 0, len(profile.Fields)+1)
+	fields := make([]mappedField, 0, len(profile.Fields)+1)
+	hasVendor := false
+	for _, f := range profile.Fields {
+		transforms, err := parseImportTransforms(f.Transform)
+		if err != nil {
+			return nil, nil, &ValidationError{Field: f.Field, Message: err.Error()}
+		}
+		mf := mappedField{index: -1, constant: f.Constant, transforms: transforms}
+		if f.Column != "" {
+			idx, ok := header[normalizeColumnName(f.Column)]
+			if !ok {
+				return nil, nil, &ValidationError{Field: f.Field, Message: fmt.Sprintf("column '%s' not found in file", f.Column)}
+			}
+			mf.index = idx
+		}
+		if f.Field == "VendorID" || f.Field == "VendorName" {
+			hasVendor = true
+		}
+		targets = append(targets, f.Field)
+		fields = append(fields, mf)
+	}
+
+	// Quotes from a vendor's profile belong to that vendor unless the file says otherwise
+	if profile.Entity == "quotes" && profile.VendorID != nil && !hasVendor {
+		targets = append(targets, "VendorID")
+		fields = append(fields, mappedField{index: -1, constant: strconv.FormatUint(uint64(*profile.VendorID), 10)})
+	}
+
+	mapped := make([][]string, 0, len(records))
+	mapped = append(mapped, targets)
+	rowErrors := make(map[int]error)
+	for i, record := range records[1:] {
+		out := make([]string, len(fields))
+		if isBlankRecord(record) {
+			mapped = append(mapped, out)
+			continue
+		}
+		for j, mf := range fields {
+			value := mf.constant
+			if mf.index >= 0 && mf.index < len(record) {
+				value = record[mf.index]
+			}
+			for _, transform := range mf.transforms {
+				var err error
+				if value, err = transform(value); err != nil {
+					if _, exists := rowErrors[i+2]; !exists {
+						rowErrors[i+2] = fmt.Errorf("%s: %v", targets[j], err)
+					}
+					break
+				}
+			}
+			out[j] = value
+		}
+		mapped = append(mapped, out)
+	}
+
+	return mapped, rowErrors, nil
+}
+
+// ==================== Profile Import ====================
+
+// ImportPreview shows how the first rows of a file map onto an import profile,
+// together with the dry-run outcome of importing the whole file
+type ImportPreview struct {
+	Profile   string             `json:"profile"`
+	Entity    string             `json:"entity"`
+	Fields    []string           `json:"fields"`
+	Rows      []ImportPreviewRow `json:"rows"`
+	TotalRows int                `json:"total_rows"`
+	Result    *ImportResult      `json:"result"`
+}
+
+// ImportPreviewRow holds the mapped values of one source row and its dry-run outcome
+type ImportPreviewRow struct {
+	Row     int          `json:"row"`
+	Values  []string     `json:"values"`
+	Action  ImportAction `json:"action"`
+	Message string       `json:"message,omitempty"`
+}
+
+// ImportCSVWithProfile imports a CSV file whose columns are described by an import profile
+func (s *ExportImportService) ImportCSVWithProfile(r io.Reader, profile *models.ImportProfile, opts ImportOptions) (*ImportResult, error) {
+	records, err := readProfileCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.importWithProfile(records, profile, opts)
+}
+
+// ImportExcelWithProfile imports an Excel workbook whose columns are described by an import
+// profile. The sheet is taken from opts.Sheet, then the profile, then the active sheet.
+func (s *ExportImportService) ImportExcelWithProfile(r io.Reader, profile *models.ImportProfile, opts ImportOptions) (*ImportResult, error) {
+	records, err := readExcelRecords(r, opts.Sheet, profile.Sheet)
+	if err != nil {
+		return nil, err
+	}
+	return s.importWithProfile(records, profile, opts)
+}
+
+// PreviewCSVWithProfile maps the first limit rows of a CSV file through an import profile
+// and dry-runs the import. Nothing is written.
+func (s *ExportImportService) PreviewCSVWithProfile(r io.Reader, profile *models.ImportProfile, opts ImportOptions, limit int) (*ImportPreview, error) {
+	records, err := readProfileCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.previewWithProfile(records, profile, opts, limit)
+}
+
+// PreviewExcelWithProfile maps the first limit rows of an Excel workbook through an import
+// profile and dry-runs the import. Nothing is written.
+func (s *ExportImportService) PreviewExcelWithProfile(r io.Reader, profile *models.ImportProfile, opts ImportOptions, limit int) (*ImportPreview, error) {
+	records, err := readExcelRecords(r, opts.Sheet, profile.Sheet)
+	if err != nil {
+		return nil, err
+	}
+	return s.previewWithProfile(records, profile, opts, limit)
+}
+
+func (s *ExportImportService) importWithProfile(records [][]string, profile *models.ImportProfile, opts ImportOptions) (*ImportResult, error) {
+	mapped, rowErrors, err := mapProfileRecords(profile, records)
+	if err != nil {
+		return nil, err
+	}
+	opts.rowErrors = rowErrors
+
+	switch profile.Entity {
+	case "brands":
+		return s.importBrands(mapped, opts)
+	case "vendors":
+		return s.importVendors(mapped, opts)
+	case "products":
+		return s.importProducts(mapped, opts)
+	case "quotes":
+		return s.importQuotes(mapped, opts)
+	case "forex":
+		return s.importForex(mapped, opts)
+	default:
+		return nil, &ValidationError{Field: "entity", Message: fmt.Sprintf("unknown import entity '%s'", profile.Entity)}
+	}
+}
+
+func (s *ExportImportService) previewWithProfile(records [][]string, profile *models.ImportProfile, opts ImportOptions, limit int) (*ImportPreview, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	mapped, _, err := mapProfileRecords(profile, records)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.DryRun = true
+	result, err := s.importWithProfile(records, profile, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes := make(map[int]ImportRowResult, len(result.Rows))
+	for _, row := range result.Rows {
+		outcomes[row.Row] = row
+	}
+
+	preview := &ImportPreview{
+		Profile: profile.Name,
+		Entity:  profile.Entity,
+		Fields:  mapped[0],
+		Rows:    make([]ImportPreviewRow, 0, limit),
+		Result:  result,
+	}
+	for i, record := range mapped[1:] {
+		if isBlankRecord(record) {
+			continue
+		}
+		preview.TotalRows++
+		if len(preview.Rows) >= limit {
+			continue
+		}
+		outcome := outcomes[i+2]
+		preview.Rows = append(preview.Rows, ImportPreviewRow{
+			Row:     i + 2,
+			Values:  record,
+			Action:  outcome.Action,
+			Message: outcome.Message,
+		})
+	}
+	return preview, nil
+}
+
+// readProfileCSV reads a CSV file whose rows may have differing lengths, as is common
+// in vendor price lists
+func readProfileCSV(r io.Reader) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	return reader.ReadAll()
+}
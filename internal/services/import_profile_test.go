@@ -0,0 +1,303 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+)
+
+func TestImportProfileService_Create(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	vendorSvc := NewVendorService(cfg.DB)
+	svc := NewImportProfileService(cfg.DB)
+
+	vendor, _ := vendorSvc.Create("Acme", "EUR", "")
+	missingVendor := uint(999)
+
+	priceField := []models.ImportProfileField{{Field: "Price", Column: "Unit Cost"}}
+
+	tests := []struct {
+		name    string
+		input   CreateImportProfileInput
+		wantErr interface{}
+	}{
+		{
+			name:    "empty name",
+			input:   CreateImportProfileInput{Entity: "quotes", Fields: priceField},
+			wantErr: &ValidationError{},
+		},
+		{
+			name:    "unknown entity",
+			input:   CreateImportProfileInput{Name: "p", Entity: "invoices", Fields: priceField},
+			wantErr: &ValidationError{},
+		},
+		{
+			name:    "unknown vendor",
+			input:   CreateImportProfileInput{Name: "p", Entity: "quotes", VendorID: &missingVendor, Fields: priceField},
+			wantErr: &NotFoundError{},
+		},
+		{
+			name:    "no fields",
+			input:   CreateImportProfileInput{Name: "p", Entity: "quotes"},
+			wantErr: &ValidationError{},
+		},
+		{
+			name: "unknown field",
+			input: CreateImportProfileInput{Name: "p", Entity: "quotes", Fields: []models.ImportProfileField{
+				{Field: "Colour", Column: "Colour"},
+			}},
+			wantErr: &ValidationError{},
+		},
+		{
+			name: "field mapped twice",
+			input: CreateImportProfileInput{Name: "p", Entity: "quotes", Fields: []models.ImportProfileField{
+				{Field: "Price", Column: "Unit Cost"},
+				{Field: "price", Column: "Net"},
+			}},
+			wantErr: &ValidationError{},
+		},
+		{
+			name: "neither column nor constant",
+			input: CreateImportProfileInput{Name: "p", Entity: "quotes", Fields: []models.ImportProfileField{
+				{Field: "Price"},
+			}},
+			wantErr: &ValidationError{},
+		},
+		{
+			name: "bad transform",
+			input: CreateImportProfileInput{Name: "p", Entity: "quotes", Fields: []models.ImportProfileField{
+				{Field: "Price", Column: "Unit Cost", Transform: "multiply:abc"},
+			}},
+			wantErr: &ValidationError{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Create(tt.input)
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			switch tt.wantErr.(type) {
+			case *ValidationError:
+				var target *ValidationError
+				if !errors.As(err, &target) {
+					t.Errorf("Expected ValidationError, got %T: %v", err, err)
+				}
+			case *NotFoundError:
+				var target *NotFoundError
+				if !errors.As(err, &target) {
+					t.Errorf("Expected NotFoundError, got %T: %v", err, err)
+				}
+			}
+		})
+	}
+
+	t.Run("valid profile", func(t *testing.T) {
+		profile, err := svc.Create(CreateImportProfileInput{
+			Name:     "acme-prices",
+			Entity:   "Quotes",
+			VendorID: &vendor.ID,
+			Fields: []models.ImportProfileField{
+				{Field: "product_sku", Column: "Part #"},
+				{Field: "Price", Column: "Net EUR", Transform: "decimal-comma|multiply:1.2"},
+				{Field: "Currency", Constant: "EUR"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create profile: %v", err)
+		}
+		if profile.Entity != "quotes" {
+			t.Errorf("Expected entity 'quotes', got '%s'", profile.Entity)
+		}
+		if len(profile.Fields) != 3 || profile.Fields[0].Field != "ProductSKU" {
+			t.Errorf("Expected canonical field names, got %+v", profile.Fields)
+		}
+		if profile.Vendor == nil || profile.Vendor.Name != "Acme" {
+			t.Errorf("Expected vendor Acme to be loaded")
+		}
+
+		_, err = svc.Create(CreateImportProfileInput{Name: "acme-prices", Entity: "quotes", Fields: priceField})
+		var dupErr *DuplicateError
+		if !errors.As(err, &dupErr) {
+			t.Errorf("Expected DuplicateError, got %v", err)
+		}
+
+		profiles, err := svc.ListByVendor(vendor.ID)
+		if err != nil || len(profiles) != 1 {
+			t.Errorf("Expected 1 profile for vendor, got %d (err: %v)", len(profiles), err)
+		}
+
+		if err := svc.Delete(profile.ID); err != nil {
+			t.Fatalf("Failed to delete profile: %v", err)
+		}
+		var fieldCount int64
+		cfg.DB.Model(&models.ImportProfileField{}).Where("profile_id = ?", profile.ID).Count(&fieldCount)
+		if fieldCount != 0 {
+			t.Errorf("Expected profile fields to be deleted, got %d", fieldCount)
+		}
+		if _, err := svc.GetByName("acme-prices"); err == nil {
+			t.Error("Expected profile to be deleted")
+		}
+	})
+}
+
+func TestParseImportTransforms(t *testing.T) {
+	tests := []struct {
+		spec    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{spec: "", input: " as is ", want: " as is "},
+		{spec: "trim|upper", input: "  abc ", want: "ABC"},
+		{spec: "lower", input: "ABC", want: "abc"},
+		{spec: "multiply:1.1", input: "10", want: "11"},
+		{spec: "decimal-comma|multiply:2", input: "1.234,50", want: "2469"},
+		{spec: "multiply:2", input: "", want: ""},
+		{spec: "multiply:2", input: "n/a", wantErr: true},
+		{spec: "date:DD/MM/YYYY", input: "31/12/2024", want: "2024-12-31"},
+		{spec: "date:MM-DD-YY", input: "03-15-24", want: "2024-03-15"},
+		{spec: "date:DD/MM/YYYY", input: "2024-12-31T00:00:00Z", want: "2024-12-31T00:00:00Z"},
+		{spec: "date:DD/MM/YYYY", input: "2024-12-31", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec+"/"+tt.input, func(t *testing.T) {
+			transforms, err := parseImportTransforms(tt.spec)
+			if err != nil {
+				t.Fatalf("Failed to parse transforms: %v", err)
+			}
+			value := tt.input
+			for _, transform := range transforms {
+				if value, err = transform(value); err != nil {
+					break
+				}
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %q", value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if value != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, value)
+			}
+		})
+	}
+
+	for _, spec := range []string{"reverse", "multiply:", "date:"} {
+		if _, err := parseImportTransforms(spec); err == nil {
+			t.Errorf("Expected error for transform %q", spec)
+		}
+	}
+}
+
+func TestExportImportService_ImportCSVWithProfile(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	vendorSvc := NewVendorService(cfg.DB)
+	brandSvc := NewBrandService(cfg.DB)
+	productSvc := NewProductService(cfg.DB)
+	forexSvc := NewForexService(cfg.DB)
+	profileSvc := NewImportProfileService(cfg.DB)
+	exportSvc := NewExportImportService(cfg.DB)
+
+	vendor, _ := vendorSvc.Create("Acme", "EUR", "")
+	brand, _ := brandSvc.Create("Apple")
+	product, _ := productSvc.Create("iPhone 15", brand.ID, nil)
+	sku := "IP15"
+	product.SKU = &sku
+	cfg.DB.Save(product)
+	_, _ = forexSvc.Create("EUR", "USD", 1.10, time.Now())
+
+	profile, err := profileSvc.Create(CreateImportProfileInput{
+		Name:     "acme-prices",
+		Entity:   "quotes",
+		VendorID: &vendor.ID,
+		Fields: []models.ImportProfileField{
+			{Field: "ProductSKU", Column: "Part #"},
+			{Field: "Price", Column: "Net EUR", Transform: "decimal-comma|multiply:1.2"},
+			{Field: "ValidUntil", Column: "Valid", Transform: "date:DD/MM/YYYY"},
+			{Field: "Currency", Constant: "EUR"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create profile: %v", err)
+	}
+
+	csvData := "Part #,Description,Net EUR,Valid\n" +
+		"IP15,iPhone,\"1.000,00\",31/12/2024\n" +
+		"IP15,iPhone,\"900,00\",not a date\n" +
+		",,,\n" +
+		"UNKNOWN,Nothing,\"5,00\",01/01/2025\n"
+
+	t.Run("Preview", func(t *testing.T) {
+		preview, err := exportSvc.PreviewCSVWithProfile(strings.NewReader(csvData), profile, ImportOptions{}, 2)
+		if err != nil {
+			t.Fatalf("Preview failed: %v", err)
+		}
+		if preview.TotalRows != 3 || len(preview.Rows) != 2 {
+			t.Fatalf("Expected 2 of 3 rows, got %d of %d", len(preview.Rows), preview.TotalRows)
+		}
+		if got := strings.Join(preview.Fields, ","); got != "ProductSKU,Price,ValidUntil,Currency,VendorID" {
+			t.Errorf("Unexpected preview fields: %s", got)
+		}
+		if preview.Rows[0].Values[1] != "1200" || preview.Rows[0].Action != ImportActionCreate {
+			t.Errorf("Unexpected first row: %+v", preview.Rows[0])
+		}
+		if preview.Rows[1].Action != ImportActionError {
+			t.Errorf("Expected second row to fail, got %+v", preview.Rows[1])
+		}
+
+		var count int64
+		cfg.DB.Model(&models.Quote{}).Count(&count)
+		if count != 0 {
+			t.Errorf("Preview must not write quotes, found %d", count)
+		}
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		result, err := exportSvc.ImportCSVWithProfile(strings.NewReader(csvData), profile, ImportOptions{ContinueOnError: true})
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if result.CreatedCount != 1 || result.ErrorCount != 2 {
+			t.Fatalf("Expected 1 created and 2 errors, got %d/%d: %v", result.CreatedCount, result.ErrorCount, result.Errors)
+		}
+		if !strings.Contains(result.Errors[0], "Row 3: ValidUntil") {
+			t.Errorf("Expected transform error on row 3, got %s", result.Errors[0])
+		}
+
+		var quote models.Quote
+		if err := cfg.DB.First(&quote).Error; err != nil {
+			t.Fatalf("Expected a quote: %v", err)
+		}
+		if quote.VendorID != vendor.ID || quote.ProductID != product.ID {
+			t.Errorf("Expected quote for Acme/iPhone 15, got vendor %d product %d", quote.VendorID, quote.ProductID)
+		}
+		if quote.Price != 1200 || quote.Currency != "EUR" {
+			t.Errorf("Expected 1200 EUR, got %.2f %s", quote.Price, quote.Currency)
+		}
+		if quote.ValidUntil == nil || quote.ValidUntil.Format("2006-01-02") != "2024-12-31" {
+			t.Errorf("Expected valid until 2024-12-31, got %v", quote.ValidUntil)
+		}
+	})
+
+	t.Run("Missing source column", func(t *testing.T) {
+		_, err := exportSvc.ImportCSVWithProfile(strings.NewReader("SKU,Price\nIP15,10\n"), profile, ImportOptions{})
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected ValidationError for missing column, got %v", err)
+		}
+	})
+}
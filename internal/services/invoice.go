@@ -0,0 +1,511 @@
+package services
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// Invoice document types and statuses
+const (
+	InvoiceTypeInvoice    = "invoice"
+	InvoiceTypeCreditNote = "credit_note"
+
+	InvoiceStatusMatched   = "matched"
+	InvoiceStatusUnmatched = "unmatched"
+)
+
+// InvoiceImportResult is an imported invoice and what did not match
+type InvoiceImportResult struct {
+	Invoice *models.Invoice
+	// Problems lists the unmatched supplier, unmatched lines and differences
+	// from the orders, in document order; empty when the invoice matched
+	Problems []string
+}
+
+// InvoiceService imports vendor invoices and credit notes and matches them
+// to vendors and purchase orders
+type InvoiceService struct {
+	db *gorm.DB
+}
+
+// NewInvoiceService creates a new invoice service
+func NewInvoiceService(db *gorm.DB) *InvoiceService {
+	return &InvoiceService{db: db}
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *InvoiceService) WithContext(ctx context.Context) *InvoiceService {
+	return NewInvoiceService(s.db.WithContext(ctx))
+}
+
+// List retrieves invoices, newest first, optionally only those with a status
+func (s *InvoiceService) List(status string) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	query := s.db.Preload("Vendor", func(db *gorm.DB) *gorm.DB { return db.Unscoped() })
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("issue_date DESC, id DESC").Find(&invoices).Error
+	return invoices, err
+}
+
+// GetByID retrieves an invoice with its lines and their purchase orders
+func (s *InvoiceService) GetByID(id uint) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := s.db.Preload("Vendor", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Lines.PurchaseOrder").
+		First(&invoice, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &NotFoundError{Entity: "Invoice", ID: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// UBL 2.1 documents, by local element name so that any namespace prefixes
+// are accepted
+type ublParty struct {
+	EndpointID string   `xml:"EndpointID"`
+	Names      []string `xml:"PartyName>Name"`
+	TaxIDs     []string `xml:"PartyTaxScheme>CompanyID"`
+	LegalName  string   `xml:"PartyLegalEntity>RegistrationName"`
+	LegalID    string   `xml:"PartyLegalEntity>CompanyID"`
+}
+
+type ublAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"currencyID,attr"`
+}
+
+type ublLine struct {
+	ID                  string `xml:"ID"`
+	InvoicedQuantity    string `xml:"InvoicedQuantity"`
+	CreditedQuantity    string `xml:"CreditedQuantity"`
+	LineExtensionAmount string `xml:"LineExtensionAmount"`
+	OrderReference      string `xml:"OrderLineReference>OrderReference>ID"`
+	Name                string `xml:"Item>Name"`
+	Description         string `xml:"Item>Description"`
+	SellersItemID       string `xml:"Item>SellersItemIdentification>ID"`
+	PriceAmount         string `xml:"Price>PriceAmount"`
+	BaseQuantity        string `xml:"Price>BaseQuantity"`
+}
+
+type ublDocument struct {
+	XMLName        xml.Name
+	ID             string      `xml:"ID"`
+	IssueDate      string      `xml:"IssueDate"`
+	DueDate        string      `xml:"DueDate"`
+	PaymentDueDate string      `xml:"PaymentMeans>PaymentDueDate"`
+	Currency       string      `xml:"DocumentCurrencyCode"`
+	OrderReference string      `xml:"OrderReference>ID"`
+	Supplier       ublParty    `xml:"AccountingSupplierParty>Party"`
+	TaxAmounts     []ublAmount `xml:"TaxTotal>TaxAmount"`
+	TaxExclusive   string      `xml:"LegalMonetaryTotal>TaxExclusiveAmount"`
+	TaxInclusive   string      `xml:"LegalMonetaryTotal>TaxInclusiveAmount"`
+	Payable        string      `xml:"LegalMonetaryTotal>PayableAmount"`
+	InvoiceLines   []ublLine   `xml:"InvoiceLine"`
+	CreditLines    []ublLine   `xml:"CreditNoteLine"`
+}
+
+// ublNumber parses an optional UBL amount or quantity, 0 when empty
+func ublNumber(field, value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, &ValidationError{Field: field, Message: fmt.Sprintf("invalid number %q", value)}
+	}
+	return n, nil
+}
+
+// ublDate parses an optional UBL date, nil when empty
+func ublDate(field, value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, &ValidationError{Field: field, Message: fmt.Sprintf("invalid date %q (expected YYYY-MM-DD)", value)}
+	}
+	return &date, nil
+}
+
+// parseUBLInvoice reads a UBL 2.1 Invoice or CreditNote into an unsaved
+// invoice with its lines, and returns the supplier party to match
+func parseUBLInvoice(r io.Reader) (*models.Invoice, *ublParty, error) {
+	var doc ublDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, nil, &ValidationError{Field: "file", Message: fmt.Sprintf("not a UBL document: %v", err)}
+	}
+
+	invoice := &models.Invoice{Source: "ubl"}
+	lines := doc.InvoiceLines
+	quantityField := "InvoicedQuantity"
+	switch doc.XMLName.Local {
+	case "Invoice":
+		invoice.DocumentType = InvoiceTypeInvoice
+	case "CreditNote":
+		invoice.DocumentType = InvoiceTypeCreditNote
+		lines = doc.CreditLines
+		quantityField = "CreditedQuantity"
+	default:
+		return nil, nil, &ValidationError{Field: "file", Message: fmt.Sprintf("expected a UBL Invoice or CreditNote, got %s", doc.XMLName.Local)}
+	}
+
+	invoice.InvoiceNumber = strings.TrimSpace(doc.ID)
+	if invoice.InvoiceNumber == "" {
+		return nil, nil, &ValidationError{Field: "ID", Message: "the document has no number"}
+	}
+	issued, err := ublDate("IssueDate", doc.IssueDate)
+	if err != nil {
+		return nil, nil, err
+	}
+	if issued == nil {
+		return nil, nil, &ValidationError{Field: "IssueDate", Message: "the document has no issue date"}
+	}
+	invoice.IssueDate = *issued
+	dueDate := doc.DueDate
+	if dueDate == "" {
+		dueDate = doc.PaymentDueDate
+	}
+	if invoice.DueDate, err = ublDate("DueDate", dueDate); err != nil {
+		return nil, nil, err
+	}
+	invoice.Currency = strings.ToUpper(strings.TrimSpace(doc.Currency))
+	if invoice.Currency == "" {
+		return nil, nil, &ValidationError{Field: "DocumentCurrencyCode", Message: "the document has no currency"}
+	}
+	invoice.OrderReference = strings.TrimSpace(doc.OrderReference)
+
+	supplier := doc.Supplier
+	invoice.SupplierName = strings.TrimSpace(supplier.LegalName)
+	for _, name := range supplier.Names {
+		if invoice.SupplierName == "" {
+			invoice.SupplierName = strings.TrimSpace(name)
+		}
+	}
+	for _, id := range append(supplier.TaxIDs, supplier.LegalID) {
+		if invoice.SupplierTaxID == "" {
+			invoice.SupplierTaxID = strings.TrimSpace(id)
+		}
+	}
+
+	if len(lines) == 0 {
+		return nil, nil, &ValidationError{Field: "lines", Message: "the document has no lines"}
+	}
+	for i, line := range lines {
+		parsed := models.InvoiceLine{
+			LineNumber:     strings.TrimSpace(line.ID),
+			OrderReference: strings.TrimSpace(line.OrderReference),
+			SKU:            strings.TrimSpace(line.SellersItemID),
+			Description:    strings.TrimSpace(line.Name),
+		}
+		if parsed.LineNumber == "" {
+			parsed.LineNumber = strconv.Itoa(i + 1)
+		}
+		if parsed.Description == "" {
+			parsed.Description = strings.TrimSpace(line.Description)
+		}
+		quantity := line.InvoicedQuantity
+		if invoice.DocumentType == InvoiceTypeCreditNote {
+			quantity = line.CreditedQuantity
+		}
+		if parsed.Quantity, err = ublNumber(quantityField, quantity); err != nil {
+			return nil, nil, err
+		}
+		if parsed.LineAmount, err = ublNumber("LineExtensionAmount", line.LineExtensionAmount); err != nil {
+			return nil, nil, err
+		}
+		if parsed.UnitPrice, err = ublNumber("PriceAmount", line.PriceAmount); err != nil {
+			return nil, nil, err
+		}
+		base, err := ublNumber("BaseQuantity", line.BaseQuantity)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case base > 0:
+			parsed.UnitPrice /= base
+		case line.PriceAmount == "" && parsed.Quantity != 0:
+			parsed.UnitPrice = roundCents(parsed.LineAmount / parsed.Quantity)
+		}
+		invoice.Lines = append(invoice.Lines, parsed)
+		invoice.NetAmount += parsed.LineAmount
+	}
+
+	// The document totals win over the sum of the lines, which leaves out
+	// document-level allowances and charges
+	if doc.TaxExclusive != "" {
+		if invoice.NetAmount, err = ublNumber("TaxExclusiveAmount", doc.TaxExclusive); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, amount := range doc.TaxAmounts {
+		if amount.Currency == "" || strings.EqualFold(amount.Currency, invoice.Currency) {
+			if invoice.TaxAmount, err = ublNumber("TaxAmount", amount.Value); err != nil {
+				return nil, nil, err
+			}
+			break
+		}
+	}
+	invoice.NetAmount = roundCents(invoice.NetAmount)
+	invoice.TotalAmount = roundCents(invoice.NetAmount + invoice.TaxAmount)
+	payable := doc.Payable
+	if payable == "" {
+		payable = doc.TaxInclusive
+	}
+	if payable != "" {
+		if invoice.TotalAmount, err = ublNumber("PayableAmount", payable); err != nil {
+			return nil, nil, err
+		}
+	}
+	return invoice, &doc.Supplier, nil
+}
+
+// normalizeTaxID returns a tax ID without separators, uppercased
+func normalizeTaxID(id string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(id) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// sameTaxID reports whether two tax IDs are the same, with or without a
+// two-letter country prefix such as the one of EU VAT numbers
+func sameTaxID(a, b string) bool {
+	a, b = normalizeTaxID(a), normalizeTaxID(b)
+	if a == "" || b == "" {
+		return false
+	}
+	strip := func(id string) string {
+		if len(id) > 2 && id[0] >= 'A' && id[0] <= 'Z' && id[1] >= 'A' && id[1] <= 'Z' {
+			return id[2:]
+		}
+		return id
+	}
+	return a == b || strip(a) == strip(b)
+}
+
+// matchVendor finds the vendor of a UBL supplier by tax ID, then by name
+func (s *InvoiceService) matchVendor(supplier *ublParty) (*models.Vendor, error) {
+	ids := append(append([]string{}, supplier.TaxIDs...), supplier.LegalID, supplier.EndpointID)
+	var vendors []models.Vendor
+	if err := s.db.Where("tax_id IS NOT NULL AND tax_id <> ''").Find(&vendors).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		for i := range vendors {
+			if sameTaxID(id, vendors[i].TaxID) {
+				return &vendors[i], nil
+			}
+		}
+	}
+
+	names := append([]string{supplier.LegalName}, supplier.Names...)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		var vendor models.Vendor
+		err := s.db.Where("LOWER(name) = ?", strings.ToLower(name)).First(&vendor).Error
+		if err == nil {
+			return &vendor, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// invoicedQuantity returns the quantity of a purchase order on earlier
+// invoices, less credit notes
+func (s *InvoiceService) invoicedQuantity(poID uint) (float64, error) {
+	var totals []struct {
+		DocumentType string
+		Quantity     float64
+	}
+	err := s.db.Table("invoice_lines").
+		Select("invoices.document_type, SUM(invoice_lines.quantity) AS quantity").
+		Joins("JOIN invoices ON invoices.id = invoice_lines.invoice_id").
+		Where("invoice_lines.purchase_order_id = ?", poID).
+		Group("invoices.document_type").Scan(&totals).Error
+	var quantity float64
+	for _, total := range totals {
+		if total.DocumentType == InvoiceTypeCreditNote {
+			quantity -= total.Quantity
+		} else {
+			quantity += total.Quantity
+		}
+	}
+	return quantity, err
+}
+
+// matchLine links an invoice line to its purchase order: the one of the
+// line's order reference, else of the document's, else without a reference
+// the vendor's only approved order for the line's SKU. It returns the order and the problems with
+// the line; invoiced counts the quantity of each order on this document.
+func (s *InvoiceService) matchLine(invoice *models.Invoice, line *models.InvoiceLine, invoiced map[uint]float64) (*models.PurchaseOrder, []string, error) {
+	reference := line.OrderReference
+	if reference == "" {
+		reference = invoice.OrderReference
+	}
+
+	var po *models.PurchaseOrder
+	if reference != "" {
+		var found models.PurchaseOrder
+		err := s.db.Preload("Product").Where("po_number = ?", reference).First(&found).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, []string{fmt.Sprintf("no purchase order %s", reference)}, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		po = &found
+	} else {
+		if invoice.VendorID == nil || line.SKU == "" {
+			return nil, []string{"no order reference or SKU to match"}, nil
+		}
+		var candidates []models.PurchaseOrder
+		err := s.db.Preload("Product").
+			Joins("JOIN products ON products.id = purchase_orders.product_id").
+			Where("purchase_orders.vendor_id = ? AND products.sku = ?", *invoice.VendorID, line.SKU).
+			Where("purchase_orders.status NOT IN ?", []string{"pending", "rejected", "cancelled"}).
+			Find(&candidates).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		switch len(candidates) {
+		case 0:
+			return nil, []string{fmt.Sprintf("no approved order for SKU %s", line.SKU)}, nil
+		case 1:
+			po = &candidates[0]
+		default:
+			return nil, []string{fmt.Sprintf("%d orders for SKU %s; the line needs an order reference", len(candidates), line.SKU)}, nil
+		}
+	}
+	if invoice.VendorID != nil && po.VendorID != *invoice.VendorID {
+		return nil, []string{fmt.Sprintf("purchase order %s is from another vendor", po.PONumber)}, nil
+	}
+
+	var problems []string
+	switch po.Status {
+	case "pending", "rejected", "cancelled":
+		problems = append(problems, fmt.Sprintf("purchase order %s is %s", po.PONumber, po.Status))
+	}
+	if line.SKU != "" && po.Product != nil && po.Product.SKU != nil && *po.Product.SKU != "" && !strings.EqualFold(line.SKU, *po.Product.SKU) {
+		problems = append(problems, fmt.Sprintf("SKU %s is not the order's %s", line.SKU, *po.Product.SKU))
+	}
+	if !strings.EqualFold(invoice.Currency, po.Currency) {
+		problems = append(problems, fmt.Sprintf("currency %s is not the order's %s", invoice.Currency, po.Currency))
+	} else if math.Abs(line.UnitPrice-po.UnitPrice) >= 0.005 {
+		problems = append(problems, fmt.Sprintf("unit price %s is not the order's %s", formatAmount(line.UnitPrice), formatAmount(po.UnitPrice)))
+	}
+	if invoice.DocumentType == InvoiceTypeInvoice {
+		earlier, err := s.invoicedQuantity(po.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		invoiced[po.ID] += line.Quantity
+		if total := earlier + invoiced[po.ID]; total > float64(po.Quantity)+1e-9 {
+			problems = append(problems, fmt.Sprintf("%g invoiced of %d ordered on %s", total, po.Quantity, po.PONumber))
+		}
+	}
+	return po, problems, nil
+}
+
+// ImportUBL imports a UBL 2.1 Invoice or CreditNote. The supplier is
+// matched to a vendor by tax ID, then by name, and each line to a purchase
+// order by its buyer order reference and SKU. The invoice is saved as
+// matched when the vendor and every line matched without differences from
+// the orders, and as unmatched otherwise, with the reasons in the result
+// and on the lines; purchase orders are not changed. With dryRun nothing is
+// saved. The same document from the same supplier is a DuplicateError.
+func (s *InvoiceService) ImportUBL(r io.Reader, dryRun bool) (*InvoiceImportResult, error) {
+	invoice, supplier, err := parseUBLInvoice(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &InvoiceImportResult{Invoice: invoice}
+	vendor, err := s.matchVendor(supplier)
+	if err != nil {
+		return nil, err
+	}
+	if vendor != nil {
+		invoice.VendorID = &vendor.ID
+		invoice.Vendor = vendor
+	} else {
+		name := invoice.SupplierName
+		if invoice.SupplierTaxID != "" {
+			name += " (tax ID " + invoice.SupplierTaxID + ")"
+		}
+		result.Problems = append(result.Problems, fmt.Sprintf("supplier %s matches no vendor", strings.TrimSpace(name)))
+	}
+
+	duplicate := s.db.Model(&models.Invoice{}).Where("invoice_number = ? AND document_type = ?", invoice.InvoiceNumber, invoice.DocumentType)
+	if vendor != nil {
+		duplicate = duplicate.Where("vendor_id = ?", vendor.ID)
+	} else {
+		duplicate = duplicate.Where("vendor_id IS NULL AND supplier_name = ?", invoice.SupplierName)
+	}
+	var count int64
+	if err := duplicate.Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, &DuplicateError{Entity: strings.ReplaceAll(invoice.DocumentType, "_", " "), Name: invoice.InvoiceNumber}
+	}
+
+	invoiced := make(map[uint]float64)
+	for i := range invoice.Lines {
+		line := &invoice.Lines[i]
+		po, problems, err := s.matchLine(invoice, line, invoiced)
+		if err != nil {
+			return nil, err
+		}
+		if po != nil {
+			line.PurchaseOrderID = &po.ID
+			line.PurchaseOrder = po
+		}
+		line.Matched = po != nil && len(problems) == 0
+		line.MatchNote = strings.Join(problems, "; ")
+		for _, problem := range problems {
+			result.Problems = append(result.Problems, fmt.Sprintf("line %s: %s", line.LineNumber, problem))
+		}
+	}
+
+	invoice.Status = InvoiceStatusMatched
+	if len(result.Problems) > 0 {
+		invoice.Status = InvoiceStatusUnmatched
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	invoice.CreatedBy = models.ActorFrom(s.db.Statement.Context)
+	if err := s.db.Omit("Vendor", "Lines.PurchaseOrder").Create(invoice).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// ublInvoice returns a UBL 2.1 document of kind Invoice or CreditNote from
+// the supplier party and line elements
+func ublInvoice(kind, number, supplier, lines string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<%[1]s xmlns="urn:oasis:names:specification:ubl:schema:xsd:%[1]s-2"
+    xmlns:cac="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+    xmlns:cbc="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">
+  <cbc:UBLVersionID>2.1</cbc:UBLVersionID>
+  <cbc:ID>%[2]s</cbc:ID>
+  <cbc:IssueDate>2026-10-15</cbc:IssueDate>
+  <cbc:DueDate>2026-11-14</cbc:DueDate>
+  <cbc:DocumentCurrencyCode>USD</cbc:DocumentCurrencyCode>
+  <cac:AccountingSupplierParty><cac:Party>%[3]s</cac:Party></cac:AccountingSupplierParty>
+  <cac:TaxTotal><cbc:TaxAmount currencyID="USD">20.00</cbc:TaxAmount></cac:TaxTotal>
+  <cac:LegalMonetaryTotal>
+    <cbc:TaxExclusiveAmount currencyID="USD">200.00</cbc:TaxExclusiveAmount>
+    <cbc:PayableAmount currencyID="USD">220.00</cbc:PayableAmount>
+  </cac:LegalMonetaryTotal>
+  %[4]s
+</%[1]s>`, kind, number, supplier, lines)
+}
+
+// ublLineXML returns an InvoiceLine or CreditNoteLine element
+func ublLineXML(kind, id, quantity, amount, price, orderRef, sku string) string {
+	quantityElement := "InvoicedQuantity"
+	if kind == "CreditNote" {
+		quantityElement = "CreditedQuantity"
+	}
+	ref := ""
+	if orderRef != "" {
+		ref = "<cac:OrderLineReference><cbc:LineID>1</cbc:LineID><cac:OrderReference><cbc:ID>" + orderRef + "</cbc:ID></cac:OrderReference></cac:OrderLineReference>"
+	}
+	return fmt.Sprintf(`<cac:%[1]sLine><cbc:ID>%[2]s</cbc:ID><cbc:%[3]s unitCode="EA">%[4]s</cbc:%[3]s>
+    <cbc:LineExtensionAmount currencyID="USD">%[5]s</cbc:LineExtensionAmount>%[6]s
+    <cac:Item><cbc:Name>MacBook</cbc:Name><cac:SellersItemIdentification><cbc:ID>%[7]s</cbc:ID></cac:SellersItemIdentification></cac:Item>
+    <cac:Price><cbc:PriceAmount currencyID="USD">%[8]s</cbc:PriceAmount></cac:Price></cac:%[1]sLine>`,
+		kind, id, quantityElement, quantity, amount, ref, sku, price)
+}
+
+func TestInvoiceService_ImportUBL(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	vendor, _ := NewVendorService(cfg.DB).Create("Acme GmbH", "USD", "")
+	cfg.DB.Model(vendor).Update("tax_id", "DE 123 456 789")
+	brand, _ := NewBrandService(cfg.DB).Create("Apple")
+	product, _ := NewProductService(cfg.DB).Create("MacBook", brand.ID, nil)
+	cfg.DB.Model(product).Update("sku", "MBP-14")
+	quote, err := NewQuoteService(cfg.DB).Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 100, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	poSvc := NewPurchaseOrderService(cfg.DB)
+	po, err := poSvc.Create(CreatePurchaseOrderInput{QuoteID: quote.ID, PONumber: "PO-1", Quantity: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := poSvc.UpdateStatus(po.ID, "approved"); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewInvoiceService(cfg.DB)
+	taxParty := `<cac:PartyName><cbc:Name>Acme</cbc:Name></cac:PartyName><cac:PartyTaxScheme><cbc:CompanyID>123456789</cbc:CompanyID></cac:PartyTaxScheme>`
+
+	// The supplier matches by tax ID without its country prefix, and the
+	// line by its order reference
+	document := ublInvoice("Invoice", "INV-100", taxParty, ublLineXML("Invoice", "1", "2", "200.00", "100.00", "PO-1", "MBP-14"))
+	result, err := svc.ImportUBL(strings.NewReader(document), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoice := result.Invoice
+	if len(result.Problems) != 0 || invoice.Status != InvoiceStatusMatched || invoice.VendorID == nil || *invoice.VendorID != vendor.ID {
+		t.Errorf("Expected a matched invoice, got %+v, problems %v", invoice, result.Problems)
+	}
+	if invoice.ID == 0 || invoice.NetAmount != 200 || invoice.TaxAmount != 20 || invoice.TotalAmount != 220 || invoice.DueDate == nil {
+		t.Errorf("Expected the saved document totals, got %+v", invoice)
+	}
+	saved, err := svc.GetByID(invoice.ID)
+	if err != nil || len(saved.Lines) != 1 || !saved.Lines[0].Matched || saved.Lines[0].PurchaseOrder == nil || saved.Lines[0].PurchaseOrder.PONumber != "PO-1" {
+		t.Errorf("Expected the line linked to PO-1, got %+v, %v", saved, err)
+	}
+
+	var duplicateErr *DuplicateError
+	if _, err := svc.ImportUBL(strings.NewReader(document), false); !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected a DuplicateError importing the invoice again, got %v", err)
+	}
+
+	// A line without a reference matches the vendor's only order for its
+	// SKU, but over-invoices it; an unknown reference does not match
+	document = ublInvoice("Invoice", "INV-101", taxParty,
+		ublLineXML("Invoice", "1", "2", "220.00", "110.00", "", "MBP-14")+
+			ublLineXML("Invoice", "2", "1", "100.00", "100.00", "PO-9", "MBP-14"))
+	if result, err = svc.ImportUBL(strings.NewReader(document), false); err != nil {
+		t.Fatal(err)
+	}
+	if result.Invoice.Status != InvoiceStatusUnmatched || len(result.Problems) != 3 {
+		t.Fatalf("Expected an unmatched invoice with three problems, got %v", result.Problems)
+	}
+	for i, want := range []string{"line 1: unit price 110.00 is not the order's 100.00", "line 1: 4 invoiced of 3 ordered on PO-1", "line 2: no purchase order PO-9"} {
+		if result.Problems[i] != want {
+			t.Errorf("Expected problem %q, got %q", want, result.Problems[i])
+		}
+	}
+	if line := result.Invoice.Lines[0]; line.PurchaseOrderID == nil || line.Matched || line.MatchNote == "" {
+		t.Errorf("Expected line 1 linked to PO-1 with differences, got %+v", line)
+	}
+
+	// A credit note matches its supplier by name
+	document = ublInvoice("CreditNote", "CN-1", `<cac:PartyLegalEntity><cbc:RegistrationName>ACME GMBH</cbc:RegistrationName></cac:PartyLegalEntity>`,
+		ublLineXML("CreditNote", "1", "1", "100.00", "100.00", "PO-1", "MBP-14"))
+	if result, err = svc.ImportUBL(strings.NewReader(document), false); err != nil {
+		t.Fatal(err)
+	}
+	if result.Invoice.DocumentType != InvoiceTypeCreditNote || result.Invoice.Status != InvoiceStatusMatched {
+		t.Errorf("Expected a matched credit note, got %+v, problems %v", result.Invoice, result.Problems)
+	}
+
+	// An unknown supplier is reported; a dry run saves nothing
+	document = ublInvoice("Invoice", "X-1", `<cac:PartyName><cbc:Name>Globex</cbc:Name></cac:PartyName>`,
+		ublLineXML("Invoice", "1", "1", "100.00", "100.00", "PO-1", "MBP-14"))
+	if result, err = svc.ImportUBL(strings.NewReader(document), true); err != nil {
+		t.Fatal(err)
+	}
+	if result.Invoice.ID != 0 || len(result.Problems) == 0 || result.Problems[0] != "supplier Globex matches no vendor" {
+		t.Errorf("Expected an unsaved invoice with an unknown supplier, got %+v, problems %v", result.Invoice, result.Problems)
+	}
+
+	if invoices, err := svc.List(InvoiceStatusUnmatched); err != nil || len(invoices) != 1 || invoices[0].InvoiceNumber != "INV-101" {
+		t.Errorf("Expected one unmatched invoice, got %+v, %v", invoices, err)
+	}
+	if invoices, _ := svc.List(""); len(invoices) != 3 {
+		t.Errorf("Expected three saved documents, got %d", len(invoices))
+	}
+
+	var validationErr *ValidationError
+	if _, err := svc.ImportUBL(strings.NewReader("<Order><ID>1</ID></Order>"), false); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for a document that is not an invoice, got %v", err)
+	}
+	if _, err := svc.ImportUBL(strings.NewReader(ublInvoice("Invoice", "INV-102", taxParty, "")), false); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for an invoice without lines, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// NotificationService handles business logic for user notifications
+type NotificationService struct {
+	db *gorm.DB
+}
+
+// NewNotificationService creates a new notification service
+func NewNotificationService(db *gorm.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *NotificationService) WithContext(ctx context.Context) *NotificationService {
+	return NewNotificationService(s.db.WithContext(ctx))
+}
+
+// List returns a user's notifications, newest first, optionally only the
+// unread ones. A limit of 0 returns them all.
+func (s *NotificationService) List(recipient string, unreadOnly bool, limit int) ([]models.Notification, error) {
+	query := s.db.Where("recipient = ?", recipient).Order("created_at DESC, id DESC")
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var notifications []models.Notification
+	err := query.Find(&notifications).Error
+	return notifications, err
+}
+
+// UnreadCount returns the number of a user's unread notifications
+func (s *NotificationService) UnreadCount(recipient string) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.Notification{}).
+		Where("recipient = ? AND read_at IS NULL", recipient).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkRead marks one of a user's notifications as read
+func (s *NotificationService) MarkRead(id uint, recipient string) error {
+	var notification models.Notification
+	err := s.db.Where("id = ? AND recipient = ?", id, recipient).First(&notification).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &NotFoundError{Entity: "Notification", ID: id}
+	}
+	if err != nil {
+		return err
+	}
+	if notification.ReadAt != nil {
+		return nil
+	}
+	return s.db.Model(&notification).Update("read_at", time.Now()).Error
+}
+
+// MarkAllRead marks all of a user's notifications as read and returns how
+// many were unread
+func (s *NotificationService) MarkAllRead(recipient string) (int64, error) {
+	result := s.db.Model(&models.Notification{}).
+		Where("recipient = ? AND read_at IS NULL", recipient).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// notify sends a message to each recipient once, leaving out blank names. It
+// runs on db so that callers can notify inside their transaction.
+func notify(db *gorm.DB, recipients []string, message, link string) error {
+	seen := map[string]bool{}
+	var notifications []models.Notification
+	for _, recipient := range recipients {
+		recipient = strings.TrimSpace(recipient)
+		if recipient == "" || seen[recipient] {
+			continue
+		}
+		seen[recipient] = true
+		notifications = append(notifications, models.Notification{Recipient: recipient, Message: message, Link: link})
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+	return db.Create(&notifications).Error
+}
+
+// usersWithPermission returns the usernames of the enabled users whose role
+// grants a permission
+func usersWithPermission(db *gorm.DB, permission string) ([]string, error) {
+	var users []models.User
+	if err := db.Where("disabled = ?", false).Order("username").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	var usernames []string
+	for _, user := range users {
+		if RoleHasPermission(user.Role, permission) {
+			usernames = append(usernames, user.Username)
+		}
+	}
+	return usernames, nil
+}
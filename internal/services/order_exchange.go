@@ -0,0 +1,683 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// PostalAddress is an address in electronic documents
+type PostalAddress struct {
+	Name       string
+	Street1    string
+	Street2    string
+	City       string
+	State      string
+	PostalCode string
+	Country    string // ISO 3166-1 alpha-2
+}
+
+// OrderExchangeOptions identifies the trading partners of electronic
+// purchase orders and where the goods go
+type OrderExchangeOptions struct {
+	BuyerID      string // Our cXML identity and X12 interchange sender ID
+	VendorID     string // The vendor's identity; its tax ID, then its name, when empty
+	Qualifier    string // X12 interchange ID qualifier of both IDs, "ZZ" (mutually defined) when empty
+	Domain       string // cXML credential domain, "NetworkID" when empty
+	SharedSecret string // cXML sender shared secret
+	ShipTo       PostalAddress
+	Test         bool // Mark the documents as test data
+	// ControlNumber is the X12 interchange and group control number, the
+	// purchase order ID when 0
+	ControlNumber int
+}
+
+// OrderExchangeService writes purchase orders as cXML OrderRequest and X12
+// 850 documents and applies the vendor's confirmations and ship notices
+type OrderExchangeService struct {
+	db *gorm.DB
+}
+
+// NewOrderExchangeService creates a new order exchange service
+func NewOrderExchangeService(db *gorm.DB) *OrderExchangeService {
+	return &OrderExchangeService{db: db}
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *OrderExchangeService) WithContext(ctx context.Context) *OrderExchangeService {
+	return NewOrderExchangeService(s.db.WithContext(ctx))
+}
+
+// orderForExchange loads an approved purchase order to send to its vendor
+func (s *OrderExchangeService) orderForExchange(id uint, opts *OrderExchangeOptions) (*models.PurchaseOrder, error) {
+	var po models.PurchaseOrder
+	if err := s.db.Preload("Vendor", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Product", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		First(&po, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &NotFoundError{Entity: "purchase order", ID: id}
+		}
+		return nil, err
+	}
+	switch po.Status {
+	case "pending", "rejected", "cancelled":
+		return nil, &ValidationError{Field: "status", Message: fmt.Sprintf("purchase order %s is %s; only approved orders can be sent", po.PONumber, po.Status)}
+	}
+	if strings.TrimSpace(opts.BuyerID) == "" {
+		return nil, &ValidationError{Field: "buyer_id", Message: "the buyer's EDI identity is required"}
+	}
+	if opts.VendorID == "" {
+		opts.VendorID = vendorEDIID(po.Vendor)
+	}
+	if opts.Qualifier == "" {
+		opts.Qualifier = "ZZ"
+	}
+	if opts.Domain == "" {
+		opts.Domain = "NetworkID"
+	}
+	if opts.ControlNumber <= 0 {
+		opts.ControlNumber = int(po.ID)
+	}
+	return &po, nil
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// vendorEDIID returns the vendor's tax ID, or failing that its name in
+// upper case without punctuation, to identify it in electronic documents
+func vendorEDIID(vendor *models.Vendor) string {
+	if vendor == nil {
+		return ""
+	}
+	if vendor.TaxID != "" {
+		return vendor.TaxID
+	}
+	id := nonAlphanumeric.ReplaceAllString(strings.ToUpper(vendor.Name), "")
+	if len(id) > 15 {
+		id = id[:15]
+	}
+	return id
+}
+
+// supplierPartID is the product's SKU, or its ID without one
+func supplierPartID(po *models.PurchaseOrder) string {
+	if po.Product != nil && po.Product.SKU != nil && *po.Product.SKU != "" {
+		return *po.Product.SKU
+	}
+	return strconv.FormatUint(uint64(po.ProductID), 10)
+}
+
+func productName(po *models.PurchaseOrder) string {
+	if po.Product != nil {
+		return po.Product.Name
+	}
+	return fmt.Sprintf("Product %d", po.ProductID)
+}
+
+// ==================== cXML ====================
+
+const cxmlDocType = `<!DOCTYPE cXML SYSTEM "http://xml.cxml.org/schemas/cXML/1.2.014/cXML.dtd">`
+
+type cxmlText struct {
+	Lang  string `xml:"xml:lang,attr"`
+	Value string `xml:",chardata"`
+}
+
+type cxmlMoney struct {
+	Currency string `xml:"currency,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type cxmlCredential struct {
+	Domain       string `xml:"domain,attr"`
+	Identity     string `xml:"Identity"`
+	SharedSecret string `xml:"SharedSecret,omitempty"`
+}
+
+type cxmlAddress struct {
+	Name   cxmlText `xml:"Name"`
+	Postal struct {
+		Street     []string `xml:"Street"`
+		City       string   `xml:"City"`
+		State      string   `xml:"State,omitempty"`
+		PostalCode string   `xml:"PostalCode,omitempty"`
+		Country    struct {
+			Code string `xml:"isoCountryCode,attr"`
+			Name string `xml:",chardata"`
+		} `xml:"Country"`
+	} `xml:"PostalAddress"`
+}
+
+type cxmlCharge struct {
+	Money       cxmlMoney `xml:"Money"`
+	Description cxmlText  `xml:"Description"`
+}
+
+type cxmlOrderRequest struct {
+	XMLName   xml.Name `xml:"cXML"`
+	PayloadID string   `xml:"payloadID,attr"`
+	Timestamp string   `xml:"timestamp,attr"`
+	Lang      string   `xml:"xml:lang,attr"`
+	Header    struct {
+		From   cxmlCredential `xml:"From>Credential"`
+		To     cxmlCredential `xml:"To>Credential"`
+		Sender struct {
+			Credential cxmlCredential `xml:"Credential"`
+			UserAgent  string         `xml:"UserAgent"`
+		} `xml:"Sender"`
+	} `xml:"Header"`
+	Request struct {
+		DeploymentMode string `xml:"deploymentMode,attr"`
+		Header         struct {
+			OrderID   string       `xml:"orderID,attr"`
+			OrderDate string       `xml:"orderDate,attr"`
+			Type      string       `xml:"type,attr"`
+			Total     cxmlMoney    `xml:"Total>Money"`
+			ShipTo    *cxmlAddress `xml:"ShipTo>Address,omitempty"`
+			Shipping  *cxmlCharge  `xml:"Shipping,omitempty"`
+			Tax       *cxmlCharge  `xml:"Tax,omitempty"`
+			Comments  *cxmlText    `xml:"Comments,omitempty"`
+		} `xml:"OrderRequest>OrderRequestHeader"`
+		Item struct {
+			Quantity              int    `xml:"quantity,attr"`
+			LineNumber            int    `xml:"lineNumber,attr"`
+			RequestedDeliveryDate string `xml:"requestedDeliveryDate,attr,omitempty"`
+			SupplierPartID        string `xml:"ItemID>SupplierPartID"`
+			BuyerPartID           string `xml:"ItemID>BuyerPartID"`
+			Detail                struct {
+				UnitPrice     cxmlMoney `xml:"UnitPrice>Money"`
+				Description   cxmlText  `xml:"Description"`
+				UnitOfMeasure string    `xml:"UnitOfMeasure"`
+			} `xml:"ItemDetail"`
+		} `xml:"OrderRequest>ItemOut"`
+	} `xml:"Request"`
+}
+
+// WriteCXMLOrderRequest writes an approved purchase order as a cXML
+// OrderRequest document
+func (s *OrderExchangeService) WriteCXMLOrderRequest(w io.Writer, id uint, opts OrderExchangeOptions) error {
+	po, err := s.orderForExchange(id, &opts)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	money := func(amount float64) cxmlMoney { return cxmlMoney{po.Currency, formatAmount(amount)} }
+
+	var doc cxmlOrderRequest
+	doc.PayloadID = fmt.Sprintf("%d.%s@%s", now.UnixNano(), po.PONumber, opts.BuyerID)
+	doc.Timestamp = now.Format(time.RFC3339)
+	doc.Lang = "en-US"
+	doc.Header.From = cxmlCredential{Domain: opts.Domain, Identity: opts.BuyerID}
+	doc.Header.To = cxmlCredential{Domain: opts.Domain, Identity: opts.VendorID}
+	doc.Header.Sender.Credential = cxmlCredential{Domain: opts.Domain, Identity: opts.BuyerID, SharedSecret: opts.SharedSecret}
+	doc.Header.Sender.UserAgent = "buyer"
+	doc.Request.DeploymentMode = "production"
+	if opts.Test {
+		doc.Request.DeploymentMode = "test"
+	}
+
+	header := &doc.Request.Header
+	header.OrderID = po.PONumber
+	header.OrderDate = po.OrderDate.Format(time.RFC3339)
+	header.Type = "new"
+	header.Total = money(po.GrandTotal)
+	if opts.ShipTo.Name != "" {
+		to := opts.ShipTo
+		address := &cxmlAddress{Name: cxmlText{"en", to.Name}}
+		for _, street := range []string{to.Street1, to.Street2} {
+			if street != "" {
+				address.Postal.Street = append(address.Postal.Street, street)
+			}
+		}
+		address.Postal.City, address.Postal.State, address.Postal.PostalCode = to.City, to.State, to.PostalCode
+		address.Postal.Country.Code, address.Postal.Country.Name = to.Country, to.Country
+		header.ShipTo = address
+	}
+	if po.ShippingCost != 0 {
+		header.Shipping = &cxmlCharge{money(po.ShippingCost), cxmlText{"en", "Shipping"}}
+	}
+	if po.Tax != 0 {
+		header.Tax = &cxmlCharge{money(po.Tax), cxmlText{"en", "Tax"}}
+	}
+	if po.Notes != "" {
+		header.Comments = &cxmlText{"en", po.Notes}
+	}
+
+	item := &doc.Request.Item
+	item.Quantity = po.Quantity
+	item.LineNumber = 1
+	if po.ExpectedDelivery != nil {
+		item.RequestedDeliveryDate = po.ExpectedDelivery.Format("2006-01-02")
+	}
+	item.SupplierPartID = supplierPartID(po)
+	item.BuyerPartID = strconv.FormatUint(uint64(po.ProductID), 10)
+	item.Detail.UnitPrice = money(po.UnitPrice)
+	item.Detail.Description = cxmlText{"en", productName(po)}
+	item.Detail.UnitOfMeasure = "EA"
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n%s\n%s\n", `<?xml version="1.0" encoding="UTF-8"?>`, cxmlDocType, out)
+	return err
+}
+
+// ==================== X12 ====================
+
+// x12Clean removes the separators from an X12 element
+var x12Clean = strings.NewReplacer("*", " ", "~", " ", ">", " ", "\r", " ", "\n", " ")
+
+func x12Amount(amount float64) string {
+	return strconv.FormatFloat(roundCents(amount), 'f', -1, 64)
+}
+
+// WriteX12PurchaseOrder writes an approved purchase order as an ANSI X12
+// 004010 850 interchange
+func (s *OrderExchangeService) WriteX12PurchaseOrder(w io.Writer, id uint, opts OrderExchangeOptions) error {
+	po, err := s.orderForExchange(id, &opts)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	control := opts.ControlNumber % 1000000000
+	usage := "P"
+	if opts.Test {
+		usage = "T"
+	}
+	id15 := func(id string) string {
+		id = x12Clean.Replace(id)
+		if len(id) > 15 {
+			id = id[:15]
+		}
+		return fmt.Sprintf("%-15s", id)
+	}
+
+	var b strings.Builder
+	transactionSegments := 0
+	segment := func(elements ...string) {
+		for i, element := range elements {
+			elements[i] = strings.TrimRight(x12Clean.Replace(element), " ")
+		}
+		// Trailing empty elements are left out
+		for len(elements) > 1 && elements[len(elements)-1] == "" {
+			elements = elements[:len(elements)-1]
+		}
+		b.WriteString(strings.Join(elements, "*"))
+		b.WriteString("~\n")
+		transactionSegments++
+	}
+	address := func(code, name string, a PostalAddress) {
+		segment("N1", code, name)
+		if a.Street1 != "" || a.Street2 != "" {
+			segment("N3", a.Street1, a.Street2)
+		}
+		if a.City != "" {
+			segment("N4", a.City, a.State, a.PostalCode, a.Country)
+		}
+	}
+
+	// The ISA header is fixed width, so it is written as is
+	fmt.Fprintf(&b, "ISA*00*%-10s*00*%-10s*%-2s*%s*%-2s*%s*%s*%s*U*00401*%09d*0*%s*>~\n",
+		"", "", opts.Qualifier, id15(opts.BuyerID), opts.Qualifier, id15(opts.VendorID),
+		now.Format("060102"), now.Format("1504"), control, usage)
+	segment("GS", "PO", opts.BuyerID, opts.VendorID, now.Format("20060102"), now.Format("1504"), strconv.Itoa(control), "X", "004010")
+
+	transactionSegments = 0
+	segment("ST", "850", "0001")
+	segment("BEG", "00", "SA", po.PONumber, "", po.OrderDate.Format("20060102"))
+	segment("CUR", "BY", po.Currency)
+	if po.ExpectedDelivery != nil {
+		segment("DTM", "002", po.ExpectedDelivery.Format("20060102"))
+	}
+	if opts.ShipTo.Name != "" {
+		address("ST", opts.ShipTo.Name, opts.ShipTo)
+	}
+	if po.Vendor != nil {
+		v := po.Vendor
+		address("SE", v.Name, PostalAddress{Street1: v.AddressLine1, Street2: v.AddressLine2, City: v.City, State: v.State, PostalCode: v.PostalCode, Country: v.Country})
+	}
+	segment("PO1", "1", strconv.Itoa(po.Quantity), "EA", x12Amount(po.UnitPrice), "PE", "VP", supplierPartID(po), "BP", strconv.FormatUint(uint64(po.ProductID), 10))
+	segment("PID", "F", "", "", "", productName(po))
+	if po.ShippingCost != 0 {
+		// SAC05 is in cents
+		segment("SAC", "C", "D240", "", "", strconv.FormatInt(int64(math.Round(po.ShippingCost*100)), 10))
+	}
+	if po.Tax != 0 {
+		segment("TXI", "TX", x12Amount(po.Tax))
+	}
+	segment("CTT", "1", strconv.Itoa(po.Quantity))
+	segment("AMT", "TT", x12Amount(po.GrandTotal))
+	segment("SE", strconv.Itoa(transactionSegments+1), "0001")
+
+	segment("GE", "1", strconv.Itoa(control))
+	segment("IEA", "1", fmt.Sprintf("%09d", control))
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// ==================== Confirmations and ship notices ====================
+
+// OrderResponse is what a vendor's order confirmation or ship notice says
+// about one purchase order
+type OrderResponse struct {
+	Document         string // "cXML ConfirmationRequest", "cXML ShipNoticeRequest", "X12 855" or "X12 856"
+	PONumber         string
+	Status           string     // ordered when confirmed, shipped, or cancelled when the vendor rejects the order
+	ExpectedDelivery *time.Time // Nil when the response has no delivery date
+}
+
+// OrderResponseResult is the outcome of applying an OrderResponse
+type OrderResponseResult struct {
+	OrderResponse
+	PurchaseOrder *models.PurchaseOrder // Nil when no order has the PO number
+	Changes       []string              // What was updated, empty when nothing was
+	Error         string                // Why the response could not be applied
+}
+
+// ParseOrderResponses reads the order confirmations and ship notices of a
+// cXML ConfirmationRequest or ShipNoticeRequest, or an X12 interchange of
+// 855 and 856 transaction sets
+func ParseOrderResponses(r io.Reader) ([]OrderResponse, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	var responses []OrderResponse
+	if bytes.HasPrefix(data, []byte("<")) {
+		responses, err = parseCXMLResponses(data)
+	} else {
+		responses, err = parseX12Responses(string(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(responses) == 0 {
+		return nil, &ValidationError{Field: "file", Message: "no order confirmations or ship notices found"}
+	}
+	return responses, nil
+}
+
+type cxmlResponseDocument struct {
+	Request struct {
+		Confirmation *struct {
+			Header struct {
+				Type string `xml:"type,attr"`
+			} `xml:"ConfirmationHeader"`
+			OrderReference struct {
+				OrderID string `xml:"orderID,attr"`
+			} `xml:"OrderReference"`
+			Items []struct {
+				Statuses []struct {
+					Type         string `xml:"type,attr"`
+					DeliveryDate string `xml:"deliveryDate,attr"`
+				} `xml:"ConfirmationStatus"`
+			} `xml:"ConfirmationItem"`
+		} `xml:"ConfirmationRequest"`
+		ShipNotice *struct {
+			Header struct {
+				DeliveryDate string `xml:"deliveryDate,attr"`
+			} `xml:"ShipNoticeHeader"`
+			Portions []struct {
+				OrderReference struct {
+					OrderID string `xml:"orderID,attr"`
+				} `xml:"OrderReference"`
+			} `xml:"ShipNoticePortion"`
+		} `xml:"ShipNoticeRequest"`
+	} `xml:"Request"`
+}
+
+// parseCXMLDate reads a cXML date, which is ISO 8601 with or without a time
+func parseCXMLDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, &ValidationError{Field: "deliveryDate", Message: fmt.Sprintf("invalid cXML date %q", value)}
+}
+
+func parseCXMLResponses(data []byte) ([]OrderResponse, error) {
+	var doc cxmlResponseDocument
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, &ValidationError{Field: "file", Message: fmt.Sprintf("invalid cXML: %v", err)}
+	}
+
+	var responses []OrderResponse
+	if c := doc.Request.Confirmation; c != nil {
+		response := OrderResponse{Document: "cXML ConfirmationRequest", PONumber: c.OrderReference.OrderID, Status: "ordered"}
+		if c.Header.Type == "reject" {
+			response.Status = "cancelled"
+		}
+		// The order is expected when its last confirmed item is
+		for _, item := range c.Items {
+			for _, status := range item.Statuses {
+				if status.Type == "reject" {
+					continue
+				}
+				date, err := parseCXMLDate(status.DeliveryDate)
+				if err != nil {
+					return nil, err
+				}
+				if date != nil && (response.ExpectedDelivery == nil || date.After(*response.ExpectedDelivery)) {
+					response.ExpectedDelivery = date
+				}
+			}
+		}
+		responses = append(responses, response)
+	}
+	if n := doc.Request.ShipNotice; n != nil {
+		date, err := parseCXMLDate(n.Header.DeliveryDate)
+		if err != nil {
+			return nil, err
+		}
+		for _, portion := range n.Portions {
+			responses = append(responses, OrderResponse{Document: "cXML ShipNoticeRequest", PONumber: portion.OrderReference.OrderID, Status: "shipped", ExpectedDelivery: date})
+		}
+	}
+	return responses, nil
+}
+
+// x12DeliveryQualifiers are the DTM and ACK date qualifiers that give a
+// delivery date, best first: current schedule delivery, estimated delivery
+// and requested delivery
+var x12DeliveryQualifiers = []string{"067", "017", "002"}
+
+// x12Transaction gathers the dates and orders of one transaction set
+type x12Transaction struct {
+	kind      string
+	responses []OrderResponse
+	dates     map[string]time.Time // Latest date per qualifier
+}
+
+func (t *x12Transaction) addDate(qualifier, value string) error {
+	if value == "" {
+		return nil
+	}
+	layout := "20060102"
+	if len(value) == 6 {
+		layout = "060102"
+	}
+	date, err := time.ParseInLocation(layout, value, time.Local)
+	if err != nil {
+		return &ValidationError{Field: "file", Message: fmt.Sprintf("invalid X12 date %q", value)}
+	}
+	if current, ok := t.dates[qualifier]; !ok || date.After(current) {
+		t.dates[qualifier] = date
+	}
+	return nil
+}
+
+// finish returns the transaction's orders with its delivery date
+func (t *x12Transaction) finish() []OrderResponse {
+	var delivery *time.Time
+	for _, qualifier := range x12DeliveryQualifiers {
+		if date, ok := t.dates[qualifier]; ok {
+			delivery = &date
+			break
+		}
+	}
+	for i := range t.responses {
+		if t.responses[i].Status != "cancelled" {
+			t.responses[i].ExpectedDelivery = delivery
+		}
+	}
+	return t.responses
+}
+
+// splitX12 splits an interchange into segments of elements, taking the
+// separators from the ISA header when there is one
+func splitX12(data string) ([][]string, error) {
+	elementSep, segmentSep := "*", "~"
+	if strings.HasPrefix(data, "ISA") {
+		if len(data) < 106 {
+			return nil, &ValidationError{Field: "file", Message: "X12 ISA header is too short"}
+		}
+		elementSep, segmentSep = data[3:4], data[105:106]
+	}
+	var segments [][]string
+	for _, raw := range strings.Split(data, segmentSep) {
+		raw = strings.TrimSpace(raw)
+		if raw != "" {
+			segments = append(segments, strings.Split(raw, elementSep))
+		}
+	}
+	return segments, nil
+}
+
+func parseX12Responses(data string) ([]OrderResponse, error) {
+	if !strings.HasPrefix(data, "ISA") && !strings.HasPrefix(data, "ST") {
+		return nil, &ValidationError{Field: "file", Message: "not a cXML document or an X12 interchange"}
+	}
+	segments, err := splitX12(data)
+	if err != nil {
+		return nil, err
+	}
+	element := func(segment []string, i int) string {
+		if i < len(segment) {
+			return strings.TrimSpace(segment[i])
+		}
+		return ""
+	}
+
+	var responses []OrderResponse
+	var tx *x12Transaction
+	for _, segment := range segments {
+		id := segment[0]
+		if id == "ST" {
+			tx = &x12Transaction{kind: element(segment, 1), dates: map[string]time.Time{}}
+			continue
+		}
+		if tx == nil {
+			continue
+		}
+		switch {
+		case id == "SE":
+			responses = append(responses, tx.finish()...)
+			tx = nil
+		case tx.kind == "855" && id == "BAK":
+			status := "ordered"
+			if ack := element(segment, 2); ack == "RD" || ack == "RJ" {
+				status = "cancelled"
+			}
+			tx.responses = append(tx.responses, OrderResponse{Document: "X12 855", PONumber: element(segment, 3), Status: status})
+		case tx.kind == "855" && id == "ACK":
+			// Lines rejected (IR) or deleted (ID) do not date the order
+			if code := element(segment, 1); code != "IR" && code != "ID" {
+				if err := tx.addDate(element(segment, 4), element(segment, 5)); err != nil {
+					return nil, err
+				}
+			}
+		case tx.kind == "856" && id == "PRF":
+			tx.responses = append(tx.responses, OrderResponse{Document: "X12 856", PONumber: element(segment, 1), Status: "shipped"})
+		case (tx.kind == "855" || tx.kind == "856") && id == "DTM":
+			if err := tx.addDate(element(segment, 1), element(segment, 2)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return responses, nil
+}
+
+// orderStatusRank orders the statuses an order goes through once approved
+var orderStatusRank = map[string]int{"approved": 1, "ordered": 2, "shipped": 3, "received": 4}
+
+// ApplyOrderResponses reads a vendor's confirmations and ship notices with
+// ParseOrderResponses and updates the purchase orders they name. A
+// confirmation moves an approved order to ordered and a ship notice moves it
+// to shipped, never back; a rejection cancels an order not yet shipped.
+// Delivery dates become the expected delivery. Responses that cannot be
+// applied are reported in their result and do not stop the others.
+func (s *OrderExchangeService) ApplyOrderResponses(r io.Reader) ([]OrderResponseResult, error) {
+	responses, err := ParseOrderResponses(r)
+	if err != nil {
+		return nil, err
+	}
+	poSvc := NewPurchaseOrderService(s.db)
+	results := make([]OrderResponseResult, 0, len(responses))
+	for _, response := range responses {
+		result := OrderResponseResult{OrderResponse: response}
+		if err := s.applyOrderResponse(poSvc, &result); err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (s *OrderExchangeService) applyOrderResponse(poSvc *PurchaseOrderService, result *OrderResponseResult) error {
+	var po models.PurchaseOrder
+	if err := s.db.Where("po_number = ?", result.PONumber).First(&po).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("no purchase order %s", result.PONumber)
+		}
+		return err
+	}
+	result.PurchaseOrder = &po
+
+	previous := po.Status
+	current, target := orderStatusRank[previous], orderStatusRank[result.Status]
+	switch {
+	case current == 0 && po.Status != result.Status:
+		return fmt.Errorf("purchase order %s is %s, not with the vendor", po.PONumber, po.Status)
+	case result.Status == "cancelled" && current >= orderStatusRank["shipped"]:
+		return fmt.Errorf("purchase order %s is already %s and cannot be rejected", po.PONumber, po.Status)
+	case result.Status == "cancelled" && current > 0, target > current:
+		updated, err := poSvc.UpdateStatus(po.ID, result.Status)
+		if err != nil {
+			return err
+		}
+		*result.PurchaseOrder = *updated
+		result.Changes = append(result.Changes, fmt.Sprintf("status %s -> %s", previous, result.Status))
+	}
+
+	if result.ExpectedDelivery != nil && result.Status != "cancelled" && previous != "received" {
+		date := *result.ExpectedDelivery
+		if po.ExpectedDelivery == nil || !po.ExpectedDelivery.Equal(date) {
+			updated, err := poSvc.UpdateDeliveryDates(po.ID, &date, nil)
+			if err != nil {
+				return err
+			}
+			*result.PurchaseOrder = *updated
+			result.Changes = append(result.Changes, "expected delivery "+date.Format("2006-01-02"))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+)
+
+func TestOrderExchange_Documents(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	vendor, _ := NewVendorService(cfg.DB).Create("Acme Distribution", "USD", "")
+	cfg.DB.Model(vendor).Updates(map[string]interface{}{"address_line1": "1 Depot Rd", "city": "Reno", "state": "NV", "postal_code": "89501", "country": "US"})
+	brand, _ := NewBrandService(cfg.DB).Create("Apple")
+	product, _ := NewProductService(cfg.DB).Create("MacBook", brand.ID, nil)
+	cfg.DB.Model(product).Update("sku", "MBP-14")
+	quote, err := NewQuoteService(cfg.DB).Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 1299.5, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(2026, 11, 2, 0, 0, 0, 0, time.Local)
+	poSvc := NewPurchaseOrderService(cfg.DB)
+	po, err := poSvc.Create(CreatePurchaseOrderInput{QuoteID: quote.ID, PONumber: "PO-850", Quantity: 3, ShippingCost: 25, Tax: 80.25, ExpectedDelivery: &expected, Notes: "Dock 4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewOrderExchangeService(cfg.DB)
+	opts := OrderExchangeOptions{
+		BuyerID: "BUYERCO",
+		ShipTo:  PostalAddress{Name: "Buyer Co Warehouse", Street1: "9 Main St", City: "Austin", State: "TX", PostalCode: "78701", Country: "US"},
+	}
+
+	// Pending orders cannot be sent
+	var validationErr *ValidationError
+	if err := svc.WriteX12PurchaseOrder(&bytes.Buffer{}, po.ID, opts); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for a pending order, got %v", err)
+	}
+	if _, err := poSvc.UpdateStatus(po.ID, "approved"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.WriteX12PurchaseOrder(&bytes.Buffer{}, po.ID, OrderExchangeOptions{}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError without a buyer ID, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := svc.WriteX12PurchaseOrder(&buf, po.ID, opts); err != nil {
+		t.Fatal(err)
+	}
+	x12 := buf.String()
+	lines := strings.Split(strings.TrimSpace(x12), "\n")
+	if len(lines[0]) != 106 {
+		t.Errorf("Expected a 106 character ISA segment, got %d: %q", len(lines[0]), lines[0])
+	}
+	for _, want := range []string{
+		"*ZZ*BUYERCO        *ZZ*ACMEDISTRIBUTIO*",
+		"GS*PO*BUYERCO*ACMEDISTRIBUTIO*",
+		"ST*850*0001~",
+		"BEG*00*SA*PO-850**",
+		"DTM*002*20261102~",
+		"N1*ST*Buyer Co Warehouse~\nN3*9 Main St~\nN4*Austin*TX*78701*US~",
+		"N1*SE*Acme Distribution~\nN3*1 Depot Rd~\nN4*Reno*NV*89501*US~",
+		"PO1*1*3*EA*1299.5*PE*VP*MBP-14*BP*",
+		"PID*F****MacBook~",
+		"SAC*C*D240***2500~",
+		"TXI*TX*80.25~",
+		"CTT*1*3~",
+		"AMT*TT*4003.75~",
+		"SE*17*0001~",
+		"IEA*1*",
+	} {
+		if !strings.Contains(x12, want) {
+			t.Errorf("Expected the 850 to contain %q, got:\n%s", want, x12)
+		}
+	}
+
+	buf.Reset()
+	if err := svc.WriteCXMLOrderRequest(&buf, po.ID, opts); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Header struct {
+			To string `xml:"To>Credential>Identity"`
+		} `xml:"Header"`
+		Order struct {
+			Header struct {
+				OrderID string `xml:"orderID,attr"`
+				Total   string `xml:"Total>Money"`
+				City    string `xml:"ShipTo>Address>PostalAddress>City"`
+			} `xml:"OrderRequestHeader"`
+			Item struct {
+				Quantity  int    `xml:"quantity,attr"`
+				Part      string `xml:"ItemID>SupplierPartID"`
+				UnitPrice string `xml:"ItemDetail>UnitPrice>Money"`
+			} `xml:"ItemOut"`
+		} `xml:"Request>OrderRequest"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected valid XML: %v\n%s", err, buf.String())
+	}
+	if doc.Header.To != "ACMEDISTRIBUTIO" || doc.Order.Header.OrderID != "PO-850" || doc.Order.Header.Total != "4003.75" ||
+		doc.Order.Header.City != "Austin" || doc.Order.Item.Quantity != 3 || doc.Order.Item.Part != "MBP-14" || doc.Order.Item.UnitPrice != "1299.50" {
+		t.Errorf("Unexpected OrderRequest %+v:\n%s", doc, buf.String())
+	}
+	if !strings.Contains(buf.String(), "<!DOCTYPE cXML") || !strings.Contains(buf.String(), `<Comments xml:lang="en">Dock 4</Comments>`) {
+		t.Errorf("Expected a doctype and comments, got:\n%s", buf.String())
+	}
+}
+
+func TestOrderExchange_Responses(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	vendor, _ := NewVendorService(cfg.DB).Create("Acme", "USD", "")
+	brand, _ := NewBrandService(cfg.DB).Create("Apple")
+	product, _ := NewProductService(cfg.DB).Create("MacBook", brand.ID, nil)
+	quote, _ := NewQuoteService(cfg.DB).Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 100, Currency: "USD"})
+	poSvc := NewPurchaseOrderService(cfg.DB)
+	newPO := func(number, status string) *models.PurchaseOrder {
+		t.Helper()
+		po, err := poSvc.Create(CreatePurchaseOrderInput{QuoteID: quote.ID, PONumber: number, Quantity: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != "pending" {
+			if po, err = poSvc.UpdateStatus(po.ID, status); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return po
+	}
+	newPO("PO-1", "approved")
+	newPO("PO-2", "approved")
+	newPO("PO-3", "approved")
+	newPO("PO-4", "pending")
+
+	svc := NewOrderExchangeService(cfg.DB)
+	apply := func(document string) []OrderResponseResult {
+		t.Helper()
+		results, err := svc.ApplyOrderResponses(strings.NewReader(document))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return results
+	}
+	order := func(number string) *models.PurchaseOrder {
+		var po models.PurchaseOrder
+		cfg.DB.Where("po_number = ?", number).First(&po)
+		return &po
+	}
+
+	// An X12 855 acknowledgment with a scheduled delivery per line
+	results := apply("ISA*00*          *00*          *ZZ*ACME           *ZZ*BUYERCO        *261018*1200*U*00401*000000007*0*P*>~\n" +
+		"GS*PR*ACME*BUYERCO*20261018*1200*7*X*004010~ST*855*0001~BAK*00*AC*PO-1*20261018~" +
+		"PO1*1*1*EA*100**VP*MBP~ACK*IA*1*EA*067*20261105~SE*5*0001~" +
+		"ST*855*0002~BAK*00*RJ*PO-2*20261018~SE*3*0002~GE*2*7~IEA*1*000000007~")
+	if len(results) != 2 || results[0].Error != "" || results[1].Error != "" {
+		t.Fatalf("Expected two applied acknowledgments, got %+v", results)
+	}
+	if po := order("PO-1"); po.Status != "ordered" || po.ExpectedDelivery == nil || po.ExpectedDelivery.Format("2006-01-02") != "2026-11-05" {
+		t.Errorf("Expected PO-1 ordered for 2026-11-05, got %s %v", po.Status, po.ExpectedDelivery)
+	}
+	if po := order("PO-2"); po.Status != "cancelled" {
+		t.Errorf("Expected the rejected PO-2 to be cancelled, got %s", po.Status)
+	}
+
+	// A cXML confirmation, then an X12 856 ship notice that moves it on
+	results = apply(`<?xml version="1.0"?>
+<!DOCTYPE cXML SYSTEM "http://xml.cxml.org/schemas/cXML/1.2.014/Fulfill.dtd">
+<cXML payloadID="1@acme" timestamp="2026-10-18T12:00:00Z">
+  <Request>
+    <ConfirmationRequest>
+      <ConfirmationHeader type="detail" noticeDate="2026-10-18T12:00:00Z"/>
+      <OrderReference orderID="PO-3"><DocumentReference payloadID="x"/></OrderReference>
+      <ConfirmationItem lineNumber="1" quantity="1">
+        <ConfirmationStatus type="detail" quantity="1" deliveryDate="2026-11-09"/>
+      </ConfirmationItem>
+    </ConfirmationRequest>
+  </Request>
+</cXML>`)
+	if len(results) != 1 || results[0].Document != "cXML ConfirmationRequest" || len(results[0].Changes) != 2 {
+		t.Errorf("Expected a status and date change, got %+v", results)
+	}
+	results = apply("ST*856*0001~BSN*00*SHIP1*20261020*1200~DTM*011*20261020~DTM*017*20261104~HL*1**S~HL*2*1*O~PRF*PO-3~HL*3*1*O~PRF*PO-1~SE*9*0001~")
+	if len(results) != 2 {
+		t.Fatalf("Expected two orders shipped, got %+v", results)
+	}
+	for _, number := range []string{"PO-1", "PO-3"} {
+		if po := order(number); po.Status != "shipped" || po.ExpectedDelivery.Format("2006-01-02") != "2026-11-04" {
+			t.Errorf("Expected %s shipped for 2026-11-04, got %s %v", number, po.Status, po.ExpectedDelivery)
+		}
+	}
+
+	// A late confirmation does not move a shipped order back, and orders
+	// not with the vendor are refused
+	results = apply("ST*855*0001~BAK*00*AD*PO-3*20261018~SE*3*0001~ST*855*0002~BAK*00*AD*PO-4~SE*3*0002~ST*855*0003~BAK*00*AD*PO-9~SE*3*0003~")
+	if results[0].Error != "" || len(results[0].Changes) != 0 || order("PO-3").Status != "shipped" {
+		t.Errorf("Expected no change to the shipped order, got %+v", results[0])
+	}
+	if results[1].Error == "" || order("PO-4").Status != "pending" {
+		t.Errorf("Expected the pending order to be refused, got %+v", results[1])
+	}
+	if results[2].Error == "" || results[2].PurchaseOrder != nil {
+		t.Errorf("Expected an unknown order to be reported, got %+v", results[2])
+	}
+
+	var validationErr *ValidationError
+	if _, err := svc.ApplyOrderResponses(strings.NewReader("hello")); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for an unknown file, got %v", err)
+	}
+	if _, err := svc.ApplyOrderResponses(strings.NewReader("ST*850*0001~BEG*00*SA*PO-1~SE*3*0001~")); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for a file without responses, got %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultPONumberPattern numbers purchase orders per year: PO-2025-00001
+const DefaultPONumberPattern = "PO-{YYYY}-{seq:5}"
+
+// poNumberTokens are the placeholders a PO number pattern may use besides
+// {seq} and {seq:N}
+var poNumberTokens = map[string]bool{
+	"YYYY":        true, // Four-digit year of the order date
+	"YY":          true, // Two-digit year
+	"MM":          true, // Two-digit month
+	"project":     true, // Project code, from the project name
+	"project_id":  true,
+	"vendor_code": true, // Vendor code, from the vendor name
+	"vendor_id":   true,
+}
+
+var poNumberToken = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// PONumberPattern is a parsed PO number pattern such as PO-{YYYY}-{seq:5} or
+// {project}-{vendor_code}-{seq}. The sequence counts separately for every
+// value of the rest of the pattern: per year with {YYYY}, per project with
+// {project}.
+type PONumberPattern struct {
+	pattern string
+	prefix  string // Pattern text before {seq}
+	suffix  string // Pattern text after {seq}
+	width   int    // Zero padding of the sequence
+}
+
+// ParsePONumberPattern checks a PO number pattern. It must contain {seq} (or
+// {seq:N} for N digits) exactly once, and otherwise only the placeholders
+// {YYYY}, {YY}, {MM}, {project}, {project_id}, {vendor_code} and {vendor_id}.
+// An empty pattern is DefaultPONumberPattern.
+func ParsePONumberPattern(pattern string) (*PONumberPattern, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		pattern = DefaultPONumberPattern
+	}
+
+	parsed := &PONumberPattern{pattern: pattern}
+	seqs := 0
+	for _, match := range poNumberToken.FindAllStringSubmatchIndex(pattern, -1) {
+		token := pattern[match[2]:match[3]]
+		name, width, hasWidth := strings.Cut(token, ":")
+		if name == "seq" {
+			seqs++
+			if hasWidth {
+				n, err := strconv.Atoi(width)
+				if err != nil || n < 1 || n > 12 {
+					return nil, &ValidationError{Field: "po_number_pattern", Message: fmt.Sprintf("invalid sequence width in {%s} (use 1 to 12 digits)", token)}
+				}
+				parsed.width = n
+			}
+			parsed.prefix = pattern[:match[0]]
+			parsed.suffix = pattern[match[1]:]
+			continue
+		}
+		if hasWidth || !poNumberTokens[name] {
+			return nil, &ValidationError{Field: "po_number_pattern", Message: fmt.Sprintf("unknown placeholder {%s} in PO number pattern %q", token, pattern)}
+		}
+	}
+	if seqs != 1 {
+		return nil, &ValidationError{Field: "po_number_pattern", Message: fmt.Sprintf("PO number pattern %q must contain {seq} exactly once", pattern)}
+	}
+	return parsed, nil
+}
+
+// String returns the pattern
+func (p *PONumberPattern) String() string {
+	return p.pattern
+}
+
+// PONumberValues are what a PO number pattern is filled in from
+type PONumberValues struct {
+	Date    time.Time
+	Project *models.Project
+	Vendor  *models.Vendor
+}
+
+// fill replaces the placeholders other than {seq} in text
+func (p *PONumberPattern) fill(text string, values PONumberValues) (string, error) {
+	var err error
+	filled := poNumberToken.ReplaceAllStringFunc(text, func(token string) string {
+		switch name := token[1 : len(token)-1]; name {
+		case "YYYY":
+			return values.Date.Format("2006")
+		case "YY":
+			return values.Date.Format("06")
+		case "MM":
+			return values.Date.Format("01")
+		case "project", "project_id":
+			if values.Project == nil {
+				err = &ValidationError{Field: "po_number", Message: fmt.Sprintf("PO number pattern %q needs a project; give the order a project or a PO number", p.pattern)}
+				return ""
+			}
+			if name == "project_id" {
+				return strconv.FormatUint(uint64(values.Project.ID), 10)
+			}
+			return nameCode(values.Project.Name, 12)
+		case "vendor_code", "vendor_id":
+			if values.Vendor == nil {
+				err = &ValidationError{Field: "po_number", Message: fmt.Sprintf("PO number pattern %q needs a vendor", p.pattern)}
+				return ""
+			}
+			if name == "vendor_id" {
+				return strconv.FormatUint(uint64(values.Vendor.ID), 10)
+			}
+			return nameCode(values.Vendor.Name, 6)
+		}
+		return token
+	})
+	return filled, err
+}
+
+// format returns the PO number for sequence value seq
+func (p *PONumberPattern) format(prefix, suffix string, seq int64) string {
+	return fmt.Sprintf("%s%0*d%s", prefix, p.width, seq, suffix)
+}
+
+// nameCode turns a name into an upper case code of at most max letters and
+// digits, with dashes between words: "Acme Corp" becomes ACME-C with max 6
+func nameCode(name string, max int) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToUpper(name) {
+		isAlnum := (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if !isAlnum {
+			dash = b.Len() > 0
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteRune(r)
+	}
+	code := b.String()
+	if len(code) > max {
+		code = strings.TrimRight(code[:max], "-")
+	}
+	if code == "" {
+		code = "X"
+	}
+	return code
+}
+
+// nextPONumber hands out the next PO number of pattern for values. The
+// sequence of the pattern's scope is bumped with a single upsert, which
+// SQLite and PostgreSQL both apply atomically, so concurrent requests never
+// get the same number. Numbers already taken, for example typed by hand, are
+// skipped by moving the sequence past the highest of them.
+func nextPONumber(db *gorm.DB, pattern *PONumberPattern, values PONumberValues) (string, error) {
+	prefix, err := pattern.fill(pattern.prefix, values)
+	if err != nil {
+		return "", err
+	}
+	suffix, err := pattern.fill(pattern.suffix, values)
+	if err != nil {
+		return "", err
+	}
+	scope := prefix + "{seq}" + suffix
+	if len(scope) > 200 {
+		return "", &ValidationError{Field: "po_number", Message: fmt.Sprintf("PO number pattern %q gives numbers that are too long", pattern)}
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		var seq int64
+		if err := db.Raw(`INSERT INTO po_number_sequences (scope, value, updated_at) VALUES (?, 1, ?)
+			ON CONFLICT (scope) DO UPDATE SET value = po_number_sequences.value + 1, updated_at = excluded.updated_at
+			RETURNING value`, scope, time.Now()).Scan(&seq).Error; err != nil {
+			return "", err
+		}
+		number := pattern.format(prefix, suffix, seq)
+		if len(number) > 50 {
+			return "", &ValidationError{Field: "po_number", Message: fmt.Sprintf("PO number %s is longer than 50 characters", number)}
+		}
+
+		var taken int64
+		if err := db.Unscoped().Model(&models.PurchaseOrder{}).Where("po_number = ?", number).Count(&taken).Error; err != nil {
+			return "", err
+		}
+		if taken == 0 {
+			return number, nil
+		}
+
+		// Continue after the highest number of this scope already in use
+		var numbers []string
+		if err := db.Unscoped().Model(&models.PurchaseOrder{}).
+			Where("po_number LIKE ?", escapeLike(prefix)+"%"+escapeLike(suffix)).
+			Pluck("po_number", &numbers).Error; err != nil {
+			return "", err
+		}
+		highest := seq
+		for _, existing := range numbers {
+			digits := strings.TrimSuffix(strings.TrimPrefix(existing, prefix), suffix)
+			if n, err := strconv.ParseInt(digits, 10, 64); err == nil && n > highest {
+				highest = n
+			}
+		}
+		if err := db.Model(&models.PONumberSequence{}).Where("scope = ? AND value < ?", scope, highest).
+			Update("value", highest).Error; err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("could not find a free PO number for pattern %q", pattern)
+}
+
+// escapeLike turns the % wildcards in s into _, so a LIKE pattern built from
+// it leaves only the intended part open
+func escapeLike(s string) string {
+	return strings.ReplaceAll(s, "%", "_")
+}
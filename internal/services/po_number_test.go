@@ -0,0 +1,154 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shakfu/buyer/internal/config"
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm/logger"
+)
+
+func TestParsePONumberPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{"", false},
+		{"PO-{YYYY}-{seq:5}", false},
+		{"{project}-{vendor_code}-{seq}", false},
+		{"{YY}{MM}/{vendor_id}/{seq:3}", false},
+		{"PO-{YYYY}", true},
+		{"PO-{seq}-{seq}", true},
+		{"PO-{seq:0}", true},
+		{"PO-{seq:x}", true},
+		{"PO-{DD}-{seq}", true},
+		{"PO-{YYYY:2}-{seq}", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			_, err := ParsePONumberPattern(tt.pattern)
+			var validationErr *ValidationError
+			if tt.wantErr && !errors.As(err, &validationErr) {
+				t.Errorf("Expected a ValidationError, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPurchaseOrderService_AutomaticPONumbers(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	spec, _ := NewSpecificationService(cfg.DB).Create("Laptop", "")
+	vendor, _ := NewVendorService(cfg.DB).Create("Acme Corp", "USD", "")
+	brand, _ := NewBrandService(cfg.DB).Create("Apple")
+	product, _ := NewProductService(cfg.DB).Create("MacBook", brand.ID, &spec.ID)
+	quote, err := NewQuoteService(cfg.DB).Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 1000, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	projects := NewProjectService(cfg.DB)
+	office, _ := projects.Create("Office Fit-out", "", 0, nil)
+	lab, _ := projects.Create("Lab", "", 0, nil)
+
+	create := func(svc *PurchaseOrderService, poNumber string, projectID *uint) string {
+		t.Helper()
+		po, err := svc.Create(CreatePurchaseOrderInput{QuoteID: quote.ID, PONumber: poNumber, ProjectID: projectID, Quantity: 1})
+		if err != nil {
+			t.Fatalf("Failed to create purchase order: %v", err)
+		}
+		return po.PONumber
+	}
+
+	// The default pattern numbers per year and skips numbers already taken
+	svc := NewPurchaseOrderService(cfg.DB)
+	year := time.Now().Year()
+	if got := create(svc, "", nil); got != fmt.Sprintf("PO-%d-00001", year) {
+		t.Errorf("Expected the first number of the year, got %s", got)
+	}
+	create(svc, fmt.Sprintf("PO-%d-00002", year), nil)
+	create(svc, fmt.Sprintf("PO-%d-00003", year), nil)
+	if got := create(svc, "  ", nil); got != fmt.Sprintf("PO-%d-00004", year) {
+		t.Errorf("Expected the number after those taken by hand, got %s", got)
+	}
+
+	// Per project sequences
+	svc = svc.WithPONumberPattern("{project}-{vendor_code}-{seq}")
+	for _, want := range []struct {
+		project *uint
+		number  string
+	}{
+		{&office.ID, "OFFICE-FIT-O-ACME-C-1"},
+		{&lab.ID, "LAB-ACME-C-1"},
+		{&office.ID, "OFFICE-FIT-O-ACME-C-2"},
+	} {
+		if got := create(svc, "", want.project); got != want.number {
+			t.Errorf("Expected %s, got %s", want.number, got)
+		}
+	}
+	var validationErr *ValidationError
+	if _, err := svc.Create(CreatePurchaseOrderInput{QuoteID: quote.ID, Quantity: 1}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError numbering an order without a project, got %v", err)
+	}
+	if _, err := svc.WithPONumberPattern("PO-{oops}").Create(CreatePurchaseOrderInput{QuoteID: quote.ID, Quantity: 1}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for a bad pattern, got %v", err)
+	}
+}
+
+func TestPurchaseOrderService_AutomaticPONumbersConcurrent(t *testing.T) {
+	db, err := config.OpenDatabase("sqlite", filepath.Join(t.TempDir(), "buyer.db"), logger.Silent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models.All()...); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, _ := NewSpecificationService(db).Create("Laptop", "")
+	vendor, _ := NewVendorService(db).Create("Acme", "USD", "")
+	brand, _ := NewBrandService(db).Create("Apple")
+	product, _ := NewProductService(db).Create("MacBook", brand.ID, &spec.ID)
+	quote, err := NewQuoteService(db).Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 1000, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers = 8
+	svc := NewPurchaseOrderService(db)
+	numbers := make(chan string, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			po, err := svc.Create(CreatePurchaseOrderInput{QuoteID: quote.ID, Quantity: 1})
+			if err != nil {
+				t.Errorf("Failed to create purchase order: %v", err)
+				return
+			}
+			numbers <- po.PONumber
+		}()
+	}
+	wg.Wait()
+	close(numbers)
+
+	seen := map[string]bool{}
+	for number := range numbers {
+		if seen[number] {
+			t.Errorf("PO number %s handed out twice", number)
+		}
+		seen[number] = true
+	}
+	if len(seen) != workers || !seen[fmt.Sprintf("PO-%d-%05d", time.Now().Year(), workers)] {
+		t.Errorf("Expected %d consecutive numbers, got %v", workers, seen)
+	}
+}
@@ -18,25 +18,7 @@ func TestProcurementIntegration_EndToEndWorkflow(t *testing.T) {
 	defer func() { _ = cfg.Close() }()
 
 	// Migrate all models
-	if err := cfg.AutoMigrate(
-		&models.Vendor{},
-		&models.Brand{},
-		&models.Specification{},
-		&models.SpecificationAttribute{},
-		&models.Product{},
-		&models.ProductAttribute{},
-		&models.Quote{},
-		&models.Forex{},
-		&models.Project{},
-		&models.BillOfMaterials{},
-		&models.BillOfMaterialsItem{},
-		&models.ProjectRequisition{},
-		&models.ProjectRequisitionItem{},
-		&models.ProjectProcurementStrategy{},
-		&models.VendorRating{},
-		&models.PurchaseOrder{},
-		&models.Document{},
-	); err != nil {
+	if err := cfg.AutoMigrate(models.All()...); err != nil {
 		t.Fatalf("Failed to migrate: %v", err)
 	}
 
@@ -568,25 +550,7 @@ func TestProcurementIntegration_LargeProject(t *testing.T) {
 	defer func() { _ = cfg.Close() }()
 
 	// Migrate
-	if err := cfg.AutoMigrate(
-		&models.Vendor{},
-		&models.Brand{},
-		&models.Specification{},
-		&models.SpecificationAttribute{},
-		&models.Product{},
-		&models.ProductAttribute{},
-		&models.Quote{},
-		&models.Forex{},
-		&models.Project{},
-		&models.BillOfMaterials{},
-		&models.BillOfMaterialsItem{},
-		&models.ProjectRequisition{},
-		&models.ProjectRequisitionItem{},
-		&models.ProjectProcurementStrategy{},
-		&models.VendorRating{},
-		&models.PurchaseOrder{},
-		&models.Document{},
-	); err != nil {
+	if err := cfg.AutoMigrate(models.All()...); err != nil {
 		t.Fatalf("Failed to migrate: %v", err)
 	}
 
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"strings"
 
@@ -18,6 +19,11 @@ func NewProductService(db *gorm.DB) *ProductService {
 	return &ProductService{db: db}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *ProductService) WithContext(ctx context.Context) *ProductService {
+	return &ProductService{db: s.db.WithContext(ctx)}
+}
+
 // Create creates a new product
 func (s *ProductService) Create(name string, brandID uint, specificationID *uint) (*models.Product, error) {
 	name = strings.TrimSpace(name)
@@ -47,9 +53,9 @@ func (s *ProductService) Create(name string, brandID uint, specificationID *uint
 
 	// Check for duplicate
 	var existing models.Product
-	err := s.db.Where("name = ?", name).First(&existing).Error
+	err := s.db.Unscoped().Where("name = ?", name).First(&existing).Error
 	if err == nil {
-		return nil, &DuplicateError{Entity: "Product", Name: name}
+		return nil, &DuplicateError{Entity: "Product", Name: name, InTrash: existing.DeletedAt.Valid}
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
@@ -168,9 +174,9 @@ func (s *ProductService) Update(id uint, newName string, specificationID *uint)
 
 	// Check for duplicate name
 	var existing models.Product
-	err = s.db.Where("name = ? AND id != ?", newName, id).First(&existing).Error
+	err = s.db.Unscoped().Where("name = ? AND id != ?", newName, id).First(&existing).Error
 	if err == nil {
-		return nil, &DuplicateError{Entity: "Product", Name: newName}
+		return nil, &DuplicateError{Entity: "Product", Name: newName, InTrash: existing.DeletedAt.Valid}
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
@@ -186,16 +192,9 @@ func (s *ProductService) Update(id uint, newName string, specificationID *uint)
 	return s.GetByID(id)
 }
 
-// Delete deletes a product by ID
+// Delete moves a product to the trash
 func (s *ProductService) Delete(id uint) error {
-	result := s.db.Delete(&models.Product{}, id)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return &NotFoundError{Entity: "Product", ID: id}
-	}
-	return nil
+	return moveToTrash(s.db, "product", id)
 }
 
 // Count returns the total number of products
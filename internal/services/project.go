@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"time"
@@ -19,6 +20,11 @@ func NewProjectService(db *gorm.DB) *ProjectService {
 	return &ProjectService{db: db}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *ProjectService) WithContext(ctx context.Context) *ProjectService {
+	return NewProjectService(s.db.WithContext(ctx))
+}
+
 // Create creates a new project with an associated Bill of Materials
 func (s *ProjectService) Create(name, description string, budget float64, deadline *time.Time) (*models.Project, error) {
 	name = strings.TrimSpace(name)
@@ -34,9 +40,9 @@ func (s *ProjectService) Create(name, description string, budget float64, deadli
 
 	// Check for duplicate
 	var existing models.Project
-	err := s.db.Where("name = ?", name).First(&existing).Error
+	err := s.db.Unscoped().Where("name = ?", name).First(&existing).Error
 	if err == nil {
-		return nil, &DuplicateError{Entity: "Project", Name: name}
+		return nil, &DuplicateError{Entity: "Project", Name: name, InTrash: existing.DeletedAt.Valid}
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
@@ -81,6 +87,7 @@ func (s *ProjectService) GetByID(id uint) (*models.Project, error) {
 	var project models.Project
 	err := s.db.Preload("BillOfMaterials.Items.Specification").
 		Preload("Requisitions.Items.BOMItem.Specification").
+		Preload("CostCenter").Preload("GLAccount").
 		First(&project, id).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, &NotFoundError{Entity: "Project", ID: id}
@@ -157,9 +164,9 @@ func (s *ProjectService) Update(id uint, name, description string, budget float6
 
 	// Check for duplicate name (excluding current project)
 	var existing models.Project
-	err = s.db.Where("name = ? AND id != ?", name, id).First(&existing).Error
+	err = s.db.Unscoped().Where("name = ? AND id != ?", name, id).First(&existing).Error
 	if err == nil {
-		return nil, &DuplicateError{Entity: "Project", Name: name}
+		return nil, &DuplicateError{Entity: "Project", Name: name, InTrash: existing.DeletedAt.Valid}
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
@@ -187,18 +194,10 @@ func (s *ProjectService) Update(id uint, name, description string, budget float6
 	return s.GetByID(id)
 }
 
-// Delete deletes a project (and cascades to BOM and ProjectRequisitions)
+// Delete moves a project to the trash. Its BOM and ProjectRequisitions are
+// kept for a restore and removed when the trash is purged.
 func (s *ProjectService) Delete(id uint) error {
-	project, err := s.GetByID(id)
-	if err != nil {
-		return err
-	}
-
-	if err := s.db.Delete(project).Error; err != nil {
-		return err
-	}
-
-	return nil
+	return moveToTrash(s.db, "project", id)
 }
 
 // Count returns the total number of projects
@@ -266,6 +265,19 @@ func (s *ProjectService) AddBillOfMaterialsItem(projectID, specificationID uint,
 	return bomItem, nil
 }
 
+// GetBillOfMaterialsItem retrieves a BOM item by ID with its specification
+func (s *ProjectService) GetBillOfMaterialsItem(itemID uint) (*models.BillOfMaterialsItem, error) {
+	var bomItem models.BillOfMaterialsItem
+	err := s.db.Preload("Specification").First(&bomItem, itemID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &NotFoundError{Entity: "BillOfMaterialsItem", ID: itemID}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &bomItem, nil
+}
+
 // UpdateBillOfMaterialsItem updates a BOM item's quantity and notes
 func (s *ProjectService) UpdateBillOfMaterialsItem(itemID uint, quantity int, notes string) (*models.BillOfMaterialsItem, error) {
 	if quantity <= 0 {
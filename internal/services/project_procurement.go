@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
@@ -26,6 +27,11 @@ func NewProjectProcurementService(db *gorm.DB, quoteService *QuoteService, proje
 	}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *ProjectProcurementService) WithContext(ctx context.Context) *ProjectProcurementService {
+	return NewProjectProcurementService(s.db.WithContext(ctx), s.quoteService.WithContext(ctx), s.projectService.WithContext(ctx))
+}
+
 // BOMItemProcurementAnalysis holds analysis for a single BOM item across all requisitions
 type BOMItemProcurementAnalysis struct {
 	BOMItem              *models.BillOfMaterialsItem
@@ -1935,17 +1941,25 @@ func (s *ProjectProcurementService) calculateProjectProgress(project *models.Pro
 		progress.BOMCoverage = float64(itemsWithQuotes) / float64(totalBOMItems) * 100
 	}
 
-	// Requisition counts
+	// Requisition counts: a requisition is complete once every item is
+	// received (or cancelled), which follows its purchase orders
 	progress.RequisitionsTotal = len(project.Requisitions)
-	// Note: ProjectRequisitions don't have status, count all as complete if they exist
-	progress.RequisitionsComplete = len(project.Requisitions)
+	for _, req := range project.Requisitions {
+		complete := len(req.Items) > 0
+		for _, item := range req.Items {
+			if item.ProcurementStatus != "received" && item.ProcurementStatus != "cancelled" {
+				complete = false
+			}
+		}
+		if complete {
+			progress.RequisitionsComplete++
+		}
+	}
 
 	// Order counts
 	var orders []models.PurchaseOrder
-	s.db.Joins("JOIN quotes ON quotes.id = purchase_orders.quote_id").
-		Joins("JOIN products ON products.id = quotes.product_id").
-		Joins("JOIN bill_of_materials_items ON bill_of_materials_items.specification_id = products.specification_id").
-		Where("bill_of_materials_items.bill_of_materials_id = ?", project.BillOfMaterials.ID).
+	s.db.Where("project_id = ?", project.ID).
+		Where("status NOT IN (?)", []string{"cancelled", "rejected"}).
 		Find(&orders)
 
 	progress.OrdersPlaced = len(orders)
@@ -1981,19 +1995,12 @@ func (s *ProjectProcurementService) calculateFinancialOverview(project *models.P
 		Budget: project.Budget,
 	}
 
-	// Calculate committed (orders placed)
-	var committedSum float64
-	if project.BillOfMaterials != nil {
-		s.db.Model(&models.PurchaseOrder{}).
-			Select("COALESCE(SUM(purchase_orders.quantity * quotes.converted_price), 0)").
-			Joins("JOIN quotes ON quotes.id = purchase_orders.quote_id").
-			Joins("JOIN products ON products.id = quotes.product_id").
-			Joins("JOIN bill_of_materials_items ON bill_of_materials_items.specification_id = products.specification_id").
-			Where("bill_of_materials_items.bill_of_materials_id = ?", project.BillOfMaterials.ID).
-			Where("purchase_orders.status NOT IN (?)", []string{"cancelled"}).
-			Scan(&committedSum)
+	// Calculate committed (the project's orders, in USD)
+	committed, err := projectCommitted(s.db, project.ID)
+	if err != nil {
+		return financial, err
 	}
-	financial.Committed = committedSum
+	financial.Committed = committed
 
 	// Calculate estimated (best quotes for remaining items)
 	if project.BillOfMaterials != nil {
@@ -2079,33 +2086,24 @@ func (s *ProjectProcurementService) calculateProcurementStatus(project *models.P
 	}
 	status.ItemsWithQuotes = itemsWithQuotes
 
-	// Items ordered and received
-	for _, bomItem := range project.BillOfMaterials.Items {
-		if bomItem.SpecificationID == 0 {
-			continue
-		}
-
-		var orderCount int64
-		s.db.Model(&models.PurchaseOrder{}).
-			Joins("JOIN quotes ON quotes.id = purchase_orders.quote_id").
-			Joins("JOIN products ON products.id = quotes.product_id").
-			Where("products.specification_id = ?", bomItem.SpecificationID).
-			Where("purchase_orders.status NOT IN (?)", []string{"cancelled"}).
-			Count(&orderCount)
-
-		if orderCount > 0 {
-			status.ItemsOrdered++
+	// Items ordered and received, from the procurement status of the project
+	// requisition items, which follows their purchase orders
+	ordered := make(map[uint]bool)
+	outstanding := make(map[uint]bool)
+	for _, req := range project.Requisitions {
+		for _, item := range req.Items {
+			switch item.ProcurementStatus {
+			case "ordered":
+				ordered[item.BillOfMaterialsItemID] = true
+				outstanding[item.BillOfMaterialsItemID] = true
+			case "received":
+				ordered[item.BillOfMaterialsItemID] = true
+			}
 		}
-
-		var receivedCount int64
-		s.db.Model(&models.PurchaseOrder{}).
-			Joins("JOIN quotes ON quotes.id = purchase_orders.quote_id").
-			Joins("JOIN products ON products.id = quotes.product_id").
-			Where("products.specification_id = ?", bomItem.SpecificationID).
-			Where("purchase_orders.status = ?", "received").
-			Count(&receivedCount)
-
-		if receivedCount > 0 {
+	}
+	for bomItemID := range ordered {
+		status.ItemsOrdered++
+		if !outstanding[bomItemID] {
 			status.ItemsReceived++
 		}
 	}
@@ -2529,7 +2527,9 @@ func (s *ProjectProcurementService) calculateBOMItemsByValue(projectID uint) ([]
 
 				// Calculate value: use PO price if available, else selected quote, else best available quote
 				var unitPrice float64
-				if item.SelectedQuote != nil {
+				if item.ActualUnitPrice > 0 {
+					unitPrice = item.ActualUnitPrice
+				} else if item.SelectedQuote != nil {
 					unitPrice = item.SelectedQuote.ConvertedPrice
 				} else if item.TargetUnitPrice > 0 {
 					unitPrice = item.TargetUnitPrice
@@ -1485,6 +1485,7 @@ func TestProjectProcurementService_CalculateFinancialOverview(t *testing.T) {
 	// Create purchase order for 5 units
 	cfg.DB.Create(&models.PurchaseOrder{
 		PONumber:    "PO-FIN-001",
+		ProjectID:   &project.ID,
 		QuoteID:     quote.ID,
 		VendorID:    vendor.ID,
 		ProductID:   product.ID,
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"strings"
 
@@ -18,6 +19,11 @@ func NewProjectRequisitionService(db *gorm.DB) *ProjectRequisitionService {
 	return &ProjectRequisitionService{db: db}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *ProjectRequisitionService) WithContext(ctx context.Context) *ProjectRequisitionService {
+	return NewProjectRequisitionService(s.db.WithContext(ctx))
+}
+
 // ProjectRequisitionItemInput represents input for creating a project requisition item
 type ProjectRequisitionItemInput struct {
 	BOMItemID         uint
@@ -138,7 +144,8 @@ func (s *ProjectRequisitionService) GetByID(id uint) (*models.ProjectRequisition
 // List retrieves all project requisitions with optional pagination
 func (s *ProjectRequisitionService) List(limit, offset int) ([]models.ProjectRequisition, error) {
 	var requisitions []models.ProjectRequisition
-	query := s.db.Preload("Items.BOMItem.Specification").
+	query := s.db.Where("project_id IN (?)", s.liveProjects()).
+		Preload("Items.BOMItem.Specification").
 		Preload("Project").
 		Order("created_at DESC")
 
@@ -187,6 +194,9 @@ func (s *ProjectRequisitionService) Update(id uint, name, justification string,
 	if err != nil {
 		return nil, err
 	}
+	if err := requireEditable(requisition.Status); err != nil {
+		return nil, err
+	}
 
 	// Update fields
 	updates := map[string]interface{}{
@@ -227,6 +237,9 @@ func (s *ProjectRequisitionService) AddItem(requisitionID, bomItemID uint, quant
 	if err != nil {
 		return nil, err
 	}
+	if err := requireEditable(requisition.Status); err != nil {
+		return nil, err
+	}
 
 	// Verify BOM item exists and belongs to the same project
 	var bomItem models.BillOfMaterialsItem
@@ -291,6 +304,10 @@ func (s *ProjectRequisitionService) UpdateItem(itemID uint, quantityRequested in
 		return nil, err
 	}
 
+	if err := s.requireEditable(item.ProjectRequisitionID); err != nil {
+		return nil, err
+	}
+
 	// Validate quantity
 	if quantityRequested > item.BOMItem.Quantity {
 		return nil, &ValidationError{Field: "quantity", Message: "quantity requested exceeds BOM item quantity"}
@@ -320,6 +337,9 @@ func (s *ProjectRequisitionService) DeleteItem(itemID uint) error {
 		}
 		return err
 	}
+	if err := s.requireEditable(item.ProjectRequisitionID); err != nil {
+		return err
+	}
 
 	if err := s.db.Delete(&item).Error; err != nil {
 		return err
@@ -331,8 +351,74 @@ func (s *ProjectRequisitionService) DeleteItem(itemID uint) error {
 // Count returns the total number of project requisitions
 func (s *ProjectRequisitionService) Count() (int64, error) {
 	var count int64
-	if err := s.db.Model(&models.ProjectRequisition{}).Count(&count).Error; err != nil {
+	if err := s.db.Model(&models.ProjectRequisition{}).Where("project_id IN (?)", s.liveProjects()).Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
 }
+
+// liveProjects selects the IDs of the projects that are not in the trash
+func (s *ProjectRequisitionService) liveProjects() *gorm.DB {
+	return s.db.Model(&models.Project{}).Select("id")
+}
+
+// requireEditable refuses changes to the items of a project requisition that
+// is under review or approved
+func (s *ProjectRequisitionService) requireEditable(requisitionID uint) error {
+	var requisition models.ProjectRequisition
+	if err := s.db.Select("id", "status").First(&requisition, requisitionID).Error; err != nil {
+		return err
+	}
+	return requireEditable(requisition.Status)
+}
+
+// ProjectRequisitionQuoteComparison holds the best quotes for the items of a
+// project requisition
+type ProjectRequisitionQuoteComparison struct {
+	Requisition        *models.ProjectRequisition
+	Items              []ProjectRequisitionItemQuotes
+	TotalEstimate      float64 // Sum of all best quote totals, in the base currency
+	AllItemsHaveQuotes bool
+}
+
+// GetQuoteComparison finds the best current quote for each item of a project
+// requisition and the best-case estimate of the whole requisition
+func (s *ProjectRequisitionService) GetQuoteComparison(requisitionID uint, quoteService *QuoteService) (*ProjectRequisitionQuoteComparison, error) {
+	requisition, err := s.GetByID(requisitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &ProjectRequisitionQuoteComparison{
+		Requisition:        requisition,
+		Items:              make([]ProjectRequisitionItemQuotes, 0, len(requisition.Items)),
+		AllItemsHaveQuotes: true,
+	}
+	for i := range requisition.Items {
+		item := &requisition.Items[i]
+		itemQuotes := ProjectRequisitionItemQuotes{
+			RequisitionItem: item,
+			RequisitionName: requisition.Name,
+			Quantity:        item.QuantityRequested,
+			TargetUnitPrice: item.TargetUnitPrice,
+			SelectedQuote:   item.SelectedQuote,
+			Status:          item.ProcurementStatus,
+		}
+		if item.BOMItem != nil {
+			quotes, err := quoteService.CompareQuotesForSpecification(item.BOMItem.SpecificationID)
+			if err != nil {
+				return nil, err
+			}
+			if len(quotes) > 0 {
+				itemQuotes.BestQuote = &quotes[0]
+				comparison.TotalEstimate += quotes[0].ConvertedPrice * float64(item.QuantityRequested)
+			}
+		}
+		if itemQuotes.BestQuote == nil {
+			comparison.AllItemsHaveQuotes = false
+		}
+		comparison.Items = append(comparison.Items, itemQuotes)
+	}
+
+	return comparison, nil
+}
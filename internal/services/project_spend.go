@@ -0,0 +1,239 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// BudgetAlertThresholds are the percentages of a project budget at which
+// committed spend raises an alert, in ascending order
+var BudgetAlertThresholds = []float64{80, 100}
+
+// Spend is what purchase orders cost, in the base currency (USD)
+type Spend struct {
+	Committed float64 // Orders that are not cancelled or rejected
+	Received  float64 // Received orders
+	Invoiced  float64 // Orders with an invoice number
+}
+
+// Open returns the committed spend not yet received
+func (s Spend) Open() float64 {
+	return s.Committed - s.Received
+}
+
+// add counts a purchase order
+func (s *Spend) add(po *models.PurchaseOrder) {
+	amount := purchaseOrderBaseAmount(po)
+	s.Committed += amount
+	if po.Status == "received" {
+		s.Received += amount
+	}
+	if po.InvoiceNumber != "" {
+		s.Invoiced += amount
+	}
+}
+
+// BOMItemSpend is the spend on one bill of materials item
+type BOMItemSpend struct {
+	Item   models.BillOfMaterialsItem
+	Orders int
+	Spend
+}
+
+// BurnDownPoint is the state of a project budget at the end of a day
+type BurnDownPoint struct {
+	Date      time.Time
+	Committed float64 // Committed spend so far
+	Received  float64 // Received spend so far
+	Remaining float64 // Budget less committed spend
+}
+
+// BudgetAlert is raised when committed spend reaches a threshold
+type BudgetAlert struct {
+	Threshold float64 // Highest of BudgetAlertThresholds reached
+	Percent   float64 // Committed spend as a percentage of the budget
+	Level     string  // warning, or critical from 100%
+}
+
+// Message describes the alert
+func (a *BudgetAlert) Message() string {
+	if a.Threshold >= 100 {
+		return fmt.Sprintf("Committed spend has reached the budget (%.0f%%)", a.Percent)
+	}
+	return fmt.Sprintf("Committed spend is %.0f%% of the budget (alert at %.0f%%)", a.Percent, a.Threshold)
+}
+
+// ProjectSpend compares a project's purchase orders with its budget
+type ProjectSpend struct {
+	Project *models.Project
+	Spend
+	Items       []BOMItemSpend  // One per bill of materials item
+	Unallocated Spend           // Orders for products outside the bill of materials
+	BurnDown    []BurnDownPoint // One point per day with orders or receipts
+	Alert       *BudgetAlert    // Nil below the first threshold or without a budget
+}
+
+// Remaining returns the budget not yet committed
+func (p *ProjectSpend) Remaining() float64 {
+	return p.Project.Budget - p.Committed
+}
+
+// PercentCommitted returns committed spend as a percentage of the budget, or
+// 0 without a budget
+func (p *ProjectSpend) PercentCommitted() float64 {
+	if p.Project.Budget <= 0 {
+		return 0
+	}
+	return p.Committed / p.Project.Budget * 100
+}
+
+// GetProjectSpend returns the committed, received and invoiced spend of a
+// project's purchase orders, in total and per bill of materials item. An
+// order counts for the BOM item of the project requisition item it
+// fulfils, or else for the BOM item of its product's specification.
+func (s *DashboardService) GetProjectSpend(projectID uint) (*ProjectSpend, error) {
+	var project models.Project
+	if err := s.db.Preload("BillOfMaterials.Items.Specification").First(&project, projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &NotFoundError{Entity: "Project", ID: projectID}
+		}
+		return nil, err
+	}
+
+	var orders []models.PurchaseOrder
+	if err := s.db.Preload("Quote", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Product", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("ProjectRequisitionItem").
+		Where("project_id = ? AND status NOT IN ?", projectID, []string{"cancelled", "rejected"}).
+		Order("order_date, id").
+		Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	report := &ProjectSpend{Project: &project}
+	byID := map[uint]int{}
+	bySpec := map[uint]int{}
+	if project.BillOfMaterials != nil {
+		for i, item := range project.BillOfMaterials.Items {
+			report.Items = append(report.Items, BOMItemSpend{Item: item})
+			byID[item.ID] = i
+			bySpec[item.SpecificationID] = i
+		}
+	}
+
+	type dayChange struct{ committed, received float64 }
+	days := map[time.Time]*dayChange{}
+	change := func(at time.Time) *dayChange {
+		day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+		if days[day] == nil {
+			days[day] = &dayChange{}
+		}
+		return days[day]
+	}
+
+	for i := range orders {
+		po := &orders[i]
+		report.add(po)
+
+		index, found := -1, false
+		if po.ProjectRequisitionItem != nil {
+			index, found = byID[po.ProjectRequisitionItem.BillOfMaterialsItemID]
+		}
+		if !found && po.Product != nil && po.Product.SpecificationID != nil {
+			index, found = bySpec[*po.Product.SpecificationID]
+		}
+		if found {
+			report.Items[index].Orders++
+			report.Items[index].add(po)
+		} else {
+			report.Unallocated.add(po)
+		}
+
+		amount := purchaseOrderBaseAmount(po)
+		change(po.OrderDate).committed += amount
+		if po.Status == "received" {
+			receivedAt := po.UpdatedAt
+			if po.ActualDelivery != nil {
+				receivedAt = *po.ActualDelivery
+			}
+			change(receivedAt).received += amount
+		}
+	}
+
+	dates := make([]time.Time, 0, len(days))
+	for day := range days {
+		dates = append(dates, day)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	var point BurnDownPoint
+	for _, day := range dates {
+		point.Date = day
+		point.Committed += days[day].committed
+		point.Received += days[day].received
+		point.Remaining = project.Budget - point.Committed
+		report.BurnDown = append(report.BurnDown, point)
+	}
+
+	report.Alert = budgetAlert(project.Budget, report.Committed)
+	return report, nil
+}
+
+// budgetAlert returns the alert for committed spend against a budget, or nil
+func budgetAlert(budget, committed float64) *BudgetAlert {
+	if budget <= 0 {
+		return nil
+	}
+	var alert *BudgetAlert
+	for _, threshold := range BudgetAlertThresholds {
+		if committed < budget*threshold/100 {
+			break
+		}
+		alert = &BudgetAlert{Threshold: threshold, Percent: committed / budget * 100, Level: "warning"}
+		if threshold >= 100 {
+			alert.Level = "critical"
+		}
+	}
+	return alert
+}
+
+// projectCommitted returns the committed spend of a project's purchase
+// orders in the base currency
+func projectCommitted(db *gorm.DB, projectID uint) (float64, error) {
+	var committed float64
+	err := db.Model(&models.PurchaseOrder{}).
+		Joins("JOIN quotes ON quotes.id = purchase_orders.quote_id").
+		Where("purchase_orders.project_id = ? AND purchase_orders.status NOT IN ?", projectID, []string{"cancelled", "rejected"}).
+		Select("COALESCE(SUM(purchase_orders.grand_total * CASE WHEN quotes.conversion_rate > 0 THEN quotes.conversion_rate ELSE 1 END), 0)").
+		Scan(&committed).Error
+	return committed, err
+}
+
+// notifyBudgetAlert tells the users who manage projects when a new purchase
+// order takes its project's committed spend past an alert threshold
+func notifyBudgetAlert(db *gorm.DB, po *models.PurchaseOrder, project *models.Project) error {
+	if project == nil || project.Budget <= 0 {
+		return nil
+	}
+	after, err := projectCommitted(db, project.ID)
+	if err != nil {
+		return err
+	}
+	alert := budgetAlert(project.Budget, after)
+	if alert == nil {
+		return nil
+	}
+	if before := budgetAlert(project.Budget, after-purchaseOrderBaseAmount(po)); before != nil && before.Threshold == alert.Threshold {
+		return nil
+	}
+	managers, err := usersWithPermission(db, PermProjectsWrite)
+	if err != nil {
+		return err
+	}
+	return notify(db, managers, fmt.Sprintf("Project %s: %s after %s", project.Name, alert.Message(), po.PONumber),
+		fmt.Sprintf("/projects/%d/dashboard", project.ID))
+}
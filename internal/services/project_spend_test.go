@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDashboardService_GetProjectSpend(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	laptop, _ := NewSpecificationService(cfg.DB).Create("Laptop", "")
+	monitor, _ := NewSpecificationService(cfg.DB).Create("Monitor", "")
+	cable, _ := NewSpecificationService(cfg.DB).Create("Cable", "")
+	vendor, _ := NewVendorService(cfg.DB).Create("Acme", "EUR", "")
+	brand, _ := NewBrandService(cfg.DB).Create("Generic")
+	products := NewProductService(cfg.DB)
+	laptopProduct, _ := products.Create("Laptop 14", brand.ID, &laptop.ID)
+	monitorProduct, _ := products.Create("Monitor 27", brand.ID, &monitor.ID)
+	cableProduct, _ := products.Create("HDMI Cable", brand.ID, &cable.ID)
+	if _, err := NewForexService(cfg.DB).Create("EUR", "USD", 1.5, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	quotes := NewQuoteService(cfg.DB)
+	newQuote := func(productID uint, price float64, currency string) uint {
+		quote, err := quotes.Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: productID, Price: price, Currency: currency})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return quote.ID
+	}
+	laptopQuote := newQuote(laptopProduct.ID, 1000, "EUR")
+	monitorQuote := newQuote(monitorProduct.ID, 200, "USD")
+	cableQuote := newQuote(cableProduct.ID, 10, "USD")
+
+	projects := NewProjectService(cfg.DB)
+	project, _ := projects.Create("Office", "", 5000, nil)
+	laptopItem, _ := projects.AddBillOfMaterialsItem(project.ID, laptop.ID, 2, "")
+	monitorItem, _ := projects.AddBillOfMaterialsItem(project.ID, monitor.ID, 4, "")
+	if _, err := NewUserService(cfg.DB).Create(CreateUserInput{Username: "pm", Role: RoleBuyer, Password: "Correct-Horse-Battery-9"}); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewPurchaseOrderService(cfg.DB)
+	order := func(quoteID uint, quantity int) uint {
+		t.Helper()
+		po, err := svc.Create(CreatePurchaseOrderInput{QuoteID: quoteID, ProjectID: &project.ID, Quantity: quantity})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return po.ID
+	}
+	laptops := order(laptopQuote, 2)   // 3000 USD
+	monitors := order(monitorQuote, 4) // 800 USD
+	order(cableQuote, 3)               // 30 USD, not on the bill of materials
+	cancelled := order(cableQuote, 1)
+	if _, err := svc.UpdateStatus(cancelled, "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range []string{"approved", "ordered", "received"} {
+		if _, err := svc.UpdateStatus(laptops, status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := svc.UpdateInvoiceNumber(monitors, "INV-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	dashboard := NewDashboardService(cfg.DB)
+	spend, err := dashboard.GetProjectSpend(project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spend.Committed != 3830 || spend.Received != 3000 || spend.Invoiced != 800 || spend.Open() != 830 || spend.Remaining() != 1170 {
+		t.Errorf("Expected 3830 committed, 3000 received and 800 invoiced, got %+v", spend.Spend)
+	}
+	if len(spend.Items) != 2 || spend.Items[0].Item.ID != laptopItem.ID || spend.Items[0].Received != 3000 ||
+		spend.Items[1].Item.ID != monitorItem.ID || spend.Items[1].Committed != 800 || spend.Items[1].Orders != 1 {
+		t.Errorf("Expected spend per BOM item, got %+v", spend.Items)
+	}
+	if spend.Unallocated.Committed != 30 {
+		t.Errorf("Expected the cables unallocated, got %+v", spend.Unallocated)
+	}
+	if len(spend.BurnDown) != 1 || spend.BurnDown[0].Remaining != 1170 || spend.BurnDown[0].Received != 3000 {
+		t.Errorf("Expected one burn-down point for today, got %+v", spend.BurnDown)
+	}
+	if spend.Alert != nil {
+		t.Errorf("Expected no alert at 77%%, got %+v", spend.Alert)
+	}
+
+	// Crossing 80% and then 100% of the budget raises an alert and notifies
+	// the project managers once per threshold
+	notifications := NewNotificationService(cfg.DB)
+	order(monitorQuote, 1)
+	if spend, _ = dashboard.GetProjectSpend(project.ID); spend.Alert == nil || spend.Alert.Threshold != 80 || spend.Alert.Level != "warning" {
+		t.Errorf("Expected an 80%% warning, got %+v", spend.Alert)
+	}
+	order(cableQuote, 1)
+	order(laptopQuote, 1)
+	if spend, _ = dashboard.GetProjectSpend(project.ID); spend.Alert == nil || spend.Alert.Level != "critical" || spend.PercentCommitted() < 100 {
+		t.Errorf("Expected a critical alert over budget, got %+v", spend.Alert)
+	}
+	list, err := notifications.List("pm", false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || !strings.Contains(list[0].Message, "reached the budget") || !strings.Contains(list[1].Message, "alert at 80%") {
+		t.Errorf("Expected an 80%% and a 100%% notification, got %+v", list)
+	}
+
+	var notFound *NotFoundError
+	if _, err := dashboard.GetProjectSpend(9999); !errors.As(err, &notFound) {
+		t.Errorf("Expected a NotFoundError, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -12,6 +13,9 @@ import (
 // PurchaseOrderService handles business logic for purchase orders
 type PurchaseOrderService struct {
 	db *gorm.DB
+	// poNumberPattern numbers orders created without a PO number; empty is
+	// DefaultPONumberPattern
+	poNumberPattern string
 }
 
 // NewPurchaseOrderService creates a new purchase order service
@@ -19,38 +23,63 @@ func NewPurchaseOrderService(db *gorm.DB) *PurchaseOrderService {
 	return &PurchaseOrderService{db: db}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *PurchaseOrderService) WithContext(ctx context.Context) *PurchaseOrderService {
+	return &PurchaseOrderService{db: s.db.WithContext(ctx), poNumberPattern: s.poNumberPattern}
+}
+
+// WithPONumberPattern returns a copy of the service that numbers orders
+// created without a PO number by pattern (see ParsePONumberPattern)
+func (s *PurchaseOrderService) WithPONumberPattern(pattern string) *PurchaseOrderService {
+	return &PurchaseOrderService{db: s.db, poNumberPattern: pattern}
+}
+
 // CreatePurchaseOrderInput represents input for creating a purchase order
 type CreatePurchaseOrderInput struct {
-	QuoteID          uint
-	RequisitionID    *uint
-	PONumber         string
-	Quantity         int
-	ExpectedDelivery *time.Time
-	ShippingCost     float64
-	Tax              float64
-	Notes            string
+	QuoteID           uint
+	RequisitionID     *uint
+	RequisitionItemID *uint // Requisition line the order fulfils; requires RequisitionID
+	// ProjectRequisitionItemID is the project requisition line the order
+	// fulfils; the order then belongs to the line's project
+	ProjectRequisitionItemID *uint
+	ProjectID                *uint
+	PONumber                 string
+	Quantity                 int
+	ExpectedDelivery         *time.Time
+	ShippingCost             float64
+	Tax                      float64
+	Notes                    string
+	// CostCenterID and GLAccountID override the coding the order would take
+	// from its requisition or project
+	CostCenterID *uint
+	GLAccountID  *uint
 }
 
-// Create creates a new purchase order from a quote
+// Create creates a new purchase order from a quote. Without a PO number it
+// gets the next number of the service's PO number pattern.
 func (s *PurchaseOrderService) Create(input CreatePurchaseOrderInput) (*models.PurchaseOrder, error) {
-	// Validate PONumber
-	poNumber := strings.TrimSpace(input.PONumber)
-	if poNumber == "" {
-		return nil, &ValidationError{Field: "po_number", Message: "PO number cannot be empty"}
-	}
-
 	// Validate quantity
 	if input.Quantity <= 0 {
 		return nil, &ValidationError{Field: "quantity", Message: "quantity must be greater than zero"}
 	}
 
-	// Check if PO number already exists
-	var existing models.PurchaseOrder
-	err := s.db.Where("po_number = ?", poNumber).First(&existing).Error
-	if err == nil {
-		return nil, &DuplicateError{Entity: "purchase order", Name: poNumber}
-	} else if err != gorm.ErrRecordNotFound {
-		return nil, err
+	// Without a PO number one is generated from the pattern; check it first
+	poNumber := strings.TrimSpace(input.PONumber)
+	var pattern *PONumberPattern
+	if poNumber == "" {
+		var err error
+		if pattern, err = ParsePONumberPattern(s.poNumberPattern); err != nil {
+			return nil, err
+		}
+	} else {
+		// Check if PO number already exists
+		var existing models.PurchaseOrder
+		err := s.db.Unscoped().Where("po_number = ?", poNumber).First(&existing).Error
+		if err == nil {
+			return nil, &DuplicateError{Entity: "purchase order", Name: poNumber, InTrash: existing.DeletedAt.Valid}
+		} else if err != gorm.ErrRecordNotFound {
+			return nil, err
+		}
 	}
 
 	// Get the quote
@@ -71,34 +100,128 @@ func (s *PurchaseOrderService) Create(input CreatePurchaseOrderInput) (*models.P
 			}
 			return nil, err
 		}
+		if status := requisitionStatus(req.Status); status != RequisitionApproved {
+			return nil, &ValidationError{
+				Field:   "requisition_id",
+				Message: fmt.Sprintf("requisition %q is %s; only approved requisitions can be ordered", req.Name, status),
+			}
+		}
+	}
+
+	// Validate requisition item if provided
+	if input.RequisitionItemID != nil {
+		var item models.RequisitionItem
+		if err := s.db.First(&item, *input.RequisitionItemID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, &NotFoundError{Entity: "requisition item", ID: *input.RequisitionItemID}
+			}
+			return nil, err
+		}
+		if input.RequisitionID == nil || item.RequisitionID != *input.RequisitionID {
+			return nil, &ValidationError{Field: "requisition_item_id", Message: "requisition item must belong to the purchase order's requisition"}
+		}
+	}
+
+	// Validate project requisition item if provided
+	if input.ProjectRequisitionItemID != nil {
+		var item models.ProjectRequisitionItem
+		if err := s.db.Preload("ProjectRequisition").Preload("BOMItem").First(&item, *input.ProjectRequisitionItemID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, &NotFoundError{Entity: "project requisition item", ID: *input.ProjectRequisitionItemID}
+			}
+			return nil, err
+		}
+		if status := requisitionStatus(item.ProjectRequisition.Status); status != RequisitionApproved {
+			return nil, &ValidationError{
+				Field:   "project_requisition_item_id",
+				Message: fmt.Sprintf("project requisition %q is %s; only approved requisitions can be ordered", item.ProjectRequisition.Name, status),
+			}
+		}
+		if quote.Product == nil || quote.Product.SpecificationID == nil || item.BOMItem == nil || *quote.Product.SpecificationID != item.BOMItem.SpecificationID {
+			return nil, &ValidationError{Field: "quote_id", Message: "quote is not for the specification of the project requisition item"}
+		}
+		if input.ProjectID == nil {
+			input.ProjectID = &item.ProjectRequisition.ProjectID
+		} else if *input.ProjectID != item.ProjectRequisition.ProjectID {
+			return nil, &ValidationError{Field: "project_id", Message: "project requisition item belongs to another project"}
+		}
+	}
+
+	// Validate project if provided
+	var project *models.Project
+	if input.ProjectID != nil {
+		project = &models.Project{}
+		if err := s.db.First(project, *input.ProjectID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, &NotFoundError{Entity: "project", ID: *input.ProjectID}
+			}
+			return nil, err
+		}
+	}
+
+	// Validate coding overrides if provided
+	if input.CostCenterID != nil {
+		if err := checkCodeActive(s.db, "cost_center_id", *input.CostCenterID); err != nil {
+			return nil, err
+		}
+	}
+	if input.GLAccountID != nil {
+		if err := checkCodeActive(s.db, "gl_account_id", *input.GLAccountID); err != nil {
+			return nil, err
+		}
 	}
 
 	// Create purchase order from quote
 	po := &models.PurchaseOrder{
-		QuoteID:          quote.ID,
-		VendorID:         quote.VendorID,
-		ProductID:        quote.ProductID,
-		RequisitionID:    input.RequisitionID,
-		PONumber:         poNumber,
-		Status:           "pending",  // Will be set by BeforeCreate hook if empty
-		OrderDate:        time.Now(), // Will be set by BeforeCreate hook if zero
-		ExpectedDelivery: input.ExpectedDelivery,
-		Quantity:         input.Quantity,
-		UnitPrice:        quote.Price,
-		Currency:         quote.Currency,
-		TotalAmount:      quote.Price * float64(input.Quantity),
-		ShippingCost:     input.ShippingCost,
-		Tax:              input.Tax,
-		Notes:            input.Notes,
+		QuoteID:                  quote.ID,
+		VendorID:                 quote.VendorID,
+		ProductID:                quote.ProductID,
+		RequisitionID:            input.RequisitionID,
+		RequisitionItemID:        input.RequisitionItemID,
+		ProjectRequisitionItemID: input.ProjectRequisitionItemID,
+		ProjectID:                input.ProjectID,
+		PONumber:                 poNumber,
+		Status:                   "pending",  // Will be set by BeforeCreate hook if empty
+		OrderDate:                time.Now(), // Will be set by BeforeCreate hook if zero
+		ExpectedDelivery:         input.ExpectedDelivery,
+		Quantity:                 input.Quantity,
+		UnitPrice:                quote.Price,
+		Currency:                 quote.Currency,
+		TotalAmount:              quote.Price * float64(input.Quantity),
+		ShippingCost:             input.ShippingCost,
+		Tax:                      input.Tax,
+		Notes:                    input.Notes,
+		CostCenterID:             input.CostCenterID,
+		GLAccountID:              input.GLAccountID,
+	}
+	if err := applyDefaultCoding(s.db, po); err != nil {
+		return nil, err
 	}
 
 	// GrandTotal will be calculated by BeforeCreate hook
-	if err := s.db.Create(po).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if pattern != nil {
+			number, err := nextPONumber(tx, pattern, PONumberValues{Date: po.OrderDate, Project: project, Vendor: quote.Vendor})
+			if err != nil {
+				return err
+			}
+			po.PONumber = number
+		}
+		if err := tx.Create(po).Error; err != nil {
+			return err
+		}
+		po.Quote = &quote
+		if err := notifyBudgetAlert(tx, po, project); err != nil {
+			return err
+		}
+		return syncProjectRequisitionItem(tx, po.ProjectRequisitionItemID)
+	}); err != nil {
 		return nil, err
 	}
 
 	// Reload with associations
-	if err := s.db.Preload("Quote").Preload("Vendor").Preload("Product").Preload("Requisition").First(po, po.ID).Error; err != nil {
+	if err := s.db.Preload("Quote").Preload("Vendor").Preload("Product").Preload("Requisition").Preload("Project").
+		Preload("CostCenter").Preload("GLAccount").First(po, po.ID).Error; err != nil {
 		return nil, err
 	}
 
@@ -109,7 +232,8 @@ func (s *PurchaseOrderService) Create(input CreatePurchaseOrderInput) (*models.P
 func (s *PurchaseOrderService) GetByID(id uint) (*models.PurchaseOrder, error) {
 	var po models.PurchaseOrder
 	if err := s.db.Preload("Quote").Preload("Vendor").Preload("Product").
-		Preload("Requisition").Preload("VendorRatings").First(&po, id).Error; err != nil {
+		Preload("Requisition").Preload("Project").Preload("VendorRatings").
+		Preload("CostCenter").Preload("GLAccount").First(&po, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, &NotFoundError{Entity: "purchase order", ID: id}
 		}
@@ -220,12 +344,16 @@ func (s *PurchaseOrderService) ListByVendor(vendorID uint, limit, offset int) ([
 	return orders, nil
 }
 
-// UpdateStatus updates the status of a purchase order
+// UpdateStatus updates the status of a purchase order. A pending order that
+// the approval policy applies to cannot move on until every approval step is
+// approved (see ApprovalService), nor until it is coded to a cost center and
+// GL account once those exist.
 func (s *PurchaseOrderService) UpdateStatus(id uint, status string) (*models.PurchaseOrder, error) {
 	// Validate status
 	validStatuses := map[string]bool{
 		"pending":   true,
 		"approved":  true,
+		"rejected":  true,
 		"ordered":   true,
 		"shipped":   true,
 		"received":  true,
@@ -242,9 +370,20 @@ func (s *PurchaseOrderService) UpdateStatus(id uint, status string) (*models.Pur
 		}
 		return nil, err
 	}
+	if err := checkApprovals(s.db, &po, status); err != nil {
+		return nil, err
+	}
+	if err := checkCoding(s.db, &po, status); err != nil {
+		return nil, err
+	}
 
 	po.Status = status
-	if err := s.db.Save(&po).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&po).Error; err != nil {
+			return err
+		}
+		return syncProjectRequisitionItem(tx, po.ProjectRequisitionItemID)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -273,11 +412,22 @@ func (s *PurchaseOrderService) UpdateDeliveryDates(id uint, expectedDelivery, ac
 		po.ActualDelivery = actualDelivery
 		// Automatically set status to received if actual delivery is set
 		if po.Status != "received" && po.Status != "cancelled" {
+			if err := checkApprovals(s.db, &po, "received"); err != nil {
+				return nil, err
+			}
+			if err := checkCoding(s.db, &po, "received"); err != nil {
+				return nil, err
+			}
 			po.Status = "received"
 		}
 	}
 
-	if err := s.db.Save(&po).Error; err != nil {
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&po).Error; err != nil {
+			return err
+		}
+		return syncProjectRequisitionItem(tx, po.ProjectRequisitionItemID)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -289,7 +439,8 @@ func (s *PurchaseOrderService) UpdateDeliveryDates(id uint, expectedDelivery, ac
 	return &po, nil
 }
 
-// UpdateInvoiceNumber updates the invoice number of a purchase order
+// UpdateInvoiceNumber updates the invoice number of a purchase order and
+// dates the invoice when the number is first set or changed
 func (s *PurchaseOrderService) UpdateInvoiceNumber(id uint, invoiceNumber string) (*models.PurchaseOrder, error) {
 	invoiceNumber = strings.TrimSpace(invoiceNumber)
 
@@ -301,6 +452,13 @@ func (s *PurchaseOrderService) UpdateInvoiceNumber(id uint, invoiceNumber string
 		return nil, err
 	}
 
+	// The invoice date dates the invoice for accounting exports
+	if invoiceNumber == "" {
+		po.InvoiceDate = nil
+	} else if invoiceNumber != po.InvoiceNumber || po.InvoiceDate == nil {
+		now := time.Now()
+		po.InvoiceDate = &now
+	}
 	po.InvoiceNumber = invoiceNumber
 	if err := s.db.Save(&po).Error; err != nil {
 		return nil, err
@@ -314,7 +472,7 @@ func (s *PurchaseOrderService) UpdateInvoiceNumber(id uint, invoiceNumber string
 	return &po, nil
 }
 
-// Delete deletes a purchase order
+// Delete moves a pending, rejected or cancelled purchase order to the trash
 func (s *PurchaseOrderService) Delete(id uint) error {
 	var po models.PurchaseOrder
 	if err := s.db.First(&po, id).Error; err != nil {
@@ -324,15 +482,20 @@ func (s *PurchaseOrderService) Delete(id uint) error {
 		return err
 	}
 
-	// Only allow deletion of pending or cancelled orders
-	if po.Status != "pending" && po.Status != "cancelled" {
+	// Only allow deletion of pending, rejected or cancelled orders
+	if po.Status != "pending" && po.Status != "rejected" && po.Status != "cancelled" {
 		return &ValidationError{
 			Field:   "status",
 			Message: fmt.Sprintf("cannot delete purchase order with status: %s", po.Status),
 		}
 	}
 
-	return s.db.Delete(&po).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := moveToTrash(tx, "purchase_order", id); err != nil {
+			return err
+		}
+		return syncProjectRequisitionItem(tx, po.ProjectRequisitionItemID)
+	})
 }
 
 // Count returns the total number of purchase orders
@@ -359,3 +522,63 @@ func (s *PurchaseOrderService) loadDocuments(po *models.PurchaseOrder) {
 	s.db.Where("entity_type = ? AND entity_id = ?", "purchase_order", po.ID).Find(&docs)
 	po.Documents = docs
 }
+
+// syncProjectRequisitionItem brings the procurement status, actual unit price
+// and selected quote of a project requisition item in line with its live
+// purchase orders. The item is received once all of them are received and
+// ordered while any is not; without orders it goes back to quoted or pending.
+// A cancelled item is left alone.
+func syncProjectRequisitionItem(db *gorm.DB, itemID *uint) error {
+	if itemID == nil {
+		return nil
+	}
+	var item models.ProjectRequisitionItem
+	if err := db.First(&item, *itemID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+	if item.ProcurementStatus == "cancelled" {
+		return nil
+	}
+
+	var orders []models.PurchaseOrder
+	if err := db.Preload("Quote", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("project_requisition_item_id = ?", item.ID).
+		Where("status NOT IN (?)", []string{"cancelled", "rejected"}).
+		Order("id").
+		Find(&orders).Error; err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{}
+	if len(orders) == 0 {
+		status := "pending"
+		if item.SelectedQuoteID != nil {
+			status = "quoted"
+		}
+		updates["procurement_status"] = status
+		updates["actual_unit_price"] = 0
+	} else {
+		status := "received"
+		quantity, cost := 0, 0.0
+		for _, po := range orders {
+			if po.Status != "received" {
+				status = "ordered"
+			}
+			unitPrice := po.UnitPrice
+			if po.Quote != nil && po.Quote.ConvertedPrice > 0 {
+				unitPrice = po.Quote.ConvertedPrice
+			}
+			quantity += po.Quantity
+			cost += unitPrice * float64(po.Quantity)
+		}
+		updates["procurement_status"] = status
+		updates["actual_unit_price"] = cost / float64(quantity)
+		if item.SelectedQuoteID == nil {
+			updates["selected_quote_id"] = orders[0].QuoteID
+		}
+	}
+	return db.Model(&item).Updates(updates).Error
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+	"github.com/shakfu/buyer/internal/pdf"
+	"gorm.io/gorm"
+)
+
+// CompanyDetails is the buyer's company as printed on purchase orders
+type CompanyDetails struct {
+	PostalAddress
+	Phone string
+	Email string
+	TaxID string
+}
+
+// Lines returns the name and address as printed, leaving out empty lines
+func (a PostalAddress) Lines() []string {
+	return append(nonEmpty(a.Name), a.AddressLines()...)
+}
+
+// AddressLines returns the address without the name, leaving out empty
+// lines
+func (a PostalAddress) AddressLines() []string {
+	city := strings.TrimSpace(strings.Join(nonEmpty(a.City, strings.TrimSpace(a.State+" "+a.PostalCode)), ", "))
+	return nonEmpty(a.Street1, a.Street2, city, a.Country)
+}
+
+// nonEmpty returns the values that are not blank
+func nonEmpty(values ...string) []string {
+	var out []string
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			out = append(out, strings.TrimSpace(value))
+		}
+	}
+	return out
+}
+
+// PurchaseOrderPDFOptions sets what purchase order PDFs print besides the
+// order
+type PurchaseOrderPDFOptions struct {
+	Company CompanyDetails
+	ShipTo  PostalAddress // Left out when empty
+	// Template is the layout, a text/template executed with a
+	// PurchaseOrderDocument that produces pdf.Render markup;
+	// DefaultPurchaseOrderTemplate when empty
+	Template string
+}
+
+// PurchaseOrderDocument is the data of purchase order templates
+type PurchaseOrderDocument struct {
+	Company       CompanyDetails
+	ShipTo        PostalAddress
+	Order         *models.PurchaseOrder
+	Vendor        *models.Vendor
+	VendorAddress PostalAddress
+	Lines         []PurchaseOrderDocumentLine
+	Subtotal      float64
+	Shipping      float64
+	Tax           float64
+	Total         float64
+	PaymentTerms  string // The vendor's
+}
+
+// PurchaseOrderDocumentLine is one line of a printed purchase order
+type PurchaseOrderDocumentLine struct {
+	Number      int
+	SKU         string
+	Description string
+	Quantity    int
+	UnitPrice   float64
+	Amount      float64
+}
+
+// DefaultPurchaseOrderTemplate is the layout of purchase order PDFs
+const DefaultPurchaseOrderTemplate = `@title Purchase Order {{.Order.PONumber}}
+@footer Purchase Order {{.Order.PONumber}} - Page {page} of {pages}
+{{with .Company.Name}}# {{.}}
+{{end}}{{range .Company.AddressLines}}{{.}}
+{{end}}{{with .Company.Phone}}Phone: {{.}}
+{{end}}{{with .Company.Email}}Email: {{.}}
+{{end}}{{with .Company.TaxID}}Tax ID: {{.}}
+{{end}}
+---
+## PURCHASE ORDER {{.Order.PONumber}}
+@columns 110 *
+| Order date | {{date .Order.OrderDate}} |
+{{with .Order.ExpectedDelivery}}| Deliver by | {{date .}} |
+{{end}}| Payment terms | {{or .PaymentTerms "As agreed"}} |
+| Currency | {{.Order.Currency}} |
+
+@columns * *
+| **Vendor** | {{if .ShipTo.Lines}}**Ship To**{{end}} |
+{{range zip .VendorAddress.Lines .ShipTo.Lines}}| {{index . 0}} | {{index . 1}} |
+{{end}}{{with .Vendor.TaxID}}| Tax ID: {{.}} | |
+{{end}}
+@columns 25 80 * 40r 80r 80r
+| **#** | **SKU** | **Description** | **Qty** | **Unit Price** | **Amount** |
+---
+{{range .Lines}}| {{.Number}} | {{.SKU}} | {{.Description}} | {{.Quantity}} | {{money .UnitPrice}} | {{money .Amount}} |
+{{end}}---
+@columns * 80r
+| Subtotal | {{money .Subtotal}} |
+| Shipping | {{money .Shipping}} |
+| Tax | {{money .Tax}} |
+| **Total ({{.Order.Currency}})** | **{{money .Total}}** |
+{{with .Order.Notes}}
+## Notes
+{{.}}
+{{end}}`
+
+// purchaseOrderTemplateFuncs are the functions of purchase order templates
+var purchaseOrderTemplateFuncs = template.FuncMap{
+	// money formats an amount with thousands separators and two decimals
+	"money": func(amount float64) string {
+		s := strconv.FormatFloat(roundCents(amount), 'f', 2, 64)
+		sign := ""
+		if strings.HasPrefix(s, "-") {
+			sign, s = "-", s[1:]
+		}
+		whole, cents, _ := strings.Cut(s, ".")
+		for i := len(whole) - 3; i > 0; i -= 3 {
+			whole = whole[:i] + "," + whole[i:]
+		}
+		return sign + whole + "." + cents
+	},
+	// date formats a time or a non-nil time pointer as YYYY-MM-DD
+	"date": func(t interface{}) string {
+		switch t := t.(type) {
+		case time.Time:
+			return t.Format("2006-01-02")
+		case *time.Time:
+			if t != nil {
+				return t.Format("2006-01-02")
+			}
+		}
+		return ""
+	},
+	// zip pairs up two lists of lines, padding the shorter with blanks
+	"zip": func(a, b []string) [][]string {
+		var rows [][]string
+		for i := 0; i < len(a) || i < len(b); i++ {
+			row := []string{"", ""}
+			if i < len(a) {
+				row[0] = a[i]
+			}
+			if i < len(b) {
+				row[1] = b[i]
+			}
+			rows = append(rows, row)
+		}
+		return rows
+	},
+}
+
+// ParsePurchaseOrderTemplate parses a purchase order layout template
+func ParsePurchaseOrderTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("purchase-order").Funcs(purchaseOrderTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, &ValidationError{Field: "template", Message: err.Error()}
+	}
+	return tmpl, nil
+}
+
+// PurchaseOrderPDFService prints purchase orders as PDF documents for
+// vendors and keeps them as documents of the order
+type PurchaseOrderPDFService struct {
+	db *gorm.DB
+}
+
+// NewPurchaseOrderPDFService creates a new purchase order PDF service
+func NewPurchaseOrderPDFService(db *gorm.DB) *PurchaseOrderPDFService {
+	return &PurchaseOrderPDFService{db: db}
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *PurchaseOrderPDFService) WithContext(ctx context.Context) *PurchaseOrderPDFService {
+	return NewPurchaseOrderPDFService(s.db.WithContext(ctx))
+}
+
+// document loads a purchase order as template data
+func (s *PurchaseOrderPDFService) document(id uint, opts PurchaseOrderPDFOptions) (*PurchaseOrderDocument, error) {
+	var po models.PurchaseOrder
+	if err := s.db.Preload("Vendor", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Product", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Product.Brand", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		First(&po, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &NotFoundError{Entity: "purchase order", ID: id}
+		}
+		return nil, err
+	}
+
+	doc := &PurchaseOrderDocument{
+		Company:  opts.Company,
+		ShipTo:   opts.ShipTo,
+		Order:    &po,
+		Vendor:   po.Vendor,
+		Subtotal: po.TotalAmount,
+		Shipping: po.ShippingCost,
+		Tax:      po.Tax,
+		Total:    po.GrandTotal,
+	}
+	if doc.Vendor == nil {
+		doc.Vendor = &models.Vendor{}
+	}
+	doc.VendorAddress = PostalAddress{
+		Name:       doc.Vendor.Name,
+		Street1:    doc.Vendor.AddressLine1,
+		Street2:    doc.Vendor.AddressLine2,
+		City:       doc.Vendor.City,
+		State:      doc.Vendor.State,
+		PostalCode: doc.Vendor.PostalCode,
+		Country:    doc.Vendor.Country,
+	}
+	doc.PaymentTerms = doc.Vendor.PaymentTerms
+
+	line := PurchaseOrderDocumentLine{Number: 1, Quantity: po.Quantity, UnitPrice: po.UnitPrice, Amount: po.TotalAmount}
+	if po.Product != nil {
+		line.Description = po.Product.Name
+		if po.Product.Brand != nil {
+			line.Description = po.Product.Brand.Name + " " + po.Product.Name
+		}
+		if po.Product.SKU != nil {
+			line.SKU = *po.Product.SKU
+		}
+	}
+	doc.Lines = []PurchaseOrderDocumentLine{line}
+	return doc, nil
+}
+
+// WritePDF prints a purchase order as a PDF to w
+func (s *PurchaseOrderPDFService) WritePDF(w io.Writer, id uint, opts PurchaseOrderPDFOptions) error {
+	text := opts.Template
+	if text == "" {
+		text = DefaultPurchaseOrderTemplate
+	}
+	tmpl, err := ParsePurchaseOrderTemplate(text)
+	if err != nil {
+		return err
+	}
+	doc, err := s.document(id, opts)
+	if err != nil {
+		return err
+	}
+
+	var markup bytes.Buffer
+	if err := tmpl.Execute(&markup, doc); err != nil {
+		return &ValidationError{Field: "template", Message: err.Error()}
+	}
+	if err := pdf.Render(w, markup.String()); err != nil {
+		return &ValidationError{Field: "template", Message: err.Error()}
+	}
+	return nil
+}
+
+// SavePDF prints a purchase order as a PDF and uploads it with docs, which
+// must have storage, as a document of the order named after the PO number.
+// Each call stores a new file, so earlier copies stay as they were sent. It
+// returns the document and the PDF.
+func (s *PurchaseOrderPDFService) SavePDF(id uint, docs *DocumentService, opts PurchaseOrderPDFOptions) (*models.Document, []byte, error) {
+	var buf bytes.Buffer
+	if err := s.WritePDF(&buf, id, opts); err != nil {
+		return nil, nil, err
+	}
+	var po models.PurchaseOrder
+	if err := s.db.Select("id", "po_number").First(&po, id).Error; err != nil {
+		return nil, nil, err
+	}
+
+	document, err := docs.WithContext(s.db.Statement.Context).Upload(CreateDocumentInput{
+		EntityType:  "purchase_order",
+		EntityID:    id,
+		FileName:    po.PONumber + ".pdf",
+		FileType:    "pdf",
+		FileSize:    int64(buf.Len()),
+		Description: "Purchase order " + po.PONumber,
+		UploadedBy:  models.ActorFrom(s.db.Statement.Context),
+	}, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return document, buf.Bytes(), nil
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/shakfu/buyer/internal/storage"
+)
+
+// pdfText returns the decompressed page contents of a PDF
+func pdfText(t *testing.T, data []byte) string {
+	t.Helper()
+	var text strings.Builder
+	for _, stream := range regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindAllSubmatch(data, -1) {
+		r, err := zlib.NewReader(bytes.NewReader(stream[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(r)
+		text.Write(content)
+	}
+	return text.String()
+}
+
+func TestPurchaseOrderPDF(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	vendor, _ := NewVendorService(cfg.DB).Create("Acme Distribution", "USD", "")
+	cfg.DB.Model(vendor).Updates(map[string]interface{}{"address_line1": "1 Depot Rd", "city": "Reno", "state": "NV", "postal_code": "89501", "country": "US", "payment_terms": "Net 30"})
+	brand, _ := NewBrandService(cfg.DB).Create("Apple")
+	product, _ := NewProductService(cfg.DB).Create("MacBook", brand.ID, nil)
+	cfg.DB.Model(product).Update("sku", "MBP-14")
+	quote, err := NewQuoteService(cfg.DB).Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 1299.5, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	po, err := NewPurchaseOrderService(cfg.DB).Create(CreatePurchaseOrderInput{QuoteID: quote.ID, PONumber: "PO-850", Quantity: 2, ShippingCost: 25, Tax: 80.25, Notes: "Deliver to dock 4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewPurchaseOrderPDFService(cfg.DB)
+	opts := PurchaseOrderPDFOptions{
+		Company: CompanyDetails{PostalAddress: PostalAddress{Name: "Buyer Co", Street1: "9 Main St", City: "Austin", State: "TX", PostalCode: "78701"}, Phone: "555-0100"},
+		ShipTo:  PostalAddress{Name: "Buyer Co Warehouse", City: "Austin"},
+	}
+	var buf bytes.Buffer
+	if err := svc.WritePDF(&buf, po.ID, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Fatalf("Expected a PDF, got %q", buf.Bytes()[:10])
+	}
+	text := pdfText(t, buf.Bytes())
+	for _, want := range []string{
+		"(Buyer Co) Tj", "(Austin, TX 78701) Tj", "(Phone: 555-0100) Tj",
+		"(PURCHASE ORDER PO-850) Tj", "(Net 30) Tj",
+		"(Acme Distribution) Tj", "(Reno, NV 89501) Tj", "(Buyer Co Warehouse) Tj",
+		"(MBP-14) Tj", "(Apple MacBook) Tj", "(1,299.50) Tj", "(2,599.00) Tj",
+		"(25.00) Tj", "(80.25) Tj", "(Total \\(USD\\)) Tj", "(2,704.25) Tj",
+		"(Deliver to dock 4) Tj", "(Purchase Order PO-850 - Page 1 of 1) Tj",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected the PDF to contain %q, got:\n%s", want, text)
+		}
+	}
+
+	// A custom layout
+	opts.Template = "@page a4\n# Order {{.Order.PONumber}} for {{.Vendor.Name}}\n"
+	buf.Reset()
+	if err := svc.WritePDF(&buf, po.ID, opts); err != nil {
+		t.Fatal(err)
+	}
+	if text := pdfText(t, buf.Bytes()); !strings.Contains(text, "(Order PO-850 for Acme Distribution) Tj") || !strings.Contains(buf.String(), "595.28") {
+		t.Errorf("Expected the custom A4 layout, got:\n%s", text)
+	}
+
+	var validationErr *ValidationError
+	for _, bad := range []string{"{{.Order.PONumber", "{{.Nope}}", "@colour red"} {
+		opts.Template = bad
+		if err := svc.WritePDF(io.Discard, po.ID, opts); !errors.As(err, &validationErr) {
+			t.Errorf("Expected a ValidationError for template %q, got %v", bad, err)
+		}
+	}
+	opts.Template = ""
+	var notFoundErr *NotFoundError
+	if err := svc.WritePDF(io.Discard, 999, opts); !errors.As(err, &notFoundErr) {
+		t.Errorf("Expected a NotFoundError, got %v", err)
+	}
+
+	// Saved PDFs are documents of the order
+	backend, err := storage.NewLocalBackend(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := NewDocumentService(cfg.DB).WithStorage(backend)
+	doc, data, err := svc.SavePDF(po.ID, docs, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.EntityType != "purchase_order" || doc.EntityID != po.ID || doc.FileName != "PO-850.pdf" || doc.FileType != "pdf" || doc.FileSize != int64(len(data)) {
+		t.Errorf("Unexpected document %+v", doc)
+	}
+	reader, _, err := docs.Download(doc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(stored, data) {
+		t.Errorf("Expected the PDF stored at %s", doc.FilePath)
+	}
+	if docs, _ := NewDocumentService(cfg.DB).ListByEntity("purchase_order", po.ID); len(docs) != 1 {
+		t.Errorf("Expected one document of the order, got %d", len(docs))
+	}
+}
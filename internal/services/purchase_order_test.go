@@ -16,25 +16,7 @@ func setupPurchaseOrderTestDB(t *testing.T) *config.Config {
 	}
 
 	// Run migrations for all models
-	if err := cfg.AutoMigrate(
-		&models.Vendor{},
-		&models.Brand{},
-		&models.Specification{},
-		&models.SpecificationAttribute{},
-		&models.Product{},
-		&models.ProductAttribute{},
-		&models.Quote{},
-		&models.Forex{},
-		&models.Requisition{},
-		&models.RequisitionItem{},
-		&models.Project{},
-		&models.BillOfMaterials{},
-		&models.BillOfMaterialsItem{},
-		&models.ProjectRequisition{},
-		&models.PurchaseOrder{},
-		&models.Document{},
-		&models.VendorRating{},
-	); err != nil {
+	if err := cfg.AutoMigrate(models.All()...); err != nil {
 		t.Fatalf("Failed to run migrations: %v", err)
 	}
 
@@ -68,6 +50,8 @@ func TestPurchaseOrderService_Create(t *testing.T) {
 
 	reqSvc := NewRequisitionService(cfg.DB)
 	req, _ := reqSvc.Create("Test Requisition", "", 0, []RequisitionItemInput{})
+	cfg.DB.Model(req).Update("status", RequisitionApproved)
+	draft, _ := reqSvc.Create("Draft Requisition", "", 0, []RequisitionItemInput{})
 
 	poSvc := NewPurchaseOrderService(cfg.DB)
 
@@ -98,26 +82,6 @@ func TestPurchaseOrderService_Create(t *testing.T) {
 			},
 			wantErr: false,
 		},
-		{
-			name: "empty po number",
-			input: CreatePurchaseOrderInput{
-				QuoteID:  quote.ID,
-				PONumber: "",
-				Quantity: 5,
-			},
-			wantErr: true,
-			errType: "validation",
-		},
-		{
-			name: "whitespace po number",
-			input: CreatePurchaseOrderInput{
-				QuoteID:  quote.ID,
-				PONumber: "   ",
-				Quantity: 5,
-			},
-			wantErr: true,
-			errType: "validation",
-		},
 		{
 			name: "zero quantity",
 			input: CreatePurchaseOrderInput{
@@ -158,6 +122,17 @@ func TestPurchaseOrderService_Create(t *testing.T) {
 			wantErr: true,
 			errType: "not_found",
 		},
+		{
+			name: "requisition not approved",
+			input: CreatePurchaseOrderInput{
+				QuoteID:       quote.ID,
+				RequisitionID: &draft.ID,
+				PONumber:      "PO-007",
+				Quantity:      5,
+			},
+			wantErr: true,
+			errType: "validation",
+		},
 		{
 			name: "non-existent requisition",
 			input: CreatePurchaseOrderInput{
@@ -822,3 +797,113 @@ func TestPurchaseOrderService_ListByStatus(t *testing.T) {
 		t.Errorf("Got %d pending orders, want 1", len(pendingOrders))
 	}
 }
+
+func TestPurchaseOrderService_ProjectRequisitionItem(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	laptop, _ := NewSpecificationService(cfg.DB).Create("Laptop", "")
+	monitor, _ := NewSpecificationService(cfg.DB).Create("Monitor", "")
+	vendor, _ := NewVendorService(cfg.DB).Create("Acme", "USD", "")
+	brand, _ := NewBrandService(cfg.DB).Create("Generic")
+	laptopProduct, _ := NewProductService(cfg.DB).Create("Laptop 14", brand.ID, &laptop.ID)
+	monitorProduct, _ := NewProductService(cfg.DB).Create("Monitor 27", brand.ID, &monitor.ID)
+	quotes := NewQuoteService(cfg.DB)
+	cheap, _ := quotes.Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: laptopProduct.ID, Price: 100, Currency: "USD"})
+	dear, _ := quotes.Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: laptopProduct.ID, Price: 130, Currency: "USD"})
+	monitorQuote, _ := quotes.Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: monitorProduct.ID, Price: 50, Currency: "USD"})
+
+	projectSvc := NewProjectService(cfg.DB)
+	project, _ := projectSvc.Create("Office", "", 10000, nil)
+	bomItem, err := projectSvc.AddBillOfMaterialsItem(project.ID, laptop.ID, 5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectReq, err := NewProjectRequisitionService(cfg.DB).Create(project.ID, "Phase 1", "", 0,
+		[]ProjectRequisitionItemInput{{BOMItemID: bomItem.ID, QuantityRequested: 5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemID := projectReq.Items[0].ID
+	item := func() models.ProjectRequisitionItem {
+		var item models.ProjectRequisitionItem
+		if err := cfg.DB.First(&item, itemID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return item
+	}
+
+	svc := NewPurchaseOrderService(cfg.DB)
+	newPO := func(number string, quoteID uint, quantity int) (*models.PurchaseOrder, error) {
+		return svc.Create(CreatePurchaseOrderInput{QuoteID: quoteID, ProjectRequisitionItemID: &itemID, PONumber: number, Quantity: quantity})
+	}
+	if _, err := newPO("PO-DRAFT", cheap.ID, 3); err == nil {
+		t.Error("Expected an error ordering from a draft project requisition")
+	}
+	cfg.DB.Model(projectReq).Update("status", RequisitionApproved)
+	if _, err := newPO("PO-MONITOR", monitorQuote.ID, 3); err == nil {
+		t.Error("Expected an error ordering a quote of another specification")
+	}
+
+	first, err := newPO("PO-1", cheap.ID, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ProjectID == nil || *first.ProjectID != project.ID {
+		t.Errorf("Expected the order to belong to the project, got %v", first.ProjectID)
+	}
+	if got := item(); got.ProcurementStatus != "ordered" || got.ActualUnitPrice != 100 || got.SelectedQuoteID == nil || *got.SelectedQuoteID != cheap.ID {
+		t.Errorf("Expected the item ordered at 100, got %+v", got)
+	}
+
+	second, err := newPO("PO-2", dear.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := item(); got.ActualUnitPrice != 112 {
+		t.Errorf("Expected an average actual price of 112, got %.2f", got.ActualUnitPrice)
+	}
+
+	for _, status := range []string{"shipped", "received"} {
+		if _, err := svc.UpdateStatus(first.ID, status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := item(); got.ProcurementStatus != "ordered" {
+		t.Errorf("Expected the item still ordered while an order is open, got %s", got.ProcurementStatus)
+	}
+	if _, err := svc.UpdateStatus(second.ID, "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	if got := item(); got.ProcurementStatus != "received" || got.ActualUnitPrice != 100 {
+		t.Errorf("Expected the item received at 100 once the other order is cancelled, got %+v", got)
+	}
+
+	dashboard, err := NewProjectProcurementService(cfg.DB, quotes, projectSvc).GetProjectDashboard(project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dashboard.Procurement.ItemsOrdered != 1 || dashboard.Procurement.ItemsReceived != 1 ||
+		dashboard.Progress.OrdersPlaced != 1 || dashboard.Progress.OrdersReceived != 1 || dashboard.Progress.RequisitionsComplete != 1 {
+		t.Errorf("Expected the dashboard to follow the orders, got %+v %+v", dashboard.Procurement, dashboard.Progress)
+	}
+
+	if _, err := svc.UpdateStatus(first.ID, "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	if got := item(); got.ProcurementStatus != "quoted" || got.ActualUnitPrice != 0 {
+		t.Errorf("Expected the item back to quoted without orders, got %+v", got)
+	}
+
+	third, err := newPO("PO-3", cheap.ID, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivered := time.Now()
+	if _, err := svc.UpdateDeliveryDates(third.ID, nil, &delivered); err != nil {
+		t.Fatal(err)
+	}
+	if got := item(); got.ProcurementStatus != "received" {
+		t.Errorf("Expected the item received once the delivery is recorded, got %s", got.ProcurementStatus)
+	}
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	// DefaultListLimit is the page size used when a ListQuery has no limit
+	DefaultListLimit = 50
+	// MaxListLimit is the largest page size a ListQuery may ask for
+	MaxListLimit = 500
+)
+
+// Filter operators accepted by ListFilter
+const (
+	FilterEq   = "eq"
+	FilterNe   = "ne"
+	FilterGt   = "gt"
+	FilterGte  = "gte"
+	FilterLt   = "lt"
+	FilterLte  = "lte"
+	FilterLike = "like"
+	FilterIn   = "in"
+)
+
+var filterOperators = map[string]string{
+	FilterEq:  "=",
+	FilterNe:  "<>",
+	FilterGt:  ">",
+	FilterGte: ">=",
+	FilterLt:  "<",
+	FilterLte: "<=",
+}
+
+// ListFilter restricts a list to rows whose field matches a value. Field is the
+// JSON name of a model column.
+type ListFilter struct {
+	Field string
+	Op    string
+	Value string
+}
+
+// ListQuery describes one page of a filtered, sorted list
+type ListQuery struct {
+	Limit   int
+	Offset  int
+	Sort    string // Comma-separated JSON field names, "-" prefix for descending
+	Filters []ListFilter
+}
+
+// ListPage is one page of results and the number of rows matching the filters
+type ListPage[T any] struct {
+	Items  []T   `json:"items"`
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+}
+
+var listSchemaCache = &sync.Map{}
+
+// QueryList returns a page of models of type T. Filters and sort keys are
+// checked against the model's columns, so unknown fields are a ValidationError
+// rather than raw SQL. Preloads name associations to load for each item.
+func QueryList[T any](db *gorm.DB, q ListQuery, preloads ...string) (*ListPage[T], error) {
+	var model T
+	sch, err := schema.Parse(&model, listSchemaCache, db.NamingStrategy)
+	if err != nil {
+		return nil, err
+	}
+	columns := listColumns(sch)
+
+	if q.Limit <= 0 {
+		q.Limit = DefaultListLimit
+	}
+	if q.Limit > MaxListLimit {
+		return nil, &ValidationError{Field: "limit", Message: fmt.Sprintf("limit must be at most %d", MaxListLimit)}
+	}
+	if q.Offset < 0 {
+		return nil, &ValidationError{Field: "offset", Message: "offset must not be negative"}
+	}
+
+	query := db.Model(&model)
+	for _, filter := range q.Filters {
+		field, ok := columns[filter.Field]
+		if !ok {
+			return nil, &ValidationError{Field: filter.Field, Message: "unknown filter field"}
+		}
+		if query, err = applyListFilter(query, field, filter); err != nil {
+			return nil, err
+		}
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	order, err := listOrder(q.Sort, columns)
+	if err != nil {
+		return nil, err
+	}
+	for _, preload := range preloads {
+		query = query.Preload(preload)
+	}
+
+	items := []T{}
+	if err := query.Order(order).Limit(q.Limit).Offset(q.Offset).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return &ListPage[T]{Items: items, Total: total, Limit: q.Limit, Offset: q.Offset}, nil
+}
+
+// listColumns maps the JSON names of a model's columns to their fields
+func listColumns(sch *schema.Schema) map[string]*schema.Field {
+	columns := make(map[string]*schema.Field)
+	for _, field := range sch.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.DBName
+		}
+		columns[name] = field
+	}
+	return columns
+}
+
+// applyListFilter adds one filter condition to a query
+func applyListFilter(query *gorm.DB, field *schema.Field, filter ListFilter) (*gorm.DB, error) {
+	column := query.Statement.Quote(field.DBName)
+	op := filter.Op
+	if op == "" {
+		op = FilterEq
+	}
+
+	switch op {
+	case FilterLike:
+		return query.Where("LOWER("+column+") LIKE ?", "%"+strings.ToLower(filter.Value)+"%"), nil
+	case FilterIn:
+		var values []interface{}
+		for _, raw := range strings.Split(filter.Value, ",") {
+			value, err := parseListValue(field, filter.Field, strings.TrimSpace(raw))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return query.Where(column+" IN ?", values), nil
+	}
+
+	sqlOp, ok := filterOperators[op]
+	if !ok {
+		return nil, &ValidationError{Field: filter.Field, Message: fmt.Sprintf("unknown filter operator '%s'", op)}
+	}
+	if filter.Value == "null" && (op == FilterEq || op == FilterNe) {
+		if op == FilterEq {
+			return query.Where(column + " IS NULL"), nil
+		}
+		return query.Where(column + " IS NOT NULL"), nil
+	}
+	value, err := parseListValue(field, filter.Field, filter.Value)
+	if err != nil {
+		return nil, err
+	}
+	return query.Where(column+" "+sqlOp+" ?", value), nil
+}
+
+// parseListValue converts a filter value to the column's type
+func parseListValue(field *schema.Field, name, raw string) (interface{}, error) {
+	invalid := func(kind string) error {
+		return &ValidationError{Field: name, Message: fmt.Sprintf("'%s' is not a valid %s", raw, kind)}
+	}
+
+	switch field.DataType {
+	case schema.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, invalid("boolean")
+		}
+		return value, nil
+	case schema.Int, schema.Uint:
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, invalid("integer")
+		}
+		return value, nil
+	case schema.Float:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, invalid("number")
+		}
+		return value, nil
+	case schema.Time:
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if value, err := time.Parse(layout, raw); err == nil {
+				return value, nil
+			}
+		}
+		return nil, invalid("date (YYYY-MM-DD or RFC 3339)")
+	}
+	return raw, nil
+}
+
+// listOrder builds an ORDER BY clause from a sort parameter, defaulting to id
+func listOrder(sort string, columns map[string]*schema.Field) (string, error) {
+	var parts []string
+	for _, key := range strings.Split(sort, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		direction := "ASC"
+		if strings.HasPrefix(key, "-") {
+			direction = "DESC"
+			key = key[1:]
+		}
+		field, ok := columns[key]
+		if !ok {
+			return "", &ValidationError{Field: "sort", Message: fmt.Sprintf("cannot sort by unknown field '%s'", key)}
+		}
+		parts = append(parts, field.DBName+" "+direction)
+	}
+	if len(parts) == 0 {
+		return "id ASC", nil
+	}
+	// Break ties by id so that pages are stable
+	if _, ok := columns["id"]; ok {
+		parts = append(parts, "id ASC")
+	}
+	return strings.Join(parts, ", "), nil
+}
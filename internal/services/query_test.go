@@ -0,0 +1,90 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+)
+
+func TestQueryList(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	brand, _ := NewBrandService(cfg.DB).Create("Acme")
+	productSvc := NewProductService(cfg.DB)
+	for _, name := range []string{"Widget", "Gadget", "Gizmo"} {
+		if _, err := productSvc.Create(name, brand.ID, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg.DB.Model(&models.Product{}).Where("name = ?", "Gizmo").Update("is_active", false)
+
+	forexSvc := NewForexService(cfg.DB)
+	_, _ = forexSvc.Create("EUR", "USD", 1.1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	_, _ = forexSvc.Create("GBP", "USD", 1.3, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	t.Run("sort, limit and offset", func(t *testing.T) {
+		page, err := QueryList[models.Product](cfg.DB, ListQuery{Sort: "-name", Limit: 2, Offset: 1}, "Brand")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 3 || len(page.Items) != 2 || page.Items[0].Name != "Gizmo" || page.Items[1].Name != "Gadget" {
+			t.Errorf("Expected Gizmo, Gadget of 3, got %+v", page)
+		}
+		if page.Items[0].Brand == nil || page.Items[0].Brand.Name != "Acme" {
+			t.Error("Expected Brand to be preloaded")
+		}
+	})
+
+	t.Run("typed filters", func(t *testing.T) {
+		tests := []struct {
+			filter ListFilter
+			want   int64
+		}{
+			{ListFilter{Field: "is_active", Value: "false"}, 1},
+			{ListFilter{Field: "name", Op: FilterLike, Value: "GI"}, 1},
+			{ListFilter{Field: "name", Op: FilterIn, Value: "Widget, Gizmo"}, 2},
+			{ListFilter{Field: "specification_id", Value: "null"}, 3},
+			{ListFilter{Field: "brand_id", Op: FilterNe, Value: "1"}, 0},
+		}
+		for _, tt := range tests {
+			page, err := QueryList[models.Product](cfg.DB, ListQuery{Filters: []ListFilter{tt.filter}})
+			if err != nil {
+				t.Errorf("%+v: %v", tt.filter, err)
+				continue
+			}
+			if page.Total != tt.want {
+				t.Errorf("%+v: expected %d, got %d", tt.filter, tt.want, page.Total)
+			}
+		}
+
+		page, err := QueryList[models.Forex](cfg.DB, ListQuery{Filters: []ListFilter{
+			{Field: "effective_date", Op: FilterGte, Value: "2024-03-01"},
+			{Field: "rate", Op: FilterGt, Value: "1.2"},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 1 || page.Items[0].FromCurrency != "GBP" {
+			t.Errorf("Expected GBP rate, got %+v", page.Items)
+		}
+	})
+
+	t.Run("invalid queries", func(t *testing.T) {
+		for _, q := range []ListQuery{
+			{Sort: "colour"},
+			{Limit: MaxListLimit + 1},
+			{Filters: []ListFilter{{Field: "brand", Value: "1"}}},
+			{Filters: []ListFilter{{Field: "name", Op: "near", Value: "x"}}},
+			{Filters: []ListFilter{{Field: "min_order_qty", Value: "many"}}},
+		} {
+			_, err := QueryList[models.Product](cfg.DB, q)
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Errorf("%+v: expected ValidationError, got %v", q, err)
+			}
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -22,6 +23,11 @@ func NewQuoteService(db *gorm.DB) *QuoteService {
 	}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *QuoteService) WithContext(ctx context.Context) *QuoteService {
+	return NewQuoteService(s.db.WithContext(ctx))
+}
+
 // CreateQuoteInput holds the input for creating a quote
 type CreateQuoteInput struct {
 	VendorID   uint
@@ -94,6 +100,51 @@ func (s *QuoteService) Create(input CreateQuoteInput) (*models.Quote, error) {
 	return s.GetByID(quote.ID)
 }
 
+// UpdateQuoteInput holds the editable fields of a quote
+type UpdateQuoteInput struct {
+	Price      float64
+	Currency   string
+	ValidUntil *time.Time
+	Notes      string
+}
+
+// Update changes the price, validity and notes of a quote, converting the
+// price to USD again
+func (s *QuoteService) Update(id uint, input UpdateQuoteInput) (*models.Quote, error) {
+	if input.Price <= 0 {
+		return nil, &ValidationError{Field: "price", Message: "price must be positive"}
+	}
+
+	var quote models.Quote
+	if err := s.db.First(&quote, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &NotFoundError{Entity: "Quote", ID: id}
+		}
+		return nil, err
+	}
+
+	currency := input.Currency
+	if currency == "" {
+		currency = quote.Currency
+	}
+	convertedPrice, conversionRate, err := s.forexService.Convert(input.Price, currency, "USD")
+	if err != nil {
+		return nil, err
+	}
+
+	quote.Price = input.Price
+	quote.Currency = currency
+	quote.ConvertedPrice = convertedPrice
+	quote.ConversionRate = conversionRate
+	quote.ValidUntil = input.ValidUntil
+	quote.Notes = input.Notes
+	if err := s.db.Save(&quote).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(id)
+}
+
 // GetByID retrieves a quote by ID with preloaded relationships
 func (s *QuoteService) GetByID(id uint) (*models.Quote, error) {
 	var quote models.Quote
@@ -161,16 +212,9 @@ func (s *QuoteService) GetBestQuote(productID uint) (*models.Quote, error) {
 	return &quote, nil
 }
 
-// Delete deletes a quote by ID
+// Delete moves a quote to the trash
 func (s *QuoteService) Delete(id uint) error {
-	result := s.db.Delete(&models.Quote{}, id)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return &NotFoundError{Entity: "Quote", ID: id}
-	}
-	return nil
+	return moveToTrash(s.db, "quote", id)
 }
 
 // Count returns the total number of quotes
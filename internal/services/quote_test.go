@@ -1261,3 +1261,38 @@ func ptrFloat(f float64) *float64 {
 func ptrTime(t time.Time) *time.Time {
 	return &t
 }
+
+func TestQuoteService_Update(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	brand, _ := NewBrandService(cfg.DB).Create("Leica")
+	product, _ := NewProductService(cfg.DB).Create("Q3", brand.ID, nil)
+	vendor, _ := NewVendorService(cfg.DB).Create("Leica Store", "USD", "")
+	forexSvc := NewForexService(cfg.DB)
+	if _, err := forexSvc.Create("EUR", "USD", 1.2, time.Now()); err != nil {
+		t.Fatalf("Failed to create forex: %v", err)
+	}
+	quoteSvc := NewQuoteService(cfg.DB)
+
+	quote, err := quoteSvc.Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 5995, Currency: "USD"})
+	if err != nil {
+		t.Fatalf("Failed to create quote: %v", err)
+	}
+
+	validUntil := time.Now().AddDate(0, 1, 0)
+	updated, err := quoteSvc.Update(quote.ID, UpdateQuoteInput{Price: 5000, Currency: "EUR", ValidUntil: &validUntil, Notes: "revised"})
+	if err != nil {
+		t.Fatalf("Update() unexpected error = %v", err)
+	}
+	if updated.Price != 5000 || updated.Currency != "EUR" || updated.ConvertedPrice != 6000 || updated.Notes != "revised" || updated.ValidUntil == nil {
+		t.Errorf("Update() = %+v, want price 5000 EUR converted to 6000 USD", updated)
+	}
+
+	if _, err := quoteSvc.Update(quote.ID, UpdateQuoteInput{Price: 0}); err == nil {
+		t.Error("Update() with zero price should fail")
+	}
+	if _, err := quoteSvc.Update(9999, UpdateQuoteInput{Price: 1}); err == nil {
+		t.Error("Update() of missing quote should fail")
+	}
+}
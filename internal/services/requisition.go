@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"strings"
 
@@ -18,6 +19,11 @@ func NewRequisitionService(db *gorm.DB) *RequisitionService {
 	return &RequisitionService{db: db}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *RequisitionService) WithContext(ctx context.Context) *RequisitionService {
+	return NewRequisitionService(s.db.WithContext(ctx))
+}
+
 // RequisitionItemInput represents a requisition item input
 type RequisitionItemInput struct {
 	SpecificationID uint
@@ -41,8 +47,8 @@ func (s *RequisitionService) Create(name, justification string, budget float64,
 
 	// Check for duplicates
 	var existing models.Requisition
-	if err := s.db.Where("name = ?", name).First(&existing).Error; err == nil {
-		return nil, &DuplicateError{Entity: "Requisition", Name: name}
+	if err := s.db.Unscoped().Where("name = ?", name).First(&existing).Error; err == nil {
+		return nil, &DuplicateError{Entity: "Requisition", Name: name, InTrash: existing.DeletedAt.Valid}
 	}
 
 	// Validate each item (if any items are provided)
@@ -107,6 +113,7 @@ func (s *RequisitionService) Create(name, justification string, budget float64,
 func (s *RequisitionService) GetByID(id uint) (*models.Requisition, error) {
 	var requisition models.Requisition
 	err := s.db.Preload("Items.Specification").Preload("PurchaseOrders").
+		Preload("CostCenter").Preload("GLAccount").
 		First(&requisition, id).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, &NotFoundError{Entity: "Requisition", ID: id}
@@ -138,11 +145,14 @@ func (s *RequisitionService) Update(id uint, name, justification string, budget
 		}
 		return nil, err
 	}
+	if err := requireEditable(requisition.Status); err != nil {
+		return nil, err
+	}
 
 	// Check for duplicate name (excluding current record)
 	var existing models.Requisition
-	if err := s.db.Where("name = ? AND id != ?", name, id).First(&existing).Error; err == nil {
-		return nil, &DuplicateError{Entity: "Requisition", Name: name}
+	if err := s.db.Unscoped().Where("name = ? AND id != ?", name, id).First(&existing).Error; err == nil {
+		return nil, &DuplicateError{Entity: "Requisition", Name: name, InTrash: existing.DeletedAt.Valid}
 	}
 
 	requisition.Name = name
@@ -174,6 +184,9 @@ func (s *RequisitionService) AddItem(requisitionID, specificationID uint, quanti
 		}
 		return nil, err
 	}
+	if err := requireEditable(requisition.Status); err != nil {
+		return nil, err
+	}
 
 	// Verify specification exists
 	var spec models.Specification
@@ -223,6 +236,9 @@ func (s *RequisitionService) UpdateItem(itemID, specificationID uint, quantity i
 		}
 		return nil, err
 	}
+	if err := s.requireEditable(item.RequisitionID); err != nil {
+		return nil, err
+	}
 
 	// Verify specification exists
 	var spec models.Specification
@@ -253,37 +269,33 @@ func (s *RequisitionService) UpdateItem(itemID, specificationID uint, quantity i
 
 // DeleteItem removes an item from a requisition
 func (s *RequisitionService) DeleteItem(itemID uint) error {
-	result := s.db.Delete(&models.RequisitionItem{}, itemID)
-	if result.Error != nil {
-		return result.Error
+	var item models.RequisitionItem
+	if err := s.db.First(&item, itemID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &NotFoundError{Entity: "RequisitionItem", ID: itemID}
+		}
+		return err
 	}
-	if result.RowsAffected == 0 {
-		return &NotFoundError{Entity: "RequisitionItem", ID: itemID}
+	if err := s.requireEditable(item.RequisitionID); err != nil {
+		return err
 	}
-	return nil
+	return s.db.Delete(&item).Error
 }
 
-// Delete deletes a requisition by ID (cascades to items)
-func (s *RequisitionService) Delete(id uint) error {
-	err := s.db.Transaction(func(tx *gorm.DB) error {
-		// Delete all items first
-		if err := tx.Where("requisition_id = ?", id).Delete(&models.RequisitionItem{}).Error; err != nil {
-			return err
-		}
-
-		// Delete requisition
-		result := tx.Delete(&models.Requisition{}, id)
-		if result.Error != nil {
-			return result.Error
-		}
-		if result.RowsAffected == 0 {
-			return &NotFoundError{Entity: "Requisition", ID: id}
-		}
-
-		return nil
-	})
+// requireEditable refuses changes to the items of a requisition that is under
+// review or approved
+func (s *RequisitionService) requireEditable(requisitionID uint) error {
+	var requisition models.Requisition
+	if err := s.db.Select("id", "status").First(&requisition, requisitionID).Error; err != nil {
+		return err
+	}
+	return requireEditable(requisition.Status)
+}
 
-	return err
+// Delete moves a requisition to the trash. Its items are kept for a restore
+// and removed when the trash is purged.
+func (s *RequisitionService) Delete(id uint) error {
+	return moveToTrash(s.db, "requisition", id)
 }
 
 // List retrieves all requisitions with optional pagination
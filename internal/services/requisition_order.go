@@ -0,0 +1,225 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// RequisitionOrderInput selects what to order for a requisition
+type RequisitionOrderInput struct {
+	RequisitionID uint
+	// Quotes maps requisition item IDs to the quote to order them from.
+	// Items not listed are ordered from their best quote.
+	Quotes map[uint]uint
+	// ItemIDs limits the order to these requisition items; empty means all
+	ItemIDs          []uint
+	ExpectedDelivery *time.Time
+}
+
+// RequisitionOrderLine is a requisition item and the quote it is ordered from
+type RequisitionOrderLine struct {
+	Item          *models.RequisitionItem
+	Quote         *models.Quote
+	Total         float64               // Converted quote price * quantity, in USD
+	PurchaseOrder *models.PurchaseOrder // Set once the line is ordered
+}
+
+// VendorOrder groups the lines ordered from one vendor
+type VendorOrder struct {
+	Vendor *models.Vendor
+	Lines  []RequisitionOrderLine
+}
+
+// Total returns the value of the vendor's lines in USD
+func (o VendorOrder) Total() float64 {
+	total := 0.0
+	for _, line := range o.Lines {
+		total += line.Total
+	}
+	return total
+}
+
+// SkippedItem is a requisition item left out of an order, and why
+type SkippedItem struct {
+	Item   *models.RequisitionItem
+	Reason string
+}
+
+// RequisitionOrder is the result of converting a requisition to purchase
+// orders, grouped by vendor
+type RequisitionOrder struct {
+	Requisition *models.Requisition
+	Vendors     []VendorOrder
+	Skipped     []SkippedItem
+}
+
+// Total returns the value of every line in USD
+func (o *RequisitionOrder) Total() float64 {
+	total := 0.0
+	for _, vendor := range o.Vendors {
+		total += vendor.Total()
+	}
+	return total
+}
+
+// PurchaseOrders returns the purchase orders created, vendor by vendor
+func (o *RequisitionOrder) PurchaseOrders() []*models.PurchaseOrder {
+	var orders []*models.PurchaseOrder
+	for _, vendor := range o.Vendors {
+		for _, line := range vendor.Lines {
+			if line.PurchaseOrder != nil {
+				orders = append(orders, line.PurchaseOrder)
+			}
+		}
+	}
+	return orders
+}
+
+// PlanRequisitionOrder works out which quote each requisition item would be
+// ordered from, grouped by vendor, without creating anything. Items without
+// a valid quote, or already on an open purchase order, are skipped.
+func (s *PurchaseOrderService) PlanRequisitionOrder(input RequisitionOrderInput) (*RequisitionOrder, error) {
+	comparison, err := NewRequisitionService(s.db).GetQuoteComparison(input.RequisitionID, NewQuoteService(s.db))
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[uint]bool, len(input.ItemIDs))
+	for _, id := range input.ItemIDs {
+		selected[id] = true
+	}
+	itemIDs := make(map[uint]bool, len(comparison.ItemComparisons))
+	for _, itemComp := range comparison.ItemComparisons {
+		itemIDs[itemComp.Item.ID] = true
+	}
+	checkItem := func(id uint) error {
+		if itemIDs[id] {
+			return nil
+		}
+		return &ValidationError{
+			Field:   "item_id",
+			Message: fmt.Sprintf("item %d is not on requisition %q", id, comparison.Requisition.Name),
+		}
+	}
+	for id := range selected {
+		if err := checkItem(id); err != nil {
+			return nil, err
+		}
+	}
+	for id := range input.Quotes {
+		if err := checkItem(id); err != nil {
+			return nil, err
+		}
+	}
+
+	var ordered []models.PurchaseOrder
+	if err := s.db.Where("requisition_id = ? AND requisition_item_id IS NOT NULL", input.RequisitionID).
+		Where("status NOT IN (?)", []string{"cancelled", "rejected"}).
+		Find(&ordered).Error; err != nil {
+		return nil, err
+	}
+	onOrder := make(map[uint]string, len(ordered))
+	for _, po := range ordered {
+		onOrder[*po.RequisitionItemID] = po.PONumber
+	}
+
+	order := &RequisitionOrder{Requisition: comparison.Requisition}
+	byVendor := make(map[uint]int)
+	for _, itemComp := range comparison.ItemComparisons {
+		item := itemComp.Item
+		if len(selected) > 0 && !selected[item.ID] {
+			continue
+		}
+
+		quote := itemComp.BestQuote
+		if quoteID, ok := input.Quotes[item.ID]; ok {
+			quote = nil
+			for i := range itemComp.Quotes {
+				if itemComp.Quotes[i].ID == quoteID {
+					quote = &itemComp.Quotes[i]
+				}
+			}
+			if quote == nil {
+				return nil, &ValidationError{
+					Field:   "quote_id",
+					Message: fmt.Sprintf("quote %d is not a valid quote for item %d", quoteID, item.ID),
+				}
+			}
+		}
+		if number, ok := onOrder[item.ID]; ok {
+			order.Skipped = append(order.Skipped, SkippedItem{Item: item, Reason: "already on purchase order " + number})
+			continue
+		}
+		if quote == nil {
+			order.Skipped = append(order.Skipped, SkippedItem{Item: item, Reason: "no valid quotes"})
+			continue
+		}
+
+		i, ok := byVendor[quote.VendorID]
+		if !ok {
+			i = len(order.Vendors)
+			byVendor[quote.VendorID] = i
+			order.Vendors = append(order.Vendors, VendorOrder{Vendor: quote.Vendor})
+		}
+		order.Vendors[i].Lines = append(order.Vendors[i].Lines, RequisitionOrderLine{
+			Item:  item,
+			Quote: quote,
+			Total: quote.ConvertedPrice * float64(item.Quantity),
+		})
+	}
+
+	sort.SliceStable(order.Vendors, func(i, j int) bool {
+		return order.Vendors[i].Vendor.Name < order.Vendors[j].Vendor.Name
+	})
+	return order, nil
+}
+
+// OrderRequisition converts an approved requisition to purchase orders: one
+// per item, from the selected quote or else the best one, numbered by the
+// service's PO number pattern and linked to the requisition. All orders are created in one
+// transaction, so either every line is ordered or none is.
+func (s *PurchaseOrderService) OrderRequisition(input RequisitionOrderInput) (*RequisitionOrder, error) {
+	var order *RequisitionOrder
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		txSvc := &PurchaseOrderService{db: tx, poNumberPattern: s.poNumberPattern}
+		var err error
+		if order, err = txSvc.PlanRequisitionOrder(input); err != nil {
+			return err
+		}
+		if status := requisitionStatus(order.Requisition.Status); status != RequisitionApproved {
+			return &ValidationError{
+				Field:   "requisition_id",
+				Message: fmt.Sprintf("requisition %q is %s; only approved requisitions can be ordered", order.Requisition.Name, status),
+			}
+		}
+		if len(order.Vendors) == 0 {
+			return &ValidationError{Field: "requisition_id", Message: "nothing to order: every item is skipped"}
+		}
+
+		for v := range order.Vendors {
+			for l := range order.Vendors[v].Lines {
+				line := &order.Vendors[v].Lines[l]
+				po, err := txSvc.Create(CreatePurchaseOrderInput{
+					QuoteID:           line.Quote.ID,
+					RequisitionID:     &order.Requisition.ID,
+					RequisitionItemID: &line.Item.ID,
+					Quantity:          line.Item.Quantity,
+					ExpectedDelivery:  input.ExpectedDelivery,
+				})
+				if err != nil {
+					return fmt.Errorf("ordering item %d: %w", line.Item.ID, err)
+				}
+				line.PurchaseOrder = po
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPurchaseOrderService_OrderRequisition(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	laptop, _ := NewSpecificationService(cfg.DB).Create("Laptop", "")
+	monitor, _ := NewSpecificationService(cfg.DB).Create("Monitor", "")
+	dock, _ := NewSpecificationService(cfg.DB).Create("Dock", "")
+	acme, _ := NewVendorService(cfg.DB).Create("Acme", "USD", "")
+	zenith, _ := NewVendorService(cfg.DB).Create("Zenith", "USD", "")
+	brand, _ := NewBrandService(cfg.DB).Create("Generic")
+	products := NewProductService(cfg.DB)
+	laptopProduct, _ := products.Create("Laptop 14", brand.ID, &laptop.ID)
+	monitorProduct, _ := products.Create("Monitor 27", brand.ID, &monitor.ID)
+	products.Create("Dock USB-C", brand.ID, &dock.ID)
+
+	quotes := NewQuoteService(cfg.DB)
+	newQuote := func(vendorID, productID uint, price float64) uint {
+		quote, err := quotes.Create(CreateQuoteInput{VendorID: vendorID, ProductID: productID, Price: price, Currency: "USD"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return quote.ID
+	}
+	newQuote(acme.ID, laptopProduct.ID, 1000)
+	newQuote(zenith.ID, laptopProduct.ID, 1100)
+	zenithMonitor := newQuote(zenith.ID, monitorProduct.ID, 300)
+	acmeMonitor := newQuote(acme.ID, monitorProduct.ID, 280)
+
+	reqSvc := NewRequisitionService(cfg.DB)
+	req, err := reqSvc.Create("Office", "", 0, []RequisitionItemInput{
+		{SpecificationID: laptop.ID, Quantity: 2},
+		{SpecificationID: monitor.ID, Quantity: 3},
+		{SpecificationID: dock.ID, Quantity: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	laptopItem, monitorItem, dockItem := req.Items[0].ID, req.Items[1].ID, req.Items[2].ID
+
+	svc := NewPurchaseOrderService(cfg.DB)
+	var validationErr *ValidationError
+	input := RequisitionOrderInput{RequisitionID: req.ID, Quotes: map[uint]uint{monitorItem: zenithMonitor}}
+
+	// Only approved requisitions are ordered
+	if _, err := svc.OrderRequisition(input); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError ordering a draft requisition, got %v", err)
+	}
+	if count, _ := svc.Count(); count != 0 {
+		t.Errorf("Expected no purchase orders, got %d", count)
+	}
+	cfg.DB.Model(req).Update("status", RequisitionApproved)
+
+	plan, err := svc.PlanRequisitionOrder(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Vendors) != 2 || plan.Vendors[0].Vendor.Name != "Acme" || plan.Vendors[1].Vendor.Name != "Zenith" {
+		t.Fatalf("Expected lines for Acme and Zenith, got %+v", plan.Vendors)
+	}
+	if plan.Total() != 2900 || len(plan.Skipped) != 1 || plan.Skipped[0].Item.ID != dockItem {
+		t.Errorf("Expected 2900 planned and the dock skipped, got %.2f and %+v", plan.Total(), plan.Skipped)
+	}
+	if count, _ := svc.Count(); count != 0 {
+		t.Errorf("Expected planning not to create orders, got %d", count)
+	}
+
+	order, err := svc.OrderRequisition(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := order.PurchaseOrders()
+	year := time.Now().Year()
+	if len(pos) != 2 || pos[0].PONumber != fmt.Sprintf("PO-%d-00001", year) || pos[1].PONumber != fmt.Sprintf("PO-%d-00002", year) {
+		t.Fatalf("Expected two numbered orders, got %+v", pos)
+	}
+	if pos[0].VendorID != acme.ID || pos[0].Quantity != 2 || *pos[0].RequisitionID != req.ID || *pos[0].RequisitionItemID != laptopItem {
+		t.Errorf("Expected the laptops from Acme, got %+v", pos[0])
+	}
+	if pos[1].QuoteID != zenithMonitor || pos[1].TotalAmount != 900 {
+		t.Errorf("Expected the selected Zenith monitor quote, got %+v", pos[1])
+	}
+
+	// Ordered items are not ordered again until their order is cancelled
+	if _, err := svc.OrderRequisition(input); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError with nothing left to order, got %v", err)
+	}
+	if _, err := svc.UpdateStatus(pos[1].ID, "cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	order, err = svc.OrderRequisition(RequisitionOrderInput{RequisitionID: req.ID, ItemIDs: []uint{monitorItem}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos = order.PurchaseOrders(); len(pos) != 1 || pos[0].QuoteID != acmeMonitor || pos[0].PONumber != fmt.Sprintf("PO-%d-00003", year) {
+		t.Errorf("Expected the monitors reordered from the best quote, got %+v", pos)
+	}
+
+	// Selections must belong to the requisition
+	if _, err := svc.PlanRequisitionOrder(RequisitionOrderInput{RequisitionID: req.ID, ItemIDs: []uint{9999}}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for an unknown item, got %v", err)
+	}
+	if _, err := svc.PlanRequisitionOrder(RequisitionOrderInput{RequisitionID: req.ID, Quotes: map[uint]uint{laptopItem: acmeMonitor}}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for a quote of another specification, got %v", err)
+	}
+	other, _ := reqSvc.Create("Other", "", 0, []RequisitionItemInput{{SpecificationID: laptop.ID, Quantity: 1}})
+	if _, err := svc.Create(CreatePurchaseOrderInput{QuoteID: acmeMonitor, RequisitionID: &req.ID, RequisitionItemID: &other.Items[0].ID, PONumber: "PO-X", Quantity: 1}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for an item of another requisition, got %v", err)
+	}
+}
@@ -0,0 +1,456 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// Requisition statuses. A requisition is drafted, submitted for review and
+// then approved or rejected; a rejected requisition can be changed and
+// submitted again.
+const (
+	RequisitionDraft     = "draft"
+	RequisitionSubmitted = "submitted"
+	RequisitionApproved  = "approved"
+	RequisitionRejected  = "rejected"
+)
+
+// Kinds of requisition under review
+const (
+	KindRequisition        = "requisition"
+	KindProjectRequisition = "project-requisition"
+)
+
+// Actions recorded in RequisitionReview.Action
+const (
+	ReviewSubmitted = "submitted"
+	ReviewApproved  = "approved"
+	ReviewRejected  = "rejected"
+)
+
+// requisitionStatus returns a requisition's status, treating rows saved
+// before the review workflow as drafts
+func requisitionStatus(status string) string {
+	if status == "" {
+		return RequisitionDraft
+	}
+	return status
+}
+
+// requireEditable refuses changes to a requisition that is under review or
+// approved; a rejected requisition can be changed and submitted again
+func requireEditable(status string) error {
+	switch status = requisitionStatus(status); status {
+	case RequisitionSubmitted, RequisitionApproved:
+		return &ValidationError{Field: "status", Message: fmt.Sprintf("requisition is %s and can no longer be changed", status)}
+	}
+	return nil
+}
+
+// BudgetCheck compares the best-case estimate of a requisition with its
+// budget and, for a project requisition, with what remains of the project
+// budget. Amounts are in the base currency (USD).
+type BudgetCheck struct {
+	Estimate         float64 // Sum of the best current quote for each item
+	MissingQuotes    int     // Items without a current quote, left out of the estimate
+	Budget           float64 // Requisition budget; 0 when none is set
+	ProjectBudget    float64 // Project budget; 0 when none is set or not a project requisition
+	ProjectCommitted float64 // Approved estimates of the project's other requisitions
+}
+
+// ProjectRemaining returns what is left of the project budget before this
+// requisition
+func (c *BudgetCheck) ProjectRemaining() float64 {
+	return c.ProjectBudget - c.ProjectCommitted
+}
+
+// Problems describes each budget the estimate exceeds
+func (c *BudgetCheck) Problems() []string {
+	var problems []string
+	if c.Budget > 0 && c.Estimate > c.Budget {
+		problems = append(problems, fmt.Sprintf("best-case estimate %.2f exceeds the requisition budget %.2f", c.Estimate, c.Budget))
+	}
+	if c.ProjectBudget > 0 && c.Estimate > c.ProjectRemaining() {
+		problems = append(problems, fmt.Sprintf("best-case estimate %.2f exceeds the remaining project budget %.2f", c.Estimate, c.ProjectRemaining()))
+	}
+	return problems
+}
+
+// OK reports whether the estimate is within every budget
+func (c *BudgetCheck) OK() bool {
+	return len(c.Problems()) == 0
+}
+
+// ReviewedRequisition summarizes a requisition or project requisition and its
+// place in the review workflow
+type ReviewedRequisition struct {
+	Kind        string // KindRequisition or KindProjectRequisition
+	ID          uint
+	Name        string
+	ProjectID   uint   // 0 for requisitions
+	ProjectName string // Empty for requisitions
+	Status      string
+	SubmittedBy string
+	Items       int
+	Budget      float64
+	Check       *BudgetCheck
+}
+
+// Label describes the requisition in messages, e.g. `project requisition "Phase 1"`
+func (r *ReviewedRequisition) Label() string {
+	return fmt.Sprintf("%s %q", strings.ReplaceAll(r.Kind, "-", " "), r.Name)
+}
+
+// Link returns the web interface path of the requisition
+func (r *ReviewedRequisition) Link() string {
+	if r.Kind == KindProjectRequisition {
+		return fmt.Sprintf("/projects/%d", r.ProjectID)
+	}
+	return "/requisitions"
+}
+
+// RequisitionReviewService handles the review of requisitions and project
+// requisitions: submitting them, approving or rejecting them, and the budget
+// check that gates approval
+type RequisitionReviewService struct {
+	db *gorm.DB
+}
+
+// NewRequisitionReviewService creates a new requisition review service
+func NewRequisitionReviewService(db *gorm.DB) *RequisitionReviewService {
+	return &RequisitionReviewService{db: db}
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *RequisitionReviewService) WithContext(ctx context.Context) *RequisitionReviewService {
+	return NewRequisitionReviewService(s.db.WithContext(ctx))
+}
+
+// Submit submits a draft or rejected requisition for review and notifies the
+// users who may approve it
+func (s *RequisitionReviewService) Submit(kind string, id uint, submitter, comment string) (*ReviewedRequisition, error) {
+	submitter = strings.TrimSpace(submitter)
+	if submitter == "" {
+		return nil, &ValidationError{Field: "submitter", Message: "submitter cannot be empty"}
+	}
+
+	var reviewed *ReviewedRequisition
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if reviewed, err = loadReviewedRequisition(tx, kind, id); err != nil {
+			return err
+		}
+		if reviewed.Status != RequisitionDraft && reviewed.Status != RequisitionRejected {
+			return &ValidationError{Field: "status", Message: fmt.Sprintf("%s is %s; only draft or rejected requisitions can be submitted", reviewed.Label(), reviewed.Status)}
+		}
+		if reviewed.Items == 0 {
+			return &ValidationError{Field: "items", Message: fmt.Sprintf("%s has no items", reviewed.Label())}
+		}
+
+		if err := setReviewStatus(tx, reviewed, RequisitionSubmitted, map[string]interface{}{"submitted_by": submitter}); err != nil {
+			return err
+		}
+		reviewed.SubmittedBy = submitter
+		if err := tx.Create(newReview(reviewed, ReviewSubmitted, submitter, comment)).Error; err != nil {
+			return err
+		}
+
+		reviewers, err := usersWithPermission(tx, PermRequisitionsApprove)
+		if err != nil {
+			return err
+		}
+		reviewers = without(reviewers, submitter)
+		return notify(tx, reviewers, fmt.Sprintf("%s submitted %s for review", submitter, reviewed.Label()), "/requisition-reviews")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.withCheck(reviewed)
+}
+
+// Approve approves a submitted requisition and notifies its submitter. When the
+// best-case estimate exceeds the requisition budget or the remaining project
+// budget, approval needs an override reason.
+func (s *RequisitionReviewService) Approve(kind string, id uint, reviewer, comment, overrideReason string) (*ReviewedRequisition, error) {
+	reviewer = strings.TrimSpace(reviewer)
+	if reviewer == "" {
+		return nil, &ValidationError{Field: "reviewer", Message: "reviewer cannot be empty"}
+	}
+	overrideReason = strings.TrimSpace(overrideReason)
+
+	var reviewed *ReviewedRequisition
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if reviewed, err = s.loadSubmitted(tx, kind, id); err != nil {
+			return err
+		}
+		if reviewed.Check, err = checkBudget(tx, reviewed); err != nil {
+			return err
+		}
+		if problems := reviewed.Check.Problems(); len(problems) > 0 && overrideReason == "" {
+			return &ValidationError{Field: "budget", Message: strings.Join(problems, "; ") + "; give an override reason to approve anyway"}
+		}
+		if reviewed.Check.OK() {
+			overrideReason = ""
+		}
+
+		if err := setReviewStatus(tx, reviewed, RequisitionApproved, nil); err != nil {
+			return err
+		}
+		review := newReview(reviewed, ReviewApproved, reviewer, comment)
+		review.Estimate = reviewed.Check.Estimate
+		review.OverrideReason = overrideReason
+		if err := tx.Create(review).Error; err != nil {
+			return err
+		}
+		return notify(tx, without([]string{reviewed.SubmittedBy}, reviewer),
+			fmt.Sprintf("%s approved %s", reviewer, reviewed.Label()), reviewed.Link())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reviewed, nil
+}
+
+// Reject rejects a submitted requisition with a comment explaining why and
+// notifies its submitter
+func (s *RequisitionReviewService) Reject(kind string, id uint, reviewer, comment string) (*ReviewedRequisition, error) {
+	reviewer = strings.TrimSpace(reviewer)
+	if reviewer == "" {
+		return nil, &ValidationError{Field: "reviewer", Message: "reviewer cannot be empty"}
+	}
+	if strings.TrimSpace(comment) == "" {
+		return nil, &ValidationError{Field: "comment", Message: "a rejection needs a comment"}
+	}
+
+	var reviewed *ReviewedRequisition
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if reviewed, err = s.loadSubmitted(tx, kind, id); err != nil {
+			return err
+		}
+		if err := setReviewStatus(tx, reviewed, RequisitionRejected, nil); err != nil {
+			return err
+		}
+		if err := tx.Create(newReview(reviewed, ReviewRejected, reviewer, comment)).Error; err != nil {
+			return err
+		}
+		return notify(tx, without([]string{reviewed.SubmittedBy}, reviewer),
+			fmt.Sprintf("%s rejected %s: %s", reviewer, reviewed.Label(), strings.TrimSpace(comment)), reviewed.Link())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.withCheck(reviewed)
+}
+
+// Get returns a requisition's review status and budget check
+func (s *RequisitionReviewService) Get(kind string, id uint) (*ReviewedRequisition, error) {
+	reviewed, err := loadReviewedRequisition(s.db, kind, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.withCheck(reviewed)
+}
+
+// Queue returns the requisitions and project requisitions waiting for review,
+// oldest first, with their budget checks
+func (s *RequisitionReviewService) Queue() ([]ReviewedRequisition, error) {
+	var requisitions []models.Requisition
+	if err := s.db.Where("status = ?", RequisitionSubmitted).Order("updated_at, id").Find(&requisitions).Error; err != nil {
+		return nil, err
+	}
+	var projectRequisitions []models.ProjectRequisition
+	if err := s.db.Where("status = ? AND project_id IN (?)", RequisitionSubmitted, s.db.Model(&models.Project{}).Select("id")).
+		Order("updated_at, id").Find(&projectRequisitions).Error; err != nil {
+		return nil, err
+	}
+
+	queue := make([]ReviewedRequisition, 0, len(requisitions)+len(projectRequisitions))
+	for _, requisition := range requisitions {
+		reviewed, err := s.Get(KindRequisition, requisition.ID)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, *reviewed)
+	}
+	for _, requisition := range projectRequisitions {
+		reviewed, err := s.Get(KindProjectRequisition, requisition.ID)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, *reviewed)
+	}
+	return queue, nil
+}
+
+// History returns the reviews of a requisition, oldest first
+func (s *RequisitionReviewService) History(kind string, id uint) ([]models.RequisitionReview, error) {
+	if _, err := loadReviewedRequisition(s.db, kind, id); err != nil {
+		return nil, err
+	}
+	column := "requisition_id"
+	if kind == KindProjectRequisition {
+		column = "project_requisition_id"
+	}
+	var reviews []models.RequisitionReview
+	err := s.db.Where(column+" = ?", id).Order("created_at, id").Find(&reviews).Error
+	return reviews, err
+}
+
+// loadSubmitted loads a requisition that is waiting for review
+func (s *RequisitionReviewService) loadSubmitted(db *gorm.DB, kind string, id uint) (*ReviewedRequisition, error) {
+	reviewed, err := loadReviewedRequisition(db, kind, id)
+	if err != nil {
+		return nil, err
+	}
+	if reviewed.Status != RequisitionSubmitted {
+		return nil, &ValidationError{Field: "status", Message: fmt.Sprintf("%s is %s, not waiting for review", reviewed.Label(), reviewed.Status)}
+	}
+	return reviewed, nil
+}
+
+func (s *RequisitionReviewService) withCheck(reviewed *ReviewedRequisition) (*ReviewedRequisition, error) {
+	check, err := checkBudget(s.db, reviewed)
+	if err != nil {
+		return nil, err
+	}
+	reviewed.Check = check
+	return reviewed, nil
+}
+
+// loadReviewedRequisition loads a requisition or project requisition
+func loadReviewedRequisition(db *gorm.DB, kind string, id uint) (*ReviewedRequisition, error) {
+	switch kind {
+	case KindRequisition:
+		var requisition models.Requisition
+		err := db.Preload("Items").First(&requisition, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &NotFoundError{Entity: "Requisition", ID: id}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &ReviewedRequisition{
+			Kind:        kind,
+			ID:          requisition.ID,
+			Name:        requisition.Name,
+			Status:      requisitionStatus(requisition.Status),
+			SubmittedBy: requisition.SubmittedBy,
+			Items:       len(requisition.Items),
+			Budget:      requisition.Budget,
+		}, nil
+	case KindProjectRequisition:
+		var requisition models.ProjectRequisition
+		err := db.Preload("Items").Preload("Project").First(&requisition, id).Error
+		if err == nil && requisition.Project == nil {
+			err = gorm.ErrRecordNotFound // The project is in the trash
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &NotFoundError{Entity: "ProjectRequisition", ID: id}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &ReviewedRequisition{
+			Kind:        kind,
+			ID:          requisition.ID,
+			Name:        requisition.Name,
+			ProjectID:   requisition.ProjectID,
+			ProjectName: requisition.Project.Name,
+			Status:      requisitionStatus(requisition.Status),
+			SubmittedBy: requisition.SubmittedBy,
+			Items:       len(requisition.Items),
+			Budget:      requisition.Budget,
+		}, nil
+	}
+	return nil, &ValidationError{Field: "kind", Message: fmt.Sprintf("unknown requisition kind %q (use %s or %s)", kind, KindRequisition, KindProjectRequisition)}
+}
+
+// checkBudget computes the budget check of a requisition from the best current
+// quotes of its items
+func checkBudget(db *gorm.DB, reviewed *ReviewedRequisition) (*BudgetCheck, error) {
+	quoteService := NewQuoteService(db)
+	check := &BudgetCheck{Budget: reviewed.Budget}
+
+	if reviewed.Kind == KindRequisition {
+		comparison, err := NewRequisitionService(db).GetQuoteComparison(reviewed.ID, quoteService)
+		if err != nil {
+			return nil, err
+		}
+		check.Estimate = comparison.TotalEstimate
+		for _, item := range comparison.ItemComparisons {
+			if !item.HasQuotes {
+				check.MissingQuotes++
+			}
+		}
+		return check, nil
+	}
+
+	comparison, err := NewProjectRequisitionService(db).GetQuoteComparison(reviewed.ID, quoteService)
+	if err != nil {
+		return nil, err
+	}
+	check.Estimate = comparison.TotalEstimate
+	for _, item := range comparison.Items {
+		if item.BestQuote == nil {
+			check.MissingQuotes++
+		}
+	}
+
+	check.ProjectBudget = comparison.Requisition.Project.Budget
+	err = db.Model(&models.RequisitionReview{}).
+		Joins("JOIN project_requisitions ON project_requisitions.id = requisition_reviews.project_requisition_id").
+		Where("project_requisitions.project_id = ? AND project_requisitions.id <> ?", reviewed.ProjectID, reviewed.ID).
+		Where("project_requisitions.status = ? AND requisition_reviews.action = ?", RequisitionApproved, ReviewApproved).
+		Select("COALESCE(SUM(requisition_reviews.estimate), 0)").
+		Scan(&check.ProjectCommitted).Error
+	if err != nil {
+		return nil, err
+	}
+	return check, nil
+}
+
+// setReviewStatus changes the status of a requisition, with any other columns
+func setReviewStatus(db *gorm.DB, reviewed *ReviewedRequisition, status string, columns map[string]interface{}) error {
+	updates := map[string]interface{}{"status": status}
+	for column, value := range columns {
+		updates[column] = value
+	}
+	var model interface{} = &models.Requisition{ID: reviewed.ID}
+	if reviewed.Kind == KindProjectRequisition {
+		model = &models.ProjectRequisition{ID: reviewed.ID}
+	}
+	if err := db.Model(model).Updates(updates).Error; err != nil {
+		return err
+	}
+	reviewed.Status = status
+	return nil
+}
+
+func newReview(reviewed *ReviewedRequisition, action, actor, comment string) *models.RequisitionReview {
+	review := &models.RequisitionReview{Action: action, Actor: actor, Comment: strings.TrimSpace(comment)}
+	id := reviewed.ID
+	if reviewed.Kind == KindProjectRequisition {
+		review.ProjectRequisitionID = &id
+	} else {
+		review.RequisitionID = &id
+	}
+	return review
+}
+
+// without returns names without name
+func without(names []string, name string) []string {
+	var kept []string
+	for _, n := range names {
+		if n != name {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRequisitionReviewService(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	users := NewUserService(cfg.DB)
+	for _, input := range []CreateUserInput{
+		{Username: "rita", Role: RoleApprover, Password: testPassword},
+		{Username: "sam", Role: RoleRequester, Password: testPassword},
+	} {
+		if _, err := users.Create(input); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	spec, _ := NewSpecificationService(cfg.DB).Create("Laptop", "")
+	vendor, _ := NewVendorService(cfg.DB).Create("Acme", "USD", "")
+	brand, _ := NewBrandService(cfg.DB).Create("Apple")
+	product, _ := NewProductService(cfg.DB).Create("MacBook", brand.ID, &spec.ID)
+	if _, err := NewQuoteService(cfg.DB).Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 100, Currency: "USD"}); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewRequisitionReviewService(cfg.DB)
+	notifications := NewNotificationService(cfg.DB)
+	reqSvc := NewRequisitionService(cfg.DB)
+	var validationErr *ValidationError
+
+	empty, _ := reqSvc.Create("Empty", "", 0, nil)
+	if _, err := svc.Submit(KindRequisition, empty.ID, "sam", ""); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError submitting a requisition without items, got %v", err)
+	}
+
+	// Requisition budget
+	req, err := reqSvc.Create("Laptops", "", 500, []RequisitionItemInput{{SpecificationID: spec.ID, Quantity: 6}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Approve(KindRequisition, req.ID, "rita", "", ""); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError approving a draft, got %v", err)
+	}
+	reviewed, err := svc.Submit(KindRequisition, req.ID, "sam", "For the new hires")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reviewed.Status != RequisitionSubmitted || reviewed.Check.Estimate != 600 || reviewed.Check.OK() {
+		t.Errorf("Expected a submitted requisition over budget, got %+v %+v", reviewed, reviewed.Check)
+	}
+	if count, _ := notifications.UnreadCount("rita"); count != 1 {
+		t.Errorf("Expected the approver to be notified, got %d notifications", count)
+	}
+	if count, _ := notifications.UnreadCount("sam"); count != 0 {
+		t.Errorf("Expected no notification for the submitter, got %d", count)
+	}
+	if _, err := reqSvc.Update(req.ID, "Laptops", "", 1000); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError changing a submitted requisition, got %v", err)
+	}
+
+	if _, err := svc.Approve(KindRequisition, req.ID, "rita", "", ""); !errors.As(err, &validationErr) {
+		t.Errorf("Expected the budget check to block approval, got %v", err)
+	}
+	if reviewed, err = svc.Approve(KindRequisition, req.ID, "rita", "Fine", "Prices rose this quarter"); err != nil || reviewed.Status != RequisitionApproved {
+		t.Fatalf("Expected the requisition to be approved with an override, got %+v, %v", reviewed, err)
+	}
+	history, _ := svc.History(KindRequisition, req.ID)
+	if len(history) != 2 || history[1].OverrideReason != "Prices rose this quarter" || history[1].Estimate != 600 {
+		t.Errorf("Expected the override to be recorded, got %+v", history)
+	}
+	if list, _ := notifications.List("sam", true, 0); len(list) != 1 || list[0].Link != "/requisitions" {
+		t.Errorf("Expected the submitter to be notified, got %+v", list)
+	}
+
+	// Remaining project budget
+	project, _ := NewProjectService(cfg.DB).Create("Office", "", 1000, nil)
+	bomItem, err := NewProjectService(cfg.DB).AddBillOfMaterialsItem(project.ID, spec.ID, 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prSvc := NewProjectRequisitionService(cfg.DB)
+	first, _ := prSvc.Create(project.ID, "Phase 1", "", 0, []ProjectRequisitionItemInput{{BOMItemID: bomItem.ID, QuantityRequested: 5}})
+	second, _ := prSvc.Create(project.ID, "Phase 2", "", 0, []ProjectRequisitionItemInput{{BOMItemID: bomItem.ID, QuantityRequested: 6}})
+	for _, id := range []uint{first.ID, second.ID} {
+		if _, err := svc.Submit(KindProjectRequisition, id, "sam", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if queue, _ := svc.Queue(); len(queue) != 2 || queue[0].ProjectName != "Office" {
+		t.Errorf("Expected two project requisitions waiting, got %+v", queue)
+	}
+	if _, err := svc.Approve(KindProjectRequisition, first.ID, "rita", "", ""); err != nil {
+		t.Fatalf("Expected the first phase to fit the project budget: %v", err)
+	}
+	reviewed, _ = svc.Get(KindProjectRequisition, second.ID)
+	if reviewed.Check.ProjectCommitted != 500 || reviewed.Check.ProjectRemaining() != 500 || reviewed.Check.OK() {
+		t.Errorf("Expected 500 left of the project budget, got %+v", reviewed.Check)
+	}
+	if _, err := svc.Approve(KindProjectRequisition, second.ID, "rita", "", ""); !errors.As(err, &validationErr) {
+		t.Errorf("Expected the remaining project budget to block approval, got %v", err)
+	}
+
+	// Rejection needs a comment, and a rejected requisition can be changed
+	if _, err := svc.Reject(KindProjectRequisition, second.ID, "rita", ""); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError rejecting without a comment, got %v", err)
+	}
+	if reviewed, err = svc.Reject(KindProjectRequisition, second.ID, "rita", "Split it up"); err != nil || reviewed.Status != RequisitionRejected {
+		t.Fatalf("Expected the requisition to be rejected, got %+v, %v", reviewed, err)
+	}
+	if _, err := prSvc.UpdateItem(second.Items[0].ID, 4, ""); err != nil {
+		t.Errorf("Expected a rejected requisition to be editable: %v", err)
+	}
+	if _, err := svc.Submit(KindProjectRequisition, second.ID, "sam", "Smaller"); err != nil {
+		t.Errorf("Expected a rejected requisition to be submitted again: %v", err)
+	}
+	if _, err := svc.Approve(KindProjectRequisition, second.ID, "rita", "", ""); err != nil {
+		t.Errorf("Expected the smaller requisition to fit: %v", err)
+	}
+
+	if count, _ := notifications.MarkAllRead("sam"); count != 4 {
+		t.Errorf("Expected 4 unread notifications for the submitter, got %d", count)
+	}
+	if _, err := svc.Get("order", 1); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError for an unknown kind, got %v", err)
+	}
+}
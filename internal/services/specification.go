@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"strings"
 
@@ -18,6 +19,11 @@ func NewSpecificationService(db *gorm.DB) *SpecificationService {
 	return &SpecificationService{db: db}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *SpecificationService) WithContext(ctx context.Context) *SpecificationService {
+	return NewSpecificationService(s.db.WithContext(ctx))
+}
+
 // Create creates a new specification
 func (s *SpecificationService) Create(name, description string) (*models.Specification, error) {
 	name = strings.TrimSpace(name)
@@ -27,8 +33,8 @@ func (s *SpecificationService) Create(name, description string) (*models.Specifi
 
 	// Check for duplicates
 	var existing models.Specification
-	if err := s.db.Where("name = ?", name).First(&existing).Error; err == nil {
-		return nil, &DuplicateError{Entity: "Specification", Name: name}
+	if err := s.db.Unscoped().Where("name = ?", name).First(&existing).Error; err == nil {
+		return nil, &DuplicateError{Entity: "Specification", Name: name, InTrash: existing.DeletedAt.Valid}
 	}
 
 	spec := &models.Specification{
@@ -88,8 +94,8 @@ func (s *SpecificationService) Update(id uint, name, description string) (*model
 
 	// Check for duplicate name (excluding current record)
 	var existing models.Specification
-	if err := s.db.Where("name = ? AND id != ?", name, id).First(&existing).Error; err == nil {
-		return nil, &DuplicateError{Entity: "Specification", Name: name}
+	if err := s.db.Unscoped().Where("name = ? AND id != ?", name, id).First(&existing).Error; err == nil {
+		return nil, &DuplicateError{Entity: "Specification", Name: name, InTrash: existing.DeletedAt.Valid}
 	}
 
 	spec.Name = name
@@ -102,16 +108,9 @@ func (s *SpecificationService) Update(id uint, name, description string) (*model
 	return &spec, nil
 }
 
-// Delete deletes a specification by ID
+// Delete moves a specification to the trash
 func (s *SpecificationService) Delete(id uint) error {
-	result := s.db.Delete(&models.Specification{}, id)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return &NotFoundError{Entity: "Specification", ID: id}
-	}
-	return nil
+	return moveToTrash(s.db, "specification", id)
 }
 
 // List retrieves all specifications with optional pagination
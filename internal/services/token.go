@@ -0,0 +1,217 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// Token scopes. Every token can read; the other scopes grant writes.
+const (
+	ScopeRead        = "read"         // Read-only access
+	ScopeQuotesWrite = "quotes:write" // Create, update and delete quotes
+	ScopePOApprove   = "po:approve"   // Approve or reject purchase orders and their approval steps
+	ScopeAdmin       = "admin"        // Every operation
+)
+
+// TokenScopes lists the valid scopes
+var TokenScopes = []string{ScopeRead, ScopeQuotesWrite, ScopePOApprove, ScopeAdmin}
+
+// tokenPrefix starts every token, so leaked tokens are easy to recognize
+const tokenPrefix = "buyer_"
+
+// ErrInvalidToken is returned by Authenticate for unknown, expired or revoked tokens
+var ErrInvalidToken = errors.New("invalid, expired or revoked token")
+
+// TokenService handles business logic for API tokens
+type TokenService struct {
+	db *gorm.DB
+}
+
+// NewTokenService creates a new token service
+func NewTokenService(db *gorm.DB) *TokenService {
+	return &TokenService{db: db}
+}
+
+// CreateTokenInput holds the input for creating a token
+type CreateTokenInput struct {
+	Name           string
+	Owner          string // User name, or the service account name
+	ServiceAccount bool
+	Scopes         []string
+	ExpiresAt      *time.Time // Nil for a token that does not expire
+}
+
+// Create stores a new token and returns it with the plaintext token, which is
+// not stored and cannot be recovered later
+func (s *TokenService) Create(input CreateTokenInput) (*models.APIToken, string, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, "", &ValidationError{Field: "name", Message: "token name cannot be empty"}
+	}
+	owner := strings.TrimSpace(input.Owner)
+	if owner == "" {
+		return nil, "", &ValidationError{Field: "owner", Message: "token owner cannot be empty"}
+	}
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, "", &ValidationError{Field: "expires_at", Message: "expiry must be in the future"}
+	}
+
+	var count int64
+	if err := s.db.Model(&models.APIToken{}).
+		Where("owner = ? AND name = ? AND revoked_at IS NULL", owner, name).
+		Count(&count).Error; err != nil {
+		return nil, "", err
+	}
+	if count > 0 {
+		return nil, "", &DuplicateError{Entity: "Token", Name: name}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	plaintext := tokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	token := &models.APIToken{
+		Name:           name,
+		Owner:          owner,
+		ServiceAccount: input.ServiceAccount,
+		Prefix:         plaintext[:len(tokenPrefix)+6],
+		TokenHash:      hashToken(plaintext),
+		Scopes:         strings.Join(scopes, ","),
+		ExpiresAt:      input.ExpiresAt,
+	}
+	if err := s.db.Create(token).Error; err != nil {
+		return nil, "", err
+	}
+	return token, plaintext, nil
+}
+
+// GetByID retrieves a token by ID
+func (s *TokenService) GetByID(id uint) (*models.APIToken, error) {
+	var token models.APIToken
+	if err := s.db.First(&token, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &NotFoundError{Entity: "Token", ID: id}
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// List returns tokens ordered by owner and name; revoked tokens are included
+// only if includeRevoked is set
+func (s *TokenService) List(includeRevoked bool) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	query := s.db.Order("owner, name, id")
+	if !includeRevoked {
+		query = query.Where("revoked_at IS NULL")
+	}
+	if err := query.Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Revoke marks a token as revoked; it is kept for the record
+func (s *TokenService) Revoke(id uint) (*models.APIToken, error) {
+	token, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if token.RevokedAt != nil {
+		return nil, &ValidationError{Field: "token", Message: fmt.Sprintf("token %d is already revoked", id)}
+	}
+	now := time.Now()
+	token.RevokedAt = &now
+	if err := s.db.Model(token).Update("revoked_at", now).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Authenticate returns the token matching a plaintext token, recording its
+// use. It returns ErrInvalidToken for unknown, expired or revoked tokens.
+func (s *TokenService) Authenticate(plaintext string) (*models.APIToken, error) {
+	if !strings.HasPrefix(plaintext, tokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+	hash := hashToken(plaintext)
+
+	var token models.APIToken
+	if err := s.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if token.RevokedAt != nil || (token.ExpiresAt != nil && !token.ExpiresAt.After(now)) {
+		return nil, ErrInvalidToken
+	}
+	token.LastUsedAt = &now
+	if err := s.db.Model(&token).UpdateColumn("last_used_at", now).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// TokenHasScope reports whether a token grants a scope. Every token may read,
+// and admin grants every scope.
+func TokenHasScope(token *models.APIToken, scope string) bool {
+	if scope == ScopeRead {
+		return true
+	}
+	for _, granted := range strings.Split(token.Scopes, ",") {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeScopes validates, de-duplicates and sorts scopes in TokenScopes order
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, &ValidationError{Field: "scopes", Message: "at least one scope is required"}
+	}
+	wanted := make(map[string]bool)
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		valid := false
+		for _, known := range TokenScopes {
+			valid = valid || scope == known
+		}
+		if !valid {
+			return nil, &ValidationError{Field: "scopes", Message: fmt.Sprintf("unknown scope '%s' (must be one of: %s)", scope, strings.Join(TokenScopes, ", "))}
+		}
+		wanted[scope] = true
+	}
+	var normalized []string
+	for _, known := range TokenScopes {
+		if wanted[known] {
+			normalized = append(normalized, known)
+		}
+	}
+	return normalized, nil
+}
+
+// hashToken returns the hex SHA-256 of a token. Tokens are 256 random bits, so
+// a fast hash is enough; unlike passwords they cannot be guessed.
+func hashToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenService_Create(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	svc := NewTokenService(cfg.DB)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		input   CreateTokenInput
+		wantErr bool
+	}{
+		{name: "valid", input: CreateTokenInput{Name: "ci", Owner: "admin", Scopes: []string{"read"}}},
+		{name: "empty name", input: CreateTokenInput{Owner: "admin", Scopes: []string{"read"}}, wantErr: true},
+		{name: "empty owner", input: CreateTokenInput{Name: "x", Scopes: []string{"read"}}, wantErr: true},
+		{name: "no scopes", input: CreateTokenInput{Name: "x", Owner: "admin"}, wantErr: true},
+		{name: "unknown scope", input: CreateTokenInput{Name: "x", Owner: "admin", Scopes: []string{"brands:write"}}, wantErr: true},
+		{name: "expired", input: CreateTokenInput{Name: "x", Owner: "admin", Scopes: []string{"read"}, ExpiresAt: &past}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := svc.Create(tt.input)
+			if tt.wantErr {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Errorf("Expected ValidationError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+
+	token, plaintext, err := svc.Create(CreateTokenInput{
+		Name: "importer", Owner: "price-bot", ServiceAccount: true,
+		Scopes: []string{"po:approve", "Quotes:Write", "quotes:write"},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !strings.HasPrefix(plaintext, tokenPrefix) || !strings.HasPrefix(plaintext, token.Prefix) {
+		t.Errorf("Unexpected token %q with prefix %q", plaintext, token.Prefix)
+	}
+	if token.TokenHash == plaintext || strings.Contains(token.TokenHash, plaintext[len(tokenPrefix):]) {
+		t.Error("Expected only a hash of the token to be stored")
+	}
+	if token.Scopes != "quotes:write,po:approve" {
+		t.Errorf("Expected normalized scopes, got %q", token.Scopes)
+	}
+
+	if _, _, err := svc.Create(CreateTokenInput{Name: "importer", Owner: "price-bot", Scopes: []string{"read"}}); err == nil {
+		t.Error("Expected DuplicateError for a second active token with the same name")
+	} else {
+		var duplicateErr *DuplicateError
+		if !errors.As(err, &duplicateErr) {
+			t.Errorf("Expected DuplicateError, got %v", err)
+		}
+	}
+}
+
+func TestTokenService_Authenticate(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	svc := NewTokenService(cfg.DB)
+	token, plaintext, err := svc.Create(CreateTokenInput{Name: "ci", Owner: "admin", Scopes: []string{"read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := svc.Authenticate(plaintext)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if got.ID != token.ID || got.LastUsedAt == nil {
+		t.Errorf("Expected token %d with last use recorded, got %+v", token.ID, got)
+	}
+
+	for _, bad := range []string{"", "buyer_nope", plaintext + "x", strings.TrimPrefix(plaintext, tokenPrefix)} {
+		if _, err := svc.Authenticate(bad); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Authenticate(%q): expected ErrInvalidToken, got %v", bad, err)
+		}
+	}
+
+	// Expired tokens are refused
+	expiring, expiringPlaintext, err := svc.Create(CreateTokenInput{Name: "short", Owner: "admin", Scopes: []string{"read"}, ExpiresAt: timePtr(time.Now().Add(time.Hour))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.DB.Model(expiring).Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := svc.Authenticate(expiringPlaintext); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected expired token to be refused, got %v", err)
+	}
+
+	// Revoked tokens are refused, and revoking twice fails
+	if _, err := svc.Revoke(token.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, err := svc.Authenticate(plaintext); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected revoked token to be refused, got %v", err)
+	}
+	if _, err := svc.Revoke(token.ID); err == nil {
+		t.Error("Expected an error revoking a revoked token")
+	}
+	if _, err := svc.Revoke(999); err == nil {
+		t.Error("Expected NotFoundError revoking a missing token")
+	}
+
+	active, _ := svc.List(false)
+	all, _ := svc.List(true)
+	if len(active) != 1 || len(all) != 2 {
+		t.Errorf("Expected 1 active and 2 total tokens, got %d and %d", len(active), len(all))
+	}
+
+	// The name of a revoked token can be reused
+	if _, _, err := svc.Create(CreateTokenInput{Name: "ci", Owner: "admin", Scopes: []string{"read"}}); err != nil {
+		t.Errorf("Expected to reuse a revoked token's name, got %v", err)
+	}
+}
+
+func TestTokenHasScope(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	svc := NewTokenService(cfg.DB)
+	readOnly, _, _ := svc.Create(CreateTokenInput{Name: "r", Owner: "a", Scopes: []string{ScopeRead}})
+	quotes, _, _ := svc.Create(CreateTokenInput{Name: "q", Owner: "a", Scopes: []string{ScopeQuotesWrite}})
+	admin, _, _ := svc.Create(CreateTokenInput{Name: "x", Owner: "a", Scopes: []string{ScopeAdmin}})
+
+	tests := []struct {
+		name  string
+		scope string
+		read  bool
+		quote bool
+		admin bool
+	}{
+		{"read", ScopeRead, true, true, true},
+		{"quotes:write", ScopeQuotesWrite, false, true, true},
+		{"po:approve", ScopePOApprove, false, false, true},
+		{"admin", ScopeAdmin, false, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TokenHasScope(readOnly, tt.scope); got != tt.read {
+				t.Errorf("read-only token: got %v", got)
+			}
+			if got := TokenHasScope(quotes, tt.scope); got != tt.quote {
+				t.Errorf("quotes token: got %v", got)
+			}
+			if got := TokenHasScope(admin, tt.scope); got != tt.admin {
+				t.Errorf("admin token: got %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,460 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+	"gorm.io/gorm"
+)
+
+// trashEntity describes a model whose records are moved to the trash instead
+// of being deleted (see models.DeletedAt)
+type trashEntity struct {
+	name  string // Entity name, as in the audit log
+	label string // Name used in messages
+	// notFound is the entity of the NotFoundError returned for a missing record
+	notFound string
+	model    func() interface{}
+	title    string // SQL expression describing a record in the trash listing
+
+	// dependents are moved to the trash and restored along with the record
+	dependents []trashLink
+	// blockers are live records that prevent moving the record to the trash,
+	// mirroring the RESTRICT constraints of the schema
+	blockers []trashLink
+	// parents must be out of the trash before the record is restored
+	parents []trashLink
+}
+
+// trashLink relates a trash entity to other records by a foreign key. For
+// dependents and blockers the column is on the other records; for parents it
+// is on the record itself.
+type trashLink struct {
+	entity string // Trash entity name, for dependents and parents
+	label  string // Plural name used in messages, for blockers
+	model  func() interface{}
+	column string
+}
+
+// trashEntities lists the entities with a trash, in the order they are purged:
+// records before the records they reference
+var trashEntities = []trashEntity{
+	{
+		name: "vendor_rating", label: "vendor rating", notFound: "VendorRating", title: "comments",
+		model:   func() interface{} { return &models.VendorRating{} },
+		parents: []trashLink{{entity: "vendor", column: "vendor_id"}},
+	},
+	{
+		name: "purchase_order", label: "purchase order", notFound: "purchase order", title: "po_number",
+		model: func() interface{} { return &models.PurchaseOrder{} },
+		parents: []trashLink{
+			{entity: "quote", column: "quote_id"},
+			{entity: "vendor", column: "vendor_id"},
+			{entity: "product", column: "product_id"},
+		},
+	},
+	{
+		name: "quote", label: "quote", notFound: "Quote", title: "currency || ' ' || price",
+		model: func() interface{} { return &models.Quote{} },
+		blockers: []trashLink{
+			{label: "purchase orders", model: func() interface{} { return &models.PurchaseOrder{} }, column: "quote_id"},
+		},
+		parents: []trashLink{
+			{entity: "vendor", column: "vendor_id"},
+			{entity: "product", column: "product_id"},
+		},
+	},
+	{
+		name: "product", label: "product", notFound: "Product", title: "name",
+		model:      func() interface{} { return &models.Product{} },
+		dependents: []trashLink{{entity: "quote", column: "product_id"}},
+		blockers: []trashLink{
+			{label: "purchase orders", model: func() interface{} { return &models.PurchaseOrder{} }, column: "product_id"},
+		},
+		parents: []trashLink{{entity: "brand", column: "brand_id"}},
+	},
+	{
+		name: "brand", label: "brand", notFound: "Brand", title: "name",
+		model: func() interface{} { return &models.Brand{} },
+		blockers: []trashLink{
+			{label: "products", model: func() interface{} { return &models.Product{} }, column: "brand_id"},
+		},
+	},
+	{
+		name: "requisition", label: "requisition", notFound: "Requisition", title: "name",
+		model: func() interface{} { return &models.Requisition{} },
+	},
+	{
+		name: "project", label: "project", notFound: "Project", title: "name",
+		model: func() interface{} { return &models.Project{} },
+	},
+	{
+		name: "specification", label: "specification", notFound: "Specification", title: "name",
+		model: func() interface{} { return &models.Specification{} },
+		blockers: []trashLink{
+			{label: "requisition items", model: func() interface{} { return &models.RequisitionItem{} }, column: "specification_id"},
+			{label: "bill of materials items", model: func() interface{} { return &models.BillOfMaterialsItem{} }, column: "specification_id"},
+		},
+	},
+	{
+		name: "vendor", label: "vendor", notFound: "Vendor", title: "name",
+		model:      func() interface{} { return &models.Vendor{} },
+		dependents: []trashLink{{entity: "vendor_rating", column: "vendor_id"}},
+		blockers: []trashLink{
+			{label: "quotes", model: func() interface{} { return &models.Quote{} }, column: "vendor_id"},
+			{label: "purchase orders", model: func() interface{} { return &models.PurchaseOrder{} }, column: "vendor_id"},
+		},
+	},
+}
+
+func findTrashEntity(name string) (*trashEntity, bool) {
+	for i := range trashEntities {
+		if trashEntities[i].name == name {
+			return &trashEntities[i], true
+		}
+	}
+	return nil, false
+}
+
+// TrashEntities lists the entities that have a trash
+func TrashEntities() []string {
+	names := make([]string, len(trashEntities))
+	for i, entity := range trashEntities {
+		names[i] = entity.name
+	}
+	return names
+}
+
+// TrashEntity resolves an entity name given by a user, as AuditEntity does,
+// and checks that it has a trash
+func TrashEntity(name string) (string, error) {
+	entity, err := AuditEntity(name)
+	if err == nil {
+		if _, ok := findTrashEntity(entity); ok {
+			return entity, nil
+		}
+	}
+	return "", &ValidationError{Field: "entity", Message: fmt.Sprintf("unknown entity '%s' (must be one of: %s)", name, strings.Join(TrashEntities(), ", "))}
+}
+
+// moveToTrash moves a live record, and its live dependents, to the trash in
+// one transaction. It fails with a ValidationError while live records that
+// the schema protects from deletion refer to the record.
+func moveToTrash(db *gorm.DB, name string, id uint) error {
+	entity, ok := findTrashEntity(name)
+	if !ok {
+		return fmt.Errorf("%s has no trash", name)
+	}
+	now := time.Now().UTC()
+	return db.Transaction(func(tx *gorm.DB) error {
+		var found int64
+		if err := tx.Model(entity.model()).Where("id = ?", id).Count(&found).Error; err != nil {
+			return err
+		}
+		if found == 0 {
+			return &NotFoundError{Entity: entity.notFound, ID: id}
+		}
+		for _, blocker := range entity.blockers {
+			var count int64
+			if err := tx.Model(blocker.model()).Where(blocker.column+" = ?", id).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return &ValidationError{Field: "id", Message: fmt.Sprintf("%s %d is used by %d %s; delete them first", entity.label, id, count, blocker.label)}
+			}
+		}
+		return trashRecords(tx, entity, []uint{id}, now)
+	})
+}
+
+// trashRecords marks records and their dependents as deleted at now
+func trashRecords(tx *gorm.DB, entity *trashEntity, ids []uint, now time.Time) error {
+	for _, id := range ids {
+		if err := setDeletedAt(tx, entity, id, now); err != nil {
+			return err
+		}
+	}
+	for _, link := range entity.dependents {
+		dependent, _ := findTrashEntity(link.entity)
+		var dependentIDs []uint
+		if err := tx.Model(dependent.model()).Where(link.column+" IN ?", ids).Pluck("id", &dependentIDs).Error; err != nil {
+			return err
+		}
+		if len(dependentIDs) > 0 {
+			if err := trashRecords(tx, dependent, dependentIDs, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setDeletedAt moves a record to the trash or, with a nil value, out of it.
+// The record is loaded first so that its save hooks see its actual values.
+func setDeletedAt(tx *gorm.DB, entity *trashEntity, id uint, value interface{}) error {
+	record := entity.model()
+	if err := tx.Unscoped().First(record, id).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(record).Update("deleted_at", value).Error
+}
+
+// TrashService lists, restores and purges records in the trash
+type TrashService struct {
+	db *gorm.DB
+}
+
+// NewTrashService creates a new trash service
+func NewTrashService(db *gorm.DB) *TrashService {
+	return &TrashService{db: db}
+}
+
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *TrashService) WithContext(ctx context.Context) *TrashService {
+	return NewTrashService(s.db.WithContext(ctx))
+}
+
+// TrashItem is a record in the trash, with the dependents that were moved to
+// the trash along with it
+type TrashItem struct {
+	Entity     string
+	ID         uint
+	Title      string
+	DeletedAt  time.Time
+	DeletedBy  string
+	Dependents []TrashItem
+}
+
+type trashRow struct {
+	ID        uint
+	Title     string
+	DeletedAt time.Time
+}
+
+// List returns the records in the trash, most recently deleted first. Records
+// deleted along with another record are listed as its dependents. entity
+// limits the list to one entity; "" lists every entity.
+func (s *TrashService) List(entity string) ([]TrashItem, error) {
+	var items []TrashItem
+	for i := range trashEntities {
+		e := &trashEntities[i]
+		if entity != "" && e.name != entity {
+			continue
+		}
+		rows, err := s.rows(e, s.db.Where("deleted_at IS NOT NULL"))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			isDependent, err := s.deletedWithParent(e, row)
+			if err != nil {
+				return nil, err
+			}
+			if isDependent {
+				continue
+			}
+			item, err := s.item(e, row)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, *item)
+		}
+	}
+	sortTrashItems(items)
+	return items, nil
+}
+
+// Restore takes a record, and the dependents deleted along with it, out of
+// the trash. Records it refers to must be restored first.
+func (s *TrashService) Restore(entity string, id uint) (*TrashItem, error) {
+	e, ok := findTrashEntity(entity)
+	if !ok {
+		_, err := TrashEntity(entity)
+		return nil, err
+	}
+
+	var restored *TrashItem
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		rows, err := s.rows(e, tx.Where("id = ?", id))
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return &NotFoundError{Entity: e.notFound, ID: id}
+		}
+		row := rows[0]
+		if row.DeletedAt.IsZero() {
+			return &ValidationError{Field: "id", Message: fmt.Sprintf("%s %d is not in the trash", e.label, id)}
+		}
+		for _, parent := range e.parents {
+			parentEntity, _ := findTrashEntity(parent.entity)
+			var parentID uint
+			if err := tx.Unscoped().Model(e.model()).Where("id = ?", id).Pluck(parent.column, &parentID).Error; err != nil {
+				return err
+			}
+			var trashed int64
+			if err := tx.Unscoped().Model(parentEntity.model()).
+				Where("id = ? AND deleted_at IS NOT NULL", parentID).Count(&trashed).Error; err != nil {
+				return err
+			}
+			if trashed > 0 {
+				return &ValidationError{Field: "id", Message: fmt.Sprintf("%s %d is in the trash; restore it first", parentEntity.label, parentID)}
+			}
+		}
+
+		restored, err = NewTrashService(tx).item(e, row)
+		if err != nil {
+			return err
+		}
+		if err := restoreItem(tx, restored); err != nil {
+			return err
+		}
+		if e.name == "purchase_order" {
+			// A restored order counts towards its project requisition item again
+			var po models.PurchaseOrder
+			if err := tx.First(&po, id).Error; err != nil {
+				return err
+			}
+			return syncProjectRequisitionItem(tx, po.ProjectRequisitionItemID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+func restoreItem(tx *gorm.DB, item *TrashItem) error {
+	e, _ := findTrashEntity(item.Entity)
+	if err := setDeletedAt(tx, e, item.ID, nil); err != nil {
+		return err
+	}
+	for i := range item.Dependents {
+		if err := restoreItem(tx, &item.Dependents[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PurgeCount is the number of records of one entity removed by Purge
+type PurgeCount struct {
+	Entity string
+	Count  int64
+}
+
+// Purge permanently deletes the records that have been in the trash for
+// longer than retention. With dryRun nothing is deleted and the counts are
+// of the records that would be.
+func (s *TrashService) Purge(retention time.Duration, dryRun bool) ([]PurgeCount, error) {
+	if retention < 0 {
+		return nil, &ValidationError{Field: "retention", Message: "retention cannot be negative"}
+	}
+	cutoff := time.Now().UTC().Add(-retention)
+
+	var counts []PurgeCount
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, e := range trashEntities {
+			query := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at <= ?", cutoff)
+			var count int64
+			if dryRun {
+				if err := query.Model(e.model()).Count(&count).Error; err != nil {
+					return err
+				}
+			} else {
+				result := query.Delete(e.model())
+				if result.Error != nil {
+					return fmt.Errorf("failed to purge %s records: %w", e.label, result.Error)
+				}
+				count = result.RowsAffected
+			}
+			if count > 0 {
+				counts = append(counts, PurgeCount{Entity: e.name, Count: count})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// rows reads the id, title and deletion time of matching records, trashed
+// or not, most recently deleted first
+func (s *TrashService) rows(e *trashEntity, query *gorm.DB) ([]trashRow, error) {
+	var rows []trashRow
+	err := query.Unscoped().Model(e.model()).
+		Select("id, " + e.title + " AS title, deleted_at").
+		Order("deleted_at DESC, id DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// deletedWithParent reports whether a record was moved to the trash as a
+// dependent of another record
+func (s *TrashService) deletedWithParent(e *trashEntity, row trashRow) (bool, error) {
+	for _, parent := range trashEntities {
+		for _, link := range parent.dependents {
+			if link.entity != e.name {
+				continue
+			}
+			var count int64
+			err := s.db.Unscoped().Model(parent.model()).
+				Where("deleted_at = ? AND id = (?)", row.DeletedAt,
+					s.db.Unscoped().Model(e.model()).Select(link.column).Where("id = ?", row.ID)).
+				Count(&count).Error
+			if err != nil {
+				return false, err
+			}
+			if count > 0 {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// item builds the trash item of a record with the dependents deleted along
+// with it
+func (s *TrashService) item(e *trashEntity, row trashRow) (*TrashItem, error) {
+	item := &TrashItem{Entity: e.name, ID: row.ID, Title: row.Title, DeletedAt: row.DeletedAt}
+
+	var log models.AuditLog
+	err := s.db.Where("entity = ? AND entity_id = ? AND action = ?", e.name, row.ID, "delete").
+		Order("created_at DESC, id DESC").First(&log).Error
+	if err == nil {
+		item.DeletedBy = log.Actor
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	for _, link := range e.dependents {
+		dependent, _ := findTrashEntity(link.entity)
+		rows, err := s.rows(dependent, s.db.Where(link.column+" = ? AND deleted_at = ?", row.ID, row.DeletedAt))
+		if err != nil {
+			return nil, err
+		}
+		for _, dependentRow := range rows {
+			dependentItem, err := s.item(dependent, dependentRow)
+			if err != nil {
+				return nil, err
+			}
+			item.Dependents = append(item.Dependents, *dependentItem)
+		}
+	}
+	return item, nil
+}
+
+func sortTrashItems(items []TrashItem) {
+	for i := 1; i < len(items); i++ {
+		for j := i; j > 0 && items[j].DeletedAt.After(items[j-1].DeletedAt); j-- {
+			items[j], items[j-1] = items[j-1], items[j]
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+)
+
+func TestTrashService(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	brandSvc := NewBrandService(cfg.DB)
+	productSvc := NewProductService(cfg.DB)
+	quoteSvc := NewQuoteService(cfg.DB)
+	brand, _ := brandSvc.Create("Apple")
+	vendor, _ := NewVendorService(cfg.DB).Create("Acme", "USD", "")
+	product, _ := productSvc.Create("MacBook", brand.ID, nil)
+	quote, err := quoteSvc.Create(CreateQuoteInput{VendorID: vendor.ID, ProductID: product.ID, Price: 1000, Currency: "USD", QuoteDate: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A brand with live products cannot be deleted
+	var validationErr *ValidationError
+	if err := brandSvc.Delete(brand.ID); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError deleting a brand in use, got %v", err)
+	}
+
+	// Deleting a product takes its quotes along
+	if err := productSvc.Delete(product.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := productSvc.GetByID(product.ID); err == nil {
+		t.Error("Expected a deleted product to be hidden")
+	}
+	if quotes, _ := quoteSvc.List(0, 0); len(quotes) != 0 {
+		t.Errorf("Expected the quotes of a deleted product to be hidden, got %d", len(quotes))
+	}
+	var notFound *NotFoundError
+	if err := productSvc.Delete(product.ID); !errors.As(err, &notFound) {
+		t.Errorf("Expected NotFoundError deleting a product twice, got %v", err)
+	}
+
+	svc := NewTrashService(cfg.DB)
+	items, err := svc.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Entity != "product" || items[0].Title != "MacBook" {
+		t.Fatalf("Expected the product in the trash, got %+v", items)
+	}
+	if len(items[0].Dependents) != 1 || items[0].Dependents[0].Entity != "quote" || items[0].Dependents[0].ID != quote.ID {
+		t.Errorf("Expected the quote as a dependent, got %+v", items[0].Dependents)
+	}
+
+	// Names stay taken while a record is in the trash
+	_, err = productSvc.Create("MacBook", brand.ID, nil)
+	var duplicate *DuplicateError
+	if !errors.As(err, &duplicate) || !duplicate.InTrash {
+		t.Errorf("Expected a DuplicateError for a name in the trash, got %v", err)
+	}
+
+	// A quote cannot be restored without its product
+	if _, err := svc.Restore("quote", quote.ID); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError restoring a quote of a deleted product, got %v", err)
+	}
+	restored, err := svc.Restore("product", product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored.Dependents) != 1 {
+		t.Errorf("Expected the quote to be restored with the product, got %+v", restored)
+	}
+	if _, err := quoteSvc.GetByID(quote.ID); err != nil {
+		t.Errorf("Expected the quote to be restored: %v", err)
+	}
+	if _, err := svc.Restore("product", product.ID); !errors.As(err, &validationErr) {
+		t.Errorf("Expected a ValidationError restoring a live product, got %v", err)
+	}
+
+	// Purging only removes records older than the retention period
+	if err := quoteSvc.Delete(quote.ID); err != nil {
+		t.Fatal(err)
+	}
+	if counts, err := svc.Purge(time.Hour, false); err != nil || len(counts) != 0 {
+		t.Errorf("Expected nothing to purge within the retention period, got %+v, %v", counts, err)
+	}
+	counts, err := svc.Purge(0, true)
+	if err != nil || len(counts) != 1 || counts[0].Count != 1 {
+		t.Errorf("Expected a dry run to count one quote, got %+v, %v", counts, err)
+	}
+	if _, err := svc.Purge(0, false); err != nil {
+		t.Fatal(err)
+	}
+	var remaining int64
+	cfg.DB.Unscoped().Model(&models.Quote{}).Count(&remaining)
+	if remaining != 0 {
+		t.Errorf("Expected the quote to be purged, %d left", remaining)
+	}
+}
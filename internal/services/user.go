@@ -0,0 +1,486 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/shakfu/buyer/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// User roles
+const (
+	RoleRequester = "requester" // Raises requisitions
+	RoleBuyer     = "buyer"     // Maintains the catalog and vendors, enters quotes and issues POs
+	RoleApprover  = "approver"  // Approves requisitions and purchase orders
+	RoleFinance   = "finance"   // Maintains forex rates and vendors
+	RoleAdmin     = "admin"     // Every operation, including managing users
+)
+
+// Roles lists the valid roles
+var Roles = []string{RoleRequester, RoleBuyer, RoleApprover, RoleFinance, RoleAdmin}
+
+// Permissions checked by the web server before a change. Every signed-in user
+// may read.
+const (
+	PermRequisitionsWrite   = "requisitions:write"   // Requisitions and project requisitions
+	PermRequisitionsApprove = "requisitions:approve" // Approve or reject submitted requisitions
+	PermQuotesWrite         = "quotes:write"         // Quotes and quote imports
+	PermPOIssue             = "po:issue"             // Create, update and delete purchase orders
+	PermPOApprove           = "po:approve"           // Approve or reject purchase orders
+	PermCatalogWrite        = "catalog:write"        // Specifications, brands and products
+	PermVendorsWrite        = "vendors:write"        // Vendors and vendor ratings
+	PermForexWrite          = "forex:write"          // Exchange rates
+	PermProjectsWrite       = "projects:write"       // Projects, bills of materials and procurement strategies
+	PermDocumentsWrite      = "documents:write"      // Document attachments
+	PermAccountingWrite     = "accounting:write"     // Cost centers, GL accounts and coding
+	PermAdmin               = "admin"                // Everything else
+)
+
+// RolePermissions maps each role to the permissions it grants. Admins hold
+// every permission.
+var RolePermissions = map[string][]string{
+	RoleRequester: {PermRequisitionsWrite, PermDocumentsWrite},
+	RoleBuyer: {PermRequisitionsWrite, PermQuotesWrite, PermPOIssue, PermCatalogWrite,
+		PermVendorsWrite, PermProjectsWrite, PermDocumentsWrite},
+	RoleApprover: {PermRequisitionsApprove, PermPOApprove, PermDocumentsWrite},
+	RoleFinance:  {PermForexWrite, PermVendorsWrite, PermDocumentsWrite, PermAccountingWrite},
+}
+
+// RoleHasPermission reports whether a role grants a permission
+func RoleHasPermission(role, permission string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	for _, granted := range RolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// rolePrecedence orders roles from most to least privileged, to pick one
+// role for a user whose groups map to several
+var rolePrecedence = []string{RoleAdmin, RoleApprover, RoleFinance, RoleBuyer, RoleRequester}
+
+// RoleForGroups returns the role for a user in the given identity provider
+// groups. mapping maps group names to roles; when several groups match, the
+// most privileged role wins. fallback, which may be "", is returned when no
+// group matches.
+func RoleForGroups(groups []string, mapping map[string]string, fallback string) string {
+	matched := map[string]bool{}
+	for _, group := range groups {
+		if role, ok := mapping[group]; ok {
+			matched[role] = true
+		}
+	}
+	for _, role := range rolePrecedence {
+		if matched[role] {
+			return role
+		}
+	}
+	return fallback
+}
+
+// DefaultSessionTTL is how long a browser session lasts after signing in
+const DefaultSessionTTL = 12 * time.Hour
+
+// ErrInvalidCredentials is returned for an unknown user, a wrong password or a
+// disabled account, without saying which
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// ErrInvalidSession is returned for unknown or expired sessions
+var ErrInvalidSession = errors.New("invalid or expired session")
+
+// UserService handles business logic for users and their sessions
+type UserService struct {
+	db *gorm.DB
+}
+
+// NewUserService creates a new user service
+func NewUserService(db *gorm.DB) *UserService {
+	return &UserService{db: db}
+}
+
+// CreateUserInput holds the input for creating a user
+type CreateUserInput struct {
+	Username string
+	Name     string
+	Email    string
+	Role     string
+	Password string
+}
+
+// Create creates a new user with a bcrypt-hashed password
+func (s *UserService) Create(input CreateUserInput) (*models.User, error) {
+	username := strings.TrimSpace(input.Username)
+	if username == "" {
+		return nil, &ValidationError{Field: "username", Message: "username cannot be empty"}
+	}
+	if err := validateRole(input.Role); err != nil {
+		return nil, err
+	}
+	hash, err := hashPassword(input.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := s.db.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, &DuplicateError{Entity: "User", Name: username}
+	}
+
+	user := &models.User{
+		Username:     username,
+		Name:         strings.TrimSpace(input.Name),
+		Email:        strings.TrimSpace(input.Email),
+		Role:         input.Role,
+		PasswordHash: hash,
+	}
+	if err := s.db.Create(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetByID retrieves a user by ID
+func (s *UserService) GetByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &NotFoundError{Entity: "User", ID: id}
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByUsername retrieves a user by username
+func (s *UserService) GetByUsername(username string) (*models.User, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &NotFoundError{Entity: "User", ID: username}
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// List returns all users ordered by username
+func (s *UserService) List() ([]models.User, error) {
+	var users []models.User
+	if err := s.db.Order("username").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Count returns the number of users
+func (s *UserService) Count() (int64, error) {
+	var count int64
+	err := s.db.Model(&models.User{}).Count(&count).Error
+	return count, err
+}
+
+// SetRole changes a user's role
+func (s *UserService) SetRole(username, role string) (*models.User, error) {
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
+	user, err := s.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	user.Role = role
+	if err := s.db.Save(user).Error; err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetPassword replaces a user's password and signs out their sessions
+func (s *UserService) SetPassword(username, password string) (*models.User, error) {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = hash
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetDisabled disables or re-enables a user. Disabling signs out the user's
+// sessions.
+func (s *UserService) SetDisabled(username string, disabled bool) (*models.User, error) {
+	user, err := s.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	user.Disabled = disabled
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		if !disabled {
+			return nil
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.Session{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Authenticate checks a username and password. It returns
+// ErrInvalidCredentials for unknown users, wrong passwords and disabled
+// accounts.
+func (s *UserService) Authenticate(username, password string) (*models.User, error) {
+	user, err := s.GetByUsername(strings.TrimSpace(username))
+	if err != nil {
+		var notFound *NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if user.Disabled || user.PasswordHash == "" {
+		return nil, ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// CreateSession starts a session for a user and returns the plaintext session
+// token for the cookie; only its hash is stored. Expired sessions are removed
+// at the same time.
+func (s *UserService) CreateSession(user *models.User, ttl time.Duration) (string, *models.Session, error) {
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate session: %w", err)
+	}
+	plaintext := base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now()
+	session := &models.Session{
+		TokenHash:  hashToken(plaintext),
+		UserID:     user.ID,
+		ExpiresAt:  now.Add(ttl),
+		LastSeenAt: now,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at <= ?", now).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		return tx.Model(user).UpdateColumn("last_login_at", now).Error
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	user.LastLoginAt = &now
+	return plaintext, session, nil
+}
+
+// SessionUser returns the user of a session. It returns ErrInvalidSession for
+// unknown or expired sessions and for disabled users.
+func (s *UserService) SessionUser(plaintext string) (*models.User, error) {
+	if plaintext == "" {
+		return nil, ErrInvalidSession
+	}
+	var session models.Session
+	if err := s.db.Preload("User").Where("token_hash = ?", hashToken(plaintext)).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidSession
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if !session.ExpiresAt.After(now) || session.User == nil || session.User.Disabled {
+		return nil, ErrInvalidSession
+	}
+	if err := s.db.Model(&session).UpdateColumn("last_seen_at", now).Error; err != nil {
+		return nil, err
+	}
+	return session.User, nil
+}
+
+// DeleteSession ends a session; unknown sessions are ignored
+func (s *UserService) DeleteSession(plaintext string) error {
+	return s.db.Where("token_hash = ?", hashToken(plaintext)).Delete(&models.Session{}).Error
+}
+
+// ExternalUserInput describes a user signed in by an identity provider
+type ExternalUserInput struct {
+	Issuer   string // Identity provider
+	Subject  string // The provider's stable ID for the user
+	Username string
+	Name     string
+	Email    string
+	Role     string
+}
+
+// SignInExternal returns the user linked to an identity provider account,
+// creating the user and link on first sign-in. The provider is the source of
+// truth: the name, email and role are updated on every sign-in. Users created
+// this way have no password, so they cannot use the login form or basic
+// auth. A username already taken by another account is refused rather than
+// linked, so the provider cannot take over local accounts. Disabled users
+// get ErrInvalidCredentials.
+func (s *UserService) SignInExternal(input ExternalUserInput) (*models.User, error) {
+	if input.Issuer == "" || input.Subject == "" {
+		return nil, &ValidationError{Field: "subject", Message: "issuer and subject are required"}
+	}
+	username := strings.TrimSpace(input.Username)
+	if username == "" {
+		return nil, &ValidationError{Field: "username", Message: "username cannot be empty"}
+	}
+	if err := validateRole(input.Role); err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		err := tx.Preload("User").Where("issuer = ? AND subject = ?", input.Issuer, input.Subject).First(&identity).Error
+		switch {
+		case err == nil:
+			user = *identity.User
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			var count int64
+			if err := tx.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return &DuplicateError{Entity: "User", Name: username}
+			}
+			user = models.User{Username: username, Role: input.Role}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			identity = models.UserIdentity{UserID: user.ID, Issuer: input.Issuer, Subject: input.Subject}
+			if err := tx.Create(&identity).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+		if user.Disabled {
+			return ErrInvalidCredentials
+		}
+
+		user.Name = strings.TrimSpace(input.Name)
+		user.Email = strings.TrimSpace(input.Email)
+		user.Role = input.Role
+		if err := tx.Model(&user).Select("name", "email", "role").Updates(&user).Error; err != nil {
+			return err
+		}
+		return tx.Model(&identity).UpdateColumn("last_login_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// HasIdentity reports whether a user signs in through the given identity
+// provider
+func (s *UserService) HasIdentity(userID uint, issuer string) (bool, error) {
+	var count int64
+	err := s.db.Model(&models.UserIdentity{}).Where("user_id = ? AND issuer = ?", userID, issuer).Count(&count).Error
+	return count > 0, err
+}
+
+// ValidatePassword checks that a password is at least 12 characters long and
+// mixes upper and lower case letters, digits and special characters
+func ValidatePassword(password string) error {
+	if len(password) < 12 {
+		return fmt.Errorf("password must be at least 12 characters long")
+	}
+
+	var (
+		hasUpper   bool
+		hasLower   bool
+		hasDigit   bool
+		hasSpecial bool
+	)
+
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			hasUpper = true
+		case unicode.IsLower(c):
+			hasLower = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c):
+			hasSpecial = true
+		}
+	}
+
+	if !hasUpper {
+		return fmt.Errorf("password must contain at least one uppercase letter")
+	}
+	if !hasLower {
+		return fmt.Errorf("password must contain at least one lowercase letter")
+	}
+	if !hasDigit {
+		return fmt.Errorf("password must contain at least one digit")
+	}
+	if !hasSpecial {
+		return fmt.Errorf("password must contain at least one special character")
+	}
+
+	return nil
+}
+
+// hashPassword validates a password and returns its bcrypt hash
+func hashPassword(password string) (string, error) {
+	if err := ValidatePassword(password); err != nil {
+		return "", &ValidationError{Field: "password", Message: err.Error()}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+func validateRole(role string) error {
+	for _, known := range Roles {
+		if role == known {
+			return nil
+		}
+	}
+	return &ValidationError{Field: "role", Message: fmt.Sprintf("unknown role '%s' (must be one of: %s)", role, strings.Join(Roles, ", "))}
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shakfu/buyer/internal/models"
+)
+
+const testPassword = "Correct-Horse-9"
+
+func TestUserService_Create(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	svc := NewUserService(cfg.DB)
+
+	tests := []struct {
+		name    string
+		input   CreateUserInput
+		wantErr bool
+	}{
+		{name: "valid", input: CreateUserInput{Username: "alice", Role: RoleBuyer, Password: testPassword}},
+		{name: "empty username", input: CreateUserInput{Role: RoleBuyer, Password: testPassword}, wantErr: true},
+		{name: "unknown role", input: CreateUserInput{Username: "bob", Role: "owner", Password: testPassword}, wantErr: true},
+		{name: "weak password", input: CreateUserInput{Username: "bob", Role: RoleBuyer, Password: "password"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := svc.Create(tt.input)
+			if tt.wantErr {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Errorf("Expected ValidationError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if user.PasswordHash == "" || user.PasswordHash == tt.input.Password {
+				t.Error("Expected the password to be stored hashed")
+			}
+		})
+	}
+
+	_, err := svc.Create(CreateUserInput{Username: "alice", Role: RoleAdmin, Password: testPassword})
+	var duplicateErr *DuplicateError
+	if !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected DuplicateError, got %v", err)
+	}
+}
+
+func TestUserService_Authenticate(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	svc := NewUserService(cfg.DB)
+	if _, err := svc.Create(CreateUserInput{Username: "alice", Role: RoleBuyer, Password: testPassword}); err != nil {
+		t.Fatal(err)
+	}
+
+	if user, err := svc.Authenticate("alice", testPassword); err != nil || user.Username != "alice" {
+		t.Fatalf("Expected alice to authenticate, got %v, %v", user, err)
+	}
+	for _, tt := range []struct{ username, password string }{
+		{"alice", "Wrong-Horse-9"},
+		{"nobody", testPassword},
+		{"", ""},
+	} {
+		if _, err := svc.Authenticate(tt.username, tt.password); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Authenticate(%q): expected ErrInvalidCredentials, got %v", tt.username, err)
+		}
+	}
+
+	if _, err := svc.SetDisabled("alice", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Authenticate("alice", testPassword); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected a disabled user to be refused, got %v", err)
+	}
+}
+
+func TestUserService_Sessions(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	svc := NewUserService(cfg.DB)
+	user, err := svc.Create(CreateUserInput{Username: "alice", Role: RoleBuyer, Password: testPassword})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext, session, err := svc.CreateSession(user, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if session.TokenHash == plaintext {
+		t.Error("Expected only a hash of the session to be stored")
+	}
+	if got, err := svc.SessionUser(plaintext); err != nil || got.ID != user.ID {
+		t.Errorf("Expected the session to belong to alice, got %v, %v", got, err)
+	}
+	if _, err := svc.SessionUser("unknown"); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected ErrInvalidSession, got %v", err)
+	}
+
+	// Expired sessions are refused and removed by the next sign-in
+	cfg.DB.Model(session).Update("expires_at", time.Now().Add(-time.Minute))
+	if _, err := svc.SessionUser(plaintext); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected an expired session to be refused, got %v", err)
+	}
+	second, _, err := svc.CreateSession(user, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	cfg.DB.Model(&models.Session{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected the expired session to be removed, got %d sessions", count)
+	}
+
+	// Changing the password signs out every session
+	if _, err := svc.SetPassword("alice", "Another-Horse-10"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.SessionUser(second); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected sessions to end after a password change, got %v", err)
+	}
+
+	third, _, err := svc.CreateSession(user, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.DeleteSession(third); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.SessionUser(third); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected a deleted session to be refused, got %v", err)
+	}
+}
+
+func TestRoleHasPermission(t *testing.T) {
+	tests := []struct {
+		role       string
+		permission string
+		want       bool
+	}{
+		{RoleRequester, PermRequisitionsWrite, true},
+		{RoleRequester, PermQuotesWrite, false},
+		{RoleBuyer, PermQuotesWrite, true},
+		{RoleBuyer, PermPOIssue, true},
+		{RoleBuyer, PermPOApprove, false},
+		{RoleBuyer, PermForexWrite, false},
+		{RoleApprover, PermPOApprove, true},
+		{RoleApprover, PermPOIssue, false},
+		{RoleFinance, PermForexWrite, true},
+		{RoleFinance, PermVendorsWrite, true},
+		{RoleFinance, PermCatalogWrite, false},
+		{RoleAdmin, PermAdmin, true},
+		{RoleAdmin, PermPOApprove, true},
+		{"unknown", PermDocumentsWrite, false},
+	}
+	for _, tt := range tests {
+		if got := RoleHasPermission(tt.role, tt.permission); got != tt.want {
+			t.Errorf("RoleHasPermission(%s, %s) = %v, want %v", tt.role, tt.permission, got, tt.want)
+		}
+	}
+}
+
+func TestUserService_SignInExternal(t *testing.T) {
+	cfg := setupTestDB(t)
+	defer func() { _ = cfg.Close() }()
+
+	svc := NewUserService(cfg.DB)
+	if _, err := svc.Create(CreateUserInput{Username: "admin", Role: RoleAdmin, Password: testPassword}); err != nil {
+		t.Fatal(err)
+	}
+	input := ExternalUserInput{Issuer: "https://idp.test", Subject: "u-1", Username: "alice", Email: "alice@example.com", Role: RoleBuyer}
+
+	user, err := svc.SignInExternal(input)
+	if err != nil {
+		t.Fatalf("First sign-in failed: %v", err)
+	}
+	if user.Role != RoleBuyer || user.PasswordHash != "" {
+		t.Errorf("Expected a buyer without a password, got %+v", user)
+	}
+	if _, err := svc.Authenticate("alice", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected a single sign-on user to be refused by password sign-in, got %v", err)
+	}
+
+	// The same subject is the same user, even after a rename; the role follows the provider
+	input.Username = "alice.smith"
+	input.Role = RoleApprover
+	again, err := svc.SignInExternal(input)
+	if err != nil || again.ID != user.ID || again.Role != RoleApprover {
+		t.Errorf("Expected the linked user with the new role, got %+v, %v", again, err)
+	}
+	if linked, err := svc.HasIdentity(user.ID, "https://idp.test"); err != nil || !linked {
+		t.Errorf("Expected alice to be linked to the provider, got %v, %v", linked, err)
+	}
+
+	// Existing accounts are not taken over
+	_, err = svc.SignInExternal(ExternalUserInput{Issuer: "https://idp.test", Subject: "u-2", Username: "admin", Role: RoleAdmin})
+	var duplicateErr *DuplicateError
+	if !errors.As(err, &duplicateErr) {
+		t.Errorf("Expected DuplicateError for a local username, got %v", err)
+	}
+
+	if _, err := svc.SetDisabled("alice", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.SignInExternal(input); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected a disabled user to be refused, got %v", err)
+	}
+}
+
+func TestRoleForGroups(t *testing.T) {
+	mapping := map[string]string{"purchasing": RoleBuyer, "it-admins": RoleAdmin, "controllers": RoleFinance}
+	tests := []struct {
+		groups   []string
+		fallback string
+		want     string
+	}{
+		{[]string{"purchasing"}, "", RoleBuyer},
+		{[]string{"purchasing", "it-admins"}, "", RoleAdmin},
+		{[]string{"controllers", "purchasing"}, "", RoleFinance},
+		{[]string{"marketing"}, "", ""},
+		{nil, RoleRequester, RoleRequester},
+	}
+	for _, tt := range tests {
+		if got := RoleForGroups(tt.groups, mapping, tt.fallback); got != tt.want {
+			t.Errorf("RoleForGroups(%v) = %q, want %q", tt.groups, got, tt.want)
+		}
+	}
+}
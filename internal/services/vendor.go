@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"strings"
 
@@ -18,6 +19,11 @@ func NewVendorService(db *gorm.DB) *VendorService {
 	return &VendorService{db: db}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *VendorService) WithContext(ctx context.Context) *VendorService {
+	return NewVendorService(s.db.WithContext(ctx))
+}
+
 // Create creates a new vendor
 func (s *VendorService) Create(name, currency, discountCode string) (*models.Vendor, error) {
 	name = strings.TrimSpace(name)
@@ -35,9 +41,9 @@ func (s *VendorService) Create(name, currency, discountCode string) (*models.Ven
 
 	// Check for duplicate
 	var existing models.Vendor
-	err := s.db.Where("name = ?", name).First(&existing).Error
+	err := s.db.Unscoped().Where("name = ?", name).First(&existing).Error
 	if err == nil {
-		return nil, &DuplicateError{Entity: "Vendor", Name: name}
+		return nil, &DuplicateError{Entity: "Vendor", Name: name, InTrash: existing.DeletedAt.Valid}
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
@@ -127,9 +133,9 @@ func (s *VendorService) Update(id uint, newName string) (*models.Vendor, error)
 
 	// Check for duplicate name
 	var existing models.Vendor
-	err = s.db.Where("name = ? AND id != ?", newName, id).First(&existing).Error
+	err = s.db.Unscoped().Where("name = ? AND id != ?", newName, id).First(&existing).Error
 	if err == nil {
-		return nil, &DuplicateError{Entity: "Vendor", Name: newName}
+		return nil, &DuplicateError{Entity: "Vendor", Name: newName, InTrash: existing.DeletedAt.Valid}
 	}
 	if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
@@ -143,16 +149,9 @@ func (s *VendorService) Update(id uint, newName string) (*models.Vendor, error)
 	return vendor, nil
 }
 
-// Delete deletes a vendor by ID
+// Delete moves a vendor to the trash
 func (s *VendorService) Delete(id uint) error {
-	result := s.db.Delete(&models.Vendor{}, id)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return &NotFoundError{Entity: "Vendor", ID: id}
-	}
-	return nil
+	return moveToTrash(s.db, "vendor", id)
 }
 
 // AddBrand adds a brand association to a vendor
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -18,6 +19,11 @@ func NewVendorRatingService(db *gorm.DB) *VendorRatingService {
 	return &VendorRatingService{db: db}
 }
 
+// WithContext returns a copy of the service that runs its queries with ctx
+func (s *VendorRatingService) WithContext(ctx context.Context) *VendorRatingService {
+	return NewVendorRatingService(s.db.WithContext(ctx))
+}
+
 // CreateVendorRatingInput represents input for creating a vendor rating
 type CreateVendorRatingInput struct {
 	VendorID        uint
@@ -243,16 +249,9 @@ func (s *VendorRatingService) Update(id uint, input CreateVendorRatingInput) (*m
 	return &rating, nil
 }
 
-// Delete deletes a vendor rating
+// Delete moves a vendor rating to the trash
 func (s *VendorRatingService) Delete(id uint) error {
-	result := s.db.Delete(&models.VendorRating{}, id)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return &NotFoundError{Entity: "VendorRating", ID: id}
-	}
-	return nil
+	return moveToTrash(s.db, "vendor_rating", id)
 }
 
 // GetAverageRatings calculates average ratings for a vendor
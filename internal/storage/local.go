@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalBackend keeps objects as files under a directory. Local storage has
+// no buckets: keys are paths relative to the directory, as document file
+// paths always were, and the bucket is ignored. Document keys begin with the
+// entity type, so they do not collide.
+type LocalBackend struct {
+	root string
+}
+
+// NewLocalBackend returns a backend keeping files under root, creating it if
+// needed
+func NewLocalBackend(root string) (*LocalBackend, error) {
+	if strings.TrimSpace(root) == "" {
+		return nil, fmt.Errorf("no document storage path")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create document storage directory: %w", err)
+	}
+	return &LocalBackend{root: root}, nil
+}
+
+// path returns the file of a key, refusing keys that would leave the root
+func (b *LocalBackend) path(key string) (string, error) {
+	cleaned := path.Clean(strings.ReplaceAll(key, "\\", "/"))
+	if key == "" || path.IsAbs(cleaned) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") || filepath.VolumeName(key) != "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(b.root, filepath.FromSlash(cleaned)), nil
+}
+
+// Upload writes the object to a temporary file and renames it into place,
+// so readers never see part of a file
+func (b *LocalBackend) Upload(ctx context.Context, bucket, key string, reader io.Reader, size int64, contentType string) error {
+	target, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(tmp, reader)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size >= 0 && written != size {
+		err = fmt.Errorf("uploaded %d bytes, expected %d", written, size)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), target)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Download opens the file of an object
+func (b *LocalBackend) Download(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	target, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return file, err
+}
+
+// Delete removes the file of an object
+func (b *LocalBackend) Delete(ctx context.Context, bucket, key string) error {
+	target, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Exists reports whether the file of an object exists
+func (b *LocalBackend) Exists(ctx context.Context, bucket, key string) (bool, error) {
+	_, err := b.GetMetadata(ctx, bucket, key)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetMetadata returns the size and modification time of the file of an
+// object; the content type follows from its extension
+func (b *LocalBackend) GetMetadata(ctx context.Context, bucket, key string) (*FileMetadata, error) {
+	target, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(target)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &FileMetadata{
+		Size:         info.Size(),
+		ContentType:  mime.TypeByExtension(filepath.Ext(target)),
+		LastModified: info.ModTime(),
+	}, nil
+}
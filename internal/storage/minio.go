@@ -0,0 +1,387 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unsignedPayload is the content hash of requests whose body is streamed
+// without being hashed first
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// emptyPayload is the SHA-256 of an empty body
+const emptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// MinioBackend keeps objects in MinIO or another S3-compatible store, using
+// path-style requests signed with AWS Signature Version 4. Buckets are
+// created on first upload.
+type MinioBackend struct {
+	endpoint  *url.URL
+	accessKey string
+	secretKey string
+	region    string
+	client    *http.Client
+
+	mu      *sync.Mutex
+	buckets map[string]bool // Buckets known to exist
+}
+
+// NewMinioBackend returns a backend for the store at endpoint, a host and
+// optional port such as localhost:9000, reached over HTTPS when useSSL is
+// set. The region is us-east-1, MinIO's default, unless changed with
+// WithRegion.
+func NewMinioBackend(endpoint, accessKey, secretKey string, useSSL bool) (*MinioBackend, error) {
+	endpoint = strings.TrimSpace(endpoint)
+	if endpoint == "" {
+		return nil, fmt.Errorf("no MinIO endpoint (set MINIO_ENDPOINT)")
+	}
+	if strings.Contains(endpoint, "://") || strings.ContainsAny(endpoint, "/?#") {
+		return nil, fmt.Errorf("invalid MinIO endpoint %q: use host:port and MINIO_USE_SSL", endpoint)
+	}
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("no MinIO credentials (set MINIO_ACCESS_KEY and MINIO_SECRET_KEY)")
+	}
+	scheme := "http"
+	if useSSL {
+		scheme = "https"
+	}
+	return &MinioBackend{
+		endpoint:  &url.URL{Scheme: scheme, Host: endpoint},
+		accessKey: accessKey,
+		secretKey: secretKey,
+		region:    "us-east-1",
+		client:    &http.Client{},
+		mu:        &sync.Mutex{},
+		buckets:   map[string]bool{},
+	}, nil
+}
+
+// WithRegion returns a copy of the backend that signs requests for region
+func (b *MinioBackend) WithRegion(region string) *MinioBackend {
+	copied := *b
+	copied.region = region
+	return &copied
+}
+
+// Upload stores an object, creating its bucket if needed. Objects of unknown
+// size are read into memory first, since S3 needs the length up front.
+func (b *MinioBackend) Upload(ctx context.Context, bucket, key string, reader io.Reader, size int64, contentType string) error {
+	if err := b.ensureBucket(ctx, bucket); err != nil {
+		return err
+	}
+	if size < 0 {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		reader, size = bytes.NewReader(data), int64(len(data))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	req, err := b.newRequest(ctx, http.MethodPut, bucket, key, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.ContentLength = size
+	req.Body = io.NopCloser(reader)
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	resp, err := b.do(req, unsignedPayload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp, bucket, key)
+	}
+	return nil
+}
+
+// Download streams an object
+func (b *MinioBackend) Download(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	req, err := b.newRequest(ctx, http.MethodGet, bucket, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.do(req, emptyPayload)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp, bucket, key)
+	}
+	return resp.Body, nil
+}
+
+// Delete removes an object
+func (b *MinioBackend) Delete(ctx context.Context, bucket, key string) error {
+	req, err := b.newRequest(ctx, http.MethodDelete, bucket, key, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.do(req, emptyPayload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	}
+	return responseError(resp, bucket, key)
+}
+
+// Exists reports whether an object exists
+func (b *MinioBackend) Exists(ctx context.Context, bucket, key string) (bool, error) {
+	_, err := b.GetMetadata(ctx, bucket, key)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetMetadata returns the size, type, modification time and ETag of an
+// object
+func (b *MinioBackend) GetMetadata(ctx context.Context, bucket, key string) (*FileMetadata, error) {
+	req, err := b.newRequest(ctx, http.MethodHead, bucket, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.do(req, emptyPayload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp, bucket, key)
+	}
+	meta := &FileMetadata{
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		meta.LastModified = modified
+	}
+	return meta, nil
+}
+
+// ensureBucket creates a bucket unless it is known to exist
+func (b *MinioBackend) ensureBucket(ctx context.Context, bucket string) error {
+	b.mu.Lock()
+	known := b.buckets[bucket]
+	b.mu.Unlock()
+	if known {
+		return nil
+	}
+
+	req, err := b.newRequest(ctx, http.MethodHead, bucket, "", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.do(req, emptyPayload)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// Outside us-east-1 the bucket's region is given in the body
+		var body []byte
+		if b.region != "us-east-1" {
+			body = []byte(fmt.Sprintf(`<CreateBucketConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><LocationConstraint>%s</LocationConstraint></CreateBucketConfiguration>`, b.region))
+		}
+		req, err := b.newRequest(ctx, http.MethodPut, bucket, "", body)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(body)
+		resp, err := b.do(req, hex.EncodeToString(sum[:]))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			if failure := readError(resp); failure.Code != "BucketAlreadyOwnedByYou" {
+				return failure.err(resp, bucket, "")
+			}
+		}
+	default:
+		return responseError(resp, bucket, "")
+	}
+
+	b.mu.Lock()
+	b.buckets[bucket] = true
+	b.mu.Unlock()
+	return nil
+}
+
+// newRequest returns a path-style request for a bucket or an object
+func (b *MinioBackend) newRequest(ctx context.Context, method, bucket, key string, body []byte) (*http.Request, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("%w: no bucket", ErrInvalidKey)
+	}
+	target := *b.endpoint
+	target.Path = "/" + bucket
+	if key != "" {
+		target.Path += "/" + strings.TrimPrefix(key, "/")
+	}
+	target.RawPath = uriEncode(target.Path, false)
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	return http.NewRequestWithContext(ctx, method, target.String(), reader)
+}
+
+// do signs and sends a request
+func (b *MinioBackend) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	signRequest(req, b.accessKey, b.secretKey, b.region, payloadHash, time.Now())
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("object storage request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// s3Error is the body of S3 error responses
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// readError reads the error of a failed response; HEAD responses have no
+// body, so the code is then the status
+func readError(resp *http.Response) s3Error {
+	var body s3Error
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	_ = xml.Unmarshal(data, &body)
+	if body.Code == "" {
+		body.Code = resp.Status
+	}
+	return body
+}
+
+// responseError describes a failed response, wrapping ErrNotFound for
+// missing objects and buckets
+func responseError(resp *http.Response, bucket, key string) error {
+	return readError(resp).err(resp, bucket, key)
+}
+
+// err describes the error of a request for an object, or for a bucket when
+// key is empty
+func (body s3Error) err(resp *http.Response, bucket, key string) error {
+	name := bucket
+	if key != "" {
+		name += "/" + key
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s (%s)", ErrNotFound, name, body.Code)
+	}
+	if body.Message != "" {
+		return fmt.Errorf("object storage %s %s: %s: %s", resp.Request.Method, name, body.Code, body.Message)
+	}
+	return fmt.Errorf("object storage %s %s: %s", resp.Request.Method, name, body.Code)
+}
+
+// signRequest adds AWS Signature Version 4 headers to req. The host and all
+// headers already set are signed.
+func signRequest(req *http.Request, accessKey, secretKey, region, payloadHash string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	for _, part := range []string{region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery returns the query string as signed: sorted and encoded
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes everything but the unreserved characters, and
+// slashes unless encodeSlash is set, as S3 signatures require
+func uriEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			sb.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
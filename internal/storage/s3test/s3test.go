@@ -0,0 +1,246 @@
+// Package s3test provides an in-process S3-compatible object store for
+// tests. It keeps buckets and objects in memory, serves the path-style
+// bucket and object requests storage.MinioBackend makes, and rejects
+// requests without a valid AWS Signature Version 4 from its one access key.
+package s3test
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a running mock object store
+type Server struct {
+	URL       string
+	Endpoint  string // host:port, as given to storage.NewMinioBackend
+	AccessKey string
+	SecretKey string
+
+	server *httptest.Server
+
+	mu      sync.Mutex
+	buckets map[string]map[string]object
+}
+
+// object is a stored object
+type object struct {
+	data        []byte
+	contentType string
+	modified    time.Time
+}
+
+// New starts a store accepting the given credentials. Close it when done.
+func New(accessKey, secretKey string) *Server {
+	s := &Server{AccessKey: accessKey, SecretKey: secretKey, buckets: map[string]map[string]object{}}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.server.URL
+	s.Endpoint = strings.TrimPrefix(s.server.URL, "http://")
+	return s
+}
+
+// Close shuts the store down
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Object returns the content of an object and whether it exists
+func (s *Server) Object(bucket, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.buckets[bucket][key]
+	return obj.data, ok
+}
+
+// Buckets returns the names of the buckets, sorted
+func (s *Server) Buckets() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.buckets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fail writes an S3 error response
+func fail(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message><Resource>%s</Resource></Error>`,
+		code, message, r.URL.Path)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		fail(w, r, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+	if code, message := s.verify(r, body); code != "" {
+		fail(w, r, http.StatusForbidden, code, message)
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket == "" {
+		fail(w, r, http.StatusNotImplemented, "NotImplemented", "listing buckets is not supported")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	objects, exists := s.buckets[bucket]
+	if key == "" {
+		switch r.Method {
+		case http.MethodHead:
+			if !exists {
+				fail(w, r, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+			}
+		case http.MethodPut:
+			if exists {
+				fail(w, r, http.StatusConflict, "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded")
+				return
+			}
+			s.buckets[bucket] = map[string]object{}
+		default:
+			fail(w, r, http.StatusNotImplemented, "NotImplemented", r.Method+" on buckets is not supported")
+		}
+		return
+	}
+	if !exists {
+		fail(w, r, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if r.ContentLength < 0 || len(r.TransferEncoding) > 0 {
+			fail(w, r, http.StatusLengthRequired, "MissingContentLength", "You must provide the Content-Length HTTP header")
+			return
+		}
+		objects[key] = object{data: body, contentType: r.Header.Get("Content-Type"), modified: time.Now().UTC()}
+		w.Header().Set("ETag", etag(body))
+	case http.MethodGet, http.MethodHead:
+		obj, ok := objects[key]
+		if !ok {
+			fail(w, r, http.StatusNotFound, "NoSuchKey", "The specified key does not exist")
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Content-Length", fmt.Sprint(len(obj.data)))
+		w.Header().Set("Last-Modified", obj.modified.Format(http.TimeFormat))
+		w.Header().Set("ETag", etag(obj.data))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(obj.data)
+		}
+	case http.MethodDelete:
+		delete(objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		fail(w, r, http.StatusNotImplemented, "NotImplemented", r.Method+" on objects is not supported")
+	}
+}
+
+// etag is the quoted MD5 of an object, as S3 gives for simple uploads
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// verify checks the request's AWS Signature Version 4, returning an S3 error
+// code and message if it is not valid
+func (s *Server) verify(r *http.Request, body []byte) (string, string) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") {
+		return "AccessDenied", "Signature Version 4 required"
+	}
+	fields := map[string]string{}
+	for _, field := range strings.Split(strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 "), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		fields[name] = value
+	}
+	credential := strings.SplitN(fields["Credential"], "/", 2)
+	if len(credential) != 2 || credential[0] != s.AccessKey {
+		return "InvalidAccessKeyId", "The access key ID you provided does not exist"
+	}
+	scope := credential[1]
+	scopeParts := strings.Split(scope, "/")
+	if len(scopeParts) != 4 || scopeParts[2] != "s3" || scopeParts[3] != "aws4_request" {
+		return "AuthorizationHeaderMalformed", "invalid credential scope " + scope
+	}
+
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash != "UNSIGNED-PAYLOAD" {
+		sum := sha256.Sum256(body)
+		if payloadHash != hex.EncodeToString(sum[:]) {
+			return "XAmzContentSHA256Mismatch", "The provided x-amz-content-sha256 header does not match what was computed"
+		}
+	}
+
+	var headers strings.Builder
+	for _, name := range strings.Split(fields["SignedHeaders"], ";") {
+		value := strings.Join(r.Header.Values(name), ",")
+		if name == "host" {
+			value = r.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	query := r.URL.Query()
+	var pairs []string
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, encode(name, true)+"="+encode(value, true))
+		}
+	}
+	sort.Strings(pairs)
+
+	canonical := strings.Join([]string{r.Method, encode(r.URL.Path, false), strings.Join(pairs, "&"),
+		headers.String(), fields["SignedHeaders"], payloadHash}, "\n")
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + r.Header.Get("X-Amz-Date") + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+	key := []byte("AWS4" + s.SecretKey)
+	for _, part := range scopeParts {
+		key = mac(key, part)
+	}
+	if want := hex.EncodeToString(mac(key, toSign)); !hmac.Equal([]byte(want), []byte(fields["Signature"])) {
+		return "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided"
+	}
+	return "", ""
+}
+
+// mac returns the HMAC-SHA256 of data
+func mac(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// encode percent-encodes s as in S3 canonical requests: all but the
+// unreserved characters, and slashes in query strings
+func encode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for _, c := range []byte(s) {
+		unreserved := c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("-_.~", c) >= 0
+		if unreserved || c == '/' && !encodeSlash {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
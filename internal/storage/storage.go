@@ -0,0 +1,86 @@
+// Package storage keeps the files of documents. A StorageBackend stores
+// objects by bucket and key: LocalBackend in a directory, MinioBackend in
+// MinIO or any other S3-compatible object store. NewStorageBackend picks one
+// from the configuration.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/shakfu/buyer/internal/config"
+)
+
+// ErrNotFound is returned for objects that do not exist
+var ErrNotFound = errors.New("object not found")
+
+// ErrInvalidKey is returned for keys a backend cannot store, such as keys
+// leaving the directory of a LocalBackend
+var ErrInvalidKey = errors.New("invalid object key")
+
+// StorageBackend stores document files
+type StorageBackend interface {
+	// Upload stores an object, replacing any object with the same key. size
+	// is the length of reader, or -1 if unknown.
+	Upload(ctx context.Context, bucket, key string, reader io.Reader, size int64, contentType string) error
+	// Download opens an object for reading; the caller closes it
+	Download(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+	// Delete removes an object; deleting an object that does not exist is
+	// not an error
+	Delete(ctx context.Context, bucket, key string) error
+	Exists(ctx context.Context, bucket, key string) (bool, error)
+	GetMetadata(ctx context.Context, bucket, key string) (*FileMetadata, error)
+}
+
+// FileMetadata describes a stored object
+type FileMetadata struct {
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+	ETag         string // Empty for local files
+}
+
+// BucketForEntityType returns the bucket of the documents of an entity type
+func BucketForEntityType(entityType string) string {
+	switch entityType {
+	case "vendor":
+		return "vendor-docs"
+	case "product":
+		return "product-docs"
+	case "quote":
+		return "quote-docs"
+	case "purchase_order":
+		return "po-docs"
+	case "requisition":
+		return "requisition-docs"
+	case "project":
+		return "project-docs"
+	case "brand":
+		return "brand-docs"
+	default:
+		return "buyer-docs"
+	}
+}
+
+// NewStorageBackend returns the backend selected by cfg.StorageType: local
+// (the default) or minio
+func NewStorageBackend(cfg config.StorageConfig) (StorageBackend, error) {
+	switch cfg.StorageType {
+	case "", "local":
+		return NewLocalBackend(cfg.StorageLocalPath)
+	case "minio", "s3":
+		backend, err := NewMinioBackend(cfg.MinioEndpoint, cfg.MinioAccessKey, cfg.MinioSecretKey, cfg.MinioUseSSL)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.MinioRegion != "" {
+			backend = backend.WithRegion(cfg.MinioRegion)
+		}
+		return backend, nil
+	default:
+		return nil, fmt.Errorf("unsupported document storage type %q (local or minio)", cfg.StorageType)
+	}
+}
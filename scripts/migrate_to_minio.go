@@ -1,8 +1,8 @@
 //go:build ignore
 // +build ignore
 
-// This file is excluded from regular builds until the storage layer is implemented.
-// To build: go build -tags minio_migration ./scripts/migrate_to_minio.go
+// This file is a standalone program, kept out of go build ./... by the ignore
+// tag. To build: go build -o migrate-docs ./scripts/migrate_to_minio.go
 
 package main
 
@@ -75,6 +75,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to initialize MinIO storage: %v", err)
 	}
+	if cfg.MinioRegion != "" {
+		minioBackend = minioBackend.WithRegion(cfg.MinioRegion)
+	}
 
 	log.Printf("  Local storage: %s\n", cfg.StorageLocalPath)
 	log.Printf("  MinIO endpoint: %s\n", cfg.MinioEndpoint)